@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// activityStore is implemented by stores that keep activities normalized
+// into rows beside the snapshot blobs, for aggregate queries. Exports need
+// one, and team charts and repo timelines show no activity without it.
+type activityStore interface {
+	SaveActivities(activities []storage.Activity) (int, error)
+	DailyActivityCounts(scope string, since time.Time, loc *time.Location) ([]storage.DayCount, error)
+	RepoActivities(scope, repo string, limit int) ([]storage.Activity, error)
+	ActivitiesSince(scope string, since time.Time) ([]storage.Activity, error)
+}
+
+// metaStore is implemented by stores that keep small key/value settings.
+type metaStore interface {
+	GetMeta(key string) (string, error)
+	SetMeta(key, value string) error
+}
+
+// snapshotActivities flattens a snapshot into activity rows for scope. Stars
+// are dated by the repo's creation, as in the report, for lack of a star
+// time from the API.
+func snapshotActivities(scope string, s *diff.Snapshot, seenAt time.Time) []storage.Activity {
+	var activities []storage.Activity
+	add := func(a storage.Activity) {
+		a.Scope = scope
+		a.FirstSeen = seenAt
+		if a.OccurredAt.IsZero() {
+			a.OccurredAt = seenAt
+		}
+		activities = append(activities, a)
+	}
+	for _, ua := range s.Users {
+		for _, r := range ua.StarredRepos {
+			add(storage.Activity{Username: ua.Username, Kind: storage.KindStar, Repo: r.FullName(), Key: r.FullName(), Details: r.Description, OccurredAt: r.CreatedAt})
+		}
+		for _, r := range ua.OwnedRepos {
+			add(storage.Activity{Username: ua.Username, Kind: storage.KindRepo, Repo: r.FullName(), Key: r.FullName(), Details: r.Description, OccurredAt: r.CreatedAt})
+		}
+		for _, e := range ua.Events {
+			add(storage.Activity{
+				Username:   ua.Username,
+				Kind:       storage.KindEvent,
+				EventType:  e.Type,
+				Repo:       e.Repo,
+				Key:        fmt.Sprintf("%s %s %s", e.Type, e.Repo, e.CreatedAt.UTC().Format(time.RFC3339)),
+				OccurredAt: e.CreatedAt,
+			})
+		}
+	}
+	return activities
+}
+
+// saveActivities records a snapshot's activities if the store keeps them.
+func saveActivities(store Store, scope string, s *diff.Snapshot, seenAt time.Time) error {
+	as, ok := store.(activityStore)
+	if !ok {
+		return nil
+	}
+	_, err := as.SaveActivities(snapshotActivities(scope, s, seenAt))
+	return err
+}
+
+// backfillActivities records the activities of a scope's stored snapshots,
+// oldest first so first-seen times are right. It runs once per scope, for
+// databases written before activities were kept.
+func backfillActivities(store Store, scope string, now time.Time) error {
+	meta, ok := store.(metaStore)
+	if _, keeps := store.(activityStore); !ok || !keeps {
+		return nil
+	}
+	key := "activities_backfilled:" + scope
+	if done, err := meta.GetMeta(key); err != nil || done != "" {
+		return err
+	}
+
+	snapshots, err := store.GetByUser(scope, backfillSnapshots)
+	if err != nil {
+		return err
+	}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		s, err := storageToSnapshot(snapshots[i])
+		if err != nil {
+			return err
+		}
+		if err := saveActivities(store, scope, s, snapshots[i].Timestamp); err != nil {
+			return err
+		}
+	}
+	return meta.SetMeta(key, now.UTC().Format(time.RFC3339))
+}
+
+// backfillSnapshots caps how many past snapshots backfillActivities reads.
+const backfillSnapshots = 100
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func activitySnapshot(now time.Time) *diff.Snapshot {
+	s := diff.NewSnapshot(now)
+	s.Users["alice"] = diff.UserActivity{
+		Username:     "alice",
+		StarredRepos: []diff.Repo{{Owner: "golang", Name: "go"}},
+		OwnedRepos:   []diff.Repo{{Owner: "alice", Name: "tool", CreatedAt: now.AddDate(0, 0, -2)}},
+		Events: []diff.Event{
+			{Type: "PushEvent", Repo: "alice/tool", CreatedAt: now.Add(-time.Hour)},
+			{Type: "PushEvent", Repo: "alice/tool", CreatedAt: now.Add(-2 * time.Hour)},
+		},
+	}
+	return s
+}
+
+func TestSnapshotActivities(t *testing.T) {
+	now := fixedTime()
+	activities := snapshotActivities("profile:team", activitySnapshot(now), now)
+	if len(activities) != 4 {
+		t.Fatalf("expected 4 activities, got %d: %+v", len(activities), activities)
+	}
+
+	kinds := make(map[string]int)
+	keys := make(map[string]bool)
+	for _, a := range activities {
+		kinds[a.Kind]++
+		keys[a.Kind+" "+a.Key] = true
+		if a.Scope != "profile:team" || a.Username != "alice" || !a.FirstSeen.Equal(now) {
+			t.Errorf("unexpected activity %+v", a)
+		}
+		if a.Kind == storage.KindStar && !a.OccurredAt.Equal(now) {
+			t.Errorf("star without a repo date should fall back to when it was seen, got %v", a.OccurredAt)
+		}
+		if a.Kind == storage.KindRepo && !a.OccurredAt.Equal(now.AddDate(0, 0, -2)) {
+			t.Errorf("repo should be dated by its creation, got %v", a.OccurredAt)
+		}
+	}
+	if kinds[storage.KindStar] != 1 || kinds[storage.KindRepo] != 1 || kinds[storage.KindEvent] != 2 {
+		t.Errorf("unexpected kinds %v", kinds)
+	}
+	if len(keys) != len(activities) {
+		t.Errorf("expected distinct keys, got %v", keys)
+	}
+}
+
+func TestSaveSnapshot_RecordsActivities(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := fixedTime()
+	// The same activity in a later snapshot isn't counted twice
+	for _, at := range []time.Time{now, now.Add(time.Hour)} {
+		if _, err := saveSnapshotAs(store, runSnapshotID(&Config{}), activitySnapshot(now), at); err != nil {
+			t.Fatalf("saveSnapshotAs failed: %v", err)
+		}
+	}
+
+	counts, err := store.DailyActivityCounts(snapshotUserID, now.AddDate(0, 0, -7), time.UTC)
+	if err != nil {
+		t.Fatalf("DailyActivityCounts failed: %v", err)
+	}
+	total := 0
+	for _, c := range counts {
+		total += c.Count
+	}
+	if total != 4 || len(counts) != 2 {
+		t.Errorf("expected 4 activities over 2 days, got %+v", counts)
+	}
+}
+
+func TestBackfillActivities(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	// Snapshots saved before activities were kept
+	now := fixedTime()
+	ss, err := snapshotToStorage(activitySnapshot(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.UserID = "profile:team"
+	ss.Timestamp = now
+	if err := store.SaveAll([]*storage.Snapshot{ss}); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func() int {
+		t.Helper()
+		counts, err := store.DailyActivityCounts("profile:team", now.AddDate(0, 0, -7), time.UTC)
+		if err != nil {
+			t.Fatalf("DailyActivityCounts failed: %v", err)
+		}
+		total := 0
+		for _, c := range counts {
+			total += c.Count
+		}
+		return total
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("expected no activities before backfill, got %d", n)
+	}
+
+	if err := backfillActivities(store, "profile:team", now); err != nil {
+		t.Fatalf("backfillActivities failed: %v", err)
+	}
+	if n := count(); n != 4 {
+		t.Errorf("expected 4 activities after backfill, got %d", n)
+	}
+	if done, _ := store.GetMeta("activities_backfilled:profile:team"); done == "" {
+		t.Error("expected the backfill to be recorded")
+	}
+
+	// Stores without an activities table are left alone
+	if err := backfillActivities(&mockStore{}, "profile:team", now); err != nil {
+		t.Errorf("backfillActivities on a plain store: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/notify"
+	"github.com/justinabrahms/gitstreams/rules"
+)
+
+const (
+	defaultRulesName = "rules.json"
+	rulesStateName   = "rules-state.json"
+)
+
+// loadRules loads the alert rules at path, or from the config directory if
+// path is empty. A missing default rules file means there are no rules.
+func loadRules(path string) ([]rules.Rule, error) {
+	explicit := path != ""
+	if !explicit {
+		dir, err := configDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, defaultRulesName)
+	}
+
+	loaded, err := rules.Load(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading rules: %w", err)
+	}
+	return loaded, nil
+}
+
+// fireAlerts evaluates the alert rules against this sync's changes and
+// sends an alert for each rule that fires, separately from the digest.
+// Counts for threshold rules are kept in the data directory between runs.
+func fireAlerts(ctx context.Context, cfg *Config, deps *Dependencies, result *diff.Result, stdout, stderr io.Writer) error {
+	ruleSet, err := loadRules(cfg.RulesPath)
+	if err != nil || len(ruleSet) == 0 {
+		return err
+	}
+
+	dir, err := dataDir(cfg.LegacyDirs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	statePath := filepath.Join(dir, rulesStateName)
+	state, err := loadRulesState(statePath)
+	if err != nil {
+		return err
+	}
+
+	alerts := rules.Evaluate(ruleSet, result, state)
+
+	notifier, err := selectNotifier(cfg, deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Warning: could not send alerts: %v\n", err)
+	}
+	for _, alert := range alerts {
+		_, _ = fmt.Fprintf(stdout, "Alert (%s): %s\n", alert.Rule, alert.Message)
+		if notifier == nil {
+			continue
+		}
+		n := notify.Notification{
+			Title:    "GitStreams alert",
+			Subtitle: alert.Rule,
+			Message:  alert.Message,
+			Sound:    "default",
+			Alert:    alert.Rule,
+		}
+		if err := notify.SendContext(ctx, notifier, n); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: could not send alert %q: %v\n", alert.Rule, err)
+		}
+	}
+
+	return saveRulesState(statePath, state)
+}
+
+func loadRulesState(path string) (*rules.State, error) {
+	state := &rules.State{}
+	data, err := os.ReadFile(path) // #nosec G304 -- path is in the gitstreams data directory
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading rules state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("reading rules state: %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func saveRulesState(path string, state *rules.State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("saving rules state: %w", err)
+	}
+	return nil
+}
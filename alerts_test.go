@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+func TestFireAlerts(t *testing.T) {
+	home := setHome(t)
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultRulesName),
+		`[{"name": "go releases", "when": "event", "type": "ReleaseEvent", "repos": ["golang/*"]},
+		  {"name": "busy", "when": "count", "threshold": 5}]`)
+
+	result := &diff.Result{
+		NewEvents: []diff.EventChange{
+			{Username: "rsc", Event: diff.Event{Type: "ReleaseEvent", Actor: "rsc", Repo: "golang/go"}},
+			{Username: "rsc", Event: diff.Event{Type: "PushEvent", Actor: "rsc", Repo: "rsc/notes"}},
+		},
+	}
+
+	notifier := &mockNotifier{}
+	deps := &Dependencies{NotifierFactory: func() Notifier { return notifier }}
+	cfg := &Config{}
+	var stdout, stderr strings.Builder
+
+	if err := fireAlerts(context.Background(), cfg, deps, result, &stdout, &stderr); err != nil {
+		t.Fatalf("fireAlerts(context.Background(), ) error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Alert (go releases): ReleaseEvent in golang/go by rsc") {
+		t.Errorf("expected alert in output, got %q", stdout.String())
+	}
+	if notifier.sentNotification == nil || notifier.sentNotification.Subtitle != "go releases" {
+		t.Errorf("expected an alert notification, got %+v", notifier.sentNotification)
+	}
+
+	// The count rule accumulates across syncs: 2 + 2 + 2 > 5
+	_ = fireAlerts(context.Background(), cfg, deps, result, &stdout, &stderr)
+	stdout.Reset()
+	_ = fireAlerts(context.Background(), cfg, deps, result, &stdout, &stderr)
+	if !strings.Contains(stdout.String(), "Alert (busy): 6 new activities") {
+		t.Errorf("expected count alert on third sync, got %q", stdout.String())
+	}
+}
+
+func TestFireAlerts_NoRules(t *testing.T) {
+	setHome(t)
+	notifier := &mockNotifier{}
+	deps := &Dependencies{NotifierFactory: func() Notifier { return notifier }}
+	var stdout, stderr strings.Builder
+
+	if err := fireAlerts(context.Background(), &Config{}, deps, &diff.Result{}, &stdout, &stderr); err != nil {
+		t.Fatalf("fireAlerts(context.Background(), ) error = %v", err)
+	}
+	if stdout.Len() != 0 || notifier.sentNotification != nil {
+		t.Error("expected nothing without a rules file")
+	}
+}
+
+func TestLoadRules_Explicit(t *testing.T) {
+	setHome(t)
+	if _, err := loadRules(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing explicit rules file")
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	writeFile(t, path, `[{"name": "x", "when": "count"}]`)
+	if _, err := loadRules(path); err == nil || !strings.Contains(err.Error(), "threshold") {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+// categoryList is a flag of comma-separated report categories, named by
+// activity type (e.g. "released,pushed"). Repeating the flag adds to it.
+type categoryList []report.ActivityType
+
+func (l *categoryList) String() string {
+	if l == nil {
+		return ""
+	}
+	names, _ := l.Get().([]string)
+	return strings.Join(names, ",")
+}
+
+// Get returns the names, and marks this as a list flag for the config file.
+func (l *categoryList) Get() any {
+	names := make([]string, len(*l))
+	for i, t := range *l {
+		names[i] = string(t)
+	}
+	return names
+}
+
+func (l *categoryList) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		t := report.ActivityType(strings.TrimSpace(name))
+		if t == "" {
+			continue
+		}
+		if err := checkCategory(t); err != nil {
+			return err
+		}
+		if !slices.Contains(*l, t) {
+			*l = append(*l, t)
+		}
+	}
+	return nil
+}
+
+// checkCategory returns an error listing the known categories if t isn't
+// one.
+func checkCategory(t report.ActivityType) error {
+	known := report.ActivityTypes()
+	if slices.Contains(known, t) {
+		return nil
+	}
+	names := make([]string, len(known))
+	for i, k := range known {
+		names[i] = string(k)
+	}
+	return fmt.Errorf("unknown category %q (known: %s)", t, strings.Join(names, ", "))
+}
+
+// aggregationList is the -aggregate flag: comma-separated category:setting
+// entries, where the category is an activity type or "all" and the setting
+// is "daily" (collapse each day separately) or a number (collapse only
+// that many or more). Repeating the flag adds to it.
+type aggregationList []string
+
+func (l *aggregationList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+// Get returns the entries, and marks this as a list flag for the config
+// file.
+func (l *aggregationList) Get() any {
+	return []string(*l)
+}
+
+func (l *aggregationList) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, _, err := parseAggregation(entry); err != nil {
+			return err
+		}
+		*l = append(*l, entry)
+	}
+	return nil
+}
+
+// rules returns the report's aggregation rules. Categories start from what
+// "all" sets, whatever the order of the entries.
+func (l aggregationList) rules() report.AggregationRules {
+	var rules report.AggregationRules
+	byType := make(map[report.ActivityType][]func(*report.Aggregation))
+	for _, entry := range l {
+		category, apply, err := parseAggregation(entry)
+		if err != nil {
+			continue // Checked by Set
+		}
+		if category == "all" {
+			apply(&rules.Default)
+		} else {
+			byType[category] = append(byType[category], apply)
+		}
+	}
+	for t, applies := range byType {
+		if rules.ByType == nil {
+			rules.ByType = make(map[report.ActivityType]report.Aggregation)
+		}
+		agg := rules.Default
+		for _, apply := range applies {
+			apply(&agg)
+		}
+		rules.ByType[t] = agg
+	}
+	return rules
+}
+
+// parseAggregation parses a category:setting entry into its category and a
+// func applying the setting.
+func parseAggregation(entry string) (report.ActivityType, func(*report.Aggregation), error) {
+	name, setting, ok := strings.Cut(entry, ":")
+	category := report.ActivityType(strings.TrimSpace(name))
+	setting = strings.TrimSpace(setting)
+	if !ok || category == "" || setting == "" {
+		return "", nil, fmt.Errorf("aggregation %q should look like category:daily or category:N", entry)
+	}
+	if category != "all" {
+		if err := checkCategory(category); err != nil {
+			return "", nil, err
+		}
+	}
+	if setting == "daily" {
+		return category, func(a *report.Aggregation) { a.Daily = true }, nil
+	}
+	n, err := strconv.Atoi(setting)
+	if err != nil || n < 1 {
+		return "", nil, fmt.Errorf("aggregation %q: setting should be daily or a positive number", entry)
+	}
+	return category, func(a *report.Aggregation) { a.MinCount = n }, nil
+}
+
+// pinList is the -pin flag: comma-separated repos, as owner/name or owner/*
+// for all of an owner's repos, whose activity the report lifts into a
+// pinned section. Repeating the flag adds to it.
+type pinList []string
+
+func (l *pinList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+// Get returns the repos, and marks this as a list flag for the config file.
+func (l *pinList) Get() any {
+	return []string(*l)
+}
+
+func (l *pinList) Set(value string) error {
+	for _, pin := range strings.Split(value, ",") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		owner, name, ok := strings.Cut(pin, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") ||
+			strings.Contains(owner, "*") || (name != "*" && strings.Contains(name, "*")) {
+			return fmt.Errorf("pinned repo %q should look like owner/name or owner/*", pin)
+		}
+		if !slices.ContainsFunc(*l, func(p string) bool { return strings.EqualFold(p, pin) }) {
+			*l = append(*l, pin)
+		}
+	}
+	return nil
+}
+
+// actorList is the -noise-actors and -noise-allow flags: comma-separated
+// username patterns, with * matching any run of characters.
+type actorList []string
+
+func (l *actorList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+// Get returns the patterns, and marks this as a list flag for the config
+// file.
+func (l *actorList) Get() any {
+	return []string(*l)
+}
+
+func (l *actorList) Set(value string) error {
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if strings.Trim(pattern, "*") == "" && pattern != "*" ||
+			strings.ContainsFunc(pattern, func(r rune) bool { return !actorPatternRune(r) }) {
+			return fmt.Errorf("actor %q should be a username, with * matching any run of characters", pattern)
+		}
+		if !slices.ContainsFunc(*l, func(p string) bool { return strings.EqualFold(p, pattern) }) {
+			*l = append(*l, pattern)
+		}
+	}
+	return nil
+}
+
+// noiseRules returns the report's automation noise rules: known bots and
+// cfg's noise actors, and force pushes, except for cfg's allowed actors.
+func (cfg *Config) noiseRules() report.NoiseRules {
+	return report.NoiseRules{
+		Actors:      append(slices.Clone(report.DefaultNoiseActors), cfg.NoiseActors...),
+		Allow:       cfg.NoiseAllow,
+		ForcePushes: true,
+	}
+}
+
+// actorPatternRune reports whether r can be part of an actor pattern: the
+// letters, digits and hyphens of usernames, the brackets of GitHub Apps'
+// [bot] suffix, and wildcards.
+func actorPatternRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_[]*", r)
+}
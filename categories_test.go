@@ -0,0 +1,151 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+func TestCategoryList_Set(t *testing.T) {
+	var l categoryList
+	if err := l.Set("released, pushed"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := l.Set("pushed,issue"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	want := categoryList{report.ActivityReleased, report.ActivityPushed, report.ActivityIssue}
+	if !slices.Equal(l, want) {
+		t.Errorf("got %v, want %v", l, want)
+	}
+	if l.String() != "released,pushed,issue" {
+		t.Errorf("String() = %q", l.String())
+	}
+
+	if err := l.Set("pushes"); err == nil || !strings.Contains(err.Error(), `unknown category "pushes"`) {
+		t.Errorf("Set(pushes) error = %v, want unknown category", err)
+	}
+}
+
+func TestPinList_Set(t *testing.T) {
+	var l pinList
+	if err := l.Set("golang/go, alice/*"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := l.Set("GoLang/Go,bob/tool"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if l.String() != "golang/go,alice/*,bob/tool" {
+		t.Errorf("String() = %q", l.String())
+	}
+
+	for _, value := range []string{"golang", "golang/", "/go", "a/b/c", "*/go", "golang/g*"} {
+		if err := l.Set(value); err == nil || !strings.Contains(err.Error(), "owner/name or owner/*") {
+			t.Errorf("Set(%q) error = %v, want a format error", value, err)
+		}
+	}
+}
+
+func TestActorList_Set(t *testing.T) {
+	var l actorList
+	if err := l.Set("deploy-*, ci-bot"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := l.Set("CI-Bot,*[bot]"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if l.String() != "deploy-*,ci-bot,*[bot]" {
+		t.Errorf("String() = %q", l.String())
+	}
+
+	for _, value := range []string{"**", "alice/tool", "bad name", "alice@example.com"} {
+		if err := l.Set(value); err == nil || !strings.Contains(err.Error(), "should be a username") {
+			t.Errorf("Set(%q) error = %v, want a format error", value, err)
+		}
+	}
+}
+
+func TestConfigNoiseRules(t *testing.T) {
+	home := setHome(t)
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultConfigName),
+		`{"noise-actors": ["deploy-*"], "noise-allow": "renovate-me"}`)
+
+	cfg, err := parseFlags(nil)
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	rules := cfg.noiseRules()
+	if want := append(slices.Clone(report.DefaultNoiseActors), "deploy-*"); !slices.Equal(rules.Actors, want) {
+		t.Errorf("Actors = %v, want %v", rules.Actors, want)
+	}
+	if want := []string{"renovate-me"}; !slices.Equal(rules.Allow, want) {
+		t.Errorf("Allow = %v, want %v", rules.Allow, want)
+	}
+	if !rules.ForcePushes {
+		t.Error("ForcePushes = false, want force pushes treated as noise")
+	}
+}
+
+func TestParseFlags_CategoriesFromConfigFile(t *testing.T) {
+	home := setHome(t)
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultConfigName),
+		`{"category-order": ["released", "pull_request"], "hide-categories": "pushed", "pin": ["golang/go", "alice/*"]}`)
+
+	cfg, err := parseFlags(nil)
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	if want := (categoryList{report.ActivityReleased, report.ActivityPR}); !slices.Equal(cfg.CategoryOrder, want) {
+		t.Errorf("CategoryOrder = %v, want %v", cfg.CategoryOrder, want)
+	}
+	if want := (categoryList{report.ActivityPushed}); !slices.Equal(cfg.HideCategories, want) {
+		t.Errorf("HideCategories = %v, want %v", cfg.HideCategories, want)
+	}
+	if want := (pinList{"golang/go", "alice/*"}); !slices.Equal(cfg.Pin, want) {
+		t.Errorf("Pin = %v, want %v", cfg.Pin, want)
+	}
+}
+
+func TestAggregationList(t *testing.T) {
+	var l aggregationList
+	if err := l.Set("pushed:daily, all:2"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := l.Set("starred:5"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if l.String() != "pushed:daily,all:2,starred:5" {
+		t.Errorf("String() = %q", l.String())
+	}
+
+	rules := l.rules()
+	if want := (report.Aggregation{MinCount: 2}); rules.Default != want {
+		t.Errorf("Default = %+v, want %+v", rules.Default, want)
+	}
+	// Categories build on "all", even when it comes later
+	if want := (report.Aggregation{MinCount: 2, Daily: true}); rules.For(report.ActivityPushed) != want {
+		t.Errorf("pushed = %+v, want %+v", rules.For(report.ActivityPushed), want)
+	}
+	if want := (report.Aggregation{MinCount: 5}); rules.For(report.ActivityStarred) != want {
+		t.Errorf("starred = %+v, want %+v", rules.For(report.ActivityStarred), want)
+	}
+
+	tests := []struct {
+		value   string
+		wantErr string
+	}{
+		{"pushed", "category:daily"},
+		{"pushed:", "category:daily"},
+		{"pushes:daily", "unknown category"},
+		{"pushed:weekly", "daily or a positive number"},
+		{"pushed:0", "daily or a positive number"},
+	}
+	for _, tt := range tests {
+		if err := l.Set(tt.value); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Set(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+		}
+	}
+}
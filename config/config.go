@@ -0,0 +1,290 @@
+// Package config loads gitstreams settings from a JSON config file and the
+// environment.
+//
+// Config keys are the command-line flag names (e.g. "db", "no-notify",
+// "sync-lookback-days"), so the file can set anything a flag can. Values are
+// applied onto a flag.FlagSet for every flag not given on the command line.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Entry is a single key/value pair read from a config file.
+type Entry struct {
+	Key   string
+	Value json.RawMessage
+	Line  int
+}
+
+// File is a parsed config file. Entries appear in file order.
+type File struct {
+	Path    string
+	Entries []Entry
+}
+
+// Problem describes an invalid entry in a config file.
+type Problem struct {
+	Path    string
+	Key     string
+	Message string
+	Line    int
+}
+
+func (p Problem) Error() string {
+	if p.Key == "" {
+		return fmt.Sprintf("%s:%d: %s", p.Path, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", p.Path, p.Line, p.Key, p.Message)
+}
+
+// Problems collects every problem found in a config file.
+type Problems []Problem
+
+func (ps Problems) Error() string {
+	msgs := make([]string, len(ps))
+	for i, p := range ps {
+		msgs[i] = p.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Load reads and parses the config file at path. If the file doesn't exist,
+// Load returns a nil File and an error wrapping os.ErrNotExist.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- config path is user-specified or a fixed default
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse parses config file contents. The top level must be a JSON object.
+// Syntax errors are returned as a Problem carrying the line number.
+func Parse(path string, data []byte) (*File, error) {
+	f := &File{Path: path}
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	syntaxProblem := func(err error) error {
+		line := lineAt(data, int(dec.InputOffset()))
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			line = lineAt(data, int(se.Offset))
+		}
+		return Problem{Path: path, Line: line, Message: err.Error()}
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return f, nil // empty file
+		}
+		return nil, syntaxProblem(err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, Problem{Path: path, Line: 1, Message: "config must be a JSON object"}
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, syntaxProblem(err)
+		}
+		key, _ := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, syntaxProblem(err)
+		}
+		valueEnd := int(dec.InputOffset())
+
+		f.Entries = append(f.Entries, Entry{
+			Key:   key,
+			Value: value,
+			Line:  lineAt(data, valueEnd-len(value)),
+		})
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, syntaxProblem(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, Problem{Path: path, Line: lineAt(data, int(dec.InputOffset())), Message: "unexpected content after config object"}
+	}
+
+	return f, nil
+}
+
+// Apply sets each entry onto the matching flag in fs, skipping flags named
+// in skip (typically those given on the command line, which take
+// precedence). Keys not in allowed are reported as unknown; a nil allowed
+// permits every flag in fs. Every problem is collected rather than stopping
+// at the first. It returns the names of the flags it set.
+func (f *File) Apply(fs *flag.FlagSet, allowed, skip map[string]bool) ([]string, error) {
+	if f == nil {
+		return nil, nil
+	}
+
+	var problems Problems
+	var applied []string
+	seen := make(map[string]int)
+
+	for _, e := range f.Entries {
+		problem := func(format string, args ...interface{}) {
+			problems = append(problems, Problem{Path: f.Path, Line: e.Line, Key: e.Key, Message: fmt.Sprintf(format, args...)})
+		}
+
+		fl := fs.Lookup(e.Key)
+		if fl == nil || (allowed != nil && !allowed[e.Key]) {
+			problem("unknown key")
+			continue
+		}
+		if line, dup := seen[e.Key]; dup {
+			problem("duplicate key (first set on line %d)", line)
+			continue
+		}
+		seen[e.Key] = e.Line
+
+		value, err := flagValue(fl, e.Value)
+		if err != nil {
+			problem("%v", err)
+			continue
+		}
+		if skip[e.Key] {
+			continue
+		}
+		if err := fs.Set(e.Key, value); err != nil {
+			problem("invalid value %q: %v", value, err)
+			continue
+		}
+		applied = append(applied, e.Key)
+	}
+
+	if len(problems) > 0 {
+		return applied, problems
+	}
+	return applied, nil
+}
+
+// ApplyEnv sets flags in fs from environment variables. envName maps each
+// allowed flag name to its variable; lookup reads the environment (normally
+// os.LookupEnv). Empty variables are treated as unset, and flags named in
+// skip are left alone. It returns the names of the flags it set.
+func ApplyEnv(fs *flag.FlagSet, allowed, skip map[string]bool, envName func(name string) string, lookup func(string) (string, bool)) ([]string, error) {
+	var errs []error
+	var applied []string
+
+	fs.VisitAll(func(fl *flag.Flag) {
+		if !allowed[fl.Name] || skip[fl.Name] {
+			return
+		}
+		name := envName(fl.Name)
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			return
+		}
+		if err := fs.Set(fl.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", name, value, err))
+			return
+		}
+		applied = append(applied, fl.Name)
+	})
+
+	return applied, errors.Join(errs...)
+}
+
+// EnvName returns the conventional environment variable for a flag: the
+// prefix followed by the flag name upper-cased with dashes as underscores,
+// e.g. EnvName("GITSTREAMS_", "no-open") is "GITSTREAMS_NO_OPEN".
+func EnvName(prefix, name string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// flagValue converts a JSON value to the string form fl.Value.Set expects,
+// checking that its JSON type matches the flag's type.
+func flagValue(fl *flag.Flag, raw json.RawMessage) (string, error) {
+	getter, ok := fl.Value.(flag.Getter)
+	if !ok {
+		return "", errors.New("flag can't be set from a config file")
+	}
+
+	switch getter.Get().(type) {
+	case bool:
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return "", fmt.Errorf("expected true or false, got %s", describe(raw))
+		}
+		return strconv.FormatBool(b), nil
+	case int, int64, uint, uint64:
+		var n json.Number
+		if describe(raw) != "a number" || json.Unmarshal(raw, &n) != nil || !isInteger(n) {
+			return "", fmt.Errorf("expected an integer, got %s", describe(raw))
+		}
+		return n.String(), nil
+	case []string:
+		// List flags take comma-separated values, given either way in JSON
+		var list []string
+		if err := json.Unmarshal(raw, &list); err == nil {
+			return strings.Join(list, ","), nil
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", fmt.Errorf("expected a list of strings, got %s", describe(raw))
+		}
+		return s, nil
+	default:
+		// Strings, and any other flag type (durations, custom values) given
+		// as a string in its flag syntax
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", fmt.Errorf("expected a string, got %s", describe(raw))
+		}
+		return s, nil
+	}
+}
+
+func isInteger(n json.Number) bool {
+	_, err := n.Int64()
+	return err == nil
+}
+
+// describe names the JSON type of raw for error messages.
+func describe(raw json.RawMessage) string {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return "nothing"
+	}
+	switch trimmed[0] {
+	case '"':
+		return "a string"
+	case '{':
+		return "an object"
+	case '[':
+		return "an array"
+	case 't', 'f':
+		return "a boolean"
+	case 'n':
+		return "null"
+	default:
+		return "a number"
+	}
+}
+
+// lineAt returns the 1-based line number of byte offset off in data.
+func lineAt(data []byte, off int) int {
+	if off > len(data) {
+		off = len(data)
+	}
+	if off < 0 {
+		off = 0
+	}
+	return bytes.Count(data[:off], []byte("\n")) + 1
+}
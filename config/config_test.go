@@ -0,0 +1,261 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFlagSet() (*flag.FlagSet, *string, *bool, *int, *time.Duration) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	db := fs.String("db", "", "")
+	noOpen := fs.Bool("no-open", false, "")
+	days := fs.Int("days", 30, "")
+	every := fs.Duration("every", time.Hour, "")
+	return fs, db, noOpen, days, every
+}
+
+func TestParse(t *testing.T) {
+	data := []byte(`{
+  "db": "/tmp/x.db",
+
+  "no-open": true,
+  "days": 7
+}`)
+
+	f, err := Parse("c.json", data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	var keys []string
+	var lines []int
+	for _, e := range f.Entries {
+		keys = append(keys, e.Key)
+		lines = append(lines, e.Line)
+	}
+	if want := []string{"db", "no-open", "days"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+	if want := []int{2, 4, 5}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantLine int
+		wantMsg  string
+	}{
+		{name: "not an object", data: `["db"]`, wantLine: 1, wantMsg: "JSON object"},
+		{name: "missing colon", data: "{\n  \"db\": \"x\",\n  \"days\" 7\n}", wantLine: 3, wantMsg: "invalid character"},
+		{name: "trailing content", data: "{}\n{}", wantLine: 2, wantMsg: "unexpected content"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("c.json", []byte(tt.data))
+			var p Problem
+			if !errors.As(err, &p) {
+				t.Fatalf("expected Problem, got %v", err)
+			}
+			if p.Line != tt.wantLine {
+				t.Errorf("line = %d, want %d (%v)", p.Line, tt.wantLine, p)
+			}
+			if !strings.Contains(p.Message, tt.wantMsg) {
+				t.Errorf("message = %q, want it to contain %q", p.Message, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	f, err := Parse("c.json", []byte("  \n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(f.Entries) != 0 {
+		t.Errorf("expected no entries, got %v", f.Entries)
+	}
+}
+
+func TestApply(t *testing.T) {
+	fs, db, noOpen, days, every := newTestFlagSet()
+	f, err := Parse("c.json", []byte(`{"db": "/tmp/x.db", "no-open": true, "days": 7, "every": "5m"}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	applied, err := f.Apply(fs, nil, map[string]bool{"days": true})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	if *db != "/tmp/x.db" || !*noOpen || *every != 5*time.Minute {
+		t.Errorf("unexpected values: db=%q no-open=%v every=%v", *db, *noOpen, *every)
+	}
+	if *days != 30 {
+		t.Errorf("expected skipped flag to keep its value, got %d", *days)
+	}
+	if want := []string{"db", "no-open", "every"}; !reflect.DeepEqual(applied, want) {
+		t.Errorf("applied = %v, want %v", applied, want)
+	}
+}
+
+func TestApplyProblems(t *testing.T) {
+	fs, _, _, _, _ := newTestFlagSet()
+	f, err := Parse("c.json", []byte(`{
+  "db": 5,
+  "no-open": "yes",
+  "days": "7",
+  "every": "soon",
+  "bogus": true,
+  "db": "x"
+}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	_, err = f.Apply(fs, map[string]bool{"db": true, "no-open": true, "days": true, "every": true}, nil)
+	var problems Problems
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected Problems, got %v", err)
+	}
+
+	want := []string{
+		"c.json:2: db: expected a string, got a number",
+		"c.json:3: no-open: expected true or false, got a string",
+		"c.json:4: days: expected an integer, got a string",
+		"c.json:5: every: invalid value",
+		"c.json:6: bogus: unknown key",
+		"c.json:7: db: duplicate key (first set on line 2)",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(problems), len(want), problems)
+	}
+	for i, p := range problems {
+		if !strings.HasPrefix(p.Error(), want[i]) {
+			t.Errorf("problem %d = %q, want prefix %q", i, p.Error(), want[i])
+		}
+	}
+}
+
+func TestApplyDisallowedKey(t *testing.T) {
+	fs, _, _, _, _ := newTestFlagSet()
+	f, err := Parse("c.json", []byte(`{"db": "x"}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if _, err := f.Apply(fs, map[string]bool{"days": true}, nil); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if _, err := Load(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected ErrNotExist for missing file, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"db": "x"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if f.Path != path || len(f.Entries) != 1 {
+		t.Errorf("unexpected file: %+v", f)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got := EnvName("APP_", "sync-lookback-days"); got != "APP_SYNC_LOOKBACK_DAYS" {
+		t.Errorf("EnvName() = %q", got)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	fs, db, noOpen, days, _ := newTestFlagSet()
+	env := map[string]string{
+		"APP_DB":      "/env.db",
+		"APP_NO_OPEN": "1",
+		"APP_DAYS":    "", // empty means unset
+		"APP_EVERY":   "2m",
+	}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+	envName := func(name string) string { return EnvName("APP_", name) }
+
+	allowed := map[string]bool{"db": true, "no-open": true, "days": true}
+	applied, err := ApplyEnv(fs, allowed, map[string]bool{"no-open": false}, envName, lookup)
+	if err != nil {
+		t.Fatalf("ApplyEnv() error = %v", err)
+	}
+
+	if *db != "/env.db" || !*noOpen || *days != 30 {
+		t.Errorf("unexpected values: db=%q no-open=%v days=%d", *db, *noOpen, *days)
+	}
+	if want := []string{"db", "no-open"}; !reflect.DeepEqual(applied, want) {
+		t.Errorf("applied = %v, want %v (disallowed flags must be ignored)", applied, want)
+	}
+}
+
+func TestApplyEnvSkipAndErrors(t *testing.T) {
+	fs, db, _, _, _ := newTestFlagSet()
+	env := map[string]string{"APP_DB": "/env.db", "APP_DAYS": "lots", "APP_NO_OPEN": "maybe"}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+	envName := func(name string) string { return EnvName("APP_", name) }
+
+	_, err := ApplyEnv(fs, map[string]bool{"db": true, "days": true, "no-open": true}, map[string]bool{"db": true}, envName, lookup)
+	if *db != "" {
+		t.Errorf("expected skipped flag to be left alone, got %q", *db)
+	}
+	if err == nil || !strings.Contains(err.Error(), `APP_DAYS: invalid value "lots"`) || !strings.Contains(err.Error(), "APP_NO_OPEN") {
+		t.Errorf("expected errors for both bad variables, got %v", err)
+	}
+}
+
+// listValue is a comma-separated list flag, like gitstreams' -source.
+type listValue []string
+
+func (l *listValue) String() string { return strings.Join(*l, ",") }
+func (l *listValue) Get() any       { return []string(*l) }
+func (l *listValue) Set(v string) error {
+	*l = append(*l, strings.Split(v, ",")...)
+	return nil
+}
+
+func TestApplyList(t *testing.T) {
+	for _, input := range []string{`{"tags": ["a", "b"]}`, `{"tags": "a,b"}`} {
+		var tags listValue
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Var(&tags, "tags", "")
+
+		f, err := Parse("c.json", []byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Apply(fs, nil, nil); err != nil {
+			t.Fatalf("Apply(%s) error = %v", input, err)
+		}
+		if !reflect.DeepEqual([]string(tags), []string{"a", "b"}) {
+			t.Errorf("Apply(%s): tags = %v, want [a b]", input, tags)
+		}
+	}
+
+	var tags listValue
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&tags, "tags", "")
+	f, _ := Parse("c.json", []byte(`{"tags": 3}`))
+	if _, err := f.Apply(fs, nil, nil); err == nil || !strings.Contains(err.Error(), "expected a list of strings") {
+		t.Errorf("expected list type error, got %v", err)
+	}
+}
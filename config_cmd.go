@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/justinabrahms/gitstreams/config"
+)
+
+const configUsage = `Usage:
+  gitstreams config validate [-config path]
+  gitstreams config show [-redact] [flags]`
+
+// runConfig handles the "config" subcommand.
+func runConfig(stdout, stderr io.Writer, args []string) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprintln(stderr, configUsage)
+		return 1
+	}
+
+	switch args[0] {
+	case "validate":
+		return runConfigValidate(stdout, stderr, args[1:])
+	case "show":
+		return runConfigShow(stdout, stderr, args[1:])
+	default:
+		_, _ = fmt.Fprintf(stderr, "Error: unknown config command %q\n%s\n", args[0], configUsage)
+		return 1
+	}
+}
+
+// runConfigValidate checks the config file and reports every problem in it.
+func runConfigValidate(stdout, stderr io.Writer, args []string) int {
+	fs := flag.NewFlagSet("gitstreams config validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("config", "", "Path to JSON config file (default: $XDG_CONFIG_HOME/gitstreams/config.json)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	file, err := loadConfigFile(*path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if file == nil {
+		_, _ = fmt.Fprintln(stdout, "No config file found; using defaults.")
+		return 0
+	}
+
+	// Apply onto a scratch flag set so every key is type-checked
+	target := newFlagSet()
+	if _, err := file.Apply(target.FlagSet, target.allowed, nil); err != nil {
+		var problems config.Problems
+		if errors.As(err, &problems) {
+			for _, p := range problems {
+				_, _ = fmt.Fprintln(stderr, p.Error())
+			}
+			_, _ = fmt.Fprintf(stderr, "%d problem(s) found\n", len(problems))
+		} else {
+			_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+	if err := target.cfg.validate(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "%s: %v\n", file.Path, err)
+		return 1
+	}
+
+	_, _ = fmt.Fprintf(stdout, "%s: OK\n", file.Path)
+	return 0
+}
+
+// runConfigShow prints the effective configuration after layering
+// defaults, the config file and any flags given, with each value's source.
+func runConfigShow(stdout, stderr io.Writer, args []string) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	redact := fs.Bool("redact", false, "Hide secrets such as the GitHub token, webhook URL and SMTP password")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	sources, err := fs.resolve()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	writeSettings(stdout, fs, sources, *redact)
+	return 0
+}
+
+// writeSettings prints each setting's effective value and source.
+func writeSettings(w io.Writer, fs *flagSet, sources map[string]string, redact bool) {
+	if fs.configPath != "" {
+		_, _ = fmt.Fprintf(w, "# config file: %s\n", fs.configPath)
+	}
+	cfg := fs.cfg
+	fs.VisitAll(func(fl *flag.Flag) {
+		if !fs.allowed[fl.Name] {
+			return
+		}
+		value := fl.Value.String()
+		// Defaults filled in after parsing aren't reflected in the flag
+		switch fl.Name {
+		case "db":
+			value = cfg.DBPath
+		case "token":
+			value = cfg.Token
+			if redact && value != "" {
+				value = redactSecret(value)
+			}
+		case "smtp-password":
+			if redact && value != "" {
+				value = redactSecret(value)
+			}
+		case "webhook", "slack-webhook", "discord-webhook", "heartbeat-url":
+			// Webhook and heartbeat URLs often embed a secret
+			if redact && value != "" {
+				value = redactSecret(value)
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%s = %q  (%s)\n", fl.Name, value, sources[fl.Name])
+	})
+}
+
+// redactSecret hides all but the last four characters of a secret.
+func redactSecret(s string) string {
+	if len(s) <= 4 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseFlags_ConfigFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GITHUB_TOKEN", "")
+	path := writeConfig(t, `{"db": "/from/config.db", "no-open": true, "sync-lookback-days": 7}`)
+
+	cfg, sources, err := parseFlagsWithSources([]string{"-config", path, "-sync-lookback-days", "14"})
+	if err != nil {
+		t.Fatalf("parseFlagsWithSources() error = %v", err)
+	}
+
+	if cfg.DBPath != "/from/config.db" || !cfg.NoOpen {
+		t.Errorf("expected config file values, got db=%q no-open=%v", cfg.DBPath, cfg.NoOpen)
+	}
+	if cfg.Days != 14 {
+		t.Errorf("expected flag to override config file, got %d days", cfg.Days)
+	}
+	if sources["db"] != sourceFile || sources["sync-lookback-days"] != sourceFlag || sources["report"] != sourceDefault {
+		t.Errorf("unexpected sources: %v", sources)
+	}
+}
+
+func TestParseFlags_DefaultConfigFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".gitstreams"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".gitstreams", "config.json"), []byte(`{"no-notify": true}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := parseFlags(nil)
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	if !cfg.NoNotify {
+		t.Error("expected default config file to be loaded")
+	}
+}
+
+func TestParseFlags_ConfigFileErrors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := parseFlags([]string{"-config", filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("expected error for missing explicit config file")
+	}
+
+	path := writeConfig(t, `{"sync-lookback-days": 999}`)
+	if _, err := parseFlags([]string{"-config", path}); err == nil || !strings.Contains(err.Error(), "between 1 and 365") {
+		t.Errorf("expected range error from config value, got %v", err)
+	}
+
+	path = writeConfig(t, `{"version": true}`)
+	if _, err := parseFlags([]string{"-config", path}); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("expected version to be rejected in config file, got %v", err)
+	}
+}
+
+func TestRunConfigValidate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name       string
+		contents   string
+		wantStdout string
+		wantStderr []string
+		wantCode   int
+	}{
+		{
+			name:       "valid",
+			contents:   `{"db": "/tmp/x.db"}`,
+			wantStdout: ": OK",
+		},
+		{
+			name:     "problems",
+			contents: "{\n  \"db\": 1,\n  \"colour\": \"blue\"\n}",
+			wantStderr: []string{
+				"config.json:2: db: expected a string, got a number",
+				"config.json:3: colour: unknown key",
+				"2 problem(s) found",
+			},
+			wantCode: 1,
+		},
+		{
+			name:       "out of range",
+			contents:   `{"sync-lookback-days": 0}`,
+			wantStderr: []string{"between 1 and 365"},
+			wantCode:   1,
+		},
+		{
+			name:       "syntax error",
+			contents:   "{\n  \"db\" \"x\"\n}",
+			wantStderr: []string{"config.json:2:"},
+			wantCode:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			path := writeConfig(t, tt.contents)
+
+			code := run(&stdout, &stderr, []string{"config", "validate", "-config", path}, nil)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (stderr: %s)", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("stdout = %q, want it to contain %q", stdout.String(), tt.wantStdout)
+			}
+			for _, want := range tt.wantStderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("stderr = %q, want it to contain %q", stderr.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunConfigValidate_NoFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var stdout, stderr bytes.Buffer
+
+	if code := run(&stdout, &stderr, []string{"config", "validate"}, nil); code != 0 {
+		t.Errorf("expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "No config file found") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestRunConfigShow(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GITHUB_TOKEN", "")
+	path := writeConfig(t, `{"token": "ghp_secret1234", "no-open": true, "smtp-password": "smtp_secret5678", "slack-webhook": "https://hooks.slack.com/services/T0/B0/slack9012", "discord-webhook": "https://discord.com/api/webhooks/1/discord3456", "heartbeat-url": "https://hc-ping.com/uuid7890"}`)
+
+	var stdout, stderr bytes.Buffer
+	code := run(&stdout, &stderr, []string{"config", "show", "-redact", "-config", path, "-v"}, nil)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+
+	out := stdout.String()
+	for _, want := range []string{
+		"# config file: " + path,
+		`token = "****1234"  (config file)`,
+		`smtp-password = "****5678"  (config file)`,
+		`slack-webhook = "****9012"  (config file)`,
+		`discord-webhook = "****3456"  (config file)`,
+		`heartbeat-url = "****7890"  (config file)`,
+		`no-open = "true"  (config file)`,
+		`v = "true"  (flag)`,
+		`report = ""  (default)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "ghp_secret") {
+		t.Error("expected token to be redacted")
+	}
+	if strings.Contains(out, "smtp_secret") || strings.Contains(out, "hooks.slack.com") || strings.Contains(out, "discord.com") || strings.Contains(out, "hc-ping.com") {
+		t.Error("expected SMTP password and chat webhooks to be redacted")
+	}
+	if strings.Contains(out, "redact =") {
+		t.Error("expected -redact not to be listed as a setting")
+	}
+}
+
+func TestRunConfig_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if code := run(&stdout, &stderr, []string{"config"}, nil); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	if code := run(&stdout, &stderr, []string{"config", "frobnicate"}, nil); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "unknown config command") {
+		t.Errorf("unexpected stderr: %s", stderr.String())
+	}
+}
+
+func TestRedactSecret(t *testing.T) {
+	tests := map[string]string{
+		"abc":          "****",
+		"ghp_abcd1234": "****1234",
+	}
+	for in, want := range tests {
+		if got := redactSecret(in); got != want {
+			t.Errorf("redactSecret(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseFlags_EnvironmentLayer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GITHUB_TOKEN", "")
+	path := writeConfig(t, `{"report": "/from/config.html", "db": "/from/config.db", "no-notify": true}`)
+	t.Setenv("GITSTREAMS_CONFIG", path)
+	t.Setenv("GITSTREAMS_DB", "/from/env.db")
+	t.Setenv("GITSTREAMS_REPORT", "/from/env.html")
+	t.Setenv("GITSTREAMS_VERBOSE", "true")
+	t.Setenv("GITSTREAMS_TOKEN", "env-token")
+
+	cfg, sources, err := parseFlagsWithSources([]string{"-report", "/from/flag.html"})
+	if err != nil {
+		t.Fatalf("parseFlagsWithSources() error = %v", err)
+	}
+
+	if cfg.ReportPath != "/from/flag.html" {
+		t.Errorf("expected flag to beat environment, got %q", cfg.ReportPath)
+	}
+	if cfg.DBPath != "/from/env.db" {
+		t.Errorf("expected environment to beat config file, got %q", cfg.DBPath)
+	}
+	if !cfg.NoNotify {
+		t.Error("expected config file value to apply when nothing overrides it")
+	}
+	if !cfg.Verbose || cfg.Token != "env-token" {
+		t.Errorf("expected verbose and token from environment, got %v %q", cfg.Verbose, cfg.Token)
+	}
+	if sources["db"] != "environment (GITSTREAMS_DB)" || sources["v"] != "environment (GITSTREAMS_VERBOSE)" {
+		t.Errorf("unexpected sources: %v", sources)
+	}
+}
+
+func TestParseFlags_InvalidEnvironment(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GITSTREAMS_SYNC_LOOKBACK_DAYS", "a week")
+
+	_, err := parseFlags(nil)
+	if err == nil || !strings.Contains(err.Error(), "GITSTREAMS_SYNC_LOOKBACK_DAYS") {
+		t.Errorf("expected error naming the variable, got %v", err)
+	}
+}
+
+func TestEnvVarName(t *testing.T) {
+	tests := map[string]string{
+		"db":                 "GITSTREAMS_DB",
+		"no-notify":          "GITSTREAMS_NO_NOTIFY",
+		"sync-lookback-days": "GITSTREAMS_SYNC_LOOKBACK_DAYS",
+		"v":                  "GITSTREAMS_VERBOSE",
+	}
+	for in, want := range tests {
+		if got := envVarName(in); got != want {
+			t.Errorf("envVarName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
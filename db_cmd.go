@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+const dbUsage = `Usage:
+  gitstreams db compact [-dry-run] [flags]
+  gitstreams db rollback [-list] [backup] [flags]`
+
+// Why compact removes a snapshot.
+const (
+	garbageOtherUser = "saved for a single user by an old version"
+	garbageOldFormat = "in an old format with no activity data"
+)
+
+// compactor is implemented by stores that can remove garbage snapshots and
+// old reported-change records, and reclaim their space.
+type compactor interface {
+	Compact(garbage func(*storage.Snapshot) string, dryRun bool) (*storage.CompactResult, error)
+	PruneReported(cutoff time.Time, dryRun bool) (int64, error)
+}
+
+// runDB handles the "db" subcommand.
+func runDB(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprintln(stderr, dbUsage)
+		return 1
+	}
+
+	switch args[0] {
+	case "compact":
+		return runDBCompact(stdout, stderr, args[1:], deps)
+	case "rollback":
+		return runDBRollback(stdout, stderr, args[1:], deps)
+	default:
+		_, _ = fmt.Fprintf(stderr, "Error: unknown db command %q\n%s\n", args[0], dbUsage)
+		return 1
+	}
+}
+
+// runDBCompact removes snapshots no current code reads and records of
+// changes reported before the lookback window, vacuums the database and
+// reports the space reclaimed.
+func runDBCompact(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	dryRun := fs.Bool("dry-run", false, "Report what would be removed without changing anything")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if _, err := fs.resolve(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+
+	// Don't let the store create a database that isn't there
+	if _, err := os.Stat(dbFile(cfg.DBPath)); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: no database at %s; run gitstreams first\n", cfg.DBPath)
+		return 1
+	}
+	store, err := openStore(stderr, cfg, deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = store.Close() }()
+
+	c, ok := store.(compactor)
+	if !ok {
+		_, _ = fmt.Fprintln(stderr, "Error: this database doesn't support compaction")
+		return 1
+	}
+	// Syncs never refetch changes older than the lookback window, so there's
+	// nothing left for their records to hold back. Pruning goes first so the
+	// vacuum reclaims the space.
+	pruned, err := c.PruneReported(deps.Now().AddDate(0, 0, -cfg.Days), *dryRun)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	result, err := c.Compact(garbageSnapshot, *dryRun)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	_, _ = fmt.Fprintf(stdout, "%s %d snapshots\n", verb, result.Total())
+	reasons := make([]string, 0, len(result.Removed))
+	for reason := range result.Removed {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		_, _ = fmt.Fprintf(stdout, "  %d %s\n", result.Removed[reason], reason)
+	}
+	_, _ = fmt.Fprintf(stdout, "%s %d reported changes older than %d days\n", verb, pruned, cfg.Days)
+	if *dryRun {
+		return 0
+	}
+	_, _ = fmt.Fprintf(stdout, "Reclaimed %s (%s → %s)\n",
+		formatBytes(int(max(0, result.BytesBefore-result.BytesAfter))),
+		formatBytes(int(result.BytesBefore)), formatBytes(int(result.BytesAfter)))
+	return 0
+}
+
+// garbageSnapshot says why a snapshot is no use to this version, or returns
+// "" if it is. Snapshots from newer versions are kept for those versions.
+func garbageSnapshot(s *storage.Snapshot) string {
+	if !keptSnapshotID(s.UserID) {
+		return garbageOtherUser
+	}
+	if s.SchemaVersion == 0 {
+		if _, ok := s.Activity[activityDataKey]; !ok {
+			return garbageOldFormat
+		}
+	}
+	return ""
+}
+
+// keptSnapshotID reports whether id is one runs or team profiles save
+// snapshots under.
+func keptSnapshotID(id string) bool {
+	for _, prefix := range []string{profileSnapshotPrefix, orgSnapshotPrefix, userSnapshotPrefix} {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return id == snapshotUserID
+}
+
+// openStore opens the database, first backing it up if this version is
+// about to upgrade its schema, unless -no-backup is set. Notes about the
+// backup go to w.
+func openStore(w io.Writer, cfg *Config, deps *Dependencies) (Store, error) {
+	if !cfg.NoBackup {
+		path, err := backupBeforeMigrate(cfg.DBPath, deps.Now)
+		if err != nil {
+			return nil, fmt.Errorf("backing up before upgrading the schema (pass -no-backup to skip): %w", err)
+		}
+		if path != "" {
+			_, _ = fmt.Fprintf(w, "Backed up the database to %s before upgrading it; undo with gitstreams db rollback\n", path)
+		}
+	}
+	store, err := deps.StoreFactory(cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	useClock(store, deps.Now)
+	return store, nil
+}
+
+// backupBeforeMigrate backs up a SQLite database whose schema is out of
+// date, returning the backup's path, or "" if none was needed.
+func backupBeforeMigrate(dsn string, now func() time.Time) (string, error) {
+	backend, path := storage.ParseDSN(dsn)
+	if backend != storage.BackendSQLite {
+		return "", nil
+	}
+	needed, err := storage.NeedsMigration(path)
+	if err != nil || !needed {
+		return "", err
+	}
+	return storage.Backup(path, now())
+}
+
+// runDBRollback replaces the database with one of its backups, the newest
+// by default. The database it replaces is kept alongside.
+func runDBRollback(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	list := fs.Bool("list", false, "List the database's backups, newest first")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if _, err := fs.resolve(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+
+	backend, path := storage.ParseDSN(cfg.DBPath)
+	if backend != storage.BackendSQLite {
+		_, _ = fmt.Fprintf(stderr, "Error: rollback needs a SQLite database, not %s\n", backend)
+		return 1
+	}
+	backups, err := storage.Backups(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *list {
+		for _, b := range backups {
+			_, _ = fmt.Fprintln(stdout, b)
+		}
+		return 0
+	}
+
+	var backup string
+	switch {
+	case fs.NArg() > 1:
+		_, _ = fmt.Fprintln(stderr, dbUsage)
+		return 1
+	case fs.NArg() == 1:
+		backup = fs.Arg(0)
+	case len(backups) == 0:
+		_, _ = fmt.Fprintf(stderr, "Error: no backups of %s\n", path)
+		return 1
+	default:
+		backup = backups[0]
+	}
+	if _, err := os.Stat(backup); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	replaced := ""
+	if _, err := os.Stat(path); err == nil {
+		replaced = path + ".rolled-back-" + deps.Now().UTC().Format("20060102-150405")
+		if err := os.Rename(path, replaced); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if err := copyFile(backup, path); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error restoring %s: %v\n", backup, err)
+		if replaced != "" {
+			_ = os.Rename(replaced, path)
+		}
+		return 1
+	}
+	_, _ = fmt.Fprintf(stdout, "Restored %s from %s\n", path, backup)
+	if replaced != "" {
+		_, _ = fmt.Fprintf(stdout, "The database it replaced is at %s\n", replaced)
+	}
+	_, _ = fmt.Fprintln(stdout, "Run the gitstreams version that wrote the backup; this one would upgrade it again.")
+	return 0
+}
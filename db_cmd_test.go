@@ -0,0 +1,264 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestRunDBCompact(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := storage.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := fixedTime()
+	if _, err := saveSnapshotAs(store, runSnapshotID(&Config{}), diff.NewSnapshot(now), now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := saveSnapshotAs(store, profileSnapshotPrefix+"team", diff.NewSnapshot(now), now); err != nil {
+		t.Fatal(err)
+	}
+	padding := strings.Repeat("x", 64*1024)
+	legacy := []*storage.Snapshot{
+		{UserID: "octocat", Timestamp: now, Activity: map[string]interface{}{"padding": padding}},
+		{UserID: "hubot", Timestamp: now, Activity: map[string]interface{}{"padding": padding}},
+		{UserID: snapshotUserID, Timestamp: now, Activity: map[string]interface{}{"commits": float64(3)}},
+	}
+	if err := store.SaveAll(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkReported([]string{"stale"}, now.AddDate(0, 0, -31)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkReported([]string{"recent"}, now.AddDate(0, 0, -29)); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		Now:          fixedTime,
+	}
+
+	var stdout, stderr strings.Builder
+	if code := run(&stdout, &stderr, []string{"db", "compact", "-dry-run", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	for _, want := range []string{"Would remove 3 snapshots", "2 " + garbageOtherUser, "1 " + garbageOldFormat, "Would remove 1 reported changes older than 30 days"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("dry run output missing %q:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"db", "compact", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Removed 3 snapshots") || !strings.Contains(stdout.String(), "Reclaimed ") {
+		t.Errorf("unexpected output:\n%s", stdout.String())
+	}
+
+	store, err = storage.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	for userID, want := range map[string]int{snapshotUserID: 1, profileSnapshotPrefix + "team": 1, "octocat": 0} {
+		if snapshots, _ := store.GetByUser(userID, 10); len(snapshots) != want {
+			t.Errorf("%s has %d snapshots after compacting, want %d", userID, len(snapshots), want)
+		}
+	}
+	if reported, _ := store.Reported([]string{"stale", "recent"}); reported["stale"] || !reported["recent"] {
+		t.Errorf("reported changes after compacting = %v, want only recent", reported)
+	}
+}
+
+func TestRunDBCompact_Errors(t *testing.T) {
+	setHome(t)
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.Open(path) },
+		Now:          fixedTime,
+	}
+	boltPath := filepath.Join(t.TempDir(), "test.bolt")
+	store, err := storage.NewBoltStore(boltPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	missing := filepath.Join(t.TempDir(), "missing.db")
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"db"}, "Usage"},
+		{[]string{"db", "shrink"}, "unknown db command"},
+		{[]string{"db", "compact", "-db", missing}, "no database"},
+		{[]string{"db", "compact", "-db", "bolt://" + boltPath}, "doesn't support compaction"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr strings.Builder
+		if code := run(&stdout, &stderr, tt.args, deps); code != 1 || !strings.Contains(stderr.String(), tt.wantErr) {
+			t.Errorf("%v: code %d, stderr %q; want %q", tt.args, code, stderr.String(), tt.wantErr)
+		}
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Error("compact created a database")
+	}
+}
+
+func TestGarbageSnapshot(t *testing.T) {
+	tests := []struct {
+		snapshot *storage.Snapshot
+		want     string
+	}{
+		{&storage.Snapshot{UserID: snapshotUserID, SchemaVersion: snapshotSchemaVersion}, ""},
+		{&storage.Snapshot{UserID: profileSnapshotPrefix + "team", SchemaVersion: snapshotSchemaVersion}, ""},
+		{&storage.Snapshot{UserID: orgSnapshotPrefix + "acme", SchemaVersion: snapshotSchemaVersion}, ""},
+		{&storage.Snapshot{UserID: userSnapshotPrefix + "octocat", SchemaVersion: snapshotSchemaVersion}, ""},
+		{&storage.Snapshot{UserID: snapshotUserID, Activity: map[string]interface{}{activityDataKey: map[string]interface{}{}}}, ""},
+		{&storage.Snapshot{UserID: snapshotUserID, SchemaVersion: snapshotSchemaVersion + 1}, ""},
+		{&storage.Snapshot{UserID: snapshotUserID, Activity: map[string]interface{}{"commits": float64(1)}}, garbageOldFormat},
+		{&storage.Snapshot{UserID: "octocat", SchemaVersion: snapshotSchemaVersion}, garbageOtherUser},
+	}
+	for _, tt := range tests {
+		if got := garbageSnapshot(tt.snapshot); got != tt.want {
+			t.Errorf("garbageSnapshot(%+v) = %q, want %q", tt.snapshot, got, tt.want)
+		}
+	}
+}
+
+// writeUnversionedDB creates a database in the original schema, which this
+// version upgrades on open.
+func writeUnversionedDB(t *testing.T, dbPath string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	if _, err := db.Exec(`CREATE TABLE snapshots (id INTEGER PRIMARY KEY, user_id TEXT NOT NULL, timestamp DATETIME NOT NULL, activity_json TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenStore_BacksUpBeforeMigrating(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	writeUnversionedDB(t, dbPath)
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.Open(path) },
+		Now:          fixedTime,
+	}
+
+	var stderr strings.Builder
+	store, err := openStore(&stderr, &Config{DBPath: dbPath, NoBackup: true}, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	if backups, _ := storage.Backups(dbPath); len(backups) != 0 {
+		t.Errorf("expected no backup with NoBackup, got %v", backups)
+	}
+
+	dbPath = filepath.Join(t.TempDir(), "test.db")
+	writeUnversionedDB(t, dbPath)
+	store, err = openStore(&stderr, &Config{DBPath: dbPath}, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	backups, _ := storage.Backups(dbPath)
+	if len(backups) != 1 || !strings.Contains(stderr.String(), "Backed up the database to "+backups[0]) {
+		t.Fatalf("expected one backup and a note about it, got %v and %q", backups, stderr.String())
+	}
+
+	// Once upgraded, opening doesn't back up again
+	stderr.Reset()
+	store, err = openStore(&stderr, &Config{DBPath: dbPath}, &Dependencies{StoreFactory: deps.StoreFactory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	if stderr.Len() != 0 {
+		t.Errorf("expected no backup of an up-to-date database, got %q", stderr.String())
+	}
+}
+
+func TestRunDBRollback(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	writeUnversionedDB(t, dbPath)
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.Open(path) },
+		Now:          fixedTime,
+	}
+
+	var stdout, stderr strings.Builder
+	if code := run(&stdout, &stderr, []string{"db", "rollback", "-db", dbPath}, deps); code != 1 || !strings.Contains(stderr.String(), "no backups") {
+		t.Errorf("rollback without backups: code %d, stderr %q", code, stderr.String())
+	}
+
+	// Upgrading makes the backup
+	store, err := openStore(&stderr, &Config{DBPath: dbPath}, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	if needed, _ := storage.NeedsMigration(dbPath); needed {
+		t.Fatal("expected the database to be upgraded")
+	}
+
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"db", "rollback", "-list", "-db", dbPath}, deps); code != 0 || !strings.Contains(stdout.String(), dbPath+".backup-") {
+		t.Errorf("rollback -list: code %d, stdout %q", code, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"db", "rollback", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Restored "+dbPath) {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+	if needed, _ := storage.NeedsMigration(dbPath); !needed {
+		t.Error("expected the old schema back after rolling back")
+	}
+	if _, err := os.Stat(dbPath + ".rolled-back-20240115-100000"); err != nil {
+		t.Errorf("expected the replaced database to be kept: %v", err)
+	}
+
+	if code := run(&stdout, &stderr, []string{"db", "rollback", "-db", "bolt://" + dbPath}, deps); code != 1 || !strings.Contains(stderr.String(), "needs a SQLite database") {
+		t.Errorf("rollback of bolt: code %d, stderr %q", code, stderr.String())
+	}
+}
+
+func TestOpenStoreUsesRunClock(t *testing.T) {
+	setHome(t)
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewMemoryStore(), nil },
+		Now:          fixedTime,
+	}
+
+	var stderr strings.Builder
+	store, err := openStore(&stderr, &Config{DBPath: filepath.Join(t.TempDir(), "test.db")}, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	snapshot := &storage.Snapshot{UserID: "alice"}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.Timestamp.Equal(fixedTime()) {
+		t.Errorf("snapshot stamped %v, want the run's clock %v", snapshot.Timestamp, fixedTime())
+	}
+}
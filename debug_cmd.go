@@ -0,0 +1,288 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+const debugUsage = `Usage:
+  gitstreams debug bundle [-o path] [-log-lines n] [flags]`
+
+// debugSampleUsers caps how many users the anonymized snapshot sample holds.
+const debugSampleUsers = 10
+
+// runDebug handles the "debug" subcommand.
+func runDebug(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprintln(stderr, debugUsage)
+		return 1
+	}
+
+	switch args[0] {
+	case "bundle":
+		return runDebugBundle(stdout, stderr, args[1:], deps)
+	default:
+		_, _ = fmt.Fprintf(stderr, "Error: unknown debug command %q\n%s\n", args[0], debugUsage)
+		return 1
+	}
+}
+
+// runDebugBundle writes a zip of diagnostics to attach to a bug report:
+// version, redacted settings, database schema details, recent log lines and
+// an anonymized sample of the latest snapshot. A part that can't be
+// collected is replaced by a note saying why, so a broken install still
+// yields a bundle.
+func runDebugBundle(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	out := fs.String("o", "", "Where to write the bundle (default: gitstreams-debug-<time>.zip)")
+	logLines := fs.Int("log-lines", 200, "How many recent log lines to include")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	sources, err := fs.resolve()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+
+	path := *out
+	if path == "" {
+		path = "gitstreams-debug-" + deps.Now().Format("20060102-150405") + ".zip"
+	}
+
+	var settings bytes.Buffer
+	writeSettings(&settings, fs, sources, true)
+
+	storageInfo, sample := debugStorage(cfg, deps)
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"version.txt", []byte(debugVersion())},
+		{"config.txt", settings.Bytes()},
+		{"storage.txt", []byte(storageInfo)},
+		{"log.txt", []byte(debugLog(cfg, *logLines))},
+		{"snapshot-sample.json", sample},
+	}
+
+	f, err := os.Create(path) // #nosec G304 -- path is user-specified via flag or a safe default
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error creating bundle: %v\n", err)
+		return 1
+	}
+	zw := zip.NewWriter(f)
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err == nil {
+			_, err = w.Write(file.data)
+		}
+		if err != nil {
+			_ = f.Close()
+			_, _ = fmt.Fprintf(stderr, "Error writing bundle: %v\n", err)
+			return 1
+		}
+	}
+	if err := zw.Close(); err != nil {
+		_ = f.Close()
+		_, _ = fmt.Fprintf(stderr, "Error writing bundle: %v\n", err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error writing bundle: %v\n", err)
+		return 1
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Debug bundle written to %s\n", path)
+	_, _ = fmt.Fprintln(stdout, "Tokens, webhook URLs and passwords are redacted and names in the snapshot sample are replaced; review it before attaching it to a bug report.")
+	return 0
+}
+
+func debugVersion() string {
+	return fmt.Sprintf("gitstreams %s (commit: %s, built: %s)\n%s %s/%s\n",
+		version, commit, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// debugLog returns the last n lines of the run log.
+func debugLog(cfg *Config, n int) string {
+	dir, err := dataDir(cfg.LegacyDirs)
+	if err != nil {
+		return fmt.Sprintf("unavailable: %v\n", err)
+	}
+	lines, err := tailLines(filepath.Join(dir, runLogName), n)
+	if err != nil {
+		return fmt.Sprintf("unavailable: %v\n", err)
+	}
+	if len(lines) == 0 {
+		return "no log entries\n"
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// debugStorage describes the database and returns an anonymized sample of
+// its latest snapshot.
+func debugStorage(cfg *Config, deps *Dependencies) (string, []byte) {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "database: %s\n", cfg.DBPath)
+	_, _ = fmt.Fprintf(&b, "supported snapshot schema version: %d\n", snapshotSchemaVersion)
+
+	// Don't let the store create a database that isn't there
+	info, err := os.Stat(dbFile(cfg.DBPath))
+	if err != nil {
+		_, _ = fmt.Fprintf(&b, "unavailable: %v\n", err)
+		return b.String(), nil
+	}
+	_, _ = fmt.Fprintf(&b, "size: %s\n", formatBytes(int(info.Size())))
+
+	store, err := openForDebug(cfg, deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(&b, "open failed: %v\n", err)
+		return b.String(), nil
+	}
+	defer func() { _ = store.Close() }()
+	if sqlite, ok := store.(*storage.SQLiteStore); ok {
+		if version, err := sqlite.SchemaVersion(); err != nil {
+			_, _ = fmt.Fprintf(&b, "database schema version unreadable: %v\n", err)
+		} else {
+			_, _ = fmt.Fprintf(&b, "database schema version: %d\n", version)
+		}
+	}
+
+	snapshots, err := store.GetByUser(runSnapshotID(cfg), 5)
+	if err != nil {
+		_, _ = fmt.Fprintf(&b, "query failed: %v\n", err)
+		return b.String(), nil
+	}
+	if len(snapshots) == 0 {
+		b.WriteString("no snapshots\n")
+		return b.String(), nil
+	}
+
+	b.WriteString("recent snapshots:\n")
+	for _, ss := range snapshots {
+		_, _ = fmt.Fprintf(&b, "  %s  schema version %d  %s",
+			ss.Timestamp.Format("2006-01-02 15:04:05"), ss.SchemaVersion, formatBytes(len(ss.Data)))
+		if ss.AppVersion != "" {
+			_, _ = fmt.Fprintf(&b, "  written by %s", ss.AppVersion)
+		}
+		if ss.Partial {
+			b.WriteString("  partial")
+		}
+		b.WriteString("\n")
+	}
+
+	latest, err := storageToSnapshot(snapshots[0])
+	if err != nil {
+		_, _ = fmt.Fprintf(&b, "latest snapshot unreadable: %v\n", err)
+		return b.String(), nil
+	}
+	sample, err := json.MarshalIndent(anonymizeSnapshot(latest, debugSampleUsers), "", "  ")
+	if err != nil {
+		_, _ = fmt.Fprintf(&b, "sample failed: %v\n", err)
+		return b.String(), nil
+	}
+	return b.String(), sample
+}
+
+// openForDebug opens the database without changing it: a SQLite database
+// read-only and unmigrated, so the bundle shows it as it is on disk, and
+// any other without the backup openStore makes first.
+func openForDebug(cfg *Config, deps *Dependencies) (Store, error) {
+	if backend, path := storage.ParseDSN(cfg.DBPath); backend == storage.BackendSQLite {
+		return storage.OpenSQLiteReadOnly(path)
+	}
+	return deps.StoreFactory(cfg.DBPath)
+}
+
+// anonymizeSnapshot returns a copy of up to maxUsers users from s with
+// every user and repository name replaced by a stable placeholder (user-1,
+// repo-1, ...) and descriptions dropped. Who starred what, event types and
+// timestamps are kept, since they're what most bugs depend on.
+func anonymizeSnapshot(s *diff.Snapshot, maxUsers int) *diff.Snapshot {
+	names := make([]string, 0, len(s.Users))
+	for name := range s.Users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > maxUsers {
+		names = names[:maxUsers]
+	}
+
+	users := newPseudonyms("user")
+	repos := newPseudonyms("repo")
+	anonRepo := func(r diff.Repo) diff.Repo {
+		return diff.Repo{
+			CreatedAt: r.CreatedAt,
+			Owner:     users.get(r.Owner),
+			Name:      repos.get(r.FullName()),
+			Language:  r.Language,
+			Stars:     r.Stars,
+		}
+	}
+	anonFullName := func(fullName string) string {
+		owner, _, _ := strings.Cut(fullName, "/")
+		return users.get(owner) + "/" + repos.get(fullName)
+	}
+
+	out := diff.NewSnapshot(s.CapturedAt)
+	for _, name := range names {
+		ua := s.Users[name]
+		anon := diff.UserActivity{Username: users.get(name)}
+		for _, r := range ua.StarredRepos {
+			anon.StarredRepos = append(anon.StarredRepos, anonRepo(r))
+		}
+		for _, r := range ua.OwnedRepos {
+			anon.OwnedRepos = append(anon.OwnedRepos, anonRepo(r))
+		}
+		for _, e := range ua.Events {
+			anon.Events = append(anon.Events, diff.Event{
+				CreatedAt: e.CreatedAt,
+				Type:      e.Type,
+				Actor:     users.get(e.Actor),
+				Repo:      anonFullName(e.Repo),
+			})
+		}
+		out.Users[anon.Username] = anon
+		for _, endpoint := range s.Incomplete[name] {
+			out.MarkIncomplete(anon.Username, endpoint)
+		}
+	}
+	return out
+}
+
+// pseudonyms hands out a stable placeholder per distinct name.
+type pseudonyms struct {
+	names  map[string]string
+	prefix string
+}
+
+func newPseudonyms(prefix string) *pseudonyms {
+	return &pseudonyms{names: make(map[string]string), prefix: prefix}
+}
+
+func (p *pseudonyms) get(name string) string {
+	if name == "" {
+		return ""
+	}
+	if alias, ok := p.names[name]; ok {
+		return alias
+	}
+	alias := p.prefix + "-" + strconv.Itoa(len(p.names)+1)
+	p.names[name] = alias
+	return alias
+}
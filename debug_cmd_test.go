@@ -0,0 +1,195 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestRunDebugBundle(t *testing.T) {
+	home := setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	store, err := storage.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := diff.NewSnapshot(fixedTime())
+	snap.Users["alice"] = diff.UserActivity{
+		Username:     "alice",
+		StarredRepos: []diff.Repo{{Owner: "bob", Name: "secret-project", Description: "private plans", Stars: 3}},
+		Events:       []diff.Event{{Type: "PushEvent", Actor: "alice", Repo: "bob/secret-project", CreatedAt: fixedTime()}},
+	}
+	if _, err := saveSnapshotAs(store, runSnapshotID(&Config{}), snap, fixedTime()); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	writeFile(t, filepath.Join(home, ".local", "share", "gitstreams", runLogName), "line 1\nline 2\nline 3\n")
+
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		Now:          fixedTime,
+	}
+	out := filepath.Join(t.TempDir(), "bundle.zip")
+	var stdout, stderr strings.Builder
+	code := run(&stdout, &stderr, []string{"debug", "bundle", "-o", out, "-log-lines", "2",
+		"-db", dbPath, "-token", "ghp_supersecret1234", "-webhook", "https://hooks.example.com/T0/abcd"}, deps)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), out) {
+		t.Errorf("expected bundle path in output, got %q", stdout.String())
+	}
+
+	files := readZip(t, out)
+
+	if !strings.Contains(files["version.txt"], "gitstreams "+version) {
+		t.Errorf("unexpected version.txt: %q", files["version.txt"])
+	}
+	cfgText := files["config.txt"]
+	if strings.Contains(cfgText, "supersecret") || strings.Contains(cfgText, "hooks.example.com") {
+		t.Errorf("config.txt leaks secrets: %q", cfgText)
+	}
+	if !strings.Contains(cfgText, `token = "****1234"`) {
+		t.Errorf("expected redacted token in config.txt: %q", cfgText)
+	}
+	if !strings.Contains(files["storage.txt"], "schema version 1") {
+		t.Errorf("expected schema version in storage.txt: %q", files["storage.txt"])
+	}
+	if files["log.txt"] != "line 2\nline 3\n" {
+		t.Errorf("expected last 2 log lines, got %q", files["log.txt"])
+	}
+
+	sample := files["snapshot-sample.json"]
+	for _, leaked := range []string{"alice", "bob", "secret-project", "private plans"} {
+		if strings.Contains(sample, leaked) {
+			t.Errorf("snapshot sample leaks %q: %s", leaked, sample)
+		}
+	}
+	var restored diff.Snapshot
+	if err := json.Unmarshal([]byte(sample), &restored); err != nil {
+		t.Fatalf("snapshot sample isn't valid JSON: %v", err)
+	}
+	if len(restored.Users) != 1 {
+		t.Errorf("expected 1 user in sample, got %d", len(restored.Users))
+	}
+}
+
+func TestRunDebugBundle_NoDatabase(t *testing.T) {
+	setHome(t)
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) {
+			t.Error("store shouldn't be opened when the database doesn't exist")
+			return nil, nil
+		},
+		Now: fixedTime,
+	}
+	out := filepath.Join(t.TempDir(), "bundle.zip")
+	var stdout, stderr strings.Builder
+	if code := run(&stdout, &stderr, []string{"debug", "bundle", "-o", out, "-db", filepath.Join(t.TempDir(), "missing.db")}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+
+	files := readZip(t, out)
+	if !strings.Contains(files["storage.txt"], "unavailable") {
+		t.Errorf("expected storage.txt to explain the missing database: %q", files["storage.txt"])
+	}
+	if files["log.txt"] != "no log entries\n" {
+		t.Errorf("unexpected log.txt: %q", files["log.txt"])
+	}
+}
+
+func TestRunDebugBundle_LeavesDatabaseAsIs(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	writeUnversionedDB(t, dbPath)
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.Open(path) },
+		Now:          fixedTime,
+	}
+	out := filepath.Join(t.TempDir(), "bundle.zip")
+	var stdout, stderr strings.Builder
+	if code := run(&stdout, &stderr, []string{"debug", "bundle", "-o", out, "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+
+	if files := readZip(t, out); !strings.Contains(files["storage.txt"], "database schema version: 0") {
+		t.Errorf("expected the unmigrated schema version in storage.txt: %q", files["storage.txt"])
+	}
+	if backups, _ := storage.Backups(dbPath); len(backups) != 0 {
+		t.Errorf("debug backed up the database: %v", backups)
+	}
+	if needed, err := storage.NeedsMigration(dbPath); err != nil || !needed {
+		t.Errorf("NeedsMigration after debug = %v, %v; want the database still unmigrated", needed, err)
+	}
+}
+
+func TestRunDebug_Usage(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := run(&stdout, &stderr, []string{"debug"}, &Dependencies{}); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	if code := run(&stdout, &stderr, []string{"debug", "nope"}, &Dependencies{}); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "gitstreams debug bundle") {
+		t.Errorf("expected usage, got %q", stderr.String())
+	}
+}
+
+func TestAnonymizeSnapshot(t *testing.T) {
+	s := diff.NewSnapshot(fixedTime())
+	s.Users["alice"] = diff.UserActivity{
+		Username:     "alice",
+		StarredRepos: []diff.Repo{{Owner: "bob", Name: "tool"}},
+	}
+	s.Users["bob"] = diff.UserActivity{
+		Username:   "bob",
+		OwnedRepos: []diff.Repo{{Owner: "bob", Name: "tool"}},
+	}
+	s.Users["carol"] = diff.UserActivity{Username: "carol"}
+
+	anon := anonymizeSnapshot(s, 2)
+	if len(anon.Users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(anon.Users))
+	}
+
+	// The same repo gets the same placeholder everywhere, and its owner the
+	// same placeholder as the user
+	starred := anon.Users["user-1"].StarredRepos[0]
+	owned := anon.Users[starred.Owner].OwnedRepos[0]
+	if starred.FullName() != owned.FullName() {
+		t.Errorf("expected consistent placeholders, got %s and %s", starred.FullName(), owned.FullName())
+	}
+}
+
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("opening bundle: %v", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	return files
+}
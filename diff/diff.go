@@ -1,15 +1,21 @@
 // Package diff compares GitHub activity snapshots to detect changes.
 package diff
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // Repo represents a GitHub repository.
 type Repo struct {
 	CreatedAt   time.Time
+	ID          string `json:",omitempty"` // The forge's ID for the repo, which survives renames
 	Owner       string
 	Name        string
 	Description string
 	Language    string
+	HTMLURL     string   `json:",omitempty"` // The repo's web page, as the API gives it
+	Topics      []string `json:",omitempty"`
 	Stars       int
 }
 
@@ -20,24 +26,72 @@ func (r Repo) FullName() string {
 
 // Event represents a GitHub activity event.
 type Event struct {
-	CreatedAt time.Time
-	Type      string // e.g., "PushEvent", "CreateEvent", "ForkEvent"
-	Actor     string // username who performed the event
-	Repo      string // full repo name
+	CreatedAt      time.Time
+	ID             string   `json:",omitempty"` // The forge's ID for the event, if it has one
+	Type           string   // e.g., "PushEvent", "CreateEvent", "ForkEvent"
+	Actor          string   // username who performed the event
+	ActorAvatarURL string   `json:",omitempty"` // The actor's avatar, as the API gives it
+	Repo           string   // full repo name
+	Member         string   `json:",omitempty"` // MemberEvent: the collaborator added
+	RefType        string   `json:",omitempty"` // CreateEvent, DeleteEvent: "repository", "branch" or "tag"; ReleaseEvent: "tag"
+	Ref            string   `json:",omitempty"` // CreateEvent, DeleteEvent, ReleaseEvent: the branch or tag's name
+	Commits        []string `json:",omitempty"` // PushEvent: the first line of each commit's message, oldest first
+	Size           int      `json:",omitempty"` // PushEvent: how many commits it carried
+	Before         string   `json:",omitempty"` // PushEvent: the commit the ref pointed to before the push
+	Head           string   `json:",omitempty"` // PushEvent: the commit it points to after
+	Forced         bool     `json:",omitempty"` // PushEvent: the push rewrote its branch's history, going by the pushes before it
+}
+
+// Profile is a user's public profile.
+type Profile struct {
+	Name      string `json:",omitempty"` // Display name
+	Bio       string `json:",omitempty"`
+	AvatarURL string `json:",omitempty"`
+	Company   string `json:",omitempty"`
 }
 
 // UserActivity represents a single user's GitHub activity at a point in time.
 type UserActivity struct {
+	Profile      *Profile `json:",omitempty"` // Nil if it wasn't fetched
 	Username     string
 	StarredRepos []Repo
 	OwnedRepos   []Repo
 	Events       []Event
+	Sources      []string // Accounts the user is followed from, when several are aggregated
+	// Following is the logins the user follows, sorted: nil if it wasn't
+	// fetched, and empty if they follow no one. It isn't omitted when
+	// empty, so the two survive being saved.
+	Following []string
 }
 
+// The parts of a user's activity a snapshot can be missing, as named in
+// Snapshot.Incomplete.
+const (
+	PartStarred     = "starred"
+	PartOwned       = "owned"
+	PartEvents      = "events"
+	PartDiscussions = "discussions"
+	PartProfile     = "profile"
+	PartFollowing   = "following"
+)
+
 // Snapshot represents the state of all followed users' activity at a point in time.
 type Snapshot struct {
 	CapturedAt time.Time
 	Users      map[string]UserActivity // keyed by username
+	// Incomplete lists the parts of each user's activity, the Part
+	// constants, that couldn't be fetched for the snapshot.
+	Incomplete map[string][]string `json:",omitempty"`
+	// Teams lists each team's members by team name, when the users are an
+	// organization's members rather than someone's follows.
+	Teams map[string][]string `json:",omitempty"`
+	// Starred is the authenticated user's own starred repos, or nil if
+	// they weren't fetched. It's kept apart from Users since the user
+	// doesn't follow themselves.
+	Starred []Repo
+	// Watched is the watchlist's repos, keyed by owner/name, or nil if
+	// there's no watchlist.
+	Watched map[string]WatchedRepo `json:",omitempty"`
 }
 
 // NewSnapshot creates an empty snapshot with the given timestamp.
@@ -48,12 +102,54 @@ func NewSnapshot(capturedAt time.Time) *Snapshot {
 	}
 }
 
+// MarkIncomplete records that part of username's activity, one of the Part
+// constants, couldn't be fetched.
+func (s *Snapshot) MarkIncomplete(username, part string) {
+	if slices.Contains(s.Incomplete[username], part) {
+		return
+	}
+	if s.Incomplete == nil {
+		s.Incomplete = make(map[string][]string)
+	}
+	s.Incomplete[username] = append(s.Incomplete[username], part)
+}
+
+// Partial reports whether some of the snapshot's activity couldn't be
+// fetched.
+func (s *Snapshot) Partial() bool {
+	return len(s.Incomplete) > 0
+}
+
+// missing returns the parts of username's activity s lacks: those marked
+// incomplete, or all of them for an incomplete user it doesn't have at all.
+func (s *Snapshot) missing(username string) map[string]bool {
+	parts := s.Incomplete[username]
+	if parts == nil {
+		return nil
+	}
+	if _, ok := s.Users[username]; !ok {
+		parts = []string{PartStarred, PartOwned, PartEvents, PartDiscussions, PartProfile, PartFollowing}
+	}
+	missing := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		missing[part] = true
+	}
+	return missing
+}
+
 // RepoChange represents a change in starred or owned repos.
 type RepoChange struct {
 	Username string
 	Repo     Repo
 }
 
+// ProfileChange is a user's profile changing between snapshots.
+type ProfileChange struct {
+	Username string
+	Old      Profile
+	New      Profile
+}
+
 // EventChange represents new events detected.
 type EventChange struct {
 	Event    Event
@@ -79,67 +175,118 @@ type Result struct {
 
 	// Gone users: users that were in old snapshot but not new
 	GoneUsers []string
+
+	// Profile changes: users whose profile differs from the old
+	// snapshot's. Users without a profile in either snapshot are left out.
+	ProfileChanges []ProfileChange
+
+	// New follows: who followed users started following. There are only
+	// any for users both snapshots have a following list for.
+	NewFollows []FollowChange
+
+	// Repos the authenticated user starred themselves. There are only any
+	// when both snapshots have the user's starred list, so the first
+	// snapshot with one doesn't make every star new.
+	YouStarred []Repo
+
+	// What changed on watched repos: new releases and pushes, and stars
+	// gained. Repos only one of the snapshots watches are left out.
+	Watched []WatchChange
 }
 
-// IsEmpty returns true if no changes were detected.
+// IsEmpty returns true if no changes were detected. Profile changes don't
+// count: they're shown alongside activity, not reported on their own.
 func (r *Result) IsEmpty() bool {
 	return len(r.NewStars) == 0 &&
 		len(r.NewRepos) == 0 &&
 		len(r.NewEvents) == 0 &&
 		len(r.NewUsers) == 0 &&
-		len(r.GoneUsers) == 0
+		len(r.GoneUsers) == 0 &&
+		len(r.NewFollows) == 0 &&
+		len(r.YouStarred) == 0 &&
+		len(r.Watched) == 0
 }
 
 // Compare compares two snapshots and returns the detected changes.
 // The old snapshot represents the previous state, new represents current state.
+// It's CompareWithOptions with the zero Options.
+//
+// Where the old snapshot is missing part of a user's activity (see
+// Snapshot.Incomplete), there's nothing to tell new activity from old by.
+// So that a failed fetch doesn't make everything it would have returned new
+// the next time round, only repos created and events that happened since
+// the old snapshot was taken are new there, and stars, which can't be
+// dated, never are.
 func Compare(old, new *Snapshot) *Result {
+	return CompareIndexed(old, NewIndex(old), new)
+}
+
+// CompareIndexed is like Compare but looks up old activity through a
+// precomputed index of the old snapshot. Callers that diff several snapshots
+// against the same baseline can build the index once with NewIndex.
+func CompareIndexed(old *Snapshot, oldIndex *Index, new *Snapshot) *Result {
+	return compareIndexed(old, oldIndex, new, false)
+}
+
+// compareIndexed is CompareIndexed, also matching repos and events by ID
+// if byID is set.
+func compareIndexed(old *Snapshot, oldIndex *Index, new *Snapshot, byID bool) *Result {
 	result := &Result{
 		OldCapturedAt: old.CapturedAt,
 		NewCapturedAt: new.CapturedAt,
 	}
 
-	// Find new and gone users
+	// Find new and gone users. Users missing because their fetch failed
+	// are neither: they're still followed.
 	for username := range new.Users {
-		if _, exists := old.Users[username]; !exists {
+		if _, exists := old.Users[username]; !exists && old.Incomplete[username] == nil {
 			result.NewUsers = append(result.NewUsers, username)
 		}
 	}
 	for username := range old.Users {
-		if _, exists := new.Users[username]; !exists {
+		if _, exists := new.Users[username]; !exists && new.Incomplete[username] == nil {
 			result.GoneUsers = append(result.GoneUsers, username)
 		}
 	}
 
-	// Compare activity for users present in both snapshots
-	for username, newActivity := range new.Users {
-		oldActivity, exists := old.Users[username]
-		if !exists {
-			// New user - all their activity is "new"
-			for _, repo := range newActivity.StarredRepos {
-				result.NewStars = append(result.NewStars, RepoChange{
-					Username: username,
-					Repo:     repo,
-				})
-			}
-			for _, repo := range newActivity.OwnedRepos {
-				result.NewRepos = append(result.NewRepos, RepoChange{
-					Username: username,
-					Repo:     repo,
-				})
+	if old.Starred != nil {
+		stars, starIDs := repoSet(old.Starred), repoIDSet(old.Starred)
+		for _, repo := range new.Starred {
+			if _, ok := stars[repoKey{owner: repo.Owner, name: repo.Name}]; ok {
+				continue
 			}
-			for _, event := range newActivity.Events {
-				result.NewEvents = append(result.NewEvents, EventChange{
-					Username: username,
-					Event:    event,
-				})
+			if _, ok := starIDs[repo.ID]; ok && byID {
+				continue
 			}
-			continue
+			result.YouStarred = append(result.YouStarred, repo)
+		}
+	}
+
+	result.Watched = compareWatched(old, new, byID)
+
+	// Compare activity for users present in both snapshots. A new user has
+	// no index entry, so all their activity is "new".
+	for username, newActivity := range new.Users {
+		idx := oldIndex.users[username]
+		missing := old.missing(username)
+		eventsMissing := missing[PartEvents] || missing[PartDiscussions]
+		sinceOld := func(at time.Time) bool { return at.After(old.CapturedAt) }
+
+		if oldActivity, ok := old.Users[username]; ok && oldActivity.Profile != nil && newActivity.Profile != nil && *oldActivity.Profile != *newActivity.Profile {
+			result.ProfileChanges = append(result.ProfileChanges, ProfileChange{
+				Username: username,
+				Old:      *oldActivity.Profile,
+				New:      *newActivity.Profile,
+			})
+		}
+
+		if oldActivity, ok := old.Users[username]; ok && !missing[PartFollowing] {
+			result.NewFollows = append(result.NewFollows, newFollows(username, oldActivity.Following, newActivity.Following)...)
 		}
 
 		// Find new stars
-		oldStars := repoSet(oldActivity.StarredRepos)
 		for _, repo := range newActivity.StarredRepos {
-			if !oldStars[repo.FullName()] {
+			if !idx.hasStar(repo, byID) && !missing[PartStarred] {
 				result.NewStars = append(result.NewStars, RepoChange{
 					Username: username,
 					Repo:     repo,
@@ -148,9 +295,8 @@ func Compare(old, new *Snapshot) *Result {
 		}
 
 		// Find new owned repos
-		oldOwned := repoSet(oldActivity.OwnedRepos)
 		for _, repo := range newActivity.OwnedRepos {
-			if !oldOwned[repo.FullName()] {
+			if !idx.hasOwned(repo, byID) && (!missing[PartOwned] || sinceOld(repo.CreatedAt)) {
 				result.NewRepos = append(result.NewRepos, RepoChange{
 					Username: username,
 					Repo:     repo,
@@ -159,10 +305,8 @@ func Compare(old, new *Snapshot) *Result {
 		}
 
 		// Find new events (by type+repo+time combination)
-		oldEvents := eventSet(oldActivity.Events)
 		for _, event := range newActivity.Events {
-			key := eventKey(event)
-			if !oldEvents[key] {
+			if !idx.hasEvent(event, byID) && (!eventsMissing || sinceOld(event.CreatedAt)) {
 				result.NewEvents = append(result.NewEvents, EventChange{
 					Username: username,
 					Event:    event,
@@ -174,25 +318,138 @@ func Compare(old, new *Snapshot) *Result {
 	return result
 }
 
-// repoSet creates a set of repo full names for quick lookup.
-func repoSet(repos []Repo) map[string]bool {
-	set := make(map[string]bool, len(repos))
+// Index holds per-user lookup sets for a snapshot's stars, owned repos and
+// events. It is read-only once built and safe for concurrent use.
+type Index struct {
+	users map[string]*userIndex
+}
+
+// NewIndex builds the lookup index for a snapshot.
+func NewIndex(s *Snapshot) *Index {
+	idx := &Index{users: make(map[string]*userIndex, len(s.Users))}
+	for username, activity := range s.Users {
+		idx.users[username] = &userIndex{
+			stars:    repoSet(activity.StarredRepos),
+			owned:    repoSet(activity.OwnedRepos),
+			events:   eventSet(activity.Events),
+			starIDs:  repoIDSet(activity.StarredRepos),
+			ownedIDs: repoIDSet(activity.OwnedRepos),
+			eventIDs: eventIDSet(activity.Events),
+		}
+	}
+	return idx
+}
+
+type userIndex struct {
+	stars  map[repoKey]struct{}
+	owned  map[repoKey]struct{}
+	events map[eventKey]struct{}
+	// The IDs of whichever have one; nil if none do
+	starIDs  map[string]struct{}
+	ownedIDs map[string]struct{}
+	eventIDs map[string]struct{}
+}
+
+// The lookup methods treat a nil index as empty, which is how users missing
+// from the old snapshot are handled. With byID, they also match by ID.
+
+func (u *userIndex) hasStar(r Repo, byID bool) bool {
+	if u == nil {
+		return false
+	}
+	if _, ok := u.stars[repoKey{owner: r.Owner, name: r.Name}]; ok {
+		return true
+	}
+	_, ok := u.starIDs[r.ID]
+	return byID && ok
+}
+
+func (u *userIndex) hasOwned(r Repo, byID bool) bool {
+	if u == nil {
+		return false
+	}
+	if _, ok := u.owned[repoKey{owner: r.Owner, name: r.Name}]; ok {
+		return true
+	}
+	_, ok := u.ownedIDs[r.ID]
+	return byID && ok
+}
+
+func (u *userIndex) hasEvent(e Event, byID bool) bool {
+	if u == nil {
+		return false
+	}
+	if _, ok := u.events[newEventKey(e)]; ok {
+		return true
+	}
+	_, ok := u.eventIDs[e.ID]
+	return byID && ok
+}
+
+// repoKey identifies a repo by owner and name. Comparable struct keys avoid
+// building a string per lookup.
+type repoKey struct {
+	owner string
+	name  string
+}
+
+// eventKey identifies an event by type, actor, repo, creation second and
+// size. GraphQL's commit contributions are a day's pushes to a repo, all
+// stamped with the day, so it's the size that tells a later push apart.
+type eventKey struct {
+	typ   string
+	actor string
+	repo  string
+	at    int64
+	size  int
+}
+
+func newEventKey(e Event) eventKey {
+	return eventKey{typ: e.Type, actor: e.Actor, repo: e.Repo, at: e.CreatedAt.Unix(), size: e.Size}
+}
+
+// repoSet creates a set of repos for quick lookup.
+func repoSet(repos []Repo) map[repoKey]struct{} {
+	set := make(map[repoKey]struct{}, len(repos))
 	for _, r := range repos {
-		set[r.FullName()] = true
+		set[repoKey{owner: r.Owner, name: r.Name}] = struct{}{}
+	}
+	return set
+}
+
+// eventSet creates a set of event keys for quick lookup.
+func eventSet(events []Event) map[eventKey]struct{} {
+	set := make(map[eventKey]struct{}, len(events))
+	for _, e := range events {
+		set[newEventKey(e)] = struct{}{}
 	}
 	return set
 }
 
-// eventKey creates a unique key for an event.
-func eventKey(e Event) string {
-	return e.Type + "|" + e.Actor + "|" + e.Repo + "|" + e.CreatedAt.Format(time.RFC3339)
+// repoIDSet creates a set of the IDs of whichever repos have one.
+func repoIDSet(repos []Repo) map[string]struct{} {
+	var set map[string]struct{}
+	for _, r := range repos {
+		if r.ID != "" {
+			if set == nil {
+				set = make(map[string]struct{})
+			}
+			set[r.ID] = struct{}{}
+		}
+	}
+	return set
 }
 
-// eventSet creates a set of event keys for quick lookup.
-func eventSet(events []Event) map[string]bool {
-	set := make(map[string]bool, len(events))
+// eventIDSet creates a set of the IDs of whichever events have one.
+func eventIDSet(events []Event) map[string]struct{} {
+	var set map[string]struct{}
 	for _, e := range events {
-		set[eventKey(e)] = true
+		if e.ID != "" {
+			if set == nil {
+				set = make(map[string]struct{})
+			}
+			set[e.ID] = struct{}{}
+		}
 	}
 	return set
 }
@@ -2,6 +2,7 @@ package diff
 
 import (
 	"slices"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -61,6 +62,11 @@ func TestResultIsEmpty(t *testing.T) {
 			result: Result{GoneUsers: []string{"bob"}},
 			want:   false,
 		},
+		{
+			name:   "has your own stars",
+			result: Result{YouStarred: []Repo{{}}},
+			want:   false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -108,6 +114,87 @@ func TestCompareDetectsGoneUser(t *testing.T) {
 	}
 }
 
+func TestCompareIncompleteUsers(t *testing.T) {
+	old := NewSnapshot(time.Now().Add(-24 * time.Hour))
+	new := NewSnapshot(time.Now())
+
+	// bob's fetch failed this time and carol's last time
+	old.Users["bob"] = UserActivity{Username: "bob"}
+	old.MarkIncomplete("carol", "events")
+	new.MarkIncomplete("bob", "events")
+	new.Users["carol"] = UserActivity{Username: "carol"}
+
+	result := Compare(old, new)
+
+	if len(result.GoneUsers) != 0 || len(result.NewUsers) != 0 {
+		t.Errorf("GoneUsers = %v, NewUsers = %v, want neither", result.GoneUsers, result.NewUsers)
+	}
+}
+
+func TestCompareMissingActivityIsNotNew(t *testing.T) {
+	oldTime := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	before, after := oldTime.Add(-time.Hour), oldTime.Add(time.Hour)
+	activity := UserActivity{
+		StarredRepos: []Repo{{Owner: "o", Name: "starred"}},
+		OwnedRepos:   []Repo{{Owner: "o", Name: "older", CreatedAt: before}, {Owner: "o", Name: "newer", CreatedAt: after}},
+		Events:       []Event{{Type: "PushEvent", Repo: "o/older", CreatedAt: before}, {Type: "PushEvent", Repo: "o/newer", CreatedAt: after}},
+	}
+
+	tests := []struct {
+		name    string
+		missing []string
+		stars   int
+		repos   int
+		events  int
+		present bool
+	}{
+		{name: "complete", stars: 1, repos: 2, events: 2, present: true},
+		{name: "failed events", missing: []string{PartEvents}, stars: 1, repos: 2, events: 1, present: true},
+		{name: "failed discussions", missing: []string{PartDiscussions}, stars: 1, repos: 2, events: 1, present: true},
+		{name: "failed listings", missing: []string{PartStarred, PartOwned}, stars: 0, repos: 1, events: 2, present: true},
+		{name: "left out entirely", missing: []string{PartProfile}, stars: 0, repos: 1, events: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := NewSnapshot(oldTime)
+			if tt.present {
+				old.Users["alice"] = UserActivity{Username: "alice"}
+			}
+			for _, part := range tt.missing {
+				old.MarkIncomplete("alice", part)
+			}
+			new := NewSnapshot(oldTime.Add(24 * time.Hour))
+			new.Users["alice"] = activity
+
+			result := Compare(old, new)
+
+			if len(result.NewStars) != tt.stars || len(result.NewRepos) != tt.repos || len(result.NewEvents) != tt.events {
+				t.Errorf("got %d stars, %d repos, %d events, want %d, %d, %d",
+					len(result.NewStars), len(result.NewRepos), len(result.NewEvents), tt.stars, tt.repos, tt.events)
+			}
+		})
+	}
+}
+
+func TestSnapshotMarkIncomplete(t *testing.T) {
+	s := NewSnapshot(time.Now())
+	if s.Partial() {
+		t.Error("expected a new snapshot to be complete")
+	}
+
+	s.MarkIncomplete("alice", "events")
+	s.MarkIncomplete("alice", "events")
+	s.MarkIncomplete("alice", "starred")
+
+	if !s.Partial() {
+		t.Error("expected the snapshot to be partial")
+	}
+	if want := []string{"events", "starred"}; !slices.Equal(s.Incomplete["alice"], want) {
+		t.Errorf("Incomplete[alice] = %v, want %v", s.Incomplete["alice"], want)
+	}
+}
+
 func TestCompareDetectsNewStars(t *testing.T) {
 	old := NewSnapshot(time.Now().Add(-24 * time.Hour))
 	new := NewSnapshot(time.Now())
@@ -340,3 +427,175 @@ func TestCompareTimestamps(t *testing.T) {
 		t.Errorf("NewCapturedAt = %v, want %v", result.NewCapturedAt, newTime)
 	}
 }
+
+func TestCompareEventTimeZones(t *testing.T) {
+	at := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	event := Event{Type: "PushEvent", Actor: "alice", Repo: "alice/repo", CreatedAt: at}
+
+	old := NewSnapshot(at)
+	old.Users["alice"] = UserActivity{Username: "alice", Events: []Event{event}}
+
+	// Same instant, different zone
+	shifted := event
+	shifted.CreatedAt = at.In(time.FixedZone("PST", -8*60*60))
+	new := NewSnapshot(at)
+	new.Users["alice"] = UserActivity{Username: "alice", Events: []Event{shifted}}
+
+	if result := Compare(old, new); len(result.NewEvents) != 0 {
+		t.Errorf("expected same-instant event to match, got %d new events", len(result.NewEvents))
+	}
+}
+
+func TestCompareIndexedReusesIndex(t *testing.T) {
+	old := NewSnapshot(time.Now())
+	old.Users["alice"] = UserActivity{
+		Username:     "alice",
+		StarredRepos: []Repo{{Owner: "a", Name: "one"}},
+	}
+	idx := NewIndex(old)
+
+	for _, name := range []string{"one", "two"} {
+		new := NewSnapshot(time.Now())
+		new.Users["alice"] = UserActivity{
+			Username:     "alice",
+			StarredRepos: []Repo{{Owner: "a", Name: name}},
+		}
+
+		got := CompareIndexed(old, idx, new)
+		want := Compare(old, new)
+		if len(got.NewStars) != len(want.NewStars) {
+			t.Errorf("%s: CompareIndexed found %d new stars, Compare found %d", name, len(got.NewStars), len(want.NewStars))
+		}
+	}
+}
+
+// benchSnapshots builds a pair of snapshots shaped like a month of activity
+// for a large follow list: each user has stars, repos and daily events, and
+// the newer snapshot adds one day of fresh events per user.
+func benchSnapshots(users, days int) (old, new *Snapshot) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	old = NewSnapshot(base.AddDate(0, 0, days-1))
+	new = NewSnapshot(base.AddDate(0, 0, days))
+
+	for u := 0; u < users; u++ {
+		username := "user" + strconv.Itoa(u)
+		var stars, owned []Repo
+		for i := 0; i < 30; i++ {
+			stars = append(stars, Repo{Owner: "owner" + strconv.Itoa(i), Name: "starred" + strconv.Itoa(u)})
+		}
+		for i := 0; i < 10; i++ {
+			owned = append(owned, Repo{Owner: username, Name: "repo" + strconv.Itoa(i)})
+		}
+
+		var events []Event
+		for d := 0; d < days; d++ {
+			for i := 0; i < 10; i++ {
+				events = append(events, Event{
+					Type:      "PushEvent",
+					Actor:     username,
+					Repo:      username + "/repo" + strconv.Itoa(i),
+					CreatedAt: base.AddDate(0, 0, d).Add(time.Duration(i) * time.Minute),
+				})
+			}
+		}
+
+		old.Users[username] = UserActivity{Username: username, StarredRepos: stars, OwnedRepos: owned, Events: events}
+
+		fresh := append([]Event(nil), events...)
+		for i := 0; i < 10; i++ {
+			fresh = append(fresh, Event{
+				Type:      "PushEvent",
+				Actor:     username,
+				Repo:      username + "/repo" + strconv.Itoa(i),
+				CreatedAt: base.AddDate(0, 0, days).Add(time.Duration(i) * time.Minute),
+			})
+		}
+		new.Users[username] = UserActivity{Username: username, StarredRepos: stars, OwnedRepos: owned, Events: fresh}
+	}
+	return old, new
+}
+
+func BenchmarkCompare(b *testing.B) {
+	old, new := benchSnapshots(300, 30)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Compare(old, new)
+	}
+}
+
+func BenchmarkCompareIndexed(b *testing.B) {
+	old, new := benchSnapshots(300, 30)
+	idx := NewIndex(old)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CompareIndexed(old, idx, new)
+	}
+}
+
+func BenchmarkNewIndex(b *testing.B) {
+	old, _ := benchSnapshots(300, 30)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewIndex(old)
+	}
+}
+
+func TestCompareDetectsProfileChanges(t *testing.T) {
+	t0 := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	old := NewSnapshot(t0)
+	old.Users["alice"] = UserActivity{Username: "alice", Profile: &Profile{Name: "Alice", Company: "Acme"}}
+	old.Users["bob"] = UserActivity{Username: "bob", Profile: &Profile{Name: "Bob"}}
+	old.Users["carol"] = UserActivity{Username: "carol"}
+
+	new := NewSnapshot(t0.Add(time.Hour))
+	new.Users["alice"] = UserActivity{Username: "alice", Profile: &Profile{Name: "Alice", Company: "Initech"}}
+	new.Users["bob"] = UserActivity{Username: "bob", Profile: &Profile{Name: "Bob"}}
+	// No profile before to compare with
+	new.Users["carol"] = UserActivity{Username: "carol", Profile: &Profile{Name: "Carol"}}
+
+	result := Compare(old, new)
+	if len(result.ProfileChanges) != 1 {
+		t.Fatalf("expected alice's profile change only, got %+v", result.ProfileChanges)
+	}
+	c := result.ProfileChanges[0]
+	if c.Username != "alice" || c.Old.Company != "Acme" || c.New.Company != "Initech" {
+		t.Errorf("unexpected profile change %+v", c)
+	}
+	if !result.IsEmpty() {
+		t.Error("expected a profile change alone to leave the result empty")
+	}
+}
+
+func TestCompareDetectsYourStars(t *testing.T) {
+	t0 := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	goRepo := Repo{ID: "1", Owner: "golang", Name: "go"}
+	tool := Repo{ID: "2", Owner: "alice", Name: "tool"}
+
+	old := NewSnapshot(t0)
+	old.Starred = []Repo{goRepo}
+	new := NewSnapshot(t0.Add(time.Hour))
+	new.Starred = []Repo{goRepo, tool}
+	if got := Compare(old, new).YouStarred; len(got) != 1 || got[0].FullName() != "alice/tool" {
+		t.Errorf("YouStarred = %+v, want only the newly starred repo", got)
+	}
+
+	// Without a starred list to compare with, nothing is new
+	if got := Compare(NewSnapshot(t0), new).YouStarred; got != nil {
+		t.Errorf("YouStarred without an old starred list = %+v, want none", got)
+	}
+
+	// Having starred nothing before is still a list to compare with
+	old.Starred = []Repo{}
+	if got := Compare(old, new).YouStarred; len(got) != 2 {
+		t.Errorf("YouStarred after starring nothing = %+v, want both repos", got)
+	}
+
+	// A renamed repo is the same star when matching by ID
+	old.Starred = []Repo{{ID: "1", Owner: "golang", Name: "golang"}, tool}
+	if got := CompareWithOptions(old, new, Options{MatchByID: true}).YouStarred; got != nil {
+		t.Errorf("YouStarred matching by ID = %+v, want none", got)
+	}
+}
@@ -0,0 +1,31 @@
+package diff
+
+// FollowChange is a followed user starting to follow someone.
+type FollowChange struct {
+	Username string // The followed user
+	Followed string // Who they started following
+}
+
+func (c FollowChange) hash() string {
+	return contentHash("follow", c.Username, c.Followed)
+}
+
+// newFollows returns who username follows in now but didn't in before.
+// Nil means a following list wasn't fetched, so there's nothing to compare
+// unless both snapshots have one.
+func newFollows(username string, before, now []string) []FollowChange {
+	if before == nil || now == nil {
+		return nil
+	}
+	followed := make(map[string]struct{}, len(before))
+	for _, login := range before {
+		followed[login] = struct{}{}
+	}
+	var changes []FollowChange
+	for _, login := range now {
+		if _, ok := followed[login]; !ok {
+			changes = append(changes, FollowChange{Username: username, Followed: login})
+		}
+	}
+	return changes
+}
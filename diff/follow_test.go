@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func followingSnapshot(at time.Time, following map[string][]string) *Snapshot {
+	s := NewSnapshot(at)
+	for username, logins := range following {
+		s.Users[username] = UserActivity{Username: username, Following: logins}
+	}
+	return s
+}
+
+func TestCompareDetectsNewFollows(t *testing.T) {
+	t0 := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	old := followingSnapshot(t0, map[string][]string{"alice": {"bob"}, "carol": nil})
+	new := followingSnapshot(t0.Add(time.Hour), map[string][]string{"alice": {"bob", "dave"}, "carol": {"erin"}, "frank": {"gina"}})
+
+	result := Compare(old, new)
+	want := []FollowChange{{Username: "alice", Followed: "dave"}}
+	if !reflect.DeepEqual(result.NewFollows, want) {
+		t.Errorf("NewFollows = %+v, want %+v", result.NewFollows, want)
+	}
+	if result.IsEmpty() {
+		t.Error("expected a new follow to make the result non-empty")
+	}
+
+	// Unfollowing isn't reported
+	if got := Compare(new, old).NewFollows; got != nil {
+		t.Errorf("NewFollows after unfollowing = %+v, want none", got)
+	}
+
+	// A following list that couldn't be fetched last time isn't new
+	old.MarkIncomplete("alice", PartFollowing)
+	if got := Compare(old, new).NewFollows; got != nil {
+		t.Errorf("NewFollows with an incomplete old snapshot = %+v, want none", got)
+	}
+}
+
+func TestFollowingSurvivesJSON(t *testing.T) {
+	// Following no one isn't the same as not having been fetched, so a
+	// user's first follow is new
+	t0 := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(followingSnapshot(t0, map[string][]string{"alice": {}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var old Snapshot
+	if err := json.Unmarshal(data, &old); err != nil {
+		t.Fatal(err)
+	}
+	if old.Users["alice"].Following == nil {
+		t.Fatalf("Following came back nil from %s", data)
+	}
+	new := followingSnapshot(t0.Add(time.Hour), map[string][]string{"alice": {"bob"}})
+	if got := Compare(&old, new).NewFollows; len(got) != 1 || got[0].Followed != "bob" {
+		t.Errorf("NewFollows = %+v, want alice following bob", got)
+	}
+}
+
+func TestFollowHashesMergeAndFilter(t *testing.T) {
+	follow := FollowChange{Username: "alice", Followed: "bob"}
+	r := &Result{NewFollows: []FollowChange{follow, {Username: "carol", Followed: "bob"}}}
+
+	hashes := r.Hashes()
+	if len(hashes) != 2 || hashes[0] == hashes[1] {
+		t.Fatalf("Hashes() = %v, want two distinct hashes", hashes)
+	}
+	if got := r.Without(map[string]bool{hashes[0]: true}).NewFollows; len(got) != 1 || got[0].Username != "carol" {
+		t.Errorf("Without(alice's follow).NewFollows = %+v, want carol's", got)
+	}
+
+	merged := Merge(&Result{NewFollows: []FollowChange{follow}}, r)
+	if len(merged.NewFollows) != 2 {
+		t.Errorf("Merge NewFollows = %+v, want each follow once", merged.NewFollows)
+	}
+
+	if got := (Options{Muted: []string{"carol"}}).Filter(r).NewFollows; !reflect.DeepEqual(got, []FollowChange{follow}) {
+		t.Errorf("Filter(muted carol).NewFollows = %+v, want alice's", got)
+	}
+}
+
+func TestMergeSnapshotsKeepsFollowing(t *testing.T) {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	a := followingSnapshot(at, map[string][]string{"alice": nil})
+	b := followingSnapshot(at, map[string][]string{"alice": {"bob"}})
+	if got := MergeSnapshots(at, a, b).Users["alice"].Following; !reflect.DeepEqual(got, []string{"bob"}) {
+		t.Errorf("Following = %v, want [bob]", got)
+	}
+}
@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The hashes of single changes. The kind is part of each, so a repo that's
+// both starred and owned by the same user hashes differently as each.
+
+func (c RepoChange) starHash() string {
+	return contentHash("star", c.Username, c.Repo.FullName())
+}
+
+func (c RepoChange) repoHash() string {
+	return contentHash("repo", c.Username, c.Repo.FullName())
+}
+
+func youStarHash(r Repo) string {
+	return contentHash("yours", r.FullName())
+}
+
+func (c EventChange) hash() string {
+	e := c.Event
+	return contentHash("event", c.Username, e.Type, e.Actor, e.Repo, strconv.FormatInt(e.CreatedAt.Unix(), 10), strconv.Itoa(e.Size))
+}
+
+// contentHash hashes the fields identifying a change.
+func contentHash(fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Hashes returns a content hash for each of r's new stars, repos, events
+// and follows, your own new stars, and watched repos' new releases and
+// pushes. A change gets the same hash in every sync that sees it, so
+// overlapping syncs can tell what's already been reported. Users following
+// or leaving, and stars gained, aren't hashed.
+func (r *Result) Hashes() []string {
+	hashes := make([]string, 0, len(r.NewStars)+len(r.NewRepos)+len(r.NewEvents)+len(r.YouStarred))
+	for _, c := range r.NewStars {
+		hashes = append(hashes, c.starHash())
+	}
+	for _, c := range r.NewRepos {
+		hashes = append(hashes, c.repoHash())
+	}
+	for _, c := range r.NewEvents {
+		hashes = append(hashes, c.hash())
+	}
+	for _, c := range r.NewFollows {
+		hashes = append(hashes, c.hash())
+	}
+	for _, repo := range r.YouStarred {
+		hashes = append(hashes, youStarHash(repo))
+	}
+	for _, c := range r.Watched {
+		for _, e := range c.NewEvents {
+			hashes = append(hashes, watchEventHash(e))
+		}
+	}
+	return hashes
+}
+
+// Without returns a copy of r leaving out the stars, repos, events,
+// follows, your own stars and watched repos' releases and pushes whose
+// hashes are in seen. Watched repos left with nothing new and no stars
+// gained are left out too.
+func (r *Result) Without(seen map[string]bool) *Result {
+	kept := &Result{
+		OldCapturedAt:  r.OldCapturedAt,
+		NewCapturedAt:  r.NewCapturedAt,
+		NewUsers:       r.NewUsers,
+		GoneUsers:      r.GoneUsers,
+		ProfileChanges: r.ProfileChanges,
+	}
+	for _, c := range r.NewStars {
+		if !seen[c.starHash()] {
+			kept.NewStars = append(kept.NewStars, c)
+		}
+	}
+	for _, c := range r.NewRepos {
+		if !seen[c.repoHash()] {
+			kept.NewRepos = append(kept.NewRepos, c)
+		}
+	}
+	for _, c := range r.NewEvents {
+		if !seen[c.hash()] {
+			kept.NewEvents = append(kept.NewEvents, c)
+		}
+	}
+	for _, c := range r.NewFollows {
+		if !seen[c.hash()] {
+			kept.NewFollows = append(kept.NewFollows, c)
+		}
+	}
+	for _, repo := range r.YouStarred {
+		if !seen[youStarHash(repo)] {
+			kept.YouStarred = append(kept.YouStarred, repo)
+		}
+	}
+	for _, c := range r.Watched {
+		c.NewEvents = slices.DeleteFunc(slices.Clone(c.NewEvents), func(e Event) bool { return seen[watchEventHash(e)] })
+		if !c.empty() {
+			kept.Watched = append(kept.Watched, c)
+		}
+	}
+	return kept
+}
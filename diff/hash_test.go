@@ -0,0 +1,73 @@
+package diff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResultHashes(t *testing.T) {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	repo := Repo{Owner: "alice", Name: "repo"}
+	push := EventChange{Username: "alice", Event: Event{Type: "PushEvent", Actor: "alice", Repo: "alice/repo", CreatedAt: at}}
+	r := &Result{
+		NewStars:  []RepoChange{{Username: "alice", Repo: repo}},
+		NewRepos:  []RepoChange{{Username: "alice", Repo: repo}},
+		NewEvents: []EventChange{push},
+		NewUsers:  []string{"bob"},
+	}
+
+	hashes := r.Hashes()
+	if len(hashes) != 3 {
+		t.Fatalf("expected a hash per star, repo and event, got %d", len(hashes))
+	}
+	if hashes[0] == hashes[1] {
+		t.Error("expected a star and an owned repo of the same name to hash differently")
+	}
+
+	// The same change seen in a later sync hashes the same, whatever else
+	// about the repo changed
+	later := &Result{NewStars: []RepoChange{{Username: "alice", Repo: Repo{Owner: "alice", Name: "repo", Stars: 10}}}}
+	if later.Hashes()[0] != hashes[0] {
+		t.Error("expected the same star to hash the same in a later sync")
+	}
+
+	// A push a second later is a different push
+	again := push
+	again.Event.CreatedAt = at.Add(time.Second)
+	if (&Result{NewEvents: []EventChange{again}}).Hashes()[0] == hashes[2] {
+		t.Error("expected events at different times to hash differently")
+	}
+}
+
+func TestResultWithout(t *testing.T) {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	r := &Result{
+		OldCapturedAt: at.Add(-time.Hour),
+		NewCapturedAt: at,
+		NewStars:      []RepoChange{{Username: "alice", Repo: Repo{Owner: "o", Name: "a"}}, {Username: "alice", Repo: Repo{Owner: "o", Name: "b"}}},
+		NewEvents:     []EventChange{{Username: "bob", Event: Event{Type: "PushEvent", Repo: "bob/x", CreatedAt: at}}},
+		NewUsers:      []string{"carol"},
+		YouStarred:    []Repo{{Owner: "o", Name: "a"}, {Owner: "o", Name: "c"}},
+	}
+	hashes := r.Hashes()
+	if hashes[3] == hashes[0] {
+		t.Error("expected your star to hash differently from alice's star of the same repo")
+	}
+
+	kept := r.Without(map[string]bool{hashes[0]: true, hashes[2]: true, hashes[3]: true})
+	if len(kept.YouStarred) != 1 || kept.YouStarred[0].Name != "c" {
+		t.Errorf("expected only your unseen star to be kept, got %+v", kept.YouStarred)
+	}
+	if len(kept.NewStars) != 1 || kept.NewStars[0].Repo.Name != "b" {
+		t.Errorf("expected only the unseen star to be kept, got %+v", kept.NewStars)
+	}
+	if len(kept.NewEvents) != 0 {
+		t.Errorf("expected the seen event to be left out, got %+v", kept.NewEvents)
+	}
+	if len(kept.NewUsers) != 1 || !kept.NewCapturedAt.Equal(at) {
+		t.Errorf("expected follow changes and times to carry over, got %+v", kept)
+	}
+	if len(r.NewStars) != 2 {
+		t.Error("expected Without to leave r alone")
+	}
+}
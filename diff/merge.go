@@ -0,0 +1,192 @@
+package diff
+
+import (
+	"slices"
+	"time"
+)
+
+// Merge combines change sets from consecutive comparisons into one, as if
+// the first snapshot had been compared with the last. Changes seen more than
+// once are kept once. A user who left and came back, or appeared and left
+// again, is listed as neither new nor gone.
+func Merge(results ...*Result) *Result {
+	merged := &Result{}
+
+	type userRepo struct {
+		user string
+		repo repoKey
+	}
+	type userEvent struct {
+		user  string
+		event eventKey
+	}
+	stars := make(map[userRepo]bool)
+	repos := make(map[userRepo]bool)
+	events := make(map[userEvent]bool)
+	follows := make(map[FollowChange]bool)
+	yours := make(map[repoKey]bool)
+	watched := make(map[string]int) // Index of each repo's change in merged
+	// Whether each user's first and latest changes were appearing (true)
+	// or leaving (false)
+	first := make(map[string]bool)
+	last := make(map[string]bool)
+	var order []string
+	profiles := make(map[string]int) // Index of each user's profile change in merged
+	userChange := func(u string, appeared bool) {
+		if _, seen := first[u]; !seen {
+			first[u] = appeared
+			order = append(order, u)
+		}
+		last[u] = appeared
+	}
+
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		if merged.OldCapturedAt.IsZero() || (!r.OldCapturedAt.IsZero() && r.OldCapturedAt.Before(merged.OldCapturedAt)) {
+			merged.OldCapturedAt = r.OldCapturedAt
+		}
+		if r.NewCapturedAt.After(merged.NewCapturedAt) {
+			merged.NewCapturedAt = r.NewCapturedAt
+		}
+
+		for _, c := range r.NewStars {
+			key := userRepo{c.Username, repoKey{owner: c.Repo.Owner, name: c.Repo.Name}}
+			if !stars[key] {
+				stars[key] = true
+				merged.NewStars = append(merged.NewStars, c)
+			}
+		}
+		for _, c := range r.NewRepos {
+			key := userRepo{c.Username, repoKey{owner: c.Repo.Owner, name: c.Repo.Name}}
+			if !repos[key] {
+				repos[key] = true
+				merged.NewRepos = append(merged.NewRepos, c)
+			}
+		}
+		for _, c := range r.NewEvents {
+			key := userEvent{c.Username, newEventKey(c.Event)}
+			if !events[key] {
+				events[key] = true
+				merged.NewEvents = append(merged.NewEvents, c)
+			}
+		}
+		for _, c := range r.NewFollows {
+			if !follows[c] {
+				follows[c] = true
+				merged.NewFollows = append(merged.NewFollows, c)
+			}
+		}
+
+		for _, repo := range r.YouStarred {
+			key := repoKey{owner: repo.Owner, name: repo.Name}
+			if !yours[key] {
+				yours[key] = true
+				merged.YouStarred = append(merged.YouStarred, repo)
+			}
+		}
+
+		for _, c := range r.Watched {
+			merged.Watched = mergeWatched(merged.Watched, watched, c)
+		}
+
+		// A profile changing several times changed from the first old
+		// profile to the last new one
+		for _, c := range r.ProfileChanges {
+			if i, ok := profiles[c.Username]; ok {
+				merged.ProfileChanges[i].New = c.New
+				continue
+			}
+			profiles[c.Username] = len(merged.ProfileChanges)
+			merged.ProfileChanges = append(merged.ProfileChanges, c)
+		}
+
+		for _, u := range r.NewUsers {
+			userChange(u, true)
+		}
+		for _, u := range r.GoneUsers {
+			userChange(u, false)
+		}
+	}
+
+	for _, u := range order {
+		switch {
+		case first[u] != last[u]:
+			// Back where they started
+		case last[u]:
+			merged.NewUsers = append(merged.NewUsers, u)
+		default:
+			merged.GoneUsers = append(merged.GoneUsers, u)
+		}
+	}
+	return merged
+}
+
+// MergeSnapshots combines snapshots of different follow lists taken at
+// about the same time into one captured at capturedAt. A user present in
+// several snapshots appears once, with their repos and events
+// de-duplicated and their Sources combined. What couldn't be fetched for
+// any of the snapshots is incomplete in the merged one.
+func MergeSnapshots(capturedAt time.Time, snapshots ...*Snapshot) *Snapshot {
+	merged := NewSnapshot(capturedAt)
+	for _, s := range snapshots {
+		if s == nil {
+			continue
+		}
+		for username, activity := range s.Users {
+			existing, ok := merged.Users[username]
+			if !ok {
+				merged.Users[username] = activity
+				continue
+			}
+			merged.Users[username] = mergeUser(existing, activity)
+		}
+		for username, endpoints := range s.Incomplete {
+			for _, endpoint := range endpoints {
+				merged.MarkIncomplete(username, endpoint)
+			}
+		}
+	}
+	return merged
+}
+
+// mergeUser combines two views of the same user's activity.
+func mergeUser(a, b UserActivity) UserActivity {
+	// Don't append into slices shared with the input snapshots
+	a.StarredRepos = slices.Clip(a.StarredRepos)
+	a.OwnedRepos = slices.Clip(a.OwnedRepos)
+	a.Events = slices.Clip(a.Events)
+	a.Sources = slices.Clip(a.Sources)
+
+	stars := repoSet(a.StarredRepos)
+	for _, r := range b.StarredRepos {
+		if _, ok := stars[repoKey{owner: r.Owner, name: r.Name}]; !ok {
+			a.StarredRepos = append(a.StarredRepos, r)
+		}
+	}
+	owned := repoSet(a.OwnedRepos)
+	for _, r := range b.OwnedRepos {
+		if _, ok := owned[repoKey{owner: r.Owner, name: r.Name}]; !ok {
+			a.OwnedRepos = append(a.OwnedRepos, r)
+		}
+	}
+	events := eventSet(a.Events)
+	for _, e := range b.Events {
+		if _, ok := events[newEventKey(e)]; !ok {
+			a.Events = append(a.Events, e)
+		}
+	}
+	for _, src := range b.Sources {
+		if !slices.Contains(a.Sources, src) {
+			a.Sources = append(a.Sources, src)
+		}
+	}
+	if a.Profile == nil {
+		a.Profile = b.Profile
+	}
+	if a.Following == nil {
+		a.Following = b.Following
+	}
+	return a
+}
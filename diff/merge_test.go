@@ -0,0 +1,138 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	t0 := time.Date(2026, 1, 15, 8, 0, 0, 0, time.UTC)
+	push := Event{Type: "PushEvent", Actor: "alice", Repo: "alice/a", CreatedAt: t0.Add(30 * time.Minute)}
+
+	first := &Result{
+		OldCapturedAt: t0,
+		NewCapturedAt: t0.Add(time.Hour),
+		NewStars:      []RepoChange{{Username: "alice", Repo: Repo{Owner: "golang", Name: "go"}}},
+		NewEvents:     []EventChange{{Username: "alice", Event: push}},
+		NewUsers:      []string{"carol", "dave"},
+		GoneUsers:     []string{"erin"},
+		YouStarred:    []Repo{{Owner: "golang", Name: "go"}},
+	}
+	second := &Result{
+		OldCapturedAt: t0.Add(time.Hour),
+		NewCapturedAt: t0.Add(2 * time.Hour),
+		NewStars:      []RepoChange{{Username: "bob", Repo: Repo{Owner: "golang", Name: "go"}}},
+		NewRepos:      []RepoChange{{Username: "bob", Repo: Repo{Owner: "bob", Name: "tool"}}},
+		// Seen again, e.g. after a lookback overlap
+		NewEvents:  []EventChange{{Username: "alice", Event: push}},
+		GoneUsers:  []string{"carol"},
+		YouStarred: []Repo{{Owner: "golang", Name: "go"}, {Owner: "golang", Name: "tools"}},
+	}
+
+	merged := Merge(first, nil, second)
+
+	if !merged.OldCapturedAt.Equal(t0) || !merged.NewCapturedAt.Equal(t0.Add(2*time.Hour)) {
+		t.Errorf("unexpected period %v - %v", merged.OldCapturedAt, merged.NewCapturedAt)
+	}
+	if len(merged.NewStars) != 2 {
+		t.Errorf("expected stars by both users, got %v", merged.NewStars)
+	}
+	if len(merged.NewRepos) != 1 {
+		t.Errorf("expected 1 new repo, got %v", merged.NewRepos)
+	}
+	if len(merged.NewEvents) != 1 {
+		t.Errorf("expected duplicate event to be merged, got %v", merged.NewEvents)
+	}
+	if len(merged.YouStarred) != 2 {
+		t.Errorf("expected your duplicate star to be merged, got %v", merged.YouStarred)
+	}
+	// carol appeared then left, so she's neither new nor gone
+	if !slices.Equal(merged.NewUsers, []string{"dave"}) {
+		t.Errorf("NewUsers = %v, want [dave]", merged.NewUsers)
+	}
+	if !slices.Equal(merged.GoneUsers, []string{"erin"}) {
+		t.Errorf("GoneUsers = %v, want [erin]", merged.GoneUsers)
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	if merged := Merge(); !merged.IsEmpty() {
+		t.Errorf("expected empty result, got %+v", merged)
+	}
+}
+
+func TestMergeSnapshots(t *testing.T) {
+	at := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	push := Event{Type: "PushEvent", Actor: "alice", Repo: "alice/a", CreatedAt: at}
+
+	work := NewSnapshot(at)
+	work.Users["alice"] = UserActivity{
+		Username:     "alice",
+		StarredRepos: []Repo{{Owner: "golang", Name: "go"}},
+		Events:       []Event{push},
+		Sources:      []string{"work"},
+	}
+	work.Users["bob"] = UserActivity{Username: "bob", Sources: []string{"work"}}
+
+	personal := NewSnapshot(at)
+	personal.Users["alice"] = UserActivity{
+		Username:     "alice",
+		StarredRepos: []Repo{{Owner: "golang", Name: "go"}, {Owner: "rust-lang", Name: "rust"}},
+		Events:       []Event{push},
+		Sources:      []string{"personal"},
+	}
+
+	merged := MergeSnapshots(at, work, personal)
+
+	if len(merged.Users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(merged.Users))
+	}
+	alice := merged.Users["alice"]
+	if len(alice.StarredRepos) != 2 {
+		t.Errorf("expected de-duplicated stars, got %v", alice.StarredRepos)
+	}
+	if len(alice.Events) != 1 {
+		t.Errorf("expected de-duplicated events, got %v", alice.Events)
+	}
+	if !slices.Equal(alice.Sources, []string{"work", "personal"}) {
+		t.Errorf("Sources = %v, want [work personal]", alice.Sources)
+	}
+	if !slices.Equal(merged.Users["bob"].Sources, []string{"work"}) {
+		t.Errorf("unexpected sources for bob: %v", merged.Users["bob"].Sources)
+	}
+	// The inputs are left alone
+	if len(work.Users["alice"].StarredRepos) != 1 || len(work.Users["alice"].Sources) != 1 {
+		t.Error("MergeSnapshots modified its input")
+	}
+}
+
+func TestMergeProfileChanges(t *testing.T) {
+	first := &Result{ProfileChanges: []ProfileChange{{Username: "alice", Old: Profile{Name: "A"}, New: Profile{Name: "Al"}}}}
+	second := &Result{ProfileChanges: []ProfileChange{
+		{Username: "alice", Old: Profile{Name: "Al"}, New: Profile{Name: "Alice"}},
+		{Username: "bob", Old: Profile{Bio: "old"}, New: Profile{Bio: "new"}},
+	}}
+
+	merged := Merge(first, second)
+	want := []ProfileChange{
+		{Username: "alice", Old: Profile{Name: "A"}, New: Profile{Name: "Alice"}},
+		{Username: "bob", Old: Profile{Bio: "old"}, New: Profile{Bio: "new"}},
+	}
+	if !slices.Equal(merged.ProfileChanges, want) {
+		t.Errorf("ProfileChanges = %+v, want %+v", merged.ProfileChanges, want)
+	}
+}
+
+func TestMergeSnapshotsKeepsProfile(t *testing.T) {
+	t0 := time.Date(2026, 1, 15, 8, 0, 0, 0, time.UTC)
+	a := NewSnapshot(t0)
+	a.Users["alice"] = UserActivity{Username: "alice"}
+	b := NewSnapshot(t0)
+	b.Users["alice"] = UserActivity{Username: "alice", Profile: &Profile{Name: "Alice"}}
+
+	merged := MergeSnapshots(t0, a, b)
+	if p := merged.Users["alice"].Profile; p == nil || p.Name != "Alice" {
+		t.Errorf("expected the profile from the second snapshot, got %+v", p)
+	}
+}
@@ -0,0 +1,90 @@
+package diff
+
+import (
+	"slices"
+	"time"
+)
+
+// Options adjusts what CompareWithOptions reports. The zero value reports
+// everything, as Compare does.
+type Options struct {
+	// Since leaves out stars, repos and events from before it, going by
+	// when repos were created and events happened. Snapshots hold a whole
+	// lookback's worth of activity, so it narrows a diff to a window.
+	Since time.Time
+	// Muted users' changes aren't reported, not even their following or
+	// leaving.
+	Muted []string
+	// MatchByID also matches repos and events to the old snapshot's by
+	// ID, where both have one, so a renamed repo isn't new again.
+	MatchByID bool
+}
+
+// CompareWithOptions compares two snapshots as Compare does, reporting only
+// the changes opts asks for.
+func CompareWithOptions(old, new *Snapshot, opts Options) *Result {
+	return opts.Filter(compareIndexed(old, NewIndex(old), new, opts.MatchByID))
+}
+
+// Filter returns a copy of r with only the changes o asks for. MatchByID
+// has no bearing on it. Users following or leaving, profile changes, new
+// follows and your own stars aren't dated, so Since keeps them all, and nor
+// are stars watched repos gained. Muting is about other users, so it leaves
+// your own stars alone too; on watched repos, it leaves out muted users'
+// releases and pushes.
+func (o Options) Filter(r *Result) *Result {
+	keepUser := func(username string) bool { return !slices.Contains(o.Muted, username) }
+	keepRepo := func(c RepoChange) bool {
+		return keepUser(c.Username) && !c.Repo.CreatedAt.Before(o.Since)
+	}
+
+	filtered := &Result{
+		OldCapturedAt: r.OldCapturedAt,
+		NewCapturedAt: r.NewCapturedAt,
+		YouStarred:    r.YouStarred,
+	}
+	for _, c := range r.NewStars {
+		if keepRepo(c) {
+			filtered.NewStars = append(filtered.NewStars, c)
+		}
+	}
+	for _, c := range r.NewRepos {
+		if keepRepo(c) {
+			filtered.NewRepos = append(filtered.NewRepos, c)
+		}
+	}
+	for _, c := range r.NewEvents {
+		if keepUser(c.Username) && !c.Event.CreatedAt.Before(o.Since) {
+			filtered.NewEvents = append(filtered.NewEvents, c)
+		}
+	}
+	for _, u := range r.NewUsers {
+		if keepUser(u) {
+			filtered.NewUsers = append(filtered.NewUsers, u)
+		}
+	}
+	for _, u := range r.GoneUsers {
+		if keepUser(u) {
+			filtered.GoneUsers = append(filtered.GoneUsers, u)
+		}
+	}
+	for _, c := range r.ProfileChanges {
+		if keepUser(c.Username) {
+			filtered.ProfileChanges = append(filtered.ProfileChanges, c)
+		}
+	}
+	for _, c := range r.NewFollows {
+		if keepUser(c.Username) {
+			filtered.NewFollows = append(filtered.NewFollows, c)
+		}
+	}
+	for _, c := range r.Watched {
+		c.NewEvents = slices.DeleteFunc(slices.Clone(c.NewEvents), func(e Event) bool {
+			return !keepUser(e.Actor) || e.CreatedAt.Before(o.Since)
+		})
+		if !c.empty() {
+			filtered.Watched = append(filtered.Watched, c)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,239 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOptionsFilterSince(t *testing.T) {
+	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	sinceDate := time.Date(2026, 1, 21, 0, 0, 0, 0, time.UTC)
+	oldDate := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC) // 3 weeks ago
+
+	result := &Result{
+		OldCapturedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		NewCapturedAt: now,
+		NewStars: []RepoChange{
+			{
+				Username: "simonw",
+				Repo: Repo{
+					Owner:     "simonw",
+					Name:      "old-repo",
+					CreatedAt: oldDate, // Should be filtered out
+				},
+			},
+			{
+				Username: "octocat",
+				Repo: Repo{
+					Owner:     "octocat",
+					Name:      "new-repo",
+					CreatedAt: sinceDate.Add(time.Hour), // Should be included
+				},
+			},
+		},
+		NewRepos: []RepoChange{
+			{
+				Username: "user1",
+				Repo: Repo{
+					Owner:     "user1",
+					Name:      "ancient-repo",
+					CreatedAt: oldDate, // Should be filtered out
+				},
+			},
+			{
+				Username: "user2",
+				Repo: Repo{
+					Owner:     "user2",
+					Name:      "recent-repo",
+					CreatedAt: now, // Should be included
+				},
+			},
+		},
+		NewEvents: []EventChange{
+			{
+				Username: "simonw",
+				Event: Event{
+					Type:      "PushEvent",
+					Actor:     "simonw",
+					Repo:      "simonw/old-project",
+					CreatedAt: oldDate, // Should be filtered out
+				},
+			},
+			{
+				Username: "octocat",
+				Event: Event{
+					Type:      "PushEvent",
+					Actor:     "octocat",
+					Repo:      "octocat/fresh-project",
+					CreatedAt: sinceDate, // Exactly on since date - should be included
+				},
+			},
+		},
+		NewUsers:   []string{"newuser1", "newuser2"},
+		GoneUsers:  []string{"goneuser1"},
+		YouStarred: []Repo{{Owner: "golang", Name: "go", CreatedAt: oldDate}},
+	}
+
+	filtered := Options{Since: sinceDate}.Filter(result)
+
+	// Check that timestamps are preserved
+	if !filtered.OldCapturedAt.Equal(result.OldCapturedAt) {
+		t.Errorf("OldCapturedAt mismatch: got %v, want %v", filtered.OldCapturedAt, result.OldCapturedAt)
+	}
+	if !filtered.NewCapturedAt.Equal(result.NewCapturedAt) {
+		t.Errorf("NewCapturedAt mismatch: got %v, want %v", filtered.NewCapturedAt, result.NewCapturedAt)
+	}
+
+	// Check that user lists are preserved
+	if len(filtered.NewUsers) != 2 || filtered.NewUsers[0] != "newuser1" {
+		t.Errorf("NewUsers not preserved: got %v, want %v", filtered.NewUsers, result.NewUsers)
+	}
+	if len(filtered.GoneUsers) != 1 || filtered.GoneUsers[0] != "goneuser1" {
+		t.Errorf("GoneUsers not preserved: got %v, want %v", filtered.GoneUsers, result.GoneUsers)
+	}
+	// Your stars aren't dated, however old the repo
+	if len(filtered.YouStarred) != 1 {
+		t.Errorf("YouStarred not preserved: got %v, want %v", filtered.YouStarred, result.YouStarred)
+	}
+
+	// Check that old stars are filtered out
+	if len(filtered.NewStars) != 1 {
+		t.Fatalf("expected 1 new star, got %d", len(filtered.NewStars))
+	}
+	if filtered.NewStars[0].Username != "octocat" {
+		t.Errorf("wrong star kept: got %s, want octocat", filtered.NewStars[0].Username)
+	}
+
+	// Check that old repos are filtered out
+	if len(filtered.NewRepos) != 1 {
+		t.Fatalf("expected 1 new repo, got %d", len(filtered.NewRepos))
+	}
+	if filtered.NewRepos[0].Username != "user2" {
+		t.Errorf("wrong repo kept: got %s, want user2", filtered.NewRepos[0].Username)
+	}
+
+	// Check that old events are filtered out
+	if len(filtered.NewEvents) != 1 {
+		t.Fatalf("expected 1 new event, got %d", len(filtered.NewEvents))
+	}
+	if filtered.NewEvents[0].Username != "octocat" {
+		t.Errorf("wrong event kept: got %s, want octocat", filtered.NewEvents[0].Username)
+	}
+}
+
+func TestOptionsFilterSinceBoundaries(t *testing.T) {
+	sinceDate := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		createdAt     time.Time
+		name          string
+		shouldInclude bool
+	}{
+		{
+			name:          "before since date",
+			createdAt:     sinceDate.Add(-24 * time.Hour),
+			shouldInclude: false,
+		},
+		{
+			name:          "exactly on since date",
+			createdAt:     sinceDate,
+			shouldInclude: true,
+		},
+		{
+			name:          "after since date",
+			createdAt:     sinceDate.Add(24 * time.Hour),
+			shouldInclude: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &Result{
+				NewStars: []RepoChange{
+					{
+						Username: "user",
+						Repo: Repo{
+							Owner:     "user",
+							Name:      "repo",
+							CreatedAt: tt.createdAt,
+						},
+					},
+				},
+			}
+
+			filtered := Options{Since: sinceDate}.Filter(result)
+
+			expectedCount := 0
+			if tt.shouldInclude {
+				expectedCount = 1
+			}
+
+			if len(filtered.NewStars) != expectedCount {
+				t.Errorf("expected %d stars, got %d", expectedCount, len(filtered.NewStars))
+			}
+		})
+	}
+}
+
+func TestOptionsFilterMuted(t *testing.T) {
+	result := &Result{
+		NewStars: []RepoChange{{Username: "alice"}, {Username: "bob"}},
+		NewEvents: []EventChange{
+			{Username: "alice", Event: Event{Type: "PushEvent"}},
+			{Username: "bob", Event: Event{Type: "PushEvent"}},
+		},
+		NewUsers:       []string{"bob", "carol"},
+		ProfileChanges: []ProfileChange{{Username: "bob"}},
+	}
+
+	filtered := Options{Muted: []string{"bob"}}.Filter(result)
+
+	if len(filtered.NewStars) != 1 || filtered.NewStars[0].Username != "alice" {
+		t.Errorf("NewStars = %v, want alice's", filtered.NewStars)
+	}
+	if len(filtered.NewEvents) != 1 || filtered.NewEvents[0].Event.Type != "PushEvent" || filtered.NewEvents[0].Username != "alice" {
+		t.Errorf("NewEvents = %v, want alice's push", filtered.NewEvents)
+	}
+	if !slices.Equal(filtered.NewUsers, []string{"carol"}) || len(filtered.ProfileChanges) != 0 {
+		t.Errorf("NewUsers = %v, ProfileChanges = %v, want only carol", filtered.NewUsers, filtered.ProfileChanges)
+	}
+}
+
+func TestCompareWithOptionsMatchByID(t *testing.T) {
+	old := NewSnapshot(time.Now().Add(-24 * time.Hour))
+	old.Users["alice"] = UserActivity{
+		OwnedRepos: []Repo{{ID: "1", Owner: "alice", Name: "before"}},
+		Events:     []Event{{ID: "10", Type: "PushEvent", Repo: "alice/before"}},
+	}
+	new := NewSnapshot(time.Now())
+	new.Users["alice"] = UserActivity{
+		OwnedRepos: []Repo{{ID: "1", Owner: "alice", Name: "after"}, {Owner: "alice", Name: "other"}},
+		Events:     []Event{{ID: "10", Type: "PushEvent", Repo: "alice/after"}},
+	}
+
+	if got := Compare(old, new); len(got.NewRepos) != 2 || len(got.NewEvents) != 1 {
+		t.Errorf("Compare: got %d repos and %d events, want the renamed ones new", len(got.NewRepos), len(got.NewEvents))
+	}
+
+	got := CompareWithOptions(old, new, Options{MatchByID: true})
+	if len(got.NewRepos) != 1 || got.NewRepos[0].Repo.Name != "other" || len(got.NewEvents) != 0 {
+		t.Errorf("CompareWithOptions: got repos %v and events %v, want only alice/other", got.NewRepos, got.NewEvents)
+	}
+}
+
+func TestCompareWithOptionsZeroIsCompare(t *testing.T) {
+	old := NewSnapshot(time.Now().Add(-24 * time.Hour))
+	old.Users["bob"] = UserActivity{Username: "bob"}
+	new := NewSnapshot(time.Now())
+	new.Users["alice"] = UserActivity{
+		StarredRepos: []Repo{{Owner: "o", Name: "r"}},
+		Events:       []Event{{Type: "PushEvent", Repo: "o/r", CreatedAt: time.Now()}},
+	}
+
+	want, got := Compare(old, new), CompareWithOptions(old, new, Options{})
+	if len(got.NewStars) != len(want.NewStars) || len(got.NewEvents) != len(want.NewEvents) ||
+		!slices.Equal(got.NewUsers, want.NewUsers) || !slices.Equal(got.GoneUsers, want.GoneUsers) {
+		t.Errorf("CompareWithOptions with no options = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WatchedRepo is a repo on the watchlist as it stood at a point in time.
+// Watched repos are tracked for themselves, whoever acts on them, rather
+// than through the users who are followed.
+type WatchedRepo struct {
+	Repo Repo
+	// Events are the repo's recent releases, as ReleaseEvents, and
+	// pushes, newest first.
+	Events []Event `json:",omitempty"`
+}
+
+// WatchChange is what changed on a watched repo between snapshots.
+type WatchChange struct {
+	Repo      Repo    // As it is now
+	OldStars  int     // Its stars as of the old snapshot
+	NewEvents []Event // Releases and pushes the old snapshot didn't have
+}
+
+// StarsGained returns how many stars the repo gained between snapshots.
+func (c WatchChange) StarsGained() int {
+	return c.Repo.Stars - c.OldStars
+}
+
+// empty reports whether c has nothing worth reporting: no new releases or
+// pushes, and no stars gained.
+func (c WatchChange) empty() bool {
+	return len(c.NewEvents) == 0 && c.StarsGained() <= 0
+}
+
+// compareWatched returns what changed on the repos both snapshots watch,
+// ordered by name. A repo that's newly on the watchlist has nothing to be
+// compared with, so none of its history is new.
+func compareWatched(old, new *Snapshot, byID bool) []WatchChange {
+	var changes []WatchChange
+	for name, now := range new.Watched {
+		before, ok := old.Watched[name]
+		if !ok {
+			continue
+		}
+		seen, seenIDs := eventSet(before.Events), eventIDSet(before.Events)
+		change := WatchChange{Repo: now.Repo, OldStars: before.Repo.Stars}
+		for _, e := range now.Events {
+			if _, ok := seen[newEventKey(e)]; ok {
+				continue
+			}
+			if _, ok := seenIDs[e.ID]; ok && byID {
+				continue
+			}
+			change.NewEvents = append(change.NewEvents, e)
+		}
+		if !change.empty() {
+			changes = append(changes, change)
+		}
+	}
+	slices.SortFunc(changes, func(a, b WatchChange) int {
+		return strings.Compare(a.Repo.FullName(), b.Repo.FullName())
+	})
+	return changes
+}
+
+// watchEventHash hashes a new release or push on a watched repo. Stars
+// gained aren't hashed: each comparison's are its own.
+func watchEventHash(e Event) string {
+	return contentHash("watch", e.Type, e.Actor, e.Repo, strconv.FormatInt(e.CreatedAt.Unix(), 10))
+}
+
+// mergeWatched folds c into changes, which are keyed in index by repo.
+// Stars gained run from the first old count to the latest, and events seen
+// more than once are kept once.
+func mergeWatched(changes []WatchChange, index map[string]int, c WatchChange) []WatchChange {
+	name := c.Repo.FullName()
+	i, ok := index[name]
+	if !ok {
+		index[name] = len(changes)
+		c.NewEvents = slices.Clone(c.NewEvents)
+		return append(changes, c)
+	}
+	merged := &changes[i]
+	merged.Repo = c.Repo
+	seen := eventSet(merged.NewEvents)
+	for _, e := range c.NewEvents {
+		if _, ok := seen[newEventKey(e)]; !ok {
+			merged.NewEvents = append(merged.NewEvents, e)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,121 @@
+package diff
+
+import (
+	"testing"
+	"time"
+)
+
+func watchedSnapshot(at time.Time, stars int, events ...Event) *Snapshot {
+	s := NewSnapshot(at)
+	s.Watched = map[string]WatchedRepo{
+		"golang/go": {Repo: Repo{ID: "1", Owner: "golang", Name: "go", Stars: stars}, Events: events},
+	}
+	return s
+}
+
+func TestCompareDetectsWatchedChanges(t *testing.T) {
+	t0 := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	push := Event{ID: "1", Type: "PushEvent", Actor: "rsc", Repo: "golang/go", CreatedAt: t0.Add(-time.Hour)}
+	release := Event{Type: "ReleaseEvent", Actor: "gopherbot", Repo: "golang/go", Ref: "go1.26", CreatedAt: t0.Add(30 * time.Minute)}
+
+	old := watchedSnapshot(t0, 100, push)
+	new := watchedSnapshot(t0.Add(time.Hour), 110, release, push)
+	result := Compare(old, new)
+	if len(result.Watched) != 1 {
+		t.Fatalf("Watched = %+v, want one change", result.Watched)
+	}
+	c := result.Watched[0]
+	if c.StarsGained() != 10 || len(c.NewEvents) != 1 || c.NewEvents[0].Ref != "go1.26" {
+		t.Errorf("change = %+v, want 10 stars and the new release", c)
+	}
+	if result.IsEmpty() {
+		t.Error("expected a watched change to make the result non-empty")
+	}
+
+	// Nothing new and no stars gained isn't a change
+	if got := Compare(old, watchedSnapshot(t0.Add(time.Hour), 95, push)).Watched; got != nil {
+		t.Errorf("Watched after losing stars = %+v, want none", got)
+	}
+
+	// A newly watched repo's history isn't new
+	if got := Compare(NewSnapshot(t0), new).Watched; got != nil {
+		t.Errorf("Watched for a newly watched repo = %+v, want none", got)
+	}
+
+	// An event whose time shifted is the same one when matching by ID
+	moved := push
+	moved.CreatedAt = moved.CreatedAt.Add(time.Second)
+	if got := CompareWithOptions(old, watchedSnapshot(t0.Add(time.Hour), 100, moved), Options{MatchByID: true}).Watched; got != nil {
+		t.Errorf("Watched matching by ID = %+v, want none", got)
+	}
+}
+
+func TestWatchedHashesAndWithout(t *testing.T) {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	push := Event{Type: "PushEvent", Actor: "rsc", Repo: "golang/go", CreatedAt: at}
+	r := &Result{Watched: []WatchChange{
+		{Repo: Repo{Owner: "golang", Name: "go", Stars: 10}, OldStars: 10, NewEvents: []Event{push}},
+		{Repo: Repo{Owner: "rust-lang", Name: "rust", Stars: 12}, OldStars: 10, NewEvents: []Event{{Type: "PushEvent", Repo: "rust-lang/rust", CreatedAt: at}}},
+	}}
+	hashes := r.Hashes()
+	if len(hashes) != 2 {
+		t.Fatalf("Hashes() = %v, want one per new event", hashes)
+	}
+	if same := (EventChange{Username: "rsc", Event: push}).hash(); hashes[0] == same {
+		t.Error("expected a watched push to hash differently from a followed user's")
+	}
+
+	kept := r.Without(map[string]bool{hashes[0]: true, hashes[1]: true})
+	// golang/go has nothing left; rust-lang/rust still gained stars
+	if len(kept.Watched) != 1 || kept.Watched[0].Repo.Name != "rust" || len(kept.Watched[0].NewEvents) != 0 {
+		t.Errorf("Without() kept %+v, want only rust's stars", kept.Watched)
+	}
+	if len(r.Watched[0].NewEvents) != 1 {
+		t.Error("Without() changed the original result")
+	}
+}
+
+func TestMergeWatched(t *testing.T) {
+	t0 := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	push := Event{Type: "PushEvent", Actor: "rsc", Repo: "golang/go", CreatedAt: t0}
+	release := Event{Type: "ReleaseEvent", Actor: "gopherbot", Repo: "golang/go", CreatedAt: t0.Add(time.Hour)}
+	first := &Result{Watched: []WatchChange{{Repo: Repo{Owner: "golang", Name: "go", Stars: 105}, OldStars: 100, NewEvents: []Event{push}}}}
+	second := &Result{Watched: []WatchChange{{Repo: Repo{Owner: "golang", Name: "go", Stars: 112}, OldStars: 105, NewEvents: []Event{push, release}}}}
+
+	merged := Merge(first, second)
+	if len(merged.Watched) != 1 {
+		t.Fatalf("Watched = %+v, want one change", merged.Watched)
+	}
+	if c := merged.Watched[0]; c.StarsGained() != 12 || len(c.NewEvents) != 2 {
+		t.Errorf("merged change = %+v, want 12 stars and both events once", c)
+	}
+	if len(first.Watched[0].NewEvents) != 1 {
+		t.Error("Merge() changed its input")
+	}
+}
+
+func TestOptionsFilterWatched(t *testing.T) {
+	since := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	r := &Result{Watched: []WatchChange{
+		{Repo: Repo{Owner: "golang", Name: "go", Stars: 10}, OldStars: 10, NewEvents: []Event{
+			{Type: "PushEvent", Actor: "rsc", CreatedAt: since.Add(time.Hour)},
+			{Type: "PushEvent", Actor: "bot", CreatedAt: since.Add(time.Hour)},
+			{Type: "ReleaseEvent", Actor: "rsc", CreatedAt: since.Add(-time.Hour)},
+		}},
+		{Repo: Repo{Owner: "rust-lang", Name: "rust", Stars: 12}, OldStars: 10, NewEvents: []Event{
+			{Type: "PushEvent", Actor: "bot", CreatedAt: since.Add(time.Hour)},
+		}},
+	}}
+
+	filtered := Options{Since: since, Muted: []string{"bot"}}.Filter(r)
+	if len(filtered.Watched) != 2 {
+		t.Fatalf("Watched = %+v, want both repos", filtered.Watched)
+	}
+	if got := filtered.Watched[0].NewEvents; len(got) != 1 || got[0].Actor != "rsc" || got[0].Type != "PushEvent" {
+		t.Errorf("golang/go events = %+v, want only rsc's recent push", got)
+	}
+	// Stars gained aren't dated, so they stay
+	if got := filtered.Watched[1]; len(got.NewEvents) != 0 || got.StarsGained() != 2 {
+		t.Errorf("rust-lang/rust = %+v, want only its stars", got)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// digestStore is implemented by stores that can queue changes between
+// digests. -digest-at needs one.
+type digestStore interface {
+	QueueDiff(capturedAt time.Time, data []byte) (int64, error)
+	PendingDiffs() ([]storage.PendingDiff, error)
+	ClearPendingDiffs(throughID int64) error
+	metaStore
+}
+
+// reportedStore is implemented by stores that remember which changes have
+// gone out in a report, so overlapping syncs don't report them twice. Other
+// stores report whatever each sync finds.
+type reportedStore interface {
+	Reported(hashes []string) (map[string]bool, error)
+	MarkReported(hashes []string, at time.Time) error
+	PruneReported(cutoff time.Time, dryRun bool) (int64, error)
+}
+
+// lastDigestKey records when the last digest was delivered.
+const lastDigestKey = "last_digest"
+
+// parseDigestAt parses a time of day in 24-hour "HH:MM" form.
+func parseDigestAt(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("digest-at must be a time of day like 09:00, got %q", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// lastDigestPoint returns the most recent scheduled digest time at or before
+// now, in now's location.
+func lastDigestPoint(now time.Time, hour, minute int) time.Time {
+	point := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if point.After(now) {
+		point = point.AddDate(0, 0, -1)
+	}
+	return point
+}
+
+// queueForDigest adds this sync's changes to the queue kept between digests.
+// If a digest is due (the first run since the scheduled time, or the very
+// first run), it returns everything queued since the last digest merged into
+// one result, plus a function that clears the queue once the digest has been
+// delivered. Otherwise the result is nil.
+func queueForDigest(store Store, result *diff.Result, at string, now time.Time) (*diff.Result, func() error, error) {
+	queue, ok := store.(digestStore)
+	if !ok {
+		return nil, nil, errors.New("digest scheduling isn't supported by this store")
+	}
+	hour, minute, err := parseDigestAt(at)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !result.IsEmpty() {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, nil, fmt.Errorf("encoding changes: %w", err)
+		}
+		if _, err := queue.QueueDiff(result.NewCapturedAt, data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	last, err := queue.GetMeta(lastDigestKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if last != "" {
+		lastAt, err := time.Parse(time.RFC3339, last)
+		if err == nil && !lastAt.Before(lastDigestPoint(now, hour, minute)) {
+			return nil, nil, nil
+		}
+	}
+
+	pending, err := queue.PendingDiffs()
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make([]*diff.Result, 0, len(pending))
+	var throughID int64
+	for _, p := range pending {
+		var r diff.Result
+		if err := json.Unmarshal(p.Data, &r); err != nil {
+			return nil, nil, fmt.Errorf("decoding queued changes from %s: %w", p.CapturedAt.Format(time.RFC3339), err)
+		}
+		results = append(results, &r)
+		throughID = p.ID
+	}
+
+	merged := diff.Merge(results...)
+	if len(results) == 0 {
+		merged.OldCapturedAt = result.OldCapturedAt
+		merged.NewCapturedAt = result.NewCapturedAt
+	}
+
+	done := func() error {
+		if throughID > 0 {
+			if err := queue.ClearPendingDiffs(throughID); err != nil {
+				return err
+			}
+		}
+		return queue.SetMeta(lastDigestKey, now.Format(time.RFC3339))
+	}
+	return merged, done, nil
+}
+
+// countChanges returns how many stars, repos, events and follows a result
+// holds, counting your own stars and watched repos' releases and pushes.
+func countChanges(result *diff.Result) int {
+	n := len(result.NewStars) + len(result.NewRepos) + len(result.NewEvents) + len(result.NewFollows) + len(result.YouStarred)
+	for _, c := range result.Watched {
+		n += len(c.NewEvents)
+	}
+	return n
+}
+
+// dropReported leaves out of result the changes an earlier report already
+// held. With a store that doesn't remember, result is returned as is.
+func dropReported(store Store, result *diff.Result) (*diff.Result, error) {
+	rs, ok := store.(reportedStore)
+	if !ok {
+		return result, nil
+	}
+	reported, err := rs.Reported(result.Hashes())
+	if err != nil || len(reported) == 0 {
+		return result, err
+	}
+	return result.Without(reported), nil
+}
+
+// markReported records result's changes as reported, if the store
+// remembers, and forgets those reported before cutoff. Syncs don't fetch
+// anything older than their lookback, so it makes a good cutoff; it also
+// lets a star or follow undone and redone outside it be reported again.
+func markReported(store Store, result *diff.Result, now, cutoff time.Time) error {
+	rs, ok := store.(reportedStore)
+	if !ok {
+		return nil
+	}
+	if err := rs.MarkReported(result.Hashes(), now); err != nil {
+		return err
+	}
+	_, err := rs.PruneReported(cutoff, false)
+	return err
+}
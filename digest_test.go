@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestLastDigestPoint(t *testing.T) {
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC), time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC), time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 1, 15, 8, 59, 0, 0, time.UTC), time.Date(2026, 1, 14, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := lastDigestPoint(tt.now, 9, 0); !got.Equal(tt.want) {
+			t.Errorf("lastDigestPoint(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestParseDigestAt(t *testing.T) {
+	if h, m, err := parseDigestAt("09:30"); err != nil || h != 9 || m != 30 {
+		t.Errorf("parseDigestAt(09:30) = %d, %d, %v", h, m, err)
+	}
+	for _, bad := range []string{"9am", "25:00", "09:60", ""} {
+		if _, _, err := parseDigestAt(bad); err == nil {
+			t.Errorf("parseDigestAt(%q) expected error", bad)
+		}
+	}
+}
+
+func TestQueueForDigest(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	day1 := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	starAt := func(at time.Time, repo string) *diff.Result {
+		return &diff.Result{
+			OldCapturedAt: at.Add(-time.Hour),
+			NewCapturedAt: at,
+			NewStars:      []diff.RepoChange{{Username: "alice", Repo: diff.Repo{Owner: "o", Name: repo}}},
+		}
+	}
+
+	// The very first run delivers right away
+	digest, done, err := queueForDigest(store, starAt(day1.Add(10*time.Hour), "a"), "09:00", day1.Add(10*time.Hour))
+	if err != nil || digest == nil {
+		t.Fatalf("first run: digest = %v, err = %v; want a digest", digest, err)
+	}
+	if len(digest.NewStars) != 1 {
+		t.Errorf("first digest: expected 1 star, got %d", len(digest.NewStars))
+	}
+	if err := done(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Later that day, and early the next morning, changes are only queued
+	for _, at := range []time.Time{day1.Add(11 * time.Hour), day1.Add(32 * time.Hour)} {
+		digest, _, err := queueForDigest(store, starAt(at, at.Format("15")), "09:00", at)
+		if err != nil || digest != nil {
+			t.Fatalf("run at %v: digest = %v, err = %v; want queued", at, digest, err)
+		}
+	}
+
+	// The first run after 09:00 delivers everything queued since
+	at := day1.Add(33*time.Hour + 30*time.Minute)
+	digest, done, err = queueForDigest(store, starAt(at, "c"), "09:00", at)
+	if err != nil || digest == nil {
+		t.Fatalf("digest run: digest = %v, err = %v; want a digest", digest, err)
+	}
+	if len(digest.NewStars) != 3 {
+		t.Errorf("expected 3 queued stars in the digest, got %d", len(digest.NewStars))
+	}
+	if !digest.OldCapturedAt.Equal(day1.Add(10 * time.Hour)) {
+		t.Errorf("digest should start at the first queued sync, got %v", digest.OldCapturedAt)
+	}
+
+	// Until delivery is confirmed, the queue is kept
+	pending, _ := store.PendingDiffs()
+	if len(pending) != 3 {
+		t.Errorf("expected queue intact before done, got %d entries", len(pending))
+	}
+	if err := done(); err != nil {
+		t.Fatal(err)
+	}
+	pending, _ = store.PendingDiffs()
+	if len(pending) != 0 {
+		t.Errorf("expected queue cleared after done, got %d entries", len(pending))
+	}
+}
+
+func TestQueueForDigest_UnsupportedStore(t *testing.T) {
+	if _, _, err := queueForDigest(&mockStore{}, &diff.Result{}, "09:00", fixedTime()); err == nil {
+		t.Error("expected error for a store without a digest queue")
+	}
+}
+
+func TestParseFlags_InvalidDigestAt(t *testing.T) {
+	setHome(t)
+	if _, err := parseFlags([]string{"-digest-at", "9am"}); err == nil || !strings.Contains(err.Error(), "digest-at") {
+		t.Errorf("expected digest-at error, got %v", err)
+	}
+}
+
+func TestRun_DigestAt(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	runAt := func(now time.Time, repo string) (string, *mockReportGenerator) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		gen := &mockReportGenerator{}
+		deps := &Dependencies{
+			GitHubClientFactory: func(token string) GitHubClient {
+				return &mockGitHubClient{
+					followedUsers: []github.User{{Login: "testuser", ID: 1}},
+					starredRepos: map[string][]github.Repository{
+						"testuser": {{Name: repo, Owner: github.User{Login: "owner1"}, CreatedAt: now}},
+					},
+				}
+			},
+			StoreFactory:    func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+			NotifierFactory: func() Notifier { return &mockNotifier{} },
+			ReportGenerator: func(string) (ReportGenerator, error) { return gen, nil },
+			OpenBrowser:     func(url string) error { return nil },
+			Now:             func() time.Time { return now },
+		}
+		code := run(&stdout, &stderr, []string{
+			"-token", "test-token",
+			"-db", dbPath,
+			"-report", filepath.Join(t.TempDir(), "report.html"),
+			"-no-notify",
+			"-digest-at", "09:00",
+		}, deps)
+		if code != 0 {
+			t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+		}
+		return stdout.String(), gen
+	}
+
+	day := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	if _, gen := runAt(day, "first"); gen.generatedReport == nil {
+		t.Fatal("expected the first run to deliver a digest")
+	}
+
+	out, gen := runAt(day.Add(time.Hour), "second")
+	if gen.generatedReport != nil {
+		t.Error("expected no report between digests")
+	}
+	if !strings.Contains(out, "Queued 1 changes for the 09:00 digest") {
+		t.Errorf("expected queue message, got %q", out)
+	}
+
+	_, gen = runAt(day.Add(24*time.Hour), "third")
+	if gen.generatedReport == nil {
+		t.Fatal("expected a digest the next morning")
+	}
+	if got := gen.generatedReport.TotalActivities(); got != 2 {
+		t.Errorf("expected both queued stars in the digest, got %d", got)
+	}
+}
+
+func TestDropReported(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	star := func(repo string) diff.RepoChange {
+		return diff.RepoChange{Username: "alice", Repo: diff.Repo{Owner: "o", Name: repo}}
+	}
+	now := fixedTime()
+	first := &diff.Result{NewStars: []diff.RepoChange{star("a")}}
+	if err := markReported(store, first, now, now.AddDate(0, 0, -30)); err != nil {
+		t.Fatal(err)
+	}
+
+	overlapping := &diff.Result{NewStars: []diff.RepoChange{star("a"), star("b")}}
+	got, err := dropReported(store, overlapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.NewStars) != 1 || got.NewStars[0].Repo.Name != "b" {
+		t.Errorf("expected only the unreported star, got %+v", got.NewStars)
+	}
+
+	// Stores that don't remember leave results alone
+	if got, err := dropReported(&mockStore{}, overlapping); err != nil || got != overlapping {
+		t.Errorf("dropReported on a plain store = %v, %v; want the result as is", got, err)
+	}
+
+	// Once a later sync's lookback has passed it, a star undone and redone
+	// is reported again
+	later := now.AddDate(0, 0, 31)
+	if err := markReported(store, &diff.Result{NewStars: []diff.RepoChange{star("b")}}, later, later.AddDate(0, 0, -30)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := dropReported(store, first); err != nil || len(got.NewStars) != 1 {
+		t.Errorf("dropReported after the lookback = %+v, %v; want star a again", got, err)
+	}
+}
+
+func TestRun_SkipsReportedChanges(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	starredAt := time.Date(2026, 1, 15, 9, 30, 0, 0, time.UTC)
+
+	runAt := func(now time.Time, repos ...string) (string, *mockReportGenerator) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		gen := &mockReportGenerator{}
+		var starred []github.Repository
+		for _, repo := range repos {
+			starred = append(starred, github.Repository{Name: repo, Owner: github.User{Login: "owner1"}, CreatedAt: starredAt})
+		}
+		deps := &Dependencies{
+			GitHubClientFactory: func(token string) GitHubClient {
+				return &mockGitHubClient{
+					followedUsers: []github.User{{Login: "testuser", ID: 1}},
+					starredRepos:  map[string][]github.Repository{"testuser": starred},
+				}
+			},
+			StoreFactory:    func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+			NotifierFactory: func() Notifier { return &mockNotifier{} },
+			ReportGenerator: func(string) (ReportGenerator, error) { return gen, nil },
+			OpenBrowser:     func(url string) error { return nil },
+			Now:             func() time.Time { return now },
+		}
+		code := run(&stdout, &stderr, []string{
+			"-token", "test-token",
+			"-db", dbPath,
+			"-report", filepath.Join(t.TempDir(), "report.html"),
+			"-no-notify",
+		}, deps)
+		if code != 0 {
+			t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+		}
+		return stdout.String(), gen
+	}
+
+	day := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	if _, gen := runAt(day, "first"); gen.generatedReport == nil {
+		t.Fatal("expected the first run to report the star")
+	}
+
+	// A sync that misses the star, then one that sees it again, would
+	// otherwise report it a second time
+	runAt(day.Add(time.Hour))
+	out, gen := runAt(day.Add(2*time.Hour), "first")
+	if gen.generatedReport != nil {
+		t.Errorf("expected the star not to be reported again, got %d activities", gen.generatedReport.TotalActivities())
+	}
+	if !strings.Contains(out, "No new activity detected.") {
+		t.Errorf("expected no new activity, got %q", out)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// appDirName names gitstreams' directory under the XDG base directories.
+const appDirName = "gitstreams"
+
+// dataDir returns where gitstreams keeps its database and reports:
+// $XDG_DATA_HOME/gitstreams, ~/.local/share/gitstreams if that's unset, or
+// ~/.gitstreams when legacy is set.
+func dataDir(legacy bool) (string, error) {
+	if legacy {
+		return legacyDir()
+	}
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// dbFile returns the file behind a -db value, which may start with a
+// backend scheme like bolt://.
+func dbFile(dsn string) string {
+	_, path := storage.ParseDSN(dsn)
+	return path
+}
+
+// configDir returns where gitstreams looks for its config file:
+// $XDG_CONFIG_HOME/gitstreams, or ~/.config/gitstreams if that's unset.
+func configDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// legacyDir returns ~/.gitstreams, which held both data and config before
+// gitstreams followed the XDG base directory spec.
+func legacyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	return filepath.Join(home, ".gitstreams"), nil
+}
+
+// xdgDir returns the gitstreams directory under the base directory named by
+// envVar, or under homeRel in the home directory if it's unset. The spec
+// says relative paths must be ignored, so they are treated as unset.
+func xdgDir(envVar, homeRel string) (string, error) {
+	if base := os.Getenv(envVar); filepath.IsAbs(base) {
+		return filepath.Join(base, appDirName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	return filepath.Join(home, homeRel, appDirName), nil
+}
+
+// migrateDefaultDirs moves ~/.gitstreams into the XDG directories.
+func migrateDefaultDirs() ([]string, error) {
+	legacy, err := legacyDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := dataDir(false)
+	if err != nil {
+		return nil, err
+	}
+	cfgDir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	return migrateLegacyDir(legacy, data, cfgDir)
+}
+
+// migrateLegacyDir moves the contents of legacy into the XDG directories:
+// the config file to cfgDir and everything else (database, reports) to
+// data. It does nothing unless legacy exists and data has no database yet,
+// so it runs once. Files already present at the destination are left in
+// legacy. legacy is removed if it ends up empty. It returns a notice for
+// each move.
+func migrateLegacyDir(legacy, data, cfgDir string) ([]string, error) {
+	entries, err := os.ReadDir(legacy)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", legacy, err)
+	}
+	if fileExists(filepath.Join(data, defaultDBName)) {
+		return nil, nil
+	}
+
+	var notices []string
+	for _, e := range entries {
+		destDir := data
+		if e.Name() == defaultConfigName {
+			destDir = cfgDir
+		}
+		src := filepath.Join(legacy, e.Name())
+		dest := filepath.Join(destDir, e.Name())
+		if fileExists(dest) {
+			continue
+		}
+		if err := os.MkdirAll(destDir, 0750); err != nil {
+			return notices, fmt.Errorf("creating %s: %w", destDir, err)
+		}
+		if err := moveFile(src, dest, e.IsDir()); err != nil {
+			return notices, err
+		}
+		notices = append(notices, fmt.Sprintf("Moved %s to %s", src, dest))
+	}
+
+	// Only succeeds if everything was moved
+	_ = os.Remove(legacy)
+	return notices, nil
+}
+
+// moveFile renames src to dest, copying regular files instead when they're
+// on different filesystems. Directories must be renamed.
+func moveFile(src, dest string, isDir bool) error {
+	err := os.Rename(src, dest)
+	if err == nil {
+		return nil
+	}
+	if isDir {
+		return fmt.Errorf("moving %s: %w", src, err)
+	}
+
+	if err := copyFile(src, dest); err != nil {
+		_ = os.Remove(dest)
+		return fmt.Errorf("moving %s: %w", src, err)
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src) // #nosec G304 -- src is an entry in the gitstreams directory
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304 -- dest is in the gitstreams directory
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// useLegacyDB points cfg at the database in ~/.gitstreams if one is still
+// there, so a failed migration doesn't start a fresh history.
+func useLegacyDB(cfg *Config) {
+	legacy, err := legacyDir()
+	if err != nil {
+		return
+	}
+	if path := filepath.Join(legacy, defaultDBName); fileExists(path) {
+		cfg.DBPath = path
+	}
+}
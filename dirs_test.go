@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setHome points HOME at a temp dir with the XDG variables unset.
+func setHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	return home
+}
+
+func TestDataDir(t *testing.T) {
+	home := setHome(t)
+
+	tests := []struct {
+		name    string
+		xdgData string
+		want    string
+		legacy  bool
+	}{
+		{name: "default", want: filepath.Join(home, ".local", "share", "gitstreams")},
+		{name: "XDG_DATA_HOME", xdgData: "/srv/data", want: filepath.Join("/srv/data", "gitstreams")},
+		{name: "relative XDG_DATA_HOME ignored", xdgData: "data", want: filepath.Join(home, ".local", "share", "gitstreams")},
+		{name: "legacy", xdgData: "/srv/data", legacy: true, want: filepath.Join(home, ".gitstreams")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_DATA_HOME", tt.xdgData)
+			got, err := dataDir(tt.legacy)
+			if err != nil {
+				t.Fatalf("dataDir() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("dataDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigDir(t *testing.T) {
+	home := setHome(t)
+
+	got, err := configDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".config", "gitstreams"); got != want {
+		t.Errorf("configDir() = %q, want %q", got, want)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "/etc/xdg")
+	got, err = configDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("/etc/xdg", "gitstreams"); got != want {
+		t.Errorf("configDir() = %q, want %q", got, want)
+	}
+}
+
+func TestParseFlags_XDGDefaults(t *testing.T) {
+	home := setHome(t)
+
+	cfg, err := parseFlags(nil)
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	if want := filepath.Join(home, ".local", "share", "gitstreams", defaultDBName); cfg.DBPath != want {
+		t.Errorf("DBPath = %q, want %q", cfg.DBPath, want)
+	}
+
+	cfg, err = parseFlags([]string{"-legacy-dirs"})
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	if want := filepath.Join(home, ".gitstreams", defaultDBName); cfg.DBPath != want {
+		t.Errorf("DBPath with -legacy-dirs = %q, want %q", cfg.DBPath, want)
+	}
+}
+
+func TestParseFlags_XDGConfigFile(t *testing.T) {
+	home := setHome(t)
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultConfigName), `{"no-open": true}`)
+	writeFile(t, filepath.Join(home, ".gitstreams", defaultConfigName), `{"no-notify": true}`)
+
+	cfg, err := parseFlags(nil)
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	if !cfg.NoOpen || cfg.NoNotify {
+		t.Errorf("expected the XDG config file to win over ~/.gitstreams, got NoOpen=%v NoNotify=%v", cfg.NoOpen, cfg.NoNotify)
+	}
+}
+
+func TestDBFile(t *testing.T) {
+	tests := map[string]string{
+		"/data/gitstreams.db":           "/data/gitstreams.db",
+		"sqlite:///data/gitstreams.db":  "/data/gitstreams.db",
+		"bolt:///data/gitstreams.bolt":  "/data/gitstreams.bolt",
+		"bolt://relative/gitstreams.db": "relative/gitstreams.db",
+	}
+	for dsn, want := range tests {
+		if got := dbFile(dsn); got != want {
+			t.Errorf("dbFile(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
+
+func TestMigrateLegacyDir(t *testing.T) {
+	root := t.TempDir()
+	legacy := filepath.Join(root, "legacy")
+	data := filepath.Join(root, "data")
+	cfgDir := filepath.Join(root, "config")
+
+	writeFile(t, filepath.Join(legacy, defaultDBName), "db")
+	writeFile(t, filepath.Join(legacy, defaultConfigName), "{}")
+	writeFile(t, filepath.Join(legacy, "reports", "gitstreams-2026-01-15.html"), "<html>")
+
+	notices, err := migrateLegacyDir(legacy, data, cfgDir)
+	if err != nil {
+		t.Fatalf("migrateLegacyDir() error = %v", err)
+	}
+	if len(notices) != 3 {
+		t.Errorf("expected 3 notices, got %v", notices)
+	}
+
+	for _, path := range []string{
+		filepath.Join(data, defaultDBName),
+		filepath.Join(data, "reports", "gitstreams-2026-01-15.html"),
+		filepath.Join(cfgDir, defaultConfigName),
+	} {
+		if !fileExists(path) {
+			t.Errorf("expected %s to exist after migration", path)
+		}
+	}
+	if fileExists(legacy) {
+		t.Error("expected empty legacy directory to be removed")
+	}
+
+	// A second run has nothing to do
+	notices, err = migrateLegacyDir(legacy, data, cfgDir)
+	if err != nil || len(notices) != 0 {
+		t.Errorf("second migrateLegacyDir() = %v, %v; want no-op", notices, err)
+	}
+}
+
+func TestMigrateLegacyDir_AlreadyMigrated(t *testing.T) {
+	root := t.TempDir()
+	legacy := filepath.Join(root, "legacy")
+	data := filepath.Join(root, "data")
+
+	writeFile(t, filepath.Join(legacy, defaultDBName), "old")
+	writeFile(t, filepath.Join(data, defaultDBName), "new")
+
+	notices, err := migrateLegacyDir(legacy, data, filepath.Join(root, "config"))
+	if err != nil || len(notices) != 0 {
+		t.Fatalf("migrateLegacyDir() = %v, %v; want no-op", notices, err)
+	}
+	// #nosec G304 -- test file in temp dir
+	got, err := os.ReadFile(filepath.Join(data, defaultDBName))
+	if err != nil || string(got) != "new" {
+		t.Errorf("existing database was touched: %q, %v", got, err)
+	}
+	if !fileExists(filepath.Join(legacy, defaultDBName)) {
+		t.Error("legacy database should be left in place")
+	}
+}
+
+func TestMigrateLegacyDir_KeepsExistingConfig(t *testing.T) {
+	root := t.TempDir()
+	legacy := filepath.Join(root, "legacy")
+	cfgDir := filepath.Join(root, "config")
+
+	writeFile(t, filepath.Join(legacy, defaultDBName), "db")
+	writeFile(t, filepath.Join(legacy, defaultConfigName), `{"old": true}`)
+	writeFile(t, filepath.Join(cfgDir, defaultConfigName), `{}`)
+
+	if _, err := migrateLegacyDir(legacy, filepath.Join(root, "data"), cfgDir); err != nil {
+		t.Fatalf("migrateLegacyDir() error = %v", err)
+	}
+	if !fileExists(filepath.Join(legacy, defaultConfigName)) {
+		t.Error("legacy config should stay when the XDG config already exists")
+	}
+}
+
+func TestMigrateLegacyDir_NoLegacyDir(t *testing.T) {
+	root := t.TempDir()
+	notices, err := migrateLegacyDir(filepath.Join(root, "missing"), filepath.Join(root, "data"), filepath.Join(root, "config"))
+	if err != nil || notices != nil {
+		t.Errorf("migrateLegacyDir() = %v, %v; want nil, nil", notices, err)
+	}
+}
+
+func TestRun_MigrateLegacyDirs(t *testing.T) {
+	setHome(t)
+
+	var migrated bool
+	deps := &Dependencies{
+		// Stop right after setup; only the migration matters here
+		StoreFactory: func(string) (Store, error) { return nil, errors.New("stop") },
+		MigrateLegacyDirs: func() ([]string, error) {
+			migrated = true
+			return []string{"Moved a to b"}, nil
+		},
+		Now:    fixedTime,
+		Logger: slog.Default(),
+	}
+	var stdout, stderr strings.Builder
+
+	_ = run(&stdout, &stderr, []string{"-no-notify", "-no-open"}, deps)
+	if !migrated {
+		t.Fatal("expected migration to run with the default database path")
+	}
+	if !strings.Contains(stdout.String(), "Moved a to b") {
+		t.Errorf("expected migration notice in output, got %q", stdout.String())
+	}
+
+	migrated = false
+	_ = run(&stdout, &stderr, []string{"-legacy-dirs"}, deps)
+	_ = run(&stdout, &stderr, []string{"-db", filepath.Join(t.TempDir(), "x.db")}, deps)
+	if migrated {
+		t.Error("migration should be skipped with -legacy-dirs or an explicit -db")
+	}
+}
+
+func TestRun_MigrationFailureFallsBackToLegacyDB(t *testing.T) {
+	home := setHome(t)
+	legacyDB := filepath.Join(home, ".gitstreams", defaultDBName)
+	writeFile(t, legacyDB, "")
+
+	var opened string
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) {
+			opened = path
+			return nil, errors.New("stop")
+		},
+		MigrateLegacyDirs: func() ([]string, error) { return nil, errors.New("permission denied") },
+		Now:               fixedTime,
+		Logger:            slog.Default(),
+	}
+	var stdout, stderr strings.Builder
+
+	_ = run(&stdout, &stderr, []string{"-no-notify", "-no-open"}, deps)
+	if !strings.Contains(stderr.String(), "permission denied") {
+		t.Errorf("expected migration warning, got %q", stderr.String())
+	}
+	if opened != legacyDB {
+		t.Errorf("opened %q, want legacy database %q", opened, legacyDB)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/source"
+)
+
+// discoverReportName is the file name discovery reports get in -report-dir
+// unless -report is given, with {profile} as the other account.
+const discoverReportName = "gitstreams-discover-{profile}-{date}.{format}"
+
+// runDiscover handles the "discover" subcommand: it reports recent activity
+// from the users another account follows and you don't, to find who to
+// follow next.
+func runDiscover(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	since := fs.String("since", "7d", "Report activity since this date: YYYY-MM-DD or relative like 7d, 2w, 3m")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	sources, err := fs.resolve()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+	if fs.NArg() != 1 {
+		_, _ = fmt.Fprintln(stderr, "Usage: gitstreams discover [flags] <username>")
+		return 1
+	}
+	other := fs.Arg(0)
+	if cfg.Token == "" && cfg.Username == "" {
+		_, _ = fmt.Fprintln(stderr, "Error: discover needs a token or -user to know who you follow")
+		return 1
+	}
+	now := deps.Now()
+	if deps.IsHeadless != nil && deps.IsHeadless() {
+		applyHeadlessDefaults(cfg, sources)
+	}
+	sinceDate, err := parseSinceDate(*since, now)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: invalid -since: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := runContext(cfg.Timeout)
+	defer cancel()
+	client := newGitHubClient(cfg, deps, cfg.Token)
+
+	var mine []github.User
+	if cfg.Username != "" {
+		mine, err = client.GetFollowedUsersByUsername(ctx, cfg.Username)
+	} else {
+		mine, err = client.GetFollowedUsers(ctx)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error fetching who you follow: %v\n", explainTimeout(err, cfg.Timeout))
+		return 1
+	}
+	theirs, err := client.GetFollowedUsersByUsername(ctx, other)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error fetching who %s follows: %v\n", other, explainTimeout(err, cfg.Timeout))
+		return 1
+	}
+	candidates := notFollowed(theirs, mine, cfg.Username)
+	if len(candidates) == 0 {
+		_, _ = fmt.Fprintf(stdout, "You already follow everyone %s follows.\n", other)
+		return 0
+	}
+
+	plan := defaultFetchPlan()
+	if cfg.Token == "" {
+		plan = fetchPlan{Events: true}
+	}
+	plan.Concurrency = cfg.Concurrency
+	plan, cutoff := adaptToBudget(ctx, client, plan, len(candidates), now, sinceDate, stderr)
+	subjects := source.Subjects(candidates)
+	prefetch(ctx, client, subjects, stdout, cfg.Verbose)
+	snapshot, warnings, err := fetchSubjectsActivity(ctx, githubSource(client, plan), subjects, plan.Concurrency, now, cutoff, stdout, stderr, nil, cfg.Verbose)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", explainTimeout(err, cfg.Timeout))
+		return 1
+	}
+	printFetchWarnings(stderr, warnings)
+
+	// Against an empty snapshot, everything since the date is new
+	result := diff.CompareWithOptions(diff.NewSnapshot(time.Time{}), snapshot, diff.Options{Since: sinceDate, MatchByID: true})
+	rpt := report.FromDiff(result, report.Options{
+		GeneratedAt:          now,
+		PeriodStart:          sinceDate,
+		PeriodEnd:            now,
+		WebURL:               cfg.GitHubURL,
+		HideBranches:         cfg.HideBranches,
+		MinStars:             cfg.MinStars,
+		HideUndescribedStars: cfg.StarsNeedDesc,
+	})
+	rpt.SetProfiles(userProfiles(snapshot))
+	rpt.Categories = report.CategoryLayout{Order: cfg.CategoryOrder, Hidden: cfg.HideCategories}
+	rpt.Aggregation = cfg.Aggregate.rules()
+	rpt.Pinned = cfg.Pin
+	rpt.Noise = cfg.noiseRules()
+	if tagSet, err := loadTags(cfg.TagsPath); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Warning: could not tag activities: %v\n", err)
+	} else {
+		tagReport(rpt, tagSet, snapshot, result)
+	}
+	if err := applyPreset(rpt, cfg, snapshot, result); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	_, _ = fmt.Fprintf(stdout, "%s follows %d users you don't; %d were active since %s\n",
+		other, len(candidates), len(rpt.UserActivities), sinceDate.Format(time.DateOnly))
+	for _, ua := range rpt.UserActivities {
+		_, _ = fmt.Fprintf(stdout, "  %s: %d activities\n", ua.User, len(ua.Activities))
+	}
+	if len(rpt.UserActivities) == 0 {
+		return 0
+	}
+
+	path, err := discoverReportPath(cfg, now, other)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	generator, err := deps.ReportGenerator(cfg.Format)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error creating report generator: %v\n", err)
+		return 1
+	}
+	useClock(generator, deps.Now)
+	if cfg.Template != "" {
+		if err := applyTemplate(generator, cfg.Template); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error creating report directory: %v\n", err)
+		return 1
+	}
+	if err := writeFileAtomic(path, func(w io.Writer) error { return generator.Generate(w, rpt) }); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error generating report: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintf(stdout, "Report written to %s\n", path)
+
+	if !cfg.NoOpen {
+		if err := deps.OpenBrowser("file://" + path); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: could not open browser: %v\n", err)
+		}
+	}
+	return 0
+}
+
+// notFollowed returns the users in theirs that aren't in mine, leaving out
+// me, the account mine was fetched for, if known.
+func notFollowed(theirs, mine []github.User, me string) []github.User {
+	followed := make(map[string]bool, len(mine))
+	for _, u := range mine {
+		followed[strings.ToLower(u.Login)] = true
+	}
+	return slices.DeleteFunc(slices.Clone(theirs), func(u github.User) bool {
+		return followed[strings.ToLower(u.Login)] || strings.EqualFold(u.Login, me)
+	})
+}
+
+// discoverReportPath returns where a discovery report about other's follows
+// goes: -report if given, or discoverReportName in -report-dir.
+func discoverReportPath(cfg *Config, now time.Time, other string) (string, error) {
+	if cfg.ReportPath != "" {
+		return cfg.ReportPath, nil
+	}
+	name, err := expandReportName(discoverReportName, now, cfg.Format, other)
+	if err != nil {
+		return "", err
+	}
+	dir := cfg.ReportDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/github/githubtest"
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+func TestRunDiscover(t *testing.T) {
+	setHome(t)
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	srv := githubtest.NewServer(t)
+	srv.Follow("bob", "carol")
+	srv.FollowFrom("teammate", "bob", "Dave", "erin", "frank")
+	srv.AddEvents("bob", github.Event{Type: "PushEvent", Repo: github.EventRepo{Name: "bob/tool"}, CreatedAt: now.Add(-time.Hour)})
+	srv.AddEvents("Dave", github.Event{Type: "ReleaseEvent", Repo: github.EventRepo{Name: "Dave/lib"}, CreatedAt: now.Add(-24 * time.Hour)})
+	srv.AddEvents("erin", github.Event{Type: "PushEvent", Repo: github.EventRepo{Name: "erin/old"}, CreatedAt: now.Add(-30 * 24 * time.Hour)})
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return srv.Client(token) },
+		ReportGenerator:     func(format string) (ReportGenerator, error) { return report.New(format) },
+		Now:                 func() time.Time { return now },
+	}
+
+	reportPath := filepath.Join(t.TempDir(), "discover.json")
+	var stdout, stderr strings.Builder
+	code := run(&stdout, &stderr, []string{"discover", "-token", "test-token", "-format", "json", "-report", reportPath, "-no-open", "teammate"}, deps)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "teammate follows 3 users you don't; 1 were active since 2026-02-23") {
+		t.Errorf("unexpected summary: %s", stdout.String())
+	}
+
+	data, err := os.ReadFile(reportPath) // #nosec G304 -- test temp dir
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rpt report.Report
+	if err := json.Unmarshal(data, &rpt); err != nil {
+		t.Fatal(err)
+	}
+	var users []string
+	for _, ua := range rpt.UserActivities {
+		users = append(users, ua.User)
+	}
+	if !slices.Equal(users, []string{"Dave"}) {
+		t.Errorf("report covers %v, want only Dave: bob is followed already, erin and frank weren't active", users)
+	}
+}
+
+func TestRunDiscover_InvalidSettings(t *testing.T) {
+	setHome(t)
+	t.Setenv("GITHUB_TOKEN", "")
+	deps := &Dependencies{Now: fixedTime}
+
+	var stdout, stderr strings.Builder
+	if code := run(&stdout, &stderr, []string{"discover", "-token", "t"}, deps); code != 1 || !strings.Contains(stderr.String(), "Usage") {
+		t.Errorf("expected usage without a username, got %d: %s", code, stderr.String())
+	}
+
+	stderr.Reset()
+	if code := run(&stdout, &stderr, []string{"discover", "teammate"}, deps); code != 1 || !strings.Contains(stderr.String(), "token or -user") {
+		t.Errorf("expected an error without a token or user, got %d: %s", code, stderr.String())
+	}
+
+	stderr.Reset()
+	if code := run(&stdout, &stderr, []string{"discover", "-token", "t", "-since", "soon", "teammate"}, deps); code != 1 || !strings.Contains(stderr.String(), "invalid -since") {
+		t.Errorf("expected a -since error, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestNotFollowed(t *testing.T) {
+	users := func(logins ...string) []github.User {
+		var us []github.User
+		for _, l := range logins {
+			us = append(us, github.User{Login: l})
+		}
+		return us
+	}
+	got := notFollowed(users("alice", "Bob", "carol", "me"), users("bob"), "ME")
+	if len(got) != 2 || got[0].Login != "alice" || got[1].Login != "carol" {
+		t.Errorf("notFollowed() = %+v, want alice and carol", got)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/github/githubtest"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// e2eRun runs gitstreams against srv with a real client, store and JSON
+// report, as of now, returning its exit code and the report it wrote.
+func e2eRun(t *testing.T, srv *githubtest.Server, dir string, now time.Time, extra ...string) (int, *report.Report, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return srv.Client(token) },
+		StoreFactory:        func(dbPath string) (Store, error) { return storage.Open(dbPath) },
+		NotifierFactory:     func() Notifier { return &mockNotifier{} },
+		ReportGenerator:     func(format string) (ReportGenerator, error) { return report.New(format) },
+		OpenBrowser:         func(url string) error { return nil },
+		Now:                 func() time.Time { return now },
+	}
+	reportPath := filepath.Join(dir, "report.json")
+	_ = os.Remove(reportPath)
+	args := append([]string{
+		"-token", "test-token",
+		"-db", filepath.Join(dir, "gitstreams.db"),
+		"-report", reportPath,
+		"-format", "json",
+		"-no-notify",
+		"-no-open",
+		"-no-update-check",
+	}, extra...)
+
+	code := run(&stdout, &stderr, args, deps)
+	data, err := os.ReadFile(reportPath) // #nosec G304 -- test temp dir
+	if err != nil {
+		return code, nil, stderr.String()
+	}
+	var rpt report.Report
+	if err := json.Unmarshal(data, &rpt); err != nil {
+		t.Fatalf("decoding report: %v\n%s", err, data)
+	}
+	return code, &rpt, stderr.String()
+}
+
+// reportedActivities returns "user type repo" for each activity in r.
+func reportedActivities(r *report.Report) []string {
+	var got []string
+	for _, ua := range r.UserActivities {
+		for _, a := range ua.Activities {
+			got = append(got, ua.User+" "+string(a.Type)+" "+a.RepoName)
+		}
+	}
+	return got
+}
+
+func TestE2E_ReportsNewActivityBetweenRuns(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	srv := githubtest.NewServer(t)
+	srv.AddUser(github.User{Login: "alice", Name: "Alice Liddell"})
+	srv.Follow("alice", "bob")
+	srv.Star("alice", github.Repository{ID: 1, Name: "old", FullName: "someone/old", Owner: github.User{Login: "someone"}, CreatedAt: now.Add(-72 * time.Hour)})
+	srv.AddEvents("bob", github.Event{Type: "WatchEvent", Repo: github.EventRepo{Name: "someone/old"}, CreatedAt: now.Add(-2 * time.Hour)})
+
+	code, _, stderr := e2eRun(t, srv, dir, now)
+	if code != 0 {
+		t.Fatalf("first run: exit code %d, stderr: %s", code, stderr)
+	}
+
+	// Between runs, alice stars a repo and bob creates one
+	later := now.Add(24 * time.Hour)
+	srv.Star("alice", github.Repository{ID: 2, Name: "go", FullName: "golang/go", Owner: github.User{Login: "golang"}, CreatedAt: later.Add(-time.Hour)})
+	srv.AddRepos("bob", github.Repository{ID: 3, Name: "tool", FullName: "bob/tool", CreatedAt: later.Add(-3 * time.Hour)})
+
+	code, rpt, stderr := e2eRun(t, srv, dir, later)
+	if code != 0 {
+		t.Fatalf("second run: exit code %d, stderr: %s", code, stderr)
+	}
+	if rpt == nil {
+		t.Fatalf("second run wrote no report, stderr: %s", stderr)
+	}
+
+	got := strings.Join(reportedActivities(rpt), "\n")
+	for _, want := range []string{"alice starred golang/go", "bob created_repo bob/tool"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report missing %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "someone/old") {
+		t.Errorf("report includes activity from before the last run:\n%s", got)
+	}
+	for _, ua := range rpt.UserActivities {
+		if ua.User == "alice" && ua.Name != "Alice Liddell" {
+			t.Errorf("alice's name = %q, want profile name", ua.Name)
+		}
+	}
+	if srv.RateLimit().Used == 0 {
+		t.Error("expected requests to count against the rate limit")
+	}
+}
+
+func TestE2E_Paginates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	srv := githubtest.NewServer(t)
+	var logins []string
+	for i := range 150 {
+		logins = append(logins, fmt.Sprintf("user%03d", i))
+	}
+	srv.Follow(logins...)
+
+	code, _, stderr := e2eRun(t, srv, dir, now)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr)
+	}
+
+	var followingPages, eventsRequests int
+	for _, r := range srv.Requests() {
+		switch {
+		case strings.HasPrefix(r, "/user/following"):
+			followingPages++
+		case strings.Contains(r, "/events"):
+			eventsRequests++
+		}
+	}
+	if followingPages != 2 {
+		t.Errorf("expected 2 pages of follows, got %d", followingPages)
+	}
+	if eventsRequests != len(logins) {
+		t.Errorf("expected events fetched for all %d follows, got %d", len(logins), eventsRequests)
+	}
+}
+
+func TestE2E_FailedUserMakesSnapshotPartial(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	srv := githubtest.NewServer(t)
+	srv.Follow("alice", "bob")
+	srv.Fail("/users/bob/events", http.StatusInternalServerError)
+
+	code, _, stderr := e2eRun(t, srv, dir, now)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, "bob") {
+		t.Errorf("expected a warning about bob, got: %s", stderr)
+	}
+
+	store, err := storage.Open(filepath.Join(dir, "gitstreams.db"))
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	snapshots, err := store.GetByUser(snapshotUserID, 1)
+	if err != nil {
+		t.Fatalf("loading snapshot: %v", err)
+	}
+	if len(snapshots) != 1 || !snapshots[0].Partial {
+		t.Errorf("expected a partial snapshot, got %+v", snapshots)
+	}
+}
+
+func TestE2E_RateLimitExhausted(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	srv := githubtest.NewServer(t)
+	srv.Follow("alice")
+	srv.SetRateLimit(githubtest.DefaultRateLimit, 0)
+
+	code, _, stderr := e2eRun(t, srv, dir, now)
+	if code == 0 {
+		t.Fatalf("expected failure with the rate limit used up, stderr: %s", stderr)
+	}
+	if !strings.Contains(stderr, "rate limit") {
+		t.Errorf("expected a rate limit error, got: %s", stderr)
+	}
+}
+
+func TestE2E_Watchlist(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	srv := githubtest.NewServer(t)
+	srv.AddUser(github.User{Login: "alice"})
+	srv.AddRepo(github.Repository{Name: "go", Owner: github.User{Login: "golang"}, StarCount: 100})
+	srv.AddReleases("golang/go", github.Release{TagName: "go1.25.0", Author: github.User{Login: "gopherbot"}, PublishedAt: now.Add(-time.Hour)})
+
+	code, _, stderr := e2eRun(t, srv, dir, now, "-watch-repo", "golang/go")
+	if code != 0 {
+		t.Fatalf("first run: exit code %d, stderr: %s", code, stderr)
+	}
+
+	// Between runs, golang/go gains stars, a release and a draft
+	later := now.Add(24 * time.Hour)
+	srv.AddRepo(github.Repository{Name: "go", Owner: github.User{Login: "golang"}, StarCount: 130})
+	srv.AddReleases("golang/go",
+		github.Release{TagName: "go1.26.0", Author: github.User{Login: "gopherbot"}, PublishedAt: later.Add(-time.Hour)},
+		github.Release{TagName: "go1.27.0", Draft: true},
+	)
+
+	code, rpt, stderr := e2eRun(t, srv, dir, later, "-watch-repo", "golang/go")
+	if code != 0 {
+		t.Fatalf("second run: exit code %d, stderr: %s", code, stderr)
+	}
+	if rpt == nil || len(rpt.Watched) != 1 {
+		t.Fatalf("second run report = %+v, want one watched repo; stderr: %s", rpt, stderr)
+	}
+	w := rpt.Watched[0]
+	if w.RepoName != "golang/go" || w.StarsGained != 30 {
+		t.Errorf("watched repo = %s gaining %d stars, want golang/go gaining 30", w.RepoName, w.StarsGained)
+	}
+	if len(w.Activities) != 1 || !strings.Contains(w.Activities[0].Details, "go1.26.0") {
+		t.Errorf("watched activities = %+v, want only the go1.26.0 release", w.Activities)
+	}
+}
+
+func TestE2E_Following(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	srv := githubtest.NewServer(t)
+	srv.Follow("alice")
+	srv.FollowFrom("alice", "bob")
+
+	code, _, stderr := e2eRun(t, srv, dir, now, "-following")
+	if code != 0 {
+		t.Fatalf("first run: exit code %d, stderr: %s", code, stderr)
+	}
+
+	// Between runs, alice follows carol too
+	srv.FollowFrom("alice", "carol")
+
+	code, rpt, stderr := e2eRun(t, srv, dir, now.Add(24*time.Hour), "-following")
+	if code != 0 {
+		t.Fatalf("second run: exit code %d, stderr: %s", code, stderr)
+	}
+	if rpt == nil || len(rpt.UserActivities) != 1 {
+		t.Fatalf("second run report = %+v, want alice's activity; stderr: %s", rpt, stderr)
+	}
+	activities := rpt.UserActivities[0].Activities
+	if len(activities) != 1 || activities[0].Type != report.ActivityFollowed || activities[0].RepoName != "carol" {
+		t.Errorf("activities = %+v, want alice following carol", activities)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/notify"
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+// defaultSMTPPort is the submission port, which -smtp uses when it's given
+// only a host.
+const defaultSMTPPort = "587"
+
+// validateEmail checks the settings for emailing the report, which
+// -email-to turns on.
+func (cfg *Config) validateEmail() error {
+	if _, err := mail.ParseAddressList(cfg.EmailTo); err != nil {
+		return fmt.Errorf("email-to: %w", err)
+	}
+	if cfg.SMTPAddr == "" {
+		return errors.New("-email-to needs an SMTP server to send through, given with -smtp")
+	}
+	if _, _, err := net.SplitHostPort(smtpAddr(cfg.SMTPAddr)); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	from := cfg.emailFrom()
+	if from == "" {
+		return errors.New("-email-to needs an address to send from, given with -email-from")
+	}
+	if _, err := mail.ParseAddress(from); err != nil {
+		return fmt.Errorf("email-from: %w", err)
+	}
+	if cfg.EmailLimit < 0 {
+		return fmt.Errorf("email-section-limit can't be negative, got %d", cfg.EmailLimit)
+	}
+	return nil
+}
+
+// emailFrom returns the address the report is emailed from: -email-from,
+// or else the SMTP username, which is usually an address.
+func (cfg *Config) emailFrom() string {
+	if cfg.EmailFrom != "" {
+		return cfg.EmailFrom
+	}
+	return cfg.SMTPUser
+}
+
+// smtpAddr returns addr as host:port, adding the default port if it's
+// just a host. Anything else is left for net.SplitHostPort to reject.
+func smtpAddr(addr string) string {
+	if strings.Contains(addr, ":") {
+		return addr
+	}
+	return net.JoinHostPort(addr, defaultSMTPPort)
+}
+
+// emailNotifier returns a notifier that emails cfg's recipients, dating
+// messages with now. cfg has been validated, so its addresses parse.
+func emailNotifier(cfg *Config, now func() time.Time) *notify.EmailNotifier {
+	list, _ := mail.ParseAddressList(cfg.EmailTo)
+	to := make([]string, 0, len(list))
+	for _, a := range list {
+		to = append(to, a.Address)
+	}
+	from, _ := mail.ParseAddress(cfg.emailFrom())
+	n := notify.NewEmailNotifier(smtpAddr(cfg.SMTPAddr), cfg.SMTPUser, cfg.SMTPPassword, from.Address, to)
+	n.Now = now
+	return n
+}
+
+// reportEmail returns rpt laid out to be emailed, as HTML and plain text,
+// if the report is emailed at all. Each section lists at most
+// -email-section-limit rows, so long reports aren't clipped by mail
+// clients. The email is the same whatever the report's format.
+func reportEmail(cfg *Config, rpt *report.Report) (html, text string, err error) {
+	if cfg.EmailTo == "" || rpt == nil {
+		return "", "", nil
+	}
+	html, err = rpt.EmailHTML(cfg.EmailLimit)
+	if err != nil {
+		return "", "", err
+	}
+	return html, rpt.EmailText(cfg.EmailLimit), nil
+}
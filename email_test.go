@@ -0,0 +1,87 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+func TestConfigValidateEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "from the smtp user", cfg: Config{EmailTo: "me@example.com", SMTPAddr: "smtp.example.com", SMTPUser: "me@example.com"}},
+		{name: "several, with names", cfg: Config{EmailTo: "Me <me@example.com>, team@example.com", SMTPAddr: "smtp.example.com:465", EmailFrom: "gitstreams@example.com"}},
+		{name: "bad recipient", cfg: Config{EmailTo: "me", SMTPAddr: "smtp.example.com", EmailFrom: "a@example.com"}, wantErr: "email-to"},
+		{name: "no server", cfg: Config{EmailTo: "me@example.com", EmailFrom: "a@example.com"}, wantErr: "-smtp"},
+		{name: "bad server", cfg: Config{EmailTo: "me@example.com", SMTPAddr: "smtp.example.com:25:25", EmailFrom: "a@example.com"}, wantErr: "smtp:"},
+		{name: "no sender", cfg: Config{EmailTo: "me@example.com", SMTPAddr: "smtp.example.com"}, wantErr: "-email-from"},
+		{name: "negative section limit", cfg: Config{EmailTo: "me@example.com", SMTPAddr: "smtp.example.com", EmailFrom: "a@example.com", EmailLimit: -1}, wantErr: "email-section-limit"},
+		{name: "username isn't an address", cfg: Config{EmailTo: "me@example.com", SMTPAddr: "smtp.example.com", SMTPUser: "apikey"}, wantErr: "email-from"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateEmail()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("validateEmail() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("validateEmail() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFlags_EmailFromEnvironment(t *testing.T) {
+	setHome(t)
+	t.Setenv("GITSTREAMS_EMAIL_TO", "me@example.com")
+	t.Setenv("GITSTREAMS_SMTP", "smtp.example.com")
+	t.Setenv("GITSTREAMS_SMTP_USER", "apikey")
+	t.Setenv("GITSTREAMS_SMTP_PASSWORD", "hunter2")
+
+	if _, err := parseFlags(nil); err == nil || !strings.Contains(err.Error(), "email-from") {
+		t.Fatalf("parseFlags() error = %v, want a missing sender", err)
+	}
+
+	t.Setenv("GITSTREAMS_EMAIL_FROM", "GitStreams <gitstreams@example.com>")
+	cfg, err := parseFlags(nil)
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	now := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	n := emailNotifier(cfg, func() time.Time { return now })
+	if n.Addr != "smtp.example.com:587" || n.Username != "apikey" || n.Password != "hunter2" {
+		t.Errorf("server = %s as %s:%s, want smtp.example.com:587 as apikey:hunter2", n.Addr, n.Username, n.Password)
+	}
+	if n.From != "gitstreams@example.com" || !slices.Equal(n.To, []string{"me@example.com"}) {
+		t.Errorf("from %s to %v, want the bare addresses", n.From, n.To)
+	}
+	if n.Now == nil || !n.Now().Equal(now) {
+		t.Error("expected messages to be dated with the run's clock")
+	}
+}
+
+func TestReportEmail(t *testing.T) {
+	rpt := &report.Report{UserActivities: []report.UserActivity{{User: "alice", Activities: []report.Activity{
+		{Type: report.ActivityStarred, User: "alice", RepoName: "golang/go"},
+		{Type: report.ActivityStarred, User: "alice", RepoName: "rust-lang/rust"},
+	}}}}
+
+	if html, text, err := reportEmail(&Config{}, rpt); err != nil || html != "" || text != "" {
+		t.Errorf("without email = %q, %q, %v, want nothing", html, text, err)
+	}
+	html, text, err := reportEmail(&Config{EmailTo: "me@example.com", EmailLimit: 1}, rpt)
+	if err != nil {
+		t.Fatalf("reportEmail() error = %v", err)
+	}
+	for name, body := range map[string]string{"html": html, "text": text} {
+		if !strings.Contains(body, "golang/go") || strings.Contains(body, "rust-lang/rust") || !strings.Contains(body, "1 more") {
+			t.Errorf("%s = %q, want the first star and a count of the rest", name, body)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// exportHeader names the columns of an export.
+var exportHeader = []string{"timestamp", "user", "type", "repo", "details"}
+
+// runExport handles the "export" subcommand: it writes the activity
+// recorded since a date as flat rows for spreadsheets and ad-hoc analysis.
+func runExport(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	format := fs.ownFormat("csv", "Output format (csv)")
+	since := fs.String("since", "30d", "Export activity since this date: YYYY-MM-DD or relative like 7d, 2w, 3m")
+	out := fs.String("o", "", "Where to write the export (default: stdout)")
+	profile := fs.String("profile", "", "Export a serve team profile's activity instead of your own")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if _, err := fs.resolve(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+
+	if *format != "csv" {
+		_, _ = fmt.Fprintf(stderr, "Error: unsupported format %q (supported: csv)\n", *format)
+		return 1
+	}
+	sinceDate, err := parseSinceDate(*since, deps.Now())
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: invalid -since: %v\n", err)
+		return 1
+	}
+	scope, err := activityScope(cfg, *profile)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Don't let the store create a database that isn't there
+	if _, err := os.Stat(dbFile(cfg.DBPath)); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: no database at %s; run gitstreams first\n", cfg.DBPath)
+		return 1
+	}
+	store, err := openStore(stderr, cfg, deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = store.Close() }()
+
+	as, ok := store.(activityStore)
+	if !ok {
+		_, _ = fmt.Fprintln(stderr, "Error: this database doesn't support exports")
+		return 1
+	}
+	if err := backfillActivities(store, scope, deps.Now()); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Warning: could not backfill activity: %v\n", err)
+	}
+	activities, err := as.ActivitiesSince(scope, sinceDate)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *out == "" {
+		if err := writeCSV(stdout, activities); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Error writing export: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	f, err := os.Create(*out) // #nosec G304 -- path is user-specified via flag
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error creating export: %v\n", err)
+		return 1
+	}
+	if err := writeCSV(f, activities); err != nil {
+		_ = f.Close()
+		_, _ = fmt.Fprintf(stderr, "Error writing export: %v\n", err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error writing export: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintf(stdout, "Exported %d activities to %s\n", len(activities), *out)
+	return 0
+}
+
+// activityScope returns the scope a serve team profile's activities are
+// kept under, or cfg's runs' if profile is empty.
+func activityScope(cfg *Config, profile string) (string, error) {
+	if profile == "" {
+		return runSnapshotID(cfg), nil
+	}
+	if !profileNamePattern.MatchString(profile) {
+		return "", fmt.Errorf("invalid profile name %q", profile)
+	}
+	return profileSnapshotPrefix + profile, nil
+}
+
+// writeCSV writes activities as CSV rows under exportHeader.
+func writeCSV(w io.Writer, activities []storage.Activity) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportHeader); err != nil {
+		return err
+	}
+	for _, a := range activities {
+		row := []string{
+			a.OccurredAt.UTC().Format(time.RFC3339),
+			a.Username,
+			exportType(a),
+			a.Repo,
+			a.Details,
+		}
+		for i := range row {
+			row[i] = spreadsheetSafe(row[i])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportType names an activity's type in an export: the report's names for
+// stars and new repos, and GitHub's event type for events.
+func exportType(a storage.Activity) string {
+	switch a.Kind {
+	case storage.KindStar:
+		return "starred"
+	case storage.KindRepo:
+		return "created_repo"
+	case storage.KindEvent:
+		return a.EventType
+	}
+	return a.Kind
+}
+
+// spreadsheetSafe keeps a cell from being read as a formula by spreadsheet
+// apps, since details like repo descriptions are written by strangers.
+func spreadsheetSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestRunExport(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := storage.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := fixedTime()
+	snap := diff.NewSnapshot(now)
+	snap.Users["alice"] = diff.UserActivity{
+		Username: "alice",
+		StarredRepos: []diff.Repo{
+			{Owner: "bob", Name: "calc", Description: "=HYPERLINK(\"http://evil.test\")", CreatedAt: now.AddDate(0, 0, -2)},
+			{Owner: "old", Name: "thing", CreatedAt: now.AddDate(0, 0, -60)},
+		},
+		Events: []diff.Event{{Type: "PushEvent", Repo: "alice/tool", CreatedAt: now.Add(-time.Hour)}},
+	}
+	if _, err := saveSnapshotAs(store, runSnapshotID(&Config{}), snap, now); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		Now:          fixedTime,
+	}
+	var stdout, stderr strings.Builder
+	if code := run(&stdout, &stderr, []string{"export", "--format", "csv", "--since", "30d", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(stdout.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("output isn't CSV: %v\n%s", err, stdout.String())
+	}
+	want := [][]string{
+		exportHeader,
+		{"2024-01-13T10:00:00Z", "alice", "starred", "bob/calc", `'=HYPERLINK("http://evil.test")`},
+		{"2024-01-15T09:00:00Z", "alice", "PushEvent", "alice/tool", ""},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %v", len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+
+	// To a file
+	out := filepath.Join(t.TempDir(), "activity.csv")
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"export", "-since", "90d", "-o", out, "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "\n"); got != 4 {
+		t.Errorf("expected a header and 3 rows, got %d lines", got)
+	}
+	if !strings.Contains(stdout.String(), "Exported 3 activities") {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+}
+
+func TestRunExport_Errors(t *testing.T) {
+	setHome(t)
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		Now:          fixedTime,
+	}
+	missing := filepath.Join(t.TempDir(), "missing.db")
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-format", "xlsx"}, "unsupported format"},
+		{[]string{"-since", "whenever"}, "invalid -since"},
+		{[]string{"-profile", "../x"}, "invalid profile name"},
+		{nil, "no database"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr strings.Builder
+		args := append([]string{"export", "-db", missing}, tt.args...)
+		if code := run(&stdout, &stderr, args, deps); code != 1 || !strings.Contains(stderr.String(), tt.wantErr) {
+			t.Errorf("export %v: code %d, stderr %q; want %q", tt.args, code, stderr.String(), tt.wantErr)
+		}
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Error("export created a database")
+	}
+}
+
+func TestSpreadsheetSafe(t *testing.T) {
+	tests := map[string]string{
+		"":           "",
+		"golang/go":  "golang/go",
+		"=1+2":       "'=1+2",
+		"+cmd":       "'+cmd",
+		"-2":         "'-2",
+		"@SUM(A1)":   "'@SUM(A1)",
+		"a = b":      "a = b",
+		"2024-01-13": "2024-01-13",
+	}
+	for in, want := range tests {
+		if got := spreadsheetSafe(in); got != want {
+			t.Errorf("spreadsheetSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
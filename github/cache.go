@@ -0,0 +1,147 @@
+package github
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Default bounds for the ETag response cache. A long daemon session caches one
+// entry per paginated URL per followed user, so the cache must not grow forever.
+const (
+	defaultCacheMaxEntries = 1000
+	defaultCacheTTL        = 24 * time.Hour
+)
+
+// CacheStats reports ETag cache usage counters.
+type CacheStats struct {
+	Hits      int64 // Lookups that found a live entry
+	Misses    int64 // Lookups that found nothing, or only an expired entry
+	Evictions int64 // Entries dropped for capacity or expiry
+	Entries   int   // Entries currently held
+}
+
+// cacheEntry stores a cached API response with its ETag.
+type cacheEntry struct {
+	timestamp time.Time
+	etag      string
+	data      []byte
+}
+
+// etagCache is an LRU cache of API responses keyed by request path.
+// Entries older than ttl are treated as absent. A non-positive maxEntries
+// or ttl disables that bound.
+type etagCache struct {
+	now        func() time.Time
+	entries    map[string]*list.Element
+	order      *list.List // front is most recently used
+	stats      CacheStats
+	ttl        time.Duration
+	maxEntries int
+	mu         sync.Mutex
+}
+
+// lruItem is the value stored in each list element.
+type lruItem struct {
+	entry *cacheEntry
+	key   string
+}
+
+func newETagCache(maxEntries int, ttl time.Duration) *etagCache {
+	return &etagCache{
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+// get returns the live entry for key, or nil if there is none.
+func (c *etagCache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+
+	item, _ := elem.Value.(*lruItem)
+	if c.expired(item.entry) {
+		c.remove(elem)
+		c.stats.Evictions++
+		c.stats.Misses++
+		return nil
+	}
+
+	c.order.MoveToFront(elem)
+	c.stats.Hits++
+	return item.entry
+}
+
+// has reports whether key has a live entry. Unlike get it leaves the
+// counters and recency alone: it's for planning, not a lookup.
+func (c *etagCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	item, _ := elem.Value.(*lruItem)
+	return !c.expired(item.entry)
+}
+
+// put stores entry under key, stamping it with the current time and evicting
+// the least recently used entries if the cache is over capacity.
+func (c *etagCache) put(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.timestamp = c.now()
+
+	if elem, ok := c.entries[key]; ok {
+		item, _ := elem.Value.(*lruItem)
+		item.entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// clear drops all entries. Counters are preserved.
+func (c *etagCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// snapshot returns a copy of the current counters.
+func (c *etagCache) snapshot() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
+
+func (c *etagCache) expired(e *cacheEntry) bool {
+	return c.ttl > 0 && c.now().Sub(e.timestamp) > c.ttl
+}
+
+func (c *etagCache) remove(elem *list.Element) {
+	item, _ := c.order.Remove(elem).(*lruItem)
+	delete(c.entries, item.key)
+}
@@ -0,0 +1,159 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestETagCacheLRUEviction(t *testing.T) {
+	c := newETagCache(2, 0)
+
+	c.put("/a", &cacheEntry{etag: "a"})
+	c.put("/b", &cacheEntry{etag: "b"})
+
+	// Touch /a so /b becomes the least recently used entry
+	if c.get("/a") == nil {
+		t.Fatal("expected /a to be cached")
+	}
+
+	c.put("/c", &cacheEntry{etag: "c"})
+
+	if c.get("/b") != nil {
+		t.Error("expected /b to be evicted")
+	}
+	if c.get("/a") == nil {
+		t.Error("expected /a to survive eviction")
+	}
+	if c.get("/c") == nil {
+		t.Error("expected /c to be cached")
+	}
+
+	stats := c.snapshot()
+	if stats.Entries != 2 {
+		t.Errorf("expected 2 entries, got %d", stats.Entries)
+	}
+	if stats.Evictions != 1 {
+		t.Errorf("expected 1 eviction, got %d", stats.Evictions)
+	}
+}
+
+func TestETagCacheTTL(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	c := newETagCache(0, time.Hour)
+	c.now = func() time.Time { return now }
+
+	c.put("/a", &cacheEntry{etag: "a"})
+
+	now = now.Add(30 * time.Minute)
+	if c.get("/a") == nil {
+		t.Fatal("expected entry within TTL to be returned")
+	}
+
+	now = now.Add(time.Hour)
+	if c.get("/a") != nil {
+		t.Error("expected expired entry to be dropped")
+	}
+
+	stats := c.snapshot()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got %+v", stats)
+	}
+	if stats.Evictions != 1 {
+		t.Errorf("expected expiry to count as an eviction, got %d", stats.Evictions)
+	}
+	if stats.Entries != 0 {
+		t.Errorf("expected 0 entries, got %d", stats.Entries)
+	}
+}
+
+func TestClientSetNow(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	c := NewClient("token", WithCacheLimits(0, time.Hour))
+	c.SetNow(func() time.Time { return now })
+
+	c.cache.put("/a", &cacheEntry{etag: "a"})
+	now = now.Add(2 * time.Hour)
+	if c.cache.get("/a") != nil {
+		t.Error("expected the entry to expire by the client's clock")
+	}
+}
+
+func TestETagCacheUpdateExisting(t *testing.T) {
+	c := newETagCache(1, 0)
+
+	c.put("/a", &cacheEntry{etag: "v1"})
+	c.put("/a", &cacheEntry{etag: "v2"})
+
+	entry := c.get("/a")
+	if entry == nil || entry.etag != "v2" {
+		t.Errorf("expected updated entry, got %+v", entry)
+	}
+	if stats := c.snapshot(); stats.Evictions != 0 {
+		t.Errorf("updating an entry should not evict, got %d evictions", stats.Evictions)
+	}
+}
+
+func TestETagCacheClearKeepsCounters(t *testing.T) {
+	c := newETagCache(0, 0)
+	c.put("/a", &cacheEntry{etag: "a"})
+	c.get("/a")
+
+	c.clear()
+
+	stats := c.snapshot()
+	if stats.Entries != 0 {
+		t.Errorf("expected 0 entries after clear, got %d", stats.Entries)
+	}
+	if stats.Hits != 1 {
+		t.Errorf("expected hit counter to survive clear, got %d", stats.Hits)
+	}
+}
+
+func TestGetCacheStats(t *testing.T) {
+	etag := `"stats-test"`
+	users := []User{{Login: "user1", ID: 1}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		if err := json.NewEncoder(w).Encode(users); err != nil {
+			t.Fatalf("encoding response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetFollowedUsers(context.Background()); err != nil {
+			t.Fatalf("GetFollowedUsers() error: %v", err)
+		}
+	}
+
+	stats := c.GetCacheStats()
+	if stats.Misses != 1 {
+		t.Errorf("expected 1 miss, got %d", stats.Misses)
+	}
+	if stats.Hits != 2 {
+		t.Errorf("expected 2 hits, got %d", stats.Hits)
+	}
+	if stats.Entries != 1 {
+		t.Errorf("expected 1 entry, got %d", stats.Entries)
+	}
+}
+
+func TestWithCacheLimits(t *testing.T) {
+	c := NewClient("token", WithCacheLimits(5, time.Minute))
+	if c.cache.maxEntries != 5 {
+		t.Errorf("expected maxEntries 5, got %d", c.cache.maxEntries)
+	}
+	if c.cache.ttl != time.Minute {
+		t.Errorf("expected ttl 1m, got %v", c.cache.ttl)
+	}
+}
@@ -2,16 +2,20 @@
 package github
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -21,17 +25,23 @@ import (
 
 const defaultBaseURL = "https://api.github.com"
 
+// defaultMaxResponseSize caps how much of a single API response body the
+// client will read. GitHub list pages top out at a few MB, so anything beyond
+// this is treated as pathological rather than buffered into memory.
+const defaultMaxResponseSize = 32 << 20
+
+// maxErrorBodySize caps how much of an error response body is included in
+// the returned error message.
+const maxErrorBodySize = 64 << 10
+
+// ErrResponseTooLarge is returned when a response body exceeds the client's
+// maximum response size.
+var ErrResponseTooLarge = errors.New("response body exceeds maximum size")
+
 // rateLimitWarningThreshold is the number of remaining requests below which
 // a warning will be logged.
 const rateLimitWarningThreshold = 100
 
-// cacheEntry stores a cached API response with its ETag.
-type cacheEntry struct {
-	timestamp time.Time
-	etag      string
-	data      []byte
-}
-
 // RateLimit contains GitHub API rate limit information.
 type RateLimit struct {
 	Reset     time.Time
@@ -40,18 +50,31 @@ type RateLimit struct {
 	Used      int
 }
 
+// RequestStats counts API requests made by a client, broken down by endpoint
+// class (e.g. "following", "starred", "repos", "events").
+type RequestStats struct {
+	ByEndpoint  map[string]int // First-page requests per endpoint class
+	Pagination  int            // Requests for pages beyond the first
+	NotModified int            // Requests answered with 304 Not Modified
+	Total       int
+}
+
 // Client is a GitHub API client.
 type Client struct {
-	httpClient  *http.Client
-	logger      *slog.Logger
-	cache       map[string]*cacheEntry
-	repoCache   map[string]*Repository // Application-level repo cache (key: "owner/repo")
-	rateLimit   *RateLimit
-	baseURL     string
-	token       string
-	cacheMu     sync.RWMutex
-	repoCacheMu sync.RWMutex
-	rateLimitMu sync.RWMutex
+	httpClient      *http.Client
+	logger          *slog.Logger
+	cache           *etagCache
+	repoCache       map[string]*Repository // Application-level repo cache (key: "owner/repo")
+	now             func() time.Time       // Ages cache entries and times rate limit resets
+	rateLimit       *RateLimit
+	requestStats    RequestStats
+	baseURL         string
+	token           string
+	maxResponseSize int64 // Largest response body (bytes) the client will read
+	repoCacheMu     sync.RWMutex
+	rateLimitMu     sync.RWMutex
+	requestStatsMu  sync.Mutex
+	maxPages        atomic.Int32 // Pagination depth cap; 0 means unlimited
 }
 
 // User represents a GitHub user.
@@ -61,6 +84,7 @@ type User struct {
 	HTMLURL   string `json:"html_url"`
 	Name      string `json:"name"`
 	Bio       string `json:"bio"`
+	Company   string `json:"company"`
 	ID        int64  `json:"id"`
 }
 
@@ -74,6 +98,7 @@ type Repository struct {
 	HTMLURL     string    `json:"html_url"`
 	Language    string    `json:"language"`
 	Owner       User      `json:"owner"`
+	Topics      []string  `json:"topics"`
 	ID          int64     `json:"id"`
 	StarCount   int       `json:"stargazers_count"`
 	ForkCount   int       `json:"forks_count"`
@@ -90,6 +115,17 @@ type Event struct {
 	Repo      EventRepo       `json:"repo"`
 }
 
+// Release represents a published GitHub release.
+type Release struct {
+	PublishedAt time.Time `json:"published_at"`
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	HTMLURL     string    `json:"html_url"`
+	Author      User      `json:"author"`
+	Prerelease  bool      `json:"prerelease"`
+	Draft       bool      `json:"draft"`
+}
+
 // EventRepo is a minimal repo representation in events.
 type EventRepo struct {
 	Name string `json:"name"`
@@ -114,6 +150,25 @@ func WithBaseURL(url string) Option {
 	}
 }
 
+// WithMaxResponseSize limits how many bytes of a response body the client
+// will read before failing with ErrResponseTooLarge. Non-positive values are
+// ignored.
+func WithMaxResponseSize(n int64) Option {
+	return func(client *Client) {
+		if n > 0 {
+			client.maxResponseSize = n
+		}
+	}
+}
+
+// WithCacheLimits bounds the ETag response cache to at most maxEntries
+// entries, each kept for at most ttl. A non-positive value disables that bound.
+func WithCacheLimits(maxEntries int, ttl time.Duration) Option {
+	return func(client *Client) {
+		client.cache = newETagCache(maxEntries, ttl)
+	}
+}
+
 // WithLogger sets a custom logger for the client.
 func WithLogger(l *slog.Logger) Option {
 	return func(client *Client) {
@@ -124,16 +179,19 @@ func WithLogger(l *slog.Logger) Option {
 // NewClient creates a new GitHub API client.
 func NewClient(token string, opts ...Option) *Client {
 	c := &Client{
-		httpClient: &http.Client{Timeout: 30 * time.Second},
-		baseURL:    defaultBaseURL,
-		token:      token,
-		logger:     slog.Default(),
-		cache:      make(map[string]*cacheEntry),
-		repoCache:  make(map[string]*Repository),
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		baseURL:         defaultBaseURL,
+		token:           token,
+		logger:          slog.Default(),
+		cache:           newETagCache(defaultCacheMaxEntries, defaultCacheTTL),
+		repoCache:       make(map[string]*Repository),
+		maxResponseSize: defaultMaxResponseSize,
+		now:             time.Now,
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.cache.now = c.now
 	return c
 }
 
@@ -150,11 +208,148 @@ func (c *Client) GetRateLimit() *RateLimit {
 	return &rl
 }
 
+// SetMaxPages caps how many pages paginated endpoints fetch. Zero or a
+// negative value removes the cap. This lets callers trade completeness for
+// API budget when the rate limit is running low.
+func (c *Client) SetMaxPages(n int) {
+	if n < 0 {
+		n = 0
+	}
+	c.maxPages.Store(int32(n)) // #nosec G115 -- page counts are small
+}
+
+// SetNow sets the clock the client ages cached responses by and times rate
+// limit resets from. It defaults to time.Now.
+func (c *Client) SetNow(now func() time.Time) {
+	c.now = now
+	c.cache.now = now
+}
+
+// FetchRateLimit queries the current core API rate limit. Calls to the
+// rate_limit endpoint don't count against the limit.
+func (c *Client) FetchRateLimit(ctx context.Context) (*RateLimit, error) {
+	var resp struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+				Used      int   `json:"used"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err := c.get(ctx, "/rate_limit", &resp); err != nil {
+		return nil, fmt.Errorf("fetching rate limit: %w", err)
+	}
+
+	core := resp.Resources.Core
+	rl := &RateLimit{
+		Limit:     core.Limit,
+		Remaining: core.Remaining,
+		Reset:     time.Unix(core.Reset, 0),
+		Used:      core.Used,
+	}
+
+	c.rateLimitMu.Lock()
+	c.rateLimit = rl
+	c.rateLimitMu.Unlock()
+
+	copied := *rl
+	return &copied, nil
+}
+
+// GetRequestStats returns a copy of the per-endpoint request counters.
+func (c *Client) GetRequestStats() RequestStats {
+	c.requestStatsMu.Lock()
+	defer c.requestStatsMu.Unlock()
+
+	stats := c.requestStats
+	stats.ByEndpoint = make(map[string]int, len(c.requestStats.ByEndpoint))
+	for k, v := range c.requestStats.ByEndpoint {
+		stats.ByEndpoint[k] = v
+	}
+	return stats
+}
+
+// ResetRequestStats zeroes the per-endpoint request counters.
+func (c *Client) ResetRequestStats() {
+	c.requestStatsMu.Lock()
+	defer c.requestStatsMu.Unlock()
+	c.requestStats = RequestStats{}
+}
+
+// recordRequest counts a request against its endpoint class, or against
+// pagination overhead if it fetched a page beyond the first.
+func (c *Client) recordRequest(path string, notModified bool) {
+	c.requestStatsMu.Lock()
+	defer c.requestStatsMu.Unlock()
+
+	c.requestStats.Total++
+	if notModified {
+		c.requestStats.NotModified++
+	}
+	if pageNumber(path) > 1 {
+		c.requestStats.Pagination++
+		return
+	}
+	if c.requestStats.ByEndpoint == nil {
+		c.requestStats.ByEndpoint = make(map[string]int)
+	}
+	c.requestStats.ByEndpoint[endpointClass(path)]++
+}
+
+// endpointClass maps an API path to a coarse endpoint class for accounting.
+func endpointClass(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	last := segments[len(segments)-1]
+
+	switch {
+	case last == "following":
+		return "following"
+	case last == "starred":
+		return "starred"
+	case last == "repos":
+		return "repos"
+	case last == "events" || last == "received_events":
+		return "events"
+	case segments[0] == "repos":
+		return "repository"
+	case segments[0] == "rate_limit":
+		return "rate_limit"
+	case segments[0] == "graphql":
+		return "graphql"
+	default:
+		return "other"
+	}
+}
+
+// pageNumber extracts the page query parameter from a path, defaulting to 1.
+func pageNumber(path string) int {
+	i := strings.IndexByte(path, '?')
+	if i < 0 {
+		return 1
+	}
+	for _, param := range strings.Split(path[i+1:], "&") {
+		if v, ok := strings.CutPrefix(param, "page="); ok {
+			if n, err := strconv.Atoi(v); err == nil {
+				return n
+			}
+		}
+	}
+	return 1
+}
+
 // ClearCache clears the ETag cache.
 func (c *Client) ClearCache() {
-	c.cacheMu.Lock()
-	defer c.cacheMu.Unlock()
-	c.cache = make(map[string]*cacheEntry)
+	c.cache.clear()
+}
+
+// GetCacheStats returns hit, miss, and eviction counters for the ETag cache.
+func (c *Client) GetCacheStats() CacheStats {
+	return c.cache.snapshot()
 }
 
 // ClearRepoCache clears the repository cache.
@@ -164,7 +359,21 @@ func (c *Client) ClearRepoCache() {
 	c.repoCache = make(map[string]*Repository)
 }
 
+// get fetches a single JSON document and decodes it into result.
 func (c *Client) get(ctx context.Context, path string, result any) error {
+	return c.fetch(ctx, path, func(r io.Reader) error {
+		if result == nil {
+			return nil
+		}
+		return json.NewDecoder(r).Decode(result)
+	})
+}
+
+// fetch performs a GET request against path and passes the response body to
+// decode. The body is capped at the client's maximum response size, and
+// successful responses carrying an ETag are recorded for conditional requests.
+// On a 304 Not Modified the cached body is replayed through decode instead.
+func (c *Client) fetch(ctx context.Context, path string, decode func(io.Reader) error) error {
 	url := c.baseURL + path
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -174,14 +383,15 @@ func (c *Client) get(ctx context.Context, path string, result any) error {
 
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	// Setting Accept-Encoding ourselves disables net/http's transparent gzip
+	// handling, so decompression happens in decompressBody below.
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
 	if c.token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.token)
 	}
 
 	// Check cache for ETag and add If-None-Match header
-	c.cacheMu.RLock()
-	cached := c.cache[path]
-	c.cacheMu.RUnlock()
+	cached := c.cache.get(path)
 	if cached != nil && cached.etag != "" {
 		req.Header.Set("If-None-Match", cached.etag)
 	}
@@ -194,6 +404,7 @@ func (c *Client) get(ctx context.Context, path string, result any) error {
 
 	// Parse and store rate limit headers
 	c.parseRateLimitHeaders(resp)
+	c.recordRequest(path, resp.StatusCode == http.StatusNotModified)
 
 	// Handle 304 Not Modified - return cached data
 	if resp.StatusCode == http.StatusNotModified && cached != nil {
@@ -201,47 +412,132 @@ func (c *Client) get(ctx context.Context, path string, result any) error {
 			"path", path,
 			"etag", cached.etag,
 		)
-		if result != nil {
-			if unmarshalErr := json.Unmarshal(cached.data, result); unmarshalErr != nil {
-				return fmt.Errorf("decoding cached response: %w", unmarshalErr)
-			}
+		if decodeErr := decode(bytes.NewReader(cached.data)); decodeErr != nil {
+			return fmt.Errorf("decoding cached response: %w", decodeErr)
 		}
 		return nil
 	}
 
+	decompressed, err := decompressBody(resp)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
+		var body []byte
+		if err == nil {
+			body, _ = io.ReadAll(io.LimitReader(decompressed, maxErrorBodySize))
+			_ = decompressed.Close()
+		}
 		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("reading response: %w", err)
+		return fmt.Errorf("decompressing response: %w", err)
 	}
+	defer func() { _ = decompressed.Close() }()
 
-	// Store ETag and response in cache if we got an ETag
-	if etag := resp.Header.Get("ETag"); etag != "" {
-		c.cacheMu.Lock()
-		c.cache[path] = &cacheEntry{
-			etag:      etag,
-			data:      body,
-			timestamp: time.Now(),
+	// The size limit applies after decompression so a small compressed
+	// payload can't expand into an unbounded amount of memory.
+	body := io.Reader(&limitedReader{r: decompressed, remaining: c.maxResponseSize})
+
+	// Keep a copy of the raw body only if we'll need it for the ETag cache
+	etag := resp.Header.Get("ETag")
+	var raw *bytes.Buffer
+	if etag != "" {
+		raw = &bytes.Buffer{}
+		body = io.TeeReader(body, raw)
+	}
+
+	if err := decode(body); err != nil {
+		if errors.Is(err, ErrResponseTooLarge) {
+			return fmt.Errorf("reading response for %s: %w", path, err)
 		}
-		c.cacheMu.Unlock()
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	// Store ETag and response in cache if we got an ETag
+	if raw != nil {
+		c.cache.put(path, &cacheEntry{
+			etag: etag,
+			data: raw.Bytes(),
+		})
 		c.logger.Debug("cached response",
 			"path", path,
 			"etag", etag,
 		)
 	}
 
-	if result != nil {
-		if err := json.Unmarshal(body, result); err != nil {
-			return fmt.Errorf("decoding response: %w", err)
+	return nil
+}
+
+// decompressBody wraps the response body in a decompressor matching its
+// Content-Encoding. Unencoded bodies are returned as-is.
+func decompressBody(resp *http.Response) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		return zlib.NewReader(resp.Body)
+	case "", "identity":
+		return io.NopCloser(resp.Body), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+// decodeList decodes a JSON array one element at a time, so only a single
+// element is buffered by the decoder at once rather than the whole page.
+// A JSON null decodes to a nil slice.
+func decodeList[T any](r io.Reader) ([]T, error) {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return nil, nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected JSON array, got %v", tok)
+	}
+
+	var items []T
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return nil, err
 		}
+		items = append(items, item)
 	}
 
-	return nil
+	// Consume the closing bracket so truncated bodies are reported
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// limitedReader reads from r until remaining bytes are exhausted, then fails
+// with ErrResponseTooLarge instead of silently truncating like io.LimitReader.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Probe for one more byte to distinguish "exactly at the limit" from "over it"
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
 }
 
 // parseRateLimitHeaders extracts rate limit information from response headers.
@@ -291,39 +587,39 @@ func (c *Client) parseRateLimitHeaders(resp *http.Response) {
 				"remaining", rl.Remaining,
 				"limit", rl.Limit,
 				"reset", rl.Reset,
-				"reset_in", time.Until(rl.Reset).Round(time.Second),
+				"reset_in", rl.Reset.Sub(c.now()).Round(time.Second),
 			)
 		}
 	}
 }
 
-// getPaginated fetches all pages of results for a given path.
+// perPage is how many results paginate asks for per page, GitHub's maximum.
+const perPage = 100
+
+// pagePath returns the path of one page of the listing at basePath.
+func pagePath(basePath string, page int) string {
+	separator := "?"
+	if strings.Contains(basePath, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%spage=%d&per_page=%d", basePath, separator, page, perPage)
+}
+
+// paginate fetches all pages of results for a given path.
 // It handles GitHub's pagination by requesting 100 items per page until
 // no more results are returned.
-func (c *Client) getPaginated(ctx context.Context, basePath string, result any) error {
+func paginate[T any](ctx context.Context, c *Client, basePath string) ([]T, error) {
 	tracer := otel.Tracer("gitstreams")
-	ctx, span := tracer.Start(ctx, "github.getPaginated",
+	ctx, span := tracer.Start(ctx, "github.paginate",
 		trace.WithAttributes(attribute.String("path", basePath)))
 	defer span.End()
 
-	// Use reflection to work with any slice type
-	resultVal := reflect.ValueOf(result)
-	if resultVal.Kind() != reflect.Ptr || resultVal.Elem().Kind() != reflect.Slice {
-		return fmt.Errorf("result must be a pointer to a slice")
-	}
-
-	sliceVal := resultVal.Elem()
+	var results []T
 
 	page := 1
-	perPage := 100 // GitHub's maximum per_page value
 
 	for {
-		// Build path with pagination parameters
-		separator := "?"
-		if strings.Contains(basePath, "?") {
-			separator = "&"
-		}
-		path := fmt.Sprintf("%s%spage=%d&per_page=%d", basePath, separator, page, perPage)
+		path := pagePath(basePath, page)
 
 		// Create a span for this page
 		_, pageSpan := tracer.Start(ctx, "github.fetchPage",
@@ -331,51 +627,59 @@ func (c *Client) getPaginated(ctx context.Context, basePath string, result any)
 				attribute.String("path", path),
 				attribute.Int("page", page)))
 
-		// Create a new slice to hold this page's results
-		pageResult := reflect.New(sliceVal.Type()).Interface()
-
-		if err := c.get(ctx, path, pageResult); err != nil {
+		var pageResults []T
+		err := c.fetch(ctx, path, func(r io.Reader) error {
+			var decodeErr error
+			pageResults, decodeErr = decodeList[T](r)
+			return decodeErr
+		})
+		if err != nil {
 			pageSpan.RecordError(err)
 			pageSpan.End()
 			span.RecordError(err)
-			return err
+			return nil, err
 		}
 
-		// Get the slice value from the pointer
-		pageSlice := reflect.ValueOf(pageResult).Elem()
-
-		pageSpan.SetAttributes(attribute.Int("results", pageSlice.Len()))
+		pageSpan.SetAttributes(attribute.Int("results", len(pageResults)))
 		pageSpan.End()
 
 		// If we got no results, we're done
-		if pageSlice.Len() == 0 {
+		if len(pageResults) == 0 {
 			break
 		}
 
-		// Append this page's results to the total
-		sliceVal = reflect.AppendSlice(sliceVal, pageSlice)
+		// Append this page's results to the total. The first page is adopted
+		// as-is to avoid copying single-page result sets.
+		if results == nil {
+			results = pageResults
+		} else {
+			results = append(results, pageResults...)
+		}
 
 		// If we got fewer results than per_page, this is the last page
-		if pageSlice.Len() < perPage {
+		if len(pageResults) < perPage {
+			break
+		}
+
+		// Stop early if the caller capped pagination depth
+		if maxPages := int(c.maxPages.Load()); maxPages > 0 && page >= maxPages {
 			break
 		}
 
 		page++
 	}
 
-	// Set the final result
-	resultVal.Elem().Set(sliceVal)
 	span.SetAttributes(
 		attribute.Int("total_pages", page),
-		attribute.Int("total_results", sliceVal.Len()))
-	return nil
+		attribute.Int("total_results", len(results)))
+	return results, nil
 }
 
 // GetFollowedUsers returns the users that the authenticated user follows.
 // This method automatically handles pagination to fetch all followed users.
 func (c *Client) GetFollowedUsers(ctx context.Context) ([]User, error) {
-	var users []User
-	if err := c.getPaginated(ctx, "/user/following", &users); err != nil {
+	users, err := paginate[User](ctx, c, "/user/following")
+	if err != nil {
 		return nil, fmt.Errorf("fetching followed users: %w", err)
 	}
 	return users, nil
@@ -384,20 +688,58 @@ func (c *Client) GetFollowedUsers(ctx context.Context) ([]User, error) {
 // GetFollowedUsersByUsername returns the users that a specific user follows.
 // This method automatically handles pagination to fetch all followed users.
 func (c *Client) GetFollowedUsersByUsername(ctx context.Context, username string) ([]User, error) {
-	var users []User
 	path := fmt.Sprintf("/users/%s/following", username)
-	if err := c.getPaginated(ctx, path, &users); err != nil {
+	users, err := paginate[User](ctx, c, path)
+	if err != nil {
 		return nil, fmt.Errorf("fetching users followed by %s: %w", username, err)
 	}
 	return users, nil
 }
 
+// Team is a team in a GitHub organization.
+type Team struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// GetOrgMembers returns the members of an organization. Unless the token
+// belongs to a member, that's only those who made their membership public.
+func (c *Client) GetOrgMembers(ctx context.Context, org string) ([]User, error) {
+	path := fmt.Sprintf("/orgs/%s/members", org)
+	users, err := paginate[User](ctx, c, path)
+	if err != nil {
+		return nil, fmt.Errorf("fetching members of %s: %w", org, err)
+	}
+	return users, nil
+}
+
+// GetOrgTeams returns the teams in an organization the token can see.
+func (c *Client) GetOrgTeams(ctx context.Context, org string) ([]Team, error) {
+	path := fmt.Sprintf("/orgs/%s/teams", org)
+	teams, err := paginate[Team](ctx, c, path)
+	if err != nil {
+		return nil, fmt.Errorf("fetching teams of %s: %w", org, err)
+	}
+	return teams, nil
+}
+
+// GetTeamMembers returns the members of an organization's team, by its
+// slug, including those of its child teams.
+func (c *Client) GetTeamMembers(ctx context.Context, org, team string) ([]User, error) {
+	path := fmt.Sprintf("/orgs/%s/teams/%s/members", org, team)
+	users, err := paginate[User](ctx, c, path)
+	if err != nil {
+		return nil, fmt.Errorf("fetching members of %s/%s: %w", org, team, err)
+	}
+	return users, nil
+}
+
 // GetStarredRepos returns repositories starred by the authenticated user.
 // This method automatically handles pagination to fetch all starred repos.
 // Repositories are cached in memory to avoid redundant API calls.
 func (c *Client) GetStarredRepos(ctx context.Context) ([]Repository, error) {
-	var repos []Repository
-	if err := c.getPaginated(ctx, "/user/starred", &repos); err != nil {
+	repos, err := paginate[Repository](ctx, c, "/user/starred")
+	if err != nil {
 		return nil, fmt.Errorf("fetching starred repos: %w", err)
 	}
 	// Cache all fetched repositories
@@ -407,13 +749,32 @@ func (c *Client) GetStarredRepos(ctx context.Context) ([]Repository, error) {
 	return repos, nil
 }
 
+// StarredCached reports whether the first page of username's starred repos
+// has an ETag cached. Asking for it again then costs nothing if it hasn't
+// changed: GitHub doesn't count 304s against the rate limit.
+func (c *Client) StarredCached(username string) bool {
+	return c.cache.has(pagePath(starredPath(username), 1))
+}
+
+// OwnedCached is StarredCached for username's owned repos.
+func (c *Client) OwnedCached(username string) bool {
+	return c.cache.has(pagePath(ownedPath(username), 1))
+}
+
+func starredPath(username string) string {
+	return fmt.Sprintf("/users/%s/starred", username)
+}
+
+func ownedPath(username string) string {
+	return fmt.Sprintf("/users/%s/repos?type=owner", username)
+}
+
 // GetStarredReposByUsername returns repositories starred by a specific user.
 // This method automatically handles pagination to fetch all starred repos.
 // Repositories are cached in memory to avoid redundant API calls.
 func (c *Client) GetStarredReposByUsername(ctx context.Context, username string) ([]Repository, error) {
-	var repos []Repository
-	path := fmt.Sprintf("/users/%s/starred", username)
-	if err := c.getPaginated(ctx, path, &repos); err != nil {
+	repos, err := paginate[Repository](ctx, c, starredPath(username))
+	if err != nil {
 		return nil, fmt.Errorf("fetching repos starred by %s: %w", username, err)
 	}
 	// Cache all fetched repositories
@@ -427,8 +788,8 @@ func (c *Client) GetStarredReposByUsername(ctx context.Context, username string)
 // This method automatically handles pagination to fetch all owned repos.
 // Repositories are cached in memory to avoid redundant API calls.
 func (c *Client) GetOwnedRepos(ctx context.Context) ([]Repository, error) {
-	var repos []Repository
-	if err := c.getPaginated(ctx, "/user/repos?type=owner", &repos); err != nil {
+	repos, err := paginate[Repository](ctx, c, "/user/repos?type=owner")
+	if err != nil {
 		return nil, fmt.Errorf("fetching owned repos: %w", err)
 	}
 	// Cache all fetched repositories
@@ -442,9 +803,8 @@ func (c *Client) GetOwnedRepos(ctx context.Context) ([]Repository, error) {
 // This method automatically handles pagination to fetch all owned repos.
 // Repositories are cached in memory to avoid redundant API calls.
 func (c *Client) GetOwnedReposByUsername(ctx context.Context, username string) ([]Repository, error) {
-	var repos []Repository
-	path := fmt.Sprintf("/users/%s/repos?type=owner", username)
-	if err := c.getPaginated(ctx, path, &repos); err != nil {
+	repos, err := paginate[Repository](ctx, c, ownedPath(username))
+	if err != nil {
 		return nil, fmt.Errorf("fetching repos owned by %s: %w", username, err)
 	}
 	// Cache all fetched repositories
@@ -457,9 +817,9 @@ func (c *Client) GetOwnedReposByUsername(ctx context.Context, username string) (
 // GetRecentEvents returns recent events for the authenticated user.
 // This method automatically handles pagination to fetch all recent events.
 func (c *Client) GetRecentEvents(ctx context.Context, username string) ([]Event, error) {
-	var events []Event
 	path := fmt.Sprintf("/users/%s/events", username)
-	if err := c.getPaginated(ctx, path, &events); err != nil {
+	events, err := paginate[Event](ctx, c, path)
+	if err != nil {
 		return nil, fmt.Errorf("fetching events for %s: %w", username, err)
 	}
 	return events, nil
@@ -468,9 +828,9 @@ func (c *Client) GetRecentEvents(ctx context.Context, username string) ([]Event,
 // GetReceivedEvents returns events received by a user (their feed).
 // This method automatically handles pagination to fetch all received events.
 func (c *Client) GetReceivedEvents(ctx context.Context, username string) ([]Event, error) {
-	var events []Event
 	path := fmt.Sprintf("/users/%s/received_events", username)
-	if err := c.getPaginated(ctx, path, &events); err != nil {
+	events, err := paginate[Event](ctx, c, path)
+	if err != nil {
 		return nil, fmt.Errorf("fetching received events for %s: %w", username, err)
 	}
 	return events, nil
@@ -513,6 +873,54 @@ func (c *Client) GetRepository(ctx context.Context, owner, name string) (*Reposi
 	return &repo, nil
 }
 
+// GetLatestRelease fetches the most recent non-prerelease, non-draft release
+// of a repository.
+func (c *Client) GetLatestRelease(ctx context.Context, owner, name string) (*Release, error) {
+	var release Release
+	path := fmt.Sprintf("/repos/%s/%s/releases/latest", owner, name)
+	if err := c.get(ctx, path, &release); err != nil {
+		return nil, fmt.Errorf("fetching latest release of %s/%s: %w", owner, name, err)
+	}
+	return &release, nil
+}
+
+// GetRepoReleases fetches a repository's most recent releases, newest
+// first: one page of them, which is as far back as a watchlist looks.
+func (c *Client) GetRepoReleases(ctx context.Context, owner, name string) ([]Release, error) {
+	var releases []Release
+	path := pagePath(fmt.Sprintf("/repos/%s/%s/releases", owner, name), 1)
+	err := c.fetch(ctx, path, func(r io.Reader) error {
+		var decodeErr error
+		releases, decodeErr = decodeList[Release](r)
+		return decodeErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching releases of %s/%s: %w", owner, name, err)
+	}
+	return releases, nil
+}
+
+// GetRepoEvents returns recent events on a repository, whoever they're by.
+// This method automatically handles pagination to fetch all recent events.
+func (c *Client) GetRepoEvents(ctx context.Context, owner, name string) ([]Event, error) {
+	path := fmt.Sprintf("/repos/%s/%s/events", owner, name)
+	events, err := paginate[Event](ctx, c, path)
+	if err != nil {
+		return nil, fmt.Errorf("fetching events of %s/%s: %w", owner, name, err)
+	}
+	return events, nil
+}
+
+// GetUser fetches a user's public profile, including their name and bio,
+// which follow lists leave out.
+func (c *Client) GetUser(ctx context.Context, username string) (*User, error) {
+	var user User
+	if err := c.get(ctx, "/users/"+username, &user); err != nil {
+		return nil, fmt.Errorf("fetching user %s: %w", username, err)
+	}
+	return &user, nil
+}
+
 // CacheRepository stores a repository in the cache.
 // This is useful for pre-populating the cache with repositories
 // we've already fetched from other API endpoints (e.g., starred repos).
@@ -2,9 +2,13 @@ package github
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -97,6 +101,46 @@ func TestGetFollowedUsersByUsername(t *testing.T) {
 	}
 }
 
+func TestGetOrgMembersAndTeams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body any
+		switch r.URL.Path {
+		case "/orgs/acme/members":
+			body = []User{{Login: "alice"}, {Login: "bob"}}
+		case "/orgs/acme/teams":
+			body = []Team{{Name: "Platform Team", Slug: "platform-team"}}
+		case "/orgs/acme/teams/platform-team/members":
+			body = []User{{Login: "alice"}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			t.Fatalf("encoding response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	ctx := context.Background()
+	members, err := c.GetOrgMembers(ctx, "acme")
+	if err != nil || len(members) != 2 {
+		t.Errorf("GetOrgMembers() = %v, %v", members, err)
+	}
+	teams, err := c.GetOrgTeams(ctx, "acme")
+	if err != nil || len(teams) != 1 || teams[0].Slug != "platform-team" || teams[0].Name != "Platform Team" {
+		t.Errorf("GetOrgTeams() = %v, %v", teams, err)
+	}
+	members, err = c.GetTeamMembers(ctx, "acme", "platform-team")
+	if err != nil || len(members) != 1 || members[0].Login != "alice" {
+		t.Errorf("GetTeamMembers() = %v, %v", members, err)
+	}
+	if _, err := c.GetOrgMembers(ctx, "nope"); err == nil || !strings.Contains(err.Error(), "members of nope") {
+		t.Errorf("expected an error naming the org, got %v", err)
+	}
+}
+
 func TestGetStarredRepos(t *testing.T) {
 	repos := []Repository{
 		{ID: 1, Name: "repo1", FullName: "owner/repo1", StarCount: 100},
@@ -498,6 +542,42 @@ func TestRateLimitLowWarning(t *testing.T) {
 	}
 }
 
+func TestStarredAndOwnedCached(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/starred") {
+			w.Header().Set("ETag", `"starred"`)
+		}
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	c := NewClient("token", WithBaseURL(server.URL))
+	if c.StarredCached("alice") || c.OwnedCached("alice") {
+		t.Fatal("expected nothing cached before fetching")
+	}
+
+	if _, err := c.GetStarredReposByUsername(context.Background(), "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetOwnedReposByUsername(context.Background(), "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.StarredCached("alice") {
+		t.Error("expected alice's starred repos to be cached")
+	}
+	// No ETag came back, so there's nothing to ask with
+	if c.OwnedCached("alice") {
+		t.Error("expected alice's owned repos not to be cached")
+	}
+	if c.StarredCached("bob") {
+		t.Error("expected bob's starred repos not to be cached")
+	}
+	if stats := c.GetCacheStats(); stats.Hits != 0 {
+		t.Errorf("expected checking not to count as hits, got %+v", stats)
+	}
+}
+
 func TestClearCache(t *testing.T) {
 	users := []User{{Login: "user1", ID: 1}}
 	requestCount := 0
@@ -634,6 +714,68 @@ func TestGetFollowedUsersPagination(t *testing.T) {
 	}
 }
 
+func TestPaginateCustomType(t *testing.T) {
+	// paginate works for any JSON-decodable element type, not just the
+	// types the client already exposes.
+	type label struct {
+		Name string `json:"name"`
+	}
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("state") != "open" {
+			t.Errorf("expected existing query to be preserved, got %s", r.URL.RawQuery)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		var labels []label
+		if r.URL.Query().Get("page") == "1" {
+			for i := 0; i < 100; i++ {
+				labels = append(labels, label{Name: fmt.Sprintf("label%d", i)})
+			}
+		} else {
+			labels = []label{{Name: "last"}}
+		}
+		if err := json.NewEncoder(w).Encode(labels); err != nil {
+			t.Fatalf("encoding response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	result, err := paginate[label](context.Background(), c, "/repos/owner/repo/labels?state=open")
+	if err != nil {
+		t.Fatalf("paginate() error: %v", err)
+	}
+
+	if len(result) != 101 {
+		t.Errorf("expected 101 labels, got %d", len(result))
+	}
+	if result[100].Name != "last" {
+		t.Errorf("expected last label 'last', got %q", result[100].Name)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestPaginateError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	result, err := paginate[User](context.Background(), c, "/user/following")
+	if err == nil {
+		t.Fatal("expected error from paginate")
+	}
+	if result != nil {
+		t.Errorf("expected nil result on error, got %v", result)
+	}
+}
+
 func TestGetStarredReposPagination(t *testing.T) {
 	// Test with exactly 100 repos (single page, should not request page 2)
 	repos := make([]Repository, 100)
@@ -915,3 +1057,419 @@ func TestGetStarredReposPopulatesCache(t *testing.T) {
 		t.Errorf("expected 0 individual repo requests (should use cache), got %d", repoRequestCount)
 	}
 }
+
+func TestWithMaxResponseSize(t *testing.T) {
+	c := NewClient("token", WithMaxResponseSize(1024))
+	if c.maxResponseSize != 1024 {
+		t.Errorf("expected maxResponseSize 1024, got %d", c.maxResponseSize)
+	}
+
+	c = NewClient("token", WithMaxResponseSize(0))
+	if c.maxResponseSize != defaultMaxResponseSize {
+		t.Errorf("expected non-positive size to be ignored, got %d", c.maxResponseSize)
+	}
+}
+
+func TestResponseTooLarge(t *testing.T) {
+	users := make([]User, 50)
+	for i := range users {
+		users[i] = User{Login: fmt.Sprintf("user%d", i), ID: int64(i)}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(users); err != nil {
+			t.Fatalf("encoding response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL), WithMaxResponseSize(256))
+	_, err := c.GetFollowedUsers(context.Background())
+	if err == nil {
+		t.Fatal("expected error for oversized response")
+	}
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestResponseExactlyAtLimit(t *testing.T) {
+	body := `{"login":"octocat","id":1}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL), WithMaxResponseSize(int64(len(body))))
+	var user User
+	if err := c.get(context.Background(), "/users/octocat", &user); err != nil {
+		t.Fatalf("get() error: %v", err)
+	}
+	if user.Login != "octocat" {
+		t.Errorf("expected login 'octocat', got %q", user.Login)
+	}
+}
+
+func TestAPIErrorBodyTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(strings.Repeat("x", 2*maxErrorBodySize)))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	err := c.get(context.Background(), "/user", nil)
+	if err == nil {
+		t.Fatal("expected API error")
+	}
+	if len(err.Error()) > maxErrorBodySize+100 {
+		t.Errorf("expected error body to be truncated, got %d bytes", len(err.Error()))
+	}
+}
+
+func TestDecodeList(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{name: "array", input: `[{"login":"a"},{"login":"b"}]`, want: 2},
+		{name: "empty array", input: `[]`, want: 0},
+		{name: "null", input: `null`, want: 0},
+		{name: "object", input: `{"login":"a"}`, wantErr: true},
+		{name: "truncated", input: `[{"login":"a"},`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeList[User](strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(got) != tt.want {
+				t.Errorf("expected %d items, got %d", tt.want, len(got))
+			}
+		})
+	}
+}
+
+func TestCompressedResponses(t *testing.T) {
+	users := []User{{Login: "user1", ID: 1}, {Login: "user2", ID: 2}}
+
+	tests := []struct {
+		compress func(w io.Writer) io.WriteCloser
+		name     string
+		encoding string
+	}{
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			compress: func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		},
+		{
+			name:     "deflate",
+			encoding: "deflate",
+			compress: func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.Header.Get("Accept-Encoding"), tt.encoding) {
+					t.Errorf("expected Accept-Encoding to include %s, got %q", tt.encoding, r.Header.Get("Accept-Encoding"))
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", tt.encoding)
+				cw := tt.compress(w)
+				if err := json.NewEncoder(cw).Encode(users); err != nil {
+					t.Fatalf("encoding response: %v", err)
+				}
+				_ = cw.Close()
+			}))
+			defer server.Close()
+
+			c := NewClient("test-token", WithBaseURL(server.URL))
+			result, err := c.GetFollowedUsers(context.Background())
+			if err != nil {
+				t.Fatalf("GetFollowedUsers() error: %v", err)
+			}
+			if len(result) != 2 || result[1].Login != "user2" {
+				t.Errorf("unexpected result: %+v", result)
+			}
+		})
+	}
+}
+
+func TestCompressedResponseSizeLimitAppliesAfterDecompression(t *testing.T) {
+	users := make([]User, 50)
+	for i := range users {
+		users[i] = User{Login: "same-login-compresses-well", ID: 1}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		if err := json.NewEncoder(gz).Encode(users); err != nil {
+			t.Fatalf("encoding response: %v", err)
+		}
+		_ = gz.Close()
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL), WithMaxResponseSize(512))
+	_, err := c.GetFollowedUsers(context.Background())
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestUnsupportedContentEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = w.Write([]byte("not really brotli"))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	err := c.get(context.Background(), "/user", nil)
+	if err == nil || !strings.Contains(err.Error(), "unsupported content encoding") {
+		t.Errorf("expected unsupported encoding error, got %v", err)
+	}
+}
+
+func TestRequestStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// Serve two pages of starred repos, single pages everywhere else
+		if strings.HasSuffix(r.URL.Path, "/starred") && r.URL.Query().Get("page") == "1" {
+			repos := make([]Repository, 100)
+			_ = json.NewEncoder(w).Encode(repos)
+			return
+		}
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	ctx := context.Background()
+	if _, err := c.GetFollowedUsers(ctx); err != nil {
+		t.Fatalf("GetFollowedUsers() error: %v", err)
+	}
+	if _, err := c.GetStarredReposByUsername(ctx, "alice"); err != nil {
+		t.Fatalf("GetStarredReposByUsername() error: %v", err)
+	}
+	if _, err := c.GetOwnedReposByUsername(ctx, "alice"); err != nil {
+		t.Fatalf("GetOwnedReposByUsername() error: %v", err)
+	}
+	if _, err := c.GetRecentEvents(ctx, "alice"); err != nil {
+		t.Fatalf("GetRecentEvents() error: %v", err)
+	}
+
+	stats := c.GetRequestStats()
+	if stats.Total != 5 {
+		t.Errorf("expected 5 total requests, got %d", stats.Total)
+	}
+	if stats.Pagination != 1 {
+		t.Errorf("expected 1 pagination request, got %d", stats.Pagination)
+	}
+	for _, class := range []string{"following", "starred", "repos", "events"} {
+		if stats.ByEndpoint[class] != 1 {
+			t.Errorf("expected 1 %s request, got %d", class, stats.ByEndpoint[class])
+		}
+	}
+
+	// Returned stats are a copy
+	stats.ByEndpoint["following"] = 99
+	if c.GetRequestStats().ByEndpoint["following"] != 1 {
+		t.Error("GetRequestStats should return a copy")
+	}
+
+	c.ResetRequestStats()
+	if c.GetRequestStats().Total != 0 {
+		t.Error("expected counters to be reset")
+	}
+}
+
+func TestEndpointClass(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/user/following?page=1&per_page=100", "following"},
+		{"/users/alice/following", "following"},
+		{"/users/alice/starred?page=2", "starred"},
+		{"/users/alice/repos?type=owner&page=1", "repos"},
+		{"/users/alice/events", "events"},
+		{"/users/alice/received_events", "events"},
+		{"/repos/owner/name", "repository"},
+		{"/rate_limit", "rate_limit"},
+		{"/user", "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := endpointClass(tt.path); got != tt.want {
+				t.Errorf("endpointClass(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetMaxPages(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		repos := make([]Repository, 100)
+		_ = json.NewEncoder(w).Encode(repos)
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	c.SetMaxPages(2)
+
+	repos, err := c.GetStarredReposByUsername(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("GetStarredReposByUsername() error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests with max pages 2, got %d", requests)
+	}
+	if len(repos) != 200 {
+		t.Errorf("expected 200 repos, got %d", len(repos))
+	}
+
+	c.SetMaxPages(-1)
+	if c.maxPages.Load() != 0 {
+		t.Errorf("expected negative max pages to remove the cap, got %d", c.maxPages.Load())
+	}
+}
+
+func TestFetchRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rate_limit" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"resources":{"core":{"limit":5000,"remaining":42,"reset":1700000000,"used":4958}}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	rl, err := c.FetchRateLimit(context.Background())
+	if err != nil {
+		t.Fatalf("FetchRateLimit() error: %v", err)
+	}
+
+	if rl.Limit != 5000 || rl.Remaining != 42 || rl.Used != 4958 {
+		t.Errorf("unexpected rate limit: %+v", rl)
+	}
+	if !rl.Reset.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected reset time: %v", rl.Reset)
+	}
+
+	if cached := c.GetRateLimit(); cached == nil || cached.Remaining != 42 {
+		t.Errorf("expected FetchRateLimit to update GetRateLimit, got %+v", cached)
+	}
+}
+
+func TestGetLatestRelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/tool/releases/latest" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"tag_name": "v1.4.0", "html_url": "https://github.com/owner/tool/releases/tag/v1.4.0", "prerelease": false}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("", WithBaseURL(server.URL))
+	release, err := c.GetLatestRelease(context.Background(), "owner", "tool")
+	if err != nil {
+		t.Fatalf("GetLatestRelease() error: %v", err)
+	}
+	if release.TagName != "v1.4.0" {
+		t.Errorf("expected tag v1.4.0, got %q", release.TagName)
+	}
+	if release.HTMLURL != "https://github.com/owner/tool/releases/tag/v1.4.0" {
+		t.Errorf("unexpected URL %q", release.HTMLURL)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/rsc" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"login": "rsc", "name": "Russ Cox", "bio": "Go"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("", WithBaseURL(server.URL))
+	user, err := c.GetUser(context.Background(), "rsc")
+	if err != nil {
+		t.Fatalf("GetUser() error: %v", err)
+	}
+	if user.Name != "Russ Cox" || user.Bio != "Go" {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+func TestGetLatestRelease_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := NewClient("", WithBaseURL(server.URL))
+	if _, err := c.GetLatestRelease(context.Background(), "owner", "tool"); err == nil {
+		t.Error("expected error when the repository has no releases")
+	}
+}
+
+func TestGetRepoReleases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/tool/releases" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") != "1" {
+			t.Errorf("expected only the first page, got %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"tag_name": "v1.4.0", "author": {"login": "alice"}, "draft": false}, {"tag_name": "v1.5.0-rc1", "draft": true}]`))
+	}))
+	defer server.Close()
+
+	c := NewClient("", WithBaseURL(server.URL))
+	releases, err := c.GetRepoReleases(context.Background(), "owner", "tool")
+	if err != nil {
+		t.Fatalf("GetRepoReleases() error: %v", err)
+	}
+	if len(releases) != 2 || releases[0].TagName != "v1.4.0" || releases[0].Author.Login != "alice" || !releases[1].Draft {
+		t.Errorf("unexpected releases %+v", releases)
+	}
+}
+
+func TestGetRepoEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/tool/events" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"id": "1", "type": "PushEvent", "actor": {"login": "bob"}, "repo": {"name": "owner/tool"}}]`))
+	}))
+	defer server.Close()
+
+	c := NewClient("", WithBaseURL(server.URL))
+	events, err := c.GetRepoEvents(context.Background(), "owner", "tool")
+	if err != nil {
+		t.Fatalf("GetRepoEvents() error: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != "PushEvent" || events[0].Actor.Login != "bob" {
+		t.Errorf("unexpected events %+v", events)
+	}
+}
@@ -0,0 +1,491 @@
+// Package githubtest runs a fake GitHub REST API for tests that want to
+// exercise a real github.Client, and everything built on one, without a
+// token or the network. It serves follow lists, organization members and
+// teams, starred and owned repos, events, profiles, and repos with their
+// releases and events from what the test sets up, paginated as GitHub
+// does, with ETags, 304s and a rate limit that runs down.
+package githubtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+// DefaultRateLimit is the hourly request limit a new Server starts with,
+// GitHub's for authenticated requests.
+const DefaultRateLimit = 5000
+
+// maxPerPage is the largest page GitHub serves, whatever per_page asks for.
+const maxPerPage = 100
+
+// Server is a fake GitHub API. Its methods are safe to call while a client
+// is using it.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	following   []string            // The authenticated user's follow list
+	ownStarred  []github.Repository // The authenticated user's starred repos
+	followingBy map[string][]string // Other users' follow lists
+	users       map[string]github.User
+	starred     map[string][]github.Repository
+	owned       map[string][]github.Repository
+	events      map[string][]github.Event
+	repos       map[string]github.Repository // By owner/name
+	releases    map[string][]github.Release  // By owner/name
+	repoEvents  map[string][]github.Event    // By owner/name
+	orgMembers  map[string][]string
+	orgTeams    map[string][]github.Team
+	teamMembers map[string][]string // By org/team-slug
+	failures    map[string]int      // Status codes to answer paths with
+	requests    []string
+	notModified int
+	rateLimit   github.RateLimit
+}
+
+// NewServer starts a fake GitHub API, which is shut down when the test
+// ends.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	s := &Server{
+		followingBy: make(map[string][]string),
+		users:       make(map[string]github.User),
+		starred:     make(map[string][]github.Repository),
+		owned:       make(map[string][]github.Repository),
+		events:      make(map[string][]github.Event),
+		repos:       make(map[string]github.Repository),
+		releases:    make(map[string][]github.Release),
+		repoEvents:  make(map[string][]github.Event),
+		orgMembers:  make(map[string][]string),
+		orgTeams:    make(map[string][]github.Team),
+		teamMembers: make(map[string][]string),
+		failures:    make(map[string]int),
+		rateLimit: github.RateLimit{
+			Limit:     DefaultRateLimit,
+			Remaining: DefaultRateLimit,
+			Reset:     time.Now().Add(time.Hour).Truncate(time.Second),
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rate_limit", s.serveRateLimit)
+	mux.HandleFunc("GET /user/following", s.counted(func(r *http.Request) (any, bool) {
+		return s.userList(s.following), true
+	}))
+	mux.HandleFunc("GET /user/starred", s.counted(func(r *http.Request) (any, bool) {
+		return s.ownStarred, true
+	}))
+	mux.HandleFunc("GET /users/{login}/following", s.counted(func(r *http.Request) (any, bool) {
+		follows, ok := s.followingBy[r.PathValue("login")]
+		return s.userList(follows), ok || s.known(r)
+	}))
+	mux.HandleFunc("GET /users/{login}/starred", s.counted(func(r *http.Request) (any, bool) {
+		return s.starred[r.PathValue("login")], s.known(r)
+	}))
+	mux.HandleFunc("GET /users/{login}/repos", s.counted(func(r *http.Request) (any, bool) {
+		return s.owned[r.PathValue("login")], s.known(r)
+	}))
+	mux.HandleFunc("GET /users/{login}/events", s.counted(func(r *http.Request) (any, bool) {
+		return s.events[r.PathValue("login")], s.known(r)
+	}))
+	mux.HandleFunc("GET /users/{login}", s.counted(func(r *http.Request) (any, bool) {
+		u, ok := s.users[r.PathValue("login")]
+		return u, ok
+	}))
+	mux.HandleFunc("GET /repos/{owner}/{name}", s.counted(func(r *http.Request) (any, bool) {
+		repo, ok := s.repos[repoName(r)]
+		return repo, ok
+	}))
+	mux.HandleFunc("GET /repos/{owner}/{name}/releases", s.counted(func(r *http.Request) (any, bool) {
+		_, ok := s.repos[repoName(r)]
+		return s.releases[repoName(r)], ok
+	}))
+	mux.HandleFunc("GET /repos/{owner}/{name}/events", s.counted(func(r *http.Request) (any, bool) {
+		_, ok := s.repos[repoName(r)]
+		return s.repoEvents[repoName(r)], ok
+	}))
+	mux.HandleFunc("GET /orgs/{org}/members", s.counted(func(r *http.Request) (any, bool) {
+		members, ok := s.orgMembers[r.PathValue("org")]
+		return s.userList(members), ok
+	}))
+	mux.HandleFunc("GET /orgs/{org}/teams", s.counted(func(r *http.Request) (any, bool) {
+		teams := s.orgTeams[r.PathValue("org")]
+		_, ok := s.orgMembers[r.PathValue("org")]
+		return teams, ok
+	}))
+	mux.HandleFunc("GET /orgs/{org}/teams/{team}/members", s.counted(func(r *http.Request) (any, bool) {
+		members, ok := s.teamMembers[r.PathValue("org")+"/"+r.PathValue("team")]
+		return s.userList(members), ok
+	}))
+
+	s.Server = httptest.NewServer(mux)
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a client for the server, authenticated with token.
+func (s *Server) Client(token string, opts ...github.Option) *github.Client {
+	return github.NewClient(token, append([]github.Option{github.WithBaseURL(s.URL)}, opts...)...)
+}
+
+// AddUser makes a user known to the server, so their profile and, however
+// empty, their activity can be fetched.
+func (s *Server) AddUser(u github.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addUser(u.Login, u)
+}
+
+// Follow adds logins to the authenticated user's follow list, adding any
+// the server doesn't know yet.
+func (s *Server) Follow(logins ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, login := range logins {
+		s.addUser(login, github.User{})
+		s.following = append(s.following, login)
+	}
+}
+
+// Unfollow takes login off the authenticated user's follow list.
+func (s *Server) Unfollow(login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.following[:0]
+	for _, l := range s.following {
+		if l != login {
+			kept = append(kept, l)
+		}
+	}
+	s.following = kept
+}
+
+// FollowFrom adds logins to follower's follow list, as seen by unauthenticated
+// runs with -user.
+func (s *Server) FollowFrom(follower string, logins ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addUser(follower, github.User{})
+	for _, login := range logins {
+		s.addUser(login, github.User{})
+		s.followingBy[follower] = append(s.followingBy[follower], login)
+	}
+}
+
+// AddOrgMembers adds logins to org's members, creating the org if it's new.
+func (s *Server) AddOrgMembers(org string, logins ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addOrgMembers(org, logins)
+}
+
+// AddTeam adds a team to org with logins as its members, who are added to
+// the org as well.
+func (s *Server) AddTeam(org string, team github.Team, logins ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addOrgMembers(org, logins)
+	s.orgTeams[org] = append(s.orgTeams[org], team)
+	key := org + "/" + team.Slug
+	s.teamMembers[key] = append(s.teamMembers[key], logins...)
+}
+
+func (s *Server) addOrgMembers(org string, logins []string) {
+	members := s.orgMembers[org]
+	if members == nil {
+		members = []string{}
+	}
+	for _, login := range logins {
+		s.addUser(login, github.User{})
+		if !slices.Contains(members, login) {
+			members = append(members, login)
+		}
+	}
+	s.orgMembers[org] = members
+}
+
+// Star adds repos to the front of login's starred repos, as the most
+// recently starred.
+func (s *Server) Star(login string, repos ...github.Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addUser(login, github.User{})
+	s.starred[login] = append(append([]github.Repository{}, repos...), s.starred[login]...)
+}
+
+// StarOwn adds repos to the front of the authenticated user's starred
+// repos, as the most recently starred.
+func (s *Server) StarOwn(repos ...github.Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ownStarred = append(append([]github.Repository{}, repos...), s.ownStarred...)
+}
+
+// AddRepos gives login repos of their own.
+func (s *Server) AddRepos(login string, repos ...github.Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addUser(login, github.User{})
+	for _, r := range repos {
+		if r.Owner.Login == "" {
+			r.Owner = github.User{Login: login}
+		}
+		s.owned[login] = append(s.owned[login], r)
+	}
+}
+
+// AddEvents adds events to the front of login's events, newest first as
+// GitHub lists them. Events without an actor or ID get one.
+func (s *Server) AddEvents(login string, events ...github.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addUser(login, github.User{})
+	for i := range events {
+		if events[i].Actor.Login == "" {
+			events[i].Actor = github.User{Login: login}
+		}
+		if events[i].ID == "" {
+			events[i].ID = strconv.Itoa(len(s.events[login]) + len(events) - i)
+		}
+	}
+	s.events[login] = append(append([]github.Event{}, events...), s.events[login]...)
+}
+
+// AddRepo makes a repo known to the server, or updates it, so it can be
+// fetched with its releases and events.
+func (s *Server) AddRepo(repo github.Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if repo.FullName == "" {
+		repo.FullName = repo.Owner.Login + "/" + repo.Name
+	}
+	s.repos[repo.FullName] = repo
+}
+
+// AddReleases adds releases to the front of the repo fullName's releases,
+// newest first as GitHub lists them.
+func (s *Server) AddReleases(fullName string, releases ...github.Release) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releases[fullName] = append(append([]github.Release{}, releases...), s.releases[fullName]...)
+}
+
+// AddRepoEvents adds events to the front of the repo fullName's events,
+// newest first as GitHub lists them. Events without a repo or ID get one.
+func (s *Server) AddRepoEvents(fullName string, events ...github.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range events {
+		if events[i].Repo.Name == "" {
+			events[i].Repo = github.EventRepo{Name: fullName}
+		}
+		if events[i].ID == "" {
+			events[i].ID = fullName + "/" + strconv.Itoa(len(s.repoEvents[fullName])+len(events)-i)
+		}
+	}
+	s.repoEvents[fullName] = append(append([]github.Event{}, events...), s.repoEvents[fullName]...)
+}
+
+// Fail answers requests for path, without its query, with status until
+// cleared with a status of 0.
+func (s *Server) Fail(path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == 0 {
+		delete(s.failures, path)
+		return
+	}
+	s.failures[path] = status
+}
+
+// SetRateLimit sets how many requests the rate limit allows and how many
+// are left. Requests past it get a 403, as from GitHub.
+func (s *Server) SetRateLimit(limit, remaining int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit.Limit = limit
+	s.rateLimit.Remaining = remaining
+}
+
+// RateLimit returns the rate limit as it stands.
+func (s *Server) RateLimit() github.RateLimit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rateLimit
+}
+
+// Requests returns the paths, with their queries, requested so far, not
+// counting the rate limit's.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// NotModified returns how many requests were answered with a 304.
+func (s *Server) NotModified() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notModified
+}
+
+// addUser records login, keeping what's known of them unless u says more.
+func (s *Server) addUser(login string, u github.User) {
+	if _, ok := s.users[login]; ok && u == (github.User{}) {
+		return
+	}
+	u.Login = login
+	if u.ID == 0 {
+		if existing, ok := s.users[login]; ok {
+			u.ID = existing.ID
+		} else {
+			u.ID = int64(len(s.users) + 1)
+		}
+	}
+	s.users[login] = u
+}
+
+// repoName returns the owner/name of the repo a request is about.
+func repoName(r *http.Request) string {
+	return r.PathValue("owner") + "/" + r.PathValue("name")
+}
+
+// known reports whether the user a request is about exists.
+func (s *Server) known(r *http.Request) bool {
+	_, ok := s.users[r.PathValue("login")]
+	return ok
+}
+
+// userList returns the users logins name, as list endpoints show them.
+func (s *Server) userList(logins []string) []github.User {
+	users := make([]github.User, 0, len(logins))
+	for _, login := range logins {
+		u := s.users[login]
+		users = append(users, github.User{Login: u.Login, ID: u.ID, AvatarURL: u.AvatarURL, HTMLURL: u.HTMLURL})
+	}
+	return users
+}
+
+// counted wraps an endpoint with what every counted request goes through:
+// the request log, failures, the rate limit, pagination and ETags. find
+// returns what the endpoint lists or shows, and whether it exists.
+func (s *Server) counted(find func(r *http.Request) (any, bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r.URL.RequestURI())
+
+		if status, ok := s.failures[r.URL.Path]; ok {
+			s.writeError(w, status, http.StatusText(status))
+			return
+		}
+		if s.rateLimit.Remaining <= 0 {
+			s.writeError(w, http.StatusForbidden, "API rate limit exceeded")
+			return
+		}
+
+		result, ok := find(r)
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		body, err := json.Marshal(page(result, r))
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		w.Header().Set("ETag", etag)
+		// Like GitHub, a 304 doesn't count against the limit
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified++
+			s.writeRateLimit(w)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.rateLimit.Remaining--
+		s.rateLimit.Used++
+		s.writeRateLimit(w)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}
+}
+
+// page returns the page of a listing r asks for, or result as is if it
+// isn't a listing.
+func page(result any, r *http.Request) any {
+	n, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	n = max(n, 1)
+	size, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if size < 1 || size > maxPerPage {
+		size = 30 // GitHub's default
+	}
+	switch list := result.(type) {
+	case []github.User:
+		return pageOf(list, n, size)
+	case []github.Repository:
+		return pageOf(list, n, size)
+	case []github.Event:
+		return pageOf(list, n, size)
+	case []github.Release:
+		return pageOf(list, n, size)
+	}
+	return result
+}
+
+// pageOf returns the nth page of list, counting from 1, never nil so it
+// encodes as an empty array.
+func pageOf[T any](list []T, n, size int) []T {
+	start := min((n-1)*size, len(list))
+	end := min(start+size, len(list))
+	return append([]T{}, list[start:end]...)
+}
+
+func (s *Server) serveRateLimit(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var resp struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+				Used      int   `json:"used"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	core := &resp.Resources.Core
+	core.Limit, core.Remaining, core.Reset, core.Used = s.rateLimit.Limit, s.rateLimit.Remaining, s.rateLimit.Reset.Unix(), s.rateLimit.Used
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeRateLimit sets the rate limit headers GitHub sends with every
+// response.
+func (s *Server) writeRateLimit(w http.ResponseWriter) {
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(s.rateLimit.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(s.rateLimit.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(s.rateLimit.Reset.Unix(), 10))
+	h.Set("X-RateLimit-Used", strconv.Itoa(s.rateLimit.Used))
+}
+
+// writeError writes a GitHub-style error response.
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeRateLimit(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,218 @@
+package githubtest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+func TestServerPaginates(t *testing.T) {
+	srv := NewServer(t)
+	for i := range 250 {
+		srv.Star("alice", github.Repository{ID: int64(i), Name: "repo", FullName: "someone/repo"})
+	}
+
+	repos, err := srv.Client("token").GetStarredReposByUsername(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("GetStarredReposByUsername() error = %v", err)
+	}
+	if len(repos) != 250 {
+		t.Errorf("got %d repos, want 250", len(repos))
+	}
+	if got := len(srv.Requests()); got != 3 {
+		t.Errorf("got %d requests, want 3 pages: %v", got, srv.Requests())
+	}
+}
+
+func TestServerNotModified(t *testing.T) {
+	srv := NewServer(t)
+	srv.AddEvents("alice", github.Event{Type: "PushEvent", Repo: github.EventRepo{Name: "alice/repo"}, CreatedAt: time.Now()})
+	client := srv.Client("token")
+	ctx := context.Background()
+
+	first, err := client.GetRecentEvents(ctx, "alice")
+	if err != nil {
+		t.Fatalf("first GetRecentEvents() error = %v", err)
+	}
+	used := srv.RateLimit().Used
+
+	second, err := client.GetRecentEvents(ctx, "alice")
+	if err != nil {
+		t.Fatalf("second GetRecentEvents() error = %v", err)
+	}
+	if srv.NotModified() != 1 {
+		t.Errorf("NotModified() = %d, want 1", srv.NotModified())
+	}
+	if len(second) != len(first) || second[0].ID != first[0].ID {
+		t.Errorf("cached events = %+v, want %+v", second, first)
+	}
+	if srv.RateLimit().Used != used {
+		t.Errorf("304 counted against the rate limit: used %d, then %d", used, srv.RateLimit().Used)
+	}
+
+	srv.AddEvents("alice", github.Event{Type: "WatchEvent", Repo: github.EventRepo{Name: "golang/go"}, CreatedAt: time.Now()})
+	third, err := client.GetRecentEvents(ctx, "alice")
+	if err != nil {
+		t.Fatalf("third GetRecentEvents() error = %v", err)
+	}
+	if len(third) != 2 || third[0].Type != "WatchEvent" {
+		t.Errorf("events after a change = %+v, want the new one first", third)
+	}
+	if third[0].ID == third[1].ID {
+		t.Errorf("events share ID %q", third[0].ID)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	srv := NewServer(t)
+	srv.Follow("alice", "bob")
+	srv.SetRateLimit(10, 1)
+	client := srv.Client("token")
+	ctx := context.Background()
+
+	if _, err := client.GetFollowedUsers(ctx); err != nil {
+		t.Fatalf("GetFollowedUsers() error = %v", err)
+	}
+	if rl := client.GetRateLimit(); rl == nil || rl.Limit != 10 || rl.Remaining != 0 {
+		t.Errorf("client rate limit = %+v, want 0 of 10 left", rl)
+	}
+
+	_, err := client.GetStarredReposByUsername(ctx, "alice")
+	if err == nil || !strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("GetStarredReposByUsername() error = %v, want rate limit error", err)
+	}
+
+	rl, err := client.FetchRateLimit(ctx)
+	if err != nil {
+		t.Fatalf("FetchRateLimit() error = %v", err)
+	}
+	if rl.Remaining != 0 || rl.Used != 1 {
+		t.Errorf("FetchRateLimit() = %+v, want 0 left, 1 used", rl)
+	}
+}
+
+func TestServerFailAndUnknownUsers(t *testing.T) {
+	srv := NewServer(t)
+	srv.AddUser(github.User{Login: "alice", Name: "Alice"})
+	client := srv.Client("token")
+	ctx := context.Background()
+
+	u, err := client.GetUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if u.Name != "Alice" || u.ID == 0 {
+		t.Errorf("GetUser() = %+v, want alice's profile with an ID", u)
+	}
+
+	if _, err := client.GetRecentEvents(ctx, "nobody"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("events for an unknown user: error = %v, want 404", err)
+	}
+
+	srv.Fail("/users/alice/repos", http.StatusBadGateway)
+	if _, err := client.GetOwnedReposByUsername(ctx, "alice"); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("failed repos: error = %v, want 502", err)
+	}
+	srv.Fail("/users/alice/repos", 0)
+	if _, err := client.GetOwnedReposByUsername(ctx, "alice"); err != nil {
+		t.Errorf("repos after clearing the failure: error = %v", err)
+	}
+}
+
+func TestServerFollowLists(t *testing.T) {
+	srv := NewServer(t)
+	srv.Follow("alice", "bob")
+	srv.Unfollow("alice")
+	srv.FollowFrom("carol", "dave")
+	client := srv.Client("")
+	ctx := context.Background()
+
+	mine, err := client.GetFollowedUsers(ctx)
+	if err != nil {
+		t.Fatalf("GetFollowedUsers() error = %v", err)
+	}
+	if len(mine) != 1 || mine[0].Login != "bob" {
+		t.Errorf("GetFollowedUsers() = %+v, want bob", mine)
+	}
+
+	carols, err := client.GetFollowedUsersByUsername(ctx, "carol")
+	if err != nil {
+		t.Fatalf("GetFollowedUsersByUsername() error = %v", err)
+	}
+	if len(carols) != 1 || carols[0].Login != "dave" {
+		t.Errorf("GetFollowedUsersByUsername() = %+v, want dave", carols)
+	}
+}
+
+func TestServerOwnStars(t *testing.T) {
+	srv := NewServer(t)
+	client := srv.Client("")
+	ctx := context.Background()
+
+	if stars, err := client.GetStarredRepos(ctx); err != nil || len(stars) != 0 {
+		t.Fatalf("GetStarredRepos() before starring = %+v, %v; want none", stars, err)
+	}
+	srv.StarOwn(github.Repository{Name: "go", FullName: "golang/go"})
+	srv.StarOwn(github.Repository{Name: "rust", FullName: "rust-lang/rust"})
+	stars, err := client.GetStarredRepos(ctx)
+	if err != nil {
+		t.Fatalf("GetStarredRepos() error = %v", err)
+	}
+	if len(stars) != 2 || stars[0].FullName != "rust-lang/rust" {
+		t.Errorf("GetStarredRepos() = %+v, want the latest star first", stars)
+	}
+}
+
+func TestServerOrgs(t *testing.T) {
+	srv := NewServer(t)
+	srv.AddOrgMembers("acme", "alice")
+	srv.AddTeam("acme", github.Team{Name: "Web", Slug: "web"}, "bob", "alice")
+	client := srv.Client("")
+	ctx := context.Background()
+
+	members, err := client.GetOrgMembers(ctx, "acme")
+	if err != nil || len(members) != 2 || members[0].Login != "alice" || members[1].Login != "bob" {
+		t.Errorf("GetOrgMembers() = %+v, %v; want alice and bob once each", members, err)
+	}
+	teams, err := client.GetOrgTeams(ctx, "acme")
+	if err != nil || len(teams) != 1 || teams[0].Slug != "web" {
+		t.Errorf("GetOrgTeams() = %+v, %v", teams, err)
+	}
+	web, err := client.GetTeamMembers(ctx, "acme", "web")
+	if err != nil || len(web) != 2 {
+		t.Errorf("GetTeamMembers() = %+v, %v", web, err)
+	}
+	if _, err := client.GetOrgMembers(ctx, "initech"); err == nil {
+		t.Error("expected an error for an unknown org")
+	}
+}
+
+func TestServerRepos(t *testing.T) {
+	srv := NewServer(t)
+	srv.AddRepo(github.Repository{Name: "go", Owner: github.User{Login: "golang"}, StarCount: 10})
+	srv.AddReleases("golang/go", github.Release{TagName: "go1.25.0"})
+	srv.AddReleases("golang/go", github.Release{TagName: "go1.26.0"})
+	srv.AddRepoEvents("golang/go", github.Event{Type: "PushEvent"})
+	client := srv.Client("")
+	ctx := context.Background()
+
+	repo, err := client.GetRepository(ctx, "golang", "go")
+	if err != nil || repo.FullName != "golang/go" || repo.StarCount != 10 {
+		t.Errorf("GetRepository() = %+v, %v", repo, err)
+	}
+	releases, err := client.GetRepoReleases(ctx, "golang", "go")
+	if err != nil || len(releases) != 2 || releases[0].TagName != "go1.26.0" {
+		t.Errorf("GetRepoReleases() = %+v, %v; want the latest release first", releases, err)
+	}
+	events, err := client.GetRepoEvents(ctx, "golang", "go")
+	if err != nil || len(events) != 1 || events[0].Repo.Name != "golang/go" || events[0].ID == "" {
+		t.Errorf("GetRepoEvents() = %+v, %v", events, err)
+	}
+	if _, err := client.GetRepository(ctx, "rust-lang", "rust"); err == nil {
+		t.Error("expected an error for an unknown repo")
+	}
+}
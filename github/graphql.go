@@ -0,0 +1,160 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// discussionsPerUser is how many of a user's latest discussions and
+// discussion comments GetRecentDiscussions asks for.
+const discussionsPerUser = 50
+
+// Discussion is a GitHub Discussions post by a user: a discussion they
+// started, or a comment they left on one.
+type Discussion struct {
+	CreatedAt time.Time
+	Title     string // Title of the discussion
+	URL       string
+	Repo      string // owner/name
+	Comment   bool   // A comment on the discussion rather than the discussion itself
+}
+
+// recentDiscussionsQuery fetches a user's latest discussions and discussion
+// comments. The REST events API doesn't include either.
+const recentDiscussionsQuery = `query($login: String!, $first: Int!) {
+  user(login: $login) {
+    repositoryDiscussions(first: $first, orderBy: {field: CREATED_AT, direction: DESC}) {
+      nodes { title url createdAt repository { nameWithOwner } }
+    }
+    repositoryDiscussionComments(first: $first) {
+      nodes { url createdAt discussion { title repository { nameWithOwner } } }
+    }
+  }
+}`
+
+// GetRecentDiscussions returns the discussions a user recently started and
+// commented on, through the GraphQL API. It needs a token: GraphQL doesn't
+// allow anonymous requests.
+func (c *Client) GetRecentDiscussions(ctx context.Context, username string) ([]Discussion, error) {
+	type repository struct {
+		NameWithOwner string `json:"nameWithOwner"`
+	}
+	var data struct {
+		User *struct {
+			RepositoryDiscussions struct {
+				Nodes []struct {
+					CreatedAt  time.Time  `json:"createdAt"`
+					Title      string     `json:"title"`
+					URL        string     `json:"url"`
+					Repository repository `json:"repository"`
+				} `json:"nodes"`
+			} `json:"repositoryDiscussions"`
+			RepositoryDiscussionComments struct {
+				Nodes []struct {
+					CreatedAt  time.Time `json:"createdAt"`
+					URL        string    `json:"url"`
+					Discussion struct {
+						Title      string     `json:"title"`
+						Repository repository `json:"repository"`
+					} `json:"discussion"`
+				} `json:"nodes"`
+			} `json:"repositoryDiscussionComments"`
+		} `json:"user"`
+	}
+	vars := map[string]any{"login": username, "first": discussionsPerUser}
+	if err := c.graphql(ctx, recentDiscussionsQuery, vars, &data); err != nil {
+		return nil, fmt.Errorf("fetching discussions for %s: %w", username, err)
+	}
+	if data.User == nil {
+		return nil, fmt.Errorf("fetching discussions for %s: no such user", username)
+	}
+
+	var discussions []Discussion
+	for _, d := range data.User.RepositoryDiscussions.Nodes {
+		discussions = append(discussions, Discussion{CreatedAt: d.CreatedAt, Title: d.Title, URL: d.URL, Repo: d.Repository.NameWithOwner})
+	}
+	for _, cm := range data.User.RepositoryDiscussionComments.Nodes {
+		discussions = append(discussions, Discussion{CreatedAt: cm.CreatedAt, Title: cm.Discussion.Title, URL: cm.URL, Repo: cm.Discussion.Repository.NameWithOwner, Comment: true})
+	}
+	return discussions, nil
+}
+
+// graphql runs a GraphQL query and decodes its data into result. GraphQL
+// has a rate limit of its own, so its headers don't update GetRateLimit.
+func (c *Client) graphql(ctx context.Context, query string, vars map[string]any, result any) error {
+	errs, err := c.graphqlPartial(ctx, query, vars, result)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, e := range errs {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("GraphQL error: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// graphqlError is an error GraphQL returned alongside, or instead of, data.
+type graphqlError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path"` // Where in the data it happened, from the top-level field
+}
+
+// graphqlPartial runs a GraphQL query as graphql does, except that errors
+// in parts of the query are returned with whatever data came back for the
+// rest, rather than as an error.
+func (c *Client) graphqlPartial(ctx context.Context, query string, vars map[string]any, result any) ([]graphqlError, error) {
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return nil, fmt.Errorf("encoding query: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/graphql", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	c.recordRequest("/graphql", false)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphqlError  `json:"errors"`
+	}
+	body := &limitedReader{r: resp.Body, remaining: c.maxResponseSize}
+	if err := json.NewDecoder(body).Decode(&envelope); err != nil {
+		if errors.Is(err, ErrResponseTooLarge) {
+			return nil, fmt.Errorf("reading response for /graphql: %w", err)
+		}
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		// Nothing to decode: the errors are all there is
+		return envelope.Errors, nil
+	}
+	if err := json.Unmarshal(envelope.Data, result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return envelope.Errors, nil
+}
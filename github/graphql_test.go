@@ -0,0 +1,86 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetRecentDiscussions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/graphql" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("unexpected auth header: %s", r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decoding request: %v", err)
+		}
+		if req.Variables["login"] != "alice" {
+			t.Errorf("unexpected variables: %v", req.Variables)
+		}
+		_, _ = w.Write([]byte(`{"data": {"user": {
+			"repositoryDiscussions": {"nodes": [
+				{"title": "Roadmap", "url": "https://github.com/o/r/discussions/1", "createdAt": "2024-01-15T10:00:00Z", "repository": {"nameWithOwner": "o/r"}}
+			]},
+			"repositoryDiscussionComments": {"nodes": [
+				{"url": "https://github.com/o/r/discussions/2#c", "createdAt": "2024-01-15T11:00:00Z", "discussion": {"title": "Help", "repository": {"nameWithOwner": "o/r"}}}
+			]}
+		}}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-token", WithBaseURL(server.URL))
+	discussions, err := c.GetRecentDiscussions(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("GetRecentDiscussions() error: %v", err)
+	}
+	if len(discussions) != 2 {
+		t.Fatalf("expected 2 discussions, got %d", len(discussions))
+	}
+	if d := discussions[0]; d.Title != "Roadmap" || d.Repo != "o/r" || d.Comment || d.CreatedAt.Hour() != 10 {
+		t.Errorf("unexpected discussion: %+v", d)
+	}
+	if d := discussions[1]; d.Title != "Help" || !d.Comment {
+		t.Errorf("unexpected comment: %+v", d)
+	}
+	if got := c.GetRequestStats().ByEndpoint["graphql"]; got != 1 {
+		t.Errorf("expected 1 graphql request counted, got %d", got)
+	}
+}
+
+func TestGetRecentDiscussions_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"GraphQL error", http.StatusOK, `{"errors": [{"message": "Could not resolve to a User"}]}`, "Could not resolve to a User"},
+		{"no such user", http.StatusOK, `{"data": {"user": null}}`, "no such user"},
+		{"HTTP error", http.StatusUnauthorized, `{"message": "Bad credentials"}`, "status 401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c := NewClient("test-token", WithBaseURL(server.URL))
+			_, err := c.GetRecentDiscussions(context.Background(), "alice")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
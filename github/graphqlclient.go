@@ -0,0 +1,414 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// graphQLBatchSize is how many users Prefetch asks about in one query.
+// Bigger batches save requests but make each costlier, and slower to come
+// back.
+const graphQLBatchSize = 10
+
+// Per-user limits on what a GraphQL activity query returns. REST listings
+// have every starred and owned repo; these are the most recent, which is
+// all a diff against the last run needs.
+const (
+	graphQLReposPerUser         = 100
+	graphQLContributionsPerKind = 30
+	graphQLCommitRepos          = 25
+)
+
+// userActivityFields is what a GraphQL activity query asks about each user.
+// GraphQL has no events feed, so recent activity comes from the user's
+// contributions.
+var userActivityFields = fmt.Sprintf(`login name bio company avatarUrl url databaseId
+    starredRepositories(first: %[1]d, orderBy: {field: STARRED_AT, direction: DESC}) { nodes { ...repo } }
+    repositories(first: %[1]d, ownerAffiliations: OWNER, orderBy: {field: CREATED_AT, direction: DESC}) { nodes { ...repo } }
+    contributionsCollection {
+      commitContributionsByRepository(maxRepositories: %[3]d) {
+        repository { nameWithOwner }
+        contributions(first: %[2]d, orderBy: {field: OCCURRED_AT, direction: DESC}) { nodes { occurredAt commitCount } }
+      }
+      pullRequestContributions(first: %[2]d, orderBy: {direction: DESC}) { nodes { occurredAt pullRequest { number title url repository { nameWithOwner } } } }
+      issueContributions(first: %[2]d, orderBy: {direction: DESC}) { nodes { occurredAt issue { number title url repository { nameWithOwner } } } }
+      pullRequestReviewContributions(first: %[2]d, orderBy: {direction: DESC}) { nodes { occurredAt pullRequest { number title url repository { nameWithOwner } } } }
+      repositoryContributions(first: %[2]d, orderBy: {direction: DESC}) { nodes { occurredAt repository { nameWithOwner } } }
+    }`, graphQLReposPerUser, graphQLContributionsPerKind, graphQLCommitRepos)
+
+// repoFragment is what a GraphQL activity query asks about each repo.
+const repoFragment = `fragment repo on Repository {
+  databaseId name nameWithOwner description url createdAt updatedAt
+  stargazerCount forkCount isPrivate
+  primaryLanguage { name }
+  repositoryTopics(first: 20) { nodes { topic { name } } }
+  owner { login }
+}`
+
+// GraphQLClient fetches users' starred repos, owned repos, recent activity
+// and profiles through the GraphQL API, which can ask about many users in
+// one request where REST takes several paginated requests per user. Follow
+// lists, and everything else, still come from the REST API through the
+// embedded Client.
+//
+// It needs a token: GraphQL doesn't allow anonymous requests.
+type GraphQLClient struct {
+	*Client
+	activity map[string]*graphQLActivity // By login, kept until the next Prefetch
+	mu       sync.Mutex
+}
+
+// graphQLActivity is what one activity query returned for a user.
+type graphQLActivity struct {
+	err     error
+	user    User
+	starred []Repository
+	owned   []Repository
+	events  []Event
+}
+
+// NewGraphQLClient returns a GraphQL client that makes its requests, and
+// counts them, through c.
+func NewGraphQLClient(c *Client) *GraphQLClient {
+	return &GraphQLClient{Client: c, activity: make(map[string]*graphQLActivity)}
+}
+
+// Prefetch fetches the activity of all of logins, a batch at a time, for
+// the per-user methods to return. Users it couldn't get are fetched on
+// their own when asked for; the error is the first batch's that failed.
+func (g *GraphQLClient) Prefetch(ctx context.Context, logins []string) error {
+	fetched := make(map[string]*graphQLActivity, len(logins))
+	var firstErr error
+	for start := 0; start < len(logins); start += graphQLBatchSize {
+		batch := logins[start:min(start+graphQLBatchSize, len(logins))]
+		activity, err := g.fetchActivity(ctx, batch)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for login, a := range activity {
+			fetched[login] = a
+		}
+	}
+
+	g.mu.Lock()
+	g.activity = fetched
+	g.mu.Unlock()
+	return firstErr
+}
+
+// GetStarredReposByUsername returns the repos a user most recently starred.
+func (g *GraphQLClient) GetStarredReposByUsername(ctx context.Context, username string) ([]Repository, error) {
+	a, err := g.userActivity(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("fetching starred repos for %s: %w", username, err)
+	}
+	return a.starred, nil
+}
+
+// GetOwnedReposByUsername returns the repos a user most recently created.
+func (g *GraphQLClient) GetOwnedReposByUsername(ctx context.Context, username string) ([]Repository, error) {
+	a, err := g.userActivity(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("fetching owned repos for %s: %w", username, err)
+	}
+	return a.owned, nil
+}
+
+// GetRecentEvents returns a user's recent contributions as the events the
+// REST API would have for them, newest first: pushes (a day's commits to a
+// repo at a time), pull requests, issues, reviews and new repos.
+func (g *GraphQLClient) GetRecentEvents(ctx context.Context, username string) ([]Event, error) {
+	a, err := g.userActivity(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("fetching events for %s: %w", username, err)
+	}
+	return a.events, nil
+}
+
+// GetUser returns a user's profile.
+func (g *GraphQLClient) GetUser(ctx context.Context, username string) (*User, error) {
+	a, err := g.userActivity(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("fetching user %s: %w", username, err)
+	}
+	u := a.user
+	return &u, nil
+}
+
+// userActivity returns what Prefetch got for username, fetching it on its
+// own if Prefetch didn't.
+func (g *GraphQLClient) userActivity(ctx context.Context, username string) (*graphQLActivity, error) {
+	g.mu.Lock()
+	a, ok := g.activity[username]
+	g.mu.Unlock()
+	if !ok {
+		activity, err := g.fetchActivity(ctx, []string{username})
+		if err != nil {
+			return nil, err
+		}
+		a = activity[username]
+		g.mu.Lock()
+		g.activity[username] = a
+		g.mu.Unlock()
+	}
+	if a.err != nil {
+		return nil, a.err
+	}
+	return a, nil
+}
+
+// fetchActivity asks about logins in one query. Users GraphQL couldn't
+// resolve get an error of their own; the error returned is for the query
+// as a whole.
+func (g *GraphQLClient) fetchActivity(ctx context.Context, logins []string) (map[string]*graphQLActivity, error) {
+	var query strings.Builder
+	vars := make(map[string]any, len(logins))
+	params := make([]string, len(logins))
+	for i, login := range logins {
+		params[i] = fmt.Sprintf("$l%d: String!", i)
+		vars["l"+strconv.Itoa(i)] = login
+	}
+	_, _ = fmt.Fprintf(&query, "query(%s) {\n", strings.Join(params, ", "))
+	for i := range logins {
+		_, _ = fmt.Fprintf(&query, "  u%d: user(login: $l%d) {\n    %s\n  }\n", i, i, userActivityFields)
+	}
+	query.WriteString("}\n" + repoFragment)
+
+	var data map[string]*graphQLUser
+	errs, err := g.graphqlPartial(ctx, query.String(), vars, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors in one user's part of the query are theirs alone
+	userErrs := make(map[string]string)
+	var queryErrs []string
+	for _, e := range errs {
+		if len(e.Path) > 0 {
+			if alias, ok := e.Path[0].(string); ok {
+				userErrs[alias] = e.Message
+				continue
+			}
+		}
+		queryErrs = append(queryErrs, e.Message)
+	}
+	if len(queryErrs) > 0 {
+		return nil, fmt.Errorf("GraphQL error: %s", strings.Join(queryErrs, "; "))
+	}
+
+	activity := make(map[string]*graphQLActivity, len(logins))
+	for i, login := range logins {
+		alias := "u" + strconv.Itoa(i)
+		switch u := data[alias]; {
+		case userErrs[alias] != "":
+			activity[login] = &graphQLActivity{err: fmt.Errorf("GraphQL error: %s", userErrs[alias])}
+		case u == nil:
+			activity[login] = &graphQLActivity{err: fmt.Errorf("no such user")}
+		default:
+			activity[login] = u.activity()
+		}
+	}
+	return activity, nil
+}
+
+// graphQLRepo is a repo as a GraphQL activity query returns it.
+type graphQLRepo struct {
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	Name          string `json:"name"`
+	NameWithOwner string `json:"nameWithOwner"`
+	Description   string `json:"description"`
+	URL           string `json:"url"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	DatabaseID     int64 `json:"databaseId"`
+	StargazerCount int   `json:"stargazerCount"`
+	ForkCount      int   `json:"forkCount"`
+	IsPrivate      bool  `json:"isPrivate"`
+}
+
+// repository converts r to the REST API's representation.
+func (r graphQLRepo) repository() Repository {
+	repo := Repository{
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+		Name:        r.Name,
+		FullName:    r.NameWithOwner,
+		Description: r.Description,
+		HTMLURL:     r.URL,
+		Owner:       User{Login: r.Owner.Login},
+		ID:          r.DatabaseID,
+		StarCount:   r.StargazerCount,
+		ForkCount:   r.ForkCount,
+		Private:     r.IsPrivate,
+	}
+	if r.PrimaryLanguage != nil {
+		repo.Language = r.PrimaryLanguage.Name
+	}
+	for _, n := range r.RepositoryTopics.Nodes {
+		repo.Topics = append(repo.Topics, n.Topic.Name)
+	}
+	return repo
+}
+
+// graphQLRepoName is a repo as contributions name it.
+type graphQLRepoName struct {
+	NameWithOwner string `json:"nameWithOwner"`
+}
+
+// graphQLContribution is a pull request, issue or review contribution.
+type graphQLContribution struct {
+	OccurredAt time.Time `json:"occurredAt"`
+	Item       struct {
+		Title      string          `json:"title"`
+		URL        string          `json:"url"`
+		Repository graphQLRepoName `json:"repository"`
+		Number     int             `json:"number"`
+	}
+}
+
+// UnmarshalJSON reads the item from whichever of pullRequest or issue it
+// was asked for as.
+func (c *graphQLContribution) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		OccurredAt  time.Time       `json:"occurredAt"`
+		PullRequest json.RawMessage `json:"pullRequest"`
+		Issue       json.RawMessage `json:"issue"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	c.OccurredAt = raw.OccurredAt
+	item := raw.PullRequest
+	if len(item) == 0 {
+		item = raw.Issue
+	}
+	if len(item) == 0 || string(item) == "null" {
+		return nil
+	}
+	return json.Unmarshal(item, &c.Item)
+}
+
+// graphQLUser is a user as a GraphQL activity query returns them.
+type graphQLUser struct {
+	StarredRepositories struct {
+		Nodes []graphQLRepo `json:"nodes"`
+	} `json:"starredRepositories"`
+	Repositories struct {
+		Nodes []graphQLRepo `json:"nodes"`
+	} `json:"repositories"`
+	Login                   string `json:"login"`
+	Name                    string `json:"name"`
+	Bio                     string `json:"bio"`
+	Company                 string `json:"company"`
+	AvatarURL               string `json:"avatarUrl"`
+	URL                     string `json:"url"`
+	ContributionsCollection struct {
+		CommitContributionsByRepository []struct {
+			Repository    graphQLRepoName `json:"repository"`
+			Contributions struct {
+				Nodes []struct {
+					OccurredAt  time.Time `json:"occurredAt"`
+					CommitCount int       `json:"commitCount"`
+				} `json:"nodes"`
+			} `json:"contributions"`
+		} `json:"commitContributionsByRepository"`
+		PullRequestContributions struct {
+			Nodes []graphQLContribution `json:"nodes"`
+		} `json:"pullRequestContributions"`
+		IssueContributions struct {
+			Nodes []graphQLContribution `json:"nodes"`
+		} `json:"issueContributions"`
+		PullRequestReviewContributions struct {
+			Nodes []graphQLContribution `json:"nodes"`
+		} `json:"pullRequestReviewContributions"`
+		RepositoryContributions struct {
+			Nodes []struct {
+				OccurredAt time.Time       `json:"occurredAt"`
+				Repository graphQLRepoName `json:"repository"`
+			} `json:"nodes"`
+		} `json:"repositoryContributions"`
+	} `json:"contributionsCollection"`
+	DatabaseID int64 `json:"databaseId"`
+}
+
+// activity converts u to what the REST API would have had for them.
+func (u *graphQLUser) activity() *graphQLActivity {
+	a := &graphQLActivity{user: User{
+		Login:     u.Login,
+		AvatarURL: u.AvatarURL,
+		HTMLURL:   u.URL,
+		Name:      u.Name,
+		Bio:       u.Bio,
+		Company:   u.Company,
+		ID:        u.DatabaseID,
+	}}
+	for _, r := range u.StarredRepositories.Nodes {
+		a.starred = append(a.starred, r.repository())
+	}
+	for _, r := range u.Repositories.Nodes {
+		a.owned = append(a.owned, r.repository())
+	}
+
+	actor := User{Login: u.Login, AvatarURL: u.AvatarURL}
+	event := func(typ, repo string, at time.Time, payload any) {
+		e := Event{Type: typ, Actor: actor, Repo: EventRepo{Name: repo}, CreatedAt: at}
+		if payload != nil {
+			e.Payload, _ = json.Marshal(payload)
+		}
+		a.events = append(a.events, e)
+	}
+	cc := &u.ContributionsCollection
+	for _, byRepo := range cc.CommitContributionsByRepository {
+		for _, c := range byRepo.Contributions.Nodes {
+			event("PushEvent", byRepo.Repository.NameWithOwner, c.OccurredAt, map[string]int{"size": c.CommitCount})
+		}
+	}
+	for _, c := range cc.PullRequestContributions.Nodes {
+		event("PullRequestEvent", c.Item.Repository.NameWithOwner, c.OccurredAt, itemPayload("opened", "pull_request", c))
+	}
+	for _, c := range cc.IssueContributions.Nodes {
+		event("IssuesEvent", c.Item.Repository.NameWithOwner, c.OccurredAt, itemPayload("opened", "issue", c))
+	}
+	for _, c := range cc.PullRequestReviewContributions.Nodes {
+		event("PullRequestReviewEvent", c.Item.Repository.NameWithOwner, c.OccurredAt, itemPayload("created", "pull_request", c))
+	}
+	for _, c := range cc.RepositoryContributions.Nodes {
+		event("CreateEvent", c.Repository.NameWithOwner, c.OccurredAt, map[string]string{"ref_type": "repository"})
+	}
+	slices.SortStableFunc(a.events, func(x, y Event) int { return y.CreatedAt.Compare(x.CreatedAt) })
+	return a
+}
+
+// itemPayload returns a REST-style event payload for a pull request or
+// issue contribution, with the item under key.
+func itemPayload(action, key string, c graphQLContribution) map[string]any {
+	return map[string]any{
+		"action": action,
+		"number": c.Item.Number,
+		key:      map[string]any{"number": c.Item.Number, "title": c.Item.Title, "html_url": c.Item.URL},
+	}
+}
@@ -0,0 +1,199 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// graphQLUserJSON is a user as fakeGraphQL answers for them: a starred
+// repo, an owned repo, and a contribution of each kind.
+func graphQLUserJSON(login string) string {
+	return fmt.Sprintf(`{
+		"login": %[1]q, "name": "User %[1]s", "bio": "", "company": "", "avatarUrl": "https://avatars.example.com/%[1]s", "url": "https://github.com/%[1]s", "databaseId": 7,
+		"starredRepositories": {"nodes": [{"databaseId": 1, "name": "go", "nameWithOwner": "golang/go", "description": "Go", "url": "https://github.com/golang/go", "createdAt": "2009-11-10T00:00:00Z", "updatedAt": "2024-01-15T00:00:00Z", "stargazerCount": 100, "forkCount": 10, "isPrivate": false, "primaryLanguage": {"name": "Go"}, "repositoryTopics": {"nodes": [{"topic": {"name": "golang"}}, {"topic": {"name": "language"}}]}, "owner": {"login": "golang"}}]},
+		"repositories": {"nodes": [{"databaseId": 2, "name": "tool", "nameWithOwner": "%[1]s/tool", "description": "", "url": "https://github.com/%[1]s/tool", "createdAt": "2024-01-14T00:00:00Z", "updatedAt": "2024-01-14T00:00:00Z", "stargazerCount": 0, "forkCount": 0, "isPrivate": false, "primaryLanguage": null, "owner": {"login": %[1]q}}]},
+		"contributionsCollection": {
+			"commitContributionsByRepository": [{"repository": {"nameWithOwner": "%[1]s/tool"}, "contributions": {"nodes": [{"occurredAt": "2024-01-15T00:00:00Z", "commitCount": 3}]}}],
+			"pullRequestContributions": {"nodes": [{"occurredAt": "2024-01-15T12:00:00Z", "pullRequest": {"number": 5, "title": "Fix it", "url": "https://github.com/golang/go/pull/5", "repository": {"nameWithOwner": "golang/go"}}}]},
+			"issueContributions": {"nodes": [{"occurredAt": "2024-01-13T12:00:00Z", "issue": {"number": 6, "title": "Broken", "url": "https://github.com/golang/go/issues/6", "repository": {"nameWithOwner": "golang/go"}}}]},
+			"pullRequestReviewContributions": {"nodes": [{"occurredAt": "2024-01-14T12:00:00Z", "pullRequest": {"number": 7, "title": "Add it", "url": "https://github.com/golang/go/pull/7", "repository": {"nameWithOwner": "golang/go"}}}]},
+			"repositoryContributions": {"nodes": [{"occurredAt": "2024-01-14T00:00:00Z", "repository": {"nameWithOwner": "%[1]s/tool"}}]}
+		}
+	}`, login)
+}
+
+// fakeGraphQL answers activity queries for every login but those in
+// missing, counting the requests it gets. With failAll, it fails whole
+// queries of more than one user.
+type fakeGraphQL struct {
+	missing  map[string]bool
+	requests atomic.Int32
+	failAll  bool
+}
+
+func (f *fakeGraphQL) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	var req struct {
+		Query     string            `json:"query"`
+		Variables map[string]string `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.failAll && len(req.Variables) > 1 {
+		_, _ = w.Write([]byte(`{"errors": [{"message": "Something went wrong"}]}`))
+		return
+	}
+
+	vars := make([]string, 0, len(req.Variables))
+	for v := range req.Variables {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	var data, errs []string
+	for _, v := range vars {
+		alias := "u" + strings.TrimPrefix(v, "l")
+		if !strings.Contains(req.Query, alias+": user(login: $"+v+")") {
+			http.Error(w, "variable "+v+" isn't used as "+alias, http.StatusBadRequest)
+			return
+		}
+		login := req.Variables[v]
+		if f.missing[login] {
+			data = append(data, fmt.Sprintf("%q: null", alias))
+			errs = append(errs, fmt.Sprintf(`{"message": "Could not resolve to a User with the login of '%s'.", "path": [%q]}`, login, alias))
+			continue
+		}
+		data = append(data, fmt.Sprintf("%q: %s", alias, graphQLUserJSON(login)))
+	}
+	_, _ = fmt.Fprintf(w, `{"data": {%s}, "errors": [%s]}`, strings.Join(data, ","), strings.Join(errs, ","))
+}
+
+func TestGraphQLClientPrefetch(t *testing.T) {
+	fake := &fakeGraphQL{missing: map[string]bool{"ghost": true}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	g := NewGraphQLClient(NewClient("test-token", WithBaseURL(server.URL)))
+	ctx := context.Background()
+	logins := []string{"ghost"}
+	for i := range 2 * graphQLBatchSize {
+		logins = append(logins, fmt.Sprintf("user%d", i))
+	}
+	if err := g.Prefetch(ctx, logins); err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+	if got := fake.requests.Load(); got != 3 {
+		t.Errorf("Prefetch() made %d requests for %d users, want 3", got, len(logins))
+	}
+
+	starred, err := g.GetStarredReposByUsername(ctx, "user3")
+	if err != nil {
+		t.Fatalf("GetStarredReposByUsername() error = %v", err)
+	}
+	if len(starred) != 1 || starred[0].FullName != "golang/go" || starred[0].ID != 1 || starred[0].Language != "Go" || starred[0].Owner.Login != "golang" || !slices.Equal(starred[0].Topics, []string{"golang", "language"}) {
+		t.Errorf("GetStarredReposByUsername() = %+v", starred)
+	}
+	owned, err := g.GetOwnedReposByUsername(ctx, "user3")
+	if err != nil {
+		t.Fatalf("GetOwnedReposByUsername() error = %v", err)
+	}
+	if len(owned) != 1 || owned[0].FullName != "user3/tool" || owned[0].Language != "" {
+		t.Errorf("GetOwnedReposByUsername() = %+v", owned)
+	}
+	user, err := g.GetUser(ctx, "user3")
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if user.Login != "user3" || user.Name != "User user3" || user.ID != 7 {
+		t.Errorf("GetUser() = %+v", user)
+	}
+	if got := fake.requests.Load(); got != 3 {
+		t.Errorf("prefetched users cost %d more requests", got-3)
+	}
+
+	if _, err := g.GetRecentEvents(ctx, "ghost"); err == nil || !strings.Contains(err.Error(), "Could not resolve") {
+		t.Errorf("GetRecentEvents() for a missing user: error = %v", err)
+	}
+	if got := g.GetRequestStats().ByEndpoint["graphql"]; got != 3 {
+		t.Errorf("expected 3 graphql requests counted, got %d", got)
+	}
+}
+
+func TestGraphQLClientEvents(t *testing.T) {
+	server := httptest.NewServer(&fakeGraphQL{})
+	defer server.Close()
+
+	g := NewGraphQLClient(NewClient("test-token", WithBaseURL(server.URL)))
+	events, err := g.GetRecentEvents(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("GetRecentEvents() error = %v", err)
+	}
+
+	var got []string
+	for _, e := range events {
+		got = append(got, e.CreatedAt.Format("01-02T15")+" "+e.Type+" "+e.Repo.Name)
+		if e.Actor.Login != "alice" {
+			t.Errorf("event %s has actor %q", e.Type, e.Actor.Login)
+		}
+	}
+	want := []string{
+		"01-15T12 PullRequestEvent golang/go",
+		"01-15T00 PushEvent alice/tool",
+		"01-14T12 PullRequestReviewEvent golang/go",
+		"01-14T00 CreateEvent alice/tool",
+		"01-13T12 IssuesEvent golang/go",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("events, newest first:\n got %s\nwant %s", strings.Join(got, "\n     "), strings.Join(want, "\n     "))
+	}
+
+	var created struct {
+		RefType string `json:"ref_type"`
+	}
+	if err := json.Unmarshal(events[3].Payload, &created); err != nil || created.RefType != "repository" {
+		t.Errorf("CreateEvent payload = %s, want a repository ref_type", events[3].Payload)
+	}
+	var pr struct {
+		PullRequest struct {
+			Title string `json:"title"`
+		} `json:"pull_request"`
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal(events[0].Payload, &pr); err != nil || pr.Action != "opened" || pr.PullRequest.Title != "Fix it" {
+		t.Errorf("PullRequestEvent payload = %s", events[0].Payload)
+	}
+}
+
+func TestGraphQLClientFallsBackToOneUserAtATime(t *testing.T) {
+	fake := &fakeGraphQL{failAll: true}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	g := NewGraphQLClient(NewClient("test-token", WithBaseURL(server.URL)))
+	ctx := context.Background()
+	if err := g.Prefetch(ctx, []string{"alice", "bob"}); err == nil || !strings.Contains(err.Error(), "Something went wrong") {
+		t.Errorf("Prefetch() error = %v, want the query's error", err)
+	}
+
+	for _, login := range []string{"alice", "bob"} {
+		if _, err := g.GetStarredReposByUsername(ctx, login); err != nil {
+			t.Errorf("GetStarredReposByUsername(%s) error = %v", login, err)
+		}
+		if _, err := g.GetRecentEvents(ctx, login); err != nil {
+			t.Errorf("GetRecentEvents(%s) error = %v", login, err)
+		}
+	}
+	// One failed batch, then a query per user, cached for the next method
+	if got := fake.requests.Load(); got != 3 {
+		t.Errorf("got %d requests, want 3", got)
+	}
+}
@@ -4,6 +4,7 @@ go 1.24.1
 
 require (
 	github.com/mattn/go-isatty v0.0.20
+	go.etcd.io/bbolt v1.4.3
 	go.opentelemetry.io/otel v1.39.0
 	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.39.0
 	go.opentelemetry.io/otel/sdk v1.39.0
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// heartbeatTimeout bounds a heartbeat ping. Monitors answer at once, and a
+// slow one shouldn't hold up the run.
+const heartbeatTimeout = 10 * time.Second
+
+// pingHeartbeat pings url, a healthchecks.io-style check that raises an
+// alarm when the pings it expects stop arriving. Any non-2xx response is an
+// error.
+func pingHeartbeat(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, heartbeatTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("heartbeat url: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pinging heartbeat: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("heartbeat returned %s", resp.Status)
+	}
+	return nil
+}
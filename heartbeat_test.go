@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+func TestPingHeartbeat(t *testing.T) {
+	var pings atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pings.Add(1)
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	if err := pingHeartbeat(context.Background(), srv.URL+"/check"); err != nil {
+		t.Errorf("pingHeartbeat() error = %v", err)
+	}
+	if err := pingHeartbeat(context.Background(), srv.URL+"/down"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected an error for a 404, got %v", err)
+	}
+	if pings.Load() != 2 {
+		t.Errorf("expected 2 pings, got %d", pings.Load())
+	}
+	if err := pingHeartbeat(context.Background(), "://bad"); err == nil {
+		t.Error("expected an error for a malformed URL")
+	}
+}
+
+func TestRun_PingsHeartbeat(t *testing.T) {
+	setHome(t)
+	var pings atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { pings.Add(1) }))
+	defer srv.Close()
+
+	client := &mockGitHubClient{followedUsers: []github.User{{Login: "alice", ID: 1}}}
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return client },
+		StoreFactory:        func(path string) (Store, error) { return &mockStore{}, nil },
+		NotifierFactory:     func() Notifier { return &mockNotifier{} },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		OpenBrowser:         func(url string) error { return nil },
+		Now:                 fixedTime,
+	}
+	args := []string{"-token", "t", "-heartbeat-url", srv.URL, "-db", filepath.Join(t.TempDir(), "test.db"), "-report", filepath.Join(t.TempDir(), "report.html")}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(&stdout, &stderr, args, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if pings.Load() != 1 {
+		t.Errorf("expected a ping after a successful run, got %d", pings.Load())
+	}
+
+	client.followedErr = errors.New("GitHub API error")
+	if code := run(&stdout, &stderr, args, deps); code != 1 {
+		t.Fatalf("exit code %d, want a failure", code)
+	}
+	if pings.Load() != 1 {
+		t.Errorf("a failed run shouldn't ping, got %d pings", pings.Load())
+	}
+}
+
+func TestRun_HeartbeatFailureIsAWarning(t *testing.T) {
+	setHome(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return &mockGitHubClient{} },
+		StoreFactory:        func(path string) (Store, error) { return &mockStore{}, nil },
+		NotifierFactory:     func() Notifier { return &mockNotifier{} },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		OpenBrowser:         func(url string) error { return nil },
+		Now:                 fixedTime,
+	}
+	args := []string{"-token", "t", "-heartbeat-url", srv.URL, "-db", filepath.Join(t.TempDir(), "test.db"), "-report", filepath.Join(t.TempDir(), "report.html")}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(&stdout, &stderr, args, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "Warning: heartbeat returned 500") {
+		t.Errorf("expected a heartbeat warning, got %q", stderr.String())
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+const logUsage = `Usage:
+  gitstreams log [-user simonw] [-type star] [-since 90d] [-format table|json] [flags]`
+
+// defaultLogLimit is how many activities `gitstreams log` lists by default.
+const defaultLogLimit = 100
+
+// activitySearcher is implemented by stores that can search their
+// normalized activities. The log command refuses to run on other stores.
+type activitySearcher interface {
+	SearchActivities(f storage.ActivityFilter) ([]storage.Activity, error)
+}
+
+// logTypePattern is what a -type can look like: a kind, or an event type
+// such as pull_request or PullRequestEvent.
+var logTypePattern = regexp.MustCompile(`^[A-Za-z_]+$`)
+
+// runLog handles the "log" subcommand: it searches the activity stored
+// from past runs, newest first, turning the database into an activity log.
+func runLog(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	format := fs.ownFormat("table", "Output format: table or json")
+	users := fs.ownUser("Only this user's activity; comma-separated for several")
+	kind := fs.String("type", "", "Only this type: star, repo, event, or an event type like push or pull_request")
+	repo := fs.String("repo", "", "Only activity on this repo, as owner/name")
+	grep := fs.String("grep", "", "Only activity whose repo or details contain this text")
+	since := fs.String("since", "30d", "Activity since this date: YYYY-MM-DD or relative like 7d, 2w, 3m")
+	limit := fs.Int("n", defaultLogLimit, "How many activities to list, newest first (0 for all)")
+	profile := fs.String("profile", "", "Search a serve team profile's activity instead of your own")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if _, err := fs.resolve(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintln(stderr, logUsage)
+		return 1
+	}
+	var write func(io.Writer, []storage.Activity) error
+	switch *format {
+	case "table":
+		write = writeLogTable
+	case "json":
+		write = writeLogJSON
+	default:
+		_, _ = fmt.Fprintf(stderr, "Error: unsupported format %q (supported: table, json)\n", *format)
+		return 1
+	}
+	filter := storage.ActivityFilter{Usernames: splitList(*users), Repo: *repo, Text: *grep, Limit: *limit}
+	var err error
+	if filter.Kind, filter.EventType, err = parseLogType(*kind); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if filter.Since, err = parseSinceDate(*since, deps.Now()); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: invalid -since: %v\n", err)
+		return 1
+	}
+	// -user picks out users here rather than naming the account whose
+	// follows were tracked
+	tracked := *cfg
+	tracked.Username = ""
+	if filter.Scope, err = activityScope(&tracked, *profile); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Don't let the store create a database that isn't there
+	if _, err := os.Stat(dbFile(cfg.DBPath)); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: no database at %s; run gitstreams first\n", cfg.DBPath)
+		return 1
+	}
+	store, err := openStore(stderr, cfg, deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = store.Close() }()
+
+	as, ok := store.(activitySearcher)
+	if !ok {
+		_, _ = fmt.Fprintln(stderr, "Error: this database doesn't support searching activity")
+		return 1
+	}
+	if err := backfillActivities(store, filter.Scope, deps.Now()); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Warning: could not backfill activity: %v\n", err)
+	}
+	activities, err := as.SearchActivities(filter)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := write(stdout, activities); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error writing activity: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parseLogType returns the activity kind a -type stands for and, for a
+// single event type, GitHub's name for it. Stars and new repos go by the
+// report's names too, and event types by their snake_case names without
+// "Event", so pull_request is PullRequestEvent.
+func parseLogType(t string) (kind, eventType string, err error) {
+	switch strings.ToLower(t) {
+	case "":
+		return "", "", nil
+	case "star", "starred":
+		return storage.KindStar, "", nil
+	case "repo", "created_repo":
+		return storage.KindRepo, "", nil
+	case "event":
+		return storage.KindEvent, "", nil
+	}
+	if !logTypePattern.MatchString(t) {
+		return "", "", fmt.Errorf("invalid -type %q: use star, repo, event or an event type like push", t)
+	}
+	if strings.HasSuffix(t, "Event") {
+		return storage.KindEvent, t, nil
+	}
+	var b strings.Builder
+	for _, word := range strings.Split(strings.ToLower(t), "_") {
+		if word != "" {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return storage.KindEvent, b.String() + "Event", nil
+}
+
+// writeLogTable writes activities as aligned columns, in local time.
+func writeLogTable(w io.Writer, activities []storage.Activity) error {
+	if len(activities) == 0 {
+		_, err := fmt.Fprintln(w, "No matching activity.")
+		return err
+	}
+	// Tabs and newlines in details would break the alignment
+	flatten := strings.NewReplacer("\t", " ", "\n", " ")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TIME\tUSER\tTYPE\tREPO\tDETAILS")
+	for _, a := range activities {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			a.OccurredAt.Local().Format(time.DateTime), a.Username, exportType(a), a.Repo, flatten.Replace(a.Details))
+	}
+	return tw.Flush()
+}
+
+// logEntry is an activity as `gitstreams log -format json` writes it, with
+// the same fields as an export's columns.
+type logEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	User      string    `json:"user"`
+	Type      string    `json:"type"`
+	Repo      string    `json:"repo"`
+	Details   string    `json:"details,omitempty"`
+}
+
+// writeLogJSON writes activities as a JSON array.
+func writeLogJSON(w io.Writer, activities []storage.Activity) error {
+	entries := make([]logEntry, len(activities))
+	for i, a := range activities {
+		entries[i] = logEntry{Timestamp: a.OccurredAt.UTC(), User: a.Username, Type: exportType(a), Repo: a.Repo, Details: a.Details}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
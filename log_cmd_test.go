@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestRunLog(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := storage.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := fixedTime()
+	snap := diff.NewSnapshot(now)
+	snap.Users["simonw"] = diff.UserActivity{
+		Username: "simonw",
+		StarredRepos: []diff.Repo{
+			{Owner: "datasette", Name: "datasette", Description: "Explore and publish data", CreatedAt: now.AddDate(0, 0, -2)},
+			{Owner: "old", Name: "thing", CreatedAt: now.AddDate(0, 0, -120)},
+		},
+		Events: []diff.Event{{Type: "PullRequestEvent", Repo: "simonw/llm", CreatedAt: now.Add(-time.Hour)}},
+	}
+	snap.Users["alice"] = diff.UserActivity{
+		Username:     "alice",
+		StarredRepos: []diff.Repo{{Owner: "golang", Name: "go", CreatedAt: now.AddDate(0, 0, -1)}},
+	}
+	if _, err := saveSnapshotAs(store, runSnapshotID(&Config{}), snap, now); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		Now:          fixedTime,
+	}
+	var stdout, stderr strings.Builder
+	if code := run(&stdout, &stderr, []string{"log", "--user", "simonw", "--type", "star", "--since", "90d", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "TIME") || !strings.Contains(lines[1], "datasette/datasette") || !strings.Contains(lines[1], "Explore and publish data") {
+		t.Errorf("unexpected table:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"log", "-type", "pull_request", "-format", "json", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	var entries []logEntry
+	if err := json.Unmarshal([]byte(stdout.String()), &entries); err != nil {
+		t.Fatalf("output isn't JSON: %v\n%s", err, stdout.String())
+	}
+	if len(entries) != 1 || entries[0].User != "simonw" || entries[0].Type != "PullRequestEvent" || entries[0].Repo != "simonw/llm" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+
+	// Newest first, cut to -n
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"log", "-n", "1", "-format", "json", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if err := json.Unmarshal([]byte(stdout.String()), &entries); err != nil || len(entries) != 1 || entries[0].Repo != "simonw/llm" {
+		t.Errorf("-n 1 = %+v, %v; want only the newest activity", entries, err)
+	}
+
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"log", "-user", "bob", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "No matching activity.") {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+}
+
+func TestRunLog_Errors(t *testing.T) {
+	setHome(t)
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		Now:          fixedTime,
+	}
+	missing := filepath.Join(t.TempDir(), "missing.db")
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-format", "csv"}, "unsupported format"},
+		{[]string{"-type", "push!"}, "invalid -type"},
+		{[]string{"-since", "whenever"}, "invalid -since"},
+		{[]string{"-profile", "../x"}, "invalid profile name"},
+		{[]string{"simonw"}, "Usage:"},
+		{nil, "no database"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr strings.Builder
+		args := append([]string{"log", "-db", missing}, tt.args...)
+		if code := run(&stdout, &stderr, args, deps); code != 1 || !strings.Contains(stderr.String(), tt.wantErr) {
+			t.Errorf("log %v: code %d, stderr %q; want %q", tt.args, code, stderr.String(), tt.wantErr)
+		}
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Error("log created a database")
+	}
+}
+
+func TestParseLogType(t *testing.T) {
+	tests := []struct {
+		in, kind, eventType string
+	}{
+		{"", "", ""},
+		{"star", storage.KindStar, ""},
+		{"Starred", storage.KindStar, ""},
+		{"created_repo", storage.KindRepo, ""},
+		{"event", storage.KindEvent, ""},
+		{"push", storage.KindEvent, "PushEvent"},
+		{"pull_request", storage.KindEvent, "PullRequestEvent"},
+		{"PullRequestReviewEvent", storage.KindEvent, "PullRequestReviewEvent"},
+	}
+	for _, tt := range tests {
+		kind, eventType, err := parseLogType(tt.in)
+		if err != nil || kind != tt.kind || eventType != tt.eventType {
+			t.Errorf("parseLogType(%q) = %q, %q, %v; want %q, %q", tt.in, kind, eventType, err, tt.kind, tt.eventType)
+		}
+	}
+	if _, _, err := parseLogType("push event"); err == nil {
+		t.Error("expected an error for a type with a space")
+	}
+}
@@ -2,8 +2,10 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -12,23 +14,39 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/justinabrahms/gitstreams/config"
 	"github.com/justinabrahms/gitstreams/diff"
 	"github.com/justinabrahms/gitstreams/github"
 	"github.com/justinabrahms/gitstreams/notify"
 	"github.com/justinabrahms/gitstreams/otel"
 	"github.com/justinabrahms/gitstreams/progress"
 	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/source"
 	"github.com/justinabrahms/gitstreams/storage"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	defaultDBName   = "gitstreams.db"
-	snapshotUserID  = "followed_users"
+	defaultDBName     = "gitstreams.db"
+	defaultConfigName = "config.json"
+	snapshotUserID    = "followed_users"
+
+	// activityDataKey wraps the snapshot in the legacy (schema version 0)
+	// Activity map. It's only read now, for databases written by older builds.
 	activityDataKey = "snapshot_data"
+
+	// snapshotSchemaVersion tags the diff.Snapshot JSON stored in the data
+	// column. Bump it when the serialized shape changes incompatibly.
+	snapshotSchemaVersion = 1
 )
 
 // Version info set via ldflags at build time.
@@ -40,15 +58,61 @@ var (
 
 // Config holds the runtime configuration for gitstreams.
 type Config struct {
-	DBPath      string
-	Token       string
-	ReportPath  string
-	ReportSince string // Generate report from this date (e.g., '2026-01-15' or '7d')
-	Days        int    // How far back to fetch GitHub data (API sync lookback, default 30)
-	NoNotify    bool
-	NoOpen      bool
-	Verbose     bool
-	Offline     bool // Use only cached data, skip GitHub API calls
+	DBPath         string
+	Token          string
+	Username       string // Track users followed by this account (enables running without a token)
+	Org            string // Track this organization's members instead of followed users, grouped by team
+	ReportPath     string
+	ReportDir      string          // Where reports go when ReportPath isn't set (default: the temp directory)
+	ReportName     string          // File name pattern for reports in ReportDir, with {date}, {format} and {profile}
+	ReportSince    string          // Generate report from this date (e.g., '2026-01-15' or '7d')
+	Format         string          // Report format, as registered with report.Register
+	Template       string          // Template file replacing the report format's own, for formats that have one
+	FeedPath       string          // Atom feed each run's activities are added to, if set
+	OnError        string          // What a per-user fetch failure does: onErrorWarn, onErrorSkipUser or onErrorFail
+	API            string          // Which GitHub API fetches followed users' activity: apiREST or apiGraphQL
+	WebhookURL     string          // POST notifications here as JSON
+	EmailTo        string          // Comma-separated addresses to email the report to
+	EmailFrom      string          // Address the report is emailed from (default: SMTPUser)
+	SMTPAddr       string          // SMTP server reports are emailed through, as host or host:port
+	SMTPUser       string          // SMTP username, if the server needs authentication
+	SMTPPassword   string          // SMTP password, for SMTPUser
+	SlackWebhook   string          // Post notifications to this Slack incoming webhook
+	DiscordWebhook string          // Post notifications to this Discord webhook, with rich embeds
+	HeartbeatURL   string          // Pinged after each successful run, so a monitor notices when they stop
+	RulesPath      string          // Alert rules file (default: rules.json in the config directory, if present)
+	TagsPath       string          // Activity tags file (default: tags.json in the config directory, if present)
+	PresetsPath    string          // Filter presets file (default: presets.json in the config directory, if present)
+	RoutesPath     string          // Notification routes file (default: routes.json in the config directory, if present)
+	Preset         string          // Filter preset, from PresetsPath, the report is narrowed to
+	DigestAt       string          // Time of day ("09:00") to deliver the digest; runs in between only queue changes
+	GitHubURL      string          // Web address report links point to, for GitHub Enterprise Server
+	Sources        sourceList      // Several accounts to aggregate; replaces Token when set
+	CategoryOrder  categoryList    // Report sections to put first, in this order
+	HideCategories categoryList    // Report sections to leave out
+	Aggregate      aggregationList // How the report collapses each category's activities
+	Pin            pinList         // Repos whose activity goes in a pinned section at the top of the report
+	Watch          repoList        // Repos whose releases, pushes and stars are tracked for themselves
+	NoiseActors    actorList       // Users whose activity is automation noise, besides report.DefaultNoiseActors
+	NoiseAllow     actorList       // Users whose activity is never automation noise
+	Days           int             // How far back to fetch GitHub data (API sync lookback, default 30)
+	MinStars       int             // Leave stars of repos with fewer stars than this out of the report
+	Concurrency    int             // How many followed users to fetch at once
+	SlackHighs     int             // How many of the report's highlights Slack messages list
+	EmailLimit     int             // How many rows each section of the emailed report lists; 0 for all
+	Timeout        time.Duration   // Give up on the whole run after this long; 0 means no limit
+	NoNotify       bool
+	NoOpen         bool
+	Verbose        bool
+	Offline        bool // Use only cached data, skip GitHub API calls
+	Append         bool // Merge into the existing report at ReportPath instead of overwriting it
+	LegacyDirs     bool // Keep data and config in ~/.gitstreams instead of the XDG directories
+	NoUpdateCheck  bool // Don't check GitHub for a newer gitstreams release
+	NoBackup       bool // Don't back up the database before upgrading its schema
+	Discussions    bool // Also fetch GitHub Discussions activity, through the GraphQL API
+	Following      bool // Also track who followed users start following
+	HideBranches   bool // Leave branch creations and deletions out of the report
+	StarsNeedDesc  bool // Leave stars of repos without a description out of the report
 }
 
 // Dependencies holds injectable dependencies for testing.
@@ -56,9 +120,13 @@ type Dependencies struct {
 	GitHubClientFactory func(token string) GitHubClient
 	StoreFactory        func(dbPath string) (Store, error)
 	NotifierFactory     func() Notifier
-	ReportGenerator     func() (ReportGenerator, error)
+	ReportGenerator     func(format string) (ReportGenerator, error)
 	OpenBrowser         func(url string) error
 	Now                 func() time.Time
+	IsHeadless          func() bool                                        // Reports whether there's no display to show a browser or notification on
+	MigrateLegacyDirs   func() ([]string, error)                           // Moves ~/.gitstreams to the XDG directories; nil skips migration
+	LatestRelease       func(ctx context.Context) (*github.Release, error) // Finds the newest gitstreams release; nil skips the update check
+	RunLog              func(cfg *Config) (io.WriteCloser, error)          // Opens the log warnings and errors are copied to; nil disables it
 	Tracer              trace.Tracer
 	Logger              *slog.Logger
 }
@@ -66,14 +134,57 @@ type Dependencies struct {
 // GitHubClient defines the GitHub API operations we need.
 type GitHubClient interface {
 	GetFollowedUsers(ctx context.Context) ([]github.User, error)
+	GetFollowedUsersByUsername(ctx context.Context, username string) ([]github.User, error)
 	GetStarredReposByUsername(ctx context.Context, username string) ([]github.Repository, error)
 	GetOwnedReposByUsername(ctx context.Context, username string) ([]github.Repository, error)
 	GetRecentEvents(ctx context.Context, username string) ([]github.Event, error)
 }
 
+// requestStatsProvider is implemented by clients that count API requests per
+// endpoint class. Runs with other clients report no request counts.
+type requestStatsProvider interface {
+	GetRequestStats() github.RequestStats
+}
+
+// prefetchingClient is implemented by clients that can fetch many users'
+// activity up front in fewer requests than asking about each in turn. Other
+// clients fetch each user in turn.
+type prefetchingClient interface {
+	Prefetch(ctx context.Context, logins []string) error
+}
+
+// clockSetter is implemented by clients, stores and report generators that
+// tell the time, so they can tell it by deps.Now. useClock leaves anything
+// else alone.
+type clockSetter interface {
+	SetNow(now func() time.Time)
+}
+
+// useClock has v tell the time by now, if it tells the time at all.
+func useClock(v any, now func() time.Time) {
+	if c, ok := v.(clockSetter); ok && now != nil {
+		c.SetNow(now)
+	}
+}
+
+// budgetAwareClient is implemented by clients that can report their remaining
+// API budget and cap pagination depth. Other clients fetch the whole plan
+// regardless of budget.
+type budgetAwareClient interface {
+	FetchRateLimit(ctx context.Context) (*github.RateLimit, error)
+	SetMaxPages(n int)
+}
+
+// templatedGenerator is implemented by report generators whose output a
+// user's template can replace. -template is refused for other formats.
+type templatedGenerator interface {
+	SetTemplate(text string) error
+}
+
 // Store defines the storage operations we need.
 type Store interface {
 	Save(snapshot *storage.Snapshot) error
+	SaveAll(snapshots []*storage.Snapshot) error
 	GetByUser(userID string, limit int) ([]*storage.Snapshot, error)
 	GetByTimeRange(userID string, start, end time.Time) ([]*storage.Snapshot, error)
 	Close() error
@@ -96,18 +207,22 @@ func DefaultDependencies() *Dependencies {
 			return github.NewClient(token)
 		},
 		StoreFactory: func(dbPath string) (Store, error) {
-			return storage.NewSQLiteStore(dbPath)
+			return storage.Open(dbPath)
 		},
 		NotifierFactory: func() Notifier {
 			return notify.NewMacNotifier()
 		},
-		ReportGenerator: func() (ReportGenerator, error) {
-			return report.NewHTMLGenerator()
+		ReportGenerator: func(format string) (ReportGenerator, error) {
+			return report.New(format)
 		},
-		OpenBrowser: openBrowser,
-		Now:         time.Now,
-		Tracer:      otel.Tracer(),
-		Logger:      slog.Default(),
+		OpenBrowser:       openBrowser,
+		Now:               time.Now,
+		IsHeadless:        detectHeadless,
+		MigrateLegacyDirs: migrateDefaultDirs,
+		LatestRelease:     latestGitstreamsRelease,
+		RunLog:            openRunLog,
+		Tracer:            otel.Tracer(),
+		Logger:            slog.Default(),
 	}
 }
 
@@ -121,7 +236,32 @@ func main() {
 }
 
 func run(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
-	cfg, err := parseFlags(args)
+	if len(args) > 0 {
+		switch args[0] {
+		case "config":
+			return runConfig(stdout, stderr, args[1:])
+		case "debug":
+			return runDebug(stdout, stderr, args[1:], deps)
+		case "serve":
+			return runServe(stdout, stderr, args[1:], deps)
+		case "watch":
+			return runWatch(stdout, stderr, args[1:], deps)
+		case "discover":
+			return runDiscover(stdout, stderr, args[1:], deps)
+		case "export":
+			return runExport(stdout, stderr, args[1:], deps)
+		case "query":
+			return runQuery(stdout, stderr, args[1:], deps)
+		case "log":
+			return runLog(stdout, stderr, args[1:], deps)
+		case "db":
+			return runDB(stdout, stderr, args[1:], deps)
+		case "runs":
+			return runRuns(stdout, stderr, args[1:], deps)
+		}
+	}
+
+	cfg, sources, err := parseFlagsWithSources(args)
 	if err != nil {
 		if err == errVersion {
 			_, _ = fmt.Fprintf(stdout, "gitstreams %s (commit: %s, built: %s)\n", version, commit, date)
@@ -131,8 +271,51 @@ func run(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
 		return 1
 	}
 
+	if deps.MigrateLegacyDirs != nil && !cfg.LegacyDirs && sources["db"] == sourceDefault {
+		notices, err := deps.MigrateLegacyDirs()
+		for _, notice := range notices {
+			_, _ = fmt.Fprintln(stdout, notice)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: failed to move ~/.gitstreams to the XDG directories: %v\n", err)
+			useLegacyDB(cfg)
+		}
+	}
+
+	// Keep a log of warnings, errors and crashes for `gitstreams debug bundle`
+	if deps.RunLog != nil {
+		if logFile, err := deps.RunLog(cfg); err == nil {
+			defer func() { _ = logFile.Close() }()
+			log := newLogWriter(logFile, deps.Now)
+			_, _ = fmt.Fprintf(log, "gitstreams %s started\n", version)
+			stderr = io.MultiWriter(stderr, log)
+			defer func() {
+				if r := recover(); r != nil {
+					_, _ = fmt.Fprintf(log, "panic: %v\n%s", r, debug.Stack())
+					panic(r)
+				}
+			}()
+		}
+	}
+
+	if deps.IsHeadless != nil && deps.IsHeadless() {
+		applyHeadlessDefaults(cfg, sources)
+		if cfg.Verbose {
+			_, _ = fmt.Fprintln(stdout, "Headless environment detected: browser and desktop notifications disabled unless set explicitly")
+		}
+	}
+
+	// Everything from here on, network calls included, gives up at -timeout
+	ctx, cancel := runContext(cfg.Timeout)
+	defer cancel()
+
+	// Announce a newer release after everything else this run prints
+	releaseNotice := updateNoticeFor(ctx, cfg, deps, stderr)
+	if releaseNotice != "" {
+		defer func() { _, _ = fmt.Fprintln(stdout, releaseNotice) }()
+	}
+
 	// Initialize OpenTelemetry (optional, only if OTEL env vars are set)
-	ctx := context.Background()
 	_, cleanup, err := otel.Setup(ctx, deps.Logger)
 	if err != nil {
 		_, _ = fmt.Fprintf(stderr, "Warning: failed to initialize OpenTelemetry: %v\n", err)
@@ -147,297 +330,449 @@ func run(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
 	}()
 
 	// Open storage first to support both live and historical modes
-	store, err := deps.StoreFactory(cfg.DBPath)
+	store, err := openStore(stderr, cfg, deps)
 	if err != nil {
 		_, _ = fmt.Fprintf(stderr, "Error opening database: %v\n", err)
 		return 1
 	}
 	defer func() { _ = store.Close() }()
+	if writer := newerWriter(store, runSnapshotID(cfg)); writer != "" {
+		_, _ = fmt.Fprintf(stderr, "Warning: the database was last written by gitstreams %s, newer than this version (%s); upgrade if anything looks missing\n", writer, version)
+	}
 
-	var currentSnapshot, previousSnapshot *diff.Snapshot
+	p := &pipeline{cfg: cfg, deps: deps, store: store, stdout: stdout, stderr: stderr, releaseNotice: releaseNotice}
+	if err := p.execute(ctx); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
 
-	// Historical mode: generate report from cached data
-	if cfg.ReportSince != "" {
-		var sinceDate time.Time
-		sinceDate, err = parseSinceDate(cfg.ReportSince, deps.Now())
-		if err != nil {
-			_, _ = fmt.Fprintf(stderr, "Error parsing --report-since date: %v\n", err)
-			return 1
-		}
+// appendReportRefresh is how often an appended report reloads in the browser.
+const appendReportRefresh = 5 * time.Minute
 
-		if cfg.Verbose {
-			_, _ = fmt.Fprintf(stdout, "Historical mode: comparing data from %s to present\n", sinceDate.Format("2006-01-02"))
-		}
+// reportDataPath returns where the report data backing an appended HTML
+// report is kept: beside it, with a .json extension.
+func reportDataPath(reportPath string) string {
+	return strings.TrimSuffix(reportPath, filepath.Ext(reportPath)) + ".json"
+}
 
-		// Get snapshot from the "since" date
-		var sinceSnapshots []*storage.Snapshot
-		sinceSnapshots, err = store.GetByTimeRange(snapshotUserID, sinceDate.Add(-24*time.Hour), sinceDate.Add(24*time.Hour))
-		if err != nil {
-			_, _ = fmt.Fprintf(stderr, "Error querying snapshots for --report-since date: %v\n", err)
-			return 1
-		}
-		if len(sinceSnapshots) == 0 {
-			_, _ = fmt.Fprintf(stderr, "No cached snapshot found for date %s (try running without --report-since first to build cache)\n", sinceDate.Format("2006-01-02"))
-			return 1
-		}
-		previousSnapshot, err = storageToSnapshot(sinceSnapshots[0])
-		if err != nil {
-			_, _ = fmt.Fprintf(stderr, "Error loading historical snapshot: %v\n", err)
-			return 1
-		}
-
-		// Get current snapshot: either from cache (offline) or from GitHub (live)
-		if cfg.Offline {
-			// Use most recent cached snapshot
-			var recentSnapshots []*storage.Snapshot
-			recentSnapshots, err = store.GetByUser(snapshotUserID, 1)
-			if err != nil {
-				_, _ = fmt.Fprintf(stderr, "Error loading most recent snapshot: %v\n", err)
-				return 1
-			}
-			if len(recentSnapshots) == 0 {
-				_, _ = fmt.Fprintf(stderr, "No cached snapshots available (run without --offline first)\n")
-				return 1
-			}
-			currentSnapshot, err = storageToSnapshot(recentSnapshots[0])
-			if err != nil {
-				_, _ = fmt.Fprintf(stderr, "Error loading current snapshot: %v\n", err)
-				return 1
-			}
-			if cfg.Verbose {
-				_, _ = fmt.Fprintf(stdout, "Using cached snapshot from %s\n", currentSnapshot.CapturedAt.Format("2006-01-02"))
-			}
-		} else {
-			// Fetch fresh data from GitHub
-			if cfg.Token == "" {
-				_, _ = fmt.Fprintln(stderr, "Error: GITHUB_TOKEN environment variable is required (or use --offline)")
-				return 1
-			}
-			ctx := context.Background()
-			client := deps.GitHubClientFactory(cfg.Token)
-			cutoff := deps.Now().AddDate(0, 0, -cfg.Days)
-			currentSnapshot, err = fetchActivity(ctx, client, deps.Now(), cutoff, stdout, stderr, cfg.Verbose)
-			if err != nil {
-				_, _ = fmt.Fprintf(stderr, "Error fetching activity: %v\n", err)
-				return 1
-			}
-			if cfg.Verbose {
-				_, _ = fmt.Fprintf(stdout, "Fetched activity for %d users\n", len(currentSnapshot.Users))
-			}
-			// Don't save in historical mode
-		}
-	} else if cfg.Offline {
-		// Standalone offline mode: use cached data without historical comparison
-		var snapshots []*storage.Snapshot
-		snapshots, err = store.GetByUser(snapshotUserID, 1)
-		if err != nil {
-			_, _ = fmt.Fprintf(stderr, "Error loading cached snapshot: %v\n", err)
-			return 1
-		}
+// mergeExistingReport merges rpt into the report data saved beside
+// reportPath by an earlier append run. If there is none, rpt is returned
+// as-is and the bool is false.
+func mergeExistingReport(reportPath string, rpt *report.Report) (*report.Report, bool, error) {
+	f, err := os.Open(reportDataPath(reportPath)) // #nosec G304 -- derived from the user-specified report path
+	if errors.Is(err, os.ErrNotExist) {
+		return rpt, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = f.Close() }()
 
-		if len(snapshots) == 0 {
-			_, _ = fmt.Fprintln(stderr, "Error: No cached data available. Run without --offline first to fetch data from GitHub.")
-			return 1
-		}
+	existing, err := report.ReadJSON(f)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", reportDataPath(reportPath), err)
+	}
+	existing.Merge(rpt)
+	return existing, true, nil
+}
 
-		currentSnapshot, err = storageToSnapshot(snapshots[0])
+// updateFeed adds rpt's activities to the Atom feed at path, starting a new
+// feed if there isn't one yet.
+func updateFeed(path string, rpt *report.Report) error {
+	feed := report.NewFeed("GitStreams")
+	f, err := os.Open(path) // #nosec G304 -- path is user-specified via flag
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		feed, err = report.ReadFeed(f)
+		_ = f.Close()
 		if err != nil {
-			_, _ = fmt.Fprintf(stderr, "Error loading cached snapshot: %v\n", err)
-			return 1
+			return fmt.Errorf("%s: %w", path, err)
 		}
+	}
+	feed.Add(rpt)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, feed.Write)
+}
 
-		_, _ = fmt.Fprintf(stdout, "Using cached data from %s (may be stale)\n", currentSnapshot.CapturedAt.Format("2006-01-02 15:04:05"))
+// writeFileAtomic writes path via a temporary file in the same directory and
+// renames it into place, so a browser reloading the file never sees it half
+// written.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
 
-		if cfg.Verbose {
-			_, _ = fmt.Fprintf(stdout, "Loaded cached activity for %d users\n", len(currentSnapshot.Users))
-		}
+	if err := write(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
 
-		// In standalone offline mode, use an empty snapshot as "previous" so the report shows all activities in the cached snapshot
-		previousSnapshot = diff.NewSnapshot(time.Time{})
-	} else {
-		// Normal mode: fetch current data and compare with most recent snapshot
-		if cfg.Token == "" {
-			_, _ = fmt.Fprintln(stderr, "Error: GITHUB_TOKEN environment variable is required")
-			return 1
-		}
+// defaultConcurrency is how many users are fetched at once by default:
+// enough to cut a long follow list's sync to a fraction of the time, few
+// enough to stay clear of GitHub's secondary rate limits.
+const defaultConcurrency = 4
 
-		ctx := context.Background()
-		client := deps.GitHubClientFactory(cfg.Token)
-		cutoff := deps.Now().AddDate(0, 0, -cfg.Days)
-		currentSnapshot, err = fetchActivity(ctx, client, deps.Now(), cutoff, stdout, stderr, cfg.Verbose)
-		if err != nil {
-			_, _ = fmt.Fprintf(stderr, "Error fetching activity: %v\n", err)
-			return 1
-		}
+// defaultTimeout bounds a run so a hung network call can't stall a cron job
+// forever. It's generous: a full sync of a long follow list is slow.
+const defaultTimeout = 30 * time.Minute
 
-		if cfg.Verbose {
-			_, _ = fmt.Fprintf(stdout, "Fetched activity for %d users\n", len(currentSnapshot.Users))
-		}
+// runContext returns the context a run works under, cancelled after timeout
+// unless timeout is 0.
+func runContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
 
-		previousSnapshot, err = loadPreviousSnapshot(store)
-		if err != nil {
-			_, _ = fmt.Fprintf(stderr, "Error loading previous snapshot: %v\n", err)
-			return 1
-		}
+// explainTimeout points at -timeout when err is the run's deadline passing.
+func explainTimeout(err error, timeout time.Duration) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("gave up after -timeout %s: %w", timeout, err)
+	}
+	return err
+}
 
-		// Save current snapshot
-		if saveErr := saveSnapshot(store, currentSnapshot, deps.Now()); saveErr != nil {
-			_, _ = fmt.Fprintf(stderr, "Error saving snapshot: %v\n", saveErr)
-			return 1
-		}
+// errVersion is a sentinel error indicating -version was requested.
+var errVersion = fmt.Errorf("version requested")
 
-		if cfg.Verbose {
-			_, _ = fmt.Fprintln(stdout, "Saved current snapshot")
-		}
-	}
+func parseFlags(args []string) (*Config, error) {
+	cfg, _, err := parseFlagsWithSources(args)
+	return cfg, err
+}
 
-	// Compare snapshots
-	if cfg.Verbose {
-		_, _ = fmt.Fprintf(stdout, "Previous snapshot has %d users, current snapshot has %d users\n",
-			len(previousSnapshot.Users), len(currentSnapshot.Users))
-	}
+// Setting sources reported by `config show`.
+const (
+	sourceDefault = "default"
+	sourceFlag    = "flag"
+	sourceFile    = "config file"
+)
 
-	result := diff.Compare(previousSnapshot, currentSnapshot)
+// flagSet holds the command-line flags and where they're bound.
+type flagSet struct {
+	*flag.FlagSet
+	cfg         *Config
+	allowed     map[string]bool // flags that may be set from the config file
+	configPath  string
+	showVersion bool
+}
 
-	// Filter results by since date if specified
-	// This is needed because snapshots contain historical data (e.g., 30 days),
-	// so we need to filter out activities that occurred before the since date
-	if cfg.ReportSince != "" {
-		var filterDate time.Time
-		filterDate, err = parseSinceDate(cfg.ReportSince, deps.Now())
-		if err != nil {
-			_, _ = fmt.Fprintf(stderr, "Error parsing --report-since date for filtering: %v\n", err)
-			return 1
-		}
-		result = filterResultBySinceDate(result, filterDate)
-		if cfg.Verbose {
-			_, _ = fmt.Fprintf(stdout, "Filtered results to only show activity from %s onwards\n", filterDate.Format("2006-01-02"))
-		}
+func newFlagSet() *flagSet {
+	f := &flagSet{
+		FlagSet: flag.NewFlagSet("gitstreams", flag.ContinueOnError),
+		cfg:     &Config{},
 	}
-
-	if cfg.Verbose {
-		_, _ = fmt.Fprintf(stdout, "Diff result: NewStars=%d, NewRepos=%d, NewEvents=%d, NewUsers=%d, GoneUsers=%d\n",
-			len(result.NewStars), len(result.NewRepos), len(result.NewEvents), len(result.NewUsers), len(result.GoneUsers))
+	cfg := f.cfg
+	f.StringVar(&f.configPath, "config", "", "Path to JSON config file (default: $GITSTREAMS_CONFIG or $XDG_CONFIG_HOME/gitstreams/config.json)")
+	f.StringVar(&cfg.DBPath, "db", "", "Path to SQLite database, or bolt://path for a Bolt one (default: $XDG_DATA_HOME/gitstreams/gitstreams.db)")
+	f.StringVar(&cfg.Token, "token", "", "GitHub token (default: $GITHUB_TOKEN)")
+	f.StringVar(&cfg.Username, "user", "", "Track users followed by this GitHub account (allows running without a token)")
+	f.StringVar(&cfg.Org, "org", "", "Track the members of this GitHub organization instead of who you follow, with the report grouped by team (needs a token)")
+	f.BoolVar(&cfg.NoNotify, "no-notify", false, "Skip desktop notification")
+	f.BoolVar(&cfg.NoOpen, "no-open", false, "Don't open report in browser")
+	f.StringVar(&cfg.ReportPath, "report", "", "Path to write the report (default: -report-name in -report-dir)")
+	f.StringVar(&cfg.ReportDir, "report-dir", "", "Directory to write reports to (default: temp directory)")
+	f.StringVar(&cfg.ReportName, "report-name", defaultReportName, "Report file name, with {date}, {format} and {profile} (the -org or -user account, or default) filled in")
+	f.StringVar(&cfg.Format, "format", report.FormatHTML, "Report format: "+strings.Join(report.Formats(), ", "))
+	f.StringVar(&cfg.Template, "template", "", "Template file to write the report with instead of the format's own (html only)")
+	f.StringVar(&cfg.FeedPath, "feed", "", "Also add each run's activities to the Atom feed at this path, for feed readers")
+	f.BoolVar(&cfg.Verbose, "v", false, "Verbose output")
+	f.BoolVar(&f.showVersion, "version", false, "Print version and exit")
+	f.IntVar(&cfg.Days, "sync-lookback-days", 30, "How far back to fetch GitHub data (1-365 days, doesn't affect report filtering)")
+	f.StringVar(&cfg.ReportSince, "report-since", "", "Generate report from historical data starting from this date (e.g., '2026-01-15' or '7d' for 7 days ago)")
+	f.BoolVar(&cfg.Offline, "offline", false, "Use only cached data, skip GitHub API calls")
+	f.BoolVar(&cfg.Append, "append-report", false, "Add new activity to the day's existing report instead of overwriting it")
+	f.StringVar(&cfg.WebhookURL, "webhook", "", "Also POST notifications as JSON to this URL")
+	f.StringVar(&cfg.EmailTo, "email-to", "", "Also email the report to these comma-separated addresses, through the -smtp server")
+	f.StringVar(&cfg.EmailFrom, "email-from", "", "Address to email the report from (default: -smtp-user)")
+	f.IntVar(&cfg.EmailLimit, "email-section-limit", 10, "How many rows each section of the emailed report lists before summing up the rest (0 for all)")
+	f.StringVar(&cfg.SMTPAddr, "smtp", "", "SMTP server to email the report through, as host or host:port (default port 587)")
+	f.StringVar(&cfg.SMTPUser, "smtp-user", "", "SMTP username, if the server needs authentication")
+	f.StringVar(&cfg.SMTPPassword, "smtp-password", "", "SMTP password (best set as $GITSTREAMS_SMTP_PASSWORD)")
+	f.StringVar(&cfg.SlackWebhook, "slack-webhook", "", "Also post notifications to this Slack incoming webhook URL")
+	f.StringVar(&cfg.DiscordWebhook, "discord-webhook", "", "Also post notifications to this Discord webhook URL")
+	f.StringVar(&cfg.HeartbeatURL, "heartbeat-url", "", "Ping this URL after each successful run, e.g. a healthchecks.io check")
+	f.IntVar(&cfg.SlackHighs, "slack-highlights", 3, "How many of the report's highlights Slack messages list, up to 5 (0 for none)")
+	f.StringVar(&cfg.DigestAt, "digest-at", "", "Only write the report and notify on the first run after this time of day (HH:MM); other runs queue their changes")
+	f.Var(&cfg.Sources, "source", "Aggregate several accounts as label=token (or label=$ENV_VAR); repeatable, replaces -token")
+	f.StringVar(&cfg.RulesPath, "rules", "", "Path to alert rules JSON file (default: $XDG_CONFIG_HOME/gitstreams/rules.json, if present)")
+	f.StringVar(&cfg.TagsPath, "tags", "", "Path to activity tags JSON file (default: $XDG_CONFIG_HOME/gitstreams/tags.json, if present)")
+	f.StringVar(&cfg.PresetsPath, "presets", "", "Path to filter presets JSON file (default: $XDG_CONFIG_HOME/gitstreams/presets.json, if present)")
+	f.StringVar(&cfg.Preset, "preset", "", "Narrow the report to a filter preset from the presets file, e.g. \"work\"")
+	f.StringVar(&cfg.RoutesPath, "routes", "", "Path to notification routes JSON file (default: $XDG_CONFIG_HOME/gitstreams/routes.json, if present)")
+	f.BoolVar(&cfg.LegacyDirs, "legacy-dirs", false, "Keep data and config in ~/.gitstreams instead of the XDG base directories")
+	f.BoolVar(&cfg.NoUpdateCheck, "no-update-check", false, "Don't check daily for a newer gitstreams release")
+	f.BoolVar(&cfg.NoBackup, "no-backup", false, "Don't back up the database before upgrading its schema")
+	f.BoolVar(&cfg.Discussions, "discussions", false, "Also fetch the discussions followed users start and comment on (needs a token)")
+	f.BoolVar(&cfg.Following, "following", false, "Also track who followed users start following (a request per user; needs a token)")
+	f.Var(&cfg.CategoryOrder, "category-order", "Report sections to put first, in this order, by activity type (e.g. released,pull_request); repeatable")
+	f.Var(&cfg.HideCategories, "hide-categories", "Report sections to leave out, by activity type (e.g. pushed); repeatable")
+	f.Var(&cfg.Pin, "pin", "Repos whose activity goes in a pinned section at the top of the report, as owner/name or owner/* (e.g. golang/go); repeatable")
+	f.Var(&cfg.Watch, "watch-repo", "Repos to track releases, pushes and star growth of, whoever follows them, as owner/name (e.g. golang/go); repeatable")
+	f.Var(&cfg.NoiseActors, "noise-actors", "Users whose activity goes in the report's collapsed automation noise section, besides known bots, as patterns with * (e.g. deploy-*); repeatable")
+	f.Var(&cfg.NoiseAllow, "noise-allow", "Users whose activity is never automation noise, even bots and force pushes, as patterns with * (\"*\" turns it off); repeatable")
+	f.Var(&cfg.Aggregate, "aggregate", "How to collapse similar activities, as category:daily or category:N (only collapse N or more), with \"all\" for every category; repeatable")
+	f.StringVar(&cfg.GitHubURL, "github-url", report.DefaultWebURL, "Web address of the GitHub instance report links point to (for GitHub Enterprise Server)")
+	f.BoolVar(&cfg.HideBranches, "hide-branches", false, "Leave branches being created and deleted out of the report")
+	f.IntVar(&cfg.MinStars, "min-stars", 0, "Leave out stars of repos with fewer than this many stars of their own, such as empty scratch repos")
+	f.BoolVar(&cfg.StarsNeedDesc, "hide-undescribed-stars", false, "Leave out stars of repos without a description")
+	f.StringVar(&cfg.OnError, "on-error", onErrorWarn, "What to do when some of a user's activity can't be fetched: warn (keep the rest), skip-user (keep their last snapshot instead) or fail")
+	f.DurationVar(&cfg.Timeout, "timeout", defaultTimeout, "Give up if the whole run takes longer than this (0 for no limit)")
+	f.IntVar(&cfg.Concurrency, "concurrency", defaultConcurrency, "How many followed users to fetch at once")
+	f.StringVar(&cfg.API, "api", apiREST, "GitHub API to fetch activity through: rest, or graphql to batch many users per request (needs a token)")
+
+	f.Usage = func() {
+		out := f.Output()
+		_, _ = fmt.Fprintln(out, "Usage of gitstreams:")
+		f.PrintDefaults()
+		_, _ = fmt.Fprintf(out, "\nEach flag can also be set in the config file or with a %s* environment\nvariable, e.g. %s for -no-open and %s for -v.\n",
+			envPrefix, envVarName("no-open"), envVarName("v"))
 	}
 
-	if result.IsEmpty() {
-		_, _ = fmt.Fprintln(stdout, "No new activity detected.")
-		return 0
-	}
+	// Every setting so far, but not flags added later by subcommands
+	f.allowed = make(map[string]bool)
+	f.VisitAll(func(fl *flag.Flag) {
+		if fl.Name != "config" && fl.Name != "version" {
+			f.allowed[fl.Name] = true
+		}
+	})
+	return f
+}
+
+// ownFormat repurposes -format for a subcommand's own output formats,
+// defaulting to def. It counts as given, so a report format from the config
+// file or environment doesn't leak in.
+func (f *flagSet) ownFormat(def, usage string) *string {
+	fl := f.Lookup("format")
+	fl.Usage, fl.DefValue = usage, def
+	_ = f.Set("format", def)
+	return &f.cfg.Format
+}
 
-	// Generate report
-	rpt := buildReportWithLogging(result, previousSnapshot.CapturedAt, currentSnapshot.CapturedAt, deps.Now(), stderr, cfg.Verbose)
+// ownUser repurposes -user for a subcommand that picks out users' activity
+// rather than tracking an account's follows. Like ownFormat, it counts as
+// given, so the tracked account from the config file or environment doesn't
+// leak in.
+func (f *flagSet) ownUser(usage string) *string {
+	fl := f.Lookup("user")
+	fl.Usage = usage
+	_ = f.Set("user", "")
+	return &f.cfg.Username
+}
 
-	reportPath := cfg.ReportPath
-	if reportPath == "" {
-		reportPath = filepath.Join(os.TempDir(), fmt.Sprintf("gitstreams-%s.html", deps.Now().Format("2006-01-02")))
+// parseFlagsWithSources parses args, fills in settings not given on the
+// command line from the config file, and applies defaults. It also reports
+// where each setting came from.
+func parseFlagsWithSources(args []string) (*Config, map[string]string, error) {
+	fs := newFlagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if fs.showVersion {
+		return nil, nil, errVersion
 	}
 
-	generator, err := deps.ReportGenerator()
+	sources, err := fs.resolve()
 	if err != nil {
-		_, _ = fmt.Fprintf(stderr, "Error creating report generator: %v\n", err)
-		return 1
+		return nil, nil, err
+	}
+	// Checked here rather than in validate, as subcommands give -format
+	// their own meaning
+	if !slices.Contains(report.Formats(), fs.cfg.Format) {
+		return nil, nil, fmt.Errorf("unknown report format %q (supported: %s)", fs.cfg.Format, strings.Join(report.Formats(), ", "))
 	}
+	return fs.cfg, sources, nil
+}
+
+// resolve layers the config file under the parsed command line, validates
+// the result and fills in defaults. It returns each setting's source.
+func (f *flagSet) resolve() (map[string]string, error) {
+	cfg := f.cfg
+	sources := make(map[string]string)
+	f.VisitAll(func(fl *flag.Flag) { sources[fl.Name] = sourceDefault })
+	f.Visit(func(fl *flag.Flag) { sources[fl.Name] = sourceFlag })
+
+	// Precedence: flags > environment > config file > defaults
+	skip := make(map[string]bool)
+	f.Visit(func(fl *flag.Flag) { skip[fl.Name] = true })
 
-	f, err := os.Create(reportPath) // #nosec G304 -- reportPath is user-specified via flag or safe default
+	if f.configPath == "" {
+		f.configPath = os.Getenv(envPrefix + "CONFIG")
+	}
+	file, err := loadConfigFile(f.configPath)
 	if err != nil {
-		_, _ = fmt.Fprintf(stderr, "Error creating report file: %v\n", err)
-		return 1
+		return nil, err
+	}
+	if file != nil {
+		applied, err := file.Apply(f.FlagSet, f.allowed, skip)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range applied {
+			sources[name] = sourceFile
+		}
+		f.configPath = file.Path
 	}
 
-	if err := generator.Generate(f, rpt); err != nil {
-		_ = f.Close()
-		_, _ = fmt.Fprintf(stderr, "Error generating report: %v\n", err)
-		return 1
+	applied, err := config.ApplyEnv(f.FlagSet, f.allowed, skip, envVarName, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range applied {
+		sources[name] = "environment (" + envVarName(name) + ")"
 	}
-	_ = f.Close()
 
-	_, _ = fmt.Fprintf(stdout, "Report written to %s\n", reportPath)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 
-	// Send notification
-	if !cfg.NoNotify {
-		notifier := deps.NotifierFactory()
-		n := notify.Notification{
-			Title:    "GitStreams",
-			Message:  formatNotificationMessage(result),
-			Subtitle: "Activity from people you follow",
-			Sound:    "default",
-			OpenURL:  "file://" + reportPath,
-		}
-		if err := notifier.Send(n); err != nil {
-			_, _ = fmt.Fprintf(stderr, "Warning: could not send notification: %v\n", err)
-			// Don't fail on notification errors
+	// Default token from environment
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("GITHUB_TOKEN")
+		if cfg.Token != "" {
+			sources["token"] = "environment (GITHUB_TOKEN)"
 		}
 	}
 
-	// Open report in browser
-	if !cfg.NoOpen {
-		if err := deps.OpenBrowser("file://" + reportPath); err != nil {
-			_, _ = fmt.Fprintf(stderr, "Warning: could not open browser: %v\n", err)
-			// Don't fail on browser errors
+	// Default database path
+	if cfg.DBPath == "" {
+		dir, err := dataDir(cfg.LegacyDirs)
+		if err != nil {
+			return nil, err
 		}
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return nil, fmt.Errorf("creating data directory: %w", err)
+		}
+		cfg.DBPath = filepath.Join(dir, defaultDBName)
 	}
 
-	return 0
+	return sources, nil
 }
 
-// errVersion is a sentinel error indicating -version was requested.
-var errVersion = fmt.Errorf("version requested")
-
-func parseFlags(args []string) (*Config, error) {
-	cfg := &Config{}
-	var showVersion bool
-
-	fs := flag.NewFlagSet("gitstreams", flag.ContinueOnError)
-	fs.StringVar(&cfg.DBPath, "db", "", "Path to SQLite database (default: ~/.gitstreams/gitstreams.db)")
-	fs.StringVar(&cfg.Token, "token", "", "GitHub token (default: $GITHUB_TOKEN)")
-	fs.BoolVar(&cfg.NoNotify, "no-notify", false, "Skip desktop notification")
-	fs.BoolVar(&cfg.NoOpen, "no-open", false, "Don't open report in browser")
-	fs.StringVar(&cfg.ReportPath, "report", "", "Path to write HTML report (default: temp file)")
-	fs.BoolVar(&cfg.Verbose, "v", false, "Verbose output")
-	fs.BoolVar(&showVersion, "version", false, "Print version and exit")
-	fs.IntVar(&cfg.Days, "sync-lookback-days", 30, "How far back to fetch GitHub data (1-365 days, doesn't affect report filtering)")
-	fs.StringVar(&cfg.ReportSince, "report-since", "", "Generate report from historical data starting from this date (e.g., '2026-01-15' or '7d' for 7 days ago)")
-	fs.BoolVar(&cfg.Offline, "offline", false, "Use only cached data, skip GitHub API calls")
+// envPrefix starts the environment variable for every setting.
+const envPrefix = "GITSTREAMS_"
 
-	if err := fs.Parse(args); err != nil {
-		return nil, err
-	}
-
-	if showVersion {
-		return nil, errVersion
+// envVarName returns the environment variable for a flag, e.g.
+// GITSTREAMS_NO_OPEN for -no-open.
+func envVarName(flagName string) string {
+	if flagName == "v" {
+		return envPrefix + "VERBOSE"
 	}
+	return config.EnvName(envPrefix, flagName)
+}
 
+// validate checks settings whose values can be wrong regardless of type.
+func (cfg *Config) validate() error {
 	// Validate days parameter
 	if cfg.Days < 1 || cfg.Days > 365 {
-		return nil, fmt.Errorf("days must be between 1 and 365, got %d", cfg.Days)
+		return fmt.Errorf("days must be between 1 and 365, got %d", cfg.Days)
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("timeout can't be negative, got %s", cfg.Timeout)
+	}
+	if cfg.SlackHighs < 0 || cfg.SlackHighs > notificationHighlights {
+		return fmt.Errorf("slack-highlights must be between 0 and %d, got %d", notificationHighlights, cfg.SlackHighs)
+	}
+	if cfg.MinStars < 0 {
+		return fmt.Errorf("min-stars can't be negative, got %d", cfg.MinStars)
+	}
+	if cfg.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", cfg.Concurrency)
+	}
+
+	if cfg.DigestAt != "" {
+		if _, _, err := parseDigestAt(cfg.DigestAt); err != nil {
+			return err
+		}
+	}
+	if cfg.GitHubURL != "" {
+		if _, err := report.ParseWebURL(cfg.GitHubURL); err != nil {
+			return fmt.Errorf("github-url: %w", err)
+		}
+	}
+	if !slices.Contains(onErrorPolicies, cfg.OnError) {
+		return fmt.Errorf("on-error must be one of %s, got %q", strings.Join(onErrorPolicies, ", "), cfg.OnError)
+	}
+	if cfg.Org != "" && (cfg.Username != "" || len(cfg.Sources) > 0) {
+		return errors.New("-org tracks an organization's members instead of follows, so it can't be combined with -user or -source")
+	}
+	if cfg.EmailTo != "" {
+		if err := cfg.validateEmail(); err != nil {
+			return err
+		}
+	}
+	if !slices.Contains(apis, cfg.API) {
+		return fmt.Errorf("api must be one of %s, got %q", strings.Join(apis, ", "), cfg.API)
+	}
+	if _, err := expandReportName(cfg.ReportName, time.Time{}, cfg.Format, ""); err != nil {
+		return fmt.Errorf("report-name: %w", err)
+	}
+	// Caught here so a missing template doesn't waste a sync
+	if cfg.Template != "" {
+		if _, err := os.Stat(cfg.Template); err != nil {
+			return fmt.Errorf("template: %w", err)
+		}
+	}
+	if _, err := cfg.preset(); err != nil {
+		return err
+	}
+	if _, err := loadRoutes(cfg.RoutesPath); err != nil {
+		return err
 	}
 
 	// Validate since and offline flags
 	// Note: --since without --offline is allowed, but requires token for current data
 	// Note: --offline without --since is allowed for standalone cached mode
+	return nil
+}
 
-	// Default token from environment
-	if cfg.Token == "" {
-		cfg.Token = os.Getenv("GITHUB_TOKEN")
+// loadConfigFile loads the config file at path, or from the default
+// location if path is empty: the XDG config directory, falling back to
+// ~/.gitstreams for installs that haven't migrated. A missing default config
+// file is not an error; it returns nil.
+func loadConfigFile(path string) (*config.File, error) {
+	if path != "" {
+		file, err := config.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading config: %w", err)
+		}
+		return file, nil
 	}
 
-	// Default database path
-	if cfg.DBPath == "" {
-		home, err := os.UserHomeDir()
+	for _, dir := range []func() (string, error){configDir, legacyDir} {
+		d, err := dir()
 		if err != nil {
-			return nil, fmt.Errorf("getting home directory: %w", err)
+			return nil, err
 		}
-		dataDir := filepath.Join(home, ".gitstreams")
-		if err := os.MkdirAll(dataDir, 0750); err != nil {
-			return nil, fmt.Errorf("creating data directory: %w", err)
+		file, err := config.Load(filepath.Join(d, defaultConfigName))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loading config: %w", err)
 		}
-		cfg.DBPath = filepath.Join(dataDir, defaultDBName)
+		return file, nil
 	}
-
-	return cfg, nil
+	return nil, nil
 }
 
+// maxSinceYears is how far back a relative -since can reach.
+const maxSinceYears = 100
+
 // parseSinceDate parses a date string in various formats:
 // - Absolute: '2026-01-15', '2026-01-15T10:30:00Z'
 // - Relative: '7d' (7 days ago), '2w' (2 weeks ago), '3m' (3 months ago)
@@ -451,18 +786,25 @@ func parseSinceDate(dateStr string, now time.Time) (time.Time, error) {
 		unit := dateStr[len(dateStr)-1]
 		valueStr := dateStr[:len(dateStr)-1]
 
-		// Parse the numeric value
-		var value int
-		if _, err := fmt.Sscanf(valueStr, "%d", &value); err == nil && value > 0 {
+		// Parse the numeric value. Anything further back than maxSinceYears
+		// is a typo, and would overflow the date arithmetic besides.
+		if value, err := strconv.Atoi(valueStr); err == nil && value > 0 && value <= maxSinceYears*366 {
+			var since time.Time
 			switch unit {
 			case 'd':
-				return now.AddDate(0, 0, -value), nil
+				since = now.AddDate(0, 0, -value)
 			case 'w':
-				return now.AddDate(0, 0, -value*7), nil
+				since = now.AddDate(0, 0, -value*7)
 			case 'm':
-				return now.AddDate(0, -value, 0), nil
+				since = now.AddDate(0, -value, 0)
 			case 'y':
-				return now.AddDate(-value, 0, 0), nil
+				since = now.AddDate(-value, 0, 0)
+			}
+			if !since.IsZero() {
+				if now.Sub(since) > maxSinceYears*366*24*time.Hour {
+					return time.Time{}, fmt.Errorf("%q is more than %d years ago", dateStr, maxSinceYears)
+				}
+				return since, nil
 			}
 		}
 	}
@@ -485,347 +827,581 @@ func parseSinceDate(dateStr string, now time.Time) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse date %q (try formats like '2026-01-15' or '7d')", dateStr)
 }
 
-func fetchActivity(ctx context.Context, client GitHubClient, now, cutoff time.Time, w, progressW io.Writer, verbose bool) (*diff.Snapshot, error) {
-	tracer := otel.Tracer()
-	ctx, span := tracer.Start(ctx, "fetchActivity")
-	defer span.End()
+// fetchPlan selects which API endpoints the GitHub source calls.
+type fetchPlan struct {
+	FollowedBy string // List users followed by this account instead of the authenticated user
+	Org        string // List this organization's members instead of followed users
+	Starred    bool   // Fetch each user's starred repos
+	Owned      bool   // Fetch each user's owned repos
+	Events     bool   // Fetch each user's recent events
+	// Discussions fetches each user's discussions. It's off by default:
+	// it costs a GraphQL request per user.
+	Discussions bool
+	// Profiles fetches each user's profile. It's a request per user, but
+	// unchanged profiles come back as free 304s.
+	Profiles bool
+	// Following fetches who each user follows, to report who they start
+	// following. It's off by default: it costs a request per user.
+	Following bool
+	// Quiet leaves out the per-user progress indicator, for fetches that
+	// run alongside others.
+	Quiet bool
+	// Previous is the last snapshot, which planFetches keeps what it skips
+	// from. Nil fetches everything for everyone.
+	Previous *diff.Snapshot
+	// Budget is how many requests the per-user fetches can spend, which
+	// planFetches shares out. Zero means there's no need to count.
+	Budget int
+	// Concurrency is how many users are fetched at once. Below 1 means
+	// one at a time.
+	Concurrency int
+}
 
-	// Fetch followed users
-	ctx, usersSpan := tracer.Start(ctx, "getFollowedUsers")
-	users, err := client.GetFollowedUsers(ctx)
-	usersSpan.End()
-	if err != nil {
-		span.RecordError(err)
-		return nil, fmt.Errorf("fetching followed users: %w", err)
+// defaultFetchPlan fetches everything for the authenticated user's follow list.
+func defaultFetchPlan() fetchPlan {
+	return fetchPlan{Starred: true, Owned: true, Events: true, Profiles: true}
+}
+
+// anonymousFetchPlan degrades to events only for the given account's follow
+// list. Unauthenticated requests are limited to 60 per hour, so spending three
+// calls per followed user would exhaust the budget for all but tiny lists.
+func anonymousFetchPlan(username string) fetchPlan {
+	return fetchPlan{FollowedBy: username, Events: true}
+}
+
+// resolveFetchPlan picks a fetch plan based on whether a token is available.
+func resolveFetchPlan(cfg *Config) (fetchPlan, error) {
+	if cfg.Org != "" && cfg.Token == "" {
+		return fetchPlan{}, errors.New("-org needs GITHUB_TOKEN to list the organization's members and teams")
+	}
+	if cfg.Token != "" || len(cfg.Sources) > 0 {
+		plan := defaultFetchPlan()
+		plan.FollowedBy = cfg.Username
+		plan.Org = cfg.Org
+		plan.Discussions = cfg.Discussions
+		plan.Following = cfg.Following
+		plan.Concurrency = cfg.Concurrency
+		return plan, nil
+	}
+	if cfg.Username != "" {
+		plan := anonymousFetchPlan(cfg.Username)
+		plan.Concurrency = cfg.Concurrency
+		return plan, nil
 	}
-	span.SetAttributes(attribute.Int("user_count", len(users)))
+	return fetchPlan{}, fmt.Errorf("GITHUB_TOKEN environment variable is required (or pass -user to run unauthenticated)")
+}
 
-	snapshot := diff.NewSnapshot(now)
+// warnAnonymous tells the user when we're running without a token.
+func warnAnonymous(w io.Writer, cfg *Config) {
+	if cfg.Token == "" && cfg.Username != "" && len(cfg.Sources) == 0 {
+		_, _ = fmt.Fprintln(w, "Note: running without GITHUB_TOKEN (60 requests/hour); fetching events only")
+	}
+}
 
-	// Create progress tracker for stderr output
-	prog := progress.NewProgress(progressW, len(users))
-	if len(users) > 0 {
-		prog.Start(fmt.Sprintf("Fetching activity for %d users...", len(users)))
+// endpointNames describes each part of a user's activity in warning
+// messages.
+var endpointNames = map[string]string{
+	source.EndpointStarred:     "starred repos",
+	source.EndpointOwned:       "owned repos",
+	source.EndpointEvents:      "events",
+	source.EndpointDiscussions: "discussions",
+	source.EndpointProfile:     "profile",
+	source.EndpointFollowing:   "following list",
+}
+
+// What -on-error does with a per-user fetch failure.
+const (
+	onErrorWarn     = "warn"      // Keep what was fetched and warn
+	onErrorSkipUser = "skip-user" // Keep the user's last snapshot instead, so nothing of theirs changes this run
+	onErrorFail     = "fail"      // Fail the run
+)
+
+var onErrorPolicies = []string{onErrorWarn, onErrorSkipUser, onErrorFail}
+
+// Which GitHub API -api fetches activity through.
+const (
+	apiREST    = "rest"    // Several paginated requests per user, with free 304s for what hasn't changed
+	apiGraphQL = "graphql" // A request per batch of users, for the most recent of their activity
+)
+
+var apis = []string{apiREST, apiGraphQL}
+
+// newGitHubClient creates the client a run fetches with for token, through
+// GraphQL if cfg asks for it. GraphQL doesn't allow anonymous requests, so
+// without a token it's REST either way, as it is for test doubles.
+func newGitHubClient(cfg *Config, deps *Dependencies, token string) GitHubClient {
+	client := deps.GitHubClientFactory(token)
+	useClock(client, deps.Now)
+	if c, ok := client.(*github.Client); ok && cfg.API == apiGraphQL && token != "" {
+		return github.NewGraphQLClient(c)
 	}
+	return client
+}
 
-	for i, user := range users {
-		// Update progress indicator (1-indexed for human-readable output)
-		prog.SetItem(i+1, user.Login)
+// FetchWarning records a per-user fetch that failed without failing the
+// sync.
+type FetchWarning struct {
+	Err      error
+	User     string
+	Endpoint string // source.EndpointStarred, EndpointOwned or EndpointEvents
+	// Partial is set when the user's other endpoints were fetched, so the
+	// snapshot has some of their activity rather than none.
+	Partial bool
+}
 
-		if verbose {
-			_, _ = fmt.Fprintf(w, "Fetching activity for %s...\n", user.Login)
-		}
+func (fw FetchWarning) Error() string {
+	return fmt.Sprintf("could not fetch %s for %s: %v", endpointNames[fw.Endpoint], fw.User, fw.Err)
+}
 
-		// Create a span for this user's activity
-		_, userSpan := tracer.Start(ctx, "fetchUserActivity",
-			trace.WithAttributes(attribute.String("user", user.Login)))
+func (fw FetchWarning) Unwrap() error {
+	return fw.Err
+}
 
-		activity := diff.UserActivity{
-			Username: user.Login,
+// applyErrorPolicy handles snapshot's fetch warnings as policy says, one of
+// the onError constants, marking what they left out as incomplete. Skipped
+// users get their activity from previous, which may be nil, or are left out
+// if it doesn't have them.
+func applyErrorPolicy(policy string, snapshot, previous *diff.Snapshot, warnings []FetchWarning) error {
+	switch policy {
+	case onErrorFail:
+		if len(warnings) == 1 {
+			return warnings[0]
 		}
-
-		// Fetch starred repos - filter by repo creation date
-		_, starredSpan := tracer.Start(ctx, "getStarredRepos",
-			trace.WithAttributes(attribute.String("user", user.Login)))
-		starred, err := client.GetStarredReposByUsername(ctx, user.Login)
-		starredSpan.End()
-		if err != nil {
-			if verbose {
-				_, _ = fmt.Fprintf(w, "  Warning: could not fetch starred repos for %s: %v\n", user.Login, err)
-			}
-		} else {
-			for _, repo := range starred {
-				// Only include repos created after the cutoff date
-				if !repo.CreatedAt.Before(cutoff) {
-					activity.StarredRepos = append(activity.StarredRepos, convertRepo(repo))
-				}
-			}
+		if len(warnings) > 1 {
+			return fmt.Errorf("%w, and %d more", warnings[0], len(warnings)-1)
 		}
-
-		// Fetch owned repos - filter by creation or recent push date
-		_, ownedSpan := tracer.Start(ctx, "getOwnedRepos",
-			trace.WithAttributes(attribute.String("user", user.Login)))
-		owned, err := client.GetOwnedReposByUsername(ctx, user.Login)
-		ownedSpan.End()
-		if err != nil {
-			if verbose {
-				_, _ = fmt.Fprintf(w, "  Warning: could not fetch owned repos for %s: %v\n", user.Login, err)
+	case onErrorSkipUser:
+		for _, fw := range warnings {
+			delete(snapshot.Users, fw.User)
+			if previous == nil {
+				continue
 			}
-		} else {
-			for _, repo := range owned {
-				// Only include repos created after the cutoff date
-				if !repo.CreatedAt.Before(cutoff) {
-					activity.OwnedRepos = append(activity.OwnedRepos, convertRepo(repo))
-				}
+			if prev, ok := previous.Users[fw.User]; ok {
+				snapshot.Users[fw.User] = prev
 			}
 		}
+	}
 
-		// Fetch events - filter by event creation date
-		_, eventsSpan := tracer.Start(ctx, "getRecentEvents",
-			trace.WithAttributes(attribute.String("user", user.Login)))
-		events, err := client.GetRecentEvents(ctx, user.Login)
-		eventsSpan.End()
-		if err != nil {
-			if verbose {
-				_, _ = fmt.Fprintf(w, "  Warning: could not fetch events for %s: %v\n", user.Login, err)
+	for _, fw := range warnings {
+		snapshot.MarkIncomplete(fw.User, fw.Endpoint)
+	}
+	if policy == onErrorSkipUser && previous != nil {
+		// Restored users are only as incomplete as they were last time
+		for _, fw := range warnings {
+			if _, ok := previous.Users[fw.User]; !ok {
+				continue
 			}
-		} else {
-			for _, event := range events {
-				// Only include events created after the cutoff date
-				if !event.CreatedAt.Before(cutoff) {
-					activity.Events = append(activity.Events, convertEvent(event))
-				}
+			delete(snapshot.Incomplete, fw.User)
+			for _, endpoint := range previous.Incomplete[fw.User] {
+				snapshot.MarkIncomplete(fw.User, endpoint)
 			}
 		}
-
-		snapshot.Users[user.Login] = activity
-		userSpan.End()
 	}
-
-	// Stop progress indicator
-	prog.Done()
-
-	return snapshot, nil
+	return nil
 }
 
-func convertRepo(r github.Repository) diff.Repo {
-	return diff.Repo{
-		CreatedAt:   r.CreatedAt,
-		Owner:       r.Owner.Login,
-		Name:        r.Name,
-		Description: r.Description,
-		Language:    r.Language,
-		Stars:       r.StarCount,
+// printFetchWarnings writes one line per warning to w.
+func printFetchWarnings(w io.Writer, warnings []FetchWarning) {
+	for _, fw := range warnings {
+		_, _ = fmt.Fprintf(w, "  Warning: %v\n", fw)
 	}
 }
 
-func convertEvent(e github.Event) diff.Event {
-	return diff.Event{
-		Type:      e.Type,
-		Actor:     e.Actor.Login,
-		Repo:      e.Repo.Name,
-		CreatedAt: e.CreatedAt,
+// githubSource returns the GitHub source that fetches what plan asks for
+// with client.
+func githubSource(client GitHubClient, plan fetchPlan) *source.GitHub {
+	return &source.GitHub{
+		Client:      client,
+		FollowedBy:  plan.FollowedBy,
+		Org:         plan.Org,
+		Starred:     plan.Starred,
+		Owned:       plan.Owned,
+		Events:      plan.Events,
+		Discussions: plan.Discussions,
+		Profiles:    plan.Profiles,
+		Following:   plan.Following,
 	}
 }
 
-func loadPreviousSnapshot(store Store) (*diff.Snapshot, error) {
-	snapshots, err := store.GetByUser(snapshotUserID, 1)
+func fetchActivity(ctx context.Context, client GitHubClient, now, cutoff time.Time, w, progressW io.Writer, verbose bool) (*diff.Snapshot, []FetchWarning, error) {
+	return fetchActivityWithPlan(ctx, client, defaultFetchPlan(), now, cutoff, w, progressW, verbose)
+}
+
+// fetchActivityWithPlan fetches what plan asks for from every followed user.
+// Only failing to list the followed users is an error; per-user failures
+// come back as warnings alongside the snapshot.
+func fetchActivityWithPlan(ctx context.Context, client GitHubClient, plan fetchPlan, now, cutoff time.Time, w, progressW io.Writer, verbose bool) (*diff.Snapshot, []FetchWarning, error) {
+	tracer := otel.Tracer()
+	ctx, span := tracer.Start(ctx, "fetchActivity")
+	defer span.End()
+
+	// Fetch followed users
+	src := githubSource(client, plan)
+	usersCtx, usersSpan := tracer.Start(ctx, "getFollowedUsers")
+	subjects, err := src.ListSubjects(usersCtx)
+	usersSpan.End()
 	if err != nil {
-		return nil, fmt.Errorf("loading snapshots: %w", err)
+		span.RecordError(err)
+		return nil, nil, fmt.Errorf("fetching followed users: %w", err)
 	}
+	span.SetAttributes(attribute.Int("user_count", len(subjects)))
 
-	if len(snapshots) == 0 {
-		// No previous snapshot, return empty one
-		return diff.NewSnapshot(time.Time{}), nil
+	// Shrink the fetch if the remaining API budget can't cover it
+	plan, cutoff = adaptToBudget(ctx, client, plan, len(subjects), now, cutoff, progressW)
+	src = githubSource(client, plan)
+	src.Planner = planFetches(client, plan, subjects)
+	prefetch(ctx, client, subjects, w, verbose)
+
+	userProgress := progressW
+	if plan.Quiet {
+		userProgress = io.Discard
+	}
+	snapshot, warnings, err := fetchSubjectsActivity(ctx, src, subjects, plan.Concurrency, now, cutoff, w, userProgress, nil, verbose)
+	if err != nil {
+		span.RecordError(err)
+		return nil, nil, fmt.Errorf("fetching activity: %w", err)
+	}
+	span.SetAttributes(attribute.Int("fetch_warnings", len(warnings)))
+
+	// Export per-endpoint request counts on the span for metrics backends
+	if p, ok := client.(requestStatsProvider); ok {
+		stats := p.GetRequestStats()
+		attrs := []attribute.KeyValue{
+			attribute.Int("api_requests.total", stats.Total),
+			attribute.Int("api_requests.pagination", stats.Pagination),
+			attribute.Int("api_requests.not_modified", stats.NotModified),
+		}
+		for class, n := range stats.ByEndpoint {
+			attrs = append(attrs, attribute.Int("api_requests."+class, n))
+		}
+		span.SetAttributes(attrs...)
 	}
 
-	return storageToSnapshot(snapshots[0])
+	return snapshot, warnings, nil
 }
 
-func saveSnapshot(store Store, snapshot *diff.Snapshot, now time.Time) error {
-	ss, err := snapshotToStorage(snapshot)
-	if err != nil {
-		return err
+// prefetch has client fetch subjects' activity up front, if it can. Users
+// the prefetch misses are fetched one at a time, so it failing only costs
+// requests.
+func prefetch(ctx context.Context, client GitHubClient, subjects []source.Subject, w io.Writer, verbose bool) {
+	pc, ok := client.(prefetchingClient)
+	if !ok || len(subjects) == 0 {
+		return
+	}
+	logins := make([]string, len(subjects))
+	for i, s := range subjects {
+		logins[i] = s.Name
+	}
+	if err := pc.Prefetch(ctx, logins); err != nil && verbose {
+		_, _ = fmt.Fprintf(w, "Couldn't prefetch activity, fetching users one at a time: %v\n", err)
 	}
-	ss.Timestamp = now
-	return store.Save(ss)
 }
 
-func snapshotToStorage(s *diff.Snapshot) (*storage.Snapshot, error) {
-	// Serialize the diff.Snapshot to JSON-compatible map
-	data, err := json.Marshal(s)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling snapshot: %w", err)
+// fetchSubjectsActivity fetches each subject's activity from src, up to
+// concurrency of them at once. Per-user failures are skipped and returned as
+// warnings rather than failing the whole sync, but once ctx is done it stops
+// and returns ctx's error: a snapshot missing the users it didn't get to
+// would look like they'd been unfollowed. Progress and warnings are also
+// published to events, if it isn't nil.
+func fetchSubjectsActivity(ctx context.Context, src source.Source, subjects []source.Subject, concurrency int, now, cutoff time.Time, w, progressW io.Writer, events *progress.Broadcaster, verbose bool) (*diff.Snapshot, []FetchWarning, error) {
+	tracer := otel.Tracer()
+
+	// Create progress tracker for stderr output
+	prog := progress.NewProgress(progressW, len(subjects))
+	if events != nil {
+		prog.Broadcast(events)
+	}
+	if len(subjects) > 0 {
+		prog.Start(fmt.Sprintf("Fetching activity for %d users...", len(subjects)))
 	}
 
-	var activity map[string]interface{}
-	if err := json.Unmarshal(data, &activity); err != nil {
-		return nil, fmt.Errorf("unmarshaling to map: %w", err)
+	// Each subject's results go in their own slot, so the snapshot and
+	// warnings come out in the same order however the fetches interleave
+	activities := make([]diff.UserActivity, len(subjects))
+	failures := make([][]source.Failure, len(subjects))
+	var (
+		mu      sync.Mutex // Guards started and writes to w
+		started int
+		wg      sync.WaitGroup
+	)
+	next := make(chan int)
+	for range max(1, min(concurrency, len(subjects))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				subject := subjects[i]
+
+				// Update progress indicator (1-indexed for human-readable output)
+				mu.Lock()
+				started++
+				prog.SetItem(started, subject.Name)
+				if verbose {
+					_, _ = fmt.Fprintf(w, "Fetching activity for %s...\n", subject.Name)
+				}
+				mu.Unlock()
+
+				// Create a span for this user's activity
+				userCtx, userSpan := tracer.Start(ctx, "fetchUserActivity",
+					trace.WithAttributes(attribute.String("user", subject.Name)))
+				activities[i], failures[i] = src.FetchActivity(userCtx, subject, cutoff)
+				for _, f := range failures[i] {
+					userSpan.RecordError(f.Err, trace.WithAttributes(attribute.String("endpoint", f.Endpoint)))
+					prog.Warn(FetchWarning{User: subject.Name, Endpoint: f.Endpoint, Err: f.Err}.Error())
+				}
+				userSpan.End()
+			}
+		}()
 	}
+feed:
+	for i := range subjects {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
 
-	return &storage.Snapshot{
-		UserID:    snapshotUserID,
-		Timestamp: s.CapturedAt,
-		Activity:  map[string]interface{}{activityDataKey: activity},
-	}, nil
+	// Stop progress indicator
+	prog.Done()
+
+	// The last users' fetches may have been cut short too
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	snapshot := diff.NewSnapshot(now)
+	var warnings []FetchWarning
+	for i, subject := range subjects {
+		for _, f := range failures[i] {
+			warnings = append(warnings, FetchWarning{User: subject.Name, Endpoint: f.Endpoint, Err: f.Err, Partial: f.Partial})
+		}
+		snapshot.Users[subject.Name] = activities[i]
+	}
+	return snapshot, warnings, nil
 }
 
-func storageToSnapshot(ss *storage.Snapshot) (*diff.Snapshot, error) {
-	activityData, ok := ss.Activity[activityDataKey]
-	if !ok {
-		return diff.NewSnapshot(ss.Timestamp), nil
+// fullDepthPagesPerEndpoint is a rough estimate of how many pages each
+// per-user endpoint costs at full pagination depth, used for budget planning.
+const fullDepthPagesPerEndpoint = 2
+
+// adaptToBudget checks the remaining rate limit before per-user fetching
+// starts and, if it can't cover a full sync, reduces pagination depth and the
+// effective lookback (and, if still short, sets a budget for planFetches to
+// share out) rather than
+// failing halfway through the user list. When the budget is healthy, full
+// depth is restored. It returns the possibly-reduced plan and cutoff.
+func adaptToBudget(ctx context.Context, client GitHubClient, plan fetchPlan, userCount int, now, cutoff time.Time, w io.Writer) (fetchPlan, time.Time) {
+	bc, ok := client.(budgetAwareClient)
+	if !ok || userCount == 0 {
+		return plan, cutoff
 	}
 
-	data, err := json.Marshal(activityData)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling activity data: %w", err)
+	rl, err := bc.FetchRateLimit(ctx)
+	if err != nil || rl == nil || rl.Limit == 0 {
+		// Unknown budget: proceed as normal
+		return plan, cutoff
 	}
 
-	var snapshot diff.Snapshot
-	if err := json.Unmarshal(data, &snapshot); err != nil {
-		return nil, fmt.Errorf("unmarshaling snapshot: %w", err)
+	endpoints := 0
+	for _, enabled := range []bool{plan.Starred, plan.Owned, plan.Events} {
+		if enabled {
+			endpoints++
+		}
 	}
+	minCost := userCount * endpoints
+	fullCost := minCost * fullDepthPagesPerEndpoint
 
-	return &snapshot, nil
-}
+	if rl.Remaining >= fullCost {
+		bc.SetMaxPages(0)
+		return plan, cutoff
+	}
 
-func buildReport(result *diff.Result, periodStart, periodEnd, generatedAt time.Time) *report.Report {
-	return buildReportWithLogging(result, periodStart, periodEnd, generatedAt, nil, false)
-}
+	// One page per endpoint from here on, and profiles can wait
+	bc.SetMaxPages(1)
+	plan.Profiles = false
 
-func buildReportWithLogging(result *diff.Result, periodStart, periodEnd, generatedAt time.Time, w io.Writer, verbose bool) *report.Report {
-	rpt := &report.Report{
-		GeneratedAt: generatedAt,
-		PeriodStart: periodStart,
-		PeriodEnd:   periodEnd,
+	// Scale the lookback by the fraction of a full sync we can afford
+	lookback := now.Sub(cutoff)
+	if fullCost > 0 {
+		lookback = time.Duration(int64(lookback) * int64(rl.Remaining) / int64(fullCost))
+	}
+	if lookback < 24*time.Hour {
+		lookback = 24 * time.Hour
+	}
+	if reduced := now.Add(-lookback); reduced.After(cutoff) {
+		cutoff = reduced
 	}
+	days := int(now.Sub(cutoff).Hours() / 24)
 
-	if verbose && w != nil {
-		_, _ = fmt.Fprintf(w, "buildReport input: NewStars=%d, NewRepos=%d, NewEvents=%d, NewUsers=%d\n",
-			len(result.NewStars), len(result.NewRepos), len(result.NewEvents), len(result.NewUsers))
+	if rl.Remaining < minCost && (plan.Starred || plan.Owned) {
+		plan.Budget = rl.Remaining
+		_, _ = fmt.Fprintf(w, "Note: only %d of %d API requests remain (a full sync needs ~%d); fetching starred and owned repos for the most recently active users only, with a %d-day lookback, until the budget recovers\n",
+			rl.Remaining, rl.Limit, fullCost, days)
+		return plan, cutoff
 	}
 
-	// Group activities by user
-	userActivities := make(map[string]*report.UserActivity)
+	_, _ = fmt.Fprintf(w, "Note: only %d of %d API requests remain (a full sync needs ~%d); limiting to 1 page per endpoint and a %d-day lookback until the budget recovers\n",
+		rl.Remaining, rl.Limit, fullCost, days)
+	return plan, cutoff
+}
 
-	// Add new stars
-	for _, star := range result.NewStars {
-		ua := getOrCreateUserActivity(userActivities, star.Username)
-		ua.Activities = append(ua.Activities, report.Activity{
-			Type:      report.ActivityStarred,
-			User:      star.Username,
-			AvatarURL: ua.AvatarURL,
-			RepoName:  star.Repo.FullName(),
-			RepoURL:   fmt.Sprintf("https://github.com/%s", star.Repo.FullName()),
-			Timestamp: star.Repo.CreatedAt,
-			Details:   star.Repo.Description,
-		})
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
 
-	if verbose && w != nil {
-		_, _ = fmt.Fprintf(w, "buildReport after stars: userActivities map has %d entries\n", len(userActivities))
+// printRequestStats writes a per-endpoint breakdown of API requests if the
+// client tracks them.
+func printRequestStats(w io.Writer, client GitHubClient) {
+	p, ok := client.(requestStatsProvider)
+	if !ok {
+		return
 	}
+	_, _ = fmt.Fprintln(w, formatRequestStats(p.GetRequestStats()))
+}
 
-	// Add new repos
-	for _, repo := range result.NewRepos {
-		ua := getOrCreateUserActivity(userActivities, repo.Username)
-		ua.Activities = append(ua.Activities, report.Activity{
-			Type:      report.ActivityCreatedRepo,
-			User:      repo.Username,
-			AvatarURL: ua.AvatarURL,
-			RepoName:  repo.Repo.FullName(),
-			RepoURL:   fmt.Sprintf("https://github.com/%s", repo.Repo.FullName()),
-			Timestamp: repo.Repo.CreatedAt,
-			Details:   repo.Repo.Description,
-		})
+// requestCount returns how many API requests client has made, or 0 if it
+// doesn't count them.
+func requestCount(client GitHubClient) int {
+	if p, ok := client.(requestStatsProvider); ok {
+		return p.GetRequestStats().Total
 	}
+	return 0
+}
 
-	if verbose && w != nil {
-		_, _ = fmt.Fprintf(w, "buildReport after repos: userActivities map has %d entries\n", len(userActivities))
+// formatRequestStats renders request counts as a single line, e.g.
+// "API requests: 31 total (events 10, following 1, repos 10, starred 10, pagination 0, 304 not modified 4)".
+func formatRequestStats(stats github.RequestStats) string {
+	classes := make([]string, 0, len(stats.ByEndpoint))
+	for class := range stats.ByEndpoint {
+		classes = append(classes, class)
 	}
+	sort.Strings(classes)
 
-	// Add new events
-	for _, event := range result.NewEvents {
-		ua := getOrCreateUserActivity(userActivities, event.Username)
-		activityType := eventTypeToActivityType(event.Event.Type)
-		// Skip events that don't map to an activity type (like CreateEvent)
-		if activityType == "" {
-			continue
-		}
-		ua.Activities = append(ua.Activities, report.Activity{
-			Type:      activityType,
-			User:      event.Username,
-			AvatarURL: ua.AvatarURL,
-			RepoName:  event.Event.Repo,
-			RepoURL:   fmt.Sprintf("https://github.com/%s", event.Event.Repo),
-			Timestamp: event.Event.CreatedAt,
-		})
+	parts := make([]string, 0, len(classes)+2)
+	for _, class := range classes {
+		parts = append(parts, fmt.Sprintf("%s %d", class, stats.ByEndpoint[class]))
 	}
+	parts = append(parts, fmt.Sprintf("pagination %d", stats.Pagination))
+	parts = append(parts, fmt.Sprintf("304 not modified %d", stats.NotModified))
+
+	return fmt.Sprintf("API requests: %d total (%s)", stats.Total, strings.Join(parts, ", "))
+}
 
-	if verbose && w != nil {
-		_, _ = fmt.Fprintf(w, "buildReport after events: userActivities map has %d entries\n", len(userActivities))
+// newerWriter returns the version of gitstreams that wrote the latest
+// snapshot under userID if it's newer than this one, or "" if it isn't or
+// can't be told.
+func newerWriter(store Store, userID string) string {
+	snapshots, err := store.GetByUser(userID, 1)
+	if err != nil || len(snapshots) == 0 || !newerVersion(snapshots[0].AppVersion, version) {
+		return ""
 	}
+	return snapshots[0].AppVersion
+}
 
-	// Convert map to slice
-	for _, ua := range userActivities {
-		rpt.UserActivities = append(rpt.UserActivities, *ua)
+// loadLatestSnapshot returns the newest snapshot saved under userID, or an
+// empty one if there's none yet.
+func loadLatestSnapshot(store Store, userID string) (*diff.Snapshot, error) {
+	snapshots, err := store.GetByUser(userID, 1)
+	if err != nil {
+		return nil, fmt.Errorf("loading snapshots: %w", err)
 	}
 
-	if verbose && w != nil {
-		_, _ = fmt.Fprintf(w, "buildReport output: UserActivities slice has %d entries\n", len(rpt.UserActivities))
+	if len(snapshots) == 0 {
+		// No previous snapshot, return empty one
+		return diff.NewSnapshot(time.Time{}), nil
 	}
 
-	return rpt
+	return storageToSnapshot(snapshots[0])
 }
 
-func getOrCreateUserActivity(m map[string]*report.UserActivity, username string) *report.UserActivity {
-	if ua, ok := m[username]; ok {
-		return ua
+// saveSnapshotAs persists snapshot under userID, such as runSnapshotID's or
+// a team profile's, and returns its serialized size in bytes.
+func saveSnapshotAs(store Store, userID string, snapshot *diff.Snapshot, now time.Time) (int, error) {
+	ss, err := snapshotToStorage(snapshot)
+	if err != nil {
+		return 0, err
 	}
-	ua := &report.UserActivity{
-		User:      username,
-		AvatarURL: fmt.Sprintf("https://github.com/%s.png", username),
+	ss.UserID = userID
+	ss.Timestamp = now
+	// Write through SaveAll so every row a sync produces lands atomically.
+	if err := store.SaveAll([]*storage.Snapshot{ss}); err != nil {
+		return 0, err
+	}
+	if err := saveActivities(store, userID, snapshot, now); err != nil {
+		return 0, fmt.Errorf("saving activities: %w", err)
 	}
-	m[username] = ua
-	return ua
+	return len(ss.Data), nil
 }
 
-func eventTypeToActivityType(eventType string) report.ActivityType {
-	switch eventType {
-	case "WatchEvent":
-		return report.ActivityStarred
-	case "CreateEvent":
-		// Don't convert CreateEvent to ActivityCreatedRepo because:
-		// 1. NewRepos already tracks actual repository creations
-		// 2. CreateEvent includes branch/tag creation, not just repos
-		// Returning empty string will cause this event to be skipped
-		return ""
-	case "ForkEvent":
-		return report.ActivityForked
-	case "PushEvent":
-		return report.ActivityPushed
-	case "PullRequestEvent":
-		return report.ActivityPR
-	case "IssuesEvent":
-		return report.ActivityIssue
-	default:
-		return report.ActivityType(eventType)
-	}
+// snapshotBufPool recycles encode buffers across snapshot saves; a snapshot
+// for a few hundred followed users runs to megabytes of JSON.
+var snapshotBufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
 }
 
-// filterResultBySinceDate filters a diff result to only include activities created on or after the given date.
-// This is necessary when using --since because snapshots contain historical data (e.g., 30 days),
-// and we only want to show activities that occurred after the specified since date.
-func filterResultBySinceDate(result *diff.Result, sinceDate time.Time) *diff.Result {
-	filtered := &diff.Result{
-		OldCapturedAt: result.OldCapturedAt,
-		NewCapturedAt: result.NewCapturedAt,
-		NewUsers:      result.NewUsers,
-		GoneUsers:     result.GoneUsers,
-	}
+func snapshotToStorage(s *diff.Snapshot) (*storage.Snapshot, error) {
+	buf, _ := snapshotBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer snapshotBufPool.Put(buf)
 
-	// Filter new stars - only include repos created on or after since date
-	for _, star := range result.NewStars {
-		if !star.Repo.CreatedAt.Before(sinceDate) {
-			filtered.NewStars = append(filtered.NewStars, star)
-		}
+	if err := json.NewEncoder(buf).Encode(s); err != nil {
+		return nil, fmt.Errorf("marshaling snapshot: %w", err)
 	}
 
-	// Filter new repos - only include repos created on or after since date
-	for _, repo := range result.NewRepos {
-		if !repo.Repo.CreatedAt.Before(sinceDate) {
-			filtered.NewRepos = append(filtered.NewRepos, repo)
+	// Copy out of the pooled buffer, dropping the encoder's trailing newline
+	data := make(json.RawMessage, buf.Len()-1)
+	copy(data, buf.Bytes())
+
+	return &storage.Snapshot{
+		UserID:        snapshotUserID,
+		Timestamp:     s.CapturedAt,
+		AppVersion:    version,
+		SchemaVersion: snapshotSchemaVersion,
+		Data:          data,
+		Partial:       s.Partial(),
+	}, nil
+}
+
+func storageToSnapshot(ss *storage.Snapshot) (*diff.Snapshot, error) {
+	var data []byte
+	switch {
+	case ss.SchemaVersion == 0:
+		// Legacy rows wrap the snapshot in a generic Activity map
+		activityData, ok := ss.Activity[activityDataKey]
+		if !ok {
+			return diff.NewSnapshot(ss.Timestamp), nil
 		}
+		var err error
+		data, err = json.Marshal(activityData)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling activity data: %w", err)
+		}
+	case ss.SchemaVersion > snapshotSchemaVersion:
+		return nil, fmt.Errorf("snapshot %d has schema version %d, newer than supported version %d; upgrade gitstreams",
+			ss.ID, ss.SchemaVersion, snapshotSchemaVersion)
+	default:
+		data = ss.Data
 	}
 
-	// Filter new events - only include events created on or after since date
-	for _, event := range result.NewEvents {
-		if !event.Event.CreatedAt.Before(sinceDate) {
-			filtered.NewEvents = append(filtered.NewEvents, event)
-		}
+	var snapshot diff.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("unmarshaling snapshot: %w", err)
 	}
 
-	return filtered
+	return &snapshot, nil
 }
 
 func formatNotificationMessage(result *diff.Result) string {
@@ -843,6 +1419,12 @@ func formatNotificationMessage(result *diff.Result) string {
 	if n := len(result.NewUsers); n > 0 {
 		parts = append(parts, fmt.Sprintf("%d new users", n))
 	}
+	if n := len(result.NewFollows); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d new follows", n))
+	}
+	if n := len(result.Watched); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d watched repos", n))
+	}
 
 	if len(parts) == 0 {
 		return "New activity detected"
@@ -863,6 +1445,139 @@ func formatNotificationMessage(result *diff.Result) string {
 	return msg
 }
 
+// notificationHighlights is how many of the report's highlights a
+// notification carries, for notifiers that list them.
+const notificationHighlights = 5
+
+// reportNotification announces a written report: counts of what changed,
+// then the report's highlight and most active user when there are any. It
+// offers to open the report and the highlight's repo.
+func reportNotification(result *diff.Result, rpt *report.Report, reportPath string) notify.Notification {
+	n := notify.Notification{
+		Title:    "GitStreams",
+		Message:  formatNotificationMessage(result),
+		Subtitle: "Activity from people you follow",
+		Sound:    "default",
+		OpenURL:  "file://" + reportPath,
+	}
+	n.Actions = []notify.Action{{Label: "Open report", URL: n.OpenURL}}
+	if rpt == nil {
+		return n
+	}
+	n.Summary = &notify.Summary{}
+	for _, c := range rpt.CategoryCounts() {
+		n.Summary.Counts = append(n.Summary.Counts, notify.CategoryCount{Name: c.Name, Icon: c.Icon, Count: c.Count})
+	}
+	if h := rpt.GetHighlight(); h != nil {
+		n.Highlight = h.String()
+		n.Summary.Featured = &notify.FeaturedActivity{
+			Text:      n.Highlight,
+			Reason:    h.Reason,
+			Details:   h.Activity.Details,
+			URL:       h.Activity.Link(),
+			User:      h.User,
+			UserURL:   h.Activity.UserURL,
+			AvatarURL: h.AvatarURL,
+		}
+		if h.Activity.RepoURL != "" {
+			n.Actions = append(n.Actions, notify.Action{Label: "Open top repo", URL: h.Activity.RepoURL})
+		}
+		if h.Activity.Type == report.ActivityOpenSourced {
+			// Rare enough to lead with
+			n.Message = n.Highlight + "! " + n.Message
+		} else {
+			n.Message += ". Highlight: " + n.Highlight
+		}
+	}
+	for _, h := range rpt.TopHighlights(notificationHighlights) {
+		n.Highlights = append(n.Highlights, h.String())
+	}
+	if n.MostActive = rpt.MostActiveUser(); n.MostActive != "" {
+		n.Subtitle = n.MostActive + " was the most active"
+	}
+	return n
+}
+
+// selectNotifier returns the notifier for this run: desktop notifications
+// unless disabled, plus the webhook, Slack, Discord and email if they're
+// configured, with notifications routed between them if there are routes.
+// It returns nil if there's nothing to notify.
+func selectNotifier(cfg *Config, deps *Dependencies) (Notifier, error) {
+	var notifiers notify.MultiNotifier
+	named := make(map[string]notify.Notifier)
+	add := func(name string, n notify.Notifier) {
+		notifiers = append(notifiers, n)
+		named[name] = n
+	}
+	if !cfg.NoNotify {
+		add("desktop", deps.NotifierFactory())
+	}
+	if cfg.WebhookURL != "" {
+		add("webhook", notify.NewWebhookNotifier(cfg.WebhookURL))
+	}
+	if cfg.SlackWebhook != "" {
+		add("slack", notify.NewSlackNotifier(cfg.SlackWebhook, cfg.SlackHighs))
+	}
+	if cfg.DiscordWebhook != "" {
+		add("discord", notify.NewDiscordNotifier(cfg.DiscordWebhook))
+	}
+	if cfg.EmailTo != "" {
+		add("email", emailNotifier(cfg, deps.Now))
+	}
+
+	routes, err := loadRoutes(cfg.RoutesPath)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(notifiers) == 0:
+		return nil, nil
+	case len(routes) > 0:
+		return routeNotifiers(named, routes), nil
+	case len(notifiers) == 1:
+		return notifiers[0], nil
+	default:
+		return notifiers, nil
+	}
+}
+
+// applyHeadlessDefaults adjusts settings for a host with no display: the
+// browser isn't opened, desktop notifications are skipped, and the report
+// goes beside the database rather than to a temp directory that may not
+// outlive the container. Settings given explicitly are left alone.
+func applyHeadlessDefaults(cfg *Config, sources map[string]string) {
+	if sources["no-open"] == sourceDefault {
+		cfg.NoOpen = true
+	}
+	if sources["no-notify"] == sourceDefault {
+		cfg.NoNotify = true
+	}
+	if cfg.ReportDir == "" {
+		cfg.ReportDir = filepath.Join(filepath.Dir(dbFile(cfg.DBPath)), "reports")
+	}
+}
+
+// detectHeadless reports whether gitstreams is running without a display:
+// inside a container, or on a Unix-like system with no X11 or Wayland
+// session.
+func detectHeadless() bool {
+	return isContainer(os.Getenv, fileExists) || (runtime.GOOS != "darwin" && runtime.GOOS != "windows" && noDisplay(os.Getenv))
+}
+
+func noDisplay(getenv func(string) string) bool {
+	return getenv("DISPLAY") == "" && getenv("WAYLAND_DISPLAY") == ""
+}
+
+// isContainer checks the markers Docker, Podman and systemd-nspawn leave.
+func isContainer(getenv func(string) string, exists func(string) bool) bool {
+	return getenv("container") != "" || exists("/.dockerenv") || exists("/run/.containerenv")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 func openBrowser(url string) error {
 	var cmd string
 	var args []string
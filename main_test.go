@@ -3,12 +3,19 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,6 +24,7 @@ import (
 	"github.com/justinabrahms/gitstreams/notify"
 	"github.com/justinabrahms/gitstreams/otel"
 	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/source"
 	"github.com/justinabrahms/gitstreams/storage"
 )
 
@@ -29,14 +37,23 @@ type mockGitHubClient struct {
 	starredErr    map[string]error
 	ownedErr      map[string]error
 	eventsErr     map[string]error
+	followedBy    map[string][]github.User
 	followedUsers []github.User
+	starredCalls  int
+	ownedCalls    int
+	eventsCalls   int
 }
 
 func (m *mockGitHubClient) GetFollowedUsers(ctx context.Context) ([]github.User, error) {
 	return m.followedUsers, m.followedErr
 }
 
+func (m *mockGitHubClient) GetFollowedUsersByUsername(ctx context.Context, username string) ([]github.User, error) {
+	return m.followedBy[username], m.followedErr
+}
+
 func (m *mockGitHubClient) GetStarredReposByUsername(ctx context.Context, username string) ([]github.Repository, error) {
+	m.starredCalls++
 	if m.starredErr != nil {
 		if err, ok := m.starredErr[username]; ok {
 			return nil, err
@@ -46,6 +63,7 @@ func (m *mockGitHubClient) GetStarredReposByUsername(ctx context.Context, userna
 }
 
 func (m *mockGitHubClient) GetOwnedReposByUsername(ctx context.Context, username string) ([]github.Repository, error) {
+	m.ownedCalls++
 	if m.ownedErr != nil {
 		if err, ok := m.ownedErr[username]; ok {
 			return nil, err
@@ -55,6 +73,7 @@ func (m *mockGitHubClient) GetOwnedReposByUsername(ctx context.Context, username
 }
 
 func (m *mockGitHubClient) GetRecentEvents(ctx context.Context, username string) ([]github.Event, error) {
+	m.eventsCalls++
 	if m.eventsErr != nil {
 		if err, ok := m.eventsErr[username]; ok {
 			return nil, err
@@ -79,6 +98,14 @@ func (m *mockStore) Save(snapshot *storage.Snapshot) error {
 	return m.saveErr
 }
 
+func (m *mockStore) SaveAll(snapshots []*storage.Snapshot) error {
+	m.savedCalled = true
+	if len(snapshots) > 0 {
+		m.savedSnapshot = snapshots[len(snapshots)-1]
+	}
+	return m.saveErr
+}
+
 func (m *mockStore) GetByUser(userID string, limit int) ([]*storage.Snapshot, error) {
 	if m.getErr != nil {
 		return nil, m.getErr
@@ -137,6 +164,8 @@ func fixedTime() time.Time {
 
 func TestRun_MissingToken(t *testing.T) {
 	var stdout, stderr bytes.Buffer
+	// The store and run log are opened before the token is checked
+	setHome(t)
 
 	// Unset GITHUB_TOKEN for this test
 	t.Setenv("GITHUB_TOKEN", "")
@@ -152,6 +181,113 @@ func TestRun_MissingToken(t *testing.T) {
 	}
 }
 
+func TestRun_AnonymousMode(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	tmpDir := t.TempDir()
+	t.Setenv("GITHUB_TOKEN", "")
+
+	mockClient := &mockGitHubClient{
+		followedBy: map[string][]github.User{
+			"alice": {{Login: "bob"}},
+		},
+		events: map[string][]github.Event{
+			"bob": {{Type: "PushEvent", Actor: github.User{Login: "bob"}, Repo: github.EventRepo{Name: "bob/repo"}, CreatedAt: fixedTime()}},
+		},
+	}
+
+	var gotToken string
+	store := &mockStore{}
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { gotToken = token; return mockClient },
+		StoreFactory:        func(dbPath string) (Store, error) { return store, nil },
+		NotifierFactory:     func() Notifier { return &mockNotifier{} },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		OpenBrowser:         func(url string) error { return nil },
+		Now:                 fixedTime,
+	}
+
+	result := run(&stdout, &stderr, []string{
+		"-user", "alice",
+		"-db", filepath.Join(tmpDir, "test.db"),
+		"-report", filepath.Join(tmpDir, "report.html"),
+		"-no-notify",
+		"-no-open",
+	}, deps)
+
+	if result != 0 {
+		t.Fatalf("expected exit code 0, got %d. stderr: %s", result, stderr.String())
+	}
+	if gotToken != "" {
+		t.Errorf("expected client to be created without a token, got %q", gotToken)
+	}
+	if !strings.Contains(stderr.String(), "running without GITHUB_TOKEN") {
+		t.Errorf("expected notice about unauthenticated mode, got: %s", stderr.String())
+	}
+	if mockClient.starredCalls != 0 || mockClient.ownedCalls != 0 {
+		t.Errorf("expected events-only fetching, got %d starred and %d owned calls",
+			mockClient.starredCalls, mockClient.ownedCalls)
+	}
+	if mockClient.eventsCalls != 1 {
+		t.Errorf("expected 1 events call, got %d", mockClient.eventsCalls)
+	}
+	// alice's follows are kept apart from anyone else's
+	if store.savedSnapshot == nil || store.savedSnapshot.UserID != userSnapshotPrefix+"alice" {
+		t.Errorf("saved snapshot = %+v, want it under %s", store.savedSnapshot, userSnapshotPrefix+"alice")
+	}
+}
+
+func TestResolveFetchPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    fetchPlan
+		wantErr bool
+	}{
+		{
+			name: "token uses full plan",
+			cfg:  Config{Token: "token"},
+			want: fetchPlan{Starred: true, Owned: true, Events: true, Profiles: true},
+		},
+		{
+			name: "token with user tracks that user's follow list",
+			cfg:  Config{Token: "token", Username: "alice"},
+			want: fetchPlan{FollowedBy: "alice", Starred: true, Owned: true, Events: true, Profiles: true},
+		},
+		{
+			name: "token with discussions",
+			cfg:  Config{Token: "token", Discussions: true},
+			want: fetchPlan{Starred: true, Owned: true, Events: true, Discussions: true, Profiles: true},
+		},
+		{
+			name: "no token can't fetch discussions",
+			cfg:  Config{Username: "alice", Discussions: true},
+			want: fetchPlan{FollowedBy: "alice", Events: true},
+		},
+		{
+			name: "no token degrades to events only",
+			cfg:  Config{Username: "alice"},
+			want: fetchPlan{FollowedBy: "alice", Events: true},
+		},
+		{
+			name:    "no token and no user is an error",
+			cfg:     Config{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveFetchPlan(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveFetchPlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveFetchPlan() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRun_SuccessfulRun_NoChanges(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	tmpDir := t.TempDir()
@@ -191,7 +327,7 @@ func TestRun_SuccessfulRun_NoChanges(t *testing.T) {
 		GitHubClientFactory: func(token string) GitHubClient { return mockClient },
 		StoreFactory:        func(dbPath string) (Store, error) { return mockStoreInst, nil },
 		NotifierFactory:     func() Notifier { return mockNotifierInst },
-		ReportGenerator:     func() (ReportGenerator, error) { return mockGenInst, nil },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return mockGenInst, nil },
 		OpenBrowser:         func(url string) error { browserOpened = true; return nil },
 		Now:                 fixedTime,
 		Tracer:              otel.Tracer(),
@@ -250,7 +386,7 @@ func TestRun_SuccessfulRun_WithChanges(t *testing.T) {
 		GitHubClientFactory: func(token string) GitHubClient { return mockClient },
 		StoreFactory:        func(dbPath string) (Store, error) { return mockStoreInst, nil },
 		NotifierFactory:     func() Notifier { return mockNotifierInst },
-		ReportGenerator:     func() (ReportGenerator, error) { return mockGenInst, nil },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return mockGenInst, nil },
 		OpenBrowser:         func(url string) error { browserOpened = true; return nil },
 		Now:                 fixedTime,
 	}
@@ -302,7 +438,7 @@ func TestRun_GitHubAPIError(t *testing.T) {
 		GitHubClientFactory: func(token string) GitHubClient { return mockClient },
 		StoreFactory:        func(dbPath string) (Store, error) { return &mockStore{}, nil },
 		NotifierFactory:     func() Notifier { return &mockNotifier{} },
-		ReportGenerator:     func() (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
 		OpenBrowser:         func(url string) error { return nil },
 		Now:                 fixedTime,
 	}
@@ -332,7 +468,7 @@ func TestRun_StoreError(t *testing.T) {
 			return nil, errors.New("database error")
 		},
 		NotifierFactory: func() Notifier { return &mockNotifier{} },
-		ReportGenerator: func() (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		ReportGenerator: func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
 		OpenBrowser:     func(url string) error { return nil },
 		Now:             fixedTime,
 	}
@@ -352,6 +488,7 @@ func TestRun_StoreError(t *testing.T) {
 }
 
 func TestParseFlags(t *testing.T) {
+	setHome(t)
 	tests := []struct {
 		check    func(*testing.T, *Config)
 		name     string
@@ -432,6 +569,96 @@ func TestParseFlags(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "default timeout",
+			args:     []string{},
+			envToken: "token",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Timeout != defaultTimeout {
+					t.Errorf("expected timeout %s, got: %s", defaultTimeout, cfg.Timeout)
+				}
+			},
+		},
+		{
+			name:     "custom timeout",
+			args:     []string{"-timeout", "90s"},
+			envToken: "token",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Timeout != 90*time.Second {
+					t.Errorf("expected timeout 90s, got: %s", cfg.Timeout)
+				}
+			},
+		},
+		{
+			name:     "default format",
+			args:     []string{},
+			envToken: "token",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Format != report.FormatHTML {
+					t.Errorf("expected html format, got: %s", cfg.Format)
+				}
+			},
+		},
+		{
+			name:     "unknown format",
+			args:     []string{"-format", "nope"},
+			envToken: "token",
+			wantErr:  true,
+		},
+		{
+			name:     "negative timeout",
+			args:     []string{"-timeout", "-1m"},
+			envToken: "token",
+			wantErr:  true,
+		},
+		{
+			name:     "star quality",
+			args:     []string{"-min-stars", "5", "-hide-undescribed-stars"},
+			envToken: "token",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.MinStars != 5 || !cfg.StarsNeedDesc {
+					t.Errorf("expected min-stars 5 and undescribed stars hidden, got %d and %v", cfg.MinStars, cfg.StarsNeedDesc)
+				}
+			},
+		},
+		{
+			name:     "slack webhook",
+			args:     []string{"-slack-webhook", "https://hooks.slack.com/x"},
+			envToken: "token",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.SlackWebhook != "https://hooks.slack.com/x" || cfg.SlackHighs != 3 {
+					t.Errorf("expected the webhook with 3 highlights by default, got %q and %d", cfg.SlackWebhook, cfg.SlackHighs)
+				}
+			},
+		},
+		{
+			name:     "too many slack highlights",
+			args:     []string{"-slack-highlights", "6"},
+			envToken: "token",
+			wantErr:  true,
+		},
+		{
+			name:     "negative min-stars",
+			args:     []string{"-min-stars", "-1"},
+			envToken: "token",
+			wantErr:  true,
+		},
+		{
+			name:     "enterprise github url",
+			args:     []string{"-github-url", "https://ghe.example.com"},
+			envToken: "token",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.GitHubURL != "https://ghe.example.com" {
+					t.Errorf("expected the enterprise url, got: %s", cfg.GitHubURL)
+				}
+			},
+		},
+		{
+			name:     "github url without scheme",
+			args:     []string{"-github-url", "ghe.example.com"},
+			envToken: "token",
+			wantErr:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -450,130 +677,209 @@ func TestParseFlags(t *testing.T) {
 	}
 }
 
-func TestConvertRepo(t *testing.T) {
-	ghRepo := github.Repository{
-		Name:        "test-repo",
-		Description: "A test repository",
-		Language:    "Go",
-		StarCount:   100,
-		Owner:       github.User{Login: "owner"},
+func TestFormatRequestStats(t *testing.T) {
+	stats := github.RequestStats{
+		ByEndpoint:  map[string]int{"starred": 10, "events": 10, "following": 1},
+		Pagination:  2,
+		NotModified: 4,
+		Total:       23,
 	}
 
-	diffRepo := convertRepo(ghRepo)
-
-	if diffRepo.Name != "test-repo" {
-		t.Errorf("expected name 'test-repo', got: %s", diffRepo.Name)
-	}
-	if diffRepo.Owner != "owner" {
-		t.Errorf("expected owner 'owner', got: %s", diffRepo.Owner)
-	}
-	if diffRepo.Description != "A test repository" {
-		t.Errorf("expected description, got: %s", diffRepo.Description)
-	}
-	if diffRepo.Language != "Go" {
-		t.Errorf("expected language 'Go', got: %s", diffRepo.Language)
-	}
-	if diffRepo.Stars != 100 {
-		t.Errorf("expected 100 stars, got: %d", diffRepo.Stars)
+	got := formatRequestStats(stats)
+	want := "API requests: 23 total (events 10, following 1, starred 10, pagination 2, 304 not modified 4)"
+	if got != want {
+		t.Errorf("formatRequestStats() = %q, want %q", got, want)
 	}
 }
 
-func TestConvertEvent(t *testing.T) {
-	eventTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
-	ghEvent := github.Event{
-		Type:      "PushEvent",
-		Actor:     github.User{Login: "actor"},
-		Repo:      github.EventRepo{Name: "owner/repo"},
-		CreatedAt: eventTime,
-	}
+// statsGitHubClient wraps mockGitHubClient with request accounting.
+type statsGitHubClient struct {
+	*mockGitHubClient
+	stats github.RequestStats
+}
+
+func (s *statsGitHubClient) GetRequestStats() github.RequestStats {
+	return s.stats
+}
 
-	diffEvent := convertEvent(ghEvent)
+func TestRun_VerbosePrintsRequestStats(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	tmpDir := t.TempDir()
+
+	client := &statsGitHubClient{
+		mockGitHubClient: &mockGitHubClient{followedUsers: []github.User{}},
+		stats:            github.RequestStats{ByEndpoint: map[string]int{"following": 1}, Total: 1},
+	}
 
-	if diffEvent.Type != "PushEvent" {
-		t.Errorf("expected type 'PushEvent', got: %s", diffEvent.Type)
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return client },
+		StoreFactory:        func(dbPath string) (Store, error) { return &mockStore{}, nil },
+		NotifierFactory:     func() Notifier { return &mockNotifier{} },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		OpenBrowser:         func(url string) error { return nil },
+		Now:                 fixedTime,
 	}
-	if diffEvent.Actor != "actor" {
-		t.Errorf("expected actor 'actor', got: %s", diffEvent.Actor)
+
+	result := run(&stdout, &stderr, []string{
+		"-token", "test-token",
+		"-db", filepath.Join(tmpDir, "test.db"),
+		"-no-notify",
+		"-no-open",
+		"-v",
+	}, deps)
+
+	if result != 0 {
+		t.Fatalf("expected exit code 0, got %d. stderr: %s", result, stderr.String())
 	}
-	if diffEvent.Repo != "owner/repo" {
-		t.Errorf("expected repo 'owner/repo', got: %s", diffEvent.Repo)
+	if !strings.Contains(stdout.String(), "API requests: 1 total (following 1") {
+		t.Errorf("expected request breakdown in verbose output, got: %s", stdout.String())
 	}
-	if !diffEvent.CreatedAt.Equal(eventTime) {
-		t.Errorf("expected time %v, got: %v", eventTime, diffEvent.CreatedAt)
+	if !strings.Contains(stdout.String(), "Saved current snapshot (") {
+		t.Errorf("expected snapshot size in verbose output, got: %s", stdout.String())
 	}
 }
 
-func TestNoDuplicateRepoCreations(t *testing.T) {
-	// This test validates that we don't get duplicate "created repo" entries
-	// when both NewRepos and CreateEvent exist for the same repository.
-	now := time.Now()
-	result := &diff.Result{
-		OldCapturedAt: now.Add(-1 * time.Hour),
-		NewCapturedAt: now,
-		NewRepos: []diff.RepoChange{
-			{
-				Username: "testuser",
-				Repo: diff.Repo{
-					Owner:     "testuser",
-					Name:      "new-repo",
-					CreatedAt: now,
-				},
-			},
-		},
-		NewEvents: []diff.EventChange{
-			{
-				Username: "testuser",
-				Event: diff.Event{
-					Type:      "CreateEvent",
-					Repo:      "testuser/new-repo",
-					CreatedAt: now,
-				},
+func TestRun_ReportProvenance(t *testing.T) {
+	client := &statsGitHubClient{
+		mockGitHubClient: &mockGitHubClient{
+			followedUsers: []github.User{{Login: "alice", ID: 1}},
+			starredRepos: map[string][]github.Repository{
+				"alice": {{Name: "repo", Owner: github.User{Login: "owner"}, CreatedAt: fixedTime()}},
 			},
+			eventsErr: map[string]error{"alice": errors.New("boom")},
 		},
+		stats: github.RequestStats{Total: 4},
+	}
+	gen := &mockReportGenerator{}
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return client },
+		StoreFactory:        func(dbPath string) (Store, error) { return &mockStore{}, nil },
+		NotifierFactory:     func() Notifier { return &mockNotifier{} },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return gen, nil },
+		OpenBrowser:         func(url string) error { return nil },
+		Now:                 fixedTime,
 	}
 
-	rpt := buildReport(result, now.Add(-1*time.Hour), now, now)
-
-	// Count ActivityCreatedRepo entries
-	createdRepoCount := 0
-	for _, ua := range rpt.UserActivities {
-		for _, a := range ua.Activities {
-			if a.Type == report.ActivityCreatedRepo {
-				createdRepoCount++
-			}
-		}
+	var stdout, stderr bytes.Buffer
+	code := run(&stdout, &stderr, []string{
+		"-token", "test-token",
+		"-db", filepath.Join(t.TempDir(), "test.db"),
+		"-report", filepath.Join(t.TempDir(), "report.html"),
+		"-no-notify", "-no-open",
+		"-sync-lookback-days", "14",
+	}, deps)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
 	}
 
-	// Should only have 1 entry (from NewRepos), not 2 (NewRepos + CreateEvent)
-	if createdRepoCount != 1 {
-		t.Errorf("expected 1 ActivityCreatedRepo entry, got %d", createdRepoCount)
+	want := &report.Provenance{
+		Mode:         report.ModeLive,
+		To:           fixedTime(),
+		Gaps:         []string{"events for alice"},
+		LookbackDays: 14,
+		APIRequests:  4,
 	}
+	if got := gen.generatedReport.Provenance; !reflect.DeepEqual(got, want) {
+		t.Errorf("Provenance = %+v, want %+v", got, want)
+	}
+}
+
+// budgetGitHubClient wraps mockGitHubClient with a fixed rate limit.
+type budgetGitHubClient struct {
+	*mockGitHubClient
+	rateLimit *github.RateLimit
+	maxPages  int
 }
 
-func TestEventTypeToActivityType(t *testing.T) {
+func (b *budgetGitHubClient) FetchRateLimit(ctx context.Context) (*github.RateLimit, error) {
+	return b.rateLimit, nil
+}
+
+func (b *budgetGitHubClient) SetMaxPages(n int) {
+	b.maxPages = n
+}
+
+func TestAdaptToBudget(t *testing.T) {
+	now := fixedTime()
+	cutoff := now.AddDate(0, 0, -30)
+
 	tests := []struct {
-		input    string
-		expected report.ActivityType
+		name         string
+		remaining    int
+		wantPlan     fetchPlan
+		wantDays     int
+		wantMaxPages int
+		wantNotice   bool
 	}{
-		{"WatchEvent", report.ActivityStarred},
-		{"CreateEvent", ""}, // CreateEvent is skipped to avoid duplicates with NewRepos
-		{"ForkEvent", report.ActivityForked},
-		{"PushEvent", report.ActivityPushed},
-		{"PullRequestEvent", report.ActivityPR},
-		{"IssuesEvent", report.ActivityIssue},
-		{"UnknownEvent", report.ActivityType("UnknownEvent")},
+		{
+			name:      "healthy budget keeps full depth",
+			remaining: 5000,
+			wantPlan:  defaultFetchPlan(),
+			wantDays:  30,
+		},
+		{
+			name:         "low budget reduces depth and lookback",
+			remaining:    300, // 100 users x 3 endpoints = 300 minimum, 600 full
+			wantPlan:     fetchPlan{Starred: true, Owned: true, Events: true},
+			wantDays:     15,
+			wantMaxPages: 1,
+			wantNotice:   true,
+		},
+		{
+			name:         "very low budget leaves repos to the planner",
+			remaining:    120,
+			wantPlan:     fetchPlan{Starred: true, Owned: true, Events: true, Budget: 120},
+			wantDays:     6,
+			wantMaxPages: 1,
+			wantNotice:   true,
+		},
+		{
+			name:         "lookback never drops below a day",
+			remaining:    1,
+			wantPlan:     fetchPlan{Starred: true, Owned: true, Events: true, Budget: 1},
+			wantDays:     1,
+			wantMaxPages: 1,
+			wantNotice:   true,
+		},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			result := eventTypeToActivityType(tt.input)
-			if result != tt.expected {
-				t.Errorf("eventTypeToActivityType(%q) = %q, want %q", tt.input, result, tt.expected)
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			client := &budgetGitHubClient{
+				mockGitHubClient: &mockGitHubClient{},
+				rateLimit:        &github.RateLimit{Limit: 5000, Remaining: tt.remaining},
+				maxPages:         -1,
+			}
+
+			plan, gotCutoff := adaptToBudget(context.Background(), client, defaultFetchPlan(), 100, now, cutoff, &w)
+
+			if plan != tt.wantPlan {
+				t.Errorf("plan = %+v, want %+v", plan, tt.wantPlan)
+			}
+			if days := int(now.Sub(gotCutoff).Hours() / 24); days != tt.wantDays {
+				t.Errorf("lookback = %d days, want %d", days, tt.wantDays)
+			}
+			if client.maxPages != tt.wantMaxPages {
+				t.Errorf("maxPages = %d, want %d", client.maxPages, tt.wantMaxPages)
+			}
+			if gotNotice := strings.Contains(w.String(), "API requests remain"); gotNotice != tt.wantNotice {
+				t.Errorf("notice = %v, want %v (output: %q)", gotNotice, tt.wantNotice, w.String())
 			}
 		})
 	}
 }
 
+func TestAdaptToBudget_UnsupportedClient(t *testing.T) {
+	now := fixedTime()
+	cutoff := now.AddDate(0, 0, -30)
+
+	plan, gotCutoff := adaptToBudget(context.Background(), &mockGitHubClient{}, defaultFetchPlan(), 100, now, cutoff, io.Discard)
+	if plan != defaultFetchPlan() || !gotCutoff.Equal(cutoff) {
+		t.Errorf("expected plan and cutoff unchanged, got %+v, %v", plan, gotCutoff)
+	}
+}
+
 func TestFormatNotificationMessage(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -600,6 +906,21 @@ func TestFormatNotificationMessage(t *testing.T) {
 			},
 			expected: "1 new stars and 3 new repos",
 		},
+		{
+			name: "new follows",
+			result: &diff.Result{
+				NewFollows: []diff.FollowChange{{}, {}},
+			},
+			expected: "2 new follows",
+		},
+		{
+			name: "watched repos",
+			result: &diff.Result{
+				NewStars: []diff.RepoChange{{}},
+				Watched:  []diff.WatchChange{{}, {}},
+			},
+			expected: "1 new stars and 2 watched repos",
+		},
 		{
 			name: "all types",
 			result: &diff.Result{
@@ -622,6 +943,61 @@ func TestFormatNotificationMessage(t *testing.T) {
 	}
 }
 
+func TestReportNotification(t *testing.T) {
+	result := &diff.Result{NewStars: []diff.RepoChange{{}}, NewRepos: []diff.RepoChange{{}}}
+	rpt := &report.Report{UserActivities: []report.UserActivity{
+		{User: "bob", Activities: []report.Activity{{Type: report.ActivityStarred, RepoName: "x/y"}}},
+		{User: "alice", Activities: []report.Activity{
+			{Type: report.ActivityCreatedRepo, RepoName: "alice/new", RepoURL: "https://github.com/alice/new"},
+			{Type: report.ActivityPushed, RepoName: "alice/old"},
+		}},
+	}}
+
+	n := reportNotification(result, rpt, "/tmp/report.html")
+	if want := "1 new stars and 1 new repos. Highlight: alice created alice/new"; n.Message != want {
+		t.Errorf("Message = %q, want %q", n.Message, want)
+	}
+	if n.Highlight != "alice created alice/new" || n.MostActive != "alice" {
+		t.Errorf("expected alice's new repo and alice, got %q and %q", n.Highlight, n.MostActive)
+	}
+	if n.Subtitle != "alice was the most active" || n.OpenURL != "file:///tmp/report.html" {
+		t.Errorf("unexpected subtitle or URL: %+v", n)
+	}
+	if want := []string{"alice created alice/new", "bob starred x/y"}; !slices.Equal(n.Highlights, want) {
+		t.Errorf("Highlights = %q, want %q", n.Highlights, want)
+	}
+	wantActions := []notify.Action{{Label: "Open report", URL: "file:///tmp/report.html"}, {Label: "Open top repo", URL: "https://github.com/alice/new"}}
+	if !slices.Equal(n.Actions, wantActions) {
+		t.Errorf("Actions = %+v, want %+v", n.Actions, wantActions)
+	}
+	if n.Summary == nil || n.Summary.Featured == nil || n.Summary.Featured.Text != n.Highlight || n.Summary.Featured.User != "alice" {
+		t.Fatalf("expected the summary to feature alice's new repo, got %+v", n.Summary)
+	}
+	var counts []string
+	for _, c := range n.Summary.Counts {
+		counts = append(counts, fmt.Sprintf("%s %d", c.Name, c.Count))
+	}
+	if want := []string{"New Stars 1", "Repos Created 1", "Recent Pushes 1"}; !slices.Equal(counts, want) {
+		t.Errorf("Summary.Counts = %q, want %q", counts, want)
+	}
+
+	// Open-sourcing leads the message
+	rpt.UserActivities[0].Activities = append(rpt.UserActivities[0].Activities, report.Activity{Type: report.ActivityOpenSourced, RepoName: "bob/secret"})
+	n = reportNotification(result, rpt, "/tmp/report.html")
+	if want := "bob open-sourced bob/secret! 1 new stars and 1 new repos"; n.Message != want {
+		t.Errorf("Message = %q, want %q", n.Message, want)
+	}
+
+	// Without a report it's counts only
+	n = reportNotification(result, nil, "/tmp/report.html")
+	if n.Message != "1 new stars and 1 new repos" || n.Highlight != "" || n.Subtitle != "Activity from people you follow" {
+		t.Errorf("expected a counts-only notification, got %+v", n)
+	}
+	if len(n.Actions) != 1 || n.Actions[0].Label != "Open report" {
+		t.Errorf("expected only the report to open, got %+v", n.Actions)
+	}
+}
+
 func TestSnapshotRoundTrip(t *testing.T) {
 	original := diff.NewSnapshot(fixedTime())
 	original.Users["user1"] = diff.UserActivity{
@@ -646,6 +1022,9 @@ func TestSnapshotRoundTrip(t *testing.T) {
 	if stored.UserID != snapshotUserID {
 		t.Errorf("expected userID %q, got %q", snapshotUserID, stored.UserID)
 	}
+	if stored.AppVersion != version {
+		t.Errorf("expected app version %q, got %q", version, stored.AppVersion)
+	}
 
 	// Convert back
 	restored, err := storageToSnapshot(stored)
@@ -667,69 +1046,509 @@ func TestSnapshotRoundTrip(t *testing.T) {
 		t.Errorf("expected username 'user1', got %q", user.Username)
 	}
 
-	if len(user.StarredRepos) != 1 {
-		t.Errorf("expected 1 starred repo, got %d", len(user.StarredRepos))
+	if len(user.StarredRepos) != 1 {
+		t.Errorf("expected 1 starred repo, got %d", len(user.StarredRepos))
+	}
+
+	if len(user.OwnedRepos) != 1 {
+		t.Errorf("expected 1 owned repo, got %d", len(user.OwnedRepos))
+	}
+
+	if len(user.Events) != 1 {
+		t.Errorf("expected 1 event, got %d", len(user.Events))
+	}
+}
+
+func TestRun_AppendReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	reportPath := filepath.Join(tmpDir, "report.html")
+
+	// Each run starts from an empty store, so everything fetched is "new"
+	runWithStar := func(repo string) (*mockReportGenerator, bool) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		mockClient := &mockGitHubClient{
+			followedUsers: []github.User{{Login: "testuser", ID: 1}},
+			starredRepos: map[string][]github.Repository{
+				"testuser": {{Name: repo, Owner: github.User{Login: "owner1"}, CreatedAt: fixedTime()}},
+			},
+		}
+		gen := &mockReportGenerator{}
+		browserOpened := false
+
+		deps := &Dependencies{
+			GitHubClientFactory: func(token string) GitHubClient { return mockClient },
+			StoreFactory:        func(dbPath string) (Store, error) { return &mockStore{}, nil },
+			NotifierFactory:     func() Notifier { return &mockNotifier{} },
+			ReportGenerator:     func(string) (ReportGenerator, error) { return gen, nil },
+			OpenBrowser:         func(url string) error { browserOpened = true; return nil },
+			Now:                 fixedTime,
+		}
+
+		result := run(&stdout, &stderr, []string{
+			"-token", "test-token",
+			"-db", filepath.Join(tmpDir, "test.db"),
+			"-report", reportPath,
+			"-no-notify",
+			"-append-report",
+		}, deps)
+		if result != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", result, stderr.String())
+		}
+		return gen, browserOpened
+	}
+
+	gen, opened := runWithStar("first")
+	if !opened {
+		t.Error("expected browser to open for a new report")
+	}
+	if gen.generatedReport.RefreshInterval != appendReportRefresh {
+		t.Errorf("expected refresh interval %v, got %v", appendReportRefresh, gen.generatedReport.RefreshInterval)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "report.json")); err != nil {
+		t.Errorf("expected report data beside the report: %v", err)
+	}
+
+	gen, opened = runWithStar("second")
+	if opened {
+		t.Error("expected browser not to reopen an appended report")
+	}
+	if got := gen.generatedReport.TotalActivities(); got != 2 {
+		t.Errorf("expected 2 activities after append, got %d", got)
+	}
+
+	// Re-running with the same activity doesn't duplicate it
+	gen, _ = runWithStar("second")
+	if got := gen.generatedReport.TotalActivities(); got != 2 {
+		t.Errorf("expected appended activity to be de-duplicated, got %d", got)
+	}
+
+	html, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("reading report: %v", err)
+	}
+	if string(html) != "<html>mock report</html>" {
+		t.Errorf("unexpected report contents: %q", html)
+	}
+}
+
+func TestRun_AppendReportCorruptData(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	tmpDir := t.TempDir()
+	reportPath := filepath.Join(tmpDir, "report.html")
+	if err := os.WriteFile(filepath.Join(tmpDir, "report.json"), []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			return &mockGitHubClient{
+				followedUsers: []github.User{{Login: "testuser", ID: 1}},
+				starredRepos: map[string][]github.Repository{
+					"testuser": {{Name: "repo", Owner: github.User{Login: "owner1"}}},
+				},
+			}
+		},
+		StoreFactory:    func(dbPath string) (Store, error) { return &mockStore{}, nil },
+		NotifierFactory: func() Notifier { return &mockNotifier{} },
+		ReportGenerator: func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		OpenBrowser:     func(url string) error { return nil },
+		Now:             fixedTime,
+	}
+
+	result := run(&stdout, &stderr, []string{
+		"-token", "test-token",
+		"-db", filepath.Join(tmpDir, "test.db"),
+		"-report", reportPath,
+		"-append-report",
+	}, deps)
+
+	if result != 1 {
+		t.Errorf("expected exit code 1, got %d", result)
+	}
+	if !strings.Contains(stderr.String(), "loading existing report") {
+		t.Errorf("expected load error, got: %s", stderr.String())
+	}
+}
+
+func TestRun_Feed(t *testing.T) {
+	tmpDir := t.TempDir()
+	feedPath := filepath.Join(tmpDir, "feeds", "gitstreams.atom")
+
+	runWithStar := func(repo string) (int, string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		deps := &Dependencies{
+			GitHubClientFactory: func(token string) GitHubClient {
+				return &mockGitHubClient{
+					followedUsers: []github.User{{Login: "testuser", ID: 1}},
+					starredRepos: map[string][]github.Repository{
+						"testuser": {{Name: repo, Owner: github.User{Login: "owner1"}, CreatedAt: fixedTime()}},
+					},
+				}
+			},
+			StoreFactory:    func(dbPath string) (Store, error) { return &mockStore{}, nil },
+			NotifierFactory: func() Notifier { return &mockNotifier{} },
+			ReportGenerator: func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+			OpenBrowser:     func(url string) error { return nil },
+			Now:             fixedTime,
+		}
+		code := run(&stdout, &stderr, []string{
+			"-token", "test-token",
+			"-db", filepath.Join(tmpDir, "test.db"),
+			"-report", filepath.Join(tmpDir, "report.html"),
+			"-feed", feedPath,
+			"-no-notify",
+		}, deps)
+		return code, stderr.String()
+	}
+	readFeed := func() *report.Feed {
+		t.Helper()
+		data, err := os.ReadFile(feedPath) // #nosec G304 -- test temp dir
+		if err != nil {
+			t.Fatalf("reading feed: %v", err)
+		}
+		feed, err := report.ReadFeed(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return feed
+	}
+
+	if code, stderr := runWithStar("first"); code != 0 {
+		t.Fatalf("expected exit code 0, got %d. stderr: %s", code, stderr)
+	}
+	if feed := readFeed(); len(feed.Entries) != 1 || feed.Entries[0].Title != "testuser starred owner1/first" {
+		t.Errorf("feed after the first run = %+v", feed.Entries)
+	}
+
+	// Each run adds its activities, once
+	runWithStar("second")
+	runWithStar("second")
+	if feed := readFeed(); len(feed.Entries) != 2 {
+		t.Errorf("expected 2 feed entries, got %+v", feed.Entries)
+	}
+
+	if err := os.WriteFile(feedPath, []byte("not a feed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code, stderr := runWithStar("third"); code != 1 || !strings.Contains(stderr, "updating feed") {
+		t.Errorf("expected a corrupt feed to fail the run, got %d: %s", code, stderr)
+	}
+}
+
+func TestReportDataPath(t *testing.T) {
+	tests := map[string]string{
+		"/tmp/gitstreams-2024-01-15.html": "/tmp/gitstreams-2024-01-15.json",
+		"report":                          "report.json",
+	}
+	for in, want := range tests {
+		if got := reportDataPath(in); got != want {
+			t.Errorf("reportDataPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRun_Headless(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	var stdout, stderr bytes.Buffer
+	tmpDir := t.TempDir()
+
+	var webhookCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhookCalls++
+	}))
+	defer server.Close()
+
+	desktop := &mockNotifier{}
+	browserOpened := false
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			return &mockGitHubClient{followedUsers: []github.User{{Login: "testuser", ID: 1}}}
+		},
+		StoreFactory:    func(dbPath string) (Store, error) { return &mockStore{}, nil },
+		NotifierFactory: func() Notifier { return desktop },
+		ReportGenerator: func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		OpenBrowser:     func(url string) error { browserOpened = true; return nil },
+		Now:             fixedTime,
+		IsHeadless:      func() bool { return true },
+	}
+
+	result := run(&stdout, &stderr, []string{
+		"-token", "test-token",
+		"-db", filepath.Join(tmpDir, "test.db"),
+		"-webhook", server.URL,
+	}, deps)
+
+	if result != 0 {
+		t.Fatalf("expected exit code 0, got %d. stderr: %s", result, stderr.String())
+	}
+	if browserOpened {
+		t.Error("expected browser not to open when headless")
+	}
+	if desktop.sentNotification != nil {
+		t.Error("expected no desktop notification when headless")
+	}
+	if webhookCalls != 1 {
+		t.Errorf("expected 1 webhook call, got %d", webhookCalls)
+	}
+
+	wantReport := filepath.Join(tmpDir, "reports", "gitstreams-2024-01-15.html")
+	if _, err := os.Stat(wantReport); err != nil {
+		t.Errorf("expected report beside the database at %s: %v", wantReport, err)
+	}
+	if strings.Contains(stderr.String(), "Warning") {
+		t.Errorf("expected no warnings, got: %s", stderr.String())
+	}
+}
+
+func TestApplyHeadlessDefaults_ExplicitSettingsWin(t *testing.T) {
+	cfg := &Config{DBPath: "/data/gitstreams.db", ReportPath: "/out/report.html"}
+	sources := map[string]string{"no-open": sourceFlag, "no-notify": sourceDefault}
+
+	applyHeadlessDefaults(cfg, sources)
+
+	if cfg.NoOpen {
+		t.Error("expected explicit -no-open=false to be kept")
+	}
+	if !cfg.NoNotify {
+		t.Error("expected desktop notifications to be disabled")
+	}
+	if cfg.ReportPath != "/out/report.html" {
+		t.Errorf("expected explicit report path to be kept, got %q", cfg.ReportPath)
+	}
+}
+
+func TestApplyHeadlessDefaults_ReportNameFollowsFormat(t *testing.T) {
+	cfg := &Config{DBPath: "/data/gitstreams.db", Format: report.FormatJSON}
+
+	applyHeadlessDefaults(cfg, map[string]string{})
+
+	got, err := reportPath(cfg, fixedTime())
+	if want := filepath.Join("/data", "reports", "gitstreams-2024-01-15.json"); err != nil || got != want {
+		t.Errorf("reportPath() = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestSelectNotifier(t *testing.T) {
+	desktop := &mockNotifier{}
+	deps := &Dependencies{NotifierFactory: func() Notifier { return desktop }}
+	setHome(t)
+	selected := func(cfg *Config) Notifier {
+		t.Helper()
+		n, err := selectNotifier(cfg, deps)
+		if err != nil {
+			t.Fatalf("selectNotifier() error = %v", err)
+		}
+		return n
+	}
+
+	if n := selected(&Config{NoNotify: true}); n != nil {
+		t.Errorf("expected no notifier, got %T", n)
+	}
+	if n := selected(&Config{}); n != desktop {
+		t.Errorf("expected desktop notifier, got %T", n)
+	}
+	if n, ok := selected(&Config{NoNotify: true, WebhookURL: "http://x"}).(*notify.WebhookNotifier); !ok || n.URL != "http://x" {
+		t.Errorf("expected webhook notifier, got %T", n)
+	}
+	if n, ok := selected(&Config{WebhookURL: "http://x"}).(notify.MultiNotifier); !ok || len(n) != 2 {
+		t.Errorf("expected desktop and webhook notifiers, got %T", n)
+	}
+	if n, ok := selected(&Config{NoNotify: true, SlackWebhook: "https://hooks.slack.com/x", SlackHighs: 2}).(*notify.SlackNotifier); !ok || n.Highlights != 2 {
+		t.Errorf("expected Slack notifier listing 2 highlights, got %T", n)
+	}
+	if n, ok := selected(&Config{NoNotify: true, DiscordWebhook: "https://discord.com/api/webhooks/x"}).(*notify.DiscordNotifier); !ok || n.URL != "https://discord.com/api/webhooks/x" {
+		t.Errorf("expected Discord notifier, got %T", n)
+	}
+	email := &Config{NoNotify: true, EmailTo: "me@example.com", SMTPAddr: "smtp.example.com", EmailFrom: "a@example.com"}
+	if n, ok := selected(email).(*notify.EmailNotifier); !ok || n.Addr != "smtp.example.com:587" {
+		t.Errorf("expected email notifier, got %T", n)
+	}
+}
+
+func TestIsContainer(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	noFiles := func(string) bool { return false }
+
+	if isContainer(noEnv, noFiles) {
+		t.Error("expected no container without markers")
+	}
+	if !isContainer(func(k string) string {
+		if k == "container" {
+			return "podman"
+		}
+		return ""
+	}, noFiles) {
+		t.Error("expected $container to mark a container")
+	}
+	if !isContainer(noEnv, func(p string) bool { return p == "/.dockerenv" }) {
+		t.Error("expected /.dockerenv to mark a container")
+	}
+}
+
+func TestNoDisplay(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+
+	if !noDisplay(getenv) {
+		t.Error("expected no display without DISPLAY or WAYLAND_DISPLAY")
+	}
+	env["WAYLAND_DISPLAY"] = "wayland-0"
+	if noDisplay(getenv) {
+		t.Error("expected WAYLAND_DISPLAY to count as a display")
+	}
+}
+
+func TestSnapshotRoundTrip_SQLite(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	original := diff.NewSnapshot(fixedTime())
+	original.Users["user1"] = diff.UserActivity{
+		Username:     "user1",
+		StarredRepos: []diff.Repo{{Owner: "owner", Name: "repo1", Stars: 10}},
+	}
+
+	id := runSnapshotID(&Config{})
+	size, err := saveSnapshotAs(store, id, original, fixedTime())
+	if err != nil {
+		t.Fatalf("saveSnapshotAs failed: %v", err)
+	}
+	if size == 0 {
+		t.Error("expected non-zero snapshot size")
+	}
+
+	restored, err := loadLatestSnapshot(store, id)
+	if err != nil {
+		t.Fatalf("loadLatestSnapshot failed: %v", err)
+	}
+	user, ok := restored.Users["user1"]
+	if !ok || len(user.StarredRepos) != 1 || user.StarredRepos[0].Stars != 10 {
+		t.Errorf("unexpected restored user: %+v", restored.Users)
+	}
+}
+
+func TestSnapshotToStorage_Versioned(t *testing.T) {
+	stored, err := snapshotToStorage(diff.NewSnapshot(fixedTime()))
+	if err != nil {
+		t.Fatalf("snapshotToStorage failed: %v", err)
+	}
+
+	if stored.SchemaVersion != snapshotSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", snapshotSchemaVersion, stored.SchemaVersion)
 	}
-
-	if len(user.OwnedRepos) != 1 {
-		t.Errorf("expected 1 owned repo, got %d", len(user.OwnedRepos))
+	if stored.Activity != nil {
+		t.Errorf("expected no legacy activity map, got %v", stored.Activity)
 	}
-
-	if len(user.Events) != 1 {
-		t.Errorf("expected 1 event, got %d", len(user.Events))
+	if !json.Valid(stored.Data) {
+		t.Errorf("expected valid JSON, got %q", stored.Data)
+	}
+	if bytes.HasSuffix(stored.Data, []byte("\n")) {
+		t.Error("expected trailing newline to be trimmed")
 	}
 }
 
-func TestBuildReport(t *testing.T) {
-	result := &diff.Result{
-		OldCapturedAt: fixedTime().Add(-24 * time.Hour),
-		NewCapturedAt: fixedTime(),
-		NewStars: []diff.RepoChange{
-			{Username: "user1", Repo: diff.Repo{Owner: "owner", Name: "starred-repo", Description: "A repo"}},
-		},
-		NewRepos: []diff.RepoChange{
-			{Username: "user1", Repo: diff.Repo{Owner: "user1", Name: "new-repo"}},
-		},
-		NewEvents: []diff.EventChange{
-			{Username: "user2", Event: diff.Event{Type: "PushEvent", Repo: "user2/repo", CreatedAt: fixedTime()}},
+func TestStorageToSnapshot_Legacy(t *testing.T) {
+	ss := &storage.Snapshot{
+		Timestamp: fixedTime(),
+		Activity: map[string]interface{}{
+			activityDataKey: map[string]interface{}{
+				"CapturedAt": fixedTime().Format(time.RFC3339),
+				"Users": map[string]interface{}{
+					"user1": map[string]interface{}{"Username": "user1"},
+				},
+			},
 		},
 	}
 
-	rpt := buildReport(result, result.OldCapturedAt, result.NewCapturedAt, fixedTime())
-
-	if rpt.TotalActivities() != 3 {
-		t.Errorf("expected 3 total activities, got %d", rpt.TotalActivities())
+	restored, err := storageToSnapshot(ss)
+	if err != nil {
+		t.Fatalf("storageToSnapshot failed: %v", err)
 	}
-
-	if len(rpt.UserActivities) != 2 {
-		t.Errorf("expected 2 users with activities, got %d", len(rpt.UserActivities))
+	if _, ok := restored.Users["user1"]; !ok {
+		t.Errorf("expected user1 in legacy snapshot, got %+v", restored.Users)
 	}
 }
 
-func TestBuildReport_ManyUsersWithActivity(t *testing.T) {
-	// Simulate 30 users, each with one event - should result in 30 UserActivities
-	result := &diff.Result{
-		OldCapturedAt: fixedTime().Add(-24 * time.Hour),
-		NewCapturedAt: fixedTime(),
+func TestStorageToSnapshot_NewerSchema(t *testing.T) {
+	ss := &storage.Snapshot{
+		ID:            7,
+		SchemaVersion: snapshotSchemaVersion + 1,
+		Data:          json.RawMessage(`{}`),
 	}
 
-	// Create 30 users with events
-	for i := 0; i < 30; i++ {
-		username := fmt.Sprintf("user%d", i)
-		result.NewEvents = append(result.NewEvents, diff.EventChange{
-			Username: username,
-			Event:    diff.Event{Type: "PushEvent", Repo: username + "/repo", CreatedAt: fixedTime()},
-		})
+	_, err := storageToSnapshot(ss)
+	if err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Errorf("expected schema version error, got %v", err)
 	}
+}
 
-	rpt := buildReport(result, result.OldCapturedAt, result.NewCapturedAt, fixedTime())
+// FuzzStorageToSnapshot feeds storageToSnapshot rows as they might be left
+// in a database by any version, or by corruption: it mustn't panic, and a
+// snapshot it loads must save and load back the same.
+func FuzzStorageToSnapshot(f *testing.F) {
+	f.Add(0, []byte(`{"gitstreams_data": {"CapturedAt": "2024-01-15T10:00:00Z", "Users": {"user1": {"Username": "user1"}}}}`))
+	f.Add(0, []byte(`{"gitstreams_data": null}`))
+	f.Add(0, []byte(`{}`))
+	f.Add(snapshotSchemaVersion, []byte(`{"CapturedAt": "2024-01-15T10:00:00Z", "Users": {"alice": {"Username": "alice", "StarredRepos": [{"Owner": "golang", "Name": "go"}]}}, "Incomplete": {"bob": ["events"]}}`))
+	f.Add(snapshotSchemaVersion, []byte(`{"Users": {"": null}}`))
+	f.Add(snapshotSchemaVersion, []byte(`null`))
+	f.Add(snapshotSchemaVersion+1, []byte(`{}`))
+
+	f.Fuzz(func(t *testing.T, schemaVersion int, data []byte) {
+		ss := &storage.Snapshot{ID: 1, Timestamp: fixedTime(), SchemaVersion: schemaVersion, Data: data}
+		if schemaVersion == 0 {
+			// Legacy rows keep the snapshot in their Activity map
+			if json.Unmarshal(data, &ss.Activity) != nil {
+				t.Skip("legacy activity isn't a JSON object")
+			}
+		}
 
-	if len(rpt.UserActivities) != 30 {
-		t.Errorf("expected 30 users with activities, got %d", len(rpt.UserActivities))
+		snapshot, err := storageToSnapshot(ss)
+		if err != nil {
+			return
+		}
+		saved, err := snapshotToStorage(snapshot)
+		if err != nil {
+			t.Fatalf("a loaded snapshot can't be saved: %v", err)
+		}
+		reloaded, err := storageToSnapshot(saved)
+		if err != nil {
+			t.Fatalf("a saved snapshot can't be loaded: %v", err)
+		}
+		resaved, err := snapshotToStorage(reloaded)
+		if err != nil {
+			t.Fatalf("a reloaded snapshot can't be saved: %v", err)
+		}
+		if !bytes.Equal(resaved.Data, saved.Data) {
+			t.Errorf("snapshot changed between saves:\n first %s\nsecond %s", saved.Data, resaved.Data)
+		}
+		if saved.Partial != snapshot.Partial() {
+			t.Errorf("saved Partial = %v, snapshot's = %v", saved.Partial, snapshot.Partial())
+		}
+	})
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		want string
+		n    int
+	}{
+		{n: 0, want: "0 B"},
+		{n: 1023, want: "1023 B"},
+		{n: 1024, want: "1.0 KiB"},
+		{n: 1536, want: "1.5 KiB"},
+		{n: 5 << 20, want: "5.0 MiB"},
 	}
 
-	if rpt.TotalActivities() != 30 {
-		t.Errorf("expected 30 total activities, got %d", rpt.TotalActivities())
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
 	}
 }
 
@@ -763,7 +1582,7 @@ func TestDiffCompare_FirstRun_AllUsersNewWithActivity(t *testing.T) {
 	}
 
 	// Build report should have 30 users
-	rpt := buildReport(result, previousSnapshot.CapturedAt, currentSnapshot.CapturedAt, fixedTime())
+	rpt := report.FromDiff(result, report.Options{GeneratedAt: fixedTime(), PeriodStart: previousSnapshot.CapturedAt, PeriodEnd: currentSnapshot.CapturedAt})
 	if len(rpt.UserActivities) != 30 {
 		t.Errorf("expected 30 users in report, got %d", len(rpt.UserActivities))
 	}
@@ -806,12 +1625,12 @@ func TestDiffCompare_FirstRun_UsersWithNoActivity(t *testing.T) {
 
 	// Build report - should only show 1 user (the one with activity)
 	// This is actually EXPECTED behavior - users without activity don't appear
-	rpt := buildReport(result, previousSnapshot.CapturedAt, currentSnapshot.CapturedAt, fixedTime())
+	rpt := report.FromDiff(result, report.Options{GeneratedAt: fixedTime(), PeriodStart: previousSnapshot.CapturedAt, PeriodEnd: currentSnapshot.CapturedAt})
 	t.Logf("NewUsers=%d, NewEvents=%d, UserActivities=%d",
 		len(result.NewUsers), len(result.NewEvents), len(rpt.UserActivities))
 
 	// The "bug" is that we have 30 NewUsers but only 1 UserActivity
-	// If we want ALL users to appear, we need to change buildReport
+	// If we want ALL users to appear, we need to change report.FromDiff
 	if len(rpt.UserActivities) != 1 {
 		t.Errorf("expected 1 user with activity (current behavior), got %d", len(rpt.UserActivities))
 	}
@@ -832,7 +1651,7 @@ func TestFetchActivity_VerboseOutput(t *testing.T) {
 	ctx := context.Background()
 	now := fixedTime()
 	cutoff := now.AddDate(0, 0, -30) // 30 days ago
-	_, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, true)
+	_, _, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, true)
 	if err != nil {
 		t.Fatalf("fetchActivity failed: %v", err)
 	}
@@ -860,7 +1679,7 @@ func TestFetchActivity_HandlesPartialErrors(t *testing.T) {
 	ctx := context.Background()
 	now := fixedTime()
 	cutoff := now.AddDate(0, 0, -30) // 30 days ago
-	snapshot, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, true)
+	snapshot, warnings, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, true)
 	if err != nil {
 		t.Fatalf("fetchActivity should not fail on partial errors: %v", err)
 	}
@@ -870,17 +1689,53 @@ func TestFetchActivity_HandlesPartialErrors(t *testing.T) {
 		t.Error("user1 should be in snapshot despite starred repos error")
 	}
 
-	if !strings.Contains(stdout.String(), "Warning") {
-		t.Errorf("expected warning in output, got: %s", stdout.String())
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %+v", warnings)
+	}
+	fw := warnings[0]
+	if fw.User != "user1" || fw.Endpoint != source.EndpointStarred || !fw.Partial {
+		t.Errorf("expected a partial starred warning for user1, got %+v", fw)
+	}
+	if want := "could not fetch starred repos for user1: rate limited"; fw.Error() != want {
+		t.Errorf("Error() = %q, want %q", fw.Error(), want)
+	}
+	if strings.Contains(stdout.String(), "Warning") {
+		t.Errorf("expected warnings to be returned rather than written, got: %s", stdout.String())
+	}
+}
+
+func TestFetchActivity_WarningNotPartialWhenEverythingFails(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	failing := map[string]error{"user1": errors.New("boom")}
+	mockClient := &mockGitHubClient{
+		followedUsers: []github.User{{Login: "user1"}, {Login: "user2"}},
+		starredErr:    failing,
+		ownedErr:      failing,
+		eventsErr:     failing,
+	}
+
+	now := fixedTime()
+	_, warnings, err := fetchActivity(context.Background(), mockClient, now, now.AddDate(0, 0, -30), &stdout, &stderr, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 3 {
+		t.Fatalf("expected a warning per endpoint, got %+v", warnings)
+	}
+	for _, fw := range warnings {
+		if fw.User != "user1" || fw.Partial {
+			t.Errorf("expected a non-partial warning for user1, got %+v", fw)
+		}
 	}
 }
 
-func TestLoadPreviousSnapshot_Empty(t *testing.T) {
+func TestLoadLatestSnapshot_Empty(t *testing.T) {
 	store := &mockStore{snapshots: []*storage.Snapshot{}}
 
-	snapshot, err := loadPreviousSnapshot(store)
+	snapshot, err := loadLatestSnapshot(store, runSnapshotID(&Config{}))
 	if err != nil {
-		t.Fatalf("loadPreviousSnapshot failed: %v", err)
+		t.Fatalf("loadLatestSnapshot failed: %v", err)
 	}
 
 	if len(snapshot.Users) != 0 {
@@ -905,7 +1760,7 @@ func TestFetchActivity_ProgressOutput(t *testing.T) {
 	ctx := context.Background()
 	now := fixedTime()
 	cutoff := now.AddDate(0, 0, -30) // 30 days ago
-	_, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, false)
+	_, _, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, false)
 	if err != nil {
 		t.Fatalf("fetchActivity failed: %v", err)
 	}
@@ -976,7 +1831,7 @@ func TestFetchActivity_FiltersOldData(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	snapshot, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, false)
+	snapshot, _, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, false)
 	if err != nil {
 		t.Fatalf("fetchActivity failed: %v", err)
 	}
@@ -1036,7 +1891,7 @@ func TestFetchActivity_FiltersBoundaryDates(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	snapshot, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, false)
+	snapshot, _, err := fetchActivity(ctx, mockClient, now, cutoff, &stdout, &stderr, false)
 	if err != nil {
 		t.Fatalf("fetchActivity failed: %v", err)
 	}
@@ -1065,7 +1920,7 @@ func TestRun_DaysFlag(t *testing.T) {
 		GitHubClientFactory: func(token string) GitHubClient { return mockClient },
 		StoreFactory:        func(dbPath string) (Store, error) { return mockStoreInst, nil },
 		NotifierFactory:     func() Notifier { return &mockNotifier{} },
-		ReportGenerator:     func() (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
 		OpenBrowser:         func(url string) error { return nil },
 		Now:                 fixedTime,
 	}
@@ -1118,6 +1973,109 @@ func TestRun_DaysFlagInvalid(t *testing.T) {
 	}
 }
 
+func TestRun_ConcurrencyFlagInvalid(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	result := run(&stdout, &stderr, []string{
+		"-token", "test-token",
+		"-concurrency", "0",
+	}, DefaultDependencies())
+
+	if result != 1 {
+		t.Errorf("expected exit code 1 for invalid concurrency, got %d", result)
+	}
+	if !strings.Contains(stderr.String(), "concurrency must be at least 1") {
+		t.Errorf("expected error about concurrency, got: %s", stderr.String())
+	}
+}
+
+func TestRun_APIFlagInvalid(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	result := run(&stdout, &stderr, []string{
+		"-token", "test-token",
+		"-api", "soap",
+	}, DefaultDependencies())
+
+	if result != 1 {
+		t.Errorf("expected exit code 1 for an unknown API, got %d", result)
+	}
+	if !strings.Contains(stderr.String(), "api must be one of rest, graphql") {
+		t.Errorf("expected error about api, got: %s", stderr.String())
+	}
+}
+
+func TestNewGitHubClient(t *testing.T) {
+	deps := DefaultDependencies()
+	tests := []struct {
+		name        string
+		api         string
+		token       string
+		wantGraphQL bool
+	}{
+		{name: "rest", api: apiREST, token: "token"},
+		{name: "graphql", api: apiGraphQL, token: "token", wantGraphQL: true},
+		{name: "graphql without a token falls back to rest", api: apiGraphQL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newGitHubClient(&Config{API: tt.api}, deps, tt.token)
+			if _, ok := client.(*github.GraphQLClient); ok != tt.wantGraphQL {
+				t.Errorf("newGitHubClient() = %T, want GraphQL %v", client, tt.wantGraphQL)
+			}
+		})
+	}
+
+	// Test doubles are used as they are
+	mock := &mockGitHubClient{}
+	if got := newGitHubClient(&Config{API: apiGraphQL}, &Dependencies{
+		GitHubClientFactory: func(string) GitHubClient { return mock },
+	}, "token"); got != mock {
+		t.Errorf("newGitHubClient() = %T, want the factory's client", got)
+	}
+}
+
+// prefetchingMock is a mockGitHubClient that records what it's asked to
+// prefetch.
+type prefetchingMock struct {
+	*mockGitHubClient
+	prefetchErr error
+	prefetched  []string
+}
+
+func (m *prefetchingMock) Prefetch(ctx context.Context, logins []string) error {
+	m.prefetched = append(m.prefetched, logins...)
+	return m.prefetchErr
+}
+
+func TestFetchActivityWithPlan_Prefetches(t *testing.T) {
+	for _, prefetchErr := range []error{nil, errors.New("batch failed")} {
+		client := &prefetchingMock{
+			mockGitHubClient: &mockGitHubClient{
+				followedUsers: []github.User{{Login: "alice"}, {Login: "bob"}},
+				events: map[string][]github.Event{
+					"bob": {{Type: "PushEvent", Actor: github.User{Login: "bob"}, Repo: github.EventRepo{Name: "bob/repo"}, CreatedAt: fixedTime()}},
+				},
+			},
+			prefetchErr: prefetchErr,
+		}
+		var out bytes.Buffer
+		snapshot, warnings, err := fetchActivityWithPlan(context.Background(), client, defaultFetchPlan(), fixedTime(), fixedTime().AddDate(0, 0, -30), &out, io.Discard, true)
+		if err != nil || len(warnings) > 0 {
+			t.Fatalf("fetchActivityWithPlan() error = %v, warnings = %v", err, warnings)
+		}
+		if !slices.Equal(client.prefetched, []string{"alice", "bob"}) {
+			t.Errorf("prefetched %v, want alice and bob", client.prefetched)
+		}
+		if len(snapshot.Users["bob"].Events) != 1 {
+			t.Errorf("expected bob's event, got %+v", snapshot.Users["bob"])
+		}
+		if noted := strings.Contains(out.String(), "Couldn't prefetch"); noted != (prefetchErr != nil) {
+			t.Errorf("prefetch error %v: verbose output %q", prefetchErr, out.String())
+		}
+	}
+}
+
 func TestRun_NotificationError_DoesNotFail(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	tmpDir := t.TempDir()
@@ -1137,7 +2095,7 @@ func TestRun_NotificationError_DoesNotFail(t *testing.T) {
 		GitHubClientFactory: func(token string) GitHubClient { return mockClient },
 		StoreFactory:        func(dbPath string) (Store, error) { return mockStoreInst, nil },
 		NotifierFactory:     func() Notifier { return mockNotifierInst },
-		ReportGenerator:     func() (ReportGenerator, error) { return mockGenInst, nil },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return mockGenInst, nil },
 		OpenBrowser:         func(url string) error { return nil },
 		Now:                 fixedTime,
 	}
@@ -1198,6 +2156,16 @@ func TestParseSinceDate(t *testing.T) {
 			expected: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC),
 			wantErr:  false,
 		},
+		{
+			name:    "trailing junk in relative date",
+			input:   "5xd",
+			wantErr: true,
+		},
+		{
+			name:    "relative date too far back",
+			input:   "9223372036854775807w",
+			wantErr: true,
+		},
 		{
 			name:    "invalid format",
 			input:   "invalid",
@@ -1230,7 +2198,38 @@ func TestParseSinceDate(t *testing.T) {
 	}
 }
 
+// FuzzParseSinceDate checks -since never panics, and never takes a
+// relative date to mean one in the future.
+func FuzzParseSinceDate(f *testing.F) {
+	for _, seed := range []string{"7d", "2w", "1m", "1y", "0d", "-3d", "+5d", "5xd", "9223372036854775807w", "2026-01-15", "2026-01-15T10:30:00Z", "2026/01/15", "01/15/2026", ""} {
+		f.Add(seed)
+	}
+
+	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	f.Fuzz(func(t *testing.T, input string) {
+		since, err := parseSinceDate(input, now)
+		if err != nil {
+			return
+		}
+		if since.After(now) && !isAbsoluteDate(input) {
+			t.Errorf("parseSinceDate(%q) = %v, after now (%v)", input, since, now)
+		}
+	})
+}
+
+// isAbsoluteDate reports whether s is in one of parseSinceDate's absolute
+// formats, which can name any date.
+func isAbsoluteDate(s string) bool {
+	for _, layout := range []string{time.RFC3339, "2006-01-02", "2006-01-02T15:04:05", "2006/01/02", "01/02/2006"} {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func TestRun_HistoricalMode(t *testing.T) {
+	setHome(t)
 	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
 	sevenDaysAgo := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
 
@@ -1277,7 +2276,7 @@ func TestRun_HistoricalMode(t *testing.T) {
 		NotifierFactory: func() Notifier {
 			return &mockNotifier{}
 		},
-		ReportGenerator: func() (ReportGenerator, error) {
+		ReportGenerator: func(string) (ReportGenerator, error) {
 			return &mockReportGenerator{}, nil
 		},
 		OpenBrowser: func(url string) error { return nil },
@@ -1301,6 +2300,7 @@ func TestRun_HistoricalMode(t *testing.T) {
 }
 
 func TestRun_OfflineMode(t *testing.T) {
+	setHome(t)
 	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
 	sevenDaysAgo := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
 
@@ -1350,7 +2350,7 @@ func TestRun_OfflineMode(t *testing.T) {
 		NotifierFactory: func() Notifier {
 			return &mockNotifier{}
 		},
-		ReportGenerator: func() (ReportGenerator, error) {
+		ReportGenerator: func(string) (ReportGenerator, error) {
 			return &mockReportGenerator{}, nil
 		},
 		OpenBrowser: func(url string) error { return nil },
@@ -1398,7 +2398,7 @@ func TestRun_BrowserError_DoesNotFail(t *testing.T) {
 		GitHubClientFactory: func(token string) GitHubClient { return mockClient },
 		StoreFactory:        func(dbPath string) (Store, error) { return mockStoreInst, nil },
 		NotifierFactory:     func() Notifier { return &mockNotifier{} },
-		ReportGenerator:     func() (ReportGenerator, error) { return mockGenInst, nil },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return mockGenInst, nil },
 		OpenBrowser:         func(url string) error { return errors.New("browser failed") },
 		Now:                 fixedTime,
 	}
@@ -1447,7 +2447,7 @@ func TestRun_OfflineMode_WithCachedData(t *testing.T) {
 		},
 		StoreFactory:    func(dbPath string) (Store, error) { return mockStoreInst, nil },
 		NotifierFactory: func() Notifier { return &mockNotifier{} },
-		ReportGenerator: func() (ReportGenerator, error) { return mockGenInst, nil },
+		ReportGenerator: func(string) (ReportGenerator, error) { return mockGenInst, nil },
 		OpenBrowser:     func(url string) error { return nil },
 		Now:             fixedTime,
 	}
@@ -1480,7 +2480,11 @@ func TestRun_OfflineMode_WithCachedData(t *testing.T) {
 
 	// Verify report was generated
 	if mockGenInst.generatedReport == nil {
-		t.Error("expected report to be generated")
+		t.Fatal("expected report to be generated")
+	}
+	prov := mockGenInst.generatedReport.Provenance
+	if prov == nil || prov.Mode != report.ModeCached || !prov.To.Equal(cachedSnapshot.CapturedAt) || prov.LookbackDays != 0 {
+		t.Errorf("expected cached provenance for the snapshot, got %+v", prov)
 	}
 }
 
@@ -1499,7 +2503,7 @@ func TestRun_OfflineMode_NoCachedData(t *testing.T) {
 		},
 		StoreFactory:    func(dbPath string) (Store, error) { return mockStoreInst, nil },
 		NotifierFactory: func() Notifier { return &mockNotifier{} },
-		ReportGenerator: func() (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		ReportGenerator: func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
 		OpenBrowser:     func(url string) error { return nil },
 		Now:             fixedTime,
 	}
@@ -1514,11 +2518,11 @@ func TestRun_OfflineMode_NoCachedData(t *testing.T) {
 	}
 
 	// Check that we got an error about missing cached data
-	if !strings.Contains(stderr.String(), "No cached data available") {
+	if !strings.Contains(stderr.String(), "no cached data available") {
 		t.Errorf("expected error about no cached data, got: %s", stderr.String())
 	}
 
-	if !strings.Contains(stderr.String(), "Run without --offline first") {
+	if !strings.Contains(stderr.String(), "run without --offline first") {
 		t.Errorf("expected suggestion to run without --offline, got: %s", stderr.String())
 	}
 }
@@ -1550,7 +2554,7 @@ func TestRun_OfflineMode_NoTokenRequired(t *testing.T) {
 		},
 		StoreFactory:    func(dbPath string) (Store, error) { return mockStoreInst, nil },
 		NotifierFactory: func() Notifier { return &mockNotifier{} },
-		ReportGenerator: func() (ReportGenerator, error) { return mockGenInst, nil },
+		ReportGenerator: func(string) (ReportGenerator, error) { return mockGenInst, nil },
 		OpenBrowser:     func(url string) error { return nil },
 		Now:             fixedTime,
 	}
@@ -1574,167 +2578,407 @@ func TestRun_OfflineMode_NoTokenRequired(t *testing.T) {
 	}
 }
 
-func TestFilterResultBySinceDate(t *testing.T) {
-	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
-	sinceDate := time.Date(2026, 1, 21, 0, 0, 0, 0, time.UTC)
-	oldDate := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC) // 3 weeks ago
-
-	result := &diff.Result{
-		OldCapturedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
-		NewCapturedAt: now,
-		NewStars: []diff.RepoChange{
-			{
-				Username: "simonw",
-				Repo: diff.Repo{
-					Owner:     "simonw",
-					Name:      "old-repo",
-					CreatedAt: oldDate, // Should be filtered out
-				},
-			},
-			{
-				Username: "octocat",
-				Repo: diff.Repo{
-					Owner:     "octocat",
-					Name:      "new-repo",
-					CreatedAt: sinceDate.Add(time.Hour), // Should be included
-				},
-			},
+func TestRun_OfflineMode_FormatsAndTemplates(t *testing.T) {
+	tmpDir := t.TempDir()
+	cachedSnapshot := diff.NewSnapshot(fixedTime().Add(-24 * time.Hour))
+	cachedSnapshot.Users["testuser"] = diff.UserActivity{
+		Username:     "testuser",
+		StarredRepos: []diff.Repo{{Owner: "owner1", Name: "cached-repo"}},
+	}
+	ss, _ := snapshotToStorage(cachedSnapshot)
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			t.Error("GitHubClient should not be created in offline mode")
+			return nil
 		},
-		NewRepos: []diff.RepoChange{
-			{
-				Username: "user1",
-				Repo: diff.Repo{
-					Owner:     "user1",
-					Name:      "ancient-repo",
-					CreatedAt: oldDate, // Should be filtered out
-				},
-			},
-			{
-				Username: "user2",
-				Repo: diff.Repo{
-					Owner:     "user2",
-					Name:      "recent-repo",
-					CreatedAt: now, // Should be included
-				},
+		StoreFactory:    func(dbPath string) (Store, error) { return &mockStore{snapshots: []*storage.Snapshot{ss}}, nil },
+		NotifierFactory: func() Notifier { return &mockNotifier{} },
+		ReportGenerator: func(format string) (ReportGenerator, error) { return report.New(format) },
+		OpenBrowser:     func(url string) error { return nil },
+		Now:             fixedTime,
+	}
+	templatePath := filepath.Join(tmpDir, "report.tmpl")
+	if err := os.WriteFile(templatePath, []byte(`{{range .UserActivities}}{{.User}} {{len .Activities}}{{end}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	runOffline := func(args ...string) (string, int, string) {
+		t.Setenv("GITHUB_TOKEN", "")
+		var stdout, stderr bytes.Buffer
+		reportPath := filepath.Join(t.TempDir(), "report")
+		code := run(&stdout, &stderr, append([]string{
+			"-offline", "-no-notify", "-no-open",
+			"-db", filepath.Join(tmpDir, "test.db"),
+			"-report", reportPath,
+		}, args...), deps)
+		data, _ := os.ReadFile(reportPath)
+		return string(data), code, stderr.String()
+	}
+
+	out, code, stderr := runOffline("-template", templatePath)
+	if code != 0 || out != "testuser 1" {
+		t.Errorf("expected the custom template's output, got %d %q: %s", code, out, stderr)
+	}
+
+	out, code, stderr = runOffline("-format", report.FormatJSON)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr)
+	}
+	rpt, err := report.ReadJSON(strings.NewReader(out))
+	if err != nil || rpt.TotalActivities() != 1 {
+		t.Errorf("expected a JSON report with the cached star, got %+v, %v", rpt, err)
+	}
+
+	_, code, stderr = runOffline("-format", report.FormatJSON, "-template", templatePath)
+	if code != 1 || !strings.Contains(stderr, "-template needs a report format with templates") {
+		t.Errorf("expected -template to be refused for json, got %d: %s", code, stderr)
+	}
+
+	_, code, stderr = runOffline("-template", filepath.Join(tmpDir, "missing.tmpl"))
+	if code != 1 || !strings.Contains(stderr, "template") {
+		t.Errorf("expected an error for a missing template, got %d: %s", code, stderr)
+	}
+}
+
+func TestRun_MemoryStorePersistsBetweenRuns(t *testing.T) {
+	setHome(t)
+	dumpPath := filepath.Join(t.TempDir(), "db.json")
+
+	runWith := func(repos ...string) *mockReportGenerator {
+		t.Helper()
+		var starred []github.Repository
+		for _, repo := range repos {
+			starred = append(starred, github.Repository{Name: repo, Owner: github.User{Login: "owner1"}, CreatedAt: fixedTime()})
+		}
+		gen := &mockReportGenerator{}
+		deps := &Dependencies{
+			GitHubClientFactory: func(token string) GitHubClient {
+				return &mockGitHubClient{
+					followedUsers: []github.User{{Login: "testuser", ID: 1}},
+					starredRepos:  map[string][]github.Repository{"testuser": starred},
+				}
 			},
+			StoreFactory:    func(path string) (Store, error) { return storage.Open(path) },
+			NotifierFactory: func() Notifier { return &mockNotifier{} },
+			ReportGenerator: func(string) (ReportGenerator, error) { return gen, nil },
+			OpenBrowser:     func(url string) error { return nil },
+			Now:             fixedTime,
+		}
+		var stdout, stderr bytes.Buffer
+		code := run(&stdout, &stderr, []string{
+			"-token", "test-token",
+			"-db", "memory://" + dumpPath,
+			"-report", filepath.Join(t.TempDir(), "report.html"),
+			"-no-notify",
+		}, deps)
+		if code != 0 {
+			t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+		}
+		return gen
+	}
+
+	runWith("first")
+	if _, err := os.Stat(dumpPath); err != nil {
+		t.Fatalf("expected the store to be dumped on exit: %v", err)
+	}
+	gen := runWith("first", "second")
+	if gen.generatedReport == nil {
+		t.Fatal("expected a report")
+	}
+	if got := gen.generatedReport.TotalActivities(); got != 1 {
+		t.Errorf("expected only the new star, got %d activities", got)
+	}
+}
+
+func TestRun_TimeoutStopsFetch(t *testing.T) {
+	setHome(t)
+	gen := &mockReportGenerator{}
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			return &mockGitHubClient{followedUsers: []github.User{{Login: "testuser", ID: 1}}}
 		},
-		NewEvents: []diff.EventChange{
-			{
-				Username: "simonw",
-				Event: diff.Event{
-					Type:      "PushEvent",
-					Actor:     "simonw",
-					Repo:      "simonw/old-project",
-					CreatedAt: oldDate, // Should be filtered out
-				},
-			},
-			{
-				Username: "octocat",
-				Event: diff.Event{
-					Type:      "PushEvent",
-					Actor:     "octocat",
-					Repo:      "octocat/fresh-project",
-					CreatedAt: sinceDate, // Exactly on since date - should be included
+		StoreFactory:    func(path string) (Store, error) { return storage.Open(path) },
+		NotifierFactory: func() Notifier { return &mockNotifier{} },
+		ReportGenerator: func(string) (ReportGenerator, error) { return gen, nil },
+		OpenBrowser:     func(url string) error { return nil },
+		Now:             fixedTime,
+	}
+	var stdout, stderr bytes.Buffer
+	code := run(&stdout, &stderr, []string{
+		"-token", "test-token",
+		"-db", filepath.Join(t.TempDir(), "test.db"),
+		"-no-notify",
+		"-timeout", "1ns",
+	}, deps)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "gave up after -timeout 1ns") {
+		t.Errorf("expected the timeout to be blamed, got: %s", stderr.String())
+	}
+	if gen.generatedReport != nil {
+		t.Error("expected no report after timing out")
+	}
+}
+
+func TestRun_ReportFormat(t *testing.T) {
+	setHome(t)
+	var gotFormat string
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			return &mockGitHubClient{
+				followedUsers: []github.User{{Login: "testuser", ID: 1}},
+				starredRepos: map[string][]github.Repository{
+					"testuser": {{Name: "repo", Owner: github.User{Login: "owner"}, CreatedAt: fixedTime()}},
 				},
-			},
+			}
+		},
+		StoreFactory:    func(path string) (Store, error) { return storage.Open(path) },
+		NotifierFactory: func() Notifier { return &mockNotifier{} },
+		ReportGenerator: func(format string) (ReportGenerator, error) {
+			gotFormat = format
+			return &mockReportGenerator{}, nil
 		},
-		NewUsers:  []string{"newuser1", "newuser2"},
-		GoneUsers: []string{"goneuser1"},
+		OpenBrowser: func(url string) error { return nil },
+		Now:         fixedTime,
+	}
+	var stdout, stderr bytes.Buffer
+	code := run(&stdout, &stderr, []string{
+		"-token", "test-token",
+		"-db", filepath.Join(t.TempDir(), "test.db"),
+		"-report", filepath.Join(t.TempDir(), "report.html"),
+		"-no-notify",
+		"-format", report.FormatHTML,
+	}, deps)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if gotFormat != report.FormatHTML {
+		t.Errorf("expected the generator for html, got %q", gotFormat)
+	}
+
+	code = run(&stdout, &stderr, []string{"-token", "test-token", "-format", "nope"}, deps)
+	if code != 1 || !strings.Contains(stderr.String(), "unknown report format") {
+		t.Errorf("expected an unknown format error, got %d: %s", code, stderr.String())
 	}
+}
 
-	filtered := filterResultBySinceDate(result, sinceDate)
+func TestFetchSubjectsActivity_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
 
-	// Check that timestamps are preserved
-	if !filtered.OldCapturedAt.Equal(result.OldCapturedAt) {
-		t.Errorf("OldCapturedAt mismatch: got %v, want %v", filtered.OldCapturedAt, result.OldCapturedAt)
+	var out bytes.Buffer
+	now := fixedTime()
+	subjects := []source.Subject{{Name: "user1"}}
+	snapshot, _, err := fetchSubjectsActivity(ctx, githubSource(&mockGitHubClient{}, defaultFetchPlan()), subjects, 1, now, now, &out, &out, nil, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
 	}
-	if !filtered.NewCapturedAt.Equal(result.NewCapturedAt) {
-		t.Errorf("NewCapturedAt mismatch: got %v, want %v", filtered.NewCapturedAt, result.NewCapturedAt)
+	if snapshot != nil {
+		t.Error("expected no snapshot from a cancelled fetch")
 	}
+}
 
-	// Check that user lists are preserved
-	if len(filtered.NewUsers) != 2 || filtered.NewUsers[0] != "newuser1" {
-		t.Errorf("NewUsers not preserved: got %v, want %v", filtered.NewUsers, result.NewUsers)
-	}
-	if len(filtered.GoneUsers) != 1 || filtered.GoneUsers[0] != "goneuser1" {
-		t.Errorf("GoneUsers not preserved: got %v, want %v", filtered.GoneUsers, result.GoneUsers)
+// slowSource fetches each subject after a pause, failing the events of
+// those in fail, and records how many fetches overlapped.
+type slowSource struct {
+	fail     map[string]bool
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (s *slowSource) ListSubjects(ctx context.Context) ([]source.Subject, error) {
+	return nil, nil
+}
+
+func (s *slowSource) FetchActivity(ctx context.Context, subject source.Subject, cutoff time.Time) (diff.UserActivity, []source.Failure) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
 	}
+	time.Sleep(10 * time.Millisecond)
 
-	// Check that old stars are filtered out
-	if len(filtered.NewStars) != 1 {
-		t.Fatalf("expected 1 new star, got %d", len(filtered.NewStars))
+	activity := diff.UserActivity{Username: subject.Name}
+	if s.fail[subject.Name] {
+		return activity, []source.Failure{{Endpoint: source.EndpointEvents, Err: errors.New("boom"), Partial: true}}
 	}
-	if filtered.NewStars[0].Username != "octocat" {
-		t.Errorf("wrong star kept: got %s, want octocat", filtered.NewStars[0].Username)
+	return activity, nil
+}
+
+func TestFetchSubjectsActivity_Concurrently(t *testing.T) {
+	var subjects []source.Subject
+	for i := range 10 {
+		subjects = append(subjects, source.Subject{Name: fmt.Sprintf("user%d", i)})
 	}
+	src := &slowSource{fail: map[string]bool{"user7": true, "user2": true}}
 
-	// Check that old repos are filtered out
-	if len(filtered.NewRepos) != 1 {
-		t.Fatalf("expected 1 new repo, got %d", len(filtered.NewRepos))
+	var out bytes.Buffer
+	now := fixedTime()
+	snapshot, warnings, err := fetchSubjectsActivity(context.Background(), src, subjects, 3, now, now, &out, io.Discard, nil, true)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if filtered.NewRepos[0].Username != "user2" {
-		t.Errorf("wrong repo kept: got %s, want user2", filtered.NewRepos[0].Username)
+	if len(snapshot.Users) != len(subjects) {
+		t.Errorf("expected every user, got %d", len(snapshot.Users))
 	}
-
-	// Check that old events are filtered out
-	if len(filtered.NewEvents) != 1 {
-		t.Fatalf("expected 1 new event, got %d", len(filtered.NewEvents))
+	if peak := src.peak.Load(); peak < 2 || peak > 3 {
+		t.Errorf("expected up to 3 fetches at once, peaked at %d", peak)
+	}
+	// Warnings follow the subjects' order, whichever fetch finished first
+	if len(warnings) != 2 || warnings[0].User != "user2" || warnings[1].User != "user7" {
+		t.Errorf("expected warnings for user2 then user7, got %v", warnings)
 	}
-	if filtered.NewEvents[0].Username != "octocat" {
-		t.Errorf("wrong event kept: got %s, want octocat", filtered.NewEvents[0].Username)
+	if got := strings.Count(out.String(), "Fetching activity for user"); got != len(subjects) {
+		t.Errorf("expected a line per user, got %d:\n%s", got, out.String())
 	}
 }
 
-func TestFilterResultBySinceDate_BoundaryConditions(t *testing.T) {
-	sinceDate := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+func TestNewerWriter(t *testing.T) {
+	oldVersion := version
+	version = "v1.2.0"
+	t.Cleanup(func() { version = oldVersion })
 
 	tests := []struct {
-		createdAt     time.Time
-		name          string
-		shouldInclude bool
+		written string
+		want    string
 	}{
-		{
-			name:          "before since date",
-			createdAt:     sinceDate.Add(-24 * time.Hour),
-			shouldInclude: false,
-		},
-		{
-			name:          "exactly on since date",
-			createdAt:     sinceDate,
-			shouldInclude: true,
-		},
-		{
-			name:          "after since date",
-			createdAt:     sinceDate.Add(24 * time.Hour),
-			shouldInclude: true,
-		},
+		{"v1.3.0", "v1.3.0"},
+		{"v1.2.0", ""},
+		{"v1.1.9", ""},
+		{"", ""},
+		{"dev", ""},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := &diff.Result{
-				NewStars: []diff.RepoChange{
-					{
-						Username: "user",
-						Repo: diff.Repo{
-							Owner:     "user",
-							Name:      "repo",
-							CreatedAt: tt.createdAt,
-						},
+		store := &mockStore{snapshots: []*storage.Snapshot{{UserID: snapshotUserID, AppVersion: tt.written}}}
+		if got := newerWriter(store, snapshotUserID); got != tt.want {
+			t.Errorf("newerWriter(written by %q) = %q, want %q", tt.written, got, tt.want)
+		}
+	}
+	if got := newerWriter(&mockStore{}, snapshotUserID); got != "" {
+		t.Errorf("newerWriter(empty) = %q, want empty", got)
+	}
+}
+
+func TestApplyErrorPolicy(t *testing.T) {
+	fetched := diff.UserActivity{Username: "alice", StarredRepos: []diff.Repo{{Owner: "a", Name: "new"}}}
+	last := diff.UserActivity{Username: "alice", StarredRepos: []diff.Repo{{Owner: "a", Name: "old"}}}
+	warnings := []FetchWarning{{User: "alice", Endpoint: source.EndpointEvents, Err: errors.New("boom"), Partial: true}}
+	newSnapshot := func() *diff.Snapshot {
+		s := diff.NewSnapshot(fixedTime())
+		s.Users["alice"] = fetched
+		s.Users["bob"] = diff.UserActivity{Username: "bob"}
+		return s
+	}
+	previous := diff.NewSnapshot(fixedTime().Add(-time.Hour))
+	previous.Users["alice"] = last
+
+	t.Run("warn keeps what was fetched", func(t *testing.T) {
+		s := newSnapshot()
+		if err := applyErrorPolicy(onErrorWarn, s, previous, warnings); err != nil {
+			t.Fatal(err)
+		}
+		if s.Users["alice"].StarredRepos[0].Name != "new" {
+			t.Errorf("expected alice's fetched activity, got %+v", s.Users["alice"])
+		}
+		if got := s.Incomplete["alice"]; !slices.Equal(got, []string{source.EndpointEvents}) || len(s.Incomplete) != 1 {
+			t.Errorf("expected alice's events to be incomplete, got %v", s.Incomplete)
+		}
+	})
+
+	t.Run("skip-user keeps the last snapshot", func(t *testing.T) {
+		s := newSnapshot()
+		if err := applyErrorPolicy(onErrorSkipUser, s, previous, warnings); err != nil {
+			t.Fatal(err)
+		}
+		if s.Users["alice"].StarredRepos[0].Name != "old" {
+			t.Errorf("expected alice's last activity, got %+v", s.Users["alice"])
+		}
+		if _, ok := s.Users["bob"]; !ok {
+			t.Error("expected bob to be kept")
+		}
+		if s.Partial() {
+			t.Errorf("expected alice's last activity to be complete, got %v", s.Incomplete)
+		}
+
+		// Without a last snapshot, the user is left out
+		s = newSnapshot()
+		if err := applyErrorPolicy(onErrorSkipUser, s, nil, warnings); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := s.Users["alice"]; ok {
+			t.Error("expected alice to be left out")
+		}
+		if s.Incomplete["alice"] == nil {
+			t.Error("expected alice to be incomplete, so they aren't reported gone")
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		err := applyErrorPolicy(onErrorFail, newSnapshot(), previous, warnings)
+		if err == nil || err.Error() != "could not fetch events for alice: boom" {
+			t.Errorf("expected alice's failure, got %v", err)
+		}
+		err = applyErrorPolicy(onErrorFail, newSnapshot(), previous, append(warnings, FetchWarning{User: "bob", Endpoint: source.EndpointOwned, Err: errors.New("nope")}))
+		if err == nil || !strings.HasSuffix(err.Error(), "and 1 more") {
+			t.Errorf("expected a count of the rest, got %v", err)
+		}
+		if err := applyErrorPolicy(onErrorFail, newSnapshot(), previous, nil); err != nil {
+			t.Errorf("expected no error without failures, got %v", err)
+		}
+	})
+}
+
+func TestRun_OnError(t *testing.T) {
+	var store *mockStore
+	newDeps := func() *Dependencies {
+		store = &mockStore{}
+		return &Dependencies{
+			GitHubClientFactory: func(token string) GitHubClient {
+				return &mockGitHubClient{
+					followedUsers: []github.User{{Login: "alice", ID: 1}},
+					starredRepos: map[string][]github.Repository{
+						"alice": {{Name: "repo", Owner: github.User{Login: "owner"}, CreatedAt: fixedTime()}},
 					},
-				},
-			}
+					eventsErr: map[string]error{"alice": errors.New("boom")},
+				}
+			},
+			StoreFactory:    func(dbPath string) (Store, error) { return store, nil },
+			NotifierFactory: func() Notifier { return &mockNotifier{} },
+			ReportGenerator: func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+			OpenBrowser:     func(url string) error { return nil },
+			Now:             fixedTime,
+		}
+	}
+	runWith := func(policy string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(&stdout, &stderr, []string{
+			"-token", "test-token",
+			"-db", filepath.Join(t.TempDir(), "test.db"),
+			"-report", filepath.Join(t.TempDir(), "report.html"),
+			"-no-notify", "-no-open",
+			"-on-error", policy,
+		}, newDeps())
+		return code, stderr.String()
+	}
 
-			filtered := filterResultBySinceDate(result, sinceDate)
+	code, stderr := runWith(onErrorWarn)
+	if code != 0 || !strings.Contains(stderr, "Warning: could not fetch events for alice") {
+		t.Errorf("expected a warning and success, got %d: %s", code, stderr)
+	}
+	if store.savedSnapshot == nil || !store.savedSnapshot.Partial {
+		t.Errorf("expected the saved snapshot to be partial, got %+v", store.savedSnapshot)
+	}
 
-			expectedCount := 0
-			if tt.shouldInclude {
-				expectedCount = 1
-			}
+	code, stderr = runWith(onErrorFail)
+	if code != 1 || !strings.Contains(stderr, "could not fetch events for alice") {
+		t.Errorf("expected the run to fail, got %d: %s", code, stderr)
+	}
 
-			if len(filtered.NewStars) != expectedCount {
-				t.Errorf("expected %d stars, got %d", expectedCount, len(filtered.NewStars))
-			}
-		})
+	code, stderr = runWith("explode")
+	if code != 1 || !strings.Contains(stderr, "on-error must be one of warn, skip-user, fail") {
+		t.Errorf("expected a bad policy to be refused, got %d: %s", code, stderr)
 	}
 }
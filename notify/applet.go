@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// appletName is the notification applet's bundle. macOS shows notifications
+// as coming from it, so it's named for gitstreams.
+const appletName = "GitStreams.app"
+
+// appletScript is the applet's source. gitstreams leaves each notification
+// in a pending file beside the applet and launches it to show it. A click on
+// a notification launches the applet again with nothing pending, and it
+// opens the URL of the last notification it showed.
+const appletScript = `on run
+	set here to do shell script "dirname " & quoted form of POSIX path of (path to me)
+	set pending to here & "/pending"
+	set lastURL to here & "/last-url"
+	try
+		set fields to paragraphs of (read (pending as POSIX file) as «class utf8»)
+	on error
+		try
+			set u to read (lastURL as POSIX file) as «class utf8»
+			if u is not "" then open location u
+		end try
+		return
+	end try
+	do shell script "rm -f " & quoted form of pending
+	set {t, s, m, snd, u} to items 1 thru 5 of fields
+	do shell script "printf %s " & quoted form of u & " > " & quoted form of lastURL
+	if snd is "" then
+		display notification m with title t subtitle s
+	else
+		display notification m with title t subtitle s sound name snd
+	end if
+end run
+`
+
+// sendApplet shows n through the notification applet in m.AppletDir,
+// building the applet first if it's missing or out of date.
+func (m *MacNotifier) sendApplet(n Notification) error {
+	app := filepath.Join(m.AppletDir, appletName)
+	if err := m.buildApplet(app); err != nil {
+		return err
+	}
+
+	// One field per line, and a last line so trailing empty fields still
+	// count as lines
+	fields := []string{n.Title, n.Subtitle, n.Message, n.Sound, n.OpenURL, "end"}
+	for i, f := range fields {
+		fields[i] = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(f)
+	}
+	pending := filepath.Join(m.AppletDir, "pending")
+	if err := os.WriteFile(pending, []byte(strings.Join(fields, "\n")+"\n"), 0600); err != nil {
+		return fmt.Errorf("writing notification for the applet: %w", err)
+	}
+	// -W waits for the applet to show it, so the next notification can't
+	// overwrite it first; -g leaves the frontmost app alone
+	return m.Executor.Run("open", "-W", "-g", app)
+}
+
+// buildApplet compiles the applet at app from appletScript, unless it was
+// already built from this version of it. It runs as an agent, so it doesn't
+// flash up in the Dock.
+func (m *MacNotifier) buildApplet(app string) error {
+	src := filepath.Join(m.AppletDir, "notifier.applescript")
+	if built, err := os.ReadFile(src); err == nil && string(built) == appletScript { // #nosec G304 -- path is in the applet directory
+		if _, err := os.Stat(app); err == nil {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(m.AppletDir, 0750); err != nil {
+		return fmt.Errorf("creating applet directory: %w", err)
+	}
+	if err := os.RemoveAll(app); err != nil {
+		return fmt.Errorf("removing old applet: %w", err)
+	}
+	if err := os.WriteFile(src, []byte(appletScript), 0600); err != nil {
+		return fmt.Errorf("writing applet source: %w", err)
+	}
+	if err := m.Executor.Run("osacompile", "-o", app, src); err != nil {
+		return fmt.Errorf("building notification applet: %w", err)
+	}
+	plist := filepath.Join(app, "Contents", "Info.plist")
+	if err := m.Executor.Run("plutil", "-replace", "LSUIElement", "-bool", "YES", plist); err != nil {
+		return fmt.Errorf("building notification applet: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMacNotifier_Send_Applet(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, appletName)
+	mock := &mockExecutor{lookPathResults: map[string]error{"osacompile": nil, "terminal-notifier": nil}}
+	notifier := &MacNotifier{Executor: mock, AppletDir: dir}
+
+	n := Notification{Title: "GitStreams", Message: "3 new stars\nand more", Sound: "default", OpenURL: "file:///tmp/report.html"}
+	if err := notifier.Send(n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	var names []string
+	for _, c := range mock.runCalls {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "osacompile,plutil,open" {
+		t.Fatalf("expected the applet built then opened, got %v", mock.runCalls)
+	}
+	if args := mock.runCalls[0].Args; args[1] != app {
+		t.Errorf("osacompile args = %v, want the applet at %s", args, app)
+	}
+	if args := mock.runCalls[2].Args; strings.Join(args, " ") != "-W -g "+app {
+		t.Errorf("open args = %v", args)
+	}
+
+	pending, err := os.ReadFile(filepath.Join(dir, "pending"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "GitStreams\n\n3 new stars and more\ndefault\nfile:///tmp/report.html\nend\n"
+	if string(pending) != want {
+		t.Errorf("pending notification = %q, want %q", pending, want)
+	}
+
+	// Built once, as long as the script hasn't changed
+	if err := os.MkdirAll(app, 0750); err != nil {
+		t.Fatal(err)
+	}
+	mock.runCalls = nil
+	if err := notifier.Send(n); err != nil || len(mock.runCalls) != 1 || mock.runCalls[0].Name != "open" {
+		t.Errorf("expected the built applet reused, got %v, %v", err, mock.runCalls)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notifier.applescript"), []byte("-- older"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mock.runCalls = nil
+	if err := notifier.Send(n); err != nil || len(mock.runCalls) != 3 || mock.runCalls[0].Name != "osacompile" {
+		t.Errorf("expected an outdated applet rebuilt, got %v, %v", err, mock.runCalls)
+	}
+}
+
+// failingCommandExecutor fails to run one command.
+type failingCommandExecutor struct {
+	*mockExecutor
+	fail string
+}
+
+func (f failingCommandExecutor) Run(name string, args ...string) error {
+	if err := f.mockExecutor.Run(name, args...); err != nil || name == f.fail {
+		return errors.New(name + " failed")
+	}
+	return nil
+}
+
+func TestMacNotifier_Send_AppletFallsBack(t *testing.T) {
+	mock := &mockExecutor{lookPathResults: map[string]error{"osacompile": nil, "terminal-notifier": nil}}
+	notifier := &MacNotifier{Executor: failingCommandExecutor{mock, "osacompile"}, AppletDir: t.TempDir()}
+	if err := notifier.Send(Notification{Message: "Hello"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if last := mock.runCalls[len(mock.runCalls)-1]; last.Name != "terminal-notifier" {
+		t.Errorf("expected terminal-notifier when the applet can't be built, got %v", mock.runCalls)
+	}
+
+	// No osacompile, as off macOS
+	mock = &mockExecutor{lookPathResults: map[string]error{}}
+	notifier = &MacNotifier{Executor: mock, AppletDir: t.TempDir()}
+	if err := notifier.Send(Notification{Message: "Hello"}); err != nil || len(mock.runCalls) != 1 || mock.runCalls[0].Name != "osascript" {
+		t.Errorf("expected osascript without osacompile, got %v, %v", err, mock.runCalls)
+	}
+}
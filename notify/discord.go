@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Discord's limits on embeds, beyond which it rejects the message.
+const (
+	discordMaxFields      = 25
+	discordMaxDescription = 4096
+)
+
+// discordColor is the accent of gitstreams' embeds, GitHub's link blue.
+const discordColor = 0x0969da
+
+// DiscordNotifier posts notifications to a Discord webhook. A report
+// notification's summary becomes rich embeds: one with counts by category
+// and one featuring its highlight.
+type DiscordNotifier struct {
+	Client *http.Client
+	URL    string
+}
+
+// discordPayload is the message posted to Discord.
+type discordPayload struct {
+	Username string         `json:"username,omitempty"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Author      *discordAuthor `json:"author,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Color       int            `json:"color,omitempty"`
+}
+
+type discordAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// NewDiscordNotifier creates a DiscordNotifier that posts to the webhook
+// at url.
+func NewDiscordNotifier(url string) *DiscordNotifier {
+	return &DiscordNotifier{
+		Client: &http.Client{Timeout: defaultWebhookTimeout},
+		URL:    url,
+	}
+}
+
+// Send posts the notification. Any non-2xx response is an error.
+func (d *DiscordNotifier) Send(n Notification) error {
+	return d.SendContext(context.Background(), n)
+}
+
+// SendContext posts the notification, giving up when ctx is done.
+func (d *DiscordNotifier) SendContext(ctx context.Context, n Notification) error {
+	if n.Message == "" {
+		return fmt.Errorf("notification message cannot be empty")
+	}
+	return postJSON(ctx, d.Client, d.URL, "discord webhook", discordMessage(n))
+}
+
+// discordMessage formats n as a Discord message.
+func discordMessage(n Notification) discordPayload {
+	summary := discordEmbed{Title: n.Title, Description: n.Message, Color: discordColor}
+	if n.Subtitle != "" {
+		summary.Description += "\n*" + n.Subtitle + "*"
+	}
+	summary.Description = truncate(summary.Description, discordMaxDescription)
+	p := discordPayload{Username: n.Title, Embeds: []discordEmbed{summary}}
+	if n.Summary == nil {
+		return p
+	}
+
+	for _, c := range n.Summary.Counts[:min(len(n.Summary.Counts), discordMaxFields)] {
+		p.Embeds[0].Fields = append(p.Embeds[0].Fields, discordField{
+			Name:   c.Icon + " " + c.Name,
+			Value:  strconv.Itoa(c.Count),
+			Inline: true,
+		})
+	}
+	if f := n.Summary.Featured; f != nil {
+		featured := discordEmbed{
+			Title:       f.Text,
+			URL:         f.URL,
+			Description: truncate(f.Details, discordMaxDescription),
+			Color:       discordColor,
+		}
+		if f.User != "" {
+			featured.Author = &discordAuthor{Name: f.User, URL: f.UserURL, IconURL: f.AvatarURL}
+		}
+		if f.Reason != "" {
+			featured.Footer = &discordFooter{Text: f.Reason}
+		}
+		p.Embeds = append(p.Embeds, featured)
+	}
+	return p
+}
+
+// truncate shortens s to at most limit characters, marking the cut.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
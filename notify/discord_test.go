@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiscordNotifier_Send(t *testing.T) {
+	var got discordPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	err := NewDiscordNotifier(server.URL).Send(Notification{
+		Title:    "GitStreams",
+		Message:  "2 new stars and 1 new repos",
+		Subtitle: "alice was the most active",
+		Summary: &Summary{
+			Counts: []CategoryCount{{Name: "New Stars", Icon: "⭐", Count: 2}, {Name: "Repos Created", Icon: "🆕", Count: 1}},
+			Featured: &FeaturedActivity{
+				Text:      "alice created alice/new",
+				Reason:    "🚀 Fresh off the press!",
+				Details:   "A new tool",
+				URL:       "https://github.com/alice/new",
+				User:      "alice",
+				UserURL:   "https://github.com/alice",
+				AvatarURL: "https://github.com/alice.png",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got.Username != "GitStreams" || len(got.Embeds) != 2 {
+		t.Fatalf("expected a summary and a featured embed from GitStreams, got %+v", got)
+	}
+	summary := got.Embeds[0]
+	if summary.Title != "GitStreams" || summary.Description != "2 new stars and 1 new repos\n*alice was the most active*" {
+		t.Errorf("summary = %+v", summary)
+	}
+	wantFields := []discordField{{Name: "⭐ New Stars", Value: "2", Inline: true}, {Name: "🆕 Repos Created", Value: "1", Inline: true}}
+	if len(summary.Fields) != 2 || summary.Fields[0] != wantFields[0] || summary.Fields[1] != wantFields[1] {
+		t.Errorf("fields = %+v, want %+v", summary.Fields, wantFields)
+	}
+	featured := got.Embeds[1]
+	if featured.Title != "alice created alice/new" || featured.URL != "https://github.com/alice/new" || featured.Description != "A new tool" {
+		t.Errorf("featured = %+v", featured)
+	}
+	if featured.Author == nil || *featured.Author != (discordAuthor{Name: "alice", URL: "https://github.com/alice", IconURL: "https://github.com/alice.png"}) {
+		t.Errorf("author = %+v", featured.Author)
+	}
+	if featured.Footer == nil || featured.Footer.Text != "🚀 Fresh off the press!" {
+		t.Errorf("footer = %+v", featured.Footer)
+	}
+}
+
+func TestDiscordMessage_WithoutSummary(t *testing.T) {
+	p := discordMessage(Notification{Title: "GitStreams alert", Subtitle: "big-repos", Message: "alice starred golang/go"})
+	if len(p.Embeds) != 1 || p.Embeds[0].Fields != nil {
+		t.Fatalf("expected one plain embed, got %+v", p.Embeds)
+	}
+	if p.Embeds[0].Description != "alice starred golang/go\n*big-repos*" {
+		t.Errorf("description = %q", p.Embeds[0].Description)
+	}
+}
+
+func TestDiscordMessage_Limits(t *testing.T) {
+	counts := make([]CategoryCount, 30)
+	p := discordMessage(Notification{Message: strings.Repeat("é", 5000), Summary: &Summary{Counts: counts}})
+	if got := len(p.Embeds[0].Fields); got != discordMaxFields {
+		t.Errorf("expected fields capped at %d, got %d", discordMaxFields, got)
+	}
+	if got := []rune(p.Embeds[0].Description); len(got) != discordMaxDescription || got[len(got)-1] != '…' {
+		t.Errorf("expected the description cut to %d characters, got %d", discordMaxDescription, len(got))
+	}
+}
+
+func TestDiscordNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Invalid Webhook Token"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	err := NewDiscordNotifier(server.URL).Send(Notification{Message: "M"})
+	if err == nil || !strings.Contains(err.Error(), "discord webhook returned 401") {
+		t.Errorf("expected 401 error, got %v", err)
+	}
+}
+
+func TestDiscordNotifier_EmptyMessage(t *testing.T) {
+	if err := NewDiscordNotifier("http://127.0.0.1:0").Send(Notification{Title: "T"}); err == nil {
+		t.Error("expected error for empty message")
+	}
+}
+
+func TestDiscordNotifier_Interface(t *testing.T) {
+	var _ ContextNotifier = (*DiscordNotifier)(nil)
+}
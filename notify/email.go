@@ -0,0 +1,225 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// defaultEmailTimeout bounds how long an email delivery may take.
+const defaultEmailTimeout = 30 * time.Second
+
+// smtpsPort is the port SMTP servers take TLS connections on from the
+// start, rather than upgrading with STARTTLS.
+const smtpsPort = "465"
+
+// EmailNotifier emails notifications through an SMTP server, with the
+// report as the body when the notification carries it, in HTML and plain
+// text. It suits servers where there's no browser to open the report in.
+type EmailNotifier struct {
+	Addr     string // SMTP server, as host:port
+	Username string // For SMTP authentication; none if empty
+	Password string
+	From     string
+	To       []string
+	Timeout  time.Duration
+	Now      func() time.Time // Dates messages; time.Now if nil
+
+	// TLSConfig is used for STARTTLS and port 465 connections. If nil,
+	// the server's certificate is checked against the host in Addr.
+	TLSConfig *tls.Config
+}
+
+// NewEmailNotifier creates an EmailNotifier that sends from from to to
+// through the SMTP server at addr, authenticating as username if it's set.
+func NewEmailNotifier(addr, username, password, from string, to []string) *EmailNotifier {
+	return &EmailNotifier{
+		Addr:     addr,
+		Username: username,
+		Password: password,
+		From:     from,
+		To:       to,
+		Timeout:  defaultEmailTimeout,
+	}
+}
+
+// Send emails the notification.
+func (e *EmailNotifier) Send(n Notification) error {
+	return e.SendContext(context.Background(), n)
+}
+
+// SendContext emails the notification, giving up when ctx is done. The
+// connection is upgraded with STARTTLS whenever the server offers it.
+func (e *EmailNotifier) SendContext(ctx context.Context, n Notification) error {
+	if n.Message == "" {
+		return fmt.Errorf("notification message cannot be empty")
+	}
+	now := time.Now
+	if e.Now != nil {
+		now = e.Now
+	}
+	msg, err := e.message(n, now())
+	if err != nil {
+		return err
+	}
+
+	host, port, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return fmt.Errorf("smtp server %q: %w", e.Addr, err)
+	}
+	tlsConfig := e.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	}
+
+	dialer := &net.Dialer{Timeout: e.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return fmt.Errorf("connecting to smtp server: %w", err)
+	}
+	if port == smtpsPort {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	if e.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(e.Timeout))
+	}
+	// Closing the connection unblocks whatever exchange is under way
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	err = e.deliver(conn, host, port, tlsConfig, msg)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// deliver runs the SMTP exchange that sends msg over conn, closing conn
+// when it's done.
+func (e *EmailNotifier) deliver(conn net.Conn, host, port string, tlsConfig *tls.Config, msg []byte) error {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("starting smtp session: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && port != smtpsPort {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starting tls: %w", err)
+		}
+	}
+	if e.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return fmt.Errorf("authenticating with smtp server: %w", err)
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return fmt.Errorf("smtp sender %s: %w", e.From, err)
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp recipient %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+	return c.Quit()
+}
+
+// message formats n as an email sent at date. Its body is n's HTML and
+// plain text, as alternatives, if it has both; otherwise it's whichever of
+// them n has, or else the message and subtitle as plain text.
+func (e *EmailNotifier) message(n Notification, date time.Time) ([]byte, error) {
+	subject := n.Message
+	if n.Title != "" {
+		subject = n.Title + ": " + n.Message
+	}
+	text := n.Text
+	if text == "" && n.HTML == "" {
+		text = n.Message + "\n"
+		if n.Subtitle != "" {
+			text += n.Subtitle + "\n"
+		}
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) { _, _ = fmt.Fprintf(&buf, "%s: %s\r\n", name, value) }
+	header("From", e.From)
+	header("To", strings.Join(e.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	switch {
+	case text != "" && n.HTML != "":
+		// Clients show the last alternative they can, so HTML goes last
+		mw := multipart.NewWriter(&buf)
+		header("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()}))
+		buf.WriteString("\r\n")
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain; charset=utf-8", text},
+			{"text/html; charset=utf-8", n.HTML},
+		} {
+			w, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.contentType},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("encoding email: %w", err)
+			}
+			if err := writeQuotedPrintable(w, part.body); err != nil {
+				return nil, err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, fmt.Errorf("encoding email: %w", err)
+		}
+	case n.HTML != "":
+		header("Content-Type", "text/html; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, n.HTML); err != nil {
+			return nil, err
+		}
+	default:
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, text); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes body to w quoted-printable, which keeps the
+// report's long lines within SMTP's limit.
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return fmt.Errorf("encoding email: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("encoding email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpServer is a fake SMTP server that accepts one message per
+// connection and records what it was sent.
+type smtpServer struct {
+	ln      net.Listener
+	done    chan struct{}
+	auth    string   // The AUTH line the client sent, if any
+	from    string   // MAIL FROM address
+	to      []string // RCPT TO addresses
+	data    string   // The message, as sent
+	rcptErr bool     // Reject recipients
+	stall   bool     // Never greet the client
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	return &smtpServer{ln: ln, done: make(chan struct{})}
+}
+
+// serve handles one connection. The caller waits on done before reading
+// what was recorded.
+func (s *smtpServer) serve() {
+	go func() {
+		defer close(s.done)
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		if s.stall {
+			_, _ = io.Copy(io.Discard, conn)
+			return
+		}
+		r := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch verb {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				s.auth = line
+				reply("235 OK")
+			case "MAIL":
+				s.from = strings.TrimSuffix(strings.TrimPrefix(line, "MAIL FROM:<"), ">")
+				reply("250 OK")
+			case "RCPT":
+				if s.rcptErr {
+					reply("550 No such user")
+					continue
+				}
+				s.to = append(s.to, strings.TrimSuffix(strings.TrimPrefix(line, "RCPT TO:<"), ">"))
+				reply("250 OK")
+			case "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				s.data = data.String()
+				reply("250 OK")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+}
+
+func TestEmailNotifier_Send(t *testing.T) {
+	srv := newSMTPServer(t)
+	srv.serve()
+
+	html := "<html><body><h1>Report</h1>" + strings.Repeat("<p>alice starred golang/go</p>", 50) + "</body></html>"
+	notifier := NewEmailNotifier(srv.ln.Addr().String(), "me@example.com", "secret", "gitstreams@example.com", []string{"me@example.com", "team@example.com"})
+	notifier.Now = func() time.Time { return time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC) }
+	err := notifier.Send(Notification{Title: "GitStreams", Message: "2 new stars", Subtitle: "alice was the most active", HTML: html})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	<-srv.done
+
+	if !strings.HasPrefix(srv.auth, "AUTH PLAIN ") {
+		t.Errorf("AUTH = %q, want PLAIN authentication", srv.auth)
+	}
+	if srv.from != "gitstreams@example.com" {
+		t.Errorf("MAIL FROM = %q", srv.from)
+	}
+	if strings.Join(srv.to, ",") != "me@example.com,team@example.com" {
+		t.Errorf("RCPT TO = %v", srv.to)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(srv.data))
+	if err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	if got := msg.Header.Get("Subject"); got != "GitStreams: 2 new stars" {
+		t.Errorf("Subject = %q", got)
+	}
+	if got := msg.Header.Get("Date"); got != "Thu, 15 Jan 2026 09:00:00 +0000" {
+		t.Errorf("Date = %q, want the notifier's clock", got)
+	}
+	if got := msg.Header.Get("To"); got != "me@example.com, team@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	if got := msg.Header.Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want HTML", got)
+	}
+	for _, line := range strings.Split(srv.data, "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("line of %d characters, over SMTP's limit", len(line))
+		}
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	// SMTP ends the message with a line break
+	if strings.TrimSuffix(string(body), "\r\n") != html {
+		t.Errorf("body = %q, want the report", body)
+	}
+}
+
+func TestEmailNotifier_PlainText(t *testing.T) {
+	n := &EmailNotifier{From: "a@example.com", To: []string{"b@example.com"}}
+	date := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	raw, err := n.message(Notification{Title: "GitStreams", Message: "1 new star — ünïcode", Subtitle: "bob was the most active"}, date)
+	if err != nil {
+		t.Fatalf("message() error = %v", err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	if got := msg.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want plain text", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "GitStreams: 1 new star — ünïcode" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if got, err := msg.Header.Date(); err != nil || !got.Equal(date) {
+		t.Errorf("Date = %v (%v), want %v", got, err, date)
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if want := "1 new star — ünïcode\r\nbob was the most active\r\n"; string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestEmailNotifier_Alternatives(t *testing.T) {
+	n := &EmailNotifier{From: "a@example.com", To: []string{"b@example.com"}}
+	html := "<p>alice starred golang/go</p>"
+	text := "- alice starred golang/go\n"
+	raw, err := n.message(Notification{Title: "GitStreams", Message: "1 new star", HTML: html, Text: text}, time.Now())
+	if err != nil {
+		t.Fatalf("message() error = %v", err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v), want alternatives", msg.Header.Get("Content-Type"), err)
+	}
+
+	// Plain text first, so clients that can show HTML prefer it
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", strings.ReplaceAll(text, "\n", "\r\n")},
+		{"text/html; charset=utf-8", html},
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for _, w := range want {
+		part, err := r.NextRawPart()
+		if err != nil {
+			t.Fatalf("reading %s part: %v", w.contentType, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, w.contentType)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		if string(body) != w.body {
+			t.Errorf("%s body = %q, want %q", w.contentType, body, w.body)
+		}
+	}
+	if _, err := r.NextPart(); err != io.EOF {
+		t.Errorf("expected two parts, got another (%v)", err)
+	}
+}
+
+func TestEmailNotifier_RecipientRejected(t *testing.T) {
+	srv := newSMTPServer(t)
+	srv.rcptErr = true
+	srv.serve()
+
+	notifier := NewEmailNotifier(srv.ln.Addr().String(), "", "", "a@example.com", []string{"nobody@example.com"})
+	err := notifier.Send(Notification{Message: "M"})
+	<-srv.done
+	if err == nil || !strings.Contains(err.Error(), "nobody@example.com") || !strings.Contains(err.Error(), "550") {
+		t.Errorf("expected the rejected recipient in the error, got %v", err)
+	}
+	if srv.auth != "" {
+		t.Errorf("expected no authentication without a username, got %q", srv.auth)
+	}
+}
+
+func TestEmailNotifier_SendContextCancelled(t *testing.T) {
+	srv := newSMTPServer(t)
+	srv.stall = true
+	srv.serve()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	notifier := NewEmailNotifier(srv.ln.Addr().String(), "", "", "a@example.com", []string{"b@example.com"})
+	if err := notifier.SendContext(ctx, Notification{Message: "M"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to cut the exchange short, got %v", err)
+	}
+}
+
+func TestEmailNotifier_EmptyMessage(t *testing.T) {
+	if err := NewEmailNotifier("127.0.0.1:0", "", "", "a@example.com", nil).Send(Notification{Title: "T"}); err == nil {
+		t.Error("expected error for empty message")
+	}
+}
+
+func TestEmailNotifier_Interface(t *testing.T) {
+	var _ ContextNotifier = (*EmailNotifier)(nil)
+}
@@ -1,9 +1,13 @@
-// Package notify provides desktop notification support for macOS.
+// Package notify delivers notifications, as macOS desktop notifications,
+// webhook posts, Slack messages or emails.
 package notify
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 )
 
 // Notification represents a desktop notification.
@@ -13,6 +17,66 @@ type Notification struct {
 	Subtitle string
 	Sound    string // macOS sound name (e.g., "default", "Ping", "Basso")
 	OpenURL  string // URL to open when notification is clicked (terminal-notifier only)
+
+	// What the report features, for notifiers that show more than the
+	// message, e.g. "alice created owner/repo"
+	Highlight  string
+	MostActive string // Who has the most activity in the report
+
+	// Alert names the alert rule an alert notification is for; it's empty
+	// for report notifications. Routers pick notifiers by it.
+	Alert string
+
+	// HTML is the report laid out for email, for notifiers that can carry
+	// it whole.
+	HTML string
+
+	// Text is a plain-text rendering of the report, for notifiers that
+	// send it alongside HTML for clients that don't show HTML.
+	Text string
+
+	// Summary lays out what a report notification's text sums up, for
+	// notifiers that can show structure; nil for other notifications
+	Summary *Summary
+
+	// Highlights are the report's most notable activities, best first, for
+	// notifiers with room to list several
+	Highlights []string
+
+	// Actions are links to follow up with, most useful first, for
+	// notifiers that can show them as buttons. macOS notifications can't:
+	// a click opens OpenURL instead
+	Actions []Action
+}
+
+// Action is a link a notification offers as a button, e.g. "Open report".
+type Action struct {
+	Label string
+	URL   string
+}
+
+// Summary is the structure behind a report notification.
+type Summary struct {
+	Featured *FeaturedActivity // The report's highlight, if it has one
+	Counts   []CategoryCount   // By category, in the report's order
+}
+
+// CategoryCount is how many activities of a category a report has.
+type CategoryCount struct {
+	Name  string // e.g. "New Stars"
+	Icon  string
+	Count int
+}
+
+// FeaturedActivity is the activity a report features.
+type FeaturedActivity struct {
+	Text      string // e.g. "alice created owner/repo"
+	Reason    string // Why it's featured, e.g. "🚀 Fresh off the press!"
+	Details   string
+	URL       string // Where it happened
+	User      string
+	UserURL   string
+	AvatarURL string
 }
 
 // Notifier sends desktop notifications.
@@ -20,6 +84,24 @@ type Notifier interface {
 	Send(n Notification) error
 }
 
+// ContextNotifier is a Notifier whose delivery can be cancelled.
+type ContextNotifier interface {
+	Notifier
+	SendContext(ctx context.Context, n Notification) error
+}
+
+// SendContext sends n with notifier, giving up when ctx is done if the
+// notifier supports cancellation.
+func SendContext(ctx context.Context, notifier Notifier, n Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cn, ok := notifier.(ContextNotifier); ok {
+		return cn.SendContext(ctx, n)
+	}
+	return notifier.Send(n)
+}
+
 // CommandExecutor runs shell commands. Used for dependency injection in tests.
 type CommandExecutor interface {
 	LookPath(file string) (string, error)
@@ -40,23 +122,42 @@ func (DefaultExecutor) Run(name string, args ...string) error {
 	return cmd.Run()
 }
 
-// MacNotifier sends notifications on macOS using terminal-notifier or osascript.
+// MacNotifier sends notifications on macOS through its own applet, or
+// failing that terminal-notifier or osascript.
 type MacNotifier struct {
 	Executor CommandExecutor
+	// AppletDir is where the notification applet is built and kept. Empty
+	// leaves the applet out.
+	AppletDir string
 }
 
-// NewMacNotifier creates a new MacNotifier with the default executor.
+// NewMacNotifier creates a new MacNotifier with the default executor, which
+// keeps its applet in the user's cache directory.
 func NewMacNotifier() *MacNotifier {
-	return &MacNotifier{Executor: DefaultExecutor{}}
+	m := &MacNotifier{Executor: DefaultExecutor{}}
+	if dir, err := os.UserCacheDir(); err == nil {
+		m.AppletDir = filepath.Join(dir, "gitstreams")
+	}
+	return m
 }
 
-// Send sends a notification using terminal-notifier if available, otherwise osascript.
+// Send sends a notification through the applet, which needs only what comes
+// with macOS and opens n.OpenURL when clicked. If the applet can't be built
+// or run, it falls back to terminal-notifier if available, otherwise
+// osascript. None of them show n.Actions: display notification has no
+// buttons, and terminal-notifier dropped its -actions in version 2.
 func (m *MacNotifier) Send(n Notification) error {
 	if n.Message == "" {
 		return fmt.Errorf("notification message cannot be empty")
 	}
 
-	// Try terminal-notifier first (better UX, more features)
+	if m.AppletDir != "" {
+		if _, err := m.Executor.LookPath("osacompile"); err == nil && m.sendApplet(n) == nil {
+			return nil
+		}
+	}
+
+	// terminal-notifier next (better UX than osascript, more features)
 	if _, err := m.Executor.LookPath("terminal-notifier"); err == nil {
 		return m.sendTerminalNotifier(n)
 	}
@@ -87,7 +188,7 @@ func (m *MacNotifier) sendTerminalNotifier(n Notification) error {
 
 // sendOsascript sends a notification using osascript.
 // Note: osascript's display notification does not support click actions.
-// For click-to-open functionality, install terminal-notifier: brew install terminal-notifier
+// Click-to-open comes from the applet, or terminal-notifier without it.
 func (m *MacNotifier) sendOsascript(n Notification) error {
 	// Build AppleScript for display notification
 	// OpenURL is intentionally ignored as osascript does not support click actions
@@ -2,6 +2,8 @@ package notify
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -212,6 +214,9 @@ func TestNewMacNotifier(t *testing.T) {
 	if _, ok := notifier.Executor.(DefaultExecutor); !ok {
 		t.Error("Executor is not DefaultExecutor")
 	}
+	if _, err := os.UserCacheDir(); err == nil && filepath.Base(notifier.AppletDir) != "gitstreams" {
+		t.Errorf("AppletDir = %q, want gitstreams' cache directory", notifier.AppletDir)
+	}
 }
 
 func TestDefaultExecutor_LookPath(t *testing.T) {
@@ -0,0 +1,49 @@
+package notify
+
+import "context"
+
+// Route sends the notifications Match accepts to the notifiers named in To.
+type Route struct {
+	Match func(n Notification) bool
+	To    []string
+}
+
+// Router sends each notification to the notifiers its matching routes
+// name, each once, as a MultiNotifier does. A notification no route
+// matches goes nowhere, as do routes to names missing from Notifiers.
+type Router struct {
+	Notifiers map[string]Notifier
+	Routes    []Route
+}
+
+// Send delivers n to the notifiers routed to.
+func (r Router) Send(n Notification) error {
+	return r.SendContext(context.Background(), n)
+}
+
+// SendContext delivers n to the notifiers routed to, cancelling those that
+// support it when ctx is done.
+func (r Router) SendContext(ctx context.Context, n Notification) error {
+	return r.For(n).SendContext(ctx, n)
+}
+
+// For returns the notifiers n is routed to, in the order routes first name
+// them.
+func (r Router) For(n Notification) MultiNotifier {
+	var to MultiNotifier
+	seen := make(map[string]bool)
+	for _, route := range r.Routes {
+		if !route.Match(n) {
+			continue
+		}
+		for _, name := range route.To {
+			notifier, ok := r.Notifiers[name]
+			if !ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+			to = append(to, notifier)
+		}
+	}
+	return to
+}
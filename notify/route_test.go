@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRouter_Send(t *testing.T) {
+	slack, desktop := &recordingNotifier{}, &recordingNotifier{err: errors.New("no display")}
+	isAlert := func(n Notification) bool { return n.Alert != "" }
+	router := Router{
+		Notifiers: map[string]Notifier{"slack": slack, "desktop": desktop},
+		Routes: []Route{
+			{Match: func(n Notification) bool { return n.Alert == "releases" }, To: []string{"slack", "desktop"}},
+			{Match: isAlert, To: []string{"slack", "email"}},
+			{Match: func(n Notification) bool { return n.Alert == "" }, To: []string{"desktop"}},
+		},
+	}
+
+	if err := router.Send(Notification{Message: "M", Alert: "releases"}); err == nil {
+		t.Error("expected desktop's error to be returned")
+	}
+	if len(slack.sent) != 1 || len(desktop.sent) != 1 {
+		t.Errorf("expected each notifier sent to once, got slack %d and desktop %d", len(slack.sent), len(desktop.sent))
+	}
+
+	if err := router.Send(Notification{Message: "M", Alert: "pushes"}); err != nil {
+		t.Errorf("Send() error = %v", err)
+	}
+	if len(slack.sent) != 2 || len(desktop.sent) != 1 {
+		t.Errorf("expected only slack to get the pushes alert, got slack %d and desktop %d", len(slack.sent), len(desktop.sent))
+	}
+
+	_ = router.Send(Notification{Message: "M"})
+	if len(slack.sent) != 2 || len(desktop.sent) != 2 {
+		t.Errorf("expected only desktop to get the report, got slack %d and desktop %d", len(slack.sent), len(desktop.sent))
+	}
+
+	if to := (Router{Notifiers: router.Notifiers}).For(Notification{Message: "M"}); len(to) != 0 {
+		t.Errorf("expected a notification no route matches to go nowhere, got %v", to)
+	}
+}
+
+func TestRouter_Interface(t *testing.T) {
+	var _ ContextNotifier = Router{}
+}
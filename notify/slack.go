@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SlackNotifier posts notifications to a Slack incoming webhook, as the
+// summary and, optionally, a list of the report's highlights.
+type SlackNotifier struct {
+	Client *http.Client
+	URL    string
+
+	// Highlights is how many of a notification's highlights to list; 0
+	// lists none.
+	Highlights int
+}
+
+// slackPayload is the message posted to Slack. Text is what
+// notifications and clients that can't show blocks fall back to.
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks,omitempty"`
+}
+
+// slackBlock is a section block of Slack's mrkdwn text, or an actions
+// block of buttons.
+type slackBlock struct {
+	Text     *slackText     `json:"text,omitempty"`
+	Type     string         `json:"type"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackElement is a button that opens a link.
+type slackElement struct {
+	Text slackText `json:"text"`
+	Type string    `json:"type"`
+	URL  string    `json:"url"`
+}
+
+// slackMaxButtonText is how long Slack lets a button's label be.
+const slackMaxButtonText = 75
+
+// NewSlackNotifier creates a SlackNotifier that posts to the incoming
+// webhook at url, listing up to highlights of each notification's
+// highlights.
+func NewSlackNotifier(url string, highlights int) *SlackNotifier {
+	return &SlackNotifier{
+		Client:     &http.Client{Timeout: defaultWebhookTimeout},
+		URL:        url,
+		Highlights: highlights,
+	}
+}
+
+// Send posts the notification. Any non-2xx response is an error.
+func (s *SlackNotifier) Send(n Notification) error {
+	return s.SendContext(context.Background(), n)
+}
+
+// SendContext posts the notification, giving up when ctx is done.
+func (s *SlackNotifier) SendContext(ctx context.Context, n Notification) error {
+	if n.Message == "" {
+		return fmt.Errorf("notification message cannot be empty")
+	}
+	return postJSON(ctx, s.Client, s.URL, "slack webhook", s.payload(n))
+}
+
+// payload formats n as a Slack message.
+func (s *SlackNotifier) payload(n Notification) slackPayload {
+	text := slackEscape(n.Message)
+	if n.Title != "" {
+		text = "*" + slackEscape(n.Title) + "*: " + text
+	}
+	p := slackPayload{Text: text}
+
+	summary := text
+	if n.Subtitle != "" {
+		summary += "\n_" + slackEscape(n.Subtitle) + "_"
+	}
+	// Slack can't open a report on the machine that wrote it, or follow
+	// any other link there
+	var buttons []slackElement
+	for _, a := range n.Actions {
+		if isWebURL(a.URL) {
+			buttons = append(buttons, slackElement{
+				Type: "button",
+				Text: slackText{Type: "plain_text", Text: truncate(a.Label, slackMaxButtonText)},
+				URL:  a.URL,
+			})
+		}
+	}
+	if isWebURL(n.OpenURL) && !slices.ContainsFunc(buttons, func(b slackElement) bool { return b.URL == n.OpenURL }) {
+		summary += "\n<" + n.OpenURL + "|Open the report>"
+	}
+	p.Blocks = append(p.Blocks, slackSection(summary))
+
+	if highlights := n.Highlights[:min(len(n.Highlights), s.Highlights)]; len(highlights) > 0 {
+		var list strings.Builder
+		list.WriteString("*Highlights*")
+		for _, h := range highlights {
+			list.WriteString("\n• " + slackEscape(h))
+		}
+		p.Blocks = append(p.Blocks, slackSection(list.String()))
+	}
+	if len(buttons) > 0 {
+		p.Blocks = append(p.Blocks, slackBlock{Type: "actions", Elements: buttons})
+	}
+	return p
+}
+
+func slackSection(text string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}
+}
+
+// isWebURL reports whether url can be opened from anywhere, unlike a
+// file:// URL.
+func isWebURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// slackEscape escapes the characters Slack reads as markup in text.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSlackNotifier_Send(t *testing.T) {
+	var got slackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	err := NewSlackNotifier(server.URL, 2).Send(Notification{
+		Title:      "GitStreams",
+		Message:    "3 new stars",
+		Subtitle:   "alice was the most active",
+		OpenURL:    "file:///tmp/report.html",
+		Highlights: []string{"alice created alice/<new>", "bob opened a PR on bob/tool", "carol starred golang/go"},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got.Text != "*GitStreams*: 3 new stars" {
+		t.Errorf("Text = %q", got.Text)
+	}
+	if len(got.Blocks) != 2 {
+		t.Fatalf("expected a summary and highlights, got %+v", got.Blocks)
+	}
+	if want := "*GitStreams*: 3 new stars\n_alice was the most active_"; got.Blocks[0].Text.Text != want {
+		t.Errorf("summary = %q, want %q without the local report link", got.Blocks[0].Text.Text, want)
+	}
+	if want := "*Highlights*\n• alice created alice/&lt;new&gt;\n• bob opened a PR on bob/tool"; got.Blocks[1].Text.Text != want {
+		t.Errorf("highlights = %q, want %q", got.Blocks[1].Text.Text, want)
+	}
+}
+
+func TestSlackNotifier_Payload(t *testing.T) {
+	n := Notification{Message: "1 new repo", OpenURL: "https://reports.example.com/today", Highlights: []string{"alice created alice/new"}}
+
+	p := (&SlackNotifier{}).payload(n)
+	if len(p.Blocks) != 1 {
+		t.Fatalf("expected no highlights block when listing none, got %+v", p.Blocks)
+	}
+	if want := "1 new repo\n<https://reports.example.com/today|Open the report>"; p.Blocks[0].Text.Text != want {
+		t.Errorf("summary = %q, want %q", p.Blocks[0].Text.Text, want)
+	}
+
+	n.Highlights = nil
+	if p := (&SlackNotifier{Highlights: 3}).payload(n); len(p.Blocks) != 1 {
+		t.Errorf("expected no highlights block without highlights, got %+v", p.Blocks)
+	}
+}
+
+func TestSlackNotifier_Actions(t *testing.T) {
+	n := Notification{
+		Message: "1 new repo",
+		OpenURL: "https://reports.example.com/today",
+		Actions: []Action{
+			{Label: "Open report", URL: "https://reports.example.com/today"},
+			{Label: "Open top repo", URL: "https://github.com/alice/new"},
+			{Label: "Open local report", URL: "file:///tmp/report.html"},
+		},
+	}
+
+	p := (&SlackNotifier{}).payload(n)
+	if len(p.Blocks) != 2 || p.Blocks[1].Type != "actions" {
+		t.Fatalf("expected a summary and buttons, got %+v", p.Blocks)
+	}
+	if p.Blocks[0].Text.Text != "1 new repo" {
+		t.Errorf("summary = %q, want the report link left to its button", p.Blocks[0].Text.Text)
+	}
+	buttons := p.Blocks[1].Elements
+	if len(buttons) != 2 || buttons[0].Text.Text != "Open report" || buttons[1].URL != "https://github.com/alice/new" || buttons[1].Type != "button" {
+		t.Errorf("expected buttons for the web links only, got %+v", buttons)
+	}
+
+	n.Actions = n.Actions[2:]
+	if p := (&SlackNotifier{}).payload(n); len(p.Blocks) != 1 {
+		t.Errorf("expected no buttons without web links, got %+v", p.Blocks)
+	}
+}
+
+func TestSlackNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewSlackNotifier(server.URL, 0).Send(Notification{Message: "M"})
+	if err == nil || !strings.Contains(err.Error(), "slack webhook returned 403") {
+		t.Errorf("expected 403 error, got %v", err)
+	}
+}
+
+func TestSlackNotifier_EmptyMessage(t *testing.T) {
+	if err := NewSlackNotifier("http://127.0.0.1:0", 0).Send(Notification{Title: "T"}); err == nil {
+		t.Error("expected error for empty message")
+	}
+}
+
+func TestSlackNotifier_Interface(t *testing.T) {
+	var _ ContextNotifier = (*SlackNotifier)(nil)
+}
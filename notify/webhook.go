@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultWebhookTimeout bounds how long a webhook delivery may take.
+const defaultWebhookTimeout = 10 * time.Second
+
+// WebhookNotifier posts notifications as JSON to an HTTP endpoint. It suits
+// headless hosts where desktop notifications can't be shown.
+type WebhookNotifier struct {
+	Client *http.Client
+	URL    string
+}
+
+// webhookPayload is the JSON body sent to the webhook.
+type webhookPayload struct {
+	Title      string          `json:"title"`
+	Message    string          `json:"message"`
+	Subtitle   string          `json:"subtitle,omitempty"`
+	URL        string          `json:"url,omitempty"`
+	Highlight  string          `json:"highlight,omitempty"`
+	MostActive string          `json:"most_active,omitempty"`
+	Actions    []webhookAction `json:"actions,omitempty"`
+}
+
+// webhookAction is an action in ntfy's shape, so ntfy shows it as a button.
+type webhookAction struct {
+	Action string `json:"action"` // Always "view", which opens URL
+	Label  string `json:"label"`
+	URL    string `json:"url"`
+}
+
+// NewWebhookNotifier creates a WebhookNotifier that posts to url.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		Client: &http.Client{Timeout: defaultWebhookTimeout},
+		URL:    url,
+	}
+}
+
+// Send posts the notification. Any non-2xx response is an error.
+func (w *WebhookNotifier) Send(n Notification) error {
+	return w.SendContext(context.Background(), n)
+}
+
+// SendContext posts the notification, giving up when ctx is done.
+func (w *WebhookNotifier) SendContext(ctx context.Context, n Notification) error {
+	if n.Message == "" {
+		return fmt.Errorf("notification message cannot be empty")
+	}
+
+	payload := webhookPayload{
+		Title:      n.Title,
+		Message:    n.Message,
+		Subtitle:   n.Subtitle,
+		URL:        n.OpenURL,
+		Highlight:  n.Highlight,
+		MostActive: n.MostActive,
+	}
+	for _, a := range n.Actions {
+		payload.Actions = append(payload.Actions, webhookAction{Action: "view", Label: a.Label, URL: a.URL})
+	}
+	return postJSON(ctx, w.Client, w.URL, "webhook", payload)
+}
+
+// postJSON posts payload as JSON to url, naming what it posts to as what
+// in errors. Any non-2xx response is an error.
+func postJSON(ctx context.Context, client *http.Client, url, what string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding %s payload: %w", what, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating %s request: %w", what, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting %s: %w", what, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", what, resp.Status)
+	}
+	return nil
+}
+
+// MultiNotifier sends each notification to every notifier in the list. All
+// notifiers are tried even if some fail; their errors are joined.
+type MultiNotifier []Notifier
+
+// Send delivers n to every notifier.
+func (m MultiNotifier) Send(n Notification) error {
+	return m.SendContext(context.Background(), n)
+}
+
+// SendContext delivers n to every notifier, cancelling those that support
+// it when ctx is done.
+func (m MultiNotifier) SendContext(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := SendContext(ctx, notifier, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
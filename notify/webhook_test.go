@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier_Send(t *testing.T) {
+	var got webhookPayload
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+	err := notifier.Send(Notification{Title: "T", Message: "M", Subtitle: "S", Sound: "Ping", OpenURL: "file:///r.html", Highlight: "H", MostActive: "A",
+		Actions: []Action{{Label: "Open report", URL: "file:///r.html"}}})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	want := webhookPayload{Title: "T", Message: "M", Subtitle: "S", URL: "file:///r.html", Highlight: "H", MostActive: "A",
+		Actions: []webhookAction{{Action: "view", Label: "Open report", URL: "file:///r.html"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("payload = %+v, want %+v", got, want)
+	}
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL).Send(Notification{Message: "M"})
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("expected 502 error, got %v", err)
+	}
+}
+
+func TestWebhookNotifier_EmptyMessage(t *testing.T) {
+	if err := NewWebhookNotifier("http://127.0.0.1:0").Send(Notification{Title: "T"}); err == nil {
+		t.Error("expected error for empty message")
+	}
+}
+
+// recordingNotifier records notifications and returns a fixed error.
+type recordingNotifier struct {
+	err  error
+	sent []Notification
+}
+
+func (r *recordingNotifier) Send(n Notification) error {
+	r.sent = append(r.sent, n)
+	return r.err
+}
+
+func TestMultiNotifier_Send(t *testing.T) {
+	errA := errors.New("a failed")
+	a := &recordingNotifier{err: errA}
+	b := &recordingNotifier{}
+
+	err := MultiNotifier{a, b}.Send(Notification{Message: "M"})
+	if !errors.Is(err, errA) {
+		t.Errorf("expected joined error to include %v, got %v", errA, err)
+	}
+	if len(a.sent) != 1 || len(b.sent) != 1 {
+		t.Errorf("expected every notifier to be tried, got %d and %d", len(a.sent), len(b.sent))
+	}
+
+	if err := (MultiNotifier{b}).Send(Notification{Message: "M"}); err != nil {
+		t.Errorf("expected nil error when all succeed, got %v", err)
+	}
+}
+
+func TestWebhookNotifier_SendContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := NewWebhookNotifier(server.URL).SendContext(ctx, Notification{Message: "M"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to cut the post short, got %v", err)
+	}
+}
+
+func TestSendContext(t *testing.T) {
+	r := &recordingNotifier{}
+	if err := SendContext(context.Background(), r, Notification{Message: "M"}); err != nil || len(r.sent) != 1 {
+		t.Errorf("expected a plain Notifier to be sent to, got %v and %d sent", err, len(r.sent))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := SendContext(ctx, MultiNotifier{r}, Notification{Message: "M"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if len(r.sent) != 1 {
+		t.Errorf("expected nothing sent after cancellation, got %d", len(r.sent))
+	}
+}
+
+func TestWebhookNotifier_Interface(t *testing.T) {
+	var _ ContextNotifier = (*WebhookNotifier)(nil)
+	var _ ContextNotifier = MultiNotifier(nil)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+// orgSnapshotPrefix and userSnapshotPrefix key -org and -user runs'
+// snapshots in the store, by organization or account, so they aren't
+// compared with another follow list's.
+const (
+	orgSnapshotPrefix  = "org:"
+	userSnapshotPrefix = "user:"
+)
+
+// runSnapshotID returns the ID cfg's runs keep their snapshots, and
+// activities, under: the organization's with -org, the account's with
+// -user, or else your own follow list's.
+func runSnapshotID(cfg *Config) string {
+	switch {
+	case cfg.Org != "":
+		return orgSnapshotPrefix + cfg.Org
+	case cfg.Username != "":
+		return userSnapshotPrefix + cfg.Username
+	default:
+		return snapshotUserID
+	}
+}
+
+// teamClient is implemented by clients that can list an organization's
+// teams and their members. Org snapshots from other clients have no teams.
+type teamClient interface {
+	GetOrgTeams(ctx context.Context, org string) ([]github.Team, error)
+	GetTeamMembers(ctx context.Context, org, team string) ([]github.User, error)
+}
+
+// fetchTeams records who's in each of org's teams in snapshot, for the
+// report's by-team view. Members the snapshot has no activity for are left
+// out, and so are teams left empty. Clients that can't list teams leave
+// the snapshot without any.
+func fetchTeams(ctx context.Context, client GitHubClient, org string, snapshot *diff.Snapshot) error {
+	tc, ok := client.(teamClient)
+	if !ok {
+		return nil
+	}
+	teams, err := tc.GetOrgTeams(ctx, org)
+	if err != nil {
+		return err
+	}
+	members := make(map[string][]string)
+	for _, team := range teams {
+		users, err := tc.GetTeamMembers(ctx, org, team.Slug)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			if _, ok := snapshot.Users[u.Login]; ok {
+				members[team.Name] = append(members[team.Name], u.Login)
+			}
+		}
+	}
+	if len(members) > 0 {
+		snapshot.Teams = members
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/github/githubtest"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestOrgRun(t *testing.T) {
+	now := fixedTime()
+	srv := githubtest.NewServer(t)
+	srv.Follow("someone-else")
+	srv.AddTeam("acme", github.Team{Name: "Web Team", Slug: "web-team"}, "alice", "bob")
+	srv.AddTeam("acme", github.Team{Name: "Infra", Slug: "infra"}, "bob")
+	srv.AddOrgMembers("acme", "carol")
+	for _, login := range []string{"alice", "bob", "carol"} {
+		srv.AddRepos(login, github.Repository{ID: int64(len(login)), Name: "tool", FullName: login + "/tool", Owner: github.User{Login: login}, CreatedAt: now.Add(-time.Hour)})
+	}
+
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return srv.Client(token) },
+		ReportGenerator:     func(format string) (ReportGenerator, error) { return report.New(format) },
+		Now:                 func() time.Time { return now },
+	}
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	cfg := &Config{Token: "test-token", Org: "acme", Format: report.FormatJSON, ReportPath: reportPath, Days: 30, Concurrency: 1, NoOpen: true, NoNotify: true}
+	var stdout, stderr strings.Builder
+	store := storage.NewMemoryStore()
+	p := &pipeline{cfg: cfg, deps: deps, store: store, stdout: &stdout, stderr: &stderr}
+	if err := runStages(context.Background(), p.stages(), &runState{}); err != nil {
+		t.Fatalf("run failed: %v\nstderr: %s", err, stderr.String())
+	}
+
+	f, err := os.Open(reportPath) // #nosec G304 -- test temp dir
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	rpt, err := report.ReadJSON(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users []string
+	for _, ua := range rpt.UserActivities {
+		users = append(users, ua.User)
+	}
+	slices.Sort(users)
+	if !slices.Equal(users, []string{"alice", "bob", "carol"}) {
+		t.Errorf("report covers %v, want acme's members rather than follows", users)
+	}
+	if !slices.Equal(rpt.Teams["Web Team"], []string{"alice", "bob"}) || !slices.Equal(rpt.Teams["Infra"], []string{"bob"}) || len(rpt.Teams) != 2 {
+		t.Errorf("report teams = %v", rpt.Teams)
+	}
+
+	// The org's snapshot is kept apart from the follow list's, so switching
+	// between them doesn't make everyone new or gone
+	if snapshots, _ := store.GetByUser(orgSnapshotPrefix+"acme", 1); len(snapshots) != 1 {
+		t.Errorf("got %d snapshots for acme, want 1", len(snapshots))
+	}
+	if snapshots, _ := store.GetByUser(snapshotUserID, 1); len(snapshots) != 0 {
+		t.Errorf("got %d snapshots for the follow list, want none", len(snapshots))
+	}
+}
+
+func TestFetchTeams_WithoutTeamClient(t *testing.T) {
+	snapshot := diff.NewSnapshot(fixedTime())
+	if err := fetchTeams(context.Background(), &mockGitHubClient{}, "acme", snapshot); err != nil || snapshot.Teams != nil {
+		t.Errorf("fetchTeams() with a client that can't list teams = %v, %v; want no teams and no error", snapshot.Teams, err)
+	}
+}
+
+func TestOrgSettings(t *testing.T) {
+	setHome(t)
+	t.Setenv("GITHUB_TOKEN", "")
+	if _, err := parseFlags([]string{"-org", "acme", "-user", "alice"}); err == nil || !strings.Contains(err.Error(), "-org") {
+		t.Errorf("expected -org and -user to conflict, got %v", err)
+	}
+	if _, err := resolveFetchPlan(&Config{Org: "acme"}); err == nil || !strings.Contains(err.Error(), "GITHUB_TOKEN") {
+		t.Errorf("expected -org to need a token, got %v", err)
+	}
+	plan, err := resolveFetchPlan(&Config{Org: "acme", Token: "t"})
+	if err != nil || plan.Org != "acme" {
+		t.Errorf("resolveFetchPlan() = %+v, %v", plan, err)
+	}
+	if got := reportProfile(&Config{Org: "acme"}); got != "acme" {
+		t.Errorf("reportProfile() = %q, want the org", got)
+	}
+}
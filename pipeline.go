@@ -0,0 +1,533 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/notify"
+	"github.com/justinabrahms/gitstreams/otel"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/source"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// errDone ends a run early but successfully, e.g. when there's nothing new
+// to report.
+var errDone = errors.New("done")
+
+// runState is what a run's stages have worked out so far. Each stage reads
+// what earlier ones left and adds its own.
+type runState struct {
+	previous     *diff.Snapshot // Acquire
+	current      *diff.Snapshot // Acquire
+	result       *diff.Result   // Diff, narrowed by Filter
+	report       *report.Report // Render: this run's report, before any merge
+	finishDigest func()         // Filter; called once the digest is delivered
+	periodStart  time.Time      // Filter
+	reportPath   string         // Render
+	warnings     []FetchWarning // Acquire
+	mode         string         // Acquire: one of the report.Mode constants
+	lookbackDays int            // Acquire: how far back the fetch reached, if there was one
+	apiRequests  int            // Acquire: requests the fetch made, if counted
+	appended     bool           // Render: merged into an existing report
+	dedup        bool           // Filter: reported changes were left out, so Deliver records these
+}
+
+// stage is one step of a run.
+type stage struct {
+	run  func(ctx context.Context, st *runState) error
+	name string
+}
+
+// acquirer fills in a run's previous and current snapshots. Which one a run
+// uses depends on its mode.
+type acquirer interface {
+	acquire(ctx context.Context, st *runState) error
+}
+
+// pipeline holds what a run's stages share.
+type pipeline struct {
+	cfg           *Config
+	deps          *Dependencies
+	store         Store
+	stdout        io.Writer
+	stderr        io.Writer
+	releaseNotice string // Shown in the report footer
+}
+
+// stages returns a full run: Acquire → Diff → Filter → Render → Deliver.
+func (p *pipeline) stages() []stage {
+	return []stage{
+		{name: "acquire", run: p.acquirer().acquire},
+		{name: "diff", run: p.diff},
+		{name: "filter", run: p.filter},
+		{name: "render", run: p.render},
+		{name: "deliver", run: p.deliver},
+	}
+}
+
+// runStages runs stages in order, each in a span of its own, stopping at
+// the first error. errDone stops the run without an error.
+func runStages(ctx context.Context, stages []stage, st *runState) error {
+	tracer := otel.Tracer()
+	for _, s := range stages {
+		stageCtx, span := tracer.Start(ctx, s.name)
+		err := s.run(stageCtx, st)
+		if err != nil && !errors.Is(err, errDone) {
+			span.RecordError(err)
+		}
+		span.End()
+		if errors.Is(err, errDone) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acquirer picks how to get snapshots for the run's mode.
+func (p *pipeline) acquirer() acquirer {
+	switch {
+	case p.cfg.ReportSince != "":
+		return historicalAcquirer{p}
+	case p.cfg.Offline:
+		return offlineAcquirer{p}
+	default:
+		return liveAcquirer{p}
+	}
+}
+
+// liveAcquirer fetches current activity and compares it with the most
+// recent snapshot, saving the new one.
+type liveAcquirer struct{ *pipeline }
+
+func (a liveAcquirer) acquire(ctx context.Context, st *runState) error {
+	previous, err := loadLatestSnapshot(a.store, runSnapshotID(a.cfg))
+	if err != nil {
+		return fmt.Errorf("loading previous snapshot: %w", err)
+	}
+	st.previous = previous
+
+	// The previous snapshot lets the fetch skip what isn't worth calling
+	st.mode = report.ModeLive
+	if err := a.fetchCurrent(ctx, st, previous, ""); err != nil {
+		return err
+	}
+
+	size, err := saveSnapshotAs(a.store, runSnapshotID(a.cfg), st.current, a.deps.Now())
+	if err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	if a.cfg.Verbose {
+		_, _ = fmt.Fprintf(a.stdout, "Saved current snapshot (%s)\n", formatBytes(size))
+	}
+	return nil
+}
+
+// offlineAcquirer reports everything in the most recent snapshot, without
+// contacting GitHub.
+type offlineAcquirer struct{ *pipeline }
+
+func (a offlineAcquirer) acquire(ctx context.Context, st *runState) error {
+	snapshots, err := a.store.GetByUser(runSnapshotID(a.cfg), 1)
+	if err != nil {
+		return fmt.Errorf("loading cached snapshot: %w", err)
+	}
+	if len(snapshots) == 0 {
+		return errors.New("no cached data available; run without --offline first to fetch data from GitHub")
+	}
+
+	st.current, err = storageToSnapshot(snapshots[0])
+	if err != nil {
+		return fmt.Errorf("loading cached snapshot: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(a.stdout, "Using cached data from %s (may be stale)\n", st.current.CapturedAt.Format("2006-01-02 15:04:05"))
+	if a.cfg.Verbose {
+		_, _ = fmt.Fprintf(a.stdout, "Loaded cached activity for %d users\n", len(st.current.Users))
+	}
+
+	// Compare with an empty snapshot so the report shows everything cached
+	st.previous = diff.NewSnapshot(time.Time{})
+	st.mode = report.ModeCached
+	return nil
+}
+
+// historicalAcquirer compares the snapshot from -report-since with the
+// most recent one (with -offline) or with fresh data, which isn't saved.
+type historicalAcquirer struct{ *pipeline }
+
+func (a historicalAcquirer) acquire(ctx context.Context, st *runState) error {
+	sinceDate, err := parseSinceDate(a.cfg.ReportSince, a.deps.Now())
+	if err != nil {
+		return fmt.Errorf("parsing --report-since date: %w", err)
+	}
+	if a.cfg.Verbose {
+		_, _ = fmt.Fprintf(a.stdout, "Historical mode: comparing data from %s to present\n", sinceDate.Format("2006-01-02"))
+	}
+
+	sinceSnapshots, err := a.store.GetByTimeRange(runSnapshotID(a.cfg), sinceDate.Add(-24*time.Hour), sinceDate.Add(24*time.Hour))
+	if err != nil {
+		return fmt.Errorf("querying snapshots for --report-since date: %w", err)
+	}
+	if len(sinceSnapshots) == 0 {
+		return fmt.Errorf("no cached snapshot found for date %s (try running without --report-since first to build cache)", sinceDate.Format("2006-01-02"))
+	}
+	st.previous, err = storageToSnapshot(sinceSnapshots[0])
+	if err != nil {
+		return fmt.Errorf("loading historical snapshot: %w", err)
+	}
+
+	st.mode = report.ModeHistorical
+	if !a.cfg.Offline {
+		return a.fetchCurrent(ctx, st, nil, " (or use --offline)")
+	}
+
+	var recent []*storage.Snapshot
+	recent, err = a.store.GetByUser(runSnapshotID(a.cfg), 1)
+	if err != nil {
+		return fmt.Errorf("loading most recent snapshot: %w", err)
+	}
+	if len(recent) == 0 {
+		return errors.New("no cached snapshots available (run without --offline first)")
+	}
+	st.current, err = storageToSnapshot(recent[0])
+	if err != nil {
+		return fmt.Errorf("loading current snapshot: %w", err)
+	}
+	if a.cfg.Verbose {
+		_, _ = fmt.Fprintf(a.stdout, "Using cached snapshot from %s\n", st.current.CapturedAt.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// fetchCurrent fetches current activity from GitHub into st, from every
+// -source account if there are several. What's skipped to save API budget
+// is kept from previous, if there is one. hint is added to the error when
+// there's no way to authenticate.
+func (p *pipeline) fetchCurrent(ctx context.Context, st *runState, previous *diff.Snapshot, hint string) error {
+	plan, err := resolveFetchPlan(p.cfg)
+	if err != nil {
+		return fmt.Errorf("%w%s", err, hint)
+	}
+	plan.Previous = previous
+	warnAnonymous(p.stderr, p.cfg)
+
+	var snapshot *diff.Snapshot
+	var warnings []FetchWarning
+	var requests int
+	cutoff := p.deps.Now().AddDate(0, 0, -p.cfg.Days)
+	if len(p.cfg.Sources) > 0 {
+		snapshot, warnings, requests, err = fetchSources(ctx, p.cfg, p.deps, previous, p.deps.Now(), cutoff, p.stdout, p.stderr)
+	} else {
+		client := newGitHubClient(p.cfg, p.deps, p.cfg.Token)
+		snapshot, warnings, err = fetchActivityWithPlan(ctx, client, plan, p.deps.Now(), cutoff, p.stdout, p.stderr, p.cfg.Verbose)
+		if err == nil && p.cfg.Org != "" {
+			if err := fetchTeams(ctx, client, p.cfg.Org, snapshot); err != nil {
+				_, _ = fmt.Fprintf(p.stderr, "Warning: could not fetch %s's teams, so the report won't group by team: %v\n", p.cfg.Org, explainTimeout(err, p.cfg.Timeout))
+			}
+		}
+		// Your own stars are only yours when the token's account is the one
+		// whose follows are tracked
+		if err == nil && p.cfg.Token != "" && plan.FollowedBy == "" {
+			if stars, starErr := source.OwnStars(ctx, client); starErr != nil {
+				_, _ = fmt.Fprintf(p.stderr, "Warning: could not fetch your starred repos, so the report won't list them: %v\n", explainTimeout(starErr, p.cfg.Timeout))
+			} else {
+				snapshot.Starred = stars
+			}
+		}
+		if err == nil && p.cfg.Verbose {
+			printRequestStats(p.stdout, client)
+		}
+		requests = requestCount(client)
+	}
+	if err != nil {
+		return fmt.Errorf("fetching activity: %w", explainTimeout(err, p.cfg.Timeout))
+	}
+	if len(p.cfg.Watch) > 0 {
+		fetchWatched(ctx, newGitHubClient(p.cfg, p.deps, watchToken(p.cfg)), p.cfg.Watch, previous, snapshot, cutoff, p.stderr)
+	}
+
+	if err := applyErrorPolicy(p.cfg.OnError, snapshot, previous, warnings); err != nil {
+		return fmt.Errorf("fetching activity: %w", err)
+	}
+	printFetchWarnings(p.stderr, warnings)
+	if p.cfg.Verbose {
+		_, _ = fmt.Fprintf(p.stdout, "Fetched activity for %d users\n", len(snapshot.Users))
+	}
+	st.current, st.warnings = snapshot, warnings
+	st.lookbackDays, st.apiRequests = p.cfg.Days, requests
+	return nil
+}
+
+// diff compares the previous snapshot with the current one.
+func (p *pipeline) diff(ctx context.Context, st *runState) error {
+	if p.cfg.Verbose {
+		_, _ = fmt.Fprintf(p.stdout, "Previous snapshot has %d users, current snapshot has %d users\n",
+			len(st.previous.Users), len(st.current.Users))
+	}
+	opts := diff.Options{MatchByID: true}
+	// Snapshots hold a whole lookback's worth of activity, so drop what
+	// happened before the since date
+	if p.cfg.ReportSince != "" {
+		filterDate, err := parseSinceDate(p.cfg.ReportSince, p.deps.Now())
+		if err != nil {
+			return fmt.Errorf("parsing --report-since date for filtering: %w", err)
+		}
+		opts.Since = filterDate
+		if p.cfg.Verbose {
+			_, _ = fmt.Fprintf(p.stdout, "Filtered results to only show activity from %s onwards\n", filterDate.Format("2006-01-02"))
+		}
+	}
+	st.result = diff.CompareWithOptions(st.previous, st.current, opts)
+	st.periodStart = st.previous.CapturedAt
+	st.finishDigest = func() {}
+	return nil
+}
+
+// filter narrows the changes to what this run should report. Alert rules
+// fire here, ahead of and separate from the digest; with a digest schedule,
+// runs between digests stop once they've queued their changes.
+func (p *pipeline) filter(ctx context.Context, st *runState) error {
+	result := st.result
+	if p.cfg.Verbose {
+		_, _ = fmt.Fprintf(p.stdout, "Diff result: NewStars=%d, NewRepos=%d, NewEvents=%d, NewUsers=%d, GoneUsers=%d\n",
+			len(result.NewStars), len(result.NewRepos), len(result.NewEvents), len(result.NewUsers), len(result.GoneUsers))
+	}
+
+	if p.cfg.ReportSince == "" && !p.cfg.Offline {
+		if err := fireAlerts(ctx, p.cfg, p.deps, result, p.stdout, p.stderr); err != nil {
+			_, _ = fmt.Fprintf(p.stderr, "Warning: could not evaluate alert rules: %v\n", err)
+		}
+	}
+
+	if p.cfg.DigestAt != "" && p.cfg.ReportSince == "" && !p.cfg.Offline {
+		digest, done, err := queueForDigest(p.store, result, p.cfg.DigestAt, p.deps.Now())
+		if err != nil {
+			return fmt.Errorf("queueing changes for the digest: %w", err)
+		}
+		if digest == nil {
+			if !result.IsEmpty() {
+				_, _ = fmt.Fprintf(p.stdout, "Queued %d changes for the %s digest.\n", countChanges(result), p.cfg.DigestAt)
+			} else {
+				_, _ = fmt.Fprintln(p.stdout, "No new activity detected.")
+			}
+			return errDone
+		}
+		st.result = digest
+		st.periodStart = digest.OldCapturedAt
+		st.finishDigest = func() {
+			if err := done(); err != nil {
+				_, _ = fmt.Fprintf(p.stderr, "Warning: could not clear the digest queue: %v\n", err)
+			}
+		}
+		if p.cfg.Verbose {
+			_, _ = fmt.Fprintf(p.stdout, "Digest due: %d changes since %s\n", countChanges(st.result), st.periodStart.Format("2006-01-02 15:04"))
+		}
+	}
+
+	// Overlapping syncs see the same activity more than once; leave out
+	// what an earlier report already held
+	if p.cfg.ReportSince == "" && !p.cfg.Offline {
+		before := countChanges(st.result)
+		unreported, err := dropReported(p.store, st.result)
+		if err != nil {
+			_, _ = fmt.Fprintf(p.stderr, "Warning: could not check for already reported changes: %v\n", err)
+		} else {
+			st.result = unreported
+			st.dedup = true
+		}
+		if p.cfg.Verbose && before > countChanges(st.result) {
+			_, _ = fmt.Fprintf(p.stdout, "Left out %d changes already reported\n", before-countChanges(st.result))
+		}
+	}
+
+	if st.result.IsEmpty() {
+		st.finishDigest()
+		_, _ = fmt.Fprintln(p.stdout, "No new activity detected.")
+		return errDone
+	}
+	return nil
+}
+
+// render builds the report and writes it in -format, using -template if
+// given, to -report or to -report-name in -report-dir.
+func (p *pipeline) render(ctx context.Context, st *runState) error {
+	// Don't start on the report once out of time
+	if err := ctx.Err(); err != nil {
+		return explainTimeout(err, p.cfg.Timeout)
+	}
+
+	opts := report.Options{
+		GeneratedAt:          p.deps.Now(),
+		PeriodStart:          st.periodStart,
+		PeriodEnd:            st.current.CapturedAt,
+		WebURL:               p.cfg.GitHubURL,
+		HideBranches:         p.cfg.HideBranches,
+		MinStars:             p.cfg.MinStars,
+		HideUndescribedStars: p.cfg.StarsNeedDesc,
+	}
+	if p.cfg.Verbose {
+		opts.Log = p.stderr
+	}
+	rpt := report.FromDiff(st.result, opts)
+	if len(p.cfg.Sources) > 1 {
+		rpt.SetUserSources(userSources(st.current))
+	}
+	rpt.SetProfiles(userProfiles(st.current))
+	if tagSet, err := loadTags(p.cfg.TagsPath); err != nil {
+		_, _ = fmt.Fprintf(p.stderr, "Warning: could not tag activities: %v\n", err)
+	} else {
+		tagReport(rpt, tagSet, st.current, st.result)
+	}
+	if err := applyPreset(rpt, p.cfg, st.current, st.result); err != nil {
+		return err
+	}
+	// Kept before any merge below, so the notification is about this run
+	st.report = rpt
+
+	path, err := reportPath(p.cfg, p.deps.Now())
+	if err != nil {
+		return err
+	}
+	st.reportPath = path
+
+	generator, err := p.deps.ReportGenerator(p.cfg.Format)
+	if err != nil {
+		return fmt.Errorf("creating report generator: %w", err)
+	}
+	useClock(generator, p.deps.Now)
+	if p.cfg.Template != "" {
+		if err := applyTemplate(generator, p.cfg.Template); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(st.reportPath), 0750); err != nil {
+		return fmt.Errorf("creating report directory: %w", err)
+	}
+
+	// In append mode, fold this run's activity into the report an earlier
+	// run left at the same path
+	if p.cfg.Append {
+		rpt, st.appended, err = mergeExistingReport(st.reportPath, rpt)
+		if err != nil {
+			return fmt.Errorf("loading existing report: %w", err)
+		}
+		rpt.RefreshInterval = appendReportRefresh
+		rpt.UpdateNotice = p.releaseNotice
+		rpt.Categories = report.CategoryLayout{Order: p.cfg.CategoryOrder, Hidden: p.cfg.HideCategories}
+		rpt.Aggregation = p.cfg.Aggregate.rules()
+		rpt.Pinned = p.cfg.Pin
+		rpt.Noise = p.cfg.noiseRules()
+		rpt.Teams = st.current.Teams
+		rpt.Provenance = st.provenance()
+		if err := writeFileAtomic(reportDataPath(st.reportPath), rpt.WriteJSON); err != nil {
+			return fmt.Errorf("saving report data: %w", err)
+		}
+		if err := writeFileAtomic(st.reportPath, func(w io.Writer) error { return generator.Generate(w, rpt) }); err != nil {
+			return fmt.Errorf("generating report: %w", err)
+		}
+	} else {
+		rpt.UpdateNotice = p.releaseNotice
+		rpt.Categories = report.CategoryLayout{Order: p.cfg.CategoryOrder, Hidden: p.cfg.HideCategories}
+		rpt.Aggregation = p.cfg.Aggregate.rules()
+		rpt.Pinned = p.cfg.Pin
+		rpt.Noise = p.cfg.noiseRules()
+		rpt.Teams = st.current.Teams
+		rpt.Provenance = st.provenance()
+		f, err := os.Create(st.reportPath) // #nosec G304 -- reportPath is user-specified via flag or safe default
+		if err != nil {
+			return fmt.Errorf("creating report file: %w", err)
+		}
+		if err := generator.Generate(f, rpt); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("generating report: %w", err)
+		}
+		_ = f.Close()
+	}
+
+	_, _ = fmt.Fprintf(p.stdout, "Report written to %s\n", st.reportPath)
+
+	if p.cfg.FeedPath != "" {
+		if err := updateFeed(p.cfg.FeedPath, st.report); err != nil {
+			return fmt.Errorf("updating feed: %w", err)
+		}
+	}
+	return nil
+}
+
+// provenance describes how st's data was gathered, for the report footer.
+func (st *runState) provenance() *report.Provenance {
+	prov := &report.Provenance{
+		Mode:         st.mode,
+		From:         st.previous.CapturedAt,
+		To:           st.current.CapturedAt,
+		LookbackDays: st.lookbackDays,
+		APIRequests:  st.apiRequests,
+	}
+	for _, fw := range st.warnings {
+		prov.Gaps = append(prov.Gaps, endpointNames[fw.Endpoint]+" for "+fw.User)
+	}
+	return prov
+}
+
+// applyTemplate swaps generator's template for the one in the file at
+// path.
+func applyTemplate(generator ReportGenerator, path string) error {
+	tg, ok := generator.(templatedGenerator)
+	if !ok {
+		return errors.New("-template needs a report format with templates, like html")
+	}
+	text, err := os.ReadFile(path) // #nosec G304 -- path is user-specified via flag
+	if err != nil {
+		return fmt.Errorf("reading template: %w", err)
+	}
+	if err := tg.SetTemplate(string(text)); err != nil {
+		return fmt.Errorf("template %s: %w", path, err)
+	}
+	return nil
+}
+
+// deliver notifies about the report and opens it. Failures here are
+// warnings: the report is already written.
+func (p *pipeline) deliver(ctx context.Context, st *runState) error {
+	notifier, err := selectNotifier(p.cfg, p.deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(p.stderr, "Warning: could not send notification: %v\n", err)
+	}
+	if notifier != nil {
+		n := reportNotification(st.result, st.report, st.reportPath)
+		if html, text, err := reportEmail(p.cfg, st.report); err != nil {
+			_, _ = fmt.Fprintf(p.stderr, "Warning: %v, so the email only summarizes the report\n", err)
+		} else {
+			n.HTML, n.Text = html, text
+		}
+		if err := notify.SendContext(ctx, notifier, n); err != nil {
+			_, _ = fmt.Fprintf(p.stderr, "Warning: could not send notification: %v\n", explainTimeout(err, p.cfg.Timeout))
+		}
+	}
+
+	// An appended report is likely already open, and reloads itself
+	if !p.cfg.NoOpen && !st.appended {
+		if err := p.deps.OpenBrowser("file://" + st.reportPath); err != nil {
+			_, _ = fmt.Fprintf(p.stderr, "Warning: could not open browser: %v\n", err)
+		}
+	}
+
+	if st.dedup {
+		now := p.deps.Now()
+		if err := markReported(p.store, st.result, now, now.AddDate(0, 0, -p.cfg.Days)); err != nil {
+			_, _ = fmt.Fprintf(p.stderr, "Warning: could not record reported changes: %v\n", err)
+		}
+	}
+	st.finishDigest()
+	return nil
+}
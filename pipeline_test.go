@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/github/githubtest"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestRunStages(t *testing.T) {
+	errBoom := errors.New("boom")
+	var ran []string
+	record := func(name string, err error) stage {
+		return stage{name: name, run: func(ctx context.Context, st *runState) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+
+	tests := []struct {
+		wantErr error
+		name    string
+		stages  []stage
+		wantRan []string
+	}{
+		{
+			name:    "all stages run in order",
+			stages:  []stage{record("a", nil), record("b", nil), record("c", nil)},
+			wantRan: []string{"a", "b", "c"},
+		},
+		{
+			name:    "errDone stops without an error",
+			stages:  []stage{record("a", errDone), record("b", nil)},
+			wantRan: []string{"a"},
+		},
+		{
+			name:    "an error stops the run",
+			stages:  []stage{record("a", nil), record("b", errBoom), record("c", nil)},
+			wantRan: []string{"a", "b"},
+			wantErr: errBoom,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran = nil
+			err := runStages(context.Background(), tt.stages, &runState{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("runStages() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ran, tt.wantRan) {
+				t.Errorf("ran %v, want %v", ran, tt.wantRan)
+			}
+		})
+	}
+}
+
+func TestPipelineAcquirer(t *testing.T) {
+	tests := []struct {
+		want acquirer
+		cfg  Config
+	}{
+		{cfg: Config{}, want: liveAcquirer{}},
+		{cfg: Config{Offline: true}, want: offlineAcquirer{}},
+		{cfg: Config{ReportSince: "7d"}, want: historicalAcquirer{}},
+		{cfg: Config{ReportSince: "7d", Offline: true}, want: historicalAcquirer{}},
+	}
+
+	for _, tt := range tests {
+		p := &pipeline{cfg: &tt.cfg}
+		if got := p.acquirer(); reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+			t.Errorf("acquirer() for %+v = %T, want %T", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestPipeline_DiffAndFilterAlone(t *testing.T) {
+	setHome(t)
+	var stdout, stderr bytes.Buffer
+	p := &pipeline{
+		cfg:    &Config{Offline: true},
+		deps:   &Dependencies{Now: fixedTime},
+		stdout: &stdout,
+		stderr: &stderr,
+	}
+
+	previous := diff.NewSnapshot(fixedTime().AddDate(0, 0, -1))
+	current := diff.NewSnapshot(fixedTime())
+	current.Users["alice"] = diff.UserActivity{
+		Username:     "alice",
+		StarredRepos: []diff.Repo{{Owner: "owner", Name: "repo", CreatedAt: fixedTime()}},
+	}
+
+	// Stages run without the ones before and after them, given the state
+	// they'd have left
+	st := &runState{previous: previous, current: current}
+	if err := runStages(context.Background(), []stage{{name: "diff", run: p.diff}, {name: "filter", run: p.filter}}, st); err != nil {
+		t.Fatalf("runStages() error = %v, stderr: %s", err, stderr.String())
+	}
+	if st.result == nil || len(st.result.NewStars) != 1 {
+		t.Fatalf("expected one new star, got %+v", st.result)
+	}
+	if !st.periodStart.Equal(previous.CapturedAt) {
+		t.Errorf("periodStart = %v, want %v", st.periodStart, previous.CapturedAt)
+	}
+
+	// Nothing new ends the run early
+	st = &runState{previous: current, current: current}
+	err := runStages(context.Background(), []stage{{name: "diff", run: p.diff}, {name: "filter", run: p.filter}, {name: "render", run: func(context.Context, *runState) error {
+		t.Error("expected render not to run")
+		return nil
+	}}}, st)
+	if err != nil {
+		t.Fatalf("runStages() error = %v", err)
+	}
+	if !bytes.Contains(stdout.Bytes(), []byte("No new activity detected.")) {
+		t.Errorf("expected no new activity, got: %s", stdout.String())
+	}
+}
+
+func TestPipeline_YouStarred(t *testing.T) {
+	srv := githubtest.NewServer(t)
+	srv.Follow("alice")
+	srv.StarOwn(github.Repository{ID: 1, Name: "go", FullName: "golang/go", Owner: github.User{Login: "golang"}})
+
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return srv.Client(token) },
+		ReportGenerator:     func(format string) (ReportGenerator, error) { return report.New(format) },
+		Now:                 fixedTime,
+	}
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	store := storage.NewMemoryStore()
+	run := func() *report.Report {
+		t.Helper()
+		_ = os.Remove(reportPath)
+		var stdout, stderr bytes.Buffer
+		cfg := &Config{Token: "test-token", Format: report.FormatJSON, ReportPath: reportPath, Days: 30, Concurrency: 1, NoOpen: true, NoNotify: true}
+		p := &pipeline{cfg: cfg, deps: deps, store: store, stdout: &stdout, stderr: &stderr}
+		if err := runStages(context.Background(), p.stages(), &runState{}); err != nil {
+			t.Fatalf("run failed: %v\nstderr: %s", err, stderr.String())
+		}
+		data, err := os.ReadFile(reportPath) // #nosec G304 -- test temp dir
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rpt, err := report.ReadJSON(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rpt
+	}
+
+	// The first run has no starred list to compare with
+	if rpt := run(); rpt != nil && len(rpt.YouStarred) != 0 {
+		t.Errorf("first run's YouStarred = %+v, want none", rpt.YouStarred)
+	}
+
+	srv.StarOwn(github.Repository{ID: 2, Name: "rust", FullName: "rust-lang/rust", Owner: github.User{Login: "rust-lang"}})
+	rpt := run()
+	if rpt == nil || len(rpt.YouStarred) != 1 || rpt.YouStarred[0].RepoName != "rust-lang/rust" {
+		t.Fatalf("second run's report = %+v, want your new star", rpt)
+	}
+}
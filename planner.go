@@ -0,0 +1,121 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/source"
+)
+
+// etagAwareClient is implemented by clients that cache listings by ETag and
+// can tell which a repeat request would likely get a free 304 for. Other
+// clients are assumed to have nothing cached.
+type etagAwareClient interface {
+	StarredCached(username string) bool
+	OwnedCached(username string) bool
+}
+
+// userPlanner is the per-user side of a fetchPlan, from planFetches.
+type userPlanner struct {
+	previous map[string]diff.UserActivity
+	skip     map[string]map[string]bool // Endpoints not worth calling, by user
+}
+
+// Plan implements source.Planner.
+func (p userPlanner) Plan(subject source.Subject) source.Plan {
+	return source.Plan{Skip: p.skip[subject.Name], Previous: p.previous[subject.Name]}
+}
+
+// planFetches decides which of plan's endpoints are worth calling for each
+// subject. Events, discussions, profiles and following lists are fetched
+// for everyone the plan has them for: events are how most activity shows up
+// at all. Starred and owned repos always are for users the last snapshot
+// doesn't have and for listings with a cached ETag, which cost nothing if
+// they haven't changed; the rest go to the most recently active users
+// first, while plan.Budget lasts. Skipped listings keep the last snapshot's
+// copy.
+func planFetches(client GitHubClient, plan fetchPlan, subjects []source.Subject) userPlanner {
+	if plan.Budget == 0 || plan.Previous == nil {
+		return userPlanner{}
+	}
+	planner := userPlanner{previous: plan.Previous.Users, skip: make(map[string]map[string]bool)}
+
+	// A request per page; adaptToBudget only sets a budget along with
+	// capping pagination at a page
+	spent := 0
+	for _, enabled := range []bool{plan.Events, plan.Profiles, plan.Following} {
+		if enabled {
+			spent += len(subjects)
+		}
+	}
+
+	var endpoints []string
+	if plan.Starred {
+		endpoints = append(endpoints, source.EndpointStarred)
+	}
+	if plan.Owned {
+		endpoints = append(endpoints, source.EndpointOwned)
+	}
+	type listing struct {
+		lastActive time.Time
+		user       string
+		endpoint   string
+	}
+	var optional []listing
+	for _, s := range subjects {
+		prev, known := plan.Previous.Users[s.Name]
+		for _, endpoint := range endpoints {
+			switch {
+			case !known:
+				spent++
+			case etagCached(client, s.Name, endpoint):
+				// Most likely a free 304
+			default:
+				optional = append(optional, listing{lastActive: lastActive(prev), user: s.Name, endpoint: endpoint})
+			}
+		}
+	}
+
+	slices.SortStableFunc(optional, func(a, b listing) int {
+		return cmp.Or(b.lastActive.Compare(a.lastActive), strings.Compare(a.user, b.user))
+	})
+	for _, l := range optional {
+		if spent < plan.Budget {
+			spent++
+			continue
+		}
+		if planner.skip[l.user] == nil {
+			planner.skip[l.user] = make(map[string]bool)
+		}
+		planner.skip[l.user][l.endpoint] = true
+	}
+	return planner
+}
+
+// etagCached reports whether client has an ETag cached for user's listing
+// at endpoint.
+func etagCached(client GitHubClient, user, endpoint string) bool {
+	c, ok := client.(etagAwareClient)
+	if !ok {
+		return false
+	}
+	if endpoint == source.EndpointStarred {
+		return c.StarredCached(user)
+	}
+	return c.OwnedCached(user)
+}
+
+// lastActive returns when ua's latest event happened, or the zero time if
+// it has none.
+func lastActive(ua diff.UserActivity) time.Time {
+	var latest time.Time
+	for _, e := range ua.Events {
+		if e.CreatedAt.After(latest) {
+			latest = e.CreatedAt
+		}
+	}
+	return latest
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/source"
+)
+
+// etagGitHubClient reports the listings in cached as having ETags.
+type etagGitHubClient struct {
+	*mockGitHubClient
+	cached map[string]bool // "user/endpoint"
+}
+
+func (e *etagGitHubClient) StarredCached(username string) bool {
+	return e.cached[username+"/"+source.EndpointStarred]
+}
+
+func (e *etagGitHubClient) OwnedCached(username string) bool {
+	return e.cached[username+"/"+source.EndpointOwned]
+}
+
+func TestPlanFetches(t *testing.T) {
+	now := fixedTime()
+	activeAt := func(ago time.Duration) diff.UserActivity {
+		return diff.UserActivity{Events: []diff.Event{{Type: "PushEvent", CreatedAt: now.Add(-ago)}}}
+	}
+	previous := diff.NewSnapshot(now.Add(-24 * time.Hour))
+	previous.Users["busy"] = activeAt(time.Hour)
+	previous.Users["quiet"] = activeAt(30 * 24 * time.Hour)
+	previous.Users["idle"] = diff.UserActivity{}
+	var subjects []source.Subject
+	for _, name := range []string{"idle", "quiet", "busy", "newcomer"} {
+		subjects = append(subjects, source.Subject{Name: name})
+	}
+	plan := fetchPlan{Starred: true, Owned: true, Events: true, Previous: previous}
+
+	tests := []struct {
+		name   string
+		budget int
+		cached map[string]bool
+		want   map[string]map[string]bool // Skipped endpoints by user
+	}{
+		{
+			name:   "no budget to count skips nothing",
+			budget: 0,
+			want:   map[string]map[string]bool{},
+		},
+		{
+			name:   "enough for everything skips nothing",
+			budget: 100,
+			want:   map[string]map[string]bool{},
+		},
+		{
+			// Events for 4 and the newcomer's 2 listings leave 2 for the
+			// most recently active
+			name:   "the least recently active go without",
+			budget: 8,
+			want: map[string]map[string]bool{
+				"quiet": {source.EndpointStarred: true, source.EndpointOwned: true},
+				"idle":  {source.EndpointStarred: true, source.EndpointOwned: true},
+			},
+		},
+		{
+			name:   "cached listings are fetched for free",
+			budget: 8,
+			cached: map[string]bool{"idle/" + source.EndpointOwned: true, "busy/" + source.EndpointStarred: true},
+			want: map[string]map[string]bool{
+				"quiet": {source.EndpointOwned: true},
+				"idle":  {source.EndpointStarred: true},
+			},
+		},
+		{
+			name:   "new users are always fetched",
+			budget: 1,
+			want: map[string]map[string]bool{
+				"busy":  {source.EndpointStarred: true, source.EndpointOwned: true},
+				"quiet": {source.EndpointStarred: true, source.EndpointOwned: true},
+				"idle":  {source.EndpointStarred: true, source.EndpointOwned: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &etagGitHubClient{mockGitHubClient: &mockGitHubClient{}, cached: tt.cached}
+			plan := plan
+			plan.Budget = tt.budget
+
+			planner := planFetches(client, plan, subjects)
+
+			for _, s := range subjects {
+				got := planner.Plan(s).Skip
+				if want := tt.want[s.Name]; !maps.Equal(got, want) {
+					t.Errorf("%s skips %v, want %v", s.Name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanFetchesKeepsPrevious(t *testing.T) {
+	previous := diff.NewSnapshot(fixedTime())
+	previous.Users["alice"] = diff.UserActivity{Username: "alice", StarredRepos: []diff.Repo{{Owner: "a", Name: "b"}}}
+	plan := fetchPlan{Starred: true, Events: true, Previous: previous, Budget: 1}
+
+	got := planFetches(&mockGitHubClient{}, plan, []source.Subject{{Name: "alice"}}).Plan(source.Subject{Name: "alice"})
+	if !got.Skip[source.EndpointStarred] {
+		t.Fatalf("expected alice's starred repos to be skipped, got %v", got.Skip)
+	}
+	if len(got.Previous.StarredRepos) != 1 {
+		t.Errorf("expected alice's previous activity to be kept, got %+v", got.Previous)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+const defaultPresetsName = "presets.json"
+
+// filterFields are what a filter term can match an activity on.
+var filterFields = []string{"language", "topic", "tag", "user", "repo"}
+
+// activityFilter narrows a report to the activities matching every one of
+// its terms.
+type activityFilter []filterTerm
+
+// filterTerm matches activities whose field is any of its values, compared
+// case-insensitively.
+type filterTerm struct {
+	field  string
+	values []string
+}
+
+// parseFilter parses a preset's filter: terms like language:Go or
+// user:alice,bob, separated by spaces and optionally joined with +, as in
+// "language:Go + tag:coworkers".
+func parseFilter(s string) (activityFilter, error) {
+	var f activityFilter
+	for _, word := range strings.Fields(s) {
+		if word == "+" {
+			continue
+		}
+		field, value, ok := strings.Cut(word, ":")
+		field = strings.ToLower(field)
+		if !ok || !slices.Contains(filterFields, field) {
+			return nil, fmt.Errorf("%q should look like field:value, where field is one of %s", word, strings.Join(filterFields, ", "))
+		}
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%q has no value to match", word)
+		}
+		f = append(f, filterTerm{field: field, values: values})
+	}
+	if len(f) == 0 {
+		return nil, errors.New("filter has no terms")
+	}
+	return f, nil
+}
+
+// matches reports whether a, on repo, matches every term of f.
+func (f activityFilter) matches(a report.Activity, repo diff.Repo) bool {
+	for _, t := range f {
+		var ok bool
+		switch t.field {
+		case "language":
+			ok = t.matchesAny([]string{repo.Language})
+		case "topic":
+			ok = t.matchesAny(repo.Topics)
+		case "tag":
+			ok = t.matchesAny(a.Tags)
+		case "user":
+			ok = t.matchesAny([]string{a.User})
+		case "repo":
+			owner, _, _ := strings.Cut(a.RepoName, "/")
+			ok = t.matchesAny([]string{a.RepoName, owner + "/*"})
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAny reports whether any of have is one of t's values.
+func (t filterTerm) matchesAny(have []string) bool {
+	for _, h := range have {
+		if h != "" && slices.ContainsFunc(t.values, func(v string) bool { return strings.EqualFold(v, h) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPresets loads the filter presets at path, or from the config
+// directory if path is empty. The file is a JSON object of filters by
+// preset name. A missing default presets file means there are no presets.
+func loadPresets(path string) (map[string]activityFilter, error) {
+	explicit := path != ""
+	if !explicit {
+		dir, err := configDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, defaultPresetsName)
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- presets path is user-specified or a fixed default
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading presets: %w", err)
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("loading presets: %s: %w", path, err)
+	}
+
+	presets := make(map[string]activityFilter, len(raw))
+	var errs []error
+	for name, s := range raw {
+		f, err := parseFilter(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("preset %q: %w", name, err))
+			continue
+		}
+		presets[name] = f
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("loading presets: %s: %w", path, err)
+	}
+	return presets, nil
+}
+
+// preset returns the filter -preset names, or nil if it's not set.
+func (cfg *Config) preset() (activityFilter, error) {
+	if cfg.Preset == "" {
+		return nil, nil
+	}
+	presets, err := loadPresets(cfg.PresetsPath)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := presets[cfg.Preset]
+	if !ok {
+		if len(presets) == 0 {
+			return nil, fmt.Errorf("preset %q isn't defined: there's no presets file", cfg.Preset)
+		}
+		names := make([]string, 0, len(presets))
+		for name := range presets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("preset %q isn't defined; the presets are %s", cfg.Preset, strings.Join(names, ", "))
+	}
+	return f, nil
+}
+
+// applyPreset narrows rpt to the activities matching cfg's preset, if it
+// has one. It runs after tagging, so tag terms see the activities' tags.
+// Users left without activities drop out of the report.
+func applyPreset(rpt *report.Report, cfg *Config, snapshot *diff.Snapshot, result *diff.Result) error {
+	f, err := cfg.preset()
+	if err != nil || f == nil {
+		return err
+	}
+	repos := knownRepos(snapshot, result)
+	kept := rpt.UserActivities[:0]
+	for _, ua := range rpt.UserActivities {
+		ua.Activities = slices.DeleteFunc(ua.Activities, func(a report.Activity) bool {
+			return !f.matches(a, repos[a.RepoName])
+		})
+		if len(ua.Activities) > 0 {
+			kept = append(kept, ua)
+		}
+	}
+	rpt.UserActivities = kept
+	return nil
+}
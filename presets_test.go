@@ -0,0 +1,146 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+func TestParseFilter(t *testing.T) {
+	f, err := parseFilter("language:Go + tag:coworkers user:alice,bob")
+	if err != nil {
+		t.Fatalf("parseFilter() error = %v", err)
+	}
+	want := activityFilter{
+		{field: "language", values: []string{"Go"}},
+		{field: "tag", values: []string{"coworkers"}},
+		{field: "user", values: []string{"alice", "bob"}},
+	}
+	if len(f) != len(want) {
+		t.Fatalf("parseFilter() = %+v, want %+v", f, want)
+	}
+	for i := range want {
+		if f[i].field != want[i].field || strings.Join(f[i].values, ",") != strings.Join(want[i].values, ",") {
+			t.Errorf("term %d = %+v, want %+v", i, f[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"", "+", "Go", "stars:10", "language:", "topic:,"} {
+		if _, err := parseFilter(bad); err == nil {
+			t.Errorf("parseFilter(%q) expected an error", bad)
+		}
+	}
+}
+
+func TestActivityFilter_Matches(t *testing.T) {
+	goRepo := diff.Repo{Owner: "golang", Name: "go", Language: "Go", Topics: []string{"compiler"}}
+	a := report.Activity{User: "alice", RepoName: "golang/go", Tags: []string{"coworkers"}}
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"language:go", true},
+		{"language:Rust,Go", true},
+		{"language:Rust", false},
+		{"topic:compiler", true},
+		{"topic:gamedev", false},
+		{"tag:Coworkers", true},
+		{"user:bob", false},
+		{"repo:golang/*", true},
+		{"repo:golang/tools", false},
+		{"language:Go + tag:coworkers", true},
+		{"language:Go + user:bob", false},
+	}
+	for _, tt := range tests {
+		f, err := parseFilter(tt.filter)
+		if err != nil {
+			t.Fatalf("parseFilter(%q) error = %v", tt.filter, err)
+		}
+		if got := f.matches(a, goRepo); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	// Nothing is known about a repo seen only in events
+	f, _ := parseFilter("language:Go")
+	if f.matches(a, diff.Repo{}) {
+		t.Error("expected a repo of unknown language not to match a language")
+	}
+}
+
+func TestLoadPresets(t *testing.T) {
+	home := setHome(t)
+	if loaded, err := loadPresets(""); err != nil || loaded != nil {
+		t.Errorf("loadPresets() without a presets file = %v, %v; want none", loaded, err)
+	}
+	if _, err := loadPresets(filepath.Join(home, "missing.json")); err == nil {
+		t.Error("expected an error for a missing presets file given explicitly")
+	}
+
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultPresetsName), `{"work": "language:Go + tag:coworkers", "weekend": "topic:gamedev"}`)
+	loaded, err := loadPresets("")
+	if err != nil || len(loaded) != 2 || len(loaded["work"]) != 2 {
+		t.Errorf("loadPresets() = %+v, %v; want the default file's presets", loaded, err)
+	}
+
+	bad := filepath.Join(home, "bad.json")
+	writeFile(t, bad, `{"one": "stars:5", "two": "", "ok": "user:alice"}`)
+	_, err = loadPresets(bad)
+	if err == nil || !strings.Contains(err.Error(), `preset "one"`) || !strings.Contains(err.Error(), `preset "two"`) {
+		t.Errorf("expected every invalid preset to be reported, got %v", err)
+	}
+}
+
+func TestConfigPreset(t *testing.T) {
+	home := setHome(t)
+	cfg := &Config{Preset: "work"}
+	if _, err := cfg.preset(); err == nil || !strings.Contains(err.Error(), "no presets file") {
+		t.Errorf("expected an error for a preset without a presets file, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultPresetsName), `{"weekend": "topic:gamedev", "evenings": "user:bob"}`)
+	if _, err := cfg.preset(); err == nil || !strings.Contains(err.Error(), "evenings, weekend") {
+		t.Errorf("expected an unknown preset's error to list the presets, got %v", err)
+	}
+	cfg.Preset = "weekend"
+	if f, err := cfg.preset(); err != nil || len(f) != 1 {
+		t.Errorf("preset() = %+v, %v; want the weekend preset", f, err)
+	}
+	if f, err := (&Config{}).preset(); err != nil || f != nil {
+		t.Errorf("preset() without -preset = %+v, %v; want none", f, err)
+	}
+}
+
+func TestApplyPreset(t *testing.T) {
+	home := setHome(t)
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultPresetsName), `{"work": "language:Go"}`)
+
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	snapshot := diff.NewSnapshot(now)
+	snapshot.Users["alice"] = diff.UserActivity{
+		Username:   "alice",
+		OwnedRepos: []diff.Repo{{Owner: "alice", Name: "server", Language: "Go"}},
+	}
+	result := &diff.Result{
+		NewStars: []diff.RepoChange{{Username: "bob", Repo: diff.Repo{Owner: "godotengine", Name: "godot", Language: "C++"}}},
+		NewEvents: []diff.EventChange{
+			{Username: "alice", Event: diff.Event{Type: "PushEvent", Actor: "alice", Repo: "alice/server", CreatedAt: now}},
+			{Username: "alice", Event: diff.Event{Type: "PushEvent", Actor: "alice", Repo: "carol/dotfiles", CreatedAt: now}},
+		},
+	}
+	rpt := report.FromDiff(result, report.Options{GeneratedAt: now})
+	if err := applyPreset(rpt, &Config{Preset: "work"}, snapshot, result); err != nil {
+		t.Fatalf("applyPreset() error = %v", err)
+	}
+
+	if len(rpt.UserActivities) != 1 || rpt.UserActivities[0].User != "alice" {
+		t.Fatalf("expected only alice to be left, got %+v", rpt.UserActivities)
+	}
+	if acts := rpt.UserActivities[0].Activities; len(acts) != 1 || acts[0].RepoName != "alice/server" {
+		t.Errorf("expected only the push to the Go repo, got %+v", acts)
+	}
+}
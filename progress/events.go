@@ -0,0 +1,148 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is what a progress Event reports.
+type EventType string
+
+const (
+	EventStart    EventType = "start"    // A sync began
+	EventProgress EventType = "progress" // A step of the sync, e.g. the next user
+	EventWarning  EventType = "warning"  // Something the sync had to skip
+	EventDone     EventType = "done"     // The sync finished, or failed
+)
+
+// Event is a step of a sync, for watching it somewhere other than the
+// terminal.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    EventType `json:"type"`
+	Message string    `json:"message"`
+	Item    string    `json:"item,omitempty"`
+	Current int       `json:"current,omitempty"` // 1-indexed, for EventProgress
+	Total   int       `json:"total,omitempty"`
+	Failed  bool      `json:"failed,omitempty"` // For EventDone
+}
+
+// maxReplayWarnings bounds how many of a sync's warnings are replayed to
+// new subscribers.
+const maxReplayWarnings = 100
+
+// subscriberBuffer is how many events a subscriber can fall behind by
+// before it's dropped.
+const subscriberBuffer = 64
+
+// Broadcaster fans a sync's progress events out to subscribers, such as
+// dashboard tabs. It's safe for concurrent use.
+type Broadcaster struct {
+	now      func() time.Time
+	subs     map[chan Event]struct{}
+	start    *Event
+	latest   *Event // The last EventProgress
+	done     *Event
+	warnings []Event
+	mu       sync.Mutex
+	closed   bool
+}
+
+// NewBroadcaster creates a Broadcaster with no subscribers. Events
+// published without a time are stamped with now, or time.Now if it's nil.
+func NewBroadcaster(now func() time.Time) *Broadcaster {
+	if now == nil {
+		now = time.Now
+	}
+	return &Broadcaster{now: now, subs: make(map[chan Event]struct{})}
+}
+
+// Publish sends e to every subscriber. An EventStart begins a new sync,
+// forgetting the last one. A subscriber that has fallen behind is dropped,
+// its channel closed, rather than holding up the sync; it can subscribe
+// again to catch up.
+func (b *Broadcaster) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = b.now()
+	}
+	switch e.Type {
+	case EventStart:
+		b.start, b.latest, b.done, b.warnings = &e, nil, nil, nil
+	case EventProgress:
+		b.latest = &e
+	case EventWarning:
+		if len(b.warnings) < maxReplayWarnings {
+			b.warnings = append(b.warnings, e)
+		}
+	case EventDone:
+		b.done = &e
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns a channel of progress events: first the running or
+// last sync's so far, with its progress up to the latest step, then those
+// published from now on. The channel is closed once cancel is called, the
+// subscriber falls behind, or b is closed.
+func (b *Broadcaster) Subscribe() (events <-chan Event, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	replay := b.replay()
+	ch := make(chan Event, subscriberBuffer+len(replay))
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	for _, e := range replay {
+		ch <- e
+	}
+	b.subs[ch] = struct{}{}
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// replay returns the events a new subscriber starts with. b.mu must be
+// held.
+func (b *Broadcaster) replay() []Event {
+	if b.start == nil {
+		return nil
+	}
+	events := append([]Event{*b.start}, b.warnings...)
+	if b.latest != nil {
+		events = append(events, *b.latest)
+	}
+	if b.done != nil {
+		events = append(events, *b.done)
+	}
+	return events
+}
+
+// Close closes every subscriber's channel and stops taking new ones, for
+// when the server they're streamed from shuts down.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,106 @@
+package progress
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// drain returns the events waiting on ch.
+func drain(ch <-chan Event) []Event {
+	var events []Event
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestBroadcaster_ReplaysCurrentSync(t *testing.T) {
+	b := NewBroadcaster(nil)
+	b.Publish(Event{Type: EventStart, Message: "old sync"})
+	b.Publish(Event{Type: EventDone, Message: "old done"})
+	b.Publish(Event{Type: EventStart, Message: "syncing"})
+	b.Publish(Event{Type: EventProgress, Current: 1, Total: 3})
+	b.Publish(Event{Type: EventWarning, Message: "could not fetch alice"})
+	b.Publish(Event{Type: EventProgress, Current: 2, Total: 3})
+
+	events, cancel := b.Subscribe()
+	got := drain(events)
+	if len(got) != 3 || got[0].Message != "syncing" || got[1].Type != EventWarning || got[2].Current != 2 {
+		t.Fatalf("replay = %+v, want the start, the warning and the latest step", got)
+	}
+
+	b.Publish(Event{Type: EventDone, Message: "done"})
+	if got := drain(events); len(got) != 1 || got[0].Type != EventDone {
+		t.Errorf("after subscribing got %+v, want the done event", got)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("expected the channel to be closed after cancel")
+	}
+	cancel() // A second cancel is a no-op
+}
+
+func TestBroadcaster_DropsSlowSubscribers(t *testing.T) {
+	b := NewBroadcaster(nil)
+	events, cancel := b.Subscribe()
+	defer cancel()
+	for i := range subscriberBuffer + 1 {
+		b.Publish(Event{Type: EventProgress, Current: i + 1})
+	}
+	got := drain(events)
+	if len(got) != subscriberBuffer {
+		t.Errorf("got %d events, want the %d that fit before being dropped", len(got), subscriberBuffer)
+	}
+	if _, ok := <-events; ok {
+		t.Error("expected a subscriber that fell behind to be closed")
+	}
+}
+
+func TestBroadcaster_Close(t *testing.T) {
+	b := NewBroadcaster(nil)
+	events, _ := b.Subscribe()
+	b.Close()
+	if _, ok := <-events; ok {
+		t.Error("expected Close to close subscribers")
+	}
+	later, _ := b.Subscribe()
+	if _, ok := <-later; ok {
+		t.Error("expected subscribing after Close to get a closed channel")
+	}
+	b.Publish(Event{Type: EventStart}) // Publishing after Close is a no-op
+}
+
+func TestProgress_Broadcast(t *testing.T) {
+	now := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	b := NewBroadcaster(func() time.Time { return now })
+	b.Publish(Event{Type: EventStart})
+	p := NewProgress(io.Discard, 2)
+	p.Broadcast(b)
+	p.Start("Fetching activity for 2 users...")
+	p.SetItem(1, "alice")
+	p.Warn("could not fetch starred repos for alice")
+	p.SetItem(2, "bob")
+	p.Done()
+
+	events, cancel := b.Subscribe()
+	defer cancel()
+	got := drain(events)
+	if len(got) != 3 {
+		t.Fatalf("replay = %+v, want the start, a warning and bob's step", got)
+	}
+	if w := got[1]; w.Type != EventWarning || w.Message != "could not fetch starred repos for alice" {
+		t.Errorf("warning = %+v", w)
+	}
+	if step := got[2]; step.Item != "bob" || step.Current != 2 || step.Total != 2 || !step.Time.Equal(now) {
+		t.Errorf("latest step = %+v, want bob, 2 of 2 at %v", step, now)
+	}
+}
@@ -140,6 +140,7 @@ func (s *Spinner) spin() {
 type Progress struct {
 	w       io.Writer
 	spinner *Spinner
+	events  *Broadcaster // nil unless Broadcast was called
 	total   int
 	current int
 	mu      sync.Mutex
@@ -154,9 +155,19 @@ func NewProgress(w io.Writer, total int) *Progress {
 	}
 }
 
+// Broadcast also publishes p's progress and warnings to b, as
+// EventProgress and EventWarning events. Starting and finishing the sync
+// is left to whoever runs it.
+func (p *Progress) Broadcast(b *Broadcaster) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = b
+}
+
 // Start begins progress tracking with an initial message.
 func (p *Progress) Start(message string) {
 	p.spinner.Start(message)
+	p.publish(Event{Type: EventProgress, Message: message, Total: p.total})
 }
 
 // SetItem updates progress to show the current item being processed.
@@ -168,6 +179,23 @@ func (p *Progress) SetItem(current int, itemName string) {
 
 	msg := fmt.Sprintf("Fetching activity for user %d/%d: %s...", current, p.total, itemName)
 	p.spinner.Update(msg)
+	p.publish(Event{Type: EventProgress, Message: msg, Item: itemName, Current: current, Total: p.total})
+}
+
+// Warn reports something the sync had to skip to Broadcast's subscribers.
+// It's not printed: the terminal gets warnings when the sync is done.
+func (p *Progress) Warn(message string) {
+	p.publish(Event{Type: EventWarning, Message: message})
+}
+
+// publish sends e to the Broadcaster, if there is one.
+func (p *Progress) publish(e Event) {
+	p.mu.Lock()
+	b := p.events
+	p.mu.Unlock()
+	if b != nil {
+		b.Publish(e)
+	}
 }
 
 // Done stops the progress indicator.
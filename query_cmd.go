@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+const queryUsage = `Usage:
+  gitstreams query [-format table|csv|json] [flags] "SELECT ..."
+
+Query the v_activities, v_stars and v_repos views; see the README.`
+
+// runQuery handles the "query" subcommand: it runs a read-only SQL query
+// against the database and prints the rows.
+func runQuery(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	format := fs.ownFormat("table", "Output format: table, csv or json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if _, err := fs.resolve(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" {
+		_, _ = fmt.Fprintln(stderr, queryUsage)
+		return 1
+	}
+	var write func(io.Writer, *storage.QueryResult) error
+	switch *format {
+	case "table":
+		write = writeQueryTable
+	case "csv":
+		write = writeQueryCSV
+	case "json":
+		write = writeQueryJSON
+	default:
+		_, _ = fmt.Fprintf(stderr, "Error: unsupported format %q (supported: table, csv, json)\n", *format)
+		return 1
+	}
+
+	if backend, _ := storage.ParseDSN(cfg.DBPath); backend != storage.BackendSQLite {
+		_, _ = fmt.Fprintf(stderr, "Error: query needs a SQLite database, not %s\n", backend)
+		return 1
+	}
+	// Don't let the store create a database that isn't there
+	if _, err := os.Stat(dbFile(cfg.DBPath)); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: no database at %s; run gitstreams first\n", cfg.DBPath)
+		return 1
+	}
+	// Opening the store brings the views up to date and fills in activity
+	// from snapshots saved before it was kept
+	store, err := openStore(stderr, cfg, deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	if err := backfillActivities(store, runSnapshotID(cfg), deps.Now()); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Warning: could not backfill activity: %v\n", err)
+	}
+	_ = store.Close()
+
+	result, err := storage.QueryReadOnly(context.Background(), dbFile(cfg.DBPath), query)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := write(stdout, result); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error writing results: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// queryCell formats a value for table and CSV output.
+func queryCell(v any) string {
+	if v == nil {
+		return "NULL"
+	}
+	return fmt.Sprint(v)
+}
+
+// writeQueryTable writes results as aligned columns.
+func writeQueryTable(w io.Writer, result *storage.QueryResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, strings.Join(result.Columns, "\t"))
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			// Tabs and newlines in values would break the alignment
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(queryCell(v))
+		}
+		_, _ = fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "(%d rows)\n", len(result.Rows))
+	return err
+}
+
+// writeQueryCSV writes results as CSV with a header row.
+func writeQueryCSV(w io.Writer, result *storage.QueryResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(result.Columns); err != nil {
+		return err
+	}
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			if v != nil {
+				cells[i] = fmt.Sprint(v)
+			}
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeQueryJSON writes results as an array of objects keyed by column.
+func writeQueryJSON(w io.Writer, result *storage.QueryResult) error {
+	objects := make([]map[string]any, len(result.Rows))
+	for i, row := range result.Rows {
+		objects[i] = make(map[string]any, len(row))
+		for j, v := range row {
+			objects[i][result.Columns[j]] = v
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(objects)
+}
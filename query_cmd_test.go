@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestRunQuery(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := storage.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saved without activities, as by an older version, so the query
+	// command has to backfill them
+	ss, err := snapshotToStorage(func() *diff.Snapshot {
+		s := diff.NewSnapshot(fixedTime())
+		s.Users["alice"] = diff.UserActivity{
+			Username:     "alice",
+			StarredRepos: []diff.Repo{{Owner: "golang", Name: "go", Description: "The Go\tlanguage"}, {Owner: "rust-lang", Name: "rust"}},
+		}
+		return s
+	}())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.UserID = snapshotUserID
+	ss.Timestamp = fixedTime()
+	if err := store.SaveAll([]*storage.Snapshot{ss}); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		Now:          fixedTime,
+	}
+	query := "SELECT owner, name, description FROM v_stars ORDER BY owner"
+
+	var stdout, stderr strings.Builder
+	if code := run(&stdout, &stderr, []string{"query", "-db", dbPath, query}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "owner") || !strings.Contains(lines[1], "The Go language") || lines[3] != "(2 rows)" {
+		t.Errorf("unexpected table:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"query", "-format", "csv", "-db", dbPath, query}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if want := "owner,name,description\ngolang,go,The Go\tlanguage\nrust-lang,rust,\n"; stdout.String() != want {
+		t.Errorf("csv = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"query", "-format", "json", "-db", dbPath, "SELECT COUNT(*) AS n FROM v_activities"}, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(stdout.String()), &got); err != nil || len(got) != 1 || got[0]["n"] != float64(2) {
+		t.Errorf("json = %s (%v)", stdout.String(), err)
+	}
+}
+
+func TestRunQuery_Errors(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := storage.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		Now:          fixedTime,
+	}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-db", dbPath}, "Usage"},
+		{[]string{"-db", dbPath, "-format", "xml", "SELECT 1"}, "unsupported format"},
+		{[]string{"-db", dbPath, "DELETE FROM snapshots"}, "readonly"},
+		{[]string{"-db", filepath.Join(t.TempDir(), "missing.db"), "SELECT 1"}, "no database"},
+		{[]string{"-db", "bolt://" + filepath.Join(t.TempDir(), "test.bolt"), "SELECT 1"}, "needs a SQLite database"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr strings.Builder
+		if code := run(&stdout, &stderr, append([]string{"query"}, tt.args...), deps); code != 1 || !strings.Contains(stderr.String(), tt.wantErr) {
+			t.Errorf("query %v: code %d, stderr %q; want %q", tt.args, code, stderr.String(), tt.wantErr)
+		}
+	}
+}
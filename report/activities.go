@@ -0,0 +1,209 @@
+package report
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+// Activity types for GitHub events beyond the core six.
+const (
+	ActivityReleased          ActivityType = "released"
+	ActivityOpenSourced       ActivityType = "open_sourced"
+	ActivityReviewed          ActivityType = "reviewed"
+	ActivityCommented         ActivityType = "commented"
+	ActivityWiki              ActivityType = "wiki"
+	ActivityMember            ActivityType = "member"
+	ActivityDeleted           ActivityType = "deleted"
+	ActivityDiscussion        ActivityType = "discussion"
+	ActivityDiscussionComment ActivityType = "discussion_comment"
+	ActivityTagged            ActivityType = "tagged"
+	ActivityBranched          ActivityType = "branched"
+	// ActivityFollowed is a user starting to follow someone. Its RepoName
+	// is who they followed, and its RepoURL their profile.
+	ActivityFollowed ActivityType = "followed"
+)
+
+// activityInfo is how an activity type is shown.
+type activityInfo struct {
+	icon     string
+	verb     string // Goes before the repo, e.g. "pushed to"
+	manyVerb string // The same for several at once, with a %d for the count
+	category string // Heading of the type's section in the by-category view
+}
+
+// activityInfos describes every known activity type. Types not here are
+// shown with otherActivity.
+var activityInfos = map[ActivityType]activityInfo{
+	ActivityStarred:           {icon: "⭐", verb: "starred", manyVerb: "starred %d repos including", category: "New Stars"},
+	ActivityCreatedRepo:       {icon: "🆕", verb: "created", manyVerb: "created %d repos including", category: "Repos Created"},
+	ActivityOpenSourced:       {icon: "🔓", verb: "open-sourced", manyVerb: "open-sourced %d repos including", category: "Open-Sourced"},
+	ActivityReleased:          {icon: "🚀", verb: "released", manyVerb: "published %d releases of", category: "Releases"},
+	ActivityTagged:            {icon: "🏷️", verb: "tagged", manyVerb: "pushed %d tags to", category: "Tags"},
+	ActivityForked:            {icon: "🔱", verb: "forked", manyVerb: "forked %d repos including", category: "Forks"},
+	ActivityPushed:            {icon: "📤", verb: "pushed to", manyVerb: "pushed %d times to", category: "Recent Pushes"},
+	ActivityPR:                {icon: "🔀", verb: "opened PR on", manyVerb: "opened %d PRs on", category: "Pull Requests"},
+	ActivityReviewed:          {icon: "👀", verb: "reviewed a PR on", manyVerb: "reviewed %d times on", category: "Reviews"},
+	ActivityIssue:             {icon: "🐛", verb: "opened issue on", manyVerb: "opened %d issues on", category: "Issues Opened"},
+	ActivityCommented:         {icon: "💬", verb: "commented on", manyVerb: "commented %d times on", category: "Comments"},
+	ActivityDiscussion:        {icon: "🗣️", verb: "started a discussion in", manyVerb: "started %d discussions in", category: "Discussions"},
+	ActivityDiscussionComment: {icon: "💭", verb: "joined a discussion in", manyVerb: "commented %d times in discussions in", category: "Discussion Replies"},
+	ActivityWiki:              {icon: "📖", verb: "edited the wiki of", manyVerb: "edited the wiki %d times on", category: "Wiki Edits"},
+	ActivityMember:            {icon: "🤝", verb: "added a collaborator to", manyVerb: "added %d collaborators to", category: "New Collaborators"},
+	ActivityBranched:          {icon: "🌿", verb: "created a branch in", manyVerb: "created %d branches in", category: "New Branches"},
+	ActivityDeleted:           {icon: "🗑️", verb: "deleted a branch or tag in", manyVerb: "deleted %d branches or tags in", category: "Deletions"},
+	ActivityFollowed:          {icon: "👥", verb: "started following", manyVerb: "started following %d people including", category: "Network Growth"},
+}
+
+// otherActivity is how activity types without an activityInfos entry are
+// shown.
+var otherActivity = activityInfo{icon: "📋", verb: "acted on", manyVerb: "acted %d times on", category: "Other Activity"}
+
+// categoryOrder is the order of the by-category view's sections. Types not
+// listed come after, by name.
+var categoryOrder = []ActivityType{
+	ActivityStarred,
+	ActivityCreatedRepo,
+	ActivityOpenSourced,
+	ActivityReleased,
+	ActivityTagged,
+	ActivityForked,
+	ActivityPushed,
+	ActivityPR,
+	ActivityReviewed,
+	ActivityIssue,
+	ActivityCommented,
+	ActivityDiscussion,
+	ActivityDiscussionComment,
+	ActivityWiki,
+	ActivityMember,
+	ActivityBranched,
+	ActivityDeleted,
+	ActivityFollowed,
+}
+
+// eventActivityTypes maps each GitHub event type to the activity it's shown
+// as. See https://docs.github.com/en/rest/using-the-rest-api/github-event-types.
+var eventActivityTypes = map[string]ActivityType{
+	"WatchEvent": ActivityStarred,
+	// Depends on what was created; see createActivityTypes. The empty type
+	// skips creations of anything else.
+	"CreateEvent":                   "",
+	"DeleteEvent":                   ActivityDeleted,
+	"ForkEvent":                     ActivityForked,
+	"PushEvent":                     ActivityPushed,
+	"PullRequestEvent":              ActivityPR,
+	"PullRequestReviewEvent":        ActivityReviewed,
+	"PullRequestReviewCommentEvent": ActivityReviewed,
+	"PullRequestReviewThreadEvent":  ActivityReviewed,
+	"IssuesEvent":                   ActivityIssue,
+	"IssueCommentEvent":             ActivityCommented,
+	"CommitCommentEvent":            ActivityCommented,
+	"ReleaseEvent":                  ActivityReleased,
+	"PublicEvent":                   ActivityOpenSourced,
+	"GollumEvent":                   ActivityWiki,
+	"MemberEvent":                   ActivityMember,
+	// Not from the events API: the GitHub source records discussions
+	// under these webhook event names
+	"DiscussionEvent":        ActivityDiscussion,
+	"DiscussionCommentEvent": ActivityDiscussionComment,
+}
+
+// createActivityTypes maps a CreateEvent's ref_type to the activity it's
+// shown as.
+var createActivityTypes = map[string]ActivityType{
+	"repository": ActivityCreatedRepo,
+	"tag":        ActivityTagged,
+	"branch":     ActivityBranched,
+}
+
+// eventActivity is EventActivityType for e, telling apart CreateEvents by
+// what they created.
+func eventActivity(e diff.Event) ActivityType {
+	if e.Type == "CreateEvent" {
+		return createActivityTypes[e.RefType]
+	}
+	return EventActivityType(e.Type)
+}
+
+// EventActivityType maps a GitHub event type to the activity it's shown
+// as, or "" for events that aren't shown. Event types GitHub adds later
+// pass through as their own activity type.
+func EventActivityType(eventType string) ActivityType {
+	if t, ok := eventActivityTypes[eventType]; ok {
+		return t
+	}
+	return ActivityType(eventType)
+}
+
+// KnownEventType reports whether eventType is one EventActivityType has a
+// mapping for.
+func KnownEventType(eventType string) bool {
+	_, ok := eventActivityTypes[eventType]
+	return ok
+}
+
+// info returns how t is shown.
+func info(t ActivityType) activityInfo {
+	if i, ok := activityInfos[t]; ok {
+		return i
+	}
+	return otherActivity
+}
+
+// ActivityTypes returns every known activity type, in the by-category
+// view's default order.
+func ActivityTypes() []ActivityType {
+	return slices.Clone(categoryOrder)
+}
+
+// CategoryLayout arranges the by-category view's sections. The zero value
+// shows every section in the default order.
+type CategoryLayout struct {
+	Order  []ActivityType // Sections to put first, in this order; the rest follow as usual
+	Hidden []ActivityType // Sections to leave out
+}
+
+// orderCategories returns the types in groups in the by-category view's
+// order, with the types in first ahead of the rest.
+func orderCategories[V any](groups map[ActivityType]V, first []ActivityType) []ActivityType {
+	var order []ActivityType
+	for _, t := range slices.Concat(first, categoryOrder) {
+		if _, ok := groups[t]; ok && !slices.Contains(order, t) {
+			order = append(order, t)
+		}
+	}
+	var others []ActivityType
+	for t := range groups {
+		if !slices.Contains(order, t) {
+			others = append(others, t)
+		}
+	}
+	slices.Sort(others)
+	return append(order, others...)
+}
+
+// activityIcon returns an emoji icon for the activity type.
+func activityIcon(t ActivityType) string {
+	return info(t).icon
+}
+
+// activityVerb returns a human-readable verb for the activity type.
+func activityVerb(t ActivityType) string {
+	return info(t).verb
+}
+
+// aggregatedVerb returns a human-readable verb for aggregated activities.
+// When count > 1, includes the count (e.g., "pushed 6 times to").
+func aggregatedVerb(t ActivityType, count int) string {
+	if count <= 1 {
+		return activityVerb(t)
+	}
+	return fmt.Sprintf(info(t).manyVerb, count)
+}
+
+// categoryName returns a human-readable name for the activity type category.
+func categoryName(t ActivityType) string {
+	return info(t).category
+}
@@ -0,0 +1,126 @@
+package report
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+func TestEventActivityTypesAreDescribed(t *testing.T) {
+	for eventType, activityType := range eventActivityTypes {
+		if activityType == "" {
+			continue
+		}
+		if _, ok := activityInfos[activityType]; !ok {
+			t.Errorf("%s maps to %q, which has no activityInfos entry", eventType, activityType)
+		}
+	}
+	for activityType, i := range activityInfos {
+		if i.icon == "" || i.verb == "" || i.manyVerb == "" || i.category == "" {
+			t.Errorf("activityInfos[%q] is incomplete: %+v", activityType, i)
+		}
+		if !slices.Contains(categoryOrder, activityType) {
+			t.Errorf("%q is missing from categoryOrder", activityType)
+		}
+	}
+}
+
+func TestKnownEventType(t *testing.T) {
+	for _, eventType := range []string{"PushEvent", "CreateEvent", "ReleaseEvent", "PublicEvent", "GollumEvent", "MemberEvent", "DeleteEvent"} {
+		if !KnownEventType(eventType) {
+			t.Errorf("KnownEventType(%q) = false, want true", eventType)
+		}
+	}
+	if KnownEventType("BrandNewEvent") {
+		t.Error("KnownEventType(BrandNewEvent) = true, want false")
+	}
+}
+
+func TestEventActivity_Create(t *testing.T) {
+	tests := []struct {
+		refType string
+		want    ActivityType
+	}{
+		{"repository", ActivityCreatedRepo},
+		{"tag", ActivityTagged},
+		{"branch", ActivityBranched},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := eventActivity(diff.Event{Type: "CreateEvent", RefType: tt.refType}); got != tt.want {
+			t.Errorf("eventActivity(CreateEvent %q) = %q, want %q", tt.refType, got, tt.want)
+		}
+	}
+	if got := eventActivity(diff.Event{Type: "PushEvent"}); got != ActivityPushed {
+		t.Errorf("eventActivity(PushEvent) = %q, want %q", got, ActivityPushed)
+	}
+}
+
+func TestActivitiesByCategory_Order(t *testing.T) {
+	r := &Report{UserActivities: []UserActivity{{
+		User: "alice",
+		Activities: []Activity{
+			{Type: ActivityType("zzz_event")},
+			{Type: ActivityDeleted},
+			{Type: ActivityType("aaa_event")},
+			{Type: ActivityReleased},
+			{Type: ActivityStarred},
+		},
+	}}}
+
+	var got []ActivityType
+	for _, g := range r.ActivitiesByCategory() {
+		got = append(got, g.Type)
+	}
+	// Unknown types still get a section, after the known ones
+	want := []ActivityType{ActivityStarred, ActivityReleased, ActivityDeleted, "aaa_event", "zzz_event"}
+	if !slices.Equal(got, want) {
+		t.Errorf("categories = %v, want %v", got, want)
+	}
+
+	var aggregated []ActivityType
+	for _, g := range r.AggregatedActivitiesByCategory() {
+		aggregated = append(aggregated, g.Type)
+	}
+	if !slices.Equal(aggregated, want) {
+		t.Errorf("aggregated categories = %v, want %v", aggregated, want)
+	}
+}
+
+func TestActivitiesByCategory_Layout(t *testing.T) {
+	r := &Report{
+		UserActivities: []UserActivity{{
+			User: "alice",
+			Activities: []Activity{
+				{Type: ActivityStarred},
+				{Type: ActivityPushed},
+				{Type: ActivityPushed},
+				{Type: ActivityReleased},
+				{Type: ActivityIssue},
+			},
+		}},
+		// Releases first, no pushes; order entries without activities are
+		// skipped
+		Categories: CategoryLayout{
+			Order:  []ActivityType{ActivityReleased, ActivityWiki, ActivityIssue},
+			Hidden: []ActivityType{ActivityPushed},
+		},
+	}
+
+	want := []ActivityType{ActivityReleased, ActivityIssue, ActivityStarred}
+	var got []ActivityType
+	for _, g := range r.ActivitiesByCategory() {
+		got = append(got, g.Type)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("categories = %v, want %v", got, want)
+	}
+	var aggregated []ActivityType
+	for _, g := range r.AggregatedActivitiesByCategory() {
+		aggregated = append(aggregated, g.Type)
+	}
+	if !slices.Equal(aggregated, want) {
+		t.Errorf("aggregated categories = %v, want %v", aggregated, want)
+	}
+}
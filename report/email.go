@@ -0,0 +1,127 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// EmailSection is a section of a report laid out for email: its first
+// rows, and how many more the full report lists.
+type EmailSection struct {
+	Title string
+	Icon  string
+	Rows  []AggregatedActivity
+	More  int
+}
+
+// EmailSections lays r out for email: the pinned and watchlist sections,
+// if there are any, then the by-category view's sections, each cut to at
+// most limit rows (0 for no limit). Automation noise is left out, as it is
+// from the summary counts.
+func (r *Report) EmailSections(limit int) []EmailSection {
+	var sections []EmailSection
+	add := func(title, icon string, rows []AggregatedActivity) {
+		s := EmailSection{Title: title, Icon: icon, Rows: rows}
+		if limit > 0 && len(rows) > limit {
+			s.Rows, s.More = rows[:limit], len(rows)-limit
+		}
+		sections = append(sections, s)
+	}
+	if pinned := r.PinnedActivities(); len(pinned) > 0 {
+		add("Pinned", "📌", pinned)
+	}
+	var watched []AggregatedActivity
+	for _, w := range r.Watched {
+		watched = append(watched, w.Rows()...)
+	}
+	if len(watched) > 0 {
+		add("Watchlist", "👀", watched)
+	}
+	for _, g := range r.AggregatedActivitiesByCategory() {
+		add(categoryName(g.Type), activityIcon(g.Type), g.Activities)
+	}
+	return sections
+}
+
+// emailPeriod describes the span r covers, e.g. "Jan 21 – Jan 22, 2026".
+func (r *Report) emailPeriod() string {
+	if r.PeriodStart.IsZero() || r.PeriodEnd.IsZero() {
+		return r.GeneratedAt.Format("Jan 2, 2006")
+	}
+	return r.PeriodStart.Format("Jan 2") + " – " + r.PeriodEnd.Format("Jan 2, 2006")
+}
+
+// emailHeading sums up r for the top of an email, e.g. "GitStreams: 3
+// new activities".
+func (r *Report) emailHeading() string {
+	if n := r.TotalActivities(); n != 1 {
+		return fmt.Sprintf("GitStreams: %d new activities", n)
+	}
+	return "GitStreams: 1 new activity"
+}
+
+// EmailText returns r as a plain-text email body, for mail clients that
+// don't show HTML, with each section cut to limit rows as for
+// EmailSections.
+func (r *Report) EmailText(limit int) string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "%s\n", r.emailHeading())
+	_, _ = fmt.Fprintf(&b, "%s\n", r.emailPeriod())
+	for _, s := range r.EmailSections(limit) {
+		_, _ = fmt.Fprintf(&b, "\n%s %s\n\n", s.Icon, s.Title)
+		for _, a := range s.Rows {
+			_, _ = fmt.Fprintf(&b, "- %s %s %s", a.User, aggregatedVerb(a.Type, a.Count), a.RepoName)
+			if a.Details != "" {
+				_, _ = fmt.Fprintf(&b, ": %s", a.Details)
+			}
+			if link := a.Link(); link != "" {
+				_, _ = fmt.Fprintf(&b, "\n  %s", link)
+			}
+			b.WriteString("\n")
+		}
+		if s.More > 0 {
+			_, _ = fmt.Fprintf(&b, "- …and %d more\n", s.More)
+		}
+	}
+	return b.String()
+}
+
+// EmailHTML returns r as an HTML email body, with each section cut to limit
+// rows as for EmailSections. Unlike the HTML report it has no scripts and
+// inline styles only, which is all most mail clients keep, and cutting
+// sections keeps it under the size Gmail clips messages at.
+func (r *Report) EmailHTML(limit int) (string, error) {
+	var b strings.Builder
+	err := emailTemplate.Execute(&b, struct {
+		Heading  string
+		Period   string
+		Sections []EmailSection
+	}{r.emailHeading(), r.emailPeriod(), r.EmailSections(limit)})
+	if err != nil {
+		return "", fmt.Errorf("rendering email: %w", err)
+	}
+	return b.String(), nil
+}
+
+var emailTemplate = template.Must(template.New("email").Funcs(template.FuncMap{
+	"verb": aggregatedVerb,
+}).Parse(`<!DOCTYPE html>
+<html>
+<body style="margin:0;padding:16px;font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Helvetica,Arial,sans-serif;font-size:14px;color:#1f2328;">
+<h1 style="font-size:20px;margin:0 0 4px;">{{.Heading}}</h1>
+<p style="margin:0 0 16px;color:#656d76;">{{.Period}}</p>
+{{- range .Sections}}
+<h2 style="font-size:16px;margin:20px 0 8px;">{{.Icon}} {{.Title}}</h2>
+<ul style="margin:0;padding-left:20px;">
+{{- range .Rows}}
+<li style="margin:0 0 6px;">{{if .UserURL}}<a href="{{.UserURL}}" style="color:#0969da;">{{.User}}</a>{{else}}{{.User}}{{end}} {{verb .Type .Count}} {{if .Link}}<a href="{{.Link}}" style="color:#0969da;">{{.RepoName}}</a>{{else}}{{.RepoName}}{{end}}{{if .Details}}<br><span style="color:#656d76;">{{.Details}}</span>{{end}}</li>
+{{- end}}
+{{- if .More}}
+<li style="margin:0 0 6px;color:#656d76;">…and {{.More}} more</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,85 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// emailReport has bob starring three repos and pinned pushes to golang/go.
+func emailReport() *Report {
+	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	return &Report{
+		GeneratedAt: now,
+		PeriodStart: now.AddDate(0, 0, -1),
+		PeriodEnd:   now,
+		Pinned:      []string{"golang/go"},
+		UserActivities: []UserActivity{
+			{User: "alice", Activities: []Activity{
+				{Type: ActivityPushed, User: "alice", RepoName: "golang/go", RepoURL: "https://github.com/golang/go", Timestamp: now},
+			}},
+			{User: "bob", Activities: []Activity{
+				{Type: ActivityStarred, User: "bob", RepoName: "a/one", RepoURL: "https://github.com/a/one", Details: "A <b>first</b> repo", Timestamp: now},
+				{Type: ActivityStarred, User: "bob", RepoName: "a/two", Timestamp: now.Add(-time.Hour)},
+				{Type: ActivityStarred, User: "bob", RepoName: "a/three", Timestamp: now.Add(-2 * time.Hour)},
+			}},
+		},
+	}
+}
+
+func TestReportEmailSections(t *testing.T) {
+	sections := emailReport().EmailSections(2)
+	if len(sections) != 2 {
+		t.Fatalf("got %d sections, want pinned and stars", len(sections))
+	}
+	if s := sections[0]; s.Title != "Pinned" || len(s.Rows) != 1 || s.More != 0 {
+		t.Errorf("first section = %s with %d rows and %d more, want all of pinned", s.Title, len(s.Rows), s.More)
+	}
+	if s := sections[1]; s.Title != categoryName(ActivityStarred) || len(s.Rows) != 2 || s.More != 1 {
+		t.Errorf("second section = %s with %d rows and %d more, want 2 stars and 1 more", s.Title, len(s.Rows), s.More)
+	}
+
+	if s := emailReport().EmailSections(0)[1]; len(s.Rows) != 3 || s.More != 0 {
+		t.Errorf("without a limit, stars have %d rows and %d more, want all 3", len(s.Rows), s.More)
+	}
+}
+
+func TestReportEmailText(t *testing.T) {
+	text := emailReport().EmailText(2)
+	for _, want := range []string{
+		"GitStreams: 4 new activities\n",
+		"Jan 21 – Jan 22, 2026\n",
+		"- bob starred a/one: A <b>first</b> repo\n  https://github.com/a/one\n",
+		"- bob starred a/two\n",
+		"- …and 1 more\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("EmailText() missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "a/three") {
+		t.Errorf("EmailText() lists a star past the limit:\n%s", text)
+	}
+}
+
+func TestReportEmailHTML(t *testing.T) {
+	html, err := emailReport().EmailHTML(2)
+	if err != nil {
+		t.Fatalf("EmailHTML() error = %v", err)
+	}
+	for _, want := range []string{
+		"<h1 style=\"font-size:20px;margin:0 0 4px;\">GitStreams: 4 new activities</h1>",
+		`<a href="https://github.com/a/one" style="color:#0969da;">a/one</a>`,
+		"A &lt;b&gt;first&lt;/b&gt; repo",
+		"…and 1 more",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("EmailHTML() missing %q:\n%s", want, html)
+		}
+	}
+	for _, unwanted := range []string{"a/three", "<script"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("EmailHTML() has %q:\n%s", unwanted, html)
+		}
+	}
+}
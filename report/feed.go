@@ -0,0 +1,145 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// MaxFeedEntries is how many entries a feed keeps. Older ones fall off as
+// new ones are added, so the file doesn't grow without end.
+const MaxFeedEntries = 500
+
+// feedID identifies gitstreams feeds, which are all the same feed as far as
+// readers can tell: one person's network.
+const feedID = "urn:gitstreams:feed"
+
+// Feed is an Atom feed of activities, kept across runs: each run's report
+// adds its activities as entries, newest first.
+type Feed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated time.Time   `xml:"updated"`
+	Entries []FeedEntry `xml:"entry"`
+}
+
+// FeedEntry is one activity in a feed.
+type FeedEntry struct {
+	Title   string     `xml:"title"`
+	ID      string     `xml:"id"`
+	Updated time.Time  `xml:"updated"`
+	Link    *FeedLink  `xml:"link,omitempty"`
+	Author  FeedPerson `xml:"author"`
+	Summary string     `xml:"summary,omitempty"`
+}
+
+// FeedPerson is who did an entry's activity.
+type FeedPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+// FeedLink is where an entry's activity happened.
+type FeedLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// NewFeed creates an empty feed with the given title.
+func NewFeed(title string) *Feed {
+	return &Feed{Title: title, ID: feedID}
+}
+
+// ReadFeed decodes a feed previously written with Feed.Write.
+func ReadFeed(r io.Reader) (*Feed, error) {
+	var f Feed
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("decoding feed: %w", err)
+	}
+	return &f, nil
+}
+
+// Write encodes the feed as Atom.
+func (f *Feed) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return fmt.Errorf("encoding feed: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Add adds r's activities, the repos you starred and watched repos'
+// releases and pushes to the feed as entries, leaving out automation noise
+// and those it already has. Entries are kept newest first, up to
+// MaxFeedEntries. It returns the number of entries added.
+func (f *Feed) Add(r *Report) int {
+	seen := make(map[string]bool, len(f.Entries))
+	for _, e := range f.Entries {
+		seen[e.ID] = true
+	}
+
+	added := 0
+	add := func(e FeedEntry) {
+		if seen[e.ID] {
+			return
+		}
+		seen[e.ID] = true
+		f.Entries = append(f.Entries, e)
+		added++
+	}
+	for _, ua := range r.UserActivities {
+		for _, a := range ua.Activities {
+			if r.IsNoise(a) && !r.IsPinned(a.RepoName) {
+				continue
+			}
+			add(feedEntry(a, a.User, a.User+" "+activityVerb(a.Type)+" "+a.RepoName, "activity|"+activityKey(a), r.GeneratedAt))
+		}
+	}
+	// Your stars aren't dated, so they're as new as the report
+	for _, a := range r.YouStarred {
+		add(feedEntry(a, "you", "You starred "+a.RepoName, "yours|"+a.RepoName, r.GeneratedAt))
+	}
+	for _, w := range r.Watched {
+		for _, a := range w.Activities {
+			add(feedEntry(a, a.User, a.User+" "+activityVerb(a.Type)+" "+a.RepoName, "watched|"+activityKey(a), r.GeneratedAt))
+		}
+	}
+
+	slices.SortStableFunc(f.Entries, func(a, b FeedEntry) int { return b.Updated.Compare(a.Updated) })
+	if len(f.Entries) > MaxFeedEntries {
+		f.Entries = f.Entries[:MaxFeedEntries]
+	}
+	if added > 0 && r.GeneratedAt.After(f.Updated) {
+		f.Updated = r.GeneratedAt
+	}
+	return added
+}
+
+// feedEntry makes an entry for a, by author, identified by key. Activities
+// without a time are given at.
+func feedEntry(a Activity, author, title, key string, at time.Time) FeedEntry {
+	sum := sha256.Sum256([]byte(key))
+	e := FeedEntry{
+		Title:   title,
+		ID:      "urn:gitstreams:" + hex.EncodeToString(sum[:]),
+		Updated: a.Timestamp,
+		Author:  FeedPerson{Name: author, URI: a.UserURL},
+		Summary: a.Details,
+	}
+	if e.Updated.IsZero() {
+		e.Updated = at
+	}
+	if link := a.Link(); link != "" {
+		e.Link = &FeedLink{Href: link}
+	}
+	return e
+}
@@ -0,0 +1,96 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedAdd(t *testing.T) {
+	day := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	push := Activity{Type: ActivityPushed, User: "alice", RepoName: "a/one", RepoURL: "https://github.com/a/one", Timestamp: day, Details: "Fix typo"}
+	first := &Report{
+		GeneratedAt:    day.Add(time.Hour),
+		UserActivities: []UserActivity{{User: "alice", Activities: []Activity{push}}},
+	}
+	second := &Report{
+		GeneratedAt: day.Add(2 * time.Hour),
+		UserActivities: []UserActivity{
+			{User: "alice", Activities: []Activity{push}}, // Seen last run
+			{User: "bob", Activities: []Activity{{Type: ActivityStarred, User: "bob", RepoName: "b/two", Timestamp: day.Add(90 * time.Minute)}}},
+		},
+		YouStarred: []Activity{{Type: ActivityStarred, RepoName: "c/three"}},
+	}
+
+	f := NewFeed("GitStreams")
+	if added := f.Add(first); added != 1 {
+		t.Errorf("first Add() added %d, want 1", added)
+	}
+	if added := f.Add(second); added != 2 {
+		t.Errorf("second Add() added %d, want 2", added)
+	}
+
+	var titles []string
+	for _, e := range f.Entries {
+		titles = append(titles, e.Title)
+	}
+	want := []string{"You starred c/three", "bob starred b/two", "alice pushed to a/one"}
+	if strings.Join(titles, "\n") != strings.Join(want, "\n") {
+		t.Errorf("entries = %q, want newest first %q", titles, want)
+	}
+	if !f.Updated.Equal(second.GeneratedAt) {
+		t.Errorf("Updated = %v, want %v", f.Updated, second.GeneratedAt)
+	}
+	last := f.Entries[2]
+	if last.Link == nil || last.Link.Href != push.RepoURL || last.Summary != "Fix typo" || last.Author.Name != "alice" {
+		t.Errorf("unexpected entry for the push: %+v", last)
+	}
+}
+
+func TestFeedAddKeepsNewest(t *testing.T) {
+	day := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	var activities []Activity
+	for i := range MaxFeedEntries + 10 {
+		activities = append(activities, Activity{Type: ActivityPushed, User: "alice", RepoName: fmt.Sprintf("a/%d", i), Timestamp: day.Add(time.Duration(i) * time.Minute)})
+	}
+	f := NewFeed("GitStreams")
+	f.Add(&Report{UserActivities: []UserActivity{{User: "alice", Activities: activities}}})
+	if len(f.Entries) != MaxFeedEntries {
+		t.Fatalf("feed has %d entries, want %d", len(f.Entries), MaxFeedEntries)
+	}
+	if f.Entries[len(f.Entries)-1].Title != "alice pushed to a/10" {
+		t.Errorf("oldest entry kept = %q, want the oldest ones dropped", f.Entries[len(f.Entries)-1].Title)
+	}
+}
+
+func TestFeedRoundTrip(t *testing.T) {
+	day := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	f := NewFeed("GitStreams")
+	f.Add(&Report{GeneratedAt: day, UserActivities: []UserActivity{{User: "alice", Activities: []Activity{{Type: ActivityForked, User: "alice", RepoName: "a/<one>", Timestamp: day}}}}})
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<feed xmlns="http://www.w3.org/2005/Atom">`) {
+		t.Errorf("expected an Atom feed, got:\n%s", buf.String())
+	}
+	got, err := ReadFeed(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != f.ID || len(got.Entries) != 1 || got.Entries[0].Title != "alice forked a/<one>" || !got.Entries[0].Updated.Equal(day) {
+		t.Errorf("ReadFeed() = %+v, want %+v", got, f)
+	}
+
+	// Adding the same activity to the feed read back is a no-op
+	if added := got.Add(&Report{UserActivities: []UserActivity{{User: "alice", Activities: []Activity{{Type: ActivityForked, User: "alice", RepoName: "a/<one>", Timestamp: day}}}}}); added != 0 {
+		t.Errorf("Add() after reading back added %d, want 0", added)
+	}
+
+	if _, err := ReadFeed(strings.NewReader("not xml")); err == nil {
+		t.Error("expected an error for a file that isn't a feed")
+	}
+}
@@ -0,0 +1,266 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+// Options controls how FromDiff builds a report.
+type Options struct {
+	GeneratedAt time.Time
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+
+	// SortUsers orders the report's users, as for slices.SortFunc. Nil
+	// means ByActivityCount.
+	SortUsers func(a, b UserActivity) int
+
+	// SortActivities orders each user's activities, as for
+	// slices.SortFunc. Nil means NewestFirst.
+	SortActivities func(a, b Activity) int
+
+	// WebURL is the GitHub instance links point to, for GitHub Enterprise
+	// Server. Empty means DefaultWebURL.
+	WebURL string
+
+	// Log, if set, gets a line after each step, for verbose output.
+	Log io.Writer
+
+	// SkipBots leaves out accounts whose logins end in "[bot]", like
+	// dependabot[bot].
+	SkipBots bool
+
+	// Dedup drops activities identical to one already in the report.
+	Dedup bool
+
+	// HideBranches leaves out branches being created and deleted, which
+	// busy repos do all day.
+	HideBranches bool
+
+	// MinStars leaves out stars of repos with fewer stars than this, so
+	// scratch repos nobody else has found don't crowd out the rest.
+	MinStars int
+
+	// HideUndescribedStars leaves out stars of repos without a
+	// description.
+	HideUndescribedStars bool
+}
+
+// FromDiff builds a report of the changes in result, grouped by user.
+func FromDiff(result *diff.Result, opts Options) *Report {
+	b := &builder{opts: opts, links: newLinks(opts.WebURL), users: make(map[string]*UserActivity), avatars: make(map[string]string)}
+	if opts.Dedup {
+		b.seen = make(map[string]bool)
+	}
+	// Events carry the avatars the API has for their actors, which beat
+	// guessing them from logins
+	for _, event := range result.NewEvents {
+		if event.Event.Actor == event.Username && event.Event.ActorAvatarURL != "" {
+			b.avatars[event.Username] = event.Event.ActorAvatarURL
+		}
+	}
+	b.logf("FromDiff input: NewStars=%d, NewRepos=%d, NewEvents=%d, NewFollows=%d, NewUsers=%d",
+		len(result.NewStars), len(result.NewRepos), len(result.NewEvents), len(result.NewFollows), len(result.NewUsers))
+
+	for _, star := range result.NewStars {
+		if star.Repo.Stars < opts.MinStars || (opts.HideUndescribedStars && strings.TrimSpace(star.Repo.Description) == "") {
+			continue
+		}
+		b.add(Activity{
+			Type:      ActivityStarred,
+			User:      star.Username,
+			RepoName:  star.Repo.FullName(),
+			RepoURL:   b.links.repoPage(star.Repo),
+			Timestamp: star.Repo.CreatedAt,
+			Details:   star.Repo.Description,
+		})
+	}
+	b.logf("FromDiff after stars: userActivities map has %d entries", len(b.users))
+
+	// CreateEvents for repos NewRepos already has would list them twice
+	created := make(map[string]bool, len(result.NewRepos))
+	for _, repo := range result.NewRepos {
+		created[repo.Username+"\x00"+repo.Repo.FullName()] = true
+		b.add(Activity{
+			Type:      ActivityCreatedRepo,
+			User:      repo.Username,
+			RepoName:  repo.Repo.FullName(),
+			RepoURL:   b.links.repoPage(repo.Repo),
+			Timestamp: repo.Repo.CreatedAt,
+			Details:   repo.Repo.Description,
+		})
+	}
+	b.logf("FromDiff after repos: userActivities map has %d entries", len(b.users))
+
+	// Publishing a release can push its tag too, which would list it twice
+	released := make(map[string]bool)
+	for _, event := range result.NewEvents {
+		if event.Event.Type == "ReleaseEvent" && event.Event.Ref != "" {
+			released[event.Username+"\x00"+event.Event.Repo+"\x00"+event.Event.Ref] = true
+		}
+	}
+
+	unknown := make(map[string]bool)
+	for _, event := range result.NewEvents {
+		if !KnownEventType(event.Event.Type) && !unknown[event.Event.Type] {
+			unknown[event.Event.Type] = true
+			b.logf("FromDiff: unknown event type %s, shown as other activity", event.Event.Type)
+		}
+		activityType := eventActivity(event.Event)
+		// Skip events that don't map to an activity type
+		if activityType == "" {
+			continue
+		}
+		if activityType == ActivityCreatedRepo && created[event.Username+"\x00"+event.Event.Repo] {
+			continue
+		}
+		if activityType == ActivityTagged && released[event.Username+"\x00"+event.Event.Repo+"\x00"+event.Event.Ref] {
+			continue
+		}
+		if opts.HideBranches && event.Event.RefType == "branch" {
+			continue
+		}
+		b.add(Activity{
+			Type:       activityType,
+			User:       event.Username,
+			RepoName:   event.Event.Repo,
+			RepoURL:    b.links.repo(event.Event.Repo),
+			CompareURL: b.links.compare(event.Event.Repo, event.Event.Before, event.Event.Head),
+			Forced:     event.Event.Forced,
+			Timestamp:  event.Event.CreatedAt,
+			Details:    eventDetails(event.Event),
+		})
+	}
+	b.logf("FromDiff after events: userActivities map has %d entries", len(b.users))
+
+	// There's no telling when someone followed someone else, only that the
+	// newer snapshot is the first to show it
+	for _, follow := range result.NewFollows {
+		b.add(Activity{
+			Type:      ActivityFollowed,
+			User:      follow.Username,
+			RepoName:  follow.Followed,
+			RepoURL:   b.links.profile(follow.Followed),
+			Timestamp: result.NewCapturedAt,
+		})
+	}
+
+	rpt := &Report{
+		GeneratedAt: opts.GeneratedAt,
+		PeriodStart: opts.PeriodStart,
+		PeriodEnd:   opts.PeriodEnd,
+		Watched:     b.watchedRepos(result.Watched),
+	}
+	for _, repo := range result.YouStarred {
+		rpt.YouStarred = append(rpt.YouStarred, Activity{
+			Type:     ActivityStarred,
+			RepoName: repo.FullName(),
+			RepoURL:  b.links.repoPage(repo),
+			Details:  repo.Description,
+		})
+	}
+	sortActivities := opts.SortActivities
+	if sortActivities == nil {
+		sortActivities = NewestFirst
+	}
+	for _, ua := range b.users {
+		slices.SortStableFunc(ua.Activities, sortActivities)
+		rpt.UserActivities = append(rpt.UserActivities, *ua)
+	}
+	// Users come out of a map, so the order has to break every tie for
+	// reports to come out the same each run
+	sortUsers := opts.SortUsers
+	if sortUsers == nil {
+		sortUsers = ByActivityCount
+	}
+	slices.SortFunc(rpt.UserActivities, func(a, b UserActivity) int {
+		return cmp.Or(sortUsers(a, b), ByUser(a, b))
+	})
+	b.logf("FromDiff output: UserActivities slice has %d entries", len(rpt.UserActivities))
+
+	return rpt
+}
+
+// ByActivityCount orders users with the most activities first.
+func ByActivityCount(a, b UserActivity) int {
+	return cmp.Compare(len(b.Activities), len(a.Activities))
+}
+
+// ByUser orders users alphabetically by login.
+func ByUser(a, b UserActivity) int {
+	return strings.Compare(a.User, b.User)
+}
+
+// NewestFirst orders activities by timestamp, most recent first.
+func NewestFirst(a, b Activity) int {
+	return b.Timestamp.Compare(a.Timestamp)
+}
+
+// OldestFirst orders activities by timestamp, earliest first.
+func OldestFirst(a, b Activity) int {
+	return a.Timestamp.Compare(b.Timestamp)
+}
+
+// eventDetails describes what an event's payload adds to its type and repo.
+func eventDetails(e diff.Event) string {
+	switch {
+	case e.Member != "":
+		return e.Member + " is now a collaborator"
+	case e.Type == "DeleteEvent" && e.Ref != "":
+		return e.RefType + " " + e.Ref
+	case e.Type == "CreateEvent" && e.RefType != "repository", e.Type == "ReleaseEvent":
+		return e.Ref
+	case len(e.Commits) == 1:
+		return e.Commits[0]
+	case len(e.Commits) > 1:
+		// The head commit, which is the last
+		return fmt.Sprintf("%s (+%d more)", e.Commits[len(e.Commits)-1], len(e.Commits)-1)
+	}
+	return ""
+}
+
+// builder accumulates a report's activities by user.
+type builder struct {
+	users   map[string]*UserActivity
+	seen    map[string]bool   // Nil unless de-duplicating
+	avatars map[string]string // Avatar URLs from the API, by user
+	links   links
+	opts    Options
+}
+
+// add files a under its user, unless the options leave it out.
+func (b *builder) add(a Activity) {
+	if b.opts.SkipBots && strings.HasSuffix(a.User, "[bot]") {
+		return
+	}
+	if b.seen != nil {
+		key := activityKey(a)
+		if b.seen[key] {
+			return
+		}
+		b.seen[key] = true
+	}
+
+	ua, ok := b.users[a.User]
+	if !ok {
+		ua = &UserActivity{
+			User:      a.User,
+			AvatarURL: b.links.avatarOf(a.User, b.avatars[a.User]),
+		}
+		b.users[a.User] = ua
+	}
+	a.AvatarURL = ua.AvatarURL
+	ua.Activities = append(ua.Activities, a)
+}
+
+func (b *builder) logf(format string, args ...any) {
+	if b.opts.Log != nil {
+		_, _ = fmt.Fprintf(b.opts.Log, format+"\n", args...)
+	}
+}
@@ -0,0 +1,477 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+var genTime = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func TestNoDuplicateRepoCreations(t *testing.T) {
+	// This test validates that we don't get duplicate "created repo" entries
+	// when both NewRepos and CreateEvent exist for the same repository.
+	now := time.Now()
+	result := &diff.Result{
+		OldCapturedAt: now.Add(-1 * time.Hour),
+		NewCapturedAt: now,
+		NewRepos: []diff.RepoChange{
+			{
+				Username: "testuser",
+				Repo: diff.Repo{
+					Owner:     "testuser",
+					Name:      "new-repo",
+					CreatedAt: now,
+				},
+			},
+		},
+		NewEvents: []diff.EventChange{
+			{
+				Username: "testuser",
+				Event: diff.Event{
+					Type:      "CreateEvent",
+					Repo:      "testuser/new-repo",
+					CreatedAt: now,
+				},
+			},
+		},
+	}
+
+	rpt := FromDiff(result, Options{GeneratedAt: now, PeriodStart: now.Add(-1 * time.Hour), PeriodEnd: now})
+
+	// Count ActivityCreatedRepo entries
+	createdRepoCount := 0
+	for _, ua := range rpt.UserActivities {
+		for _, a := range ua.Activities {
+			if a.Type == ActivityCreatedRepo {
+				createdRepoCount++
+			}
+		}
+	}
+
+	// Should only have 1 entry (from NewRepos), not 2 (NewRepos + CreateEvent)
+	if createdRepoCount != 1 {
+		t.Errorf("expected 1 ActivityCreatedRepo entry, got %d", createdRepoCount)
+	}
+}
+
+func TestEventActivityType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ActivityType
+	}{
+		{"WatchEvent", ActivityStarred},
+		{"CreateEvent", ""}, // CreateEvent is skipped to avoid duplicates with NewRepos
+		{"ForkEvent", ActivityForked},
+		{"PushEvent", ActivityPushed},
+		{"PullRequestEvent", ActivityPR},
+		{"IssuesEvent", ActivityIssue},
+		{"ReleaseEvent", ActivityReleased},
+		{"PublicEvent", ActivityOpenSourced},
+		{"GollumEvent", ActivityWiki},
+		{"MemberEvent", ActivityMember},
+		{"DeleteEvent", ActivityDeleted},
+		{"IssueCommentEvent", ActivityCommented},
+		{"PullRequestReviewEvent", ActivityReviewed},
+		{"UnknownEvent", ActivityType("UnknownEvent")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := EventActivityType(tt.input)
+			if result != tt.expected {
+				t.Errorf("EventActivityType(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFromDiff(t *testing.T) {
+	result := &diff.Result{
+		OldCapturedAt: genTime.Add(-24 * time.Hour),
+		NewCapturedAt: genTime,
+		NewStars: []diff.RepoChange{
+			{Username: "user1", Repo: diff.Repo{Owner: "owner", Name: "starred-repo", Description: "A repo"}},
+		},
+		NewRepos: []diff.RepoChange{
+			{Username: "user1", Repo: diff.Repo{Owner: "user1", Name: "new-repo"}},
+		},
+		NewEvents: []diff.EventChange{
+			{Username: "user2", Event: diff.Event{Type: "PushEvent", Repo: "user2/repo", CreatedAt: genTime}},
+		},
+		YouStarred: []diff.Repo{{Owner: "owner", Name: "mine"}},
+	}
+
+	rpt := FromDiff(result, Options{GeneratedAt: genTime, PeriodStart: result.OldCapturedAt, PeriodEnd: result.NewCapturedAt})
+
+	// Your own stars are listed apart, not counted as anyone's activity
+	if rpt.TotalActivities() != 3 {
+		t.Errorf("expected 3 total activities, got %d", rpt.TotalActivities())
+	}
+	if len(rpt.YouStarred) != 1 || rpt.YouStarred[0].RepoName != "owner/mine" || rpt.YouStarred[0].RepoURL != "https://github.com/owner/mine" {
+		t.Errorf("YouStarred = %+v, want owner/mine", rpt.YouStarred)
+	}
+
+	if len(rpt.UserActivities) != 2 {
+		t.Errorf("expected 2 users with activities, got %d", len(rpt.UserActivities))
+	}
+}
+
+func TestFromDiff_ManyUsersWithActivity(t *testing.T) {
+	// Simulate 30 users, each with one event - should result in 30 UserActivities
+	result := &diff.Result{
+		OldCapturedAt: genTime.Add(-24 * time.Hour),
+		NewCapturedAt: genTime,
+	}
+
+	// Create 30 users with events
+	for i := 0; i < 30; i++ {
+		username := fmt.Sprintf("user%d", i)
+		result.NewEvents = append(result.NewEvents, diff.EventChange{
+			Username: username,
+			Event:    diff.Event{Type: "PushEvent", Repo: username + "/repo", CreatedAt: genTime},
+		})
+	}
+
+	rpt := FromDiff(result, Options{GeneratedAt: genTime, PeriodStart: result.OldCapturedAt, PeriodEnd: result.NewCapturedAt})
+
+	if len(rpt.UserActivities) != 30 {
+		t.Errorf("expected 30 users with activities, got %d", len(rpt.UserActivities))
+	}
+
+	if rpt.TotalActivities() != 30 {
+		t.Errorf("expected 30 total activities, got %d", rpt.TotalActivities())
+	}
+}
+
+func TestFromDiff_Options(t *testing.T) {
+	push := func(user, repo string) diff.EventChange {
+		return diff.EventChange{Username: user, Event: diff.Event{Type: "PushEvent", Repo: repo, CreatedAt: genTime}}
+	}
+	result := &diff.Result{
+		NewEvents: []diff.EventChange{
+			push("alice", "alice/repo"),
+			push("alice", "alice/repo"),
+			push("bob", "bob/repo"),
+			push("dependabot[bot]", "alice/repo"),
+		},
+	}
+
+	tests := []struct {
+		opts      Options
+		name      string
+		wantUsers []string
+		wantTotal int
+	}{
+		{
+			name:      "defaults keep everything",
+			opts:      Options{SortUsers: ByUser},
+			wantUsers: []string{"alice", "bob", "dependabot[bot]"},
+			wantTotal: 4,
+		},
+		{
+			name:      "skip bots",
+			opts:      Options{SortUsers: ByUser, SkipBots: true},
+			wantUsers: []string{"alice", "bob"},
+			wantTotal: 3,
+		},
+		{
+			name:      "dedup",
+			opts:      Options{SortUsers: ByUser, Dedup: true},
+			wantUsers: []string{"alice", "bob", "dependabot[bot]"},
+			wantTotal: 3,
+		},
+		{
+			name: "sort users",
+			opts: Options{SortUsers: func(a, b UserActivity) int {
+				return strings.Compare(b.User, a.User)
+			}},
+			wantUsers: []string{"dependabot[bot]", "bob", "alice"},
+			wantTotal: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpt := FromDiff(result, tt.opts)
+			var users []string
+			for _, ua := range rpt.UserActivities {
+				users = append(users, ua.User)
+			}
+			if !slices.Equal(users, tt.wantUsers) {
+				t.Errorf("users = %v, want %v", users, tt.wantUsers)
+			}
+			if got := rpt.TotalActivities(); got != tt.wantTotal {
+				t.Errorf("TotalActivities() = %d, want %d", got, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestFromDiff_Log(t *testing.T) {
+	var buf bytes.Buffer
+	FromDiff(&diff.Result{}, Options{Log: &buf})
+	if !strings.Contains(buf.String(), "FromDiff output: UserActivities slice has 0 entries") {
+		t.Errorf("expected output summary in log, got: %s", buf.String())
+	}
+}
+
+func TestFromDiff_Ordering(t *testing.T) {
+	push := func(user string, hoursAgo int) diff.EventChange {
+		return diff.EventChange{Username: user, Event: diff.Event{
+			Type:      "PushEvent",
+			Repo:      fmt.Sprintf("%s/repo%d", user, hoursAgo),
+			CreatedAt: genTime.Add(-time.Duration(hoursAgo) * time.Hour),
+		}}
+	}
+	result := &diff.Result{
+		NewEvents: []diff.EventChange{
+			push("carol", 1),
+			push("bob", 5), push("bob", 1), push("bob", 3),
+			push("alice", 2),
+		},
+	}
+
+	tests := []struct {
+		opts      Options
+		name      string
+		wantUsers []string
+		wantBob   []string
+	}{
+		{
+			name:      "defaults",
+			wantUsers: []string{"bob", "alice", "carol"},
+			wantBob:   []string{"bob/repo1", "bob/repo3", "bob/repo5"},
+		},
+		{
+			name:      "by user, oldest first",
+			opts:      Options{SortUsers: ByUser, SortActivities: OldestFirst},
+			wantUsers: []string{"alice", "bob", "carol"},
+			wantBob:   []string{"bob/repo5", "bob/repo3", "bob/repo1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The builder collects users in a map, so repeat to catch order
+			// leaking through
+			for range 10 {
+				rpt := FromDiff(result, tt.opts)
+				var users, bob []string
+				for _, ua := range rpt.UserActivities {
+					users = append(users, ua.User)
+					if ua.User == "bob" {
+						for _, a := range ua.Activities {
+							bob = append(bob, a.RepoName)
+						}
+					}
+				}
+				if !slices.Equal(users, tt.wantUsers) {
+					t.Fatalf("users = %v, want %v", users, tt.wantUsers)
+				}
+				if !slices.Equal(bob, tt.wantBob) {
+					t.Fatalf("bob's activities = %v, want %v", bob, tt.wantBob)
+				}
+			}
+		})
+	}
+}
+
+func TestFromDiff_LogsUnknownEventTypes(t *testing.T) {
+	var buf bytes.Buffer
+	result := &diff.Result{NewEvents: []diff.EventChange{
+		{Username: "alice", Event: diff.Event{Type: "BrandNewEvent", Repo: "alice/a", CreatedAt: genTime}},
+		{Username: "alice", Event: diff.Event{Type: "BrandNewEvent", Repo: "alice/b", CreatedAt: genTime}},
+		{Username: "alice", Event: diff.Event{Type: "PushEvent", Repo: "alice/a", CreatedAt: genTime}},
+	}}
+	rpt := FromDiff(result, Options{Log: &buf})
+
+	if got := strings.Count(buf.String(), "unknown event type"); got != 1 {
+		t.Errorf("expected one unknown event type line, got %d in: %s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), "BrandNewEvent") {
+		t.Errorf("expected the unknown type to be named, got: %s", buf.String())
+	}
+	if rpt.TotalActivities() != 3 {
+		t.Errorf("expected unknown events to still be reported, got %d activities", rpt.TotalActivities())
+	}
+}
+
+func TestFromDiff_MemberDetails(t *testing.T) {
+	result := &diff.Result{NewEvents: []diff.EventChange{
+		{Username: "alice", Event: diff.Event{Type: "MemberEvent", Actor: "alice", Repo: "alice/repo", Member: "bob", CreatedAt: genTime}},
+	}}
+	rpt := FromDiff(result, Options{})
+	if len(rpt.UserActivities) != 1 || len(rpt.UserActivities[0].Activities) != 1 {
+		t.Fatalf("expected one activity, got %+v", rpt.UserActivities)
+	}
+	a := rpt.UserActivities[0].Activities[0]
+	if a.Type != ActivityMember || a.Details != "bob is now a collaborator" {
+		t.Errorf("expected bob's collaboration, got %+v", a)
+	}
+}
+
+func TestFromDiff_Follows(t *testing.T) {
+	result := &diff.Result{NewCapturedAt: genTime, NewFollows: []diff.FollowChange{{Username: "alice", Followed: "bob"}}}
+	rpt := FromDiff(result, Options{})
+	if len(rpt.UserActivities) != 1 || len(rpt.UserActivities[0].Activities) != 1 {
+		t.Fatalf("expected one activity, got %+v", rpt.UserActivities)
+	}
+	a := rpt.UserActivities[0].Activities[0]
+	if a.Type != ActivityFollowed || a.RepoName != "bob" || a.RepoURL != "https://github.com/bob" || !a.Timestamp.Equal(genTime) {
+		t.Errorf("expected alice following bob as of the new snapshot, got %+v", a)
+	}
+}
+
+func TestFromDiff_DeleteDetails(t *testing.T) {
+	result := &diff.Result{NewEvents: []diff.EventChange{
+		{Username: "alice", Event: diff.Event{Type: "DeleteEvent", Actor: "alice", Repo: "alice/repo", RefType: "tag", Ref: "v1.0", CreatedAt: genTime}},
+	}}
+	rpt := FromDiff(result, Options{})
+	if len(rpt.UserActivities) != 1 || len(rpt.UserActivities[0].Activities) != 1 {
+		t.Fatalf("expected one activity, got %+v", rpt.UserActivities)
+	}
+	a := rpt.UserActivities[0].Activities[0]
+	if a.Type != ActivityDeleted || a.Details != "tag v1.0" {
+		t.Errorf("expected the deleted tag, got %+v", a)
+	}
+}
+
+func TestFromDiff_HideBranches(t *testing.T) {
+	result := &diff.Result{NewEvents: []diff.EventChange{
+		{Username: "alice", Event: diff.Event{Type: "DeleteEvent", Actor: "alice", Repo: "alice/repo", RefType: "branch", Ref: "feature-x", CreatedAt: genTime}},
+		{Username: "alice", Event: diff.Event{Type: "DeleteEvent", Actor: "alice", Repo: "alice/repo", RefType: "tag", Ref: "v1.0", CreatedAt: genTime}},
+		{Username: "alice", Event: diff.Event{Type: "PushEvent", Actor: "alice", Repo: "alice/repo", CreatedAt: genTime}},
+	}}
+
+	if got := len(FromDiff(result, Options{}).UserActivities[0].Activities); got != 3 {
+		t.Errorf("expected all 3 activities by default, got %d", got)
+	}
+
+	rpt := FromDiff(result, Options{HideBranches: true})
+	if len(rpt.UserActivities) != 1 {
+		t.Fatalf("expected one user, got %+v", rpt.UserActivities)
+	}
+	for _, a := range rpt.UserActivities[0].Activities {
+		if a.Details == "branch feature-x" {
+			t.Errorf("expected the branch deletion to be hidden, got %+v", a)
+		}
+	}
+	if got := len(rpt.UserActivities[0].Activities); got != 2 {
+		t.Errorf("expected the tag and push to stay, got %d activities", got)
+	}
+}
+
+func TestFromDiff_StarQuality(t *testing.T) {
+	result := &diff.Result{NewStars: []diff.RepoChange{
+		{Username: "alice", Repo: diff.Repo{Owner: "golang", Name: "go", Description: "The Go programming language", Stars: 120000}},
+		{Username: "alice", Repo: diff.Repo{Owner: "bob", Name: "scratch", Stars: 1}},
+		{Username: "alice", Repo: diff.Repo{Owner: "bob", Name: "notes", Description: "My notes", Stars: 2}},
+		{Username: "alice", Repo: diff.Repo{Owner: "carol", Name: "popular", Description: "  ", Stars: 500}},
+	}}
+	starred := func(opts Options) []string {
+		var names []string
+		for _, ua := range FromDiff(result, opts).UserActivities {
+			for _, a := range ua.Activities {
+				names = append(names, a.RepoName)
+			}
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	if got := starred(Options{}); len(got) != 4 {
+		t.Errorf("expected all 4 stars by default, got %v", got)
+	}
+	if got, want := starred(Options{MinStars: 5}), []string{"carol/popular", "golang/go"}; !slices.Equal(got, want) {
+		t.Errorf("MinStars: 5 = %v, want %v", got, want)
+	}
+	if got, want := starred(Options{HideUndescribedStars: true}), []string{"bob/notes", "golang/go"}; !slices.Equal(got, want) {
+		t.Errorf("HideUndescribedStars = %v, want %v", got, want)
+	}
+	if got, want := starred(Options{MinStars: 5, HideUndescribedStars: true}), []string{"golang/go"}; !slices.Equal(got, want) {
+		t.Errorf("both = %v, want %v", got, want)
+	}
+}
+
+func TestFromDiff_CreateEvents(t *testing.T) {
+	result := &diff.Result{
+		NewRepos: []diff.RepoChange{
+			{Username: "alice", Repo: diff.Repo{Owner: "alice", Name: "listed", CreatedAt: genTime}},
+		},
+		NewEvents: []diff.EventChange{
+			{Username: "alice", Event: diff.Event{Type: "CreateEvent", Repo: "alice/listed", RefType: "repository", CreatedAt: genTime}},
+			{Username: "alice", Event: diff.Event{Type: "CreateEvent", Repo: "alice/unlisted", RefType: "repository", CreatedAt: genTime}},
+			{Username: "alice", Event: diff.Event{Type: "CreateEvent", Repo: "alice/listed", RefType: "tag", Ref: "v1.0", CreatedAt: genTime}},
+			{Username: "alice", Event: diff.Event{Type: "CreateEvent", Repo: "alice/listed", RefType: "branch", Ref: "feature-x", CreatedAt: genTime}},
+		},
+	}
+	rpt := FromDiff(result, Options{})
+
+	got := make(map[ActivityType][]string)
+	for _, a := range rpt.UserActivities[0].Activities {
+		got[a.Type] = append(got[a.Type], a.RepoName+" "+a.Details)
+	}
+	slices.Sort(got[ActivityCreatedRepo])
+	// The listed repo once, from NewRepos, and the one only the event has
+	if want := []string{"alice/listed ", "alice/unlisted "}; !slices.Equal(got[ActivityCreatedRepo], want) {
+		t.Errorf("created repos = %q, want %q", got[ActivityCreatedRepo], want)
+	}
+	if want := []string{"alice/listed v1.0"}; !slices.Equal(got[ActivityTagged], want) {
+		t.Errorf("tags = %q, want %q", got[ActivityTagged], want)
+	}
+	if want := []string{"alice/listed feature-x"}; !slices.Equal(got[ActivityBranched], want) {
+		t.Errorf("branches = %q, want %q", got[ActivityBranched], want)
+	}
+
+	hidden := FromDiff(result, Options{HideBranches: true})
+	for _, a := range hidden.UserActivities[0].Activities {
+		if a.Type == ActivityBranched {
+			t.Errorf("expected branch creations to be hidden, got %+v", a)
+		}
+	}
+}
+
+func TestFromDiff_ReleasesAndTags(t *testing.T) {
+	result := &diff.Result{NewEvents: []diff.EventChange{
+		{Username: "alice", Event: diff.Event{Type: "ReleaseEvent", Repo: "alice/repo", RefType: "tag", Ref: "v2.0", CreatedAt: genTime}},
+		{Username: "alice", Event: diff.Event{Type: "CreateEvent", Repo: "alice/repo", RefType: "tag", Ref: "v2.0", CreatedAt: genTime}},
+		{Username: "alice", Event: diff.Event{Type: "CreateEvent", Repo: "alice/repo", RefType: "tag", Ref: "v2.1-rc1", CreatedAt: genTime}},
+	}}
+	rpt := FromDiff(result, Options{})
+
+	var got []string
+	for _, g := range rpt.ActivitiesByCategory() {
+		for _, a := range g.Activities {
+			got = append(got, string(g.Type)+" "+a.Details)
+		}
+	}
+	// v2.0's tag comes with its release, so only the release shows it
+	want := []string{"released v2.0", "tagged v2.1-rc1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("activities = %q, want %q", got, want)
+	}
+}
+
+func TestFromDiff_PushDetails(t *testing.T) {
+	result := &diff.Result{NewEvents: []diff.EventChange{
+		{Username: "alice", Event: diff.Event{Type: "PushEvent", Repo: "alice/one", Commits: []string{"Fix typo"}, CreatedAt: genTime}},
+		{Username: "alice", Event: diff.Event{Type: "PushEvent", Repo: "alice/two", Commits: []string{"Add a parser", "Fix the parser"}, CreatedAt: genTime}},
+	}}
+	rpt := FromDiff(result, Options{SortActivities: func(a, b Activity) int { return strings.Compare(a.RepoName, b.RepoName) }})
+
+	var got []string
+	for _, a := range rpt.UserActivities[0].Activities {
+		got = append(got, a.Details)
+	}
+	// The head commit, which comes last
+	want := []string{"Fix typo", "Fix the parser (+1 more)"}
+	if !slices.Equal(got, want) {
+		t.Errorf("details = %q, want %q", got, want)
+	}
+}
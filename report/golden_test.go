@@ -0,0 +1,38 @@
+package report_test
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/report/reporttest"
+)
+
+// TestGolden checks every registered format against its golden file in
+// testdata. A new format needs one: run with -update to write it.
+func TestGolden(t *testing.T) {
+	for _, format := range report.Formats() {
+		t.Run(format, func(t *testing.T) {
+			g, err := report.New(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reporttest.Golden(t, g, reporttest.Fixture(), filepath.Join("testdata", "fixture."+format+".golden"))
+		})
+	}
+}
+
+// feedWriter writes a new feed of a report, so feeds can be checked
+// against a golden file like the formats.
+type feedWriter struct{}
+
+func (feedWriter) Generate(w io.Writer, r *report.Report) error {
+	f := report.NewFeed("GitStreams")
+	f.Add(r)
+	return f.Write(w)
+}
+
+func TestGoldenFeed(t *testing.T) {
+	reporttest.Golden(t, feedWriter{}, reporttest.Fixture(), filepath.Join("testdata", "fixture.atom.golden"))
+}
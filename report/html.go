@@ -1,12 +1,17 @@
-// Package report provides HTML report generation for gitstreams.
+// Package report generates gitstreams reports. Output formats are
+// registered by name; HTML is built in.
 package report
 
 import (
 	"fmt"
 	"html/template"
 	"io"
+	"slices"
 	"sort"
+	"strings"
 	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
 )
 
 // ActivityType represents the kind of activity event.
@@ -30,6 +35,23 @@ type Activity struct {
 	RepoURL   string
 	Timestamp time.Time
 	Details   string
+	UserURL   string   // Where the user's name links to, if anywhere
+	Sources   []string // Accounts the user is followed from, shown as badges
+	Tags      []string `json:",omitempty"` // What the repo is about, shown as chips the report can be filtered by
+	// CompareURL is the commits a push pushed, which the repo name links
+	// to instead of the repo when it's known.
+	CompareURL string `json:",omitempty"`
+	// Forced is set for a push that rewrote its branch's history.
+	Forced bool `json:",omitempty"`
+}
+
+// Link returns where a's repo name links to: the commits pushed, for a push
+// whose range is known, or else the repo.
+func (a Activity) Link() string {
+	if a.CompareURL != "" {
+		return a.CompareURL
+	}
+	return a.RepoURL
 }
 
 // AggregatedActivity represents multiple similar activities grouped together.
@@ -42,14 +64,33 @@ type AggregatedActivity struct {
 	RepoURL   string
 	Details   string
 	Type      ActivityType
+	UserURL   string
+	Sources   []string
+	Tags      []string
+	Members   []Activity // The activities collapsed into this one, in report order
 	Count     int
+	// CompareURL is the commits the pushes collapsed into this one pushed,
+	// if they're one unbroken run of pushes to a branch.
+	CompareURL string
+}
+
+// Link returns where a's repo name links to: the commits pushed, for
+// pushes whose range is known, or else the repo.
+func (a AggregatedActivity) Link() string {
+	if a.CompareURL != "" {
+		return a.CompareURL
+	}
+	return a.RepoURL
 }
 
 // UserActivity groups activities by user.
 type UserActivity struct {
 	User       string
+	Name       string // Display name from the user's profile, if known
 	AvatarURL  string
+	UserURL    string // Where the user's name links to, if anywhere
 	Activities []Activity
+	Sources    []string // Accounts the user is followed from, shown as badges
 }
 
 // Report contains all the data needed to generate an HTML report.
@@ -58,6 +99,145 @@ type Report struct {
 	PeriodStart    time.Time
 	PeriodEnd      time.Time
 	UserActivities []UserActivity
+
+	// RefreshInterval, if positive, makes the page reload itself on that
+	// interval so an open tab picks up a report that's being appended to.
+	RefreshInterval time.Duration
+
+	// UpdateNotice, if set, is shown in the footer to announce a newer
+	// gitstreams release.
+	UpdateNotice string
+
+	// Categories arranges the by-category view.
+	Categories CategoryLayout
+
+	// Aggregation controls how the aggregated views collapse activities.
+	Aggregation AggregationRules
+
+	// Provenance, if set, is shown in the footer so readers can judge how
+	// complete the report is.
+	Provenance *Provenance `json:",omitempty"`
+
+	// Pinned lists repos, as owner/name or owner/* for all of an owner's
+	// repos, whose activity is lifted out of the usual views into a
+	// section at the top. Names match case-insensitively, as on GitHub.
+	Pinned []string `json:",omitempty"`
+
+	// Teams lists each team's members by team name, for a by-team view of
+	// an organization's activity.
+	Teams map[string][]string `json:",omitempty"`
+
+	// YouStarred lists the repos the reader starred themselves, as a
+	// personal log kept apart from the activity of those they follow.
+	YouStarred []Activity `json:",omitempty"`
+
+	// Watched lists what happened on the watchlist's repos, which are
+	// followed for themselves rather than through users.
+	Watched []WatchedRepo `json:",omitempty"`
+
+	// Noise decides which activities are automation noise, such as CI
+	// bots' pushes, collapsed into a section of their own.
+	Noise NoiseRules
+}
+
+// Ways a report's data can have been gathered, for Provenance.Mode.
+const (
+	ModeLive       = "live"       // Fetched from GitHub this run
+	ModeCached     = "cached"     // The last snapshot, without contacting GitHub
+	ModeHistorical = "historical" // Compared with an older snapshot than the last
+)
+
+// Provenance is how a report's data was gathered.
+type Provenance struct {
+	From         time.Time // When the snapshot compared against was taken; zero if there wasn't one
+	To           time.Time // When the snapshot reported on was taken
+	Mode         string    // One of the Mode constants
+	Gaps         []string  // Parts of the data that couldn't be fetched, e.g. "events for alice"
+	LookbackDays int       // How far back the fetch reached; 0 if nothing was fetched
+	APIRequests  int       // Requests the fetch made; 0 if nothing was fetched or they weren't counted
+}
+
+// ModeLabel describes p.Mode for the footer.
+func (p *Provenance) ModeLabel() string {
+	switch p.Mode {
+	case ModeLive:
+		return "Live sync"
+	case ModeCached:
+		return "Cached data, not synced"
+	case ModeHistorical:
+		return "Historical comparison"
+	}
+	return p.Mode
+}
+
+// RefreshSeconds returns RefreshInterval in whole seconds, or 0 if the page
+// shouldn't refresh.
+func (r *Report) RefreshSeconds() int {
+	if r.RefreshInterval <= 0 {
+		return 0
+	}
+	return max(1, int(r.RefreshInterval.Seconds()))
+}
+
+// SetUserSources records which accounts each user is followed from, keyed
+// by username, so the report can badge them when several accounts are
+// aggregated.
+func (r *Report) SetUserSources(sources map[string][]string) {
+	for i := range r.UserActivities {
+		ua := &r.UserActivities[i]
+		ua.Sources = sources[ua.User]
+		for j := range ua.Activities {
+			ua.Activities[j].Sources = sources[ua.Activities[j].User]
+		}
+	}
+}
+
+// SetProfiles fills in users' display names, and avatars where the profile
+// has one, from their profiles keyed by username.
+func (r *Report) SetProfiles(profiles map[string]diff.Profile) {
+	for i := range r.UserActivities {
+		ua := &r.UserActivities[i]
+		p, ok := profiles[ua.User]
+		if !ok {
+			continue
+		}
+		ua.Name = p.Name
+		if !httpURL(p.AvatarURL) {
+			continue
+		}
+		ua.AvatarURL = p.AvatarURL
+		for j := range ua.Activities {
+			ua.Activities[j].AvatarURL = ua.AvatarURL
+		}
+	}
+}
+
+// SetRepoTags tags each activity with its repo's tags, keyed by the repo's
+// owner/name.
+func (r *Report) SetRepoTags(tags map[string][]string) {
+	for i := range r.UserActivities {
+		ua := &r.UserActivities[i]
+		for j := range ua.Activities {
+			ua.Activities[j].Tags = tags[ua.Activities[j].RepoName]
+		}
+	}
+}
+
+// TagNames returns every tag the report's activities have, sorted, for
+// the tag filter.
+func (r *Report) TagNames() []string {
+	var names []string
+	for _, ua := range r.UserActivities {
+		for _, a := range ua.Activities {
+			for _, tag := range a.Tags {
+				if !slices.Contains(names, tag) {
+					names = append(names, tag)
+				}
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
 }
 
 // TotalActivities returns the total number of activities in the report.
@@ -83,45 +263,117 @@ type AggregatedCategoryGroup struct {
 
 // ActivitiesByCategory groups all activities by their type for category-based display.
 func (r *Report) ActivitiesByCategory() []CategoryGroup {
-	groups := make(map[ActivityType][]Activity)
+	groups := r.activitiesByType()
 
-	// Collect all activities by type
+	var result []CategoryGroup
+	for _, t := range orderCategories(groups, r.Categories.Order) {
+		result = append(result, CategoryGroup{
+			Type:       t,
+			Activities: groups[t],
+		})
+	}
+
+	return result
+}
+
+// CategoryCount is how many activities of a type a report has.
+type CategoryCount struct {
+	Type  ActivityType
+	Name  string // As the by-category view titles its section, e.g. "New Stars"
+	Icon  string
+	Count int
+}
+
+// CategoryCounts counts the report's activities by type, in the
+// by-category view's order. Pinned activities count, but hidden categories
+// and automation noise don't.
+func (r *Report) CategoryCounts() []CategoryCount {
+	counts := make(map[ActivityType]int)
 	for _, ua := range r.UserActivities {
 		for _, a := range ua.Activities {
-			groups[a.Type] = append(groups[a.Type], a)
+			if !slices.Contains(r.Categories.Hidden, a.Type) && !r.IsNoise(a) {
+				counts[a.Type]++
+			}
 		}
 	}
+	var result []CategoryCount
+	for _, t := range orderCategories(counts, r.Categories.Order) {
+		result = append(result, CategoryCount{Type: t, Name: categoryName(t), Icon: activityIcon(t), Count: counts[t]})
+	}
+	return result
+}
 
-	// Order categories in a sensible way
-	order := []ActivityType{
-		ActivityStarred,
-		ActivityCreatedRepo,
-		ActivityForked,
-		ActivityPushed,
-		ActivityPR,
-		ActivityIssue,
+// singleActivity is a as an aggregated activity of one.
+func singleActivity(a Activity) AggregatedActivity {
+	return AggregatedActivity{
+		Type:       a.Type,
+		User:       a.User,
+		AvatarURL:  a.AvatarURL,
+		RepoName:   a.RepoName,
+		RepoURL:    a.RepoURL,
+		FirstTime:  a.Timestamp,
+		LastTime:   a.Timestamp,
+		Count:      1,
+		Details:    a.Details,
+		UserURL:    a.UserURL,
+		Sources:    a.Sources,
+		Tags:       a.Tags,
+		Members:    []Activity{a},
+		CompareURL: a.CompareURL,
 	}
+}
 
-	var result []CategoryGroup
-	for _, t := range order {
-		if activities, ok := groups[t]; ok && len(activities) > 0 {
-			result = append(result, CategoryGroup{
-				Type:       t,
-				Activities: activities,
-			})
+// activitiesByType collects the activities of every category that isn't
+// hidden, by type, leaving out those shown in sections of their own.
+func (r *Report) activitiesByType() map[ActivityType][]Activity {
+	groups := make(map[ActivityType][]Activity)
+	for _, ua := range r.UserActivities {
+		for _, a := range r.usual(ua.Activities) {
+			if !slices.Contains(r.Categories.Hidden, a.Type) {
+				groups[a.Type] = append(groups[a.Type], a)
+			}
 		}
 	}
+	return groups
+}
 
-	return result
+// Aggregation controls how an activity type's activities are collapsed.
+// The zero value collapses every (user, type, repo) group into one row.
+type Aggregation struct {
+	// MinCount collapses only groups of at least this many activities;
+	// smaller groups are listed one by one.
+	MinCount int
+
+	// Daily collapses each day's activities separately, so weekly reports
+	// show a row per day.
+	Daily bool
+}
+
+// AggregationRules holds the Aggregation for each activity type.
+type AggregationRules struct {
+	ByType  map[ActivityType]Aggregation // Overrides Default for these types
+	Default Aggregation
+}
+
+// For returns the Aggregation for t.
+func (r AggregationRules) For(t ActivityType) Aggregation {
+	if agg, ok := r.ByType[t]; ok {
+		return agg
+	}
+	return r.Default
 }
 
 // aggregateKey returns a unique key for grouping similar activities.
-func aggregateKey(a Activity) string {
+func aggregateKey(a Activity, agg Aggregation) string {
+	if agg.Daily {
+		return fmt.Sprintf("%s|%s|%s|%s", a.User, a.Type, a.RepoName, a.Timestamp.Local().Format(time.DateOnly))
+	}
 	return fmt.Sprintf("%s|%s|%s", a.User, a.Type, a.RepoName)
 }
 
-// aggregateActivities groups similar activities by (user, type, repo).
-func aggregateActivities(activities []Activity) []AggregatedActivity {
+// aggregateActivities groups similar activities by (user, type, repo), and
+// by day for types whose rules say so.
+func aggregateActivities(activities []Activity, rules AggregationRules) []AggregatedActivity {
 	if len(activities) == 0 {
 		return nil
 	}
@@ -131,7 +383,7 @@ func aggregateActivities(activities []Activity) []AggregatedActivity {
 	order := make([]string, 0)
 
 	for _, a := range activities {
-		key := aggregateKey(a)
+		key := aggregateKey(a, rules.For(a.Type))
 		if _, exists := groups[key]; !exists {
 			order = append(order, key)
 		}
@@ -144,6 +396,14 @@ func aggregateActivities(activities []Activity) []AggregatedActivity {
 		group := groups[key]
 		first := group[0]
 
+		// Too few to collapse: list each one
+		if len(group) < rules.For(first.Type).MinCount {
+			for _, a := range group {
+				result = append(result, singleActivity(a))
+			}
+			continue
+		}
+
 		// Find time range
 		firstTime := first.Timestamp
 		lastTime := first.Timestamp
@@ -157,15 +417,20 @@ func aggregateActivities(activities []Activity) []AggregatedActivity {
 		}
 
 		result = append(result, AggregatedActivity{
-			Type:      first.Type,
-			User:      first.User,
-			AvatarURL: first.AvatarURL,
-			RepoName:  first.RepoName,
-			RepoURL:   first.RepoURL,
-			FirstTime: firstTime,
-			LastTime:  lastTime,
-			Count:     len(group),
-			Details:   first.Details,
+			Type:       first.Type,
+			User:       first.User,
+			AvatarURL:  first.AvatarURL,
+			RepoName:   first.RepoName,
+			RepoURL:    first.RepoURL,
+			FirstTime:  firstTime,
+			LastTime:   lastTime,
+			Count:      len(group),
+			Details:    first.Details,
+			UserURL:    first.UserURL,
+			Sources:    first.Sources,
+			Tags:       first.Tags,
+			Members:    group,
+			CompareURL: spannedCompare(group),
 		})
 	}
 
@@ -174,33 +439,14 @@ func aggregateActivities(activities []Activity) []AggregatedActivity {
 
 // AggregatedActivitiesByCategory returns aggregated activities grouped by category.
 func (r *Report) AggregatedActivitiesByCategory() []AggregatedCategoryGroup {
-	groups := make(map[ActivityType][]Activity)
-
-	// Collect all activities by type
-	for _, ua := range r.UserActivities {
-		for _, a := range ua.Activities {
-			groups[a.Type] = append(groups[a.Type], a)
-		}
-	}
-
-	// Order categories in a sensible way
-	order := []ActivityType{
-		ActivityStarred,
-		ActivityCreatedRepo,
-		ActivityForked,
-		ActivityPushed,
-		ActivityPR,
-		ActivityIssue,
-	}
+	groups := r.activitiesByType()
 
 	var result []AggregatedCategoryGroup
-	for _, t := range order {
-		if activities, ok := groups[t]; ok && len(activities) > 0 {
-			result = append(result, AggregatedCategoryGroup{
-				Type:       t,
-				Activities: aggregateActivities(activities),
-			})
-		}
+	for _, t := range orderCategories(groups, r.Categories.Order) {
+		result = append(result, AggregatedCategoryGroup{
+			Type:       t,
+			Activities: aggregateActivities(groups[t], r.Aggregation),
+		})
 	}
 
 	return result
@@ -209,51 +455,43 @@ func (r *Report) AggregatedActivitiesByCategory() []AggregatedCategoryGroup {
 // AggregatedUserActivity holds a user's activities in aggregated form.
 type AggregatedUserActivity struct {
 	User       string
+	Name       string
 	AvatarURL  string
+	UserURL    string
 	Activities []AggregatedActivity
+	Sources    []string
 }
 
-// AggregatedUserActivities returns user activities with similar events aggregated.
+// AggregatedUserActivities returns user activities with similar events
+// aggregated, leaving out users whose only activity is pinned or noise.
 func (r *Report) AggregatedUserActivities() []AggregatedUserActivity {
 	result := make([]AggregatedUserActivity, 0, len(r.UserActivities))
 	for _, ua := range r.UserActivities {
+		activities := r.usual(ua.Activities)
+		if len(activities) == 0 && len(ua.Activities) > 0 {
+			continue
+		}
 		result = append(result, AggregatedUserActivity{
 			User:       ua.User,
+			Name:       ua.Name,
 			AvatarURL:  ua.AvatarURL,
-			Activities: aggregateActivities(ua.Activities),
+			UserURL:    ua.UserURL,
+			Activities: aggregateActivities(activities, r.Aggregation),
+			Sources:    ua.Sources,
 		})
 	}
 	return result
 }
 
-// categoryName returns a human-readable name for the activity type category.
-func categoryName(t ActivityType) string {
-	switch t {
-	case ActivityStarred:
-		return "New Stars"
-	case ActivityCreatedRepo:
-		return "Repos Created"
-	case ActivityForked:
-		return "Forks"
-	case ActivityPushed:
-		return "Recent Pushes"
-	case ActivityPR:
-		return "Pull Requests"
-	case ActivityIssue:
-		return "Issues Opened"
-	default:
-		return "Other Activity"
-	}
-}
-
 // ActivityStats holds counts by activity type.
 type ActivityStats struct {
-	Stars  int
-	Repos  int
-	Forks  int
-	Pushes int
-	PRs    int
-	Issues int
+	OpenSourced int
+	Stars       int
+	Repos       int
+	Forks       int
+	Pushes      int
+	PRs         int
+	Issues      int
 }
 
 // GetStats returns activity counts by type.
@@ -262,6 +500,8 @@ func (r *Report) GetStats() ActivityStats {
 	for _, ua := range r.UserActivities {
 		for _, a := range ua.Activities {
 			switch a.Type {
+			case ActivityOpenSourced:
+				stats.OpenSourced++
 			case ActivityStarred:
 				stats.Stars++
 			case ActivityCreatedRepo:
@@ -288,38 +528,47 @@ type Highlight struct {
 	Reason    string
 }
 
-// GetHighlight returns the most interesting activity to feature.
-// Priority: new repos > PRs > stars > other.
+// String describes the highlight in a few words, e.g. "alice created
+// owner/repo".
+func (h *Highlight) String() string {
+	return h.User + " " + activityVerb(h.Activity.Type) + " " + h.Activity.RepoName
+}
+
+// highlightRanks orders the activity types worth featuring, highest
+// first. Types not listed rank below all of them.
+var highlightRanks = map[ActivityType]int{
+	ActivityOpenSourced: 4, // Rare, and the code was hidden until now
+	ActivityCreatedRepo: 3,
+	ActivityPR:          2,
+	ActivityStarred:     1,
+}
+
+// highlightReasons says why each type of highlight is worth a look.
+var highlightReasons = map[ActivityType]string{
+	ActivityOpenSourced: "🔓 Just went public!",
+	ActivityCreatedRepo: "🚀 Fresh off the press!",
+	ActivityPR:          "💪 Making things happen!",
+	ActivityStarred:     "👀 Spotted something cool!",
+}
+
+// GetHighlight returns the most interesting activity to feature: the first
+// of the highest-ranked type that isn't automation noise.
+// Priority: open-sourced repos > new repos > PRs > stars > other.
 func (r *Report) GetHighlight() *Highlight {
 	var best *Highlight
+	bestRank := -1
 
 	for _, ua := range r.UserActivities {
 		for _, a := range ua.Activities {
-			candidate := &Highlight{Activity: a, User: ua.User, AvatarURL: ua.AvatarURL}
-
-			switch a.Type {
-			case ActivityCreatedRepo:
-				candidate.Reason = "🚀 Fresh off the press!"
-				if best == nil || best.Activity.Type != ActivityCreatedRepo {
-					best = candidate
-				}
-			case ActivityPR:
-				candidate.Reason = "💪 Making things happen!"
-				if best == nil || (best.Activity.Type != ActivityCreatedRepo && best.Activity.Type != ActivityPR) {
-					best = candidate
-				}
-			case ActivityStarred:
-				candidate.Reason = "👀 Spotted something cool!"
-				if best == nil || (best.Activity.Type != ActivityCreatedRepo &&
-					best.Activity.Type != ActivityPR &&
-					best.Activity.Type != ActivityStarred) {
-					best = candidate
-				}
-			default:
-				if best == nil {
-					candidate.Reason = "✨ Check this out!"
-					best = candidate
+			if r.IsNoise(a) {
+				continue
+			}
+			if rank := highlightRanks[a.Type]; rank > bestRank {
+				best = &Highlight{Activity: a, User: ua.User, AvatarURL: ua.AvatarURL, Reason: "✨ Check this out!"}
+				if reason, ok := highlightReasons[a.Type]; ok {
+					best.Reason = reason
 				}
+				bestRank = rank
 			}
 		}
 	}
@@ -327,16 +576,43 @@ func (r *Report) GetHighlight() *Highlight {
 	return best
 }
 
-// MostActiveUser returns the user with the most activities.
+// TopHighlights returns up to n activities worth featuring, best first:
+// those of the ranked types, highest-ranked first and in report order
+// within a rank, leaving out automation noise.
+func (r *Report) TopHighlights(n int) []Highlight {
+	var highlights []Highlight
+	for _, ua := range r.UserActivities {
+		for _, a := range ua.Activities {
+			if _, ranked := highlightRanks[a.Type]; ranked && !r.IsNoise(a) {
+				highlights = append(highlights, Highlight{Activity: a, User: ua.User, AvatarURL: ua.AvatarURL, Reason: highlightReasons[a.Type]})
+			}
+		}
+	}
+	slices.SortStableFunc(highlights, func(a, b Highlight) int {
+		return highlightRanks[b.Activity.Type] - highlightRanks[a.Activity.Type]
+	})
+	return highlights[:min(n, len(highlights))]
+}
+
+// MostActiveUser returns the user with the most activities, not counting
+// automation noise.
 func (r *Report) MostActiveUser() string {
 	if len(r.UserActivities) == 0 {
 		return ""
 	}
 
+	counts := make(map[string]int, len(r.UserActivities))
+	for _, ua := range r.UserActivities {
+		for _, a := range ua.Activities {
+			if !r.IsNoise(a) {
+				counts[ua.User]++
+			}
+		}
+	}
 	sorted := make([]UserActivity, len(r.UserActivities))
 	copy(sorted, r.UserActivities)
-	sort.Slice(sorted, func(i, j int) bool {
-		return len(sorted[i].Activities) > len(sorted[j].Activities)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return counts[sorted[i].User] > counts[sorted[j].User]
 	})
 
 	return sorted[0].User
@@ -344,27 +620,7 @@ func (r *Report) MostActiveUser() string {
 
 // IsHotActivity returns true if this activity type is considered "hot" (high engagement).
 func IsHotActivity(t ActivityType) bool {
-	return t == ActivityCreatedRepo || t == ActivityPR
-}
-
-// activityIcon returns an emoji icon for the activity type.
-func activityIcon(t ActivityType) string {
-	switch t {
-	case ActivityStarred:
-		return "⭐"
-	case ActivityCreatedRepo:
-		return "🆕"
-	case ActivityForked:
-		return "🔱"
-	case ActivityPushed:
-		return "📤"
-	case ActivityPR:
-		return "🔀"
-	case ActivityIssue:
-		return "🐛"
-	default:
-		return "📋"
-	}
+	return t == ActivityOpenSourced || t == ActivityCreatedRepo || t == ActivityPR
 }
 
 // isHot is a template function to check if activity is hot.
@@ -388,63 +644,18 @@ func tagline(count int) string {
 	}
 }
 
-// activityVerb returns a human-readable verb for the activity type.
-func activityVerb(t ActivityType) string {
-	switch t {
-	case ActivityStarred:
-		return "starred"
-	case ActivityCreatedRepo:
-		return "created"
-	case ActivityForked:
-		return "forked"
-	case ActivityPushed:
-		return "pushed to"
-	case ActivityPR:
-		return "opened PR on"
-	case ActivityIssue:
-		return "opened issue on"
-	default:
-		return "acted on"
-	}
-}
-
-// aggregatedVerb returns a human-readable verb for aggregated activities.
-// When count > 1, includes the count (e.g., "pushed 6 times to").
-func aggregatedVerb(t ActivityType, count int) string {
-	if count <= 1 {
-		return activityVerb(t)
-	}
-
-	switch t {
-	case ActivityStarred:
-		return fmt.Sprintf("starred %d repos including", count)
-	case ActivityCreatedRepo:
-		return fmt.Sprintf("created %d repos including", count)
-	case ActivityForked:
-		return fmt.Sprintf("forked %d repos including", count)
-	case ActivityPushed:
-		return fmt.Sprintf("pushed %d times to", count)
-	case ActivityPR:
-		return fmt.Sprintf("opened %d PRs on", count)
-	case ActivityIssue:
-		return fmt.Sprintf("opened %d issues on", count)
-	default:
-		return fmt.Sprintf("acted %d times on", count)
-	}
-}
-
-// timeRange formats a time range as a human-readable string.
-// If first and last are close together, just shows the relative time.
+// timeRange formats a time range as a human-readable string, relative to
+// now. If first and last are close together, just shows the relative time.
 // If they span a significant period, shows the range.
-func timeRange(first, last time.Time) string {
+func timeRange(first, last, now time.Time) string {
 	if first.IsZero() && last.IsZero() {
 		return "unknown time"
 	}
 	if first.IsZero() {
-		return relativeTime(last)
+		return relativeTime(last, now)
 	}
 	if last.IsZero() || first.Equal(last) {
-		return relativeTime(first)
+		return relativeTime(first, now)
 	}
 
 	// Calculate duration between first and last
@@ -452,12 +663,11 @@ func timeRange(first, last time.Time) string {
 
 	// If less than 1 hour apart, just show the most recent time
 	if duration < time.Hour {
-		return relativeTime(last)
+		return relativeTime(last, now)
 	}
 
 	// Format the duration
-	now := time.Now()
-	endRelative := relativeTime(last)
+	endRelative := relativeTime(last, now)
 
 	// Calculate how long the activity spanned
 	hours := int(duration.Hours())
@@ -478,14 +688,13 @@ func timeRange(first, last time.Time) string {
 	return fmt.Sprintf("%s (over %d days)", endRelative, days)
 }
 
-// relativeTime formats a timestamp as a human-readable relative time string.
+// relativeTime formats a timestamp as a human-readable time relative to now.
 // Examples: "just now", "2 hours ago", "yesterday", "3 days ago"
-func relativeTime(t time.Time) string {
+func relativeTime(t, now time.Time) string {
 	if t.IsZero() {
 		return "unknown time"
 	}
 
-	now := time.Now()
 	diff := now.Sub(t)
 
 	// Handle future times (shouldn't happen but be safe)
@@ -533,6 +742,7 @@ const htmlTemplate = `<!DOCTYPE html>
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    {{with .RefreshSeconds}}<meta http-equiv="refresh" content="{{.}}">{{end}}
     <title>GitStreams Activity Report</title>
     <style>
         * {
@@ -648,6 +858,31 @@ const htmlTemplate = `<!DOCTYPE html>
         .category-section details {
             margin: 0;
         }
+        .pinned-section {
+            border-color: #54aeff;
+            margin-bottom: 20px;
+        }
+        .category-section.pinned-section summary {
+            background: #ddf4ff;
+        }
+        .noise-section {
+            opacity: 0.75;
+        }
+        .watched-repo-header {
+            padding: 10px 15px;
+            border-top: 1px solid #d0d7de;
+            display: flex;
+            align-items: center;
+            gap: 10px;
+            font-weight: 600;
+        }
+        .watched-stars {
+            color: #57606a;
+            font-weight: normal;
+        }
+        .stars-gained {
+            color: #1a7f37;
+        }
         .category-section summary {
             background: #f6f8fa;
             padding: 12px 15px;
@@ -732,6 +967,10 @@ const htmlTemplate = `<!DOCTYPE html>
             font-size: 1em;
             flex: 1;
         }
+        .user-name {
+            font-weight: normal;
+            color: #656d76;
+        }
         .user-count {
             background: #ddf4ff;
             color: #0969da;
@@ -740,6 +979,43 @@ const htmlTemplate = `<!DOCTYPE html>
             font-size: 0.85em;
             font-weight: 500;
         }
+        .tag-chip {
+            background: #dafbe1;
+            color: #1a7f37;
+            font-size: 0.7em;
+            padding: 1px 6px;
+            border-radius: 10px;
+            margin-left: 6px;
+            font-weight: 500;
+        }
+        .tag-filter {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 6px;
+            margin-bottom: 20px;
+        }
+        .tag-filter button {
+            padding: 4px 12px;
+            border: 1px solid #d0d7de;
+            background: white;
+            border-radius: 16px;
+            cursor: pointer;
+            font-size: 0.85em;
+        }
+        .tag-filter button.active {
+            background: #1a7f37;
+            border-color: #1a7f37;
+            color: white;
+        }
+        .source-badge {
+            background: #ddf4ff;
+            color: #0969da;
+            font-size: 0.7em;
+            padding: 1px 6px;
+            border-radius: 10px;
+            margin-left: 6px;
+            font-weight: 500;
+        }
         .mvp-badge {
             background: #ffc107;
             color: #000;
@@ -764,6 +1040,9 @@ const htmlTemplate = `<!DOCTYPE html>
         .activity-item:last-child {
             border-bottom: none;
         }
+        .activity-item.tag-hidden {
+            display: none;
+        }
         .activity-item.hot {
             background: linear-gradient(90deg, #fff5f5 0%, white 100%);
         }
@@ -819,6 +1098,22 @@ const htmlTemplate = `<!DOCTYPE html>
         .activity-details:empty {
             display: none;
         }
+        .activity-members {
+            font-size: 0.9em;
+            margin-top: 6px;
+        }
+        .activity-members summary {
+            cursor: pointer;
+            color: #0969da;
+        }
+        .activity-members ul {
+            margin: 6px 0 0;
+            padding-left: 20px;
+            color: #57606a;
+        }
+        .activity-members .activity-time {
+            margin-right: 6px;
+        }
         .empty-state {
             text-align: center;
             padding: 40px;
@@ -828,6 +1123,26 @@ const htmlTemplate = `<!DOCTYPE html>
             font-size: 3em;
             margin-bottom: 10px;
         }
+        .update-notice {
+            text-align: center;
+            margin-top: 20px;
+            font-size: 0.85em;
+            color: #656d76;
+        }
+        .provenance {
+            text-align: center;
+            margin-top: 20px;
+            font-size: 0.8em;
+            color: #656d76;
+        }
+        .provenance-gaps summary {
+            cursor: pointer;
+        }
+        .provenance-gaps ul {
+            display: inline-block;
+            text-align: left;
+            margin: 8px 0 0;
+        }
         .view-toggle {
             display: flex;
             gap: 8px;
@@ -849,10 +1164,28 @@ const htmlTemplate = `<!DOCTYPE html>
         .view-toggle button:hover:not(.active) {
             background: #f6f8fa;
         }
-        .view-category, .view-user {
+        .category-section, .user-section {
+            content-visibility: auto;
+            contain-intrinsic-size: auto 300px;
+        }
+        .show-more {
+            display: block;
+            width: 100%;
+            padding: 10px 15px;
+            border: none;
+            border-top: 1px solid #d0d7de;
+            background: #f6f8fa;
+            color: #0969da;
+            cursor: pointer;
+            font-size: 0.9em;
+        }
+        .show-more:hover {
+            background: #eaeef2;
+        }
+        .view-category, .view-user, .view-team {
             display: none;
         }
-        .view-category.active, .view-user.active {
+        .view-category.active, .view-user.active, .view-team.active {
             display: block;
         }
     </style>
@@ -873,6 +1206,7 @@ const htmlTemplate = `<!DOCTYPE html>
         </div>
         {{if gt .TotalActivities 0}}
         <div class="stats-grid">
+            {{if gt $stats.OpenSourced 0}}<div class="stat-item"><span class="stat-icon">🔓</span><span class="stat-count">{{$stats.OpenSourced}}</span> open-sourced</div>{{end}}
             {{if gt $stats.Stars 0}}<div class="stat-item"><span class="stat-icon">⭐</span><span class="stat-count">{{$stats.Stars}}</span> star{{if ne $stats.Stars 1}}s{{end}}</div>{{end}}
             {{if gt $stats.Repos 0}}<div class="stat-item"><span class="stat-icon">🆕</span><span class="stat-count">{{$stats.Repos}}</span> new repo{{if ne $stats.Repos 1}}s{{end}}</div>{{end}}
             {{if gt $stats.PRs 0}}<div class="stat-item"><span class="stat-icon">🔀</span><span class="stat-count">{{$stats.PRs}}</span> PR{{if ne $stats.PRs 1}}s{{end}}</div>{{end}}
@@ -891,18 +1225,83 @@ const htmlTemplate = `<!DOCTYPE html>
             {{if $highlight.AvatarURL}}<img src="{{$highlight.AvatarURL}}" alt="{{$highlight.User}}" class="highlight-avatar">{{end}}
             <span class="highlight-icon">{{icon $highlight.Activity.Type}}</span>
             <div class="highlight-text">
-                <strong>{{$highlight.User}}</strong> {{verb $highlight.Activity.Type}} <a href="{{$highlight.Activity.RepoURL}}">{{$highlight.Activity.RepoName}}</a>
+                <strong>{{$highlight.User}}</strong> {{verb $highlight.Activity.Type}} {{template "repoLink" $highlight.Activity}}
                 <div class="highlight-reason">{{$highlight.Reason}}</div>
             </div>
         </div>
     </div>
     {{end}}
 
+    {{with .TagNames}}
+    <div class="tag-filter">
+        <button class="active" data-tag="" onclick="filterTag(this)">All</button>
+        {{range .}}<button data-tag="{{.}}" onclick="filterTag(this)">{{.}}</button>{{end}}
+    </div>
+    {{end}}
+
+    {{$pinned := .PinnedActivities}}
+    {{if $pinned}}
+    <div class="category-section pinned-section">
+        <details open>
+            <summary>
+                <span class="category-icon">📌</span>
+                <span class="category-title">Pinned</span>
+                <span class="category-count">{{len $pinned}}</span>
+            </summary>
+            {{template "chunkedList" chunk $pinned "categoryItem"}}
+        </details>
+    </div>
+    {{end}}
+
+    {{with .Watched}}
+    <div class="category-section watchlist-section">
+        <details open>
+            <summary>
+                <span class="category-icon">👀</span>
+                <span class="category-title">Watchlist</span>
+                <span class="category-count">{{len .}}</span>
+            </summary>
+            {{range .}}
+            <div class="watched-repo">
+                <div class="watched-repo-header">
+                    {{if .RepoURL}}<a href="{{.RepoURL}}">{{.RepoName}}</a>{{else}}{{.RepoName}}{{end}}
+                    <span class="watched-stars">⭐ {{.Stars}}{{if gt .StarsGained 0}} <span class="stars-gained">+{{.StarsGained}}</span>{{end}}</span>
+                </div>
+                {{with .Rows}}{{template "chunkedList" chunk . "categoryItem"}}{{end}}
+            </div>
+            {{end}}
+        </details>
+    </div>
+    {{end}}
+
+    {{with .YouStarred}}
+    <div class="category-section you-starred">
+        <details>
+            <summary>
+                <span class="category-icon">⭐</span>
+                <span class="category-title">You starred {{len .}} {{if eq (len .) 1}}repo{{else}}repos{{end}}</span>
+            </summary>
+            <ul class="activity-list">
+                {{range .}}
+                <li class="activity-item">
+                    <span class="activity-icon">{{icon .Type}}</span>
+                    <div class="activity-content">
+                        {{template "repoLink" .}}
+                        {{if .Details}}<div class="activity-details">💬 {{.Details}}</div>{{end}}
+                    </div>
+                </li>
+                {{end}}
+            </ul>
+        </details>
+    </div>
+    {{end}}
+
     {{$mostActive := .MostActiveUser}}
     {{if .UserActivities}}
     <div class="view-toggle">
         <button class="active" onclick="toggleView('category')">By Category</button>
         <button onclick="toggleView('user')">By User</button>
+        {{if .Teams}}<button onclick="toggleView('team')">By Team</button>{{end}}
     </div>
 
     <div class="view-category active">
@@ -914,18 +1313,7 @@ const htmlTemplate = `<!DOCTYPE html>
                     <span class="category-title">{{categoryName .Type}}</span>
                     <span class="category-count">{{len .Activities}}</span>
                 </summary>
-                <ul class="activity-list">
-                    {{range .Activities}}
-                    <li class="activity-item{{if isHot .Type}} hot{{end}}">
-                        <span class="activity-icon">{{icon .Type}}{{if isHot .Type}}<span class="hot-badge">🔥</span>{{end}}</span>
-                        <div class="activity-content">
-                            <span class="activity-user">{{if .AvatarURL}}<img src="{{.AvatarURL}}" alt="{{.User}}" class="activity-avatar">{{end}}{{.User}}</span> {{aggVerb .Type .Count}} <a href="{{.RepoURL}}">{{.RepoName}}</a>
-                            <div class="activity-time">{{timeRange .FirstTime .LastTime}}</div>
-                            {{if .Details}}<div class="activity-details">💬 {{.Details}}</div>{{end}}
-                        </div>
-                    </li>
-                    {{end}}
-                </ul>
+                {{template "chunkedList" chunk .Activities "categoryItem"}}
             </details>
         </div>
         {{end}}
@@ -937,31 +1325,87 @@ const htmlTemplate = `<!DOCTYPE html>
             <details open>
                 <summary>
                     {{if .AvatarURL}}<img src="{{.AvatarURL}}" alt="{{.User}}">{{end}}
-                    <h2>{{.User}}</h2>
+                    <h2>{{if .UserURL}}<a href="{{.UserURL}}">{{.User}}</a>{{else}}{{.User}}{{end}}{{with .Name}} <span class="user-name">{{.}}</span>{{end}}</h2>
+                    {{template "sourceBadges" .Sources}}
                     {{if eq .User $mostActive}}<span class="mvp-badge">🏆 MVP</span>{{end}}
                     <span class="user-count">{{len .Activities}}</span>
                 </summary>
-                <ul class="activity-list">
-                    {{range .Activities}}
-                    <li class="activity-item{{if isHot .Type}} hot{{end}}">
-                        <span class="activity-icon">{{icon .Type}}{{if isHot .Type}}<span class="hot-badge">🔥</span>{{end}}</span>
-                        <div class="activity-content">
-                            <span>{{aggVerb .Type .Count}} <a href="{{.RepoURL}}">{{.RepoName}}</a></span>
-                            <div class="activity-time">{{timeRange .FirstTime .LastTime}}</div>
-                            {{if .Details}}<div class="activity-details">💬 {{.Details}}</div>{{end}}
-                        </div>
-                    </li>
-                    {{end}}
-                </ul>
+                {{template "chunkedList" chunk .Activities "userItem"}}
             </details>
         </div>
         {{end}}
     </div>
 
+    {{if .Teams}}
+    <div class="view-team">
+        {{range .AggregatedTeamActivities}}
+        <div class="category-section">
+            <details open>
+                <summary>
+                    <span class="category-icon">👥</span>
+                    <span class="category-title">{{.Team}}</span>
+                    <span class="category-count">{{len .Activities}}</span>
+                </summary>
+                {{template "chunkedList" chunk .Activities "categoryItem"}}
+            </details>
+        </div>
+        {{end}}
+    </div>
+    {{end}}
+
+    {{$noise := .NoiseActivities}}
+    {{if $noise}}
+    <div class="category-section noise-section">
+        <details>
+            <summary>
+                <span class="category-icon">🤖</span>
+                <span class="category-title">Automation noise</span>
+                <span class="category-count">{{len $noise}}</span>
+            </summary>
+            {{template "chunkedList" chunk $noise "categoryItem"}}
+        </details>
+    </div>
+    {{end}}
+
     <script>
+        // Reveal the next deferred chunk of a list. Deferred chunks sit in
+        // <template> elements, which the browser parses but doesn't lay out.
+        function showMore(btn) {
+            const list = btn.previousElementSibling;
+            const next = btn.parentNode.querySelector('template.activity-chunk');
+            if (next) {
+                list.appendChild(next.content);
+                next.remove();
+                applyTagFilter();
+            }
+            let remaining = 0;
+            btn.parentNode.querySelectorAll('template.activity-chunk').forEach(t => remaining += Number(t.dataset.count));
+            if (remaining === 0) {
+                btn.remove();
+            } else {
+                btn.textContent = 'Show more (' + remaining + ' remaining)';
+            }
+        }
+
+        // Show only activities with the chosen tag, or every activity for
+        // the "All" button's empty tag.
+        let activeTag = '';
+        function filterTag(btn) {
+            activeTag = btn.dataset.tag;
+            document.querySelectorAll('.tag-filter button').forEach(b => b.classList.toggle('active', b === btn));
+            applyTagFilter();
+        }
+
+        function applyTagFilter() {
+            document.querySelectorAll('.activity-item').forEach(li => {
+                const tags = (li.dataset.tags || '').split(' ');
+                li.classList.toggle('tag-hidden', activeTag !== '' && !tags.includes(activeTag));
+            });
+        }
+
         function toggleView(view) {
             document.querySelectorAll('.view-toggle button').forEach(b => b.classList.remove('active'));
-            document.querySelectorAll('.view-category, .view-user').forEach(v => v.classList.remove('active'));
+            document.querySelectorAll('.view-category, .view-user, .view-team').forEach(v => v.classList.remove('active'));
             event.target.classList.add('active');
             document.querySelector('.view-' + view).classList.add('active');
         }
@@ -973,17 +1417,126 @@ const htmlTemplate = `<!DOCTYPE html>
             <p>Your network is taking a break. Check back later!</p>
         </div>
     {{end}}
+    {{with .Provenance}}<footer class="provenance">
+        {{.ModeLabel}}
+        · {{if .From.IsZero}}snapshot{{else}}snapshots from {{.From.Local.Format "Jan 2, 2006 at 3:04 PM"}} to{{end}} {{.To.Local.Format "Jan 2, 2006 at 3:04 PM"}}
+        {{- if .LookbackDays}} · {{.LookbackDays}}-day lookback{{end}}
+        {{- if .APIRequests}} · {{.APIRequests}} API requests{{end}}
+        {{- if .Gaps}}
+        <details class="provenance-gaps"><summary>⚠️ {{len .Gaps}} {{if eq (len .Gaps) 1}}gap{{else}}gaps{{end}} in the data</summary>
+            <ul>{{range .Gaps}}<li>{{.}}</li>{{end}}</ul>
+        </details>
+        {{- else}} · no gaps{{end}}
+    </footer>{{end}}
+    {{if .UpdateNotice}}<footer class="update-notice">{{.UpdateNotice}}</footer>{{end}}
 </body>
 </html>
+{{define "chunkedList"}}
+<ul class="activity-list">
+    {{$item := .Item}}
+    {{range .First}}{{template "item" (itemFor $item .)}}{{end}}
+</ul>
+{{if .Rest}}
+<button class="show-more" onclick="showMore(this)">Show more ({{.Remaining}} remaining)</button>
+{{range .Rest}}<template class="activity-chunk" data-count="{{len .}}">{{range .}}{{template "item" (itemFor $item .)}}{{end}}</template>{{end}}
+{{end}}
+{{end}}
+{{define "item"}}{{if eq .Kind "categoryItem"}}{{template "categoryItem" .Activity}}{{else}}{{template "userItem" .Activity}}{{end}}{{end}}
+{{define "categoryItem"}}
+                    <li class="activity-item{{if isHot .Type}} hot{{end}}"{{with .Tags}} data-tags="{{join . " "}}"{{end}}>
+                        <span class="activity-icon">{{icon .Type}}{{if isHot .Type}}<span class="hot-badge">🔥</span>{{end}}</span>
+                        <div class="activity-content">
+                            <span class="activity-user">{{if .AvatarURL}}<img src="{{.AvatarURL}}" alt="{{.User}}" class="activity-avatar" loading="lazy">{{end}}{{if .UserURL}}<a href="{{.UserURL}}">{{.User}}</a>{{else}}{{.User}}{{end}}{{template "sourceBadges" .Sources}}</span> {{aggVerb .Type .Count}} {{template "repoLink" .}}{{template "tagChips" .Tags}}
+                            <div class="activity-time">{{timeRange .FirstTime .LastTime}}</div>
+                            {{if .Details}}<div class="activity-details">💬 {{.Details}}</div>{{end}}
+                            {{if gt .Count 1}}{{template "members" .Members}}{{end}}
+                        </div>
+                    </li>
+{{end}}
+{{define "members"}}
+                            <details class="activity-members">
+                                <summary>Show all {{len .}}</summary>
+                                <ul>
+                                    {{range .}}<li><span class="activity-time">{{relTime .Timestamp}}</span>{{if .Details}} {{.Details}}{{end}}</li>{{end}}
+                                </ul>
+                            </details>
+{{end}}
+{{define "repoLink"}}{{if .Link}}<a href="{{.Link}}">{{.RepoName}}</a>{{else}}{{.RepoName}}{{end}}{{end}}
+{{define "sourceBadges"}}{{range .}}<span class="source-badge">{{.}}</span>{{end}}{{end}}
+{{define "tagChips"}}{{range .}}<span class="tag-chip">{{.}}</span>{{end}}{{end}}
+{{define "userItem"}}
+                    <li class="activity-item{{if isHot .Type}} hot{{end}}"{{with .Tags}} data-tags="{{join . " "}}"{{end}}>
+                        <span class="activity-icon">{{icon .Type}}{{if isHot .Type}}<span class="hot-badge">🔥</span>{{end}}</span>
+                        <div class="activity-content">
+                            <span>{{aggVerb .Type .Count}} {{template "repoLink" .}}{{template "tagChips" .Tags}}</span>
+                            <div class="activity-time">{{timeRange .FirstTime .LastTime}}</div>
+                            {{if .Details}}<div class="activity-details">💬 {{.Details}}</div>{{end}}
+                            {{if gt .Count 1}}{{template "members" .Members}}{{end}}
+                        </div>
+                    </li>
+{{end}}
 `
 
+// renderChunkSize is how many activities a list renders up front. The rest
+// are deferred into inert chunks of the same size behind a "Show more"
+// button, so a week with thousands of activities doesn't stall the browser.
+const renderChunkSize = 50
+
+// activityChunks splits a list into its initially rendered part and the
+// deferred remainder. Item names the template used for each entry.
+type activityChunks struct {
+	Item      string
+	First     []AggregatedActivity
+	Rest      [][]AggregatedActivity
+	Remaining int
+}
+
+// chunkActivities splits activities for lazy rendering with the given item
+// template.
+func chunkActivities(activities []AggregatedActivity, item string) activityChunks {
+	c := activityChunks{Item: item, First: activities}
+	if len(activities) <= renderChunkSize {
+		return c
+	}
+
+	c.First = activities[:renderChunkSize]
+	rest := activities[renderChunkSize:]
+	c.Remaining = len(rest)
+	for len(rest) > 0 {
+		n := min(renderChunkSize, len(rest))
+		c.Rest = append(c.Rest, rest[:n])
+		rest = rest[n:]
+	}
+	return c
+}
+
+// chunkItem pairs an activity with the template that renders it, since
+// templates can only take a single argument.
+type chunkItem struct {
+	Kind     string
+	Activity AggregatedActivity
+}
+
+func newChunkItem(kind string, a AggregatedActivity) chunkItem {
+	return chunkItem{Kind: kind, Activity: a}
+}
+
 // HTMLGenerator generates HTML reports.
 type HTMLGenerator struct {
 	tmpl *template.Template
+	now  func() time.Time // Times are relative to this for reports without a GeneratedAt
+}
+
+// FormatHTML is the name the HTML generator is registered under.
+const FormatHTML = "html"
+
+func init() {
+	Register(FormatHTML, NewHTMLGenerator)
 }
 
 // NewHTMLGenerator creates a new HTMLGenerator with the default template.
 func NewHTMLGenerator() (*HTMLGenerator, error) {
+	g := &HTMLGenerator{now: time.Now}
 	funcMap := template.FuncMap{
 		"icon":         activityIcon,
 		"verb":         activityVerb,
@@ -991,19 +1544,58 @@ func NewHTMLGenerator() (*HTMLGenerator, error) {
 		"isHot":        isHot,
 		"tagline":      tagline,
 		"categoryName": categoryName,
-		"relTime":      relativeTime,
-		"timeRange":    timeRange,
+		// Generate swaps these for ones relative to the report's time
+		"relTime":   func(t time.Time) string { return relativeTime(t, g.now()) },
+		"timeRange": func(first, last time.Time) string { return timeRange(first, last, g.now()) },
+		"chunk":     chunkActivities,
+		"itemFor":   newChunkItem,
+		"join":      strings.Join,
 	}
 
 	tmpl, err := template.New("report").Funcs(funcMap).Parse(htmlTemplate)
 	if err != nil {
 		return nil, err
 	}
+	g.tmpl = tmpl
+	return g, nil
+}
 
-	return &HTMLGenerator{tmpl: tmpl}, nil
+// SetNow sets the clock times in reports without a GeneratedAt are
+// relative to. It defaults to time.Now.
+func (g *HTMLGenerator) SetNow(now func() time.Time) {
+	g.now = now
+}
+
+// SetTemplate replaces the report's template with text, an html/template
+// that can use the default one's functions and the templates it defines,
+// like "repoLink".
+func (g *HTMLGenerator) SetTemplate(text string) error {
+	tmpl, err := g.tmpl.Clone()
+	if err != nil {
+		return fmt.Errorf("cloning template: %w", err)
+	}
+	if _, err := tmpl.Parse(text); err != nil {
+		return fmt.Errorf("parsing template: %w", err)
+	}
+	g.tmpl = tmpl
+	return nil
 }
 
 // Generate writes an HTML report to the provided writer.
 func (g *HTMLGenerator) Generate(w io.Writer, report *Report) error {
-	return g.tmpl.Execute(w, report)
+	// Times are relative to when the report was generated, so an archived
+	// report reads the same whenever it's opened
+	now := report.GeneratedAt
+	if now.IsZero() {
+		now = g.now()
+	}
+	tmpl, err := g.tmpl.Clone()
+	if err != nil {
+		return fmt.Errorf("cloning template: %w", err)
+	}
+	tmpl.Funcs(template.FuncMap{
+		"relTime":   func(t time.Time) string { return relativeTime(t, now) },
+		"timeRange": func(first, last time.Time) string { return timeRange(first, last, now) },
+	})
+	return tmpl.Execute(w, report)
 }
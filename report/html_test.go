@@ -2,9 +2,14 @@ package report
 
 import (
 	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
 )
 
 func TestActivityIcon(t *testing.T) {
@@ -289,6 +294,13 @@ func TestActivityTypes(t *testing.T) {
 		ActivityPushed,
 		ActivityPR,
 		ActivityIssue,
+		ActivityReleased,
+		ActivityOpenSourced,
+		ActivityReviewed,
+		ActivityCommented,
+		ActivityWiki,
+		ActivityMember,
+		ActivityDeleted,
 	}
 
 	for _, at := range types {
@@ -402,7 +414,7 @@ func TestRelativeTime(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := relativeTime(tt.input)
+			got := relativeTime(tt.input, now)
 			if got != tt.expected {
 				t.Errorf("relativeTime() = %q, want %q", got, tt.expected)
 			}
@@ -494,6 +506,7 @@ func TestGetStats(t *testing.T) {
 					{Type: ActivityPR, Timestamp: now},
 					{Type: ActivityIssue, Timestamp: now},
 					{Type: ActivityForked, Timestamp: now},
+					{Type: ActivityOpenSourced, Timestamp: now},
 				},
 			},
 		},
@@ -501,6 +514,10 @@ func TestGetStats(t *testing.T) {
 
 	stats := report.GetStats()
 
+	if stats.OpenSourced != 1 {
+		t.Errorf("GetStats().OpenSourced = %d, want 1", stats.OpenSourced)
+	}
+
 	if stats.Stars != 2 {
 		t.Errorf("GetStats().Stars = %d, want 2", stats.Stars)
 	}
@@ -533,6 +550,28 @@ func TestGetHighlight(t *testing.T) {
 			report:  &Report{},
 			wantNil: true,
 		},
+		{
+			name: "prefers open-sourced repos above all",
+			report: &Report{
+				UserActivities: []UserActivity{
+					{
+						User: "alice",
+						Activities: []Activity{
+							{Type: ActivityCreatedRepo, Timestamp: now},
+							{Type: ActivityPR, Timestamp: now},
+						},
+					},
+					{
+						User: "bob",
+						Activities: []Activity{
+							{Type: ActivityOpenSourced, Timestamp: now},
+						},
+					},
+				},
+			},
+			wantType: ActivityOpenSourced,
+			wantUser: "bob",
+		},
 		{
 			name: "prefers new repos",
 			report: &Report{
@@ -593,6 +632,82 @@ func TestGetHighlight(t *testing.T) {
 	}
 }
 
+func TestCategoryCounts(t *testing.T) {
+	r := &Report{
+		Categories: CategoryLayout{Order: []ActivityType{ActivityPushed}, Hidden: []ActivityType{ActivityForked}},
+		Pinned:     []string{"golang/go"},
+		Noise:      NoiseRules{Actors: DefaultNoiseActors},
+		UserActivities: []UserActivity{
+			{User: "alice", Activities: []Activity{
+				{Type: ActivityStarred, RepoName: "golang/go"},
+				{Type: ActivityStarred, RepoName: "rust-lang/rust"},
+				{Type: ActivityPushed, RepoName: "alice/tool"},
+				{Type: ActivityForked, RepoName: "bob/tool"},
+			}},
+			{User: "dependabot[bot]", Activities: []Activity{
+				{Type: ActivityPushed, User: "dependabot[bot]", RepoName: "alice/tool"},
+			}},
+		},
+	}
+
+	got := r.CategoryCounts()
+	want := []CategoryCount{
+		{Type: ActivityPushed, Name: categoryName(ActivityPushed), Icon: activityIcon(ActivityPushed), Count: 1},
+		{Type: ActivityStarred, Name: categoryName(ActivityStarred), Icon: activityIcon(ActivityStarred), Count: 2},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("CategoryCounts() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTopHighlights(t *testing.T) {
+	r := &Report{
+		Noise: NoiseRules{Actors: DefaultNoiseActors},
+		UserActivities: []UserActivity{
+			{User: "alice", Activities: []Activity{
+				{Type: ActivityStarred, RepoName: "golang/go"},
+				{Type: ActivityPushed, RepoName: "alice/tool"},
+				{Type: ActivityPR, RepoName: "bob/tool"},
+			}},
+			{User: "dependabot[bot]", Activities: []Activity{
+				{Type: ActivityPR, User: "dependabot[bot]", RepoName: "alice/tool"},
+			}},
+			{User: "bob", Activities: []Activity{
+				{Type: ActivityCreatedRepo, RepoName: "bob/new"},
+				{Type: ActivityStarred, RepoName: "rust-lang/rust"},
+			}},
+		},
+	}
+
+	var got []string
+	for _, h := range r.TopHighlights(5) {
+		got = append(got, h.String())
+	}
+	// Best first, in report order within a rank; pushes and bots left out
+	want := []string{
+		"bob created bob/new",
+		"alice opened PR on bob/tool",
+		"alice starred golang/go",
+		"bob starred rust-lang/rust",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("TopHighlights(5) =\n%q\nwant\n%q", got, want)
+	}
+	if got := r.TopHighlights(2); len(got) != 2 || got[0].Reason != highlightReasons[ActivityCreatedRepo] {
+		t.Errorf("TopHighlights(2) = %+v, want the best two with their reasons", got)
+	}
+	if got := (&Report{}).TopHighlights(3); len(got) != 0 {
+		t.Errorf("TopHighlights() of an empty report = %+v", got)
+	}
+}
+
+func TestHighlightString(t *testing.T) {
+	h := &Highlight{User: "alice", Activity: Activity{Type: ActivityPR, RepoName: "owner/repo"}}
+	if got, want := h.String(), "alice opened PR on owner/repo"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
 func TestMostActiveUser(t *testing.T) {
 	now := time.Now()
 
@@ -650,6 +765,7 @@ func TestIsHotActivity(t *testing.T) {
 		activityType ActivityType
 		want         bool
 	}{
+		{ActivityOpenSourced, true},
 		{ActivityCreatedRepo, true},
 		{ActivityPR, true},
 		{ActivityStarred, false},
@@ -744,10 +860,70 @@ func TestHTMLGeneratorGenerateCategoryView(t *testing.T) {
 	}
 }
 
+func TestHTMLGeneratorGenerateTimesRelativeToGeneratedAt(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	// An archived report, opened long after it was generated
+	generated := time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)
+	report := &Report{
+		GeneratedAt: generated,
+		UserActivities: []UserActivity{{
+			User: "alice",
+			Activities: []Activity{
+				{Type: ActivityPushed, User: "alice", RepoName: "alice/repo", Timestamp: generated.Add(-3 * time.Hour)},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, report); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if html := buf.String(); !strings.Contains(html, "3 hours ago") {
+		t.Error("expected the push's time relative to when the report was generated")
+	}
+
+	// Generating again still works, with the template cloned each time
+	buf.Reset()
+	if err := gen.Generate(&buf, report); err != nil {
+		t.Fatalf("second Generate() error = %v", err)
+	}
+}
+
+func TestHTMLGeneratorSetNow(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+	now := time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)
+	gen.SetNow(func() time.Time { return now })
+
+	// Without a GeneratedAt, times are relative to the generator's clock
+	report := &Report{
+		UserActivities: []UserActivity{{
+			User: "alice",
+			Activities: []Activity{
+				{Type: ActivityPushed, User: "alice", RepoName: "alice/repo", Timestamp: now.Add(-5 * time.Hour)},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, report); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if html := buf.String(); !strings.Contains(html, "5 hours ago") {
+		t.Error("expected the push's time relative to the generator's clock")
+	}
+}
+
 func TestRelativeTimeOldDates(t *testing.T) {
 	// Test dates more than a year old - should show absolute date
 	oldDate := time.Date(2020, 6, 15, 10, 30, 0, 0, time.UTC)
-	got := relativeTime(oldDate)
+	got := relativeTime(oldDate, time.Now())
 
 	// Should contain the year
 	if !strings.Contains(got, "2020") {
@@ -826,7 +1002,7 @@ func TestAggregateActivities(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := aggregateActivities(tt.activities)
+			got := aggregateActivities(tt.activities, AggregationRules{})
 			if len(got) != tt.wantLen {
 				t.Errorf("aggregateActivities() returned %d items, want %d", len(got), tt.wantLen)
 			}
@@ -859,12 +1035,16 @@ func TestAggregateActivitiesTimeRange(t *testing.T) {
 		{Type: ActivityPushed, User: "alice", RepoName: "repo1", Timestamp: earliest},
 	}
 
-	got := aggregateActivities(activities)
+	got := aggregateActivities(activities, AggregationRules{})
 
 	if len(got) != 1 {
 		t.Fatalf("expected 1 aggregated activity, got %d", len(got))
 	}
 
+	if len(got[0].Members) != 3 || !got[0].Members[0].Timestamp.Equal(now) {
+		t.Errorf("expected the 3 pushes as members, in order, got %+v", got[0].Members)
+	}
+
 	// FirstTime should be the earliest, LastTime should be the latest
 	if !got[0].FirstTime.Equal(earliest) {
 		t.Errorf("FirstTime = %v, want %v", got[0].FirstTime, earliest)
@@ -874,6 +1054,43 @@ func TestAggregateActivitiesTimeRange(t *testing.T) {
 	}
 }
 
+func TestAggregateActivitiesRules(t *testing.T) {
+	day1 := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	activities := []Activity{
+		{Type: ActivityPushed, User: "alice", RepoName: "repo1", Timestamp: day1},
+		{Type: ActivityPushed, User: "alice", RepoName: "repo1", Timestamp: day1.Add(time.Hour)},
+		{Type: ActivityPushed, User: "alice", RepoName: "repo1", Timestamp: day2},
+		{Type: ActivityStarred, User: "alice", RepoName: "repo2", Timestamp: day1},
+		{Type: ActivityStarred, User: "alice", RepoName: "repo2", Timestamp: day2},
+	}
+	counts := func(got []AggregatedActivity) []int {
+		var c []int
+		for _, a := range got {
+			c = append(c, a.Count)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		rules AggregationRules
+		want  []int
+	}{
+		{"default", AggregationRules{}, []int{3, 2}},
+		{"daily", AggregationRules{Default: Aggregation{Daily: true}}, []int{2, 1, 1, 1}},
+		{"threshold", AggregationRules{Default: Aggregation{MinCount: 3}}, []int{3, 1, 1}},
+		{"per type", AggregationRules{ByType: map[ActivityType]Aggregation{ActivityPushed: {Daily: true, MinCount: 2}}}, []int{2, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := counts(aggregateActivities(activities, tt.rules)); !slices.Equal(got, tt.want) {
+				t.Errorf("counts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestAggregatedVerb(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -921,7 +1138,7 @@ func TestTimeRange(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := timeRange(tt.first, tt.last)
+			got := timeRange(tt.first, tt.last, now)
 			if tt.contains != "" && !strings.Contains(got, tt.contains) {
 				t.Errorf("timeRange() = %q, should contain %q", got, tt.contains)
 			}
@@ -1072,3 +1289,367 @@ func TestHTMLGeneratorGenerateWithAggregation(t *testing.T) {
 		t.Errorf("datasette should appear limited times due to aggregation, but appeared %d times", count)
 	}
 }
+
+func TestHTMLGeneratorGenerateAggregatedMembers(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	now := time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)
+	report := &Report{
+		GeneratedAt: now,
+		UserActivities: []UserActivity{{
+			User: "alice",
+			Activities: []Activity{
+				{Type: ActivityPushed, User: "alice", RepoName: "alice/repo", Timestamp: now, Details: "Fix the parser"},
+				{Type: ActivityPushed, User: "alice", RepoName: "alice/repo", Timestamp: now.Add(-time.Hour), Details: "Add a parser"},
+				{Type: ActivityStarred, User: "alice", RepoName: "bob/lib", Timestamp: now},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, report); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	html := buf.String()
+
+	// Once per view, for the pushes only
+	if got := strings.Count(html, "Show all 2"); got != 2 {
+		t.Errorf("expected the pushes to be expandable in both views, found %d", got)
+	}
+	if strings.Count(html, "<details class=\"activity-members\">") != 2 {
+		t.Error("expected the single star not to be expandable")
+	}
+	if !strings.Contains(html, "Add a parser") {
+		t.Error("expected the earlier push's message among the members")
+	}
+}
+
+func TestChunkActivities(t *testing.T) {
+	tests := []struct {
+		name          string
+		n             int
+		wantFirst     int
+		wantRest      int
+		wantRemaining int
+	}{
+		{name: "empty", n: 0, wantFirst: 0},
+		{name: "under one chunk", n: renderChunkSize - 1, wantFirst: renderChunkSize - 1},
+		{name: "exactly one chunk", n: renderChunkSize, wantFirst: renderChunkSize},
+		{name: "one extra", n: renderChunkSize + 1, wantFirst: renderChunkSize, wantRest: 1, wantRemaining: 1},
+		{name: "several chunks", n: renderChunkSize*3 + 5, wantFirst: renderChunkSize, wantRest: 3, wantRemaining: renderChunkSize*2 + 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activities := make([]AggregatedActivity, tt.n)
+			c := chunkActivities(activities, "categoryItem")
+
+			if len(c.First) != tt.wantFirst {
+				t.Errorf("First has %d items, want %d", len(c.First), tt.wantFirst)
+			}
+			if len(c.Rest) != tt.wantRest {
+				t.Errorf("Rest has %d chunks, want %d", len(c.Rest), tt.wantRest)
+			}
+			if c.Remaining != tt.wantRemaining {
+				t.Errorf("Remaining = %d, want %d", c.Remaining, tt.wantRemaining)
+			}
+			for i, chunk := range c.Rest {
+				if len(chunk) == 0 || len(chunk) > renderChunkSize {
+					t.Errorf("chunk %d has %d items", i, len(chunk))
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLGeneratorGenerateLargeReportIsChunked(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	// Distinct repos so nothing aggregates away
+	var activities []Activity
+	for i := 0; i < renderChunkSize*2+10; i++ {
+		activities = append(activities, Activity{
+			Type:     ActivityStarred,
+			User:     "alice",
+			RepoName: fmt.Sprintf("owner/repo-%d", i),
+			RepoURL:  fmt.Sprintf("https://github.com/owner/repo-%d", i),
+		})
+	}
+	report := &Report{
+		GeneratedAt:    time.Now(),
+		UserActivities: []UserActivity{{User: "alice", Activities: activities}},
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, report); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	html := buf.String()
+
+	// Every activity is still present in both views...
+	if got := strings.Count(html, "owner/repo-"+strconv.Itoa(renderChunkSize*2+9)+"</a>"); got != 2 {
+		t.Errorf("expected last activity once per view, found %d", got)
+	}
+	// ...but only the first chunk of each list is live markup
+	if got := strings.Count(html, `<template class="activity-chunk" data-count="`); got != 4 {
+		t.Errorf("expected 2 deferred chunks per view, found %d", got)
+	}
+	if !strings.Contains(html, "Show more (60 remaining)") {
+		t.Error("expected show-more button with remaining count")
+	}
+}
+
+func TestHTMLGeneratorGenerateSmallReportNotChunked(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	report := &Report{
+		GeneratedAt: time.Now(),
+		UserActivities: []UserActivity{{
+			User:       "alice",
+			Activities: []Activity{{Type: ActivityStarred, User: "alice", RepoName: "a/b"}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, report); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(buf.String(), `class="show-more"`) {
+		t.Error("expected no show-more button for a small report")
+	}
+}
+
+func TestHTMLGeneratorGenerateUpdateNotice(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, &Report{GeneratedAt: time.Now()}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(buf.String(), `class="update-notice"`) {
+		t.Error("expected no update notice by default")
+	}
+
+	buf.Reset()
+	notice := "gitstreams v1.3.0 is available (you have v1.2.0)"
+	if err := gen.Generate(&buf, &Report{GeneratedAt: time.Now(), UpdateNotice: notice}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `<footer class="update-notice">`+notice+`</footer>`) {
+		t.Error("expected update notice in the footer")
+	}
+}
+
+func TestHTMLGeneratorGenerateProvenance(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+	to := time.Date(2025, 1, 15, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		prov    *Provenance
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "none",
+			notWant: []string{`class="provenance"`},
+		},
+		{
+			name: "live with gaps",
+			prov: &Provenance{
+				Mode: ModeLive, From: to.Add(-24 * time.Hour), To: to, LookbackDays: 30, APIRequests: 42,
+				Gaps: []string{"events for alice", "starred repos for <bob>"},
+			},
+			want: []string{
+				"Live sync", "snapshots from Jan 14, 2025 at 12:00 PM to Jan 15, 2025 at 12:00 PM",
+				"30-day lookback", "42 API requests", "2 gaps in the data",
+				"<li>events for alice</li>", "starred repos for &lt;bob&gt;",
+			},
+			notWant: []string{"no gaps"},
+		},
+		{
+			name:    "cached",
+			prov:    &Provenance{Mode: ModeCached, To: to},
+			want:    []string{"Cached data, not synced", "snapshot Jan 15, 2025 at 12:00 PM", "no gaps"},
+			notWant: []string{"lookback", "API requests"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := gen.Generate(&buf, &Report{GeneratedAt: to, Provenance: tt.prov}); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			out := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected %q in the footer", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out, notWant) {
+					t.Errorf("expected no %q", notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLGeneratorGenerateSourceBadges(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	report := &Report{
+		GeneratedAt: time.Now(),
+		UserActivities: []UserActivity{{
+			User:       "alice",
+			Activities: []Activity{{Type: ActivityStarred, User: "alice", RepoName: "a/b"}},
+		}},
+	}
+	report.SetUserSources(map[string][]string{"alice": {"work", "personal"}})
+
+	if got := report.UserActivities[0].Activities[0].Sources; len(got) != 2 {
+		t.Errorf("expected sources on activities, got %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, report); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	// Once in the category view and once in the user view
+	if got := strings.Count(buf.String(), `<span class="source-badge">work</span>`); got != 2 {
+		t.Errorf("expected 2 work badges, got %d", got)
+	}
+}
+
+func TestReportSetProfiles(t *testing.T) {
+	report := &Report{UserActivities: []UserActivity{
+		{User: "alice", AvatarURL: "https://github.com/alice.png", Activities: []Activity{{Type: ActivityPushed, User: "alice", RepoName: "alice/repo"}}},
+		{User: "bob", AvatarURL: "https://github.com/bob.png"},
+		{User: "carol", AvatarURL: "https://github.com/carol.png"},
+	}}
+	report.SetProfiles(map[string]diff.Profile{
+		"alice": {Name: "Alice Liddell", AvatarURL: "https://avatars.githubusercontent.com/u/1?v=4"},
+		"bob":   {Name: "Bob", AvatarURL: "javascript:alert(1)"},
+	})
+
+	alice := report.UserActivities[0]
+	if alice.Name != "Alice Liddell" || alice.AvatarURL != "https://avatars.githubusercontent.com/u/1?v=4" || alice.Activities[0].AvatarURL != alice.AvatarURL {
+		t.Errorf("expected alice's name and avatar from her profile, got %+v", alice)
+	}
+	if bob := report.UserActivities[1]; bob.Name != "Bob" || bob.AvatarURL != "https://github.com/bob.png" {
+		t.Errorf("expected bob's name and his built avatar, got %+v", bob)
+	}
+	if carol := report.UserActivities[2]; carol.Name != "" {
+		t.Errorf("expected no name without a profile, got %+v", carol)
+	}
+
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, report); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `<span class="user-name">Alice Liddell</span>`) {
+		t.Error("expected alice's display name in the user view")
+	}
+}
+
+func TestHTMLGeneratorGenerateTags(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+	now := time.Now()
+	r := &Report{
+		GeneratedAt: now,
+		UserActivities: []UserActivity{{User: "alice", Activities: []Activity{
+			{Type: ActivityStarred, User: "alice", RepoName: "golang/go", Timestamp: now},
+			{Type: ActivityStarred, User: "alice", RepoName: "alice/dotfiles", Timestamp: now},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, r); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(buf.String(), `<div class="tag-filter">`) {
+		t.Error("expected no tag filter without tags")
+	}
+
+	r.SetRepoTags(map[string][]string{"golang/go": {"go", "languages"}})
+	if names := r.TagNames(); !slices.Equal(names, []string{"go", "languages"}) {
+		t.Errorf("TagNames() = %v", names)
+	}
+	buf.Reset()
+	if err := gen.Generate(&buf, r); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{
+		`<div class="tag-filter">`,
+		`<button data-tag="languages" onclick="filterTag(this)">languages</button>`,
+		`<li class="activity-item" data-tags="go languages">`,
+		`<span class="tag-chip">go</span><span class="tag-chip">languages</span>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %s in the report", want)
+		}
+	}
+	if got := strings.Count(html, `data-tags=`); got != 2 {
+		t.Errorf("expected golang/go's item tagged in both views, got %d tagged items", got)
+	}
+}
+
+func TestHTMLGeneratorGenerateUserLinks(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	report := &Report{
+		GeneratedAt: time.Now(),
+		UserActivities: []UserActivity{
+			{
+				User:       "alice",
+				UserURL:    "/p/me/u/alice",
+				Activities: []Activity{{Type: ActivityStarred, User: "alice", UserURL: "/p/me/u/alice", RepoName: "a/b"}},
+			},
+			{
+				User:       "bob",
+				Activities: []Activity{{Type: ActivityStarred, User: "bob", RepoName: "a/c"}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, report); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	// Once in the category view and once in the user view
+	if got := strings.Count(buf.String(), `<a href="/p/me/u/alice">alice</a>`); got != 2 {
+		t.Errorf("expected 2 links to alice, got %d", got)
+	}
+	if strings.Contains(buf.String(), `">bob</a>`) {
+		t.Error("expected bob, without a UserURL, not to be linked")
+	}
+}
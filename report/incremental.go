@@ -0,0 +1,138 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// ReadJSON decodes a report previously written with WriteJSON.
+func ReadJSON(r io.Reader) (*Report, error) {
+	var rpt Report
+	if err := json.NewDecoder(r).Decode(&rpt); err != nil {
+		return nil, fmt.Errorf("decoding report: %w", err)
+	}
+	return &rpt, nil
+}
+
+// WriteJSON encodes the report's data so a later run can merge into it.
+func (r *Report) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// FormatJSON is the name the JSON generator is registered under.
+const FormatJSON = "json"
+
+func init() {
+	Register(FormatJSON, NewJSONGenerator)
+}
+
+// JSONGenerator writes reports as the JSON WriteJSON does, for scripts and
+// for regenerating a report in another format later.
+type JSONGenerator struct{}
+
+// NewJSONGenerator creates a JSONGenerator.
+func NewJSONGenerator() (*JSONGenerator, error) {
+	return &JSONGenerator{}, nil
+}
+
+// Generate writes the report as JSON.
+func (JSONGenerator) Generate(w io.Writer, r *Report) error {
+	return r.WriteJSON(w)
+}
+
+// Merge folds other into r: activities not already present are appended to
+// their user's list (new users are added at the end), and so are repos
+// you starred and watched repos' activities, the period is widened to
+// cover both reports, and GeneratedAt takes the later of the two. It
+// returns the number of activities added.
+func (r *Report) Merge(other *Report) int {
+	if other == nil {
+		return 0
+	}
+
+	if r.PeriodStart.IsZero() || (!other.PeriodStart.IsZero() && other.PeriodStart.Before(r.PeriodStart)) {
+		r.PeriodStart = other.PeriodStart
+	}
+	if other.PeriodEnd.After(r.PeriodEnd) {
+		r.PeriodEnd = other.PeriodEnd
+	}
+	if other.GeneratedAt.After(r.GeneratedAt) {
+		r.GeneratedAt = other.GeneratedAt
+	}
+
+	byUser := make(map[string]int, len(r.UserActivities))
+	seen := make(map[string]bool)
+	for i, ua := range r.UserActivities {
+		byUser[ua.User] = i
+		for _, a := range ua.Activities {
+			seen[activityKey(a)] = true
+		}
+	}
+
+	added := 0
+	for _, ua := range other.UserActivities {
+		i, ok := byUser[ua.User]
+		if !ok {
+			r.UserActivities = append(r.UserActivities, UserActivity{User: ua.User, AvatarURL: ua.AvatarURL, UserURL: ua.UserURL, Sources: ua.Sources})
+			i = len(r.UserActivities) - 1
+			byUser[ua.User] = i
+		}
+		if r.UserActivities[i].AvatarURL == "" {
+			r.UserActivities[i].AvatarURL = ua.AvatarURL
+		}
+
+		for _, a := range ua.Activities {
+			key := activityKey(a)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			r.UserActivities[i].Activities = append(r.UserActivities[i].Activities, a)
+			added++
+		}
+	}
+
+	for _, a := range other.YouStarred {
+		if !slices.ContainsFunc(r.YouStarred, func(b Activity) bool { return b.RepoName == a.RepoName }) {
+			r.YouStarred = append(r.YouStarred, a)
+			added++
+		}
+	}
+
+	added += r.mergeWatched(other.Watched)
+	return added
+}
+
+// mergeWatched folds watched into r's watchlist section, adding up the
+// stars each repo gained, and returns the number of activities added.
+func (r *Report) mergeWatched(watched []WatchedRepo) int {
+	added := 0
+	for _, w := range watched {
+		i := slices.IndexFunc(r.Watched, func(v WatchedRepo) bool { return v.RepoName == w.RepoName })
+		if i < 0 {
+			w.Activities = slices.Clone(w.Activities)
+			r.Watched = append(r.Watched, w)
+			added += len(w.Activities)
+			continue
+		}
+		existing := &r.Watched[i]
+		existing.Stars = w.Stars
+		existing.StarsGained += w.StarsGained
+		for _, a := range w.Activities {
+			if !slices.ContainsFunc(existing.Activities, func(b Activity) bool { return activityKey(a) == activityKey(b) }) {
+				existing.Activities = append(existing.Activities, a)
+				added++
+			}
+		}
+		slices.SortStableFunc(existing.Activities, NewestFirst)
+	}
+	return added
+}
+
+// activityKey identifies an activity for de-duplication across merges.
+func activityKey(a Activity) string {
+	return string(a.Type) + "|" + a.User + "|" + a.RepoName + "|" + strconv.FormatInt(a.Timestamp.Unix(), 10) + "|" + a.Details
+}
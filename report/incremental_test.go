@@ -0,0 +1,180 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportMerge(t *testing.T) {
+	day := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	star := Activity{Type: ActivityStarred, User: "alice", RepoName: "a/one", Timestamp: day}
+
+	base := &Report{
+		GeneratedAt:    day.Add(time.Hour),
+		PeriodStart:    day,
+		PeriodEnd:      day.Add(time.Hour),
+		UserActivities: []UserActivity{{User: "alice", Activities: []Activity{star}}},
+		YouStarred:     []Activity{{Type: ActivityStarred, RepoName: "c/three"}},
+	}
+	update := &Report{
+		GeneratedAt: day.Add(2 * time.Hour),
+		PeriodStart: day.Add(time.Hour),
+		PeriodEnd:   day.Add(2 * time.Hour),
+		UserActivities: []UserActivity{
+			{User: "alice", Activities: []Activity{
+				star, // already present
+				{Type: ActivityPushed, User: "alice", RepoName: "a/one", Timestamp: day.Add(90 * time.Minute)},
+			}},
+			{User: "bob", AvatarURL: "https://example.com/bob.png", Activities: []Activity{
+				{Type: ActivityPR, User: "bob", RepoName: "b/two", Timestamp: day.Add(time.Hour)},
+			}},
+		},
+		YouStarred: []Activity{{Type: ActivityStarred, RepoName: "c/three"}, {Type: ActivityStarred, RepoName: "c/four"}},
+	}
+
+	if added := base.Merge(update); added != 3 {
+		t.Errorf("Merge() added %d, want 3", added)
+	}
+	if len(base.YouStarred) != 2 || base.YouStarred[1].RepoName != "c/four" {
+		t.Errorf("expected your new star appended once, got %+v", base.YouStarred)
+	}
+
+	if got := base.TotalActivities(); got != 3 {
+		t.Errorf("TotalActivities() = %d, want 3", got)
+	}
+	if len(base.UserActivities) != 2 || base.UserActivities[1].User != "bob" {
+		t.Fatalf("expected bob appended as second user, got %+v", base.UserActivities)
+	}
+	if base.UserActivities[1].AvatarURL == "" {
+		t.Error("expected new user's avatar to be kept")
+	}
+	if !base.PeriodStart.Equal(day) {
+		t.Errorf("PeriodStart = %v, want %v", base.PeriodStart, day)
+	}
+	if !base.PeriodEnd.Equal(day.Add(2 * time.Hour)) {
+		t.Errorf("PeriodEnd = %v, want %v", base.PeriodEnd, day.Add(2*time.Hour))
+	}
+	if !base.GeneratedAt.Equal(update.GeneratedAt) {
+		t.Errorf("GeneratedAt = %v, want %v", base.GeneratedAt, update.GeneratedAt)
+	}
+
+	// Merging the same data again is a no-op
+	if added := base.Merge(update); added != 0 {
+		t.Errorf("second Merge() added %d, want 0", added)
+	}
+	if added := base.Merge(nil); added != 0 {
+		t.Errorf("Merge(nil) added %d, want 0", added)
+	}
+}
+
+func TestReportJSONRoundTrip(t *testing.T) {
+	original := &Report{
+		GeneratedAt: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+		UserActivities: []UserActivity{{
+			User:       "alice",
+			Activities: []Activity{{Type: ActivityStarred, User: "alice", RepoName: "a/b"}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := original.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	restored, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if !restored.GeneratedAt.Equal(original.GeneratedAt) {
+		t.Errorf("GeneratedAt = %v, want %v", restored.GeneratedAt, original.GeneratedAt)
+	}
+	if restored.TotalActivities() != 1 || restored.UserActivities[0].Activities[0].Type != ActivityStarred {
+		t.Errorf("unexpected restored activities: %+v", restored.UserActivities)
+	}
+
+	if _, err := ReadJSON(strings.NewReader("not json")); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestHTMLGeneratorRefreshMeta(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, &Report{RefreshInterval: 5 * time.Minute}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `<meta http-equiv="refresh" content="300">`) {
+		t.Error("expected refresh meta tag")
+	}
+
+	buf.Reset()
+	if err := gen.Generate(&buf, &Report{}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(buf.String(), `http-equiv="refresh"`) {
+		t.Error("expected no refresh meta tag by default")
+	}
+}
+
+func TestJSONGenerator(t *testing.T) {
+	gen, err := New(FormatJSON)
+	if err != nil {
+		t.Fatalf("New(json) error = %v", err)
+	}
+	original := &Report{UserActivities: []UserActivity{{
+		User:       "alice",
+		Activities: []Activity{{Type: ActivityForked, User: "alice", RepoName: "a/b"}},
+	}}}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, original); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	// What it writes reads back like WriteJSON's
+	restored, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if restored.TotalActivities() != 1 || restored.UserActivities[0].Activities[0].Type != ActivityForked {
+		t.Errorf("unexpected restored activities: %+v", restored.UserActivities)
+	}
+}
+
+func TestHTMLGeneratorSetTemplate(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+	rpt := &Report{UserActivities: []UserActivity{{
+		User:       "alice",
+		Activities: []Activity{{Type: ActivityStarred, User: "alice", RepoName: "a/b", RepoURL: "https://github.com/a/b"}},
+	}}}
+
+	// The default template's definitions and functions are still there
+	custom := `{{range .UserActivities}}{{.User}}:{{range .Activities}} {{icon .Type}} {{template "repoLink" .}}{{end}}{{end}}`
+	if err := gen.SetTemplate(custom); err != nil {
+		t.Fatalf("SetTemplate() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, rpt); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if want := `alice: ⭐ <a href="https://github.com/a/b">a/b</a>`; buf.String() != want {
+		t.Errorf("Generate() = %q, want %q", buf.String(), want)
+	}
+
+	if err := gen.SetTemplate("{{.Nope"); err == nil {
+		t.Error("expected an error for a malformed template")
+	}
+	// A bad template leaves the last good one in place
+	buf.Reset()
+	if err := gen.Generate(&buf, rpt); err != nil || !strings.HasPrefix(buf.String(), "alice:") {
+		t.Errorf("expected the custom template still, got %q, %v", buf.String(), err)
+	}
+}
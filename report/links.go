@@ -0,0 +1,166 @@
+package report
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+// DefaultWebURL is where report links point unless Options.WebURL says
+// otherwise.
+const DefaultWebURL = "https://github.com"
+
+var (
+	// validLogin matches GitHub logins: letters, digits and single inner
+	// hyphens, up to 39 characters, plus the "[bot]" suffix of app
+	// accounts.
+	validLogin = regexp.MustCompile(`^[A-Za-z0-9](?:-?[A-Za-z0-9]){0,38}(?:\[bot\])?$`)
+
+	// validRepoName matches GitHub repository names.
+	validRepoName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+
+	// validSHA matches commit SHAs, abbreviated or in full.
+	validSHA = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+)
+
+// ValidLogin reports whether login is a well-formed GitHub login.
+func ValidLogin(login string) bool {
+	return validLogin.MatchString(login)
+}
+
+// ValidRepo reports whether fullName is a well-formed owner/name.
+func ValidRepo(fullName string) bool {
+	owner, name, ok := strings.Cut(fullName, "/")
+	return ok && ValidLogin(owner) && validRepoName.MatchString(name) && name != "." && name != ".."
+}
+
+// links builds web URLs on a GitHub instance.
+type links struct {
+	base *url.URL
+}
+
+// newLinks returns links to the instance at webURL, or to github.com if
+// webURL is empty or isn't an absolute http(s) URL.
+func newLinks(webURL string) links {
+	u, err := ParseWebURL(webURL)
+	if err != nil {
+		u, _ = url.Parse(DefaultWebURL)
+	}
+	return links{base: u}
+}
+
+// ParseWebURL checks that webURL is an absolute http(s) URL, like
+// "https://github.example.com", for Options.WebURL.
+func ParseWebURL(webURL string) (*url.URL, error) {
+	u, err := url.Parse(webURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("%q should be an http(s) address like %s", webURL, DefaultWebURL)
+	}
+	return u, nil
+}
+
+// url returns the web URL for path, escaped.
+func (l links) url(path string) string {
+	u := *l.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + path
+	u.RawPath = ""
+	return u.String()
+}
+
+// repo returns the web URL of the repo fullName, or "" if it isn't a
+// well-formed owner/name, so odd data can't point links elsewhere.
+func (l links) repo(fullName string) string {
+	if !ValidRepo(fullName) {
+		return ""
+	}
+	return l.url(fullName)
+}
+
+// profile returns the web URL of login's profile, or "" if it isn't a
+// well-formed login.
+func (l links) profile(login string) string {
+	if !ValidLogin(login) {
+		return ""
+	}
+	return l.url(login)
+}
+
+// compare returns the web URL of the commits from before to head in the
+// repo fullName, or "" if either isn't a commit SHA. A push that created
+// its branch has a before of all zeros, so there's nothing to compare with.
+func (l links) compare(fullName, before, head string) string {
+	if !ValidRepo(fullName) || !validSHA.MatchString(before) || !validSHA.MatchString(head) || strings.Trim(before, "0") == "" {
+		return ""
+	}
+	return l.url(fullName + "/compare/" + before + "..." + head)
+}
+
+// splitCompare splits a URL made by links.compare into the repo's URL and
+// the commit range.
+func splitCompare(compareURL string) (repoURL, before, head string, ok bool) {
+	repoURL, commits, ok := strings.Cut(compareURL, "/compare/")
+	if !ok {
+		return "", "", "", false
+	}
+	before, head, ok = strings.Cut(commits, "...")
+	return repoURL, before, head, ok
+}
+
+// spannedCompare returns the URL comparing across all of pushes, if each
+// picks up where the one before left off, so they're one branch's pushes
+// with none missing. Otherwise it returns "".
+func spannedCompare(pushes []Activity) string {
+	pushes = slices.SortedStableFunc(slices.Values(pushes), OldestFirst)
+	repoURL, before, head, ok := splitCompare(pushes[0].CompareURL)
+	if !ok {
+		return ""
+	}
+	for _, a := range pushes[1:] {
+		other, from, to, ok := splitCompare(a.CompareURL)
+		if !ok || other != repoURL || from != head {
+			return ""
+		}
+		head = to
+	}
+	return repoURL + "/compare/" + before + "..." + head
+}
+
+// repoPage returns the web URL of r: the one the API gave, if it's an
+// http(s) URL, or else one built from its name.
+func (l links) repoPage(r diff.Repo) string {
+	if _, err := ParseWebURL(r.HTMLURL); err == nil {
+		return r.HTMLURL
+	}
+	return l.repo(r.FullName())
+}
+
+// avatarOf returns apiURL, the avatar the API gave for login, if it's an
+// http(s) URL, or else one built from the login.
+func (l links) avatarOf(login, apiURL string) string {
+	if httpURL(apiURL) {
+		return apiURL
+	}
+	return l.avatar(login)
+}
+
+// httpURL reports whether raw is an absolute http(s) URL.
+func httpURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// avatar returns the avatar image URL for login, or "" if it isn't a
+// well-formed login.
+func (l links) avatar(login string) string {
+	if !ValidLogin(login) {
+		return ""
+	}
+	return l.url(login + ".png")
+}
@@ -0,0 +1,206 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+func TestLinksRepo(t *testing.T) {
+	tests := []struct {
+		fullName string
+		want     string
+	}{
+		{"simonw/datasette", "https://github.com/simonw/datasette"},
+		{"a-b/c.d_e-f", "https://github.com/a-b/c.d_e-f"},
+		{"", ""},
+		{"noslash", ""},
+		{"alice/..", ""},
+		{"alice/repo/extra", ""},
+		{"../../evil.com", ""},
+		{"alice/repo?x=1", ""},
+		{"javascript:alert(1)//x/y", ""},
+		{`alice/"><script>alert(1)</script>`, ""},
+		{"-alice/repo", ""},
+	}
+	for _, tt := range tests {
+		if got := newLinks("").repo(tt.fullName); got != tt.want {
+			t.Errorf("repo(%q) = %q, want %q", tt.fullName, got, tt.want)
+		}
+	}
+}
+
+func TestLinksCompare(t *testing.T) {
+	tests := []struct {
+		fullName, before, head string
+		want                   string
+	}{
+		{"golang/go", "1a2b3c4", "5d6e7f8", "https://github.com/golang/go/compare/1a2b3c4...5d6e7f8"},
+		{"golang/go", "0000000000000000000000000000000000000000", "5d6e7f8", ""}, // A new branch
+		{"golang/go", "", "5d6e7f8", ""},
+		{"golang/go", "1a2b3c4", "../../evil", ""},
+		{"../evil", "1a2b3c4", "5d6e7f8", ""},
+	}
+	for _, tt := range tests {
+		if got := newLinks("").compare(tt.fullName, tt.before, tt.head); got != tt.want {
+			t.Errorf("compare(%q, %q, %q) = %q, want %q", tt.fullName, tt.before, tt.head, got, tt.want)
+		}
+	}
+}
+
+func TestSpannedCompare(t *testing.T) {
+	day := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	l := newLinks("")
+	push := func(at time.Duration, before, head string) Activity {
+		return Activity{Type: ActivityPushed, RepoName: "a/b", Timestamp: day.Add(at), CompareURL: l.compare("a/b", before, head)}
+	}
+
+	// Newest first, as in reports
+	chained := []Activity{push(2*time.Hour, "2222222", "3333333"), push(time.Hour, "1111111", "2222222")}
+	if got := spannedCompare(chained); got != "https://github.com/a/b/compare/1111111...3333333" {
+		t.Errorf("spannedCompare() of consecutive pushes = %q", got)
+	}
+
+	// A push missing in between, or to another branch, breaks the run
+	broken := []Activity{push(2*time.Hour, "9999999", "3333333"), push(time.Hour, "1111111", "2222222")}
+	if got := spannedCompare(broken); got != "" {
+		t.Errorf("spannedCompare() of unrelated pushes = %q, want none", got)
+	}
+	if got := spannedCompare([]Activity{push(time.Hour, "1111111", "2222222"), {Type: ActivityPushed, RepoName: "a/b", Timestamp: day}}); got != "" {
+		t.Errorf("spannedCompare() with a push of unknown range = %q, want none", got)
+	}
+}
+
+func TestLinksAvatar(t *testing.T) {
+	tests := []struct {
+		login string
+		want  string
+	}{
+		{"alice", "https://github.com/alice.png"},
+		{"dependabot[bot]", "https://github.com/dependabot%5Bbot%5D.png"},
+		{"../admin", ""},
+		{"al--ice", ""},
+		{"alice-", ""},
+		{"<img onerror=x>", ""},
+		{strings.Repeat("a", 40), ""},
+	}
+	for _, tt := range tests {
+		if got := newLinks("").avatar(tt.login); got != tt.want {
+			t.Errorf("avatar(%q) = %q, want %q", tt.login, got, tt.want)
+		}
+	}
+}
+
+func TestLinksWebURL(t *testing.T) {
+	tests := []struct {
+		webURL string
+		want   string
+	}{
+		{"", "https://github.com/alice/repo"},
+		{"https://ghe.example.com", "https://ghe.example.com/alice/repo"},
+		{"https://ghe.example.com/", "https://ghe.example.com/alice/repo"},
+		{"http://example.com/github", "http://example.com/github/alice/repo"},
+		{"ftp://example.com", "https://github.com/alice/repo"},
+		{"ghe.example.com", "https://github.com/alice/repo"},
+	}
+	for _, tt := range tests {
+		if got := newLinks(tt.webURL).repo("alice/repo"); got != tt.want {
+			t.Errorf("newLinks(%q).repo() = %q, want %q", tt.webURL, got, tt.want)
+		}
+	}
+}
+
+func TestFromDiff_WebURL(t *testing.T) {
+	result := &diff.Result{NewEvents: []diff.EventChange{
+		{Username: "alice", Event: diff.Event{Type: "PushEvent", Repo: "alice/repo", CreatedAt: genTime}},
+	}}
+	a := FromDiff(result, Options{WebURL: "https://ghe.example.com"}).UserActivities[0].Activities[0]
+	if a.RepoURL != "https://ghe.example.com/alice/repo" || a.AvatarURL != "https://ghe.example.com/alice.png" {
+		t.Errorf("expected links to the enterprise instance, got %q and %q", a.RepoURL, a.AvatarURL)
+	}
+}
+
+func TestFromDiff_APIURLs(t *testing.T) {
+	result := &diff.Result{
+		NewStars: []diff.RepoChange{
+			// Renamed since: the API's URL follows the rename
+			{Username: "alice", Repo: diff.Repo{Owner: "bob", Name: "old-name", HTMLURL: "https://github.com/bob/new-name", CreatedAt: genTime}},
+			{Username: "alice", Repo: diff.Repo{Owner: "bob", Name: "lib", HTMLURL: "javascript:alert(1)", CreatedAt: genTime}},
+		},
+		NewEvents: []diff.EventChange{
+			{Username: "alice", Event: diff.Event{Type: "PushEvent", Actor: "alice", ActorAvatarURL: "https://avatars.githubusercontent.com/u/7?v=4", Repo: "alice/repo", CreatedAt: genTime}},
+			{Username: "carol", Event: diff.Event{Type: "PushEvent", Actor: "carol", Repo: "carol/repo", CreatedAt: genTime}},
+		},
+	}
+	rpt := FromDiff(result, Options{})
+
+	repos := make(map[string]string)
+	avatars := make(map[string]string)
+	for _, ua := range rpt.UserActivities {
+		for _, a := range ua.Activities {
+			repos[a.RepoName] = a.RepoURL
+			avatars[a.User] = a.AvatarURL
+		}
+	}
+	if got := repos["bob/old-name"]; got != "https://github.com/bob/new-name" {
+		t.Errorf("expected the API's repo URL, got %q", got)
+	}
+	if got := repos["bob/lib"]; got != "https://github.com/bob/lib" {
+		t.Errorf("expected a built URL in place of a bad one, got %q", got)
+	}
+	if got := avatars["alice"]; got != "https://avatars.githubusercontent.com/u/7?v=4" {
+		t.Errorf("expected the API's avatar for alice, on her star too, got %q", got)
+	}
+	if got := avatars["carol"]; got != "https://github.com/carol.png" {
+		t.Errorf("expected a built avatar for carol, got %q", got)
+	}
+}
+
+func TestHTMLGeneratorGenerateHostileInput(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	result := &diff.Result{
+		NewStars: []diff.RepoChange{{
+			Username: "alice",
+			Repo: diff.Repo{
+				Owner:       "mallory",
+				Name:        `x"><script>alert(1)</script>`,
+				Description: `</div><script>alert("desc")</script><a href="javascript:alert(2)">`,
+				CreatedAt:   now,
+			},
+		}},
+		NewEvents: []diff.EventChange{{
+			Username: `bob"><img src=x onerror=alert(3)>`,
+			Event:    diff.Event{Type: "PushEvent", Repo: "javascript:alert(4)", CreatedAt: now},
+		}},
+	}
+	rpt := FromDiff(result, Options{GeneratedAt: now})
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, rpt); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	html := buf.String()
+
+	for _, bad := range []string{
+		"<script>alert",
+		"<img src=x",
+		`href="javascript:`,
+		`<a href="">`,
+	} {
+		if strings.Contains(html, bad) {
+			t.Errorf("report contains %q", bad)
+		}
+	}
+	// The names are still shown, escaped
+	if !strings.Contains(html, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Error("expected the hostile repo name to be shown escaped")
+	}
+}
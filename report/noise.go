@@ -0,0 +1,90 @@
+package report
+
+import (
+	"slices"
+	"strings"
+)
+
+// DefaultNoiseActors are the actors whose activity is automation noise
+// unless allowed: GitHub Apps, which are all named with a [bot] suffix, and
+// the CI and dependency bots that act from ordinary accounts.
+var DefaultNoiseActors = []string{
+	"*[bot]",
+	"*-bot",
+	"*-ci",
+	"github-actions",
+	"dependabot*",
+	"renovate*",
+}
+
+// NoiseRules decide which activities are automation noise, which is moved
+// out of the usual views into a collapsed section of its own.
+type NoiseRules struct {
+	// Actors are patterns for the users whose activity is noise. Patterns
+	// match whole usernames case-insensitively, with * matching any run of
+	// characters.
+	Actors []string `json:",omitempty"`
+
+	// Allow are patterns, as for Actors, for users whose activity is never
+	// noise, even when they match Actors or force-push.
+	Allow []string `json:",omitempty"`
+
+	// ForcePushes makes pushes that rewrote their branch's history noise,
+	// whoever made them.
+	ForcePushes bool `json:",omitempty"`
+}
+
+// IsNoise reports whether a is automation noise under r.Noise.
+func (r *Report) IsNoise(a Activity) bool {
+	n := r.Noise
+	if matchesAny(n.Allow, a.User) {
+		return false
+	}
+	return (n.ForcePushes && a.Forced) || matchesAny(n.Actors, a.User)
+}
+
+// NoiseActivities returns the activities that are automation noise,
+// aggregated and newest first. Pinned repos' activities are left where
+// they are, since pinning them asked to see them.
+func (r *Report) NoiseActivities() []AggregatedActivity {
+	var noise []Activity
+	for _, ua := range r.UserActivities {
+		for _, a := range ua.Activities {
+			if r.IsNoise(a) && !r.IsPinned(a.RepoName) {
+				noise = append(noise, a)
+			}
+		}
+	}
+	slices.SortStableFunc(noise, NewestFirst)
+	return aggregateActivities(noise, r.Aggregation)
+}
+
+// matchesAny reports whether name matches any of patterns.
+func matchesAny(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool { return matchActor(p, name) })
+}
+
+// matchActor reports whether name matches pattern, in which * matches any
+// run of characters and everything else matches itself, case-insensitively.
+// It's simpler than path.Match, which would read the [bot] of GitHub Apps'
+// names as a character class.
+func matchActor(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+	first, last := parts[0], parts[len(parts)-1]
+	if len(name) < len(first)+len(last) || !strings.HasPrefix(name, first) || !strings.HasSuffix(name, last) {
+		return false
+	}
+	name = name[len(first) : len(name)-len(last)]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return true
+}
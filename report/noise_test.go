@@ -0,0 +1,152 @@
+package report
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// noiseReport has alice pushing, one of her pushes forced, and
+// dependabot[bot] and ci-runner pushing to her repo.
+func noiseReport(rules NoiseRules) *Report {
+	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	return &Report{
+		GeneratedAt: now,
+		Noise:       rules,
+		UserActivities: []UserActivity{
+			{User: "alice", Activities: []Activity{
+				{Type: ActivityPushed, User: "alice", RepoName: "alice/tool", Timestamp: now.Add(-time.Hour)},
+				{Type: ActivityPushed, User: "alice", RepoName: "alice/tool", Timestamp: now.Add(-2 * time.Hour), Forced: true},
+				{Type: ActivityStarred, User: "alice", RepoName: "golang/go", Timestamp: now.Add(-3 * time.Hour)},
+			}},
+			{User: "dependabot[bot]", Activities: []Activity{
+				{Type: ActivityPushed, User: "dependabot[bot]", RepoName: "alice/tool", Timestamp: now.Add(-10 * time.Minute)},
+				{Type: ActivityPushed, User: "dependabot[bot]", RepoName: "alice/tool", Timestamp: now.Add(-20 * time.Minute)},
+				{Type: ActivityPushed, User: "dependabot[bot]", RepoName: "alice/tool", Timestamp: now.Add(-30 * time.Minute)},
+				{Type: ActivityPushed, User: "dependabot[bot]", RepoName: "alice/tool", Timestamp: now.Add(-40 * time.Minute)},
+			}},
+			{User: "ci-runner", Activities: []Activity{
+				{Type: ActivityPushed, User: "ci-runner", RepoName: "alice/tool", Timestamp: now.Add(-5 * time.Minute)},
+			}},
+		},
+	}
+}
+
+func TestMatchActor(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"github-actions", "github-actions", true},
+		{"github-actions", "GitHub-Actions", true},
+		{"github-actions", "github-actions2", false},
+		{"*[bot]", "dependabot[bot]", true},
+		{"*[bot]", "dependabot", false},
+		{"*[bot]", "b", false},
+		{"dependabot*", "dependabot-preview", true},
+		{"ci-*-runner", "ci-linux-runner", true},
+		{"ci-*-runner", "ci-runner", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxcyyb", false},
+		{"*", "anyone", true},
+	}
+	for _, tt := range tests {
+		if got := matchActor(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchActor(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReportIsNoise(t *testing.T) {
+	r := noiseReport(NoiseRules{Actors: append([]string{"ci-*"}, DefaultNoiseActors...), Allow: []string{"renovate-me"}, ForcePushes: true})
+	tests := []struct {
+		a    Activity
+		want bool
+	}{
+		{Activity{User: "alice"}, false},
+		{Activity{User: "alice", Forced: true}, true},
+		{Activity{User: "dependabot[bot]"}, true},
+		{Activity{User: "ci-runner"}, true},
+		{Activity{User: "renovate"}, true},
+		{Activity{User: "Renovate-Me"}, false},
+		{Activity{User: "renovate-me", Forced: true}, false},
+	}
+	for _, tt := range tests {
+		if got := r.IsNoise(tt.a); got != tt.want {
+			t.Errorf("IsNoise(%+v) = %v, want %v", tt.a, got, tt.want)
+		}
+	}
+
+	if (&Report{}).IsNoise(Activity{User: "dependabot[bot]", Forced: true}) {
+		t.Error("IsNoise() without rules = true, want false")
+	}
+}
+
+func TestReportNoiseActivities(t *testing.T) {
+	r := noiseReport(NoiseRules{Actors: DefaultNoiseActors, ForcePushes: true})
+
+	var got []string
+	for _, a := range r.NoiseActivities() {
+		got = append(got, a.User+" "+string(a.Type)+" "+a.RepoName+" x"+strconv.Itoa(a.Count))
+	}
+	// Aggregated and newest first; ci-runner doesn't match the defaults
+	want := []string{
+		"dependabot[bot] pushed alice/tool x4",
+		"alice pushed alice/tool x1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("NoiseActivities():\n got %v\nwant %v", got, want)
+	}
+
+	// Left out of the usual views, dropping users left with nothing
+	var users []string
+	for _, ua := range r.AggregatedUserActivities() {
+		users = append(users, ua.User+" x"+strconv.Itoa(len(ua.Activities)))
+	}
+	if want := "alice x2 ci-runner x1"; strings.Join(users, " ") != want {
+		t.Errorf("AggregatedUserActivities() = %v, want %s", users, want)
+	}
+	if got := r.MostActiveUser(); got != "alice" {
+		t.Errorf("MostActiveUser() = %q, want alice, not counting noise", got)
+	}
+
+	// Pinning a repo keeps its noise in the pinned section
+	r.Pinned = []string{"alice/tool"}
+	if got := r.NoiseActivities(); got != nil {
+		t.Errorf("NoiseActivities() with the repo pinned = %+v, want none", got)
+	}
+}
+
+func TestHTMLGeneratorGenerateNoise(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, noiseReport(NoiseRules{})); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(buf.String(), `class="category-section noise-section"`) {
+		t.Error("expected no noise section without noise rules")
+	}
+
+	buf.Reset()
+	if err := gen.Generate(&buf, noiseReport(NoiseRules{Actors: DefaultNoiseActors})); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	html := buf.String()
+	views := strings.Index(html, `class="view-toggle"`)
+	noise := strings.Index(html, `class="category-section noise-section"`)
+	if views < 0 || noise < views {
+		t.Fatalf("expected the noise section below the views, at %d and %d", noise, views)
+	}
+	if !strings.Contains(html[noise:], "dependabot[bot]") || strings.Contains(html[:noise], "dependabot[bot]") {
+		t.Error("expected dependabot[bot] only in the noise section")
+	}
+	if !strings.Contains(html[noise:], "<details>\n") {
+		t.Error("expected the noise section collapsed")
+	}
+}
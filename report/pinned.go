@@ -0,0 +1,44 @@
+package report
+
+import (
+	"slices"
+	"strings"
+)
+
+// IsPinned reports whether repo, named owner/name, is one of r.Pinned.
+func (r *Report) IsPinned(repo string) bool {
+	owner, _, ok := strings.Cut(repo, "/")
+	if !ok {
+		return false
+	}
+	for _, pin := range r.Pinned {
+		if strings.EqualFold(pin, repo) || strings.EqualFold(pin, owner+"/*") {
+			return true
+		}
+	}
+	return false
+}
+
+// PinnedActivities returns the activities on pinned repos, aggregated and
+// newest first. Hidden categories don't apply to them.
+func (r *Report) PinnedActivities() []AggregatedActivity {
+	if len(r.Pinned) == 0 {
+		return nil
+	}
+	var pinned []Activity
+	for _, ua := range r.UserActivities {
+		for _, a := range ua.Activities {
+			if r.IsPinned(a.RepoName) {
+				pinned = append(pinned, a)
+			}
+		}
+	}
+	slices.SortStableFunc(pinned, NewestFirst)
+	return aggregateActivities(pinned, r.Aggregation)
+}
+
+// usual returns the activities the usual views show: those not on pinned
+// repos, nor automation noise.
+func (r *Report) usual(activities []Activity) []Activity {
+	return slices.DeleteFunc(slices.Clone(activities), func(a Activity) bool { return r.IsPinned(a.RepoName) || r.IsNoise(a) })
+}
@@ -0,0 +1,114 @@
+package report
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pinnedReport has alice pushing to golang/go and her own repo, and bob
+// starring a repo of hers and forking another.
+func pinnedReport(pins ...string) *Report {
+	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	return &Report{
+		GeneratedAt: now,
+		Pinned:      pins,
+		UserActivities: []UserActivity{
+			{User: "alice", Activities: []Activity{
+				{Type: ActivityPushed, User: "alice", RepoName: "golang/go", Timestamp: now.Add(-time.Hour)},
+				{Type: ActivityPushed, User: "alice", RepoName: "golang/go", Timestamp: now.Add(-2 * time.Hour)},
+				{Type: ActivityPushed, User: "alice", RepoName: "alice/tool", Timestamp: now.Add(-3 * time.Hour)},
+			}},
+			{User: "bob", Activities: []Activity{
+				{Type: ActivityStarred, User: "bob", RepoName: "alice/tool", Timestamp: now.Add(-30 * time.Minute)},
+				{Type: ActivityForked, User: "bob", RepoName: "rust-lang/rust", Timestamp: now.Add(-4 * time.Hour)},
+			}},
+		},
+	}
+}
+
+func TestReportIsPinned(t *testing.T) {
+	r := &Report{Pinned: []string{"golang/go", "Alice/*"}}
+	tests := []struct {
+		repo string
+		want bool
+	}{
+		{"golang/go", true},
+		{"GoLang/Go", true},
+		{"golang/tools", false},
+		{"alice/tool", true},
+		{"alice/anything", true},
+		{"bob/go", false},
+		{"golang", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := r.IsPinned(tt.repo); got != tt.want {
+			t.Errorf("IsPinned(%q) = %v, want %v", tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestReportPinnedActivities(t *testing.T) {
+	r := pinnedReport("golang/go", "alice/*")
+	r.Categories.Hidden = []ActivityType{ActivityStarred}
+
+	var got []string
+	for _, a := range r.PinnedActivities() {
+		got = append(got, a.User+" "+string(a.Type)+" "+a.RepoName+" x"+strconv.Itoa(a.Count))
+	}
+	// Newest first, aggregated, and hidden categories still show
+	want := []string{
+		"bob starred alice/tool x1",
+		"alice pushed golang/go x2",
+		"alice pushed alice/tool x1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("PinnedActivities():\n got %v\nwant %v", got, want)
+	}
+
+	// Lifted out of the usual views, dropping users left with nothing
+	groups := r.AggregatedActivitiesByCategory()
+	if len(groups) != 1 || groups[0].Type != ActivityForked {
+		t.Errorf("AggregatedActivitiesByCategory() = %+v, want only bob's fork", groups)
+	}
+	users := r.AggregatedUserActivities()
+	if len(users) != 1 || users[0].User != "bob" || len(users[0].Activities) != 1 {
+		t.Errorf("AggregatedUserActivities() = %+v, want only bob's fork", users)
+	}
+
+	if got := pinnedReport().PinnedActivities(); got != nil {
+		t.Errorf("PinnedActivities() without pins = %+v, want none", got)
+	}
+}
+
+func TestHTMLGeneratorGeneratePinned(t *testing.T) {
+	gen, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatalf("NewHTMLGenerator() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, pinnedReport()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(buf.String(), `class="category-section pinned-section"`) {
+		t.Error("expected no pinned section without pins")
+	}
+
+	buf.Reset()
+	if err := gen.Generate(&buf, pinnedReport("golang/go")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	html := buf.String()
+	pinned := strings.Index(html, `class="category-section pinned-section"`)
+	views := strings.Index(html, `class="view-toggle"`)
+	if pinned < 0 || views < 0 || pinned > views {
+		t.Fatalf("expected the pinned section above the views, at %d and %d", pinned, views)
+	}
+	if !strings.Contains(html[pinned:views], "golang/go") || strings.Contains(html[views:], "golang/go") {
+		t.Error("expected golang/go only in the pinned section")
+	}
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Generator writes a report in one output format.
+type Generator interface {
+	Generate(w io.Writer, r *Report) error
+}
+
+var (
+	generatorsMu sync.RWMutex
+	generators   = make(map[string]func() (Generator, error))
+)
+
+// Register makes a report format available to New under name. It panics if
+// the name is taken, as two formats claiming it is a programming error.
+func Register[G Generator](name string, newGenerator func() (G, error)) {
+	generatorsMu.Lock()
+	defer generatorsMu.Unlock()
+	if _, dup := generators[name]; dup {
+		panic("report: format " + name + " registered twice")
+	}
+	generators[name] = func() (Generator, error) { return newGenerator() }
+}
+
+// New creates a generator for the named format.
+func New(name string) (Generator, error) {
+	generatorsMu.RLock()
+	newGenerator, ok := generators[name]
+	generatorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown report format %q (supported: %s)", name, strings.Join(Formats(), ", "))
+	}
+	return newGenerator()
+}
+
+// Formats returns the names of the registered formats, sorted.
+func Formats() []string {
+	generatorsMu.RLock()
+	defer generatorsMu.RUnlock()
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// textGenerator writes just the report's user names.
+type textGenerator struct{}
+
+func (textGenerator) Generate(w io.Writer, r *Report) error {
+	for _, ua := range r.UserActivities {
+		if _, err := io.WriteString(w, ua.User+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register("test-text", func() (textGenerator, error) { return textGenerator{}, nil })
+	t.Cleanup(func() {
+		generatorsMu.Lock()
+		delete(generators, "test-text")
+		generatorsMu.Unlock()
+	})
+
+	if formats := Formats(); !slices.Contains(formats, FormatHTML) || !slices.Contains(formats, "test-text") {
+		t.Errorf("Formats() = %v, want html and test-text", formats)
+	}
+
+	gen, err := New("test-text")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var out strings.Builder
+	if err := gen.Generate(&out, &Report{UserActivities: []UserActivity{{User: "alice"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "alice\n" {
+		t.Errorf("Generate() wrote %q, want alice", out.String())
+	}
+
+	if _, err := New(FormatHTML); err != nil {
+		t.Errorf("New(html) error = %v", err)
+	}
+	if _, err := New("nope"); err == nil || !strings.Contains(err.Error(), "html") {
+		t.Errorf("expected an error listing the formats, got %v", err)
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic registering html twice")
+		}
+	}()
+	Register(FormatHTML, NewHTMLGenerator)
+}
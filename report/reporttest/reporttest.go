@@ -0,0 +1,142 @@
+// Package reporttest checks report generators against golden files, so
+// changes to what they write show up as diffs in review. It works for
+// generators registered from outside this module too.
+//
+// Golden files are rewritten from what the generators write when the tests
+// run with -update:
+//
+//	go test ./report/ -update
+package reporttest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with what report generators write")
+
+// fixtureTime is when Fixture's report was generated.
+var fixtureTime = time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+
+// Fixture returns a report with some of each kind of activity, from a few
+// users and a bot, along with the footer's notices. Its times are fixed, so
+// what a generator writes for it only changes when the generator does.
+func Fixture() *report.Report {
+	ago := func(d time.Duration) time.Time { return fixtureTime.Add(-d) }
+	result := &diff.Result{
+		OldCapturedAt: ago(24 * time.Hour),
+		NewCapturedAt: fixtureTime,
+		NewStars: []diff.RepoChange{
+			{Username: "alice", Repo: diff.Repo{Owner: "golang", Name: "go", Description: "The Go programming language", CreatedAt: ago(2 * time.Hour)}},
+			{Username: "bob", Repo: diff.Repo{Owner: "golang", Name: "go", Description: "The Go programming language", CreatedAt: ago(3 * time.Hour)}},
+		},
+		NewRepos: []diff.RepoChange{
+			{Username: "alice", Repo: diff.Repo{Owner: "alice", Name: "dotfiles", Description: "My <dotfiles> & settings", CreatedAt: ago(5 * time.Hour)}},
+		},
+		NewEvents: []diff.EventChange{
+			{Username: "alice", Event: diff.Event{Type: "PushEvent", Actor: "alice", Repo: "alice/dotfiles", CreatedAt: ago(time.Hour), Commits: []string{"Add vimrc", "Fix typo"}, Before: "1a2b3c4", Head: "5d6e7f8"}},
+			{Username: "bob", Event: diff.Event{Type: "ReleaseEvent", Actor: "bob", Repo: "bob/tool", CreatedAt: ago(4 * time.Hour), RefType: "tag", Ref: "v1.0.0"}},
+			{Username: "bob", Event: diff.Event{Type: "CreateEvent", Actor: "bob", Repo: "bob/tool", CreatedAt: ago(6 * time.Hour), RefType: "branch", Ref: "feature"}},
+			{Username: "carol", Event: diff.Event{Type: "ForkEvent", Actor: "carol", Repo: "golang/go", CreatedAt: ago(26 * time.Hour)}},
+			{Username: "dependabot[bot]", Event: diff.Event{Type: "PushEvent", Actor: "dependabot[bot]", Repo: "bob/tool", CreatedAt: ago(30 * time.Minute), Commits: []string{"Bump golang.org/x/net"}}},
+			{Username: "carol", Event: diff.Event{Type: "MemberEvent", Actor: "carol", Repo: "carol/site", CreatedAt: ago(48 * time.Hour), Member: "dave"}},
+		},
+		NewFollows: []diff.FollowChange{{Username: "carol", Followed: "rsc"}},
+		NewUsers:   []string{"carol"},
+		YouStarred: []diff.Repo{{Owner: "rust-lang", Name: "rust", Description: "Empowering everyone to build reliable software"}},
+		Watched: []diff.WatchChange{{
+			Repo:      diff.Repo{Owner: "golang", Name: "go", Stars: 125040},
+			OldStars:  124990,
+			NewEvents: []diff.Event{{Type: "ReleaseEvent", Actor: "gopherbot", Repo: "golang/go", CreatedAt: ago(3 * time.Hour), RefType: "tag", Ref: "go1.26.0"}},
+		}},
+	}
+
+	rpt := report.FromDiff(result, report.Options{
+		GeneratedAt: fixtureTime,
+		PeriodStart: result.OldCapturedAt,
+		PeriodEnd:   result.NewCapturedAt,
+	})
+	rpt.SetProfiles(map[string]diff.Profile{
+		"alice": {Name: "Alice Liddell", AvatarURL: "https://avatars.example.com/alice"},
+	})
+	rpt.SetUserSources(map[string][]string{"bob": {"work"}})
+	rpt.SetRepoTags(map[string][]string{"golang/go": {"go", "languages"}, "bob/tool": {"go"}})
+	rpt.Teams = map[string][]string{"platform": {"alice", "bob"}}
+	rpt.Noise = report.NoiseRules{Actors: report.DefaultNoiseActors, ForcePushes: true}
+	rpt.UpdateNotice = "gitstreams v9.9.9 is available"
+	rpt.Provenance = &report.Provenance{
+		From:         result.OldCapturedAt,
+		To:           result.NewCapturedAt,
+		Mode:         report.ModeLive,
+		Gaps:         []string{"events for dave"},
+		LookbackDays: 30,
+		APIRequests:  12,
+	}
+	return rpt
+}
+
+// Golden has g write r and compares what it wrote with the golden file at
+// path, failing t at the first line that differs. With -update it writes
+// the golden file instead. Times are shown in UTC while it runs, so golden
+// files don't depend on the machine's time zone.
+func Golden(t testing.TB, g report.Generator, r *report.Report, path string) {
+	t.Helper()
+
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+
+	var buf bytes.Buffer
+	if err := g.Generate(&buf, r); err != nil {
+		t.Fatalf("generating report: %v", err)
+	}
+	got := buf.Bytes()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("creating golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path) // #nosec G304 -- path is the caller's golden file
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if line, wantLine, gotLine, differ := firstDifference(string(want), string(got)); differ {
+		t.Errorf("%s differs at line %d (run with -update if the change is intended):\n want: %s\n  got: %s",
+			path, line, wantLine, gotLine)
+	}
+}
+
+// firstDifference returns the first line, counting from 1, at which want
+// and got differ, and each one's version of it.
+func firstDifference(want, got string) (line int, wantLine, gotLine string, differ bool) {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := range max(len(wantLines), len(gotLines)) {
+		if i >= len(wantLines) || i >= len(gotLines) || wantLines[i] != gotLines[i] {
+			return i + 1, lineAt(wantLines, i), lineAt(gotLines, i), true
+		}
+	}
+	return 0, "", "", false
+}
+
+// lineAt returns lines[i], or a marker if there isn't one.
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return "(end of file)"
+}
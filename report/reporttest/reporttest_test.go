@@ -0,0 +1,64 @@
+package reporttest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+// countGenerator writes how many users a report has.
+type countGenerator struct{}
+
+func (countGenerator) Generate(w io.Writer, r *report.Report) error {
+	_, err := fmt.Fprintf(w, "users: %d\n", len(r.UserActivities))
+	return err
+}
+
+func TestGoldenUpdateThenCompare(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "count.golden")
+
+	*update = true
+	Golden(t, countGenerator{}, Fixture(), path)
+	*update = false
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "users: 4\n" {
+		t.Errorf("golden file = %q, want the generator's output", got)
+	}
+
+	// Compares clean against what it just wrote
+	Golden(t, countGenerator{}, Fixture(), path)
+}
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		line      int
+		wantLine  string
+		gotLine   string
+		differ    bool
+	}{
+		{name: "same", want: "a\nb\n", got: "a\nb\n"},
+		{name: "changed line", want: "a\nb\nc", got: "a\nB\nc", line: 2, wantLine: "b", gotLine: "B", differ: true},
+		{name: "longer", want: "a", got: "a\nb", line: 2, wantLine: "(end of file)", gotLine: "b", differ: true},
+		{name: "shorter", want: "a\nb", got: "a", line: 2, wantLine: "b", gotLine: "(end of file)", differ: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, wantLine, gotLine, differ := firstDifference(tt.want, tt.got)
+			if line != tt.line || wantLine != tt.wantLine || gotLine != tt.gotLine || differ != tt.differ {
+				t.Errorf("firstDifference() = %d, %q, %q, %v, want %d, %q, %q, %v",
+					line, wantLine, gotLine, differ, tt.line, tt.wantLine, tt.gotLine, tt.differ)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"slices"
+	"sort"
+)
+
+// NoTeam is the team name the by-team view puts users in no team under.
+const NoTeam = "No team"
+
+// AggregatedTeamActivity is a team's activity in the by-team view.
+type AggregatedTeamActivity struct {
+	Team       string
+	Activities []AggregatedActivity
+}
+
+// AggregatedTeamActivities returns each team's activity from r.Teams,
+// aggregated and newest first, with teams in name order and users in no
+// team last under NoTeam. Someone in several teams shows up in each. Teams
+// without activity are left out.
+func (r *Report) AggregatedTeamActivities() []AggregatedTeamActivity {
+	if len(r.Teams) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(r.Teams))
+	inTeam := make(map[string]bool)
+	for name, members := range r.Teams {
+		names = append(names, name)
+		for _, m := range members {
+			inTeam[m] = true
+		}
+	}
+	sort.Strings(names)
+
+	var result []AggregatedTeamActivity
+	add := func(team string, member func(user string) bool) {
+		var activities []Activity
+		for _, ua := range r.UserActivities {
+			if member(ua.User) {
+				activities = append(activities, r.usual(ua.Activities)...)
+			}
+		}
+		if len(activities) == 0 {
+			return
+		}
+		slices.SortStableFunc(activities, NewestFirst)
+		result = append(result, AggregatedTeamActivity{Team: team, Activities: aggregateActivities(activities, r.Aggregation)})
+	}
+	for _, name := range names {
+		add(name, func(user string) bool { return slices.Contains(r.Teams[name], user) })
+	}
+	add(NoTeam, func(user string) bool { return !inTeam[user] })
+	return result
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReportAggregatedTeamActivities(t *testing.T) {
+	r := pinnedReport("golang/go")
+	r.UserActivities = append(r.UserActivities, UserActivity{User: "carol", Activities: []Activity{
+		{Type: ActivityPushed, User: "carol", RepoName: "carol/site", Timestamp: r.GeneratedAt},
+	}})
+	if got := r.AggregatedTeamActivities(); got != nil {
+		t.Errorf("AggregatedTeamActivities() without teams = %+v, want nil", got)
+	}
+
+	r.Teams = map[string][]string{
+		"web":   {"bob", "alice"},
+		"infra": {"alice"},
+		"quiet": {"dave"},
+	}
+	var got []string
+	for _, ta := range r.AggregatedTeamActivities() {
+		var repos []string
+		for _, a := range ta.Activities {
+			repos = append(repos, a.User+" "+a.RepoName)
+		}
+		got = append(got, ta.Team+": "+strings.Join(repos, ", "))
+	}
+	want := []string{
+		// Pinned activity is left to the pinned section, and quiet has none
+		"infra: alice alice/tool",
+		"web: bob alice/tool, alice alice/tool, bob rust-lang/rust",
+		NoTeam + ": carol carol/site",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("AggregatedTeamActivities() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestHTMLGeneratorGenerateTeams(t *testing.T) {
+	g, err := NewHTMLGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := g.Generate(&buf, pinnedReport()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "By Team") {
+		t.Error("a report without teams shouldn't offer the by-team view")
+	}
+
+	r := pinnedReport()
+	r.Teams = map[string][]string{"web": {"bob"}}
+	buf.Reset()
+	if err := g.Generate(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"By Team", `<span class="category-title">web</span>`, `<span class="category-title">No team</span>`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report doesn't contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	"slices"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+// WatchedRepo is what happened on a repo on the watchlist: its new releases
+// and pushes, whoever made them, and the stars it gained.
+type WatchedRepo struct {
+	RepoName    string
+	RepoURL     string
+	Stars       int
+	StarsGained int
+	Activities  []Activity // Newest first
+}
+
+// Rows returns w's activities as the report's lists show them, one to a
+// row.
+func (w WatchedRepo) Rows() []AggregatedActivity {
+	rows := make([]AggregatedActivity, len(w.Activities))
+	for i, a := range w.Activities {
+		rows[i] = singleActivity(a)
+	}
+	return rows
+}
+
+// watchedRepos builds the watchlist section from changes.
+func (b *builder) watchedRepos(changes []diff.WatchChange) []WatchedRepo {
+	var watched []WatchedRepo
+	for _, c := range changes {
+		w := WatchedRepo{
+			RepoName:    c.Repo.FullName(),
+			RepoURL:     b.links.repoPage(c.Repo),
+			Stars:       c.Repo.Stars,
+			StarsGained: c.StarsGained(),
+		}
+		for _, e := range c.NewEvents {
+			activityType := eventActivity(e)
+			if activityType == "" {
+				continue
+			}
+			w.Activities = append(w.Activities, Activity{
+				Type:       activityType,
+				User:       e.Actor,
+				AvatarURL:  b.links.avatarOf(e.Actor, e.ActorAvatarURL),
+				RepoName:   e.Repo,
+				RepoURL:    w.RepoURL,
+				CompareURL: b.links.compare(e.Repo, e.Before, e.Head),
+				Forced:     e.Forced,
+				Timestamp:  e.CreatedAt,
+				Details:    eventDetails(e),
+			})
+		}
+		slices.SortStableFunc(w.Activities, NewestFirst)
+		watched = append(watched, w)
+	}
+	return watched
+}
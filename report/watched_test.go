@@ -0,0 +1,60 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+func TestFromDiffWatched(t *testing.T) {
+	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	result := &diff.Result{Watched: []diff.WatchChange{{
+		Repo:     diff.Repo{Owner: "golang", Name: "go", Stars: 110},
+		OldStars: 100,
+		NewEvents: []diff.Event{
+			{Type: "PushEvent", Actor: "rsc", Repo: "golang/go", CreatedAt: now.Add(-2 * time.Hour), Commits: []string{"cmd/go: fix"}, Before: "1a2b3c4", Head: "5d6e7f8"},
+			{Type: "ReleaseEvent", Actor: "gopherbot", Repo: "golang/go", CreatedAt: now.Add(-time.Hour), RefType: "tag", Ref: "go1.26"},
+		},
+	}}}
+
+	rpt := FromDiff(result, Options{GeneratedAt: now})
+	if len(rpt.Watched) != 1 {
+		t.Fatalf("Watched = %+v, want golang/go", rpt.Watched)
+	}
+	w := rpt.Watched[0]
+	if w.RepoName != "golang/go" || w.RepoURL != "https://github.com/golang/go" || w.Stars != 110 || w.StarsGained != 10 {
+		t.Errorf("watched repo = %+v", w)
+	}
+	if len(w.Activities) != 2 || w.Activities[0].Type != ActivityReleased || w.Activities[1].Type != ActivityPushed {
+		t.Fatalf("activities = %+v, want the release then the push", w.Activities)
+	}
+	if got := w.Activities[1].Link(); got != "https://github.com/golang/go/compare/1a2b3c4...5d6e7f8" {
+		t.Errorf("push links to %s, want the commits pushed", got)
+	}
+	// Watched repos' activity isn't anyone's the reader follows
+	if len(rpt.UserActivities) != 0 {
+		t.Errorf("UserActivities = %+v, want none", rpt.UserActivities)
+	}
+}
+
+func TestReportMergeWatched(t *testing.T) {
+	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	push := Activity{Type: ActivityPushed, User: "rsc", RepoName: "golang/go", Timestamp: now.Add(-time.Hour)}
+	release := Activity{Type: ActivityReleased, User: "gopherbot", RepoName: "golang/go", Timestamp: now}
+	r := &Report{Watched: []WatchedRepo{{RepoName: "golang/go", Stars: 105, StarsGained: 5, Activities: []Activity{push}}}}
+	other := &Report{Watched: []WatchedRepo{
+		{RepoName: "golang/go", Stars: 112, StarsGained: 7, Activities: []Activity{push, release}},
+		{RepoName: "rust-lang/rust", Stars: 50, StarsGained: 2},
+	}}
+
+	if added := r.Merge(other); added != 1 {
+		t.Errorf("Merge() = %d, want the one new release", added)
+	}
+	if len(r.Watched) != 2 {
+		t.Fatalf("Watched = %+v, want both repos", r.Watched)
+	}
+	if w := r.Watched[0]; w.Stars != 112 || w.StarsGained != 12 || len(w.Activities) != 2 || w.Activities[0].Type != ActivityReleased {
+		t.Errorf("golang/go = %+v, want 12 stars gained and the release first", w)
+	}
+}
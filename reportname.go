@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// defaultReportName is the -report-name reports get unless told otherwise.
+const defaultReportName = "gitstreams-{date}.{format}"
+
+var reportNamePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// expandReportName fills in a -report-name pattern's placeholders: {date}
+// as 2006-01-02, {format} and {profile}.
+func expandReportName(pattern string, now time.Time, format, profile string) (string, error) {
+	var unknown string
+	name := reportNamePlaceholder.ReplaceAllStringFunc(pattern, func(p string) string {
+		switch p {
+		case "{date}":
+			return now.Format(time.DateOnly)
+		case "{format}":
+			return format
+		case "{profile}":
+			return profile
+		}
+		if unknown == "" {
+			unknown = p
+		}
+		return p
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("unknown placeholder %s (use {date}, {format} or {profile})", unknown)
+	}
+	return name, nil
+}
+
+// reportPath returns where a run's report goes: -report if given, or
+// -report-name in -report-dir, which defaults to the temp directory.
+func reportPath(cfg *Config, now time.Time) (string, error) {
+	if cfg.ReportPath != "" {
+		return cfg.ReportPath, nil
+	}
+	pattern := cfg.ReportName
+	if pattern == "" {
+		pattern = defaultReportName
+	}
+	name, err := expandReportName(pattern, now, cfg.Format, reportProfile(cfg))
+	if err != nil {
+		return "", fmt.Errorf("report-name: %w", err)
+	}
+	dir := cfg.ReportDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// reportProfile names whose follows a run reports on, for {profile}: the
+// -org or -user account, or "default" as for a server without a profiles
+// file.
+func reportProfile(cfg *Config) string {
+	if cfg.Org != "" {
+		return cfg.Org
+	}
+	if cfg.Username != "" {
+		return cfg.Username
+	}
+	return "default"
+}
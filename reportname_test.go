@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+func TestExpandReportName(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{pattern: defaultReportName, want: "gitstreams-2024-01-15.html"},
+		{pattern: "{profile}/latest.{format}", want: "octocat/latest.html"},
+		{pattern: "{date}-{date}", want: "2024-01-15-2024-01-15"},
+		{pattern: "plain.html", want: "plain.html"},
+		{pattern: "{time}.html", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := expandReportName(tt.pattern, fixedTime(), "html", "octocat")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "{time}") {
+					t.Errorf("expected an error naming {time}, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("expandReportName() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportPath(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			name: "defaults to the temp directory",
+			cfg:  Config{Format: "html"},
+			want: filepath.Join(os.TempDir(), "gitstreams-2024-01-15.html"),
+		},
+		{
+			name: "explicit report wins",
+			cfg:  Config{Format: "html", ReportPath: "/out/today.html", ReportDir: "/reports", ReportName: "x"},
+			want: "/out/today.html",
+		},
+		{
+			name: "name in directory",
+			cfg:  Config{Format: "json", ReportDir: "/reports", ReportName: "{profile}-{date}.{format}", Username: "octocat"},
+			want: filepath.Join("/reports", "octocat-2024-01-15.json"),
+		},
+		{
+			name: "profile without a user",
+			cfg:  Config{Format: "html", ReportDir: "/reports", ReportName: "{profile}.{format}"},
+			want: filepath.Join("/reports", "default.html"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reportPath(&tt.cfg, fixedTime())
+			if err != nil || got != tt.want {
+				t.Errorf("reportPath() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_ReportDirAndName(t *testing.T) {
+	setHome(t)
+	dir := t.TempDir()
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			return &mockGitHubClient{
+				followedUsers: []github.User{{Login: "testuser", ID: 1}},
+				starredRepos: map[string][]github.Repository{
+					"testuser": {{Name: "repo", Owner: github.User{Login: "owner"}, CreatedAt: fixedTime()}},
+				},
+			}
+		},
+		StoreFactory:    func(path string) (Store, error) { return &mockStore{}, nil },
+		NotifierFactory: func() Notifier { return &mockNotifier{} },
+		ReportGenerator: func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		OpenBrowser:     func(url string) error { return nil },
+		Now:             fixedTime,
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(&stdout, &stderr, []string{
+		"-token", "test-token",
+		"-db", filepath.Join(dir, "test.db"),
+		"-no-notify",
+		"-report-dir", filepath.Join(dir, "reports"),
+		"-report-name", "{date}.{format}",
+	}, deps)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	want := filepath.Join(dir, "reports", "2024-01-15.html")
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("expected the report at %s: %v", want, err)
+	}
+
+	code = run(&stdout, &stderr, []string{"-token", "test-token", "-report-name", "{when}.html"}, deps)
+	if code != 1 || !strings.Contains(stderr.String(), "unknown placeholder {when}") {
+		t.Errorf("expected an unknown placeholder error, got %d: %s", code, stderr.String())
+	}
+}
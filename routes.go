@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/justinabrahms/gitstreams/notify"
+)
+
+const defaultRoutesName = "routes.json"
+
+// notifierNames are what routes call the notifiers selectNotifier can set
+// up.
+var notifierNames = []string{"desktop", "webhook", "slack", "discord", "email"}
+
+// route sends the digest (the report's notification), alerts from the
+// rules named in Alerts, or both, to the notifiers named in To.
+type route struct {
+	Alerts []string `json:"alerts,omitempty"` // Alert rule names, or patterns such as "*"
+	To     []string `json:"to"`
+	Digest bool     `json:"digest,omitempty"`
+}
+
+// validate checks that r sends something somewhere it knows of.
+func (r route) validate() error {
+	if !r.Digest && len(r.Alerts) == 0 {
+		return errors.New(`route sends nothing; give it "digest": true or "alerts"`)
+	}
+	if len(r.To) == 0 {
+		return errors.New(`route has no notifiers in "to"`)
+	}
+	for _, name := range r.To {
+		if !slices.Contains(notifierNames, name) {
+			return fmt.Errorf("unknown notifier %q (known: %s)", name, strings.Join(notifierNames, ", "))
+		}
+	}
+	for _, pattern := range r.Alerts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad alert pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether r sends n.
+func (r route) matches(n notify.Notification) bool {
+	if n.Alert == "" {
+		return r.Digest
+	}
+	for _, pattern := range r.Alerts {
+		if ok, _ := path.Match(pattern, n.Alert); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadRoutes loads the notification routes at path, or from the config
+// directory if path is empty. The file is a JSON array of routes. A missing
+// default routes file means there are no routes, so every notification goes
+// to every notifier. Every invalid route is reported, not just the first.
+func loadRoutes(path string) ([]route, error) {
+	explicit := path != ""
+	if !explicit {
+		dir, err := configDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, defaultRoutesName)
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- routes path is user-specified or a fixed default
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading routes: %w", err)
+	}
+	var routes []route
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("loading routes: %s: %w", path, err)
+	}
+
+	var errs []error
+	for i, r := range routes {
+		if err := r.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("route %d: %w", i+1, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("loading routes: %s: %w", path, err)
+	}
+	return routes, nil
+}
+
+// routeNotifiers routes notifications between the named notifiers. Routes
+// to notifiers that aren't set up, such as desktop with -no-notify, send
+// nothing.
+func routeNotifiers(notifiers map[string]notify.Notifier, routes []route) notify.Router {
+	router := notify.Router{Notifiers: notifiers}
+	for _, r := range routes {
+		router.Routes = append(router.Routes, notify.Route{Match: r.matches, To: r.To})
+	}
+	return router
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/notify"
+)
+
+func TestLoadRoutes(t *testing.T) {
+	home := setHome(t)
+	if routes, err := loadRoutes(""); err != nil || routes != nil {
+		t.Errorf("expected no routes without a routes file, got %v, %v", routes, err)
+	}
+	if _, err := loadRoutes(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing explicit routes file")
+	}
+
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultRoutesName),
+		`[{"alerts": ["releases", "keyword *"], "to": ["slack"]}, {"digest": true, "to": ["desktop"]}]`)
+	routes, err := loadRoutes("")
+	if err != nil || len(routes) != 2 || !routes[1].Digest {
+		t.Errorf("loadRoutes() = %+v, %v", routes, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "routes.json")
+	writeFile(t, path, `[{"to": ["slack"]}, {"digest": true}, {"digest": true, "to": ["pager"]}, {"alerts": ["["], "to": ["email"]}]`)
+	_, err = loadRoutes(path)
+	for _, want := range []string{"route 1: route sends nothing", "route 2: route has no notifiers", `route 3: unknown notifier "pager"`, "route 4: bad alert pattern"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q among the errors, got %v", want, err)
+		}
+	}
+
+	// Caught up front rather than when there's something to notify
+	if _, err := parseFlags([]string{"-routes", path}); err == nil || !strings.Contains(err.Error(), "loading routes") {
+		t.Errorf("expected parseFlags() to reject bad routes, got %v", err)
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	r := route{Alerts: []string{"releases", "keyword *"}, To: []string{"slack"}}
+	tests := []struct {
+		alert string
+		want  bool
+	}{
+		{"releases", true},
+		{"keyword kubernetes", true},
+		{"pushes", false},
+		{"", false}, // The digest
+	}
+	for _, tt := range tests {
+		if got := r.matches(notify.Notification{Alert: tt.alert}); got != tt.want {
+			t.Errorf("matches(%q) = %v, want %v", tt.alert, got, tt.want)
+		}
+	}
+	if !(route{Digest: true}).matches(notify.Notification{}) {
+		t.Error("expected a digest route to match the digest")
+	}
+}
+
+// countingNotifier records every notification it's sent.
+type countingNotifier struct {
+	sent []notify.Notification
+}
+
+func (c *countingNotifier) Send(n notify.Notification) error {
+	c.sent = append(c.sent, n)
+	return nil
+}
+
+func TestRun_RoutesNotifications(t *testing.T) {
+	home := setHome(t)
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultRulesName),
+		`[{"name": "releases", "when": "event", "type": "ReleaseEvent"},
+		  {"name": "pushes", "when": "event", "type": "PushEvent"}]`)
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultRoutesName),
+		`[{"alerts": ["releases"], "to": ["slack"]}, {"digest": true, "to": ["desktop"]}]`)
+
+	var mu sync.Mutex
+	var slackTexts []string
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Text string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		slackTexts = append(slackTexts, body.Text)
+		mu.Unlock()
+	}))
+	defer slack.Close()
+
+	client := &mockGitHubClient{
+		followedUsers: []github.User{{Login: "alice", ID: 1}},
+		events: map[string][]github.Event{
+			"alice": {
+				{Type: "ReleaseEvent", Actor: github.User{Login: "alice"}, Repo: github.EventRepo{Name: "alice/tool"}, CreatedAt: fixedTime()},
+				{Type: "PushEvent", Actor: github.User{Login: "alice"}, Repo: github.EventRepo{Name: "alice/notes"}, CreatedAt: fixedTime()},
+			},
+		},
+	}
+	desktop := &countingNotifier{}
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return client },
+		StoreFactory:        func(path string) (Store, error) { return &mockStore{}, nil },
+		NotifierFactory:     func() Notifier { return desktop },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		OpenBrowser:         func(url string) error { return nil },
+		Now:                 fixedTime,
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-token", "t", "-slack-webhook", slack.URL, "-report", filepath.Join(t.TempDir(), "report.html")}
+	if code := run(&stdout, &stderr, args, deps); code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+
+	// Both alerts fired, but only the releases one had somewhere to go
+	if !strings.Contains(stdout.String(), "Alert (pushes)") {
+		t.Errorf("expected the pushes alert to still be printed, got %q", stdout.String())
+	}
+	if len(slackTexts) != 1 || !strings.Contains(slackTexts[0], "ReleaseEvent in alice/tool") {
+		t.Errorf("expected only the releases alert in Slack, got %q", slackTexts)
+	}
+	if len(desktop.sent) != 1 || desktop.sent[0].Alert != "" {
+		t.Errorf("expected only the digest on the desktop, got %+v", desktop.sent)
+	}
+}
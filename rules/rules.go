@@ -0,0 +1,242 @@
+// Package rules evaluates alert rules against the changes found by each
+// sync, so activity that matters can be notified as soon as it's seen
+// rather than waiting for the digest.
+//
+// Rules are kept in a JSON file holding an array of rules:
+//
+//	[
+//	  {"name": "releases", "when": "event", "type": "ReleaseEvent", "repos": ["golang/*"]},
+//	  {"name": "busy", "when": "count", "threshold": 50}
+//	]
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+// Rule conditions.
+const (
+	// WhenEvent fires as soon as any new activity matches the rule.
+	WhenEvent = "event"
+	// WhenCount fires once more than Threshold matching activities have
+	// accumulated since the rule last fired.
+	WhenCount = "count"
+)
+
+// Activity types for new stars and new repos. Events use their GitHub
+// event type, e.g. "ReleaseEvent" or "PullRequestEvent".
+const (
+	TypeStar = "star"
+	TypeRepo = "repo"
+)
+
+// Rule describes when to send an alert. The filters (Type, Repos, Users)
+// are optional; an empty filter matches everything.
+type Rule struct {
+	Name      string   `json:"name"`
+	When      string   `json:"when"`
+	Type      string   `json:"type,omitempty"`
+	Repos     []string `json:"repos,omitempty"` // "owner/name" or a pattern such as "owner/*"
+	Users     []string `json:"users,omitempty"`
+	Threshold int      `json:"threshold,omitempty"`
+}
+
+// Validate checks that the rule is complete and its patterns are valid.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("rule has no name")
+	}
+	switch r.When {
+	case WhenEvent:
+	case WhenCount:
+		if r.Threshold < 1 {
+			return fmt.Errorf("rule %q: threshold must be at least 1", r.Name)
+		}
+	default:
+		return fmt.Errorf("rule %q: when must be %q or %q, got %q", r.Name, WhenEvent, WhenCount, r.When)
+	}
+	for _, pattern := range r.Repos {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("rule %q: bad repo pattern %q: %w", r.Name, pattern, err)
+		}
+	}
+	return nil
+}
+
+// Load reads and validates the rules in the JSON file at path. Every
+// invalid rule is reported, not just the first.
+func Load(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- rules path is user-specified or a fixed default
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if seen[r.Name] {
+			errs = append(errs, fmt.Errorf("rule %q: duplicate name", r.Name))
+		}
+		seen[r.Name] = true
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// State carries counts for WhenCount rules from one sync to the next.
+type State struct {
+	Counts map[string]int `json:"counts"`
+}
+
+// Alert is a rule that fired.
+type Alert struct {
+	Rule    string
+	Message string
+	Matches int
+}
+
+// activity is a single new star, repo or event in a diff.
+type activity struct {
+	Type string
+	User string
+	Repo string
+}
+
+func (a activity) String() string {
+	return fmt.Sprintf("%s in %s by %s", a.Type, a.Repo, a.User)
+}
+
+// maxListed caps how many matching activities an event alert names.
+const maxListed = 3
+
+// Evaluate checks every rule against result and returns the alerts that
+// fire. Counts for WhenCount rules are accumulated in state, and reset when
+// the rule fires.
+func Evaluate(rules []Rule, result *diff.Result, state *State) []Alert {
+	if state.Counts == nil {
+		state.Counts = make(map[string]int)
+	}
+	activities := flatten(result)
+
+	var alerts []Alert
+	for _, r := range rules {
+		var matches []activity
+		for _, a := range activities {
+			if r.matches(a) {
+				matches = append(matches, a)
+			}
+		}
+
+		switch r.When {
+		case WhenEvent:
+			if len(matches) == 0 {
+				continue
+			}
+			alerts = append(alerts, Alert{Rule: r.Name, Message: describe(matches), Matches: len(matches)})
+		case WhenCount:
+			count := state.Counts[r.Name] + len(matches)
+			if count <= r.Threshold {
+				state.Counts[r.Name] = count
+				continue
+			}
+			delete(state.Counts, r.Name)
+			alerts = append(alerts, Alert{
+				Rule:    r.Name,
+				Message: fmt.Sprintf("%d new activities (more than %d)", count, r.Threshold),
+				Matches: count,
+			})
+		}
+	}
+
+	// Forget counts for rules that have been removed
+	for name := range state.Counts {
+		if !hasRule(rules, name) {
+			delete(state.Counts, name)
+		}
+	}
+	return alerts
+}
+
+func (r Rule) matches(a activity) bool {
+	if r.Type != "" && !strings.EqualFold(r.Type, a.Type) {
+		return false
+	}
+	if len(r.Users) > 0 && !containsFold(r.Users, a.User) {
+		return false
+	}
+	if len(r.Repos) > 0 {
+		for _, pattern := range r.Repos {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(a.Repo)); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func flatten(result *diff.Result) []activity {
+	activities := make([]activity, 0, len(result.NewStars)+len(result.NewRepos)+len(result.NewEvents))
+	for _, c := range result.NewStars {
+		activities = append(activities, activity{Type: TypeStar, User: c.Username, Repo: c.Repo.FullName()})
+	}
+	for _, c := range result.NewRepos {
+		activities = append(activities, activity{Type: TypeRepo, User: c.Username, Repo: c.Repo.FullName()})
+	}
+	for _, c := range result.NewEvents {
+		activities = append(activities, activity{Type: c.Event.Type, User: c.Username, Repo: c.Event.Repo})
+	}
+	return activities
+}
+
+func describe(matches []activity) string {
+	listed := matches
+	if len(listed) > maxListed {
+		listed = listed[:maxListed]
+	}
+	parts := make([]string, len(listed))
+	for i, a := range listed {
+		parts[i] = a.String()
+	}
+	msg := strings.Join(parts, "; ")
+	if more := len(matches) - len(listed); more > 0 {
+		msg += fmt.Sprintf(" and %d more", more)
+	}
+	return msg
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRule(rules []Rule, name string) bool {
+	for _, r := range rules {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+func testResult() *diff.Result {
+	return &diff.Result{
+		NewStars: []diff.RepoChange{
+			{Username: "alice", Repo: diff.Repo{Owner: "golang", Name: "go"}},
+		},
+		NewRepos: []diff.RepoChange{
+			{Username: "bob", Repo: diff.Repo{Owner: "bob", Name: "tool"}},
+		},
+		NewEvents: []diff.EventChange{
+			{Username: "carol", Event: diff.Event{Type: "ReleaseEvent", Actor: "carol", Repo: "golang/tools"}},
+			{Username: "carol", Event: diff.Event{Type: "PushEvent", Actor: "carol", Repo: "carol/dotfiles"}},
+		},
+	}
+}
+
+func TestEvaluateEventRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		rule    Rule
+		matches int
+	}{
+		{
+			name:    "release in watched repos",
+			rule:    Rule{Name: "r", When: WhenEvent, Type: "ReleaseEvent", Repos: []string{"golang/*"}},
+			matches: 1,
+			want:    "ReleaseEvent in golang/tools by carol",
+		},
+		{
+			name: "release elsewhere",
+			rule: Rule{Name: "r", When: WhenEvent, Type: "ReleaseEvent", Repos: []string{"rust-lang/*"}},
+		},
+		{
+			name:    "type is case-insensitive",
+			rule:    Rule{Name: "r", When: WhenEvent, Type: "star"},
+			matches: 1,
+			want:    "star in golang/go by alice",
+		},
+		{
+			name:    "user filter",
+			rule:    Rule{Name: "r", When: WhenEvent, Users: []string{"Carol"}},
+			matches: 2,
+		},
+		{
+			name:    "no filters matches everything",
+			rule:    Rule{Name: "r", When: WhenEvent},
+			matches: 4,
+			want:    "and 1 more",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := Evaluate([]Rule{tt.rule}, testResult(), &State{})
+			if tt.matches == 0 {
+				if len(alerts) != 0 {
+					t.Errorf("expected no alerts, got %v", alerts)
+				}
+				return
+			}
+			if len(alerts) != 1 {
+				t.Fatalf("expected 1 alert, got %v", alerts)
+			}
+			if alerts[0].Matches != tt.matches {
+				t.Errorf("Matches = %d, want %d", alerts[0].Matches, tt.matches)
+			}
+			if !strings.Contains(alerts[0].Message, tt.want) {
+				t.Errorf("Message = %q, want it to contain %q", alerts[0].Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateCountRuleAccumulates(t *testing.T) {
+	rule := Rule{Name: "busy", When: WhenCount, Threshold: 6}
+	state := &State{}
+
+	// 4 activities per sync: under the threshold after one, over after two
+	if alerts := Evaluate([]Rule{rule}, testResult(), state); len(alerts) != 0 {
+		t.Fatalf("expected no alert after first sync, got %v", alerts)
+	}
+	if state.Counts["busy"] != 4 {
+		t.Errorf("expected count 4, got %d", state.Counts["busy"])
+	}
+
+	alerts := Evaluate([]Rule{rule}, testResult(), state)
+	if len(alerts) != 1 || alerts[0].Matches != 8 {
+		t.Fatalf("expected alert for 8 activities, got %v", alerts)
+	}
+	if _, ok := state.Counts["busy"]; ok {
+		t.Error("expected count to reset after firing")
+	}
+}
+
+func TestEvaluateForgetsRemovedRules(t *testing.T) {
+	state := &State{Counts: map[string]int{"old": 10}}
+	Evaluate(nil, testResult(), state)
+	if len(state.Counts) != 0 {
+		t.Errorf("expected stale counts to be dropped, got %v", state.Counts)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		rule Rule
+	}{
+		{name: "valid event", rule: Rule{Name: "a", When: WhenEvent}},
+		{name: "valid count", rule: Rule{Name: "a", When: WhenCount, Threshold: 1}},
+		{name: "no name", rule: Rule{When: WhenEvent}, want: "no name"},
+		{name: "bad when", rule: Rule{Name: "a", When: "sometimes"}, want: "when must be"},
+		{name: "no threshold", rule: Rule{Name: "a", When: WhenCount}, want: "threshold"},
+		{name: "bad pattern", rule: Rule{Name: "a", When: WhenEvent, Repos: []string{"golang/["}}, want: "bad repo pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rules, err := Load(write("ok.json", `[{"name": "releases", "when": "event", "type": "ReleaseEvent"}]`))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(rules) != 1 || rules[0].Type != "ReleaseEvent" {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	_, err = Load(write("bad.json", `[{"name": "a", "when": "never"}, {"when": "event"}, {"name": "b", "when": "event"}, {"name": "b", "when": "event"}]`))
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"when must be", "no name", "duplicate name"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	if _, err := Load(write("syntax.json", `{`)); err == nil {
+		t.Error("expected syntax error")
+	}
+}
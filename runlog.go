@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// runLogName is the log each run's warnings and errors are copied to,
+	// kept in the data directory for `gitstreams debug bundle`.
+	runLogName = "gitstreams.log"
+
+	// maxRunLogSize is how large the log may grow before it's rotated to
+	// runLogName + ".1", replacing any earlier rotation.
+	maxRunLogSize = 1 << 20
+)
+
+// openRunLog opens the run log in the data directory for appending,
+// rotating it first if it has grown too large.
+func openRunLog(cfg *Config) (io.WriteCloser, error) {
+	dir, err := dataDir(cfg.LegacyDirs)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("creating data directory: %w", err)
+	}
+
+	path := filepath.Join(dir, runLogName)
+	if info, err := os.Stat(path); err == nil && info.Size() > maxRunLogSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return nil, fmt.Errorf("rotating log: %w", err)
+		}
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600) // #nosec G304 -- path is in the gitstreams data directory
+}
+
+// logWriter prefixes each line written through it with a timestamp.
+type logWriter struct {
+	w       io.Writer
+	now     func() time.Time
+	midLine bool
+}
+
+func newLogWriter(w io.Writer, now func() time.Time) *logWriter {
+	return &logWriter{w: w, now: now}
+}
+
+func (l *logWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if !l.midLine {
+			if _, err := io.WriteString(l.w, l.now().Format(time.RFC3339)+" "); err != nil {
+				return written, err
+			}
+			l.midLine = true
+		}
+
+		line := p
+		for i, b := range p {
+			if b == '\n' {
+				line = p[:i+1]
+				l.midLine = false
+				break
+			}
+		}
+		n, err := l.w.Write(line)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(line):]
+	}
+	return written, nil
+}
+
+// tailLines returns the last n lines of the file at path. A missing file has
+// no lines.
+func tailLines(path string, n int) ([]string, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is in the gitstreams data directory
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines, scanner.Err()
+}
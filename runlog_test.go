@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogWriter(t *testing.T) {
+	var buf strings.Builder
+	w := newLogWriter(&buf, fixedTime)
+
+	_, _ = w.Write([]byte("first line\nsecond "))
+	_, _ = w.Write([]byte("line\n"))
+
+	stamp := fixedTime().Format("2006-01-02T15:04:05Z07:00")
+	want := stamp + " first line\n" + stamp + " second line\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	writeFile(t, path, "a\nb\nc\nd\n")
+
+	lines, err := tailLines(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(lines, ",") != "c,d" {
+		t.Errorf("got %v, want [c d]", lines)
+	}
+
+	lines, err = tailLines(filepath.Join(t.TempDir(), "missing"), 2)
+	if err != nil || lines != nil {
+		t.Errorf("missing file: got %v, %v", lines, err)
+	}
+}
+
+func TestOpenRunLog_Rotates(t *testing.T) {
+	home := setHome(t)
+	path := filepath.Join(home, ".local", "share", "gitstreams", runLogName)
+	writeFile(t, path, strings.Repeat("x", maxRunLogSize+1))
+
+	f, err := openRunLog(&Config{})
+	if err != nil {
+		t.Fatalf("openRunLog() error = %v", err)
+	}
+	_ = f.Close()
+
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("expected a fresh log after rotation, got %v, %v", info, err)
+	}
+	if !fileExists(path + ".1") {
+		t.Error("expected the old log to be kept as .1")
+	}
+}
+
+func TestRun_WritesRunLog(t *testing.T) {
+	setHome(t)
+	logPath := filepath.Join(t.TempDir(), "run.log")
+
+	deps := &Dependencies{
+		StoreFactory: func(string) (Store, error) { return nil, errors.New("disk on fire") },
+		RunLog: func(*Config) (io.WriteCloser, error) {
+			return os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		},
+		Now:    fixedTime,
+		Logger: slog.Default(),
+	}
+	var stdout, stderr strings.Builder
+	_ = run(&stdout, &stderr, []string{"-no-notify", "-no-open"}, deps)
+
+	if !strings.Contains(stderr.String(), "disk on fire") {
+		t.Errorf("expected error on stderr, got %q", stderr.String())
+	}
+	// #nosec G304 -- test file in temp dir
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "started") || !strings.Contains(string(data), "disk on fire") {
+		t.Errorf("expected run start and error in log, got %q", data)
+	}
+}
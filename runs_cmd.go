@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+const runsUsage = `Usage:
+  gitstreams runs [-n 20] [-format table|json] [flags]`
+
+// defaultRunsListed is how many runs `gitstreams runs` lists by default.
+const defaultRunsListed = 20
+
+// runStore is implemented by stores that keep a history of runs.
+type runStore interface {
+	RecordRun(run storage.Run) (int64, error)
+	Runs(limit int) ([]storage.Run, error)
+	LastSuccessfulRun() (*storage.Run, error)
+}
+
+// runHistory implements serve.HealthChecker from a store's run history.
+type runHistory struct {
+	store Store
+}
+
+// LastSuccessfulSync implements serve.HealthChecker.
+func (h runHistory) LastSuccessfulSync() (time.Time, error) {
+	rs, ok := h.store.(runStore)
+	if !ok {
+		return time.Time{}, errors.New("this database doesn't keep a run history")
+	}
+	run, err := rs.LastSuccessfulRun()
+	if err != nil || run == nil {
+		return time.Time{}, err
+	}
+	return run.FinishedAt, nil
+}
+
+// execute runs the pipeline once, then records how it went and pings the
+// heartbeat.
+func (p *pipeline) execute(ctx context.Context) error {
+	st := &runState{}
+	started := p.deps.Now()
+	err := runStages(ctx, p.stages(), st)
+	finishRun(ctx, p.cfg, p.store, st.record(started, p.deps.Now(), err), p.stderr)
+	return err
+}
+
+// finishRun records run in store's run history and, if it succeeded, pings
+// cfg's heartbeat. Neither failing fails the run; they're warnings.
+func finishRun(ctx context.Context, cfg *Config, store Store, run storage.Run, stderr io.Writer) {
+	if rs, ok := store.(runStore); ok {
+		if _, err := rs.RecordRun(run); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: could not record run: %v\n", err)
+		}
+	}
+	if cfg.HeartbeatURL != "" && run.Error == "" {
+		if err := pingHeartbeat(ctx, cfg.HeartbeatURL); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: %v\n", err)
+		}
+	}
+}
+
+// record describes the run st is the state of, for the run history. err is
+// how it ended; it got as far as st shows.
+func (st *runState) record(started, finished time.Time, err error) storage.Run {
+	run := storage.Run{
+		StartedAt:   started,
+		FinishedAt:  finished,
+		Mode:        st.mode,
+		APIRequests: st.apiRequests,
+		Warnings:    len(st.warnings),
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if st.current != nil {
+		run.Users = len(st.current.Users)
+	}
+	if st.result != nil {
+		run.Changes = countChanges(st.result)
+	}
+	if st.report != nil {
+		run.Activities = st.report.TotalActivities()
+	}
+	return run
+}
+
+// runRuns handles the "runs" subcommand: it lists the latest runs, newest
+// first, with what each found and whether it failed.
+func runRuns(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	format := fs.ownFormat("table", "Output format: table or json")
+	limit := fs.Int("n", defaultRunsListed, "How many runs to list (0 for all)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if _, err := fs.resolve(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintln(stderr, runsUsage)
+		return 1
+	}
+	var write func(io.Writer, []storage.Run) error
+	switch *format {
+	case "table":
+		write = writeRunsTable
+	case "json":
+		write = writeRunsJSON
+	default:
+		_, _ = fmt.Fprintf(stderr, "Error: unsupported format %q (supported: table, json)\n", *format)
+		return 1
+	}
+
+	// Don't let the store create a database that isn't there
+	if _, err := os.Stat(dbFile(cfg.DBPath)); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: no database at %s; run gitstreams first\n", cfg.DBPath)
+		return 1
+	}
+	store, err := openStore(stderr, cfg, deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = store.Close() }()
+
+	rs, ok := store.(runStore)
+	if !ok {
+		_, _ = fmt.Fprintln(stderr, "Error: this database doesn't keep a run history")
+		return 1
+	}
+	runs, err := rs.Runs(*limit)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := write(stdout, runs); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error writing runs: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeRunsTable writes runs as aligned columns, in local time.
+func writeRunsTable(w io.Writer, runs []storage.Run) error {
+	if len(runs) == 0 {
+		_, err := fmt.Fprintln(w, "No runs recorded yet.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tSTARTED\tTOOK\tMODE\tAPI\tUSERS\tCHANGES\tACTIVITIES\tRESULT")
+	for _, r := range runs {
+		mode := r.Mode
+		if mode == "" {
+			mode = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			r.ID, r.StartedAt.Local().Format(time.DateTime), r.Duration().Round(100*time.Millisecond),
+			mode, r.APIRequests, r.Users, r.Changes, r.Activities, runResult(r))
+	}
+	return tw.Flush()
+}
+
+// runResult summarizes how r ended.
+func runResult(r storage.Run) string {
+	switch {
+	case r.Error != "":
+		return "failed: " + r.Error
+	case r.Warnings == 1:
+		return "ok, 1 fetch failed"
+	case r.Warnings > 1:
+		return "ok, " + strconv.Itoa(r.Warnings) + " fetches failed"
+	}
+	return "ok"
+}
+
+// writeRunsJSON writes runs as a JSON array.
+func writeRunsJSON(w io.Writer, runs []storage.Run) error {
+	if runs == nil {
+		runs = []storage.Run{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(runs)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestRun_RecordsRuns(t *testing.T) {
+	setHome(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	client := &mockGitHubClient{
+		followedUsers: []github.User{{Login: "alice", ID: 1}},
+		events: map[string][]github.Event{
+			"alice": {{Type: "PushEvent", Actor: github.User{Login: "alice"}, Repo: github.EventRepo{Name: "alice/repo"}, CreatedAt: fixedTime()}},
+		},
+	}
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return client },
+		StoreFactory:        func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		NotifierFactory:     func() Notifier { return &mockNotifier{} },
+		ReportGenerator:     func(string) (ReportGenerator, error) { return &mockReportGenerator{}, nil },
+		OpenBrowser:         func(url string) error { return nil },
+		Now:                 fixedTime,
+	}
+	args := []string{"-token", "t", "-db", dbPath, "-report", filepath.Join(t.TempDir(), "report.html")}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(&stdout, &stderr, args, deps); code != 0 {
+		t.Fatalf("first run: exit code %d, stderr: %s", code, stderr.String())
+	}
+	client.followedErr = errors.New("GitHub API error")
+	if code := run(&stdout, &stderr, args, deps); code != 1 {
+		t.Fatalf("second run: exit code %d, want a failure", code)
+	}
+
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"runs", "-format", "json", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("runs: exit code %d, stderr: %s", code, stderr.String())
+	}
+	var runs []storage.Run
+	if err := json.Unmarshal(stdout.Bytes(), &runs); err != nil {
+		t.Fatalf("runs output isn't JSON: %v\n%s", err, stdout.String())
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected every run recorded, got %+v", runs)
+	}
+	failed, ok := runs[0], runs[1]
+	if !strings.Contains(failed.Error, "GitHub API error") {
+		t.Errorf("newest run error = %q, want the fetch failure", failed.Error)
+	}
+	if ok.Error != "" || ok.Mode != report.ModeLive || ok.Users != 1 || ok.Changes != 1 || ok.Activities != 1 {
+		t.Errorf("successful run = %+v", ok)
+	}
+	if !ok.StartedAt.Equal(fixedTime()) {
+		t.Errorf("run started at %v, want %v", ok.StartedAt, fixedTime())
+	}
+
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"runs", "-n", "1", "-db", dbPath}, deps); code != 0 {
+		t.Fatalf("runs: exit code %d, stderr: %s", code, stderr.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], "failed: ") {
+		t.Errorf("expected a header and the failed run, got:\n%s", stdout.String())
+	}
+}
+
+func TestRunRuns_Errors(t *testing.T) {
+	setHome(t)
+	deps := &Dependencies{
+		StoreFactory: func(path string) (Store, error) { return storage.NewSQLiteStore(path) },
+		Now:          fixedTime,
+	}
+	var stdout, stderr bytes.Buffer
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	if code := run(&stdout, &stderr, []string{"runs", "-db", missing}, deps); code != 1 || !strings.Contains(stderr.String(), "no database") {
+		t.Errorf("expected an error for a missing database, got %d: %s", code, stderr.String())
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := storage.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	stderr.Reset()
+	if code := run(&stdout, &stderr, []string{"runs", "-format", "csv", "-db", dbPath}, deps); code != 1 || !strings.Contains(stderr.String(), "unsupported format") {
+		t.Errorf("expected an error for an unsupported format, got %d: %s", code, stderr.String())
+	}
+	stdout.Reset()
+	if code := run(&stdout, &stderr, []string{"runs", "-db", dbPath}, deps); code != 0 || !strings.Contains(stdout.String(), "No runs recorded") {
+		t.Errorf("expected no runs, got %d: %s", code, stdout.String())
+	}
+}
+
+func TestRunStateRecord(t *testing.T) {
+	start := fixedTime()
+	now := start.Add(time.Minute)
+	snapshot := diff.NewSnapshot(now)
+	snapshot.Users["alice"] = diff.UserActivity{Username: "alice"}
+	st := &runState{
+		current:     snapshot,
+		result:      &diff.Result{NewStars: []diff.RepoChange{{Username: "alice"}}, NewEvents: []diff.EventChange{{Username: "alice"}}},
+		mode:        report.ModeCached,
+		apiRequests: 3,
+		warnings:    []FetchWarning{{User: "alice"}},
+	}
+	got := st.record(start, now, errors.New("boom"))
+	want := storage.Run{StartedAt: start, FinishedAt: now, Mode: report.ModeCached, Error: "boom", APIRequests: 3, Users: 1, Changes: 2, Warnings: 1}
+	if got != want {
+		t.Errorf("record() = %+v, want %+v", got, want)
+	}
+
+	// A run that failed before acquiring anything
+	if got := (&runState{}).record(start, now, nil); got != (storage.Run{StartedAt: start, FinishedAt: now}) {
+		t.Errorf("record() of an empty run = %+v", got)
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	tests := []struct {
+		run  storage.Run
+		want string
+	}{
+		{storage.Run{}, "ok"},
+		{storage.Run{Warnings: 1}, "ok, 1 fetch failed"},
+		{storage.Run{Warnings: 3}, "ok, 3 fetches failed"},
+		{storage.Run{Error: "timeout", Warnings: 3}, "failed: timeout"},
+	}
+	for _, tt := range tests {
+		if got := runResult(tt.run); got != tt.want {
+			t.Errorf("runResult(%+v) = %q, want %q", tt.run, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package serve
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Authenticator guards the dashboard. Share links are exempt; they carry
+// their own signature.
+type Authenticator interface {
+	// Wrap returns next, letting through only authenticated requests.
+	Wrap(next http.Handler) http.Handler
+}
+
+// BasicAuth is an Authenticator checking a single static username and
+// password.
+type BasicAuth struct {
+	User     string
+	Password string
+}
+
+// Wrap implements Authenticator.
+func (b BasicAuth) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		// Compare both, always, so timing doesn't reveal which was wrong
+		userOK := subtle.ConstantTimeCompare([]byte(user), []byte(b.User))
+		passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(b.Password))
+		if !ok || userOK&passwordOK != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gitstreams", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// mac returns a URL-safe HMAC-SHA256 of msg under key.
+func mac(key []byte, msg string) string {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(msg))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// signValue returns value with an expiry and signature appended, for
+// storing in a cookie. The signature covers purpose too, so a value signed
+// for one cookie can't be passed off as another's.
+func signValue(key []byte, purpose, value string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + mac(key, purpose+"\n"+payload)
+}
+
+// verifyValue returns the value signValue signed for purpose, if the
+// signature matches and it hasn't expired as of now.
+func verifyValue(key []byte, purpose, signed string, now time.Time) (string, error) {
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 || !hmac.Equal([]byte(signed[i+1:]), []byte(mac(key, purpose+"\n"+signed[:i]))) {
+		return "", fmt.Errorf("bad signature")
+	}
+	encoded, exp, _ := strings.Cut(signed[:i], ".")
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return "", fmt.Errorf("expired")
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
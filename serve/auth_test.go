@@ -0,0 +1,92 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+func TestBasicAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) })
+	h := BasicAuth{User: "team", Password: "s3cret"}.Wrap(ok)
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		send     bool
+		wantCode int
+	}{
+		{"no credentials", "", "", false, http.StatusUnauthorized},
+		{"wrong password", "team", "guess", true, http.StatusUnauthorized},
+		{"wrong user", "admin", "s3cret", true, http.StatusUnauthorized},
+		{"correct", "team", "s3cret", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.send {
+			req.SetBasicAuth(tt.user, tt.password)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+		if rec.Code == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic") {
+			t.Errorf("%s: expected a Basic challenge", tt.name)
+		}
+	}
+}
+
+func TestSignedValue(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	signed := signValue(key, sessionCookie, "alice@example.com", now.Add(time.Hour))
+
+	if v, err := verifyValue(key, sessionCookie, signed, now); err != nil || v != "alice@example.com" {
+		t.Errorf("verifyValue() = %q, %v", v, err)
+	}
+	if _, err := verifyValue(key, sessionCookie, signed, now.Add(2*time.Hour)); err == nil {
+		t.Error("expected an expired value to fail")
+	}
+	if _, err := verifyValue([]byte("another key"), sessionCookie, signed, now); err == nil {
+		t.Error("expected a value signed with another key to fail")
+	}
+	if _, err := verifyValue(key, loginCookie, signed, now); err == nil {
+		t.Error("expected a value signed for another cookie to fail")
+	}
+	forged := signValue([]byte("another key"), sessionCookie, "mallory@example.com", now.Add(time.Hour))
+	if _, err := verifyValue(key, sessionCookie, forged, now); err == nil {
+		t.Error("expected a forged value to fail")
+	}
+}
+
+func TestServer_AuthExemptsShareLinks(t *testing.T) {
+	now := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	source := &fakeSource{
+		profiles: []Profile{{Name: "alice"}},
+		reports:  map[string]*report.Report{"alice": {UserActivities: []report.UserActivity{{User: "rsc"}}}},
+	}
+	shares := NewShareSigner([]byte("0123456789abcdef0123456789abcdef"))
+	srv := New(source, fakeGenerator{}, Options{
+		Auth:   BasicAuth{User: "team", Password: "s3cret"},
+		Shares: shares,
+		Now:    func() time.Time { return now },
+	})
+
+	for _, path := range []string{"/", "/p/alice", "/p/alice/share"} {
+		if rec := get(t, srv, path); rec.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without credentials: status %d, want 401", path, rec.Code)
+		}
+	}
+	if rec := get(t, srv, shares.Path("alice", now.Add(time.Hour))); rec.Code != http.StatusOK {
+		t.Errorf("share link: status %d, want 200", rec.Code)
+	}
+	if rec := get(t, srv, "/share/alice?exp=1&sig=x"); rec.Code != http.StatusNotFound {
+		t.Errorf("bad share link: status %d, want 404", rec.Code)
+	}
+}
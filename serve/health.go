@@ -0,0 +1,86 @@
+package serve
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// HealthChecker reports what /healthz checks.
+type HealthChecker interface {
+	// LastSuccessfulSync returns when a sync last succeeded, zero if none
+	// has. An error means the database couldn't be read.
+	LastSuccessfulSync() (time.Time, error)
+}
+
+// health is the body of a /healthz response.
+type health struct {
+	LastSync   *time.Time `json:"last_sync,omitempty"`
+	Status     string     `json:"status"`   // "ok" or "unhealthy"
+	Database   string     `json:"database"` // "ok" or "unreadable"
+	Problems   []string   `json:"problems,omitempty"`
+	SyncAgeSec int64      `json:"last_sync_age_seconds,omitempty"`
+}
+
+// healthHandler serves /healthz.
+type healthHandler struct {
+	check      HealthChecker
+	maxSyncAge time.Duration
+	now        func() time.Time
+	started    time.Time // For before the first sync
+	logger     *slog.Logger
+}
+
+// HealthHandler serves /healthz alone, for daemons without a dashboard,
+// from opts.Health, MaxSyncAge, Now and Logger. Everything else is 404.
+func HealthHandler(opts Options) http.Handler {
+	opts = opts.withDefaults()
+	mux := http.NewServeMux()
+	mux.Handle("GET /healthz", newHealthHandler(opts, opts.Now()))
+	return mux
+}
+
+func newHealthHandler(opts Options, started time.Time) *healthHandler {
+	return &healthHandler{check: opts.Health, maxSyncAge: opts.MaxSyncAge, now: opts.Now, started: started, logger: opts.Logger}
+}
+
+// ServeHTTP reports whether syncs are succeeding and the database can be
+// read: 200 if so, 503 if not. A sync is overdue once maxSyncAge has passed
+// since the last successful one, or since started if none has. It needs no
+// authentication, so uptime checks can reach it; why the database can't be
+// read is logged rather than shown, since it can name files.
+func (hh *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := hh.now()
+	h := health{Status: "ok", Database: "ok"}
+	last, err := hh.check.LastSuccessfulSync()
+	if err != nil {
+		hh.logger.Error("health check can't read the database", "error", err)
+		h.Database = "unreadable"
+		h.Problems = append(h.Problems, "database can't be read")
+	}
+
+	since := hh.started
+	if !last.IsZero() {
+		h.LastSync = &last
+		h.SyncAgeSec = int64(now.Sub(last) / time.Second)
+		since = last
+	}
+	if err == nil && hh.maxSyncAge > 0 && now.Sub(since) > hh.maxSyncAge {
+		if last.IsZero() {
+			h.Problems = append(h.Problems, "no sync has succeeded since the server started")
+		} else {
+			h.Problems = append(h.Problems, "no sync has succeeded in "+now.Sub(last).Round(time.Second).String())
+		}
+	}
+
+	code := http.StatusOK
+	if len(h.Problems) > 0 {
+		h.Status, code = "unhealthy", http.StatusServiceUnavailable
+	}
+	data, _ := json.MarshalIndent(h, "", "  ")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_, _ = w.Write(append(data, '\n'))
+}
@@ -0,0 +1,124 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeHealth struct {
+	err  error
+	last time.Time
+}
+
+func (f *fakeHealth) LastSuccessfulSync() (time.Time, error) { return f.last, f.err }
+
+func TestServer_Health(t *testing.T) {
+	started := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		check       fakeHealth
+		now         time.Time
+		wantCode    int
+		wantProblem string
+	}{
+		{"recent sync", fakeHealth{last: started.Add(time.Hour)}, started.Add(90 * time.Minute), http.StatusOK, ""},
+		{"not synced yet", fakeHealth{}, started.Add(time.Minute), http.StatusOK, ""},
+		{"stale sync", fakeHealth{last: started.Add(time.Hour)}, started.Add(4 * time.Hour), http.StatusServiceUnavailable, "no sync has succeeded in 3h0m0s"},
+		{"never synced", fakeHealth{}, started.Add(3 * time.Hour), http.StatusServiceUnavailable, "since the server started"},
+		{"database error", fakeHealth{err: errors.New("disk I/O error")}, started, http.StatusServiceUnavailable, "database can't be read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := started
+			srv := New(&fakeSource{}, fakeGenerator{}, Options{
+				Health:     &tt.check,
+				MaxSyncAge: 2 * time.Hour,
+				Auth:       BasicAuth{User: "team", Password: "s3cret"},
+				Now:        func() time.Time { return now },
+			})
+			now = tt.now
+
+			rec := get(t, srv, "/healthz")
+			if rec.Code != tt.wantCode {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", cc)
+			}
+			var h health
+			if err := json.Unmarshal(rec.Body.Bytes(), &h); err != nil {
+				t.Fatalf("body isn't JSON: %v\n%s", err, rec.Body.String())
+			}
+			if tt.wantProblem == "" {
+				if h.Status != "ok" || len(h.Problems) != 0 {
+					t.Errorf("health = %+v, want ok", h)
+				}
+				return
+			}
+			if h.Status != "unhealthy" || !strings.Contains(strings.Join(h.Problems, "; "), tt.wantProblem) {
+				t.Errorf("health = %+v, want a problem containing %q", h, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestServer_HealthReportsSyncAge(t *testing.T) {
+	now := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	check := &fakeHealth{last: now.Add(-90 * time.Second)}
+	srv := New(&fakeSource{}, fakeGenerator{}, Options{Health: check, Now: func() time.Time { return now }})
+
+	var h health
+	if err := json.Unmarshal(get(t, srv, "/healthz").Body.Bytes(), &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.LastSync == nil || !h.LastSync.Equal(check.last) || h.SyncAgeSec != 90 || h.Database != "ok" {
+		t.Errorf("health = %+v, want the last sync 90s ago", h)
+	}
+}
+
+func TestServer_HealthDisabled(t *testing.T) {
+	if rec := get(t, New(&fakeSource{}, fakeGenerator{}, Options{}), "/healthz"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a health checker, got %d", rec.Code)
+	}
+}
+
+func TestServer_HealthHidesDatabaseErrors(t *testing.T) {
+	var logged bytes.Buffer
+	srv := New(&fakeSource{}, fakeGenerator{}, Options{
+		Health: &fakeHealth{err: errors.New("unable to open /home/alice/.local/share/gitstreams/gitstreams.db")},
+		Logger: slog.New(slog.NewTextHandler(&logged, nil)),
+	})
+
+	rec := get(t, srv, "/healthz")
+	if strings.Contains(rec.Body.String(), "/home/alice") {
+		t.Errorf("health response leaks the error: %s", rec.Body.String())
+	}
+	var h health
+	if err := json.Unmarshal(rec.Body.Bytes(), &h); err != nil || h.Database != "unreadable" {
+		t.Errorf("health = %+v, %v; want the database unreadable", h, err)
+	}
+	if !strings.Contains(logged.String(), "/home/alice") {
+		t.Errorf("expected the error logged, got %q", logged.String())
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	now := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	h := HealthHandler(Options{
+		Health:     &fakeHealth{last: now.Add(-3 * time.Hour)},
+		MaxSyncAge: 2 * time.Hour,
+		Now:        func() time.Time { return now },
+	})
+	if rec := get(t, h, "/healthz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d for a stale sync, want 503: %s", rec.Code, rec.Body.String())
+	}
+	if rec := get(t, h, "/"); rec.Code != http.StatusNotFound {
+		t.Errorf("status %d for /, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package serve
+
+import (
+	"time"
+
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// heatmapWeeks is how many weeks the activity heatmap covers, about three
+// months.
+const heatmapWeeks = 13
+
+// heatmapCell is one day in the heatmap.
+type heatmapCell struct {
+	Day   time.Time
+	Count int
+	Level int // 0 for no activity, up to 4 for the busiest days
+}
+
+// heatmap is a contributions-style grid of daily activity: a column per
+// week, Sunday first, ending with the current week.
+type heatmap struct {
+	Weeks [][]heatmapCell
+	Total int
+}
+
+// heatmapStart returns the first day the heatmap ending today shows.
+func heatmapStart(today time.Time) time.Time {
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	return day.AddDate(0, 0, -int(day.Weekday())-7*(heatmapWeeks-1))
+}
+
+// buildHeatmap lays out daily counts in a heatmap ending today. Days after
+// today are left out of the last week.
+func buildHeatmap(counts []storage.DayCount, today time.Time) *heatmap {
+	byDay := make(map[string]int, len(counts))
+	busiest := 0
+	for _, c := range counts {
+		byDay[c.Day.Format(time.DateOnly)] += c.Count
+	}
+	for _, n := range byDay {
+		busiest = max(busiest, n)
+	}
+
+	h := &heatmap{}
+	day := heatmapStart(today)
+	for w := 0; w < heatmapWeeks; w++ {
+		var week []heatmapCell
+		for d := 0; d < 7 && !day.After(today); d++ {
+			n := byDay[day.Format(time.DateOnly)]
+			cell := heatmapCell{Day: day, Count: n}
+			if n > 0 {
+				// Quarters of the busiest day, rounding up so any activity shows
+				cell.Level = (4*n + busiest - 1) / busiest
+			}
+			week = append(week, cell)
+			h.Total += n
+			day = day.AddDate(0, 0, 1)
+		}
+		h.Weeks = append(h.Weeks, week)
+	}
+	return h
+}
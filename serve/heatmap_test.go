@@ -0,0 +1,83 @@
+package serve
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestHeatmapStart(t *testing.T) {
+	// A Thursday afternoon
+	today := time.Date(2026, 1, 15, 15, 0, 0, 0, time.UTC)
+	start := heatmapStart(today)
+	if start.Weekday() != time.Sunday || start.Hour() != 0 {
+		t.Errorf("heatmapStart() = %v, want a Sunday midnight", start)
+	}
+	if want := time.Date(2025, 10, 19, 0, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("heatmapStart() = %v, want %v", start, want)
+	}
+}
+
+func TestBuildHeatmap(t *testing.T) {
+	today := time.Date(2026, 1, 15, 15, 0, 0, 0, time.UTC)
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+	h := buildHeatmap([]storage.DayCount{
+		{Day: day(1, 14), Count: 8},
+		{Day: day(1, 15), Count: 1},
+		{Day: day(1, 5), Count: 4},
+	}, today)
+
+	if len(h.Weeks) != heatmapWeeks {
+		t.Fatalf("expected %d weeks, got %d", heatmapWeeks, len(h.Weeks))
+	}
+	for i, week := range h.Weeks[:heatmapWeeks-1] {
+		if len(week) != 7 {
+			t.Errorf("week %d has %d days", i, len(week))
+		}
+	}
+	// The current week stops at Thursday
+	last := h.Weeks[heatmapWeeks-1]
+	if len(last) != 5 || !last[4].Day.Equal(day(1, 15)) {
+		t.Fatalf("unexpected last week %+v", last)
+	}
+	if h.Total != 13 {
+		t.Errorf("Total = %d, want 13", h.Total)
+	}
+
+	levels := map[int]int{}
+	for _, week := range h.Weeks {
+		for _, c := range week {
+			levels[c.Count] = c.Level
+		}
+	}
+	if levels[8] != 4 || levels[4] != 2 || levels[1] != 1 || levels[0] != 0 {
+		t.Errorf("unexpected levels by count %v", levels)
+	}
+}
+
+func TestServer_IndexHeatmap(t *testing.T) {
+	now := time.Date(2026, 1, 15, 15, 0, 0, 0, time.UTC)
+	source := &fakeSource{
+		profiles: []Profile{{Name: "alice"}},
+		reports:  map[string]*report.Report{"alice": nil},
+		activity: map[string][]storage.DayCount{
+			"alice": {{Day: time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC), Count: 3}},
+		},
+	}
+	srv := New(source, fakeGenerator{}, Options{Now: func() time.Time { return now }})
+
+	rec := get(t, srv, "/")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`class="heatmap"`, "3 activities in the last 13 weeks", "3 activities on Wed Jan 14", `class="level-4"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("index missing %q", want)
+		}
+	}
+}
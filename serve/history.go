@@ -0,0 +1,173 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+// historyDays is how far back a profile's history page goes.
+const historyDays = 90
+
+// DayDiff is what changed over one day of a profile's history.
+type DayDiff struct {
+	Result *diff.Result // The raw diff, as served by the JSON API
+	Report *report.Report
+}
+
+// historyDay is a day listed on a profile's history page.
+type historyDay struct {
+	Day       time.Time
+	Snapshots int
+}
+
+// groupByDay counts snapshot times per day in loc, keeping their newest
+// first order.
+func groupByDay(times []time.Time, loc *time.Location) []historyDay {
+	var days []historyDay
+	for _, t := range times {
+		t = t.In(loc)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		if len(days) > 0 && days[len(days)-1].Day.Equal(day) {
+			days[len(days)-1].Snapshots++
+			continue
+		}
+		days = append(days, historyDay{Day: day, Snapshots: 1})
+	}
+	return days
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	profile := r.PathValue("profile")
+	today := s.opts.Now()
+	times, err := s.source.Snapshots(profile, today.AddDate(0, 0, -historyDays))
+	switch {
+	case errors.Is(err, ErrUnknownProfile):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.render(w, historyTemplate, struct {
+		Profile string
+		Days    []historyDay
+	}{profile, groupByDay(times, today.Location())})
+}
+
+func (s *Server) handleDay(w http.ResponseWriter, r *http.Request) {
+	day, ok := s.lookupDay(w, r)
+	if !ok {
+		return
+	}
+	s.generate(w, linkDashboard(day.Report, r.PathValue("profile")))
+}
+
+func (s *Server) handleDayDiff(w http.ResponseWriter, r *http.Request) {
+	day, ok := s.lookupDay(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, day.Result)
+}
+
+// lookupDay loads the day of a profile's history a request names. If it
+// can't, it writes the error response and returns false.
+func (s *Server) lookupDay(w http.ResponseWriter, r *http.Request) (*DayDiff, bool) {
+	date := r.PathValue("date")
+	day, err := time.ParseInLocation(time.DateOnly, date, s.opts.Now().Location())
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	dd, err := s.source.Day(r.PathValue("profile"), day)
+	switch {
+	case errors.Is(err, ErrUnknownProfile):
+		http.NotFound(w, r)
+		return nil, false
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	case dd == nil:
+		http.Error(w, "No snapshot was taken on "+date+".", http.StatusNotFound)
+		return nil, false
+	}
+	return dd, true
+}
+
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	result, err := s.source.Diff(r.PathValue("profile"))
+	switch {
+	case errors.Is(err, ErrUnknownProfile):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	case result == nil:
+		notSynced(w)
+		return
+	}
+	writeJSON(w, result)
+}
+
+// writeJSON writes v as the response's JSON body.
+func writeJSON(w http.ResponseWriter, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// dayPath returns the URL path of a profile's report for a day.
+func dayPath(profile string, day time.Time) string {
+	return profilePath(profile) + "/day/" + day.Format(time.DateOnly)
+}
+
+// diffPath returns the JSON API path of the raw diff behind a profile's
+// latest report.
+func diffPath(profile string) string {
+	return "/api" + profilePath(profile) + "/diff"
+}
+
+// dayDiffPath returns the JSON API path of the raw diff behind a profile's
+// report for a day.
+func dayDiffPath(profile string, day time.Time) string {
+	return "/api" + dayPath(profile, day) + "/diff"
+}
+
+var historyTemplate = template.Must(template.New("history").Funcs(funcs).Funcs(template.FuncMap{
+	"dayPath":     dayPath,
+	"diffPath":    diffPath,
+	"dayDiffPath": dayDiffPath,
+	"historyDays": func() int { return historyDays },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>{{.Profile}}'s history - GitStreams</title>
+{{style}}
+</head>
+<body>
+<h1>{{.Profile}}'s history</h1>
+<p class="meta"><a href="{{profilePath .Profile}}">Latest report</a> · <a href="{{diffPath .Profile}}">raw diff</a></p>
+<ul>
+{{- range .Days}}
+<li><a href="{{dayPath $.Profile .Day}}">{{.Day.Format "Monday, Jan 2, 2006"}}</a>
+<div class="meta">{{.Snapshots}} snapshots · <a href="{{dayDiffPath $.Profile .Day}}">raw diff</a></div></li>
+{{- else}}
+<li class="meta">No snapshots in the last {{historyDays}} days.</li>
+{{- end}}
+</ul>
+<p class="meta"><a href="/">Back to the dashboard</a></p>
+</body>
+</html>
+`))
@@ -0,0 +1,111 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+)
+
+func TestGroupByDay(t *testing.T) {
+	loc := time.FixedZone("PST", -8*60*60)
+	times := []time.Time{
+		time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 15, 7, 0, 0, 0, time.UTC), // Jan 14 in PST
+		time.Date(2026, 1, 14, 20, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 12, 20, 0, 0, 0, time.UTC),
+	}
+	days := groupByDay(times, loc)
+	want := []string{"2026-01-15 1", "2026-01-14 2", "2026-01-12 1"}
+	if len(days) != len(want) {
+		t.Fatalf("groupByDay() = %+v, want %v", days, want)
+	}
+	for i, d := range days {
+		if got := fmt.Sprintf("%s %d", d.Day.Format(time.DateOnly), d.Snapshots); got != want[i] {
+			t.Errorf("day %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestServer_History(t *testing.T) {
+	now := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	source := &fakeSource{
+		profiles: []Profile{{Name: "alice"}, {Name: "bob"}},
+		reports:  map[string]*report.Report{"alice": {UserActivities: []report.UserActivity{{User: "rsc"}}}, "bob": nil},
+		snapshots: map[string][]time.Time{
+			"alice": {now, now.Add(-time.Hour), now.AddDate(0, 0, -2)},
+		},
+		days: map[string]*DayDiff{"2026-01-13": {
+			Result: &diff.Result{NewUsers: []string{"robpike"}},
+			Report: &report.Report{UserActivities: []report.UserActivity{{User: "robpike"}}},
+		}},
+		diffs: map[string]*diff.Result{"alice": {NewUsers: []string{"rsc"}}},
+	}
+	srv := New(source, fakeGenerator{}, Options{Now: func() time.Time { return now }})
+
+	if rec := get(t, srv, "/"); !strings.Contains(rec.Body.String(), `href="/p/alice/history"`) {
+		t.Errorf("expected a history link on the index, got %q", rec.Body.String())
+	}
+
+	rec := get(t, srv, "/p/alice/history")
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<a href="/p/alice/day/2026-01-15">Thursday, Jan 15, 2026</a>`,
+		"2 snapshots",
+		`<a href="/p/alice/day/2026-01-13">`,
+		`href="/api/p/alice/day/2026-01-13/diff"`,
+		`href="/api/p/alice/diff"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("history page doesn't contain %q:\n%s", want, body)
+		}
+	}
+	if rec := get(t, srv, "/p/bob/history"); !strings.Contains(rec.Body.String(), "No snapshots in the last 90 days") {
+		t.Errorf("expected an empty history for bob, got %q", rec.Body.String())
+	}
+
+	tests := []struct {
+		path     string
+		wantBody string
+		wantCode int
+	}{
+		{"/p/alice/day/2026-01-13", "report for robpike", http.StatusOK},
+		{"/p/alice/day/2026-01-12", "No snapshot was taken on 2026-01-12", http.StatusNotFound},
+		{"/p/alice/day/yesterday", "404", http.StatusNotFound},
+		{"/p/carol/day/2026-01-13", "404", http.StatusNotFound},
+		{"/p/carol/history", "404", http.StatusNotFound},
+		{"/api/p/bob/diff", "hasn't synced yet", http.StatusServiceUnavailable},
+		{"/api/p/carol/diff", "404", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := get(t, srv, tt.path)
+		if rec.Code != tt.wantCode {
+			t.Errorf("GET %s: status %d, want %d", tt.path, rec.Code, tt.wantCode)
+		}
+		if !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("GET %s: body %q doesn't contain %q", tt.path, rec.Body.String(), tt.wantBody)
+		}
+	}
+
+	for path, want := range map[string]string{
+		"/api/p/alice/diff":                "rsc",
+		"/api/p/alice/day/2026-01-13/diff": "robpike",
+	} {
+		rec := get(t, srv, path)
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("GET %s: Content-Type %q", path, ct)
+		}
+		var result diff.Result
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		if len(result.NewUsers) != 1 || result.NewUsers[0] != want {
+			t.Errorf("GET %s: NewUsers = %v, want %s", path, result.NewUsers, want)
+		}
+	}
+}
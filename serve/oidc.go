@@ -0,0 +1,406 @@
+package serve
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookie = "gitstreams_session"
+	loginCookie   = "gitstreams_login"
+
+	// sessionTTL is how long a login lasts before the provider is asked again.
+	sessionTTL = 12 * time.Hour
+
+	// loginTTL bounds how long a visit to the provider's login page may take.
+	loginTTL = 10 * time.Minute
+)
+
+// OIDCConfig configures login through an OpenID Connect provider.
+type OIDCConfig struct {
+	Issuer       string // e.g. https://accounts.google.com
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // This server's callback, e.g. https://gitstreams.example.com/auth/callback
+
+	// Allow lists the email addresses, or domains as "@example.com", that
+	// may sign in. Empty allows anyone the provider authenticates.
+	Allow []string
+}
+
+// OIDC is an Authenticator that signs users in with an OpenID Connect
+// provider using the authorization code flow, then keeps them signed in
+// with a signed session cookie.
+type OIDC struct {
+	now          func() time.Time
+	client       *http.Client
+	keys         map[string]*rsa.PublicKey // The provider's signing keys, by key ID
+	callbackPath string
+	authURL      string
+	tokenURL     string
+	jwksURL      string
+	cfg          OIDCConfig
+	sessionKey   []byte
+	secure       bool // Mark cookies Secure when served over HTTPS
+	mu           sync.Mutex
+}
+
+// NewOIDC discovers the provider's endpoints from its issuer URL. Sessions
+// are signed with sessionKey, which should be at least 32 random bytes.
+func NewOIDC(ctx context.Context, cfg OIDCConfig, client *http.Client, sessionKey []byte, now func() time.Time) (*OIDC, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("OIDC needs an issuer, client ID and redirect URL")
+	}
+	redirect, err := url.Parse(cfg.RedirectURL)
+	if err != nil || redirect.Host == "" {
+		return nil, fmt.Errorf("invalid OIDC redirect URL %q", cfg.RedirectURL)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if now == nil {
+		now = time.Now
+	}
+
+	var discovery struct {
+		Issuer   string `json:"issuer"`
+		AuthURL  string `json:"authorization_endpoint"`
+		TokenURL string `json:"token_endpoint"`
+		JWKSURL  string `json:"jwks_uri"`
+	}
+	wellKnown := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, client, wellKnown, &discovery); err != nil {
+		return nil, fmt.Errorf("discovering OIDC provider: %w", err)
+	}
+	if discovery.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("OIDC provider says its issuer is %q, not %q", discovery.Issuer, cfg.Issuer)
+	}
+	if discovery.AuthURL == "" || discovery.TokenURL == "" || discovery.JWKSURL == "" {
+		return nil, errors.New("OIDC provider's discovery document is missing endpoints")
+	}
+
+	return &OIDC{
+		now:          now,
+		client:       client,
+		callbackPath: redirect.Path,
+		authURL:      discovery.AuthURL,
+		tokenURL:     discovery.TokenURL,
+		jwksURL:      discovery.JWKSURL,
+		cfg:          cfg,
+		sessionKey:   sessionKey,
+		secure:       redirect.Scheme == "https",
+	}, nil
+}
+
+// Wrap implements Authenticator.
+func (o *OIDC) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == o.callbackPath {
+			o.handleCallback(w, r)
+			return
+		}
+		// The allow list is checked again, so taking someone off it ends
+		// their session
+		if c, err := r.Cookie(sessionCookie); err == nil {
+			if email, err := verifyValue(o.sessionKey, sessionCookie, c.Value, o.now()); err == nil && o.allowed(email) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		o.startLogin(w, r)
+	})
+}
+
+// startLogin sends the browser to the provider, remembering where it was
+// going. API clients get a 401 instead.
+func (o *OIDC) startLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	state, nonce := randomString(), randomString()
+	login := strings.Join([]string{state, nonce, r.URL.RequestURI()}, " ")
+	o.setCookie(w, loginCookie, signValue(o.sessionKey, loginCookie, login, o.now().Add(loginTTL)), loginTTL)
+
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {o.cfg.ClientID},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"scope":         {"openid email"},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	http.Redirect(w, r, o.authURL+"?"+q.Encode(), http.StatusFound)
+}
+
+// handleCallback finishes a login: it swaps the code for an ID token,
+// checks it, and starts a session for an allowed user.
+func (o *OIDC) handleCallback(w http.ResponseWriter, r *http.Request) {
+	c, err := r.Cookie(loginCookie)
+	if err != nil {
+		http.Error(w, "Login expired; try again.", http.StatusBadRequest)
+		return
+	}
+	login, err := verifyValue(o.sessionKey, loginCookie, c.Value, o.now())
+	if err != nil {
+		http.Error(w, "Login expired; try again.", http.StatusBadRequest)
+		return
+	}
+	parts := strings.SplitN(login, " ", 3)
+	if len(parts) != 3 || r.URL.Query().Get("state") != parts[0] {
+		http.Error(w, "Login state mismatch; try again.", http.StatusBadRequest)
+		return
+	}
+	nonce, returnTo := parts[1], parts[2]
+	if msg := r.URL.Query().Get("error"); msg != "" {
+		http.Error(w, "Login failed: "+msg, http.StatusForbidden)
+		return
+	}
+
+	email, err := o.exchange(r.Context(), r.URL.Query().Get("code"), nonce)
+	if err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	if !o.allowed(email) {
+		http.Error(w, email+" isn't allowed to use this dashboard.", http.StatusForbidden)
+		return
+	}
+
+	o.setCookie(w, loginCookie, "", -1)
+	o.setCookie(w, sessionCookie, signValue(o.sessionKey, sessionCookie, email, o.now().Add(sessionTTL)), sessionTTL)
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") {
+		returnTo = "/"
+	}
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
+
+// exchange redeems an authorization code and returns the verified email
+// from the ID token.
+func (o *OIDC) exchange(ctx context.Context, code, nonce string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"client_id":     {o.cfg.ClientID},
+		"client_secret": {o.cfg.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("redeeming code: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("redeeming code: provider returned %s", resp.Status)
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return "", fmt.Errorf("reading token response: %w", err)
+	}
+
+	claims, err := o.verifyIDToken(ctx, tokens.IDToken)
+	if err != nil {
+		return "", err
+	}
+	if claims.Nonce != nonce {
+		return "", errors.New("ID token nonce mismatch")
+	}
+	if claims.Email == "" || (claims.EmailVerified != nil && !*claims.EmailVerified) {
+		return "", errors.New("provider didn't return a verified email")
+	}
+	return claims.Email, nil
+}
+
+// idClaims are the ID token claims the dashboard checks.
+type idClaims struct {
+	EmailVerified *bool    `json:"email_verified"`
+	Issuer        string   `json:"iss"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	Audience      audience `json:"aud"`
+	Expiry        int64    `json:"exp"`
+}
+
+// audience is the aud claim, which may be a string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// verifyIDToken checks an RS256-signed ID token's signature against the
+// provider's keys, and its issuer, audience and expiry.
+func (o *OIDC) verifyIDToken(ctx context.Context, token string) (*idClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed ID token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed ID token header: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported ID token algorithm %q", header.Alg)
+	}
+	key, err := o.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed ID token signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, errors.New("ID token signature doesn't verify")
+	}
+
+	var claims idClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %w", err)
+	}
+	switch {
+	case claims.Issuer != o.cfg.Issuer:
+		return nil, fmt.Errorf("ID token is from %q, not %q", claims.Issuer, o.cfg.Issuer)
+	case !slices.Contains(claims.Audience, o.cfg.ClientID):
+		return nil, errors.New("ID token is for another client")
+	case !o.now().Before(time.Unix(claims.Expiry, 0)):
+		return nil, errors.New("ID token expired")
+	}
+	return &claims, nil
+}
+
+// key returns the provider's signing key with the given ID, refreshing the
+// key set when it's not known, as happens after the provider rotates keys.
+func (o *OIDC) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJSON(ctx, o.client, o.jwksURL, &jwks); err != nil {
+		return nil, fmt.Errorf("fetching provider keys: %w", err)
+	}
+	o.keys = make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		o.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	key, ok := o.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("provider has no signing key %q", kid)
+	}
+	return key, nil
+}
+
+// allowed reports whether email may sign in.
+func (o *OIDC) allowed(email string) bool {
+	if len(o.cfg.Allow) == 0 {
+		return true
+	}
+	email = strings.ToLower(email)
+	for _, a := range o.cfg.Allow {
+		a = strings.ToLower(a)
+		if email == a || (strings.HasPrefix(a, "@") && strings.HasSuffix(email, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *OIDC) setCookie(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   o.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// getJSON fetches url and decodes its JSON body into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT.
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// randomString returns an unguessable URL-safe string.
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,261 @@
+package serve
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a minimal OpenID Connect provider that signs in whoever
+// its email field names.
+type fakeProvider struct {
+	key      *rsa.PrivateKey
+	server   *httptest.Server
+	email    string
+	nonce    string // Nonce from the last authorization request
+	clientID string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{key: key, clientID: "gitstreams", email: "alice@example.com"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" || r.FormValue("client_secret") != "shh" {
+			http.Error(w, "bad code", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": p.idToken(t, map[string]any{
+			"iss":   p.server.URL,
+			"aud":   p.clientID,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": p.nonce,
+			"email": p.email,
+		})})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeProvider) idToken(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	enc := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := enc(map[string]string{"alg": "RS256", "kid": "k1"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newTestOIDC(t *testing.T, p *fakeProvider, allow ...string) *OIDC {
+	t.Helper()
+	o, err := NewOIDC(context.Background(), OIDCConfig{
+		Issuer:       p.server.URL,
+		ClientID:     p.clientID,
+		ClientSecret: "shh",
+		RedirectURL:  "http://dashboard.test/auth/callback",
+		Allow:        allow,
+	}, p.server.Client(), []byte("0123456789abcdef0123456789abcdef"), nil)
+	if err != nil {
+		t.Fatalf("NewOIDC() error = %v", err)
+	}
+	return o
+}
+
+// login runs the browser's side of a sign-in to path and returns the
+// callback's response.
+func login(t *testing.T, p *fakeProvider, h http.Handler, path, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected a redirect to the provider, got %d", rec.Code)
+	}
+	authURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(authURL.String(), p.server.URL+"/authorize") {
+		t.Fatalf("unexpected redirect %q", rec.Header().Get("Location"))
+	}
+	p.nonce = authURL.Query().Get("nonce")
+
+	callback := httptest.NewRequest(http.MethodGet, "/auth/callback?code="+code+"&state="+authURL.Query().Get("state"), nil)
+	for _, c := range rec.Result().Cookies() {
+		callback.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, callback)
+	return rec
+}
+
+func TestOIDC(t *testing.T) {
+	p := newFakeProvider(t)
+	h := newTestOIDC(t, p, "@example.com").Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("dashboard"))
+	}))
+
+	rec := login(t, p, h, "/p/alice", "good-code")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/p/alice" {
+		t.Fatalf("callback: %d to %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+	}
+
+	var session *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookie {
+			session = c
+		}
+	}
+	if session == nil || !session.HttpOnly {
+		t.Fatalf("expected an HttpOnly session cookie, got %v", rec.Result().Cookies())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/p/alice", nil)
+	req.AddCookie(session)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "dashboard" {
+		t.Errorf("with session: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOIDC_Rejections(t *testing.T) {
+	p := newFakeProvider(t)
+	h := newTestOIDC(t, p, "bob@example.com").Wrap(http.NotFoundHandler())
+
+	// alice authenticates, but isn't on the allow list
+	if rec := login(t, p, h, "/", "good-code"); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "isn't allowed") {
+		t.Errorf("disallowed user: %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := login(t, p, h, "/", "bad-code"); rec.Code != http.StatusForbidden {
+		t.Errorf("bad code: status %d, want 403", rec.Code)
+	}
+
+	// A callback without the login cookie, as from a forged link
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/callback?code=good-code&state=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("callback without login: status %d, want 400", rec.Code)
+	}
+
+	// The login cookie every visitor gets isn't a session
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == loginCookie {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: c.Value})
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != http.StatusFound {
+				t.Errorf("login cookie as session: status %d, want a redirect to log in", rec.Code)
+			}
+		}
+	}
+
+	// A session for someone since taken off the allow list no longer works
+	o := newTestOIDC(t, p, "bob@example.com")
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: signValue(o.sessionKey, sessionCookie, "alice@example.com", o.now().Add(time.Hour))})
+	rec = httptest.NewRecorder()
+	o.Wrap(http.NotFoundHandler()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound {
+		t.Errorf("disallowed session: status %d, want a redirect to log in", rec.Code)
+	}
+
+	// API clients get a 401 rather than a redirect
+	req = httptest.NewRequest(http.MethodGet, "/api/diff", nil)
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("API request: status %d, want 401", rec.Code)
+	}
+}
+
+func TestOIDC_VerifyIDToken(t *testing.T) {
+	p := newFakeProvider(t)
+	o := newTestOIDC(t, p)
+	valid := func() map[string]any {
+		return map[string]any{"iss": p.server.URL, "aud": []string{"other", p.clientID}, "exp": time.Now().Add(time.Hour).Unix(), "email": "a@b.c"}
+	}
+	if _, err := o.verifyIDToken(context.Background(), p.idToken(t, valid())); err != nil {
+		t.Errorf("valid token: %v", err)
+	}
+
+	tests := []struct {
+		mutate  func(map[string]any)
+		wantErr string
+	}{
+		{func(c map[string]any) { c["iss"] = "https://evil.test" }, "not"},
+		{func(c map[string]any) { c["aud"] = "someone-else" }, "another client"},
+		{func(c map[string]any) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, "expired"},
+	}
+	for _, tt := range tests {
+		claims := valid()
+		tt.mutate(claims)
+		if _, err := o.verifyIDToken(context.Background(), p.idToken(t, claims)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("verifyIDToken() error = %v, want %q", err, tt.wantErr)
+		}
+	}
+
+	// Re-signed claims with the original signature don't verify
+	token := p.idToken(t, valid())
+	parts := strings.Split(token, ".")
+	forged := valid()
+	forged["email"] = "mallory@b.c"
+	data, _ := json.Marshal(forged)
+	parts[1] = base64.RawURLEncoding.EncodeToString(data)
+	if _, err := o.verifyIDToken(context.Background(), strings.Join(parts, ".")); err == nil {
+		t.Error("expected a tampered token to fail")
+	}
+}
+
+func TestNewOIDC_IssuerMismatch(t *testing.T) {
+	p := newFakeProvider(t)
+	_, err := NewOIDC(context.Background(), OIDCConfig{
+		Issuer:      p.server.URL + "/",
+		ClientID:    "gitstreams",
+		RedirectURL: "http://dashboard.test/auth/callback",
+	}, p.server.Client(), []byte("key"), nil)
+	if err == nil {
+		t.Error("expected an error when the issuer doesn't match discovery")
+	}
+}
@@ -0,0 +1,402 @@
+// Package serve provides the HTTP dashboard for `gitstreams serve`.
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// ErrUnknownProfile is returned by a Source for a profile it doesn't have.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// Profile describes a viewer whose report the server shows.
+type Profile struct {
+	LastSync time.Time // Zero until the first sync finishes
+	Name     string
+	Users    int // How many users the profile follows
+}
+
+// Source provides the reports the server shows. Implementations must be
+// safe for concurrent use; syncs update them while requests are served.
+type Source interface {
+	// Profiles lists the viewer profiles, in display order.
+	Profiles() []Profile
+
+	// Report returns a profile's latest report, nil if it hasn't synced
+	// yet, or ErrUnknownProfile.
+	Report(profile string) (*report.Report, error)
+
+	// DailyActivity counts a profile's activities per day since the given
+	// time, in its location. Days without activity may be left out.
+	DailyActivity(profile string, since time.Time) ([]storage.DayCount, error)
+
+	// RepoTimeline returns a repo's history in a profile's stored
+	// snapshots, nil if none of them mention it, or ErrUnknownProfile.
+	RepoTimeline(profile, repo string) (*RepoTimeline, error)
+
+	// User returns what a profile knows about a followed user, with their
+	// activity per day since the given time, nil if its history has nothing
+	// on them, or ErrUnknownProfile.
+	User(ctx context.Context, profile, username string, since time.Time) (*UserDetails, error)
+
+	// SetUserPrefs saves how a profile treats a followed user. Muted users
+	// are left out of the profile's report.
+	SetUserPrefs(profile string, prefs storage.UserPrefs) error
+
+	// Snapshots returns when a profile's stored snapshots since the given
+	// time were taken, newest first, or ErrUnknownProfile.
+	Snapshots(profile string, since time.Time) ([]time.Time, error)
+
+	// Day returns what changed on a day of a profile's history: from the
+	// last snapshot before the day to the last one taken on it. It's nil if
+	// no snapshot was taken that day, or ErrUnknownProfile.
+	Day(profile string, day time.Time) (*DayDiff, error)
+
+	// Diff returns the raw diff behind a profile's latest report, nil if it
+	// hasn't synced yet, or ErrUnknownProfile.
+	Diff(profile string) (*diff.Result, error)
+}
+
+// Generator renders a report as HTML.
+type Generator interface {
+	Generate(w io.Writer, r *report.Report) error
+}
+
+// Options configures optional Server features.
+type Options struct {
+	Auth   Authenticator    // Guards everything but share links and /healthz; nil leaves the dashboard open
+	Shares *ShareSigner     // Signs read-only share links; nil disables sharing
+	Health HealthChecker    // Serves /healthz; nil disables it
+	Sync   Syncer           // Serves the sync page and its progress stream; nil disables them
+	Now    func() time.Time // Defaults to time.Now
+	Logger *slog.Logger     // Logs errors responses leave out; defaults to slog.Default()
+
+	// MaxSyncAge is how long /healthz lets pass without a successful sync
+	// before reporting the server unhealthy; 0 never does.
+	MaxSyncAge time.Duration
+}
+
+// Server serves each profile's report and an index of profiles.
+type Server struct {
+	source    Source
+	generator Generator
+	mux       *http.ServeMux
+	protected http.Handler // mux behind opts.Auth
+	opts      Options
+}
+
+// withDefaults returns opts with the defaults filled in.
+func (opts Options) withDefaults() Options {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return opts
+}
+
+// New creates a Server showing reports from source.
+func New(source Source, generator Generator, opts Options) *Server {
+	opts = opts.withDefaults()
+	s := &Server{
+		source:    source,
+		generator: generator,
+		mux:       http.NewServeMux(),
+		opts:      opts,
+	}
+	s.mux.HandleFunc("GET /{$}", s.handleIndex)
+	s.mux.HandleFunc("GET /p/{profile}", s.handleReport)
+	s.mux.HandleFunc("GET /p/{profile}/repo/{owner}/{name}", s.handleRepo)
+	s.mux.HandleFunc("GET /p/{profile}/u/{user}", s.handleUser)
+	s.mux.HandleFunc("POST /p/{profile}/u/{user}", s.handleSetUserPrefs)
+	s.mux.HandleFunc("GET /p/{profile}/history", s.handleHistory)
+	s.mux.HandleFunc("GET /p/{profile}/day/{date}", s.handleDay)
+	s.mux.HandleFunc("GET /api/p/{profile}/diff", s.handleDiff)
+	s.mux.HandleFunc("GET /api/p/{profile}/day/{date}/diff", s.handleDayDiff)
+	if opts.Shares != nil {
+		s.mux.HandleFunc("GET /p/{profile}/share", s.handleSharePage)
+		s.mux.HandleFunc("POST /p/{profile}/share", s.handleCreateShare)
+		s.mux.HandleFunc("GET /share/{profile}", s.handleShared)
+	}
+	if opts.Health != nil {
+		s.mux.Handle("GET /healthz", newHealthHandler(opts, opts.Now()))
+	}
+	if opts.Sync != nil {
+		s.mux.HandleFunc("GET /sync", s.handleSyncPage)
+		s.mux.HandleFunc("POST /sync", s.handleSyncNow)
+		s.mux.HandleFunc("GET /api/sync/events", s.handleSyncEvents)
+	}
+	s.protected = s.mux
+	if opts.Auth != nil {
+		s.protected = opts.Auth.Wrap(s.mux)
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Share links are checked by their signature instead, and health
+	// checks can't sign in
+	if strings.HasPrefix(r.URL.Path, "/share/") || r.URL.Path == "/healthz" {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+	s.protected.ServeHTTP(w, r)
+}
+
+// indexProfile is a profile as listed on the index page.
+type indexProfile struct {
+	Heatmap *heatmap // nil if activity counts couldn't be loaded
+	Profile
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	today := s.opts.Now()
+	var profiles []indexProfile
+	for _, p := range s.source.Profiles() {
+		ip := indexProfile{Profile: p}
+		if counts, err := s.source.DailyActivity(p.Name, heatmapStart(today)); err == nil {
+			ip.Heatmap = buildHeatmap(counts, today)
+		}
+		profiles = append(profiles, ip)
+	}
+	s.render(w, indexTemplate, struct {
+		Profiles []indexProfile
+		Sharing  bool
+		Syncing  bool
+	}{profiles, s.opts.Shares != nil, s.opts.Sync != nil})
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	s.writeReport(w, r, r.PathValue("profile"), true)
+}
+
+// writeReport renders a profile's latest report. Linked, repos and users
+// link to their pages on the dashboard rather than GitHub.
+func (s *Server) writeReport(w http.ResponseWriter, r *http.Request, profile string, linked bool) {
+	rpt, err := s.source.Report(profile)
+	switch {
+	case errors.Is(err, ErrUnknownProfile):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	case rpt == nil:
+		notSynced(w)
+		return
+	}
+	if linked {
+		rpt = linkDashboard(rpt, profile)
+	}
+	s.generate(w, rpt)
+}
+
+// generate renders a report with the server's generator.
+func (s *Server) generate(w http.ResponseWriter, rpt *report.Report) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.generator.Generate(w, rpt); err != nil {
+		http.Error(w, fmt.Sprintf("rendering report: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// notSynced responds that a profile has nothing to show until its first
+// sync.
+func notSynced(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "60")
+	http.Error(w, "This profile hasn't synced yet; try again in a minute.", http.StatusServiceUnavailable)
+}
+
+func (s *Server) handleSharePage(w http.ResponseWriter, r *http.Request) {
+	profile := r.PathValue("profile")
+	if !s.hasProfile(profile) {
+		http.NotFound(w, r)
+		return
+	}
+	s.render(w, shareTemplate, sharePage{Profile: profile, Days: int(DefaultShareTTL.Hours() / 24)})
+}
+
+func (s *Server) handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin request refused", http.StatusForbidden)
+		return
+	}
+	profile := r.PathValue("profile")
+	if !s.hasProfile(profile) {
+		http.NotFound(w, r)
+		return
+	}
+	ttl := DefaultShareTTL
+	if days := r.FormValue("days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			http.Error(w, "days must be a positive number", http.StatusBadRequest)
+			return
+		}
+		ttl = time.Duration(n) * 24 * time.Hour
+	}
+	if ttl > MaxShareTTL {
+		http.Error(w, fmt.Sprintf("share links can last at most %d days", int(MaxShareTTL.Hours()/24)), http.StatusBadRequest)
+		return
+	}
+
+	expires := s.opts.Now().Add(ttl)
+	link := url.URL{Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		link.Scheme = "https"
+	}
+	s.render(w, shareTemplate, sharePage{
+		Profile: profile,
+		Days:    int(ttl.Hours() / 24),
+		Link:    link.String() + s.opts.Shares.Path(profile, expires),
+		Expires: expires,
+	})
+}
+
+func (s *Server) handleShared(w http.ResponseWriter, r *http.Request) {
+	profile := r.PathValue("profile")
+	q := r.URL.Query()
+	err := s.opts.Shares.Verify(profile, q.Get("exp"), q.Get("sig"), s.opts.Now())
+	switch {
+	case errors.Is(err, ErrShareExpired):
+		http.Error(w, "This share link has expired.", http.StatusGone)
+		return
+	case err != nil:
+		http.NotFound(w, r)
+		return
+	}
+	// Shared reports aren't for search engines or shared caches
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	// Repo and user pages are behind the dashboard's authentication, so
+	// shared reports keep linking to GitHub
+	s.writeReport(w, r, profile, false)
+}
+
+// hasProfile reports whether the source knows profile.
+func (s *Server) hasProfile(profile string) bool {
+	_, err := s.source.Report(profile)
+	return !errors.Is(err, ErrUnknownProfile)
+}
+
+// render executes one of the dashboard's own page templates.
+func (s *Server) render(w http.ResponseWriter, tmpl *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// profilePath returns the URL path of a profile's report.
+func profilePath(name string) string {
+	return "/p/" + url.PathEscape(name)
+}
+
+// sharePage is the data for the share template.
+type sharePage struct {
+	Expires time.Time
+	Profile string
+	Link    string // The created link; empty on the form
+	Days    int
+}
+
+const pageStyle = `<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 40rem; margin: 3rem auto; padding: 0 1rem; color: #1f2328; }
+ul { list-style: none; padding: 0; }
+li { padding: 0.75rem 0; border-bottom: 1px solid #d0d7de; }
+a { color: #0969da; font-weight: 600; text-decoration: none; }
+.meta { color: #656d76; font-size: 0.875rem; }
+input[type=text] { width: 100%; font-family: ui-monospace, monospace; }
+.heatmap { margin-top: 0.5rem; }
+.heatmap rect { rx: 2px; fill: #ebedf0; }
+.heatmap .level-1 { fill: #9be9a8; }
+.heatmap .level-2 { fill: #40c463; }
+.heatmap .level-3 { fill: #30a14e; }
+.heatmap .level-4 { fill: #216e39; }
+.sparkline rect { fill: #40c463; }
+</style>`
+
+// heatmapCellSize is the pitch of heatmap squares, in pixels.
+const heatmapCellSize = 13
+
+var funcs = template.FuncMap{
+	"profilePath": profilePath,
+	"repoPath":    repoPath,
+	"userPath":    userPath,
+	"style":       func() template.HTML { return pageStyle },
+	"cellPos":     func(i int) int { return i * heatmapCellSize },
+	"heatmapWidth": func() int {
+		return heatmapWeeks * heatmapCellSize
+	},
+	"heatmapHeight": func() int { return 7 * heatmapCellSize },
+}
+
+var indexTemplate = template.Must(template.New("index").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>GitStreams</title>
+{{style}}
+</head>
+<body>
+<h1>GitStreams</h1>
+<ul>
+{{- range .Profiles}}
+<li><a href="{{profilePath .Name}}">{{.Name}}</a>
+<div class="meta">{{.Users}} followed users · {{if .LastSync.IsZero}}not synced yet{{else}}synced {{.LastSync.Format "Jan 2 15:04"}}{{end}} · <a href="{{profilePath .Name}}/history">history</a>
+{{- if $.Sharing}} · <a href="{{profilePath .Name}}/share">share</a>{{end}}</div>
+{{- with .Heatmap}}
+<svg class="heatmap" width="{{heatmapWidth}}" height="{{heatmapHeight}}" role="img" aria-label="{{.Total}} activities in the last {{len .Weeks}} weeks">
+{{- range $w, $week := .Weeks}}{{range $d, $cell := $week}}
+<rect x="{{cellPos $w}}" y="{{cellPos $d}}" width="10" height="10" class="level-{{$cell.Level}}"><title>{{$cell.Count}} activities on {{$cell.Day.Format "Mon Jan 2"}}</title></rect>
+{{- end}}{{end}}
+</svg>
+{{- end}}</li>
+{{- else}}
+<li class="meta">No profiles configured.</li>
+{{- end}}
+</ul>
+{{- if .Syncing}}
+<p class="meta"><a href="/sync">Sync progress</a></p>
+{{- end}}
+</body>
+</html>
+`))
+
+var shareTemplate = template.Must(template.New("share").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>Share {{.Profile}}'s report - GitStreams</title>
+{{style}}
+</head>
+<body>
+<h1>Share {{.Profile}}'s report</h1>
+{{- if .Link}}
+<p>Anyone with this link can read the latest report until {{.Expires.Format "Jan 2, 2006 15:04 MST"}}, without access to the rest of the dashboard:</p>
+<p><input type="text" readonly value="{{.Link}}" onfocus="this.select()"></p>
+{{- else}}
+<p>Create a read-only link to the latest report that stops working after a while.</p>
+<form method="post">
+<label>Expires after <input type="number" name="days" min="1" max="30" value="{{.Days}}"> days</label>
+<button type="submit">Create link</button>
+</form>
+{{- end}}
+<p class="meta"><a href="/">Back to the dashboard</a></p>
+</body>
+</html>
+`))
@@ -0,0 +1,251 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+type fakeSource struct {
+	reports   map[string]*report.Report
+	activity  map[string][]storage.DayCount
+	repos     map[string]*RepoTimeline // Keyed by owner/name, shared by all profiles
+	users     map[string]*UserDetails  // Keyed by username, shared by all profiles
+	snapshots map[string][]time.Time
+	days      map[string]*DayDiff // Keyed by YYYY-MM-DD, shared by all profiles
+	diffs     map[string]*diff.Result
+	profiles  []Profile
+}
+
+func (f *fakeSource) Profiles() []Profile { return f.profiles }
+
+func (f *fakeSource) Report(name string) (*report.Report, error) {
+	rpt, ok := f.reports[name]
+	if !ok {
+		return nil, ErrUnknownProfile
+	}
+	return rpt, nil
+}
+
+func (f *fakeSource) DailyActivity(name string, since time.Time) ([]storage.DayCount, error) {
+	if _, ok := f.reports[name]; !ok {
+		return nil, ErrUnknownProfile
+	}
+	return f.activity[name], nil
+}
+
+func (f *fakeSource) RepoTimeline(name, repo string) (*RepoTimeline, error) {
+	if _, ok := f.reports[name]; !ok {
+		return nil, ErrUnknownProfile
+	}
+	return f.repos[repo], nil
+}
+
+func (f *fakeSource) User(ctx context.Context, name, username string, since time.Time) (*UserDetails, error) {
+	if _, ok := f.reports[name]; !ok {
+		return nil, ErrUnknownProfile
+	}
+	return f.users[username], nil
+}
+
+func (f *fakeSource) SetUserPrefs(name string, prefs storage.UserPrefs) error {
+	if _, ok := f.reports[name]; !ok {
+		return ErrUnknownProfile
+	}
+	if f.users[prefs.Username] == nil {
+		f.users[prefs.Username] = &UserDetails{}
+	}
+	f.users[prefs.Username].Prefs = prefs
+	return nil
+}
+
+func (f *fakeSource) Snapshots(name string, since time.Time) ([]time.Time, error) {
+	if _, ok := f.reports[name]; !ok {
+		return nil, ErrUnknownProfile
+	}
+	return f.snapshots[name], nil
+}
+
+func (f *fakeSource) Day(name string, day time.Time) (*DayDiff, error) {
+	if _, ok := f.reports[name]; !ok {
+		return nil, ErrUnknownProfile
+	}
+	return f.days[day.Format(time.DateOnly)], nil
+}
+
+func (f *fakeSource) Diff(name string) (*diff.Result, error) {
+	if _, ok := f.reports[name]; !ok {
+		return nil, ErrUnknownProfile
+	}
+	return f.diffs[name], nil
+}
+
+type fakeGenerator struct{}
+
+func (fakeGenerator) Generate(w io.Writer, r *report.Report) error {
+	_, err := io.WriteString(w, "report for "+r.UserActivities[0].User)
+	return err
+}
+
+type failingGenerator struct{}
+
+func (failingGenerator) Generate(w io.Writer, r *report.Report) error {
+	return errors.New("boom")
+}
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestServer(t *testing.T) {
+	source := &fakeSource{
+		profiles: []Profile{
+			{Name: "alice", Users: 12, LastSync: time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)},
+			{Name: "bob"},
+		},
+		reports: map[string]*report.Report{
+			"alice": {UserActivities: []report.UserActivity{{User: "rsc"}}},
+			"bob":   nil,
+		},
+	}
+	srv := New(source, fakeGenerator{}, Options{})
+
+	tests := []struct {
+		path     string
+		wantBody string
+		wantCode int
+	}{
+		{"/", `href="/p/alice"`, http.StatusOK},
+		{"/", "not synced yet", http.StatusOK},
+		{"/p/alice", "report for rsc", http.StatusOK},
+		{"/p/bob", "hasn't synced yet", http.StatusServiceUnavailable},
+		{"/p/carol", "404", http.StatusNotFound},
+		{"/elsewhere", "404", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := get(t, srv, tt.path)
+		if rec.Code != tt.wantCode {
+			t.Errorf("GET %s: status %d, want %d", tt.path, rec.Code, tt.wantCode)
+		}
+		if !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("GET %s: body %q doesn't contain %q", tt.path, rec.Body.String(), tt.wantBody)
+		}
+	}
+}
+
+func TestServer_SharingDisabled(t *testing.T) {
+	source := &fakeSource{profiles: []Profile{{Name: "me"}}, reports: map[string]*report.Report{"me": nil}}
+	srv := New(source, fakeGenerator{}, Options{})
+	if rec := get(t, srv, "/"); strings.Contains(rec.Body.String(), "/share") {
+		t.Error("index shouldn't offer share links when sharing is off")
+	}
+	if rec := get(t, srv, "/p/me/share"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for the share page, got %d", rec.Code)
+	}
+}
+
+func TestServer_GenerateError(t *testing.T) {
+	source := &fakeSource{
+		profiles: []Profile{{Name: "alice"}},
+		reports:  map[string]*report.Report{"alice": {}},
+	}
+	rec := get(t, New(source, failingGenerator{}, Options{}), "/p/alice")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestServer_Share(t *testing.T) {
+	now := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	clock := now
+	source := &fakeSource{
+		profiles: []Profile{{Name: "alice"}},
+		reports:  map[string]*report.Report{"alice": {UserActivities: []report.UserActivity{{User: "rsc"}}}},
+	}
+	srv := New(source, fakeGenerator{}, Options{
+		Shares: NewShareSigner([]byte("0123456789abcdef0123456789abcdef")),
+		Now:    func() time.Time { return clock },
+	})
+
+	if rec := get(t, srv, "/"); !strings.Contains(rec.Body.String(), `href="/p/alice/share"`) {
+		t.Errorf("expected a share link on the index, got %q", rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/p/alice/share", strings.NewReader("days=2"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("creating share: status %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	start := strings.Index(body, "http://example.com/share/alice?")
+	if start < 0 {
+		t.Fatalf("expected a share link in %q", body)
+	}
+	link := body[start:]
+	link = strings.ReplaceAll(link[:strings.Index(link, `"`)], "&amp;", "&")
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec = get(t, srv, u.RequestURI())
+	if rec.Code != http.StatusOK || rec.Body.String() != "report for rsc" {
+		t.Errorf("shared link: %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Cache-Control") != "private, no-store" {
+		t.Errorf("expected no-store on shared reports, got %q", rec.Header().Get("Cache-Control"))
+	}
+
+	// A link for one profile doesn't open another's report
+	tampered := strings.Replace(u.RequestURI(), "/share/alice", "/share/bob", 1)
+	if rec := get(t, srv, tampered); rec.Code != http.StatusNotFound {
+		t.Errorf("tampered link: expected 404, got %d", rec.Code)
+	}
+
+	clock = now.Add(49 * time.Hour)
+	if rec := get(t, srv, u.RequestURI()); rec.Code != http.StatusGone {
+		t.Errorf("expired link: expected 410, got %d", rec.Code)
+	}
+}
+
+func TestServer_ShareCrossOrigin(t *testing.T) {
+	source := &fakeSource{profiles: []Profile{{Name: "alice"}}, reports: map[string]*report.Report{"alice": nil}}
+	srv := New(source, fakeGenerator{}, Options{Shares: NewShareSigner([]byte("key"))})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/p/alice/share", strings.NewReader("days=2"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://evil.example")
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || strings.Contains(rec.Body.String(), "/share/alice?") {
+		t.Errorf("cross-origin POST /p/alice/share = %d, want 403 without a link:\n%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServer_ShareTooLong(t *testing.T) {
+	source := &fakeSource{profiles: []Profile{{Name: "alice"}}, reports: map[string]*report.Report{"alice": nil}}
+	srv := New(source, fakeGenerator{}, Options{Shares: NewShareSigner([]byte("key"))})
+	for _, days := range []string{"31", "0", "soon"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/p/alice/share", strings.NewReader("days="+days))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		srv.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("days=%s: expected 400, got %d", days, rec.Code)
+		}
+	}
+}
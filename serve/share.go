@@ -0,0 +1,63 @@
+package serve
+
+import (
+	"crypto/hmac"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Share link lifetimes offered on the share page, and the longest accepted.
+const (
+	DefaultShareTTL = 7 * 24 * time.Hour
+	MaxShareTTL     = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrShareExpired is returned for a correctly signed link past its expiry.
+	ErrShareExpired = errors.New("share link expired")
+
+	// ErrShareInvalid is returned for a link that wasn't signed by this server.
+	ErrShareInvalid = errors.New("invalid share link")
+)
+
+// ShareSigner signs links that give read-only access to one profile's
+// report until they expire. Links signed with one key can't be checked with
+// another, so rotating the key revokes every outstanding link.
+type ShareSigner struct {
+	key []byte
+}
+
+// NewShareSigner creates a ShareSigner using key, which should be at least
+// 32 random bytes.
+func NewShareSigner(key []byte) *ShareSigner {
+	return &ShareSigner{key: key}
+}
+
+// Path returns the URL path and query of a link to profile's report that
+// works until expires.
+func (s *ShareSigner) Path(profile string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{"exp": {exp}, "sig": {s.sign(profile, exp)}}
+	return "/share/" + url.PathEscape(profile) + "?" + q.Encode()
+}
+
+// Verify checks a link's expiry and signature as of now.
+func (s *ShareSigner) Verify(profile, exp, sig string, now time.Time) error {
+	if !hmac.Equal([]byte(sig), []byte(s.sign(profile, exp))) {
+		return ErrShareInvalid
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return ErrShareInvalid
+	}
+	if !now.Before(time.Unix(unix, 0)) {
+		return ErrShareExpired
+	}
+	return nil
+}
+
+func (s *ShareSigner) sign(profile, exp string) string {
+	return mac(s.key, profile+"\n"+exp)
+}
@@ -0,0 +1,41 @@
+package serve
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareSigner(t *testing.T) {
+	signer := NewShareSigner([]byte("0123456789abcdef0123456789abcdef"))
+	now := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	path := signer.Path("alice", now.Add(time.Hour))
+
+	u, err := url.Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u.Path, "/share/alice") {
+		t.Errorf("unexpected path %q", path)
+	}
+	exp, sig := u.Query().Get("exp"), u.Query().Get("sig")
+
+	if err := signer.Verify("alice", exp, sig, now); err != nil {
+		t.Errorf("Verify() = %v for a fresh link", err)
+	}
+	if err := signer.Verify("alice", exp, sig, now.Add(time.Hour)); !errors.Is(err, ErrShareExpired) {
+		t.Errorf("Verify() = %v at expiry, want ErrShareExpired", err)
+	}
+	if err := signer.Verify("bob", exp, sig, now); !errors.Is(err, ErrShareInvalid) {
+		t.Errorf("Verify() = %v for another profile, want ErrShareInvalid", err)
+	}
+	if err := signer.Verify("alice", "99999999999", sig, now); !errors.Is(err, ErrShareInvalid) {
+		t.Errorf("Verify() = %v for an extended expiry, want ErrShareInvalid", err)
+	}
+	other := NewShareSigner([]byte("another key, another server......"))
+	if err := other.Verify("alice", exp, sig, now); !errors.Is(err, ErrShareInvalid) {
+		t.Errorf("Verify() = %v with another key, want ErrShareInvalid", err)
+	}
+}
@@ -0,0 +1,138 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/progress"
+)
+
+// Syncer runs syncs on demand and reports their progress, for the sync
+// page.
+type Syncer interface {
+	// SyncNow asks for a sync to start, reporting false if one is already
+	// running or about to.
+	SyncNow() bool
+
+	// SyncProgress subscribes to sync progress: first the running or last
+	// sync's events so far, then those to come. The channel is closed once
+	// cancel is called, or if the subscriber falls behind.
+	SyncProgress() (events <-chan progress.Event, cancel func())
+}
+
+// syncKeepAlive is how often an idle progress stream sends a comment, so
+// proxies don't time it out between syncs.
+const syncKeepAlive = 30 * time.Second
+
+func (s *Server) handleSyncPage(w http.ResponseWriter, r *http.Request) {
+	s.render(w, syncTemplate, struct{ Started bool }{r.URL.Query().Has("started")})
+}
+
+// handleSyncNow starts a sync, unless one is running, and shows its
+// progress.
+func (s *Server) handleSyncNow(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin request refused", http.StatusForbidden)
+		return
+	}
+	target := "/sync"
+	if s.opts.Sync.SyncNow() {
+		target += "?started"
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// handleSyncEvents streams sync progress as server-sent events, named for
+// their type, with the event as JSON data. A browser that's dropped for
+// falling behind reconnects and is sent the sync so far again.
+func (s *Server) handleSyncEvents(w http.ResponseWriter, r *http.Request) {
+	events, cancel := s.opts.Sync.SyncProgress()
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(syncKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				return
+			}
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+var syncTemplate = template.Must(template.New("sync").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>Sync - GitStreams</title>
+{{style}}
+</head>
+<body>
+<h1>Sync</h1>
+{{- if .Started}}
+<p class="meta">Sync started.</p>
+{{- end}}
+<p id="status">Waiting for a sync to start.</p>
+<progress id="bar" max="1" value="0" hidden></progress>
+<ul id="warnings"></ul>
+<form method="post">
+<button type="submit">Sync now</button>
+</form>
+<p class="meta"><a href="/">Back to the dashboard</a></p>
+<script>
+const statusLine = document.getElementById("status");
+const bar = document.getElementById("bar");
+const warnings = document.getElementById("warnings");
+const events = new EventSource("/api/sync/events");
+const on = (type, handle) => events.addEventListener(type, e => handle(JSON.parse(e.data)));
+on("start", e => {
+  statusLine.textContent = e.message;
+  bar.hidden = true;
+  warnings.replaceChildren();
+});
+on("progress", e => {
+  statusLine.textContent = e.message;
+  if (e.total) {
+    bar.max = e.total;
+    bar.value = e.current || 0;
+    bar.hidden = false;
+  }
+});
+on("warning", e => {
+  const li = document.createElement("li");
+  li.className = "meta";
+  li.textContent = "Warning: " + e.message;
+  warnings.append(li);
+});
+on("done", e => {
+  statusLine.textContent = e.message;
+  bar.hidden = true;
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,118 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/progress"
+)
+
+type fakeSyncer struct {
+	events    []progress.Event
+	busy      bool
+	requested int
+}
+
+func (f *fakeSyncer) SyncNow() bool {
+	f.requested++
+	return !f.busy
+}
+
+// SyncProgress returns f's events on a closed channel, so a stream of them
+// ends once they're sent.
+func (f *fakeSyncer) SyncProgress() (<-chan progress.Event, func()) {
+	ch := make(chan progress.Event, len(f.events))
+	for _, e := range f.events {
+		ch <- e
+	}
+	close(ch)
+	return ch, func() {}
+}
+
+func TestServer_SyncEvents(t *testing.T) {
+	syncer := &fakeSyncer{events: []progress.Event{
+		{Type: progress.EventStart, Message: "Fetching follow lists for 1 profiles..."},
+		{Type: progress.EventProgress, Message: "Fetching activity for user 1/2: alice...", Item: "alice", Current: 1, Total: 2},
+		{Type: progress.EventWarning, Message: "could not fetch events for bob"},
+	}}
+	srv := New(&fakeSource{}, fakeGenerator{}, Options{Sync: syncer})
+
+	rec := get(t, srv, "/api/sync/events")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"event: start\ndata: {",
+		`event: progress` + "\n" + `data: {"time":"0001-01-01T00:00:00Z","type":"progress","message":"Fetching activity for user 1/2: alice...","item":"alice","current":1,"total":2}` + "\n\n",
+		"event: warning\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stream missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestServer_SyncNow(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		busy bool
+		want string
+	}{
+		{"starts a sync", false, "/sync?started"},
+		{"already syncing", true, "/sync"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			syncer := &fakeSyncer{busy: tt.busy}
+			srv := New(&fakeSource{}, fakeGenerator{}, Options{Sync: syncer})
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sync", nil))
+			if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != tt.want {
+				t.Errorf("POST /sync = %d to %q, want 303 to %q", rec.Code, rec.Header().Get("Location"), tt.want)
+			}
+			if syncer.requested != 1 {
+				t.Errorf("SyncNow called %d times, want 1", syncer.requested)
+			}
+		})
+	}
+}
+
+func TestServer_SyncNowCrossOrigin(t *testing.T) {
+	syncer := &fakeSyncer{}
+	srv := New(&fakeSource{}, fakeGenerator{}, Options{Sync: syncer})
+	req := httptest.NewRequest(http.MethodPost, "/sync", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || syncer.requested != 0 {
+		t.Errorf("cross-origin POST /sync = %d with %d syncs, want 403 and none", rec.Code, syncer.requested)
+	}
+}
+
+func TestServer_SyncPage(t *testing.T) {
+	srv := New(&fakeSource{}, fakeGenerator{}, Options{Sync: &fakeSyncer{}})
+	rec := get(t, srv, "/sync")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `new EventSource("/api/sync/events")`) {
+		t.Errorf("GET /sync = %d, want the page streaming progress:\n%s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(get(t, srv, "/").Body.String(), `href="/sync"`) {
+		t.Error("expected the index to link to the sync page")
+	}
+}
+
+func TestServer_SyncDisabled(t *testing.T) {
+	srv := New(&fakeSource{}, fakeGenerator{}, Options{})
+	for _, path := range []string{"/sync", "/api/sync/events"} {
+		if rec := get(t, srv, path); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s without a Syncer = %d, want 404", path, rec.Code)
+		}
+	}
+	if strings.Contains(get(t, srv, "/").Body.String(), `href="/sync"`) {
+		t.Error("expected no sync link without a Syncer")
+	}
+}
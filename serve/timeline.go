@@ -0,0 +1,200 @@
+package serve
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// StarCount is a repo's star count as of one snapshot.
+type StarCount struct {
+	At    time.Time
+	Stars int
+}
+
+// RepoTimeline is a repo's history as seen across a profile's snapshots.
+type RepoTimeline struct {
+	Repo       string             // owner/name
+	Stars      []StarCount        // Oldest first, only where the count changed
+	Activities []storage.Activity // Newest first
+}
+
+// CurrentStars returns the repo's latest known star count.
+func (t *RepoTimeline) CurrentStars() int {
+	if len(t.Stars) == 0 {
+		return 0
+	}
+	return t.Stars[len(t.Stars)-1].Stars
+}
+
+// StarsGained returns how many stars the repo gained over the timeline.
+func (t *RepoTimeline) StarsGained() int {
+	if len(t.Stars) == 0 {
+		return 0
+	}
+	return t.CurrentStars() - t.Stars[0].Stars
+}
+
+// Users returns who in the network touched the repo, sorted.
+func (t *RepoTimeline) Users() []string {
+	seen := make(map[string]bool)
+	var users []string
+	for _, a := range t.Activities {
+		if !seen[a.Username] {
+			seen[a.Username] = true
+			users = append(users, a.Username)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// Releases returns the repo's release events, newest first.
+func (t *RepoTimeline) Releases() []storage.Activity {
+	var releases []storage.Activity
+	for _, a := range t.Activities {
+		if a.EventType == "ReleaseEvent" {
+			releases = append(releases, a)
+		}
+	}
+	return releases
+}
+
+func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request) {
+	profile := r.PathValue("profile")
+	repo := r.PathValue("owner") + "/" + r.PathValue("name")
+	timeline, err := s.source.RepoTimeline(profile, repo)
+	switch {
+	case errors.Is(err, ErrUnknownProfile):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	case timeline == nil:
+		http.Error(w, "Nothing in this profile's history touches "+repo+".", http.StatusNotFound)
+		return
+	}
+	s.render(w, timelineTemplate, struct {
+		*RepoTimeline
+		Profile string
+	}{timeline, profile})
+}
+
+// repoPath returns the URL path of a repo's timeline in a profile.
+func repoPath(profile, repo string) string {
+	owner, name, _ := strings.Cut(repo, "/")
+	return profilePath(profile) + "/repo/" + url.PathEscape(owner) + "/" + url.PathEscape(name)
+}
+
+// linkDashboard returns a copy of rpt whose repo and user links go to their
+// pages in profile instead of GitHub.
+func linkDashboard(rpt *report.Report, profile string) *report.Report {
+	linked := *rpt
+	linked.UserActivities = slices.Clone(rpt.UserActivities)
+	for i := range linked.UserActivities {
+		ua := &linked.UserActivities[i]
+		ua.UserURL = userPath(profile, ua.User)
+		ua.Activities = slices.Clone(ua.Activities)
+		for j := range ua.Activities {
+			a := &ua.Activities[j]
+			a.UserURL = userPath(profile, a.User)
+			if strings.Contains(a.RepoName, "/") {
+				a.RepoURL = repoPath(profile, a.RepoName)
+				a.CompareURL = ""
+			}
+		}
+	}
+	return &linked
+}
+
+// activityVerb describes what a stored activity did to its repo.
+func activityVerb(a storage.Activity) string {
+	switch a.Kind {
+	case storage.KindStar:
+		return "starred"
+	case storage.KindRepo:
+		return "created"
+	}
+	switch a.EventType {
+	case "PushEvent":
+		return "pushed to"
+	case "ReleaseEvent":
+		return "published a release of"
+	case "PullRequestEvent":
+		return "worked on a pull request in"
+	case "PullRequestReviewEvent", "PullRequestReviewCommentEvent":
+		return "reviewed a pull request in"
+	case "IssuesEvent":
+		return "worked on an issue in"
+	case "IssueCommentEvent", "CommitCommentEvent":
+		return "commented in"
+	case "ForkEvent":
+		return "forked"
+	case "WatchEvent":
+		return "starred"
+	case "CreateEvent":
+		return "created a branch or tag in"
+	case "MemberEvent":
+		return "added a collaborator to"
+	case "GollumEvent":
+		return "edited the wiki of"
+	case "DiscussionEvent":
+		return "started a discussion in"
+	case "DiscussionCommentEvent":
+		return "joined a discussion in"
+	default:
+		return "had " + a.EventType + " in"
+	}
+}
+
+var timelineTemplate = template.Must(template.New("timeline").Funcs(funcs).Funcs(template.FuncMap{
+	"verb": activityVerb,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>{{.Repo}} - GitStreams</title>
+{{style}}
+</head>
+<body>
+<h1><a href="https://github.com/{{.Repo}}">{{.Repo}}</a></h1>
+<p class="meta">
+{{- with .Stars}}{{$.CurrentStars}} stars{{if $.StarsGained}}, {{printf "%+d" $.StarsGained}} since {{(index . 0).At.Format "Jan 2"}}{{end}} · {{end -}}
+touched by {{range $i, $u := .Users}}{{if $i}}, {{end}}<a href="{{userPath $.Profile $u}}">{{$u}}</a>{{else}}no one you follow{{end}}</p>
+{{- with .Releases}}
+<h2>Releases</h2>
+<ul>
+{{- range .}}
+<li><a href="{{userPath $.Profile .Username}}">{{.Username}}</a> published a release <span class="meta">{{.OccurredAt.Format "Jan 2, 2006"}}</span></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- with .Stars}}
+<h2>Stars</h2>
+<ul>
+{{- range .}}
+<li>{{.Stars}} stars <span class="meta">as of {{.At.Format "Jan 2, 2006 15:04"}}</span></li>
+{{- end}}
+</ul>
+{{- end}}
+<h2>Timeline</h2>
+<ul>
+{{- range .Activities}}
+<li><a href="{{userPath $.Profile .Username}}">{{.Username}}</a> {{verb .}} {{$.Repo}} <span class="meta">{{.OccurredAt.Format "Jan 2, 2006 15:04"}}</span></li>
+{{- else}}
+<li class="meta">No activity from people you follow.</li>
+{{- end}}
+</ul>
+<p class="meta"><a href="{{profilePath .Profile}}">Back to {{.Profile}}'s report</a></p>
+</body>
+</html>
+`))
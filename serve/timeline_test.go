@@ -0,0 +1,81 @@
+package serve
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestServer_RepoTimeline(t *testing.T) {
+	at := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	source := &fakeSource{
+		profiles: []Profile{{Name: "alice"}},
+		reports:  map[string]*report.Report{"alice": nil},
+		repos: map[string]*RepoTimeline{"golang/go": {
+			Repo:  "golang/go",
+			Stars: []StarCount{{At: at.AddDate(0, 0, -7), Stars: 100}, {At: at, Stars: 112}},
+			Activities: []storage.Activity{
+				{Username: "rsc", Kind: storage.KindEvent, EventType: "ReleaseEvent", OccurredAt: at},
+				{Username: "bradfitz", Kind: storage.KindStar, OccurredAt: at.AddDate(0, 0, -1)},
+				{Username: "rsc", Kind: storage.KindEvent, EventType: "PushEvent", OccurredAt: at.AddDate(0, 0, -2)},
+			},
+		}},
+	}
+	srv := New(source, fakeGenerator{}, Options{})
+
+	rec := get(t, srv, "/p/alice/repo/golang/go")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"112 stars, &#43;12 since Jan 8",
+		`touched by <a href="/p/alice/u/bradfitz">bradfitz</a>, <a href="/p/alice/u/rsc">rsc</a>`,
+		"<h2>Releases</h2>",
+		"</a> published a release of golang/go",
+		"</a> starred golang/go",
+		"</a> pushed to golang/go",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("timeline missing %q", want)
+		}
+	}
+
+	for path, want := range map[string]int{
+		"/p/alice/repo/nobody/nothing": http.StatusNotFound,
+		"/p/dave/repo/golang/go":       http.StatusNotFound,
+	} {
+		if rec := get(t, srv, path); rec.Code != want {
+			t.Errorf("GET %s: status %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestLinkDashboard(t *testing.T) {
+	rpt := &report.Report{UserActivities: []report.UserActivity{{
+		User: "rsc",
+		Activities: []report.Activity{
+			{User: "rsc", RepoName: "golang/go", RepoURL: "https://github.com/golang/go", CompareURL: "https://github.com/golang/go/compare/abc1234...def5678"},
+			{User: "rsc", RepoName: "odd name/x y", RepoURL: "https://github.com/odd name/x y"},
+		},
+	}}}
+	linked := linkDashboard(rpt, "alice")
+
+	got := linked.UserActivities[0].Activities
+	if got[0].RepoURL != "/p/alice/repo/golang/go" || got[1].RepoURL != "/p/alice/repo/odd%20name/x%20y" {
+		t.Errorf("unexpected links %q, %q", got[0].RepoURL, got[1].RepoURL)
+	}
+	if got[0].Link() != got[0].RepoURL {
+		t.Errorf("expected a push to link to its repo's page, got %q", got[0].Link())
+	}
+	if got[0].UserURL != "/p/alice/u/rsc" || linked.UserActivities[0].UserURL != "/p/alice/u/rsc" {
+		t.Errorf("unexpected user links %q, %q", got[0].UserURL, linked.UserActivities[0].UserURL)
+	}
+	if rpt.UserActivities[0].Activities[0].RepoURL != "https://github.com/golang/go" {
+		t.Error("linkRepos changed the source report")
+	}
+}
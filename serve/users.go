@@ -0,0 +1,223 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// UserDetails is what the dashboard shows about a followed user.
+type UserDetails struct {
+	Info   *storage.UserInfo // nil if their GitHub profile couldn't be fetched
+	Repos  []storage.RepoCount
+	Recent []storage.Activity // Newest first
+	Daily  []storage.DayCount // Activity per day since the time asked for
+	Prefs  storage.UserPrefs
+}
+
+// sparklineDays is how many days the activity sparkline on a user's page
+// covers.
+const sparklineDays = 30
+
+// Limits on the tags a user can be given.
+const (
+	maxTags      = 10
+	maxTagLength = 32
+)
+
+// loginPattern matches GitHub usernames.
+var loginPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})$`)
+
+// sparkBar is one day's bar in a sparkline.
+type sparkBar struct {
+	Day    time.Time
+	X      int
+	Y      int
+	Height int
+	Count  int
+}
+
+// Sparkline dimensions, in pixels.
+const (
+	sparkPitch  = 5
+	sparkHeight = 24
+)
+
+// buildSparkline lays out daily counts as bars, one per day from
+// sparklineDays ago up to today, scaled to the busiest day.
+func buildSparkline(counts []storage.DayCount, today time.Time) []sparkBar {
+	byDay := make(map[string]int, len(counts))
+	busiest := 0
+	for _, c := range counts {
+		byDay[c.Day.Format(time.DateOnly)] += c.Count
+		busiest = max(busiest, byDay[c.Day.Format(time.DateOnly)])
+	}
+
+	bars := make([]sparkBar, sparklineDays)
+	day := sparklineStart(today)
+	for i := range bars {
+		n := byDay[day.Format(time.DateOnly)]
+		h := 1 // A sliver for quiet days keeps the baseline visible
+		if n > 0 {
+			h = max(2, n*sparkHeight/busiest)
+		}
+		bars[i] = sparkBar{Day: day, Count: n, X: i * sparkPitch, Y: sparkHeight - h, Height: h}
+		day = day.AddDate(0, 0, 1)
+	}
+	return bars
+}
+
+// sparklineStart returns the first day of the sparkline ending today.
+func sparklineStart(today time.Time) time.Time {
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	return day.AddDate(0, 0, 1-sparklineDays)
+}
+
+// parseTags reads tags from a comma or space separated list, lowercased and
+// without duplicates.
+func parseTags(s string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tags can be at most %d characters", maxTagLength)
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("a user can have at most %d tags", maxTags)
+	}
+	return tags, nil
+}
+
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	profile, username := r.PathValue("profile"), r.PathValue("user")
+	if !loginPattern.MatchString(username) {
+		http.NotFound(w, r)
+		return
+	}
+	today := s.opts.Now()
+	details, err := s.source.User(r.Context(), profile, username, sparklineStart(today))
+	switch {
+	case errors.Is(err, ErrUnknownProfile):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	case details == nil:
+		http.Error(w, "Nothing in this profile's history comes from "+username+".", http.StatusNotFound)
+		return
+	}
+	s.render(w, userTemplate, struct {
+		*UserDetails
+		Profile  string
+		Username string
+		Spark    []sparkBar
+	}{details, profile, username, buildSparkline(details.Daily, today)})
+}
+
+func (s *Server) handleSetUserPrefs(w http.ResponseWriter, r *http.Request) {
+	profile, username := r.PathValue("profile"), r.PathValue("user")
+	if !loginPattern.MatchString(username) {
+		http.NotFound(w, r)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin request refused", http.StatusForbidden)
+		return
+	}
+	tags, err := parseTags(r.FormValue("tags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	prefs := storage.UserPrefs{Username: username, Tags: tags, Muted: r.FormValue("muted") != ""}
+	switch err := s.source.SetUserPrefs(profile, prefs); {
+	case errors.Is(err, ErrUnknownProfile):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, userPath(profile, username), http.StatusSeeOther)
+}
+
+// sameOrigin reports whether a form post came from the dashboard itself,
+// going by the Origin header browsers send with posts. Requests without one
+// aren't from a browser's cross-site form.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// userPath returns the URL path of a followed user's page in a profile.
+func userPath(profile, username string) string {
+	return profilePath(profile) + "/u/" + url.PathEscape(username)
+}
+
+var userTemplate = template.Must(template.New("user").Funcs(funcs).Funcs(template.FuncMap{
+	"verb":        activityVerb,
+	"sparkWidth":  func() int { return sparklineDays * sparkPitch },
+	"sparkHeight": func() int { return sparkHeight },
+	"join":        strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>{{.Username}} - GitStreams</title>
+{{style}}
+</head>
+<body>
+<h1><a href="https://github.com/{{.Username}}">{{with .Info}}{{if .Name}}{{.Name}} ({{.Username}}){{else}}{{.Username}}{{end}}{{else}}{{.Username}}{{end}}</a></h1>
+{{- with .Info}}{{if .Bio}}
+<p>{{.Bio}}</p>
+{{- end}}{{end}}
+<p class="meta">
+{{- if .Prefs.Muted}}Muted · {{end}}
+{{- with .Prefs.Tags}}Tagged {{join . ", "}} · {{end -}}
+{{len .Recent}} recent activities</p>
+<svg class="sparkline" width="{{sparkWidth}}" height="{{sparkHeight}}" role="img" aria-label="Activity over the last 30 days">
+{{- range .Spark}}
+<rect x="{{.X}}" y="{{.Y}}" width="4" height="{{.Height}}"><title>{{.Count}} on {{.Day.Format "Mon Jan 2"}}</title></rect>
+{{- end}}
+</svg>
+<form method="post">
+<label><input type="checkbox" name="muted"{{if .Prefs.Muted}} checked{{end}}> Mute, leaving them out of {{.Profile}}'s report</label><br>
+<label>Tags <input type="text" name="tags" value="{{join .Prefs.Tags ", "}}" placeholder="go, infra"></label>
+<button type="submit">Save</button>
+</form>
+{{- with .Repos}}
+<h2>Repos</h2>
+<ul>
+{{- range .}}
+<li><a href="{{repoPath $.Profile .Repo}}">{{.Repo}}</a> <span class="meta">{{.Count}} activities, last {{.Last.Format "Jan 2"}}</span></li>
+{{- end}}
+</ul>
+{{- end}}
+<h2>Recent activity</h2>
+<ul>
+{{- range .Recent}}
+<li>{{verb .}} <a href="{{repoPath $.Profile .Repo}}">{{.Repo}}</a> <span class="meta">{{.OccurredAt.Format "Jan 2, 2006 15:04"}}</span></li>
+{{- else}}
+<li class="meta">No activity recorded.</li>
+{{- end}}
+</ul>
+<p class="meta"><a href="{{profilePath .Profile}}">Back to {{.Profile}}'s report</a></p>
+</body>
+</html>
+`))
@@ -0,0 +1,148 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestServer_UserPage(t *testing.T) {
+	now := time.Date(2026, 1, 15, 15, 0, 0, 0, time.UTC)
+	source := &fakeSource{
+		profiles: []Profile{{Name: "alice"}},
+		reports:  map[string]*report.Report{"alice": nil},
+		users: map[string]*UserDetails{"rsc": {
+			Info:   &storage.UserInfo{Username: "rsc", Name: "Russ Cox", Bio: "Go and more"},
+			Repos:  []storage.RepoCount{{Repo: "golang/go", Count: 3, Last: now}},
+			Recent: []storage.Activity{{Username: "rsc", Kind: storage.KindEvent, EventType: "PushEvent", Repo: "golang/go", OccurredAt: now}},
+			Daily:  []storage.DayCount{{Day: time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC), Count: 3}},
+			Prefs:  storage.UserPrefs{Username: "rsc", Tags: []string{"go", "compilers"}},
+		}},
+	}
+	srv := New(source, fakeGenerator{}, Options{Now: func() time.Time { return now }})
+
+	rec := get(t, srv, "/p/alice/u/rsc")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"Russ Cox (rsc)",
+		"<p>Go and more</p>",
+		"Tagged go, compilers",
+		`class="sparkline"`,
+		"3 on Wed Jan 14",
+		`<a href="/p/alice/repo/golang/go">golang/go</a> <span class="meta">3 activities`,
+		"pushed to <a",
+		`value="go, compilers"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("user page missing %q", want)
+		}
+	}
+
+	for path, want := range map[string]int{
+		"/p/alice/u/nobody": http.StatusNotFound,
+		"/p/alice/u/-bad-":  http.StatusNotFound,
+		"/p/dave/u/rsc":     http.StatusNotFound,
+	} {
+		if rec := get(t, srv, path); rec.Code != want {
+			t.Errorf("GET %s: status %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestServer_SetUserPrefs(t *testing.T) {
+	source := &fakeSource{
+		profiles: []Profile{{Name: "alice"}},
+		reports:  map[string]*report.Report{"alice": nil},
+		users:    map[string]*UserDetails{},
+	}
+	srv := New(source, fakeGenerator{}, Options{})
+
+	post := func(path string, form url.Values, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("/p/alice/u/rsc", url.Values{"muted": {"on"}, "tags": {"Go, infra go"}}, "http://example.com")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/p/alice/u/rsc" {
+		t.Fatalf("POST: %d to %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+	}
+	prefs := source.users["rsc"].Prefs
+	if !prefs.Muted || !slices.Equal(prefs.Tags, []string{"go", "infra"}) {
+		t.Errorf("unexpected prefs %+v", prefs)
+	}
+
+	if rec := post("/p/alice/u/rsc", url.Values{}, "https://evil.test"); rec.Code != http.StatusForbidden {
+		t.Errorf("cross-origin POST: status %d, want 403", rec.Code)
+	}
+	if !source.users["rsc"].Prefs.Muted {
+		t.Error("a refused POST changed prefs")
+	}
+	if rec := post("/p/alice/u/rsc", url.Values{"tags": {strings.Repeat("x", maxTagLength+1)}}, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("long tag: status %d, want 400", rec.Code)
+	}
+	if rec := post("/p/dave/u/rsc", url.Values{}, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown profile: status %d, want 404", rec.Code)
+	}
+
+	// Unchecking the box unmutes
+	if post("/p/alice/u/rsc", url.Values{"tags": {""}}, ""); source.users["rsc"].Prefs.Muted {
+		t.Error("expected rsc to be unmuted")
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"go", []string{"go"}, false},
+		{" Go,infra ,, go  ml", []string{"go", "infra", "ml"}, false},
+		{"a b c d e f g h i j k", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTags(tt.in)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("parseTags(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestBuildSparkline(t *testing.T) {
+	today := time.Date(2026, 1, 15, 15, 0, 0, 0, time.UTC)
+	bars := buildSparkline([]storage.DayCount{
+		{Day: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Count: 4},
+		{Day: time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC), Count: 1},
+	}, today)
+
+	if len(bars) != sparklineDays {
+		t.Fatalf("expected %d bars, got %d", sparklineDays, len(bars))
+	}
+	last, prev := bars[len(bars)-1], bars[len(bars)-2]
+	if last.Height != sparkHeight || last.Y != 0 || last.Count != 4 {
+		t.Errorf("busiest day should fill the height, got %+v", last)
+	}
+	if prev.Height != sparkHeight/4 || bars[0].Height != 1 {
+		t.Errorf("unexpected heights %d, %d", prev.Height, bars[0].Height)
+	}
+	if !bars[0].Day.Equal(time.Date(2025, 12, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("sparkline starts %v", bars[0].Day)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/config"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/serve"
+)
+
+// Key files kept beside the database. Deleting share.key revokes every
+// share link handed out; deleting session.key signs everyone out.
+const (
+	shareKeyName   = "share.key"
+	sessionKeyName = "session.key"
+)
+
+// serveShutdownTimeout bounds how long in-flight requests get to finish.
+const serveShutdownTimeout = 10 * time.Second
+
+// runServe handles the "serve" subcommand: it syncs on a schedule and serves
+// each viewer profile's latest report over HTTP until interrupted.
+func runServe(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", "localhost:8080", "Address to serve the dashboard on")
+	profilesPath := fs.String("profiles", "", "Path to team profiles JSON file (default: $XDG_CONFIG_HOME/gitstreams/profiles.json, if present)")
+	interval := fs.Duration("interval", time.Hour, "How often to sync")
+	window := fs.Duration("window", 7*24*time.Hour, "How much recent activity each report covers")
+	noShare := fs.Bool("no-share", false, "Disable expiring read-only share links")
+	basicAuth := fs.String("basic-auth", "", "Require this user:password (or $ENV_VAR holding it) to view the dashboard")
+	var oidc serve.OIDCConfig
+	fs.StringVar(&oidc.Issuer, "oidc-issuer", "", "Sign in through this OpenID Connect provider, e.g. https://accounts.google.com")
+	fs.StringVar(&oidc.ClientID, "oidc-client-id", "", "OIDC client ID")
+	fs.StringVar(&oidc.ClientSecret, "oidc-client-secret", "", "OIDC client secret")
+	fs.StringVar(&oidc.RedirectURL, "oidc-redirect-url", "", "This server's OIDC callback URL, e.g. https://gitstreams.example.com/auth/callback")
+	oidcAllow := fs.String("oidc-allow", "", "Comma-separated emails or @domains allowed to sign in (default: anyone the provider authenticates)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if _, err := fs.resolve(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := applySubcommandEnv(fs); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+	if *interval < time.Minute {
+		_, _ = fmt.Fprintf(stderr, "Error: interval must be at least 1m, got %s\n", *interval)
+		return 1
+	}
+
+	profiles, err := loadProfiles(*profilesPath, cfg)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	store, err := openStore(stderr, cfg, deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = store.Close() }()
+
+	generator, err := deps.ReportGenerator(report.FormatHTML)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error creating report generator: %v\n", err)
+		return 1
+	}
+	useClock(generator, deps.Now)
+
+	dataDir := filepath.Dir(dbFile(cfg.DBPath))
+	opts := serve.Options{Now: deps.Now, Logger: deps.Logger}
+	if !*noShare {
+		key, err := loadKey(filepath.Join(dataDir, shareKeyName))
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		opts.Shares = serve.NewShareSigner(key)
+	}
+	if oidc.Issuer != "" {
+		oidc.Allow = splitList(*oidcAllow)
+	}
+	opts.Auth, err = serveAuth(context.Background(), *basicAuth, oidc, filepath.Join(dataDir, sessionKeyName), deps.Now)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if opts.Auth == nil && !isLoopback(*addr) {
+		_, _ = fmt.Fprintf(stderr, "Warning: the dashboard on %s has no authentication; use -basic-auth or -oidc-issuer\n", *addr)
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, p := range profiles {
+		if err := backfillActivities(store, p.snapshotID, deps.Now()); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: could not backfill activity for profile %q: %v\n", p.Name, err)
+		}
+	}
+	t := newTeam(cfg, deps, store, profiles, *window)
+	opts.Health, opts.MaxSyncAge = t, maxSyncAge(*interval, cfg.Timeout)
+	opts.Sync = t
+	srv := &http.Server{
+		Handler:           serve.New(t, generator, opts),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// Progress streams stay open, so end them for Shutdown to finish
+	srv.RegisterOnShutdown(t.events.Close)
+	_, _ = fmt.Fprintf(stdout, "Serving %d profiles on http://%s\n", len(profiles), ln.Addr())
+
+	synced := make(chan struct{})
+	go func() {
+		defer close(synced)
+		t.syncEvery(ctx, *interval, stdout, stderr)
+	}()
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	code := 0
+	select {
+	case <-ctx.Done():
+	case err := <-served:
+		_, _ = fmt.Fprintf(stderr, "Error serving: %v\n", err)
+		code = 1
+		stop()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		_, _ = fmt.Fprintf(stderr, "Warning: %v\n", err)
+	}
+	<-synced
+	return code
+}
+
+// maxSyncAge is how long /healthz lets pass without a successful sync:
+// long enough for one sync to fail and the next to run its course.
+func maxSyncAge(interval, timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return 2*interval + timeout
+}
+
+// loadKey reads the signing key at path, creating a random one on first
+// use.
+func loadKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path) // #nosec G304 -- path is derived from the database location
+	if err == nil && len(key) >= 32 {
+		return key, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading key: %w", err)
+	}
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, fmt.Errorf("saving key: %w", err)
+	}
+	return key, nil
+}
+
+// applySubcommandEnv sets a subcommand's own flags, like serve's, from
+// GITSTREAMS_* environment variables, e.g. GITSTREAMS_BASIC_AUTH. They
+// aren't config file settings: the file is shared with regular runs, which
+// don't have these flags.
+func applySubcommandEnv(fs *flagSet) error {
+	own := make(map[string]bool)
+	given := make(map[string]bool)
+	fs.VisitAll(func(fl *flag.Flag) {
+		if !fs.allowed[fl.Name] && fl.Name != "config" && fl.Name != "version" {
+			own[fl.Name] = true
+		}
+	})
+	fs.Visit(func(fl *flag.Flag) { given[fl.Name] = true })
+	_, err := config.ApplyEnv(fs.FlagSet, own, given, envVarName, os.LookupEnv)
+	return err
+}
+
+// serveAuth returns the dashboard's authenticator: static basic auth, an
+// OIDC provider, or nil for none.
+func serveAuth(ctx context.Context, basicAuth string, oidc serve.OIDCConfig, sessionKeyPath string, now func() time.Time) (serve.Authenticator, error) {
+	switch {
+	case basicAuth != "" && oidc.Issuer != "":
+		return nil, errors.New("use either -basic-auth or -oidc-issuer, not both")
+	case basicAuth != "":
+		creds, err := resolveToken(basicAuth)
+		if err != nil {
+			return nil, fmt.Errorf("basic-auth: %w", err)
+		}
+		user, password, ok := strings.Cut(creds, ":")
+		if !ok || user == "" || password == "" {
+			return nil, errors.New("basic-auth must look like user:password")
+		}
+		return serve.BasicAuth{User: user, Password: password}, nil
+	case oidc.Issuer != "":
+		secret, err := resolveToken(oidc.ClientSecret)
+		if err != nil {
+			return nil, fmt.Errorf("oidc-client-secret: %w", err)
+		}
+		oidc.ClientSecret = secret
+		key, err := loadKey(sessionKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		auth, err := serve.NewOIDC(ctx, oidc, &http.Client{Timeout: 30 * time.Second}, key, now)
+		if err != nil {
+			return nil, err
+		}
+		return auth, nil
+	}
+	return nil, nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// isLoopback reports whether addr only listens on this machine.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/serve"
+)
+
+func TestRunServe_InvalidSettings(t *testing.T) {
+	setHome(t)
+	deps := &Dependencies{StoreFactory: func(path string) (Store, error) { return nil, errors.New("stop") }}
+
+	var stdout, stderr strings.Builder
+	if code := runServe(&stdout, &stderr, []string{"-interval", "10s"}, deps); code != 1 || !strings.Contains(stderr.String(), "at least 1m") {
+		t.Errorf("expected interval error, got %d: %s", code, stderr.String())
+	}
+
+	stderr.Reset()
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	writeFile(t, path, `[{"name": "a"}]`)
+	if code := runServe(&stdout, &stderr, []string{"-profiles", path}, deps); code != 1 || !strings.Contains(stderr.String(), "token or a user") {
+		t.Errorf("expected profiles error, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), shareKeyName)
+	key, err := loadKey(path)
+	if err != nil || len(key) != 32 {
+		t.Fatalf("loadKey() = %d bytes, %v", len(key), err)
+	}
+	again, err := loadKey(path)
+	if err != nil || string(again) != string(key) {
+		t.Errorf("expected the same key on the next load, got %v", err)
+	}
+}
+
+func TestMaxSyncAge(t *testing.T) {
+	if got := maxSyncAge(time.Hour, 5*time.Minute); got != 2*time.Hour+5*time.Minute {
+		t.Errorf("maxSyncAge(1h, 5m) = %v", got)
+	}
+	if got := maxSyncAge(time.Hour, 0); got != 2*time.Hour+defaultTimeout {
+		t.Errorf("maxSyncAge(1h, 0) = %v, want the default timeout allowed", got)
+	}
+}
+
+func TestServeAuth(t *testing.T) {
+	t.Setenv("DASH_AUTH", "team:s3cret")
+	keyPath := filepath.Join(t.TempDir(), sessionKeyName)
+
+	auth, err := serveAuth(context.Background(), "", serve.OIDCConfig{}, keyPath, fixedTime)
+	if err != nil || auth != nil {
+		t.Errorf("no auth configured: got %v, %v", auth, err)
+	}
+
+	auth, err = serveAuth(context.Background(), "$DASH_AUTH", serve.OIDCConfig{}, keyPath, fixedTime)
+	if err != nil {
+		t.Fatalf("serveAuth() error = %v", err)
+	}
+	if basic, ok := auth.(serve.BasicAuth); !ok || basic.User != "team" || basic.Password != "s3cret" {
+		t.Errorf("unexpected authenticator %#v", auth)
+	}
+
+	tests := []struct {
+		basic   string
+		oidc    serve.OIDCConfig
+		wantErr string
+	}{
+		{"team", serve.OIDCConfig{}, "user:password"},
+		{"team:", serve.OIDCConfig{}, "user:password"},
+		{"$UNSET_GITSTREAMS_AUTH", serve.OIDCConfig{}, "not set"},
+		{"team:pw", serve.OIDCConfig{Issuer: "https://id.example.com"}, "not both"},
+		{"", serve.OIDCConfig{Issuer: "https://id.example.com"}, "client ID"},
+	}
+	for _, tt := range tests {
+		if _, err := serveAuth(context.Background(), tt.basic, tt.oidc, keyPath, fixedTime); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("serveAuth(%q, %+v) error = %v, want %q", tt.basic, tt.oidc, err, tt.wantErr)
+		}
+	}
+}
+
+func TestApplySubcommandEnv(t *testing.T) {
+	setHome(t)
+	t.Setenv("GITSTREAMS_BASIC_AUTH", "team:s3cret")
+	t.Setenv("GITSTREAMS_ADDR", ":9000")
+
+	fs := newFlagSet()
+	basicAuth := fs.String("basic-auth", "", "")
+	addr := fs.String("addr", "localhost:8080", "")
+	if err := fs.Parse([]string{"-addr", ":7000"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySubcommandEnv(fs); err != nil {
+		t.Fatalf("applySubcommandEnv() error = %v", err)
+	}
+	if *basicAuth != "team:s3cret" {
+		t.Errorf("basic-auth = %q, want it from the environment", *basicAuth)
+	}
+	if *addr != ":7000" {
+		t.Errorf("addr = %q; the command line should win over the environment", *addr)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"localhost:8080": true,
+		"127.0.0.1:80":   true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"example.com:80": false,
+	} {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
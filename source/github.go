@@ -0,0 +1,391 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GitHubClient defines the GitHub API operations the GitHub source needs.
+type GitHubClient interface {
+	GetFollowedUsers(ctx context.Context) ([]github.User, error)
+	GetFollowedUsersByUsername(ctx context.Context, username string) ([]github.User, error)
+	GetStarredReposByUsername(ctx context.Context, username string) ([]github.Repository, error)
+	GetOwnedReposByUsername(ctx context.Context, username string) ([]github.Repository, error)
+	GetRecentEvents(ctx context.Context, username string) ([]github.Event, error)
+}
+
+// DiscussionsClient is implemented by GitHub clients that can fetch
+// discussions. Without it, FetchActivity leaves them out.
+type DiscussionsClient interface {
+	GetRecentDiscussions(ctx context.Context, username string) ([]github.Discussion, error)
+}
+
+// ProfileClient is implemented by GitHub clients that can fetch a user's
+// profile. Without it, snapshots carry no profiles and no profile changes
+// are reported.
+type ProfileClient interface {
+	GetUser(ctx context.Context, username string) (*github.User, error)
+}
+
+// OrgClient is implemented by GitHub clients that can list an
+// organization's members. Sources with Org set need one.
+type OrgClient interface {
+	GetOrgMembers(ctx context.Context, org string) ([]github.User, error)
+}
+
+// StarsClient is implemented by GitHub clients that can list the
+// authenticated user's own starred repos, which OwnStars needs.
+type StarsClient interface {
+	GetStarredRepos(ctx context.Context) ([]github.Repository, error)
+}
+
+// Planner decides which endpoints are worth calling for each subject. It's
+// how a run that can't afford every endpoint for everyone spends what it can.
+type Planner interface {
+	Plan(subject Subject) Plan
+}
+
+// Plan is what to fetch for one subject. Endpoints it skips keep what
+// Previous had for them, so skipping one doesn't make its activity look
+// like it went away.
+type Plan struct {
+	Skip     map[string]bool   // Endpoints not worth calling this run
+	Previous diff.UserActivity // The subject's activity as last fetched
+}
+
+// Event types discussions are recorded as. The events API has no
+// discussion events, so these borrow the names of GitHub's webhook events.
+const (
+	EventDiscussion        = "DiscussionEvent"
+	EventDiscussionComment = "DiscussionCommentEvent"
+)
+
+// GitHub follows the users a GitHub account follows, fetching the parts of
+// their activity it's set to.
+type GitHub struct {
+	Client     GitHubClient
+	FollowedBy string // List users followed by this account instead of the authenticated user
+	Org        string // List this organization's members instead of followed users, if Client is an OrgClient
+	Starred    bool   // Fetch each user's starred repos
+	Owned      bool   // Fetch each user's owned repos
+	Events     bool   // Fetch each user's recent events
+	// Discussions fetches the discussions each user started or commented
+	// on, if Client is a DiscussionsClient.
+	Discussions bool
+	// Profiles fetches each user's profile, if Client is a ProfileClient.
+	Profiles bool
+	// Following fetches who each user follows, to tell who they start
+	// following.
+	Following bool
+	// Planner, if set, can skip endpoints for some subjects. Nil calls
+	// every enabled endpoint for everyone.
+	Planner Planner
+}
+
+// ListSubjects returns the users the account follows, or the members of
+// Org if it's set.
+func (g *GitHub) ListSubjects(ctx context.Context) ([]Subject, error) {
+	var users []github.User
+	var err error
+	if g.Org != "" {
+		oc, ok := g.Client.(OrgClient)
+		if !ok {
+			return nil, fmt.Errorf("listing members of %s: the client can't list organization members", g.Org)
+		}
+		users, err = oc.GetOrgMembers(ctx, g.Org)
+	} else if g.FollowedBy != "" {
+		users, err = g.Client.GetFollowedUsersByUsername(ctx, g.FollowedBy)
+	} else {
+		users, err = g.Client.GetFollowedUsers(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Subjects(users), nil
+}
+
+// Subjects converts GitHub users to subjects.
+func Subjects(users []github.User) []Subject {
+	subjects := make([]Subject, len(users))
+	for i, u := range users {
+		subjects[i] = Subject{Name: u.Login}
+	}
+	return subjects
+}
+
+// OwnStars returns the repos the authenticated user has starred, or nil if
+// client can't list them. Having starred nothing is an empty list, not nil.
+func OwnStars(ctx context.Context, client GitHubClient) ([]diff.Repo, error) {
+	sc, ok := client.(StarsClient)
+	if !ok {
+		return nil, nil
+	}
+	repos, err := sc.GetStarredRepos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stars := make([]diff.Repo, 0, len(repos))
+	for _, repo := range repos {
+		stars = append(stars, convertRepo(repo))
+	}
+	return stars, nil
+}
+
+// FetchActivity fetches the subject's starred repos, owned repos, events,
+// discussions, profile and following list, as enabled and planned, keeping
+// those from after cutoff: repos created since, and events that happened
+// since.
+func (g *GitHub) FetchActivity(ctx context.Context, subject Subject, cutoff time.Time) (diff.UserActivity, []Failure) {
+	tracer := otel.Tracer()
+	activity := diff.UserActivity{Username: subject.Name}
+	var failures []Failure
+	fetched := 0
+
+	var plan Plan
+	if g.Planner != nil {
+		plan = g.Planner.Plan(subject)
+	}
+
+	// fetch makes one API call in a span of its own, noting if it fails
+	fetch := func(endpoint, spanName string, call func(ctx context.Context) error) {
+		if plan.Skip[endpoint] {
+			keepPrevious(&activity, plan.Previous, endpoint, cutoff)
+			return
+		}
+		ctx, span := tracer.Start(ctx, spanName, trace.WithAttributes(attribute.String("user", subject.Name)))
+		defer span.End()
+		if err := call(ctx); err != nil {
+			failures = append(failures, Failure{Endpoint: endpoint, Err: err})
+			return
+		}
+		fetched++
+	}
+
+	if g.Starred {
+		fetch(EndpointStarred, "getStarredRepos", func(ctx context.Context) error {
+			starred, err := g.Client.GetStarredReposByUsername(ctx, subject.Name)
+			for _, repo := range starred {
+				if !repo.CreatedAt.Before(cutoff) {
+					activity.StarredRepos = append(activity.StarredRepos, convertRepo(repo))
+				}
+			}
+			return err
+		})
+	}
+	if g.Owned {
+		fetch(EndpointOwned, "getOwnedRepos", func(ctx context.Context) error {
+			owned, err := g.Client.GetOwnedReposByUsername(ctx, subject.Name)
+			for _, repo := range owned {
+				if !repo.CreatedAt.Before(cutoff) {
+					activity.OwnedRepos = append(activity.OwnedRepos, convertRepo(repo))
+				}
+			}
+			return err
+		})
+	}
+	if g.Events {
+		fetch(EndpointEvents, "getRecentEvents", func(ctx context.Context) error {
+			events, err := g.Client.GetRecentEvents(ctx, subject.Name)
+			forced := forcePushes(events)
+			for i, event := range events {
+				if !event.CreatedAt.Before(cutoff) {
+					e := convertEvent(event)
+					e.Forced = forced[i]
+					activity.Events = append(activity.Events, e)
+				}
+			}
+			return err
+		})
+	}
+
+	if dc, ok := g.Client.(DiscussionsClient); ok && g.Discussions {
+		fetch(EndpointDiscussions, "getRecentDiscussions", func(ctx context.Context) error {
+			discussions, err := dc.GetRecentDiscussions(ctx, subject.Name)
+			for _, d := range discussions {
+				if !d.CreatedAt.Before(cutoff) {
+					activity.Events = append(activity.Events, convertDiscussion(subject.Name, d))
+				}
+			}
+			return err
+		})
+	}
+
+	if pc, ok := g.Client.(ProfileClient); ok && g.Profiles {
+		fetch(EndpointProfile, "getUser", func(ctx context.Context) error {
+			user, err := pc.GetUser(ctx, subject.Name)
+			if err == nil {
+				activity.Profile = convertProfile(*user)
+			}
+			return err
+		})
+	}
+
+	if g.Following {
+		fetch(EndpointFollowing, "getFollowedUsers", func(ctx context.Context) error {
+			users, err := g.Client.GetFollowedUsersByUsername(ctx, subject.Name)
+			if err == nil {
+				activity.Following = followingLogins(users)
+			}
+			return err
+		})
+	}
+
+	for i := range failures {
+		failures[i].Partial = fetched > 0
+	}
+	return activity, failures
+}
+
+// keepPrevious copies what prev has for endpoint into activity, keeping
+// only what's after cutoff as a fetch would.
+func keepPrevious(activity *diff.UserActivity, prev diff.UserActivity, endpoint string, cutoff time.Time) {
+	keepRepos := func(repos []diff.Repo) []diff.Repo {
+		var kept []diff.Repo
+		for _, repo := range repos {
+			if !repo.CreatedAt.Before(cutoff) {
+				kept = append(kept, repo)
+			}
+		}
+		return kept
+	}
+	switch endpoint {
+	case EndpointStarred:
+		activity.StarredRepos = keepRepos(prev.StarredRepos)
+	case EndpointOwned:
+		activity.OwnedRepos = keepRepos(prev.OwnedRepos)
+	case EndpointEvents, EndpointDiscussions:
+		discussion := endpoint == EndpointDiscussions
+		for _, e := range prev.Events {
+			isDiscussion := e.Type == EventDiscussion || e.Type == EventDiscussionComment
+			if isDiscussion == discussion && !e.CreatedAt.Before(cutoff) {
+				activity.Events = append(activity.Events, e)
+			}
+		}
+	case EndpointProfile:
+		activity.Profile = prev.Profile
+	case EndpointFollowing:
+		activity.Following = prev.Following
+	}
+}
+
+// followingLogins returns the logins of users, sorted.
+func followingLogins(users []github.User) []string {
+	logins := make([]string, len(users))
+	for i, u := range users {
+		logins[i] = u.Login
+	}
+	slices.Sort(logins)
+	return logins
+}
+
+func convertProfile(u github.User) *diff.Profile {
+	return &diff.Profile{Name: u.Name, Bio: u.Bio, AvatarURL: u.AvatarURL, Company: u.Company}
+}
+
+func convertRepo(r github.Repository) diff.Repo {
+	repo := diff.Repo{
+		CreatedAt:   r.CreatedAt,
+		Owner:       r.Owner.Login,
+		Name:        r.Name,
+		Description: r.Description,
+		Language:    r.Language,
+		HTMLURL:     r.HTMLURL,
+		Topics:      r.Topics,
+		Stars:       r.StarCount,
+	}
+	if r.ID != 0 {
+		repo.ID = strconv.FormatInt(r.ID, 10)
+	}
+	return repo
+}
+
+func convertEvent(e github.Event) diff.Event {
+	event := diff.Event{
+		ID:             e.ID,
+		Type:           e.Type,
+		Actor:          e.Actor.Login,
+		ActorAvatarURL: e.Actor.AvatarURL,
+		Repo:           e.Repo.Name,
+		CreatedAt:      e.CreatedAt,
+	}
+	// The payload is best effort: an event without the parts we look for
+	// is still worth keeping
+	var payload eventPayload
+	if len(e.Payload) > 0 && json.Unmarshal(e.Payload, &payload) == nil {
+		event.Member = payload.Member.Login
+		// PushEvent has a ref too, but no ref_type
+		if payload.RefType != "" {
+			event.RefType = payload.RefType
+			event.Ref = payload.Ref
+		}
+		for _, c := range payload.Commits {
+			summary, _, _ := strings.Cut(c.Message, "\n")
+			event.Commits = append(event.Commits, summary)
+		}
+		event.Before, event.Head, event.Size = payload.Before, payload.Head, payload.Size
+		if payload.Release.TagName != "" {
+			event.RefType = "tag"
+			event.Ref = payload.Release.TagName
+		}
+	}
+	return event
+}
+
+// forcePushes reports which of events, newest first as the API lists them,
+// are pushes that don't pick up where the push before them to the same
+// branch left off, so rewrote its history. The oldest push to each branch
+// has nothing to go by, and nor does one that created its branch, so
+// neither is ever forced.
+func forcePushes(events []github.Event) []bool {
+	forced := make([]bool, len(events))
+	heads := make(map[string]string) // By repo and ref
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		var payload eventPayload
+		if e.Type != "PushEvent" || json.Unmarshal(e.Payload, &payload) != nil || payload.Ref == "" || payload.Head == "" {
+			continue
+		}
+		branch := e.Repo.Name + "\x00" + payload.Ref
+		if head, ok := heads[branch]; ok && payload.Before != head && strings.Trim(payload.Before, "0") != "" {
+			forced[i] = true
+		}
+		heads[branch] = payload.Head
+	}
+	return forced
+}
+
+// eventPayload holds the parts of event payloads the GitHub source keeps.
+type eventPayload struct {
+	Member  github.User `json:"member"`   // MemberEvent
+	RefType string      `json:"ref_type"` // CreateEvent, DeleteEvent
+	Ref     string      `json:"ref"`      // CreateEvent, DeleteEvent
+	Release struct {
+		TagName string `json:"tag_name"`
+	} `json:"release"` // ReleaseEvent
+	Commits []struct {
+		Message string `json:"message"`
+	} `json:"commits"` // PushEvent
+	Before string `json:"before"` // PushEvent
+	Head   string `json:"head"`   // PushEvent
+	Size   int    `json:"size"`   // PushEvent
+}
+
+// convertDiscussion records a discussion as an event.
+func convertDiscussion(username string, d github.Discussion) diff.Event {
+	e := diff.Event{Type: EventDiscussion, Actor: username, Repo: d.Repo, CreatedAt: d.CreatedAt}
+	if d.Comment {
+		e.Type = EventDiscussionComment
+	}
+	return e
+}
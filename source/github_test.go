@@ -0,0 +1,541 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+// fakeClient implements GitHubClient with canned results.
+type fakeClient struct {
+	err        map[string]error // by endpoint
+	followedBy map[string][]github.User
+	followed   []github.User
+	starred    []github.Repository
+	owned      []github.Repository
+	events     []github.Event
+}
+
+func (f *fakeClient) GetFollowedUsers(ctx context.Context) ([]github.User, error) {
+	return f.followed, nil
+}
+
+func (f *fakeClient) GetFollowedUsersByUsername(ctx context.Context, username string) ([]github.User, error) {
+	if err := f.err[EndpointFollowing]; err != nil {
+		return nil, err
+	}
+	return f.followedBy[username], nil
+}
+
+func (f *fakeClient) GetStarredReposByUsername(ctx context.Context, username string) ([]github.Repository, error) {
+	if err := f.err[EndpointStarred]; err != nil {
+		return nil, err
+	}
+	return f.starred, nil
+}
+
+func (f *fakeClient) GetOwnedReposByUsername(ctx context.Context, username string) ([]github.Repository, error) {
+	if err := f.err[EndpointOwned]; err != nil {
+		return nil, err
+	}
+	return f.owned, nil
+}
+
+func (f *fakeClient) GetRecentEvents(ctx context.Context, username string) ([]github.Event, error) {
+	if err := f.err[EndpointEvents]; err != nil {
+		return nil, err
+	}
+	return f.events, nil
+}
+
+// orgClient adds organization members to fakeClient.
+type orgClient struct {
+	fakeClient
+	members map[string][]github.User
+}
+
+func (f *orgClient) GetOrgMembers(ctx context.Context, org string) ([]github.User, error) {
+	return f.members[org], nil
+}
+
+// discussionsClient adds discussions to fakeClient.
+type discussionsClient struct {
+	fakeClient
+	discussions []github.Discussion
+}
+
+func (d *discussionsClient) GetRecentDiscussions(ctx context.Context, username string) ([]github.Discussion, error) {
+	if err := d.err[EndpointDiscussions]; err != nil {
+		return nil, err
+	}
+	return d.discussions, nil
+}
+
+func TestGitHubFetchActivity_Discussions(t *testing.T) {
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := &discussionsClient{discussions: []github.Discussion{
+		{Repo: "golang/go", CreatedAt: cutoff.Add(time.Hour)},
+		{Repo: "golang/go", CreatedAt: cutoff.Add(2 * time.Hour), Comment: true},
+		{Repo: "golang/go", CreatedAt: cutoff.Add(-time.Hour)},
+	}}
+
+	activity, failures := (&GitHub{Client: client, Discussions: true}).FetchActivity(context.Background(), Subject{Name: "alice"}, cutoff)
+	if len(failures) != 0 {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+	if len(activity.Events) != 2 {
+		t.Fatalf("expected the 2 discussions since cutoff as events, got %+v", activity.Events)
+	}
+	if activity.Events[0].Type != EventDiscussion || activity.Events[1].Type != EventDiscussionComment || activity.Events[0].Actor != "alice" {
+		t.Errorf("unexpected discussion events: %+v", activity.Events)
+	}
+
+	// Off unless asked for
+	activity, _ = (&GitHub{Client: client}).FetchActivity(context.Background(), Subject{Name: "alice"}, cutoff)
+	if len(activity.Events) != 0 {
+		t.Errorf("expected no discussions when not enabled, got %+v", activity.Events)
+	}
+
+	client.err = map[string]error{EndpointDiscussions: errors.New("boom")}
+	_, failures = (&GitHub{Client: client, Discussions: true}).FetchActivity(context.Background(), Subject{Name: "alice"}, cutoff)
+	if len(failures) != 1 || failures[0].Endpoint != EndpointDiscussions {
+		t.Errorf("expected a discussions failure, got %+v", failures)
+	}
+}
+
+// profileClient adds profiles to fakeClient.
+type profileClient struct {
+	fakeClient
+	user github.User
+}
+
+func (p *profileClient) GetUser(ctx context.Context, username string) (*github.User, error) {
+	if err := p.err[EndpointProfile]; err != nil {
+		return nil, err
+	}
+	return &p.user, nil
+}
+
+func TestGitHubFetchActivity_Profile(t *testing.T) {
+	client := &profileClient{user: github.User{Login: "alice", Name: "Alice", Bio: "Gopher", Company: "Acme", AvatarURL: "https://avatars.example.com/alice"}}
+
+	activity, failures := (&GitHub{Client: client, Profiles: true}).FetchActivity(context.Background(), Subject{Name: "alice"}, time.Time{})
+	if len(failures) != 0 {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+	want := diff.Profile{Name: "Alice", Bio: "Gopher", Company: "Acme", AvatarURL: "https://avatars.example.com/alice"}
+	if activity.Profile == nil || *activity.Profile != want {
+		t.Errorf("Profile = %+v, want %+v", activity.Profile, want)
+	}
+
+	// Off unless asked for
+	activity, _ = (&GitHub{Client: client}).FetchActivity(context.Background(), Subject{Name: "alice"}, time.Time{})
+	if activity.Profile != nil {
+		t.Errorf("expected no profile when not enabled, got %+v", activity.Profile)
+	}
+
+	client.err = map[string]error{EndpointProfile: errors.New("boom")}
+	activity, failures = (&GitHub{Client: client, Profiles: true}).FetchActivity(context.Background(), Subject{Name: "alice"}, time.Time{})
+	if len(failures) != 1 || failures[0].Endpoint != EndpointProfile || activity.Profile != nil {
+		t.Errorf("expected a profile failure, got %+v", failures)
+	}
+}
+
+func TestGitHubFetchActivity_Following(t *testing.T) {
+	client := &fakeClient{followedBy: map[string][]github.User{"alice": {{Login: "dave"}, {Login: "bob"}}}}
+
+	activity, failures := (&GitHub{Client: client, Following: true}).FetchActivity(context.Background(), Subject{Name: "alice"}, time.Time{})
+	if len(failures) != 0 {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+	if want := []string{"bob", "dave"}; !reflect.DeepEqual(activity.Following, want) {
+		t.Errorf("Following = %v, want %v", activity.Following, want)
+	}
+
+	// Off unless asked for
+	activity, _ = (&GitHub{Client: client}).FetchActivity(context.Background(), Subject{Name: "alice"}, time.Time{})
+	if activity.Following != nil {
+		t.Errorf("expected no following list when not enabled, got %v", activity.Following)
+	}
+
+	client.err = map[string]error{EndpointFollowing: errors.New("boom")}
+	activity, failures = (&GitHub{Client: client, Following: true}).FetchActivity(context.Background(), Subject{Name: "alice"}, time.Time{})
+	if len(failures) != 1 || failures[0].Endpoint != EndpointFollowing || activity.Following != nil {
+		t.Errorf("expected a following failure, got %+v", failures)
+	}
+}
+
+func TestGitHubListSubjects(t *testing.T) {
+	client := &fakeClient{
+		followed:   []github.User{{Login: "alice"}, {Login: "bob"}},
+		followedBy: map[string][]github.User{"carol": {{Login: "dave"}}},
+	}
+
+	subjects, err := (&GitHub{Client: client}).ListSubjects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subjects) != 2 || subjects[0].Name != "alice" || subjects[1].Name != "bob" {
+		t.Errorf("expected alice and bob, got %+v", subjects)
+	}
+
+	subjects, err = (&GitHub{Client: client, FollowedBy: "carol"}).ListSubjects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subjects) != 1 || subjects[0].Name != "dave" {
+		t.Errorf("expected carol's follows, got %+v", subjects)
+	}
+
+	if _, err := (&GitHub{Client: client, Org: "acme"}).ListSubjects(context.Background()); err == nil {
+		t.Error("expected an error listing an org's members with a client that can't")
+	}
+	org := &orgClient{fakeClient: *client, members: map[string][]github.User{"acme": {{Login: "erin"}}}}
+	subjects, err = (&GitHub{Client: org, Org: "acme", FollowedBy: "carol"}).ListSubjects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subjects) != 1 || subjects[0].Name != "erin" {
+		t.Errorf("expected acme's members, got %+v", subjects)
+	}
+}
+
+func TestGitHubFetchActivity(t *testing.T) {
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before, after := cutoff.Add(-time.Hour), cutoff.Add(time.Hour)
+	client := &fakeClient{
+		starred: []github.Repository{{Name: "old", CreatedAt: before}, {Name: "new", CreatedAt: after}},
+		owned:   []github.Repository{{Name: "mine", CreatedAt: after}},
+		events:  []github.Event{{Type: "PushEvent", CreatedAt: before}},
+	}
+	src := &GitHub{Client: client, Starred: true, Owned: true, Events: true}
+
+	activity, failures := src.FetchActivity(context.Background(), Subject{Name: "alice"}, cutoff)
+	if len(failures) != 0 {
+		t.Fatalf("expected no failures, got %+v", failures)
+	}
+	if activity.Username != "alice" {
+		t.Errorf("expected username alice, got %q", activity.Username)
+	}
+	if len(activity.StarredRepos) != 1 || activity.StarredRepos[0].Name != "new" {
+		t.Errorf("expected only the repo created after the cutoff, got %+v", activity.StarredRepos)
+	}
+	if len(activity.OwnedRepos) != 1 || len(activity.Events) != 0 {
+		t.Errorf("expected 1 owned repo and no events, got %+v", activity)
+	}
+}
+
+// GraphQL has no push events, only each day's commit contributions to a
+// repo, so a later push the same day shows as the same event with a bigger
+// commit count.
+func TestGitHubFetchActivity_SameDayPushes(t *testing.T) {
+	day := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	sync := func(commits int) *diff.Snapshot {
+		t.Helper()
+		push := github.Event{Type: "PushEvent", Actor: github.User{Login: "alice"}, Repo: github.EventRepo{Name: "alice/tool"}, CreatedAt: day}
+		push.Payload, _ = json.Marshal(map[string]int{"size": commits})
+		src := &GitHub{Client: &fakeClient{events: []github.Event{push}}, Events: true}
+		activity, failures := src.FetchActivity(context.Background(), Subject{Name: "alice"}, day.Add(-time.Hour))
+		if len(failures) != 0 {
+			t.Fatalf("expected no failures, got %+v", failures)
+		}
+		snapshot := diff.NewSnapshot(day)
+		snapshot.Users["alice"] = activity
+		return snapshot
+	}
+	morning, evening := sync(2), sync(5)
+
+	opts := diff.Options{MatchByID: true}
+	first := diff.CompareWithOptions(diff.NewSnapshot(time.Time{}), morning, opts)
+	second := diff.CompareWithOptions(morning, evening, opts)
+	if len(second.NewEvents) != 1 || second.NewEvents[0].Event.Size != 5 {
+		t.Fatalf("second sync's new events = %+v, want the push grown to 5 commits", second.NewEvents)
+	}
+	if slices.Equal(first.Hashes(), second.Hashes()) {
+		t.Error("the grown push hashes the same as the first, so it would be left out as reported")
+	}
+	if again := diff.CompareWithOptions(evening, sync(5), opts); len(again.NewEvents) != 0 {
+		t.Errorf("a sync seeing no new commits reported %+v", again.NewEvents)
+	}
+}
+
+func TestGitHubFetchActivityFailures(t *testing.T) {
+	errStarred := errors.New("rate limited")
+	client := &fakeClient{err: map[string]error{EndpointStarred: errStarred}}
+
+	_, failures := (&GitHub{Client: client, Starred: true, Events: true}).FetchActivity(context.Background(), Subject{Name: "alice"}, time.Time{})
+	if len(failures) != 1 || failures[0].Endpoint != EndpointStarred || !failures[0].Partial || !errors.Is(failures[0].Err, errStarred) {
+		t.Errorf("expected a partial starred failure, got %+v", failures)
+	}
+
+	// With nothing else to fetch, the failure isn't partial
+	_, failures = (&GitHub{Client: client, Starred: true}).FetchActivity(context.Background(), Subject{Name: "alice"}, time.Time{})
+	if len(failures) != 1 || failures[0].Partial {
+		t.Errorf("expected a non-partial failure, got %+v", failures)
+	}
+}
+
+// skipPlanner skips the same endpoints for every subject.
+type skipPlanner struct {
+	skip     map[string]bool
+	previous diff.UserActivity
+}
+
+func (p skipPlanner) Plan(subject Subject) Plan {
+	return Plan{Skip: p.skip, Previous: p.previous}
+}
+
+func TestGitHubFetchActivity_Planner(t *testing.T) {
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before, after := cutoff.Add(-time.Hour), cutoff.Add(time.Hour)
+	client := &fakeClient{
+		starred: []github.Repository{{Name: "fresh", CreatedAt: after}},
+		owned:   []github.Repository{{Name: "fresh", CreatedAt: after}},
+		events:  []github.Event{{Type: "PushEvent", CreatedAt: after}},
+	}
+	planner := skipPlanner{
+		skip: map[string]bool{EndpointStarred: true},
+		previous: diff.UserActivity{
+			StarredRepos: []diff.Repo{{Name: "kept", CreatedAt: after}, {Name: "stale", CreatedAt: before}},
+			OwnedRepos:   []diff.Repo{{Name: "replaced", CreatedAt: after}},
+		},
+	}
+	src := &GitHub{Client: client, Starred: true, Owned: true, Events: true, Planner: planner}
+
+	activity, failures := src.FetchActivity(context.Background(), Subject{Name: "alice"}, cutoff)
+	if len(failures) != 0 {
+		t.Fatalf("expected no failures, got %+v", failures)
+	}
+	// The skipped listing comes from before, still cut off
+	if len(activity.StarredRepos) != 1 || activity.StarredRepos[0].Name != "kept" {
+		t.Errorf("StarredRepos = %+v, want the previous one after the cutoff", activity.StarredRepos)
+	}
+	if len(activity.OwnedRepos) != 1 || activity.OwnedRepos[0].Name != "fresh" {
+		t.Errorf("OwnedRepos = %+v, want the fetched one", activity.OwnedRepos)
+	}
+	if len(activity.Events) != 1 {
+		t.Errorf("Events = %+v, want the fetched one", activity.Events)
+	}
+}
+
+// starsClient adds the authenticated user's own stars to fakeClient.
+type starsClient struct {
+	fakeClient
+	own []github.Repository
+}
+
+func (f *starsClient) GetStarredRepos(ctx context.Context) ([]github.Repository, error) {
+	return f.own, nil
+}
+
+func TestOwnStars(t *testing.T) {
+	ctx := context.Background()
+	if stars, err := OwnStars(ctx, &fakeClient{}); err != nil || stars != nil {
+		t.Errorf("OwnStars() with a client that can't list them = %v, %v; want nil", stars, err)
+	}
+	if stars, err := OwnStars(ctx, &starsClient{}); err != nil || stars == nil || len(stars) != 0 {
+		t.Errorf("OwnStars() having starred nothing = %#v, %v; want an empty list", stars, err)
+	}
+	stars, err := OwnStars(ctx, &starsClient{own: []github.Repository{{ID: 7, Name: "go", Owner: github.User{Login: "golang"}}}})
+	if err != nil || len(stars) != 1 || stars[0].FullName() != "golang/go" || stars[0].ID != "7" {
+		t.Errorf("OwnStars() = %+v, %v; want golang/go", stars, err)
+	}
+}
+
+func TestConvertRepo(t *testing.T) {
+	ghRepo := github.Repository{
+		ID:          42,
+		Name:        "test-repo",
+		Description: "A test repository",
+		Language:    "Go",
+		StarCount:   100,
+		HTMLURL:     "https://github.com/owner/test-repo",
+		Owner:       github.User{Login: "owner"},
+		Topics:      []string{"cli", "golang"},
+	}
+
+	diffRepo := convertRepo(ghRepo)
+
+	if diffRepo.Name != "test-repo" {
+		t.Errorf("expected name 'test-repo', got: %s", diffRepo.Name)
+	}
+	if diffRepo.Owner != "owner" {
+		t.Errorf("expected owner 'owner', got: %s", diffRepo.Owner)
+	}
+	if diffRepo.Description != "A test repository" {
+		t.Errorf("expected description, got: %s", diffRepo.Description)
+	}
+	if diffRepo.Language != "Go" {
+		t.Errorf("expected language 'Go', got: %s", diffRepo.Language)
+	}
+	if diffRepo.Stars != 100 {
+		t.Errorf("expected 100 stars, got: %d", diffRepo.Stars)
+	}
+	if diffRepo.HTMLURL != "https://github.com/owner/test-repo" {
+		t.Errorf("expected the repo's web page, got: %s", diffRepo.HTMLURL)
+	}
+	if diffRepo.ID != "42" {
+		t.Errorf("expected ID '42', got: %s", diffRepo.ID)
+	}
+	if !slices.Equal(diffRepo.Topics, []string{"cli", "golang"}) {
+		t.Errorf("expected the repo's topics, got: %v", diffRepo.Topics)
+	}
+}
+
+func TestConvertEvent(t *testing.T) {
+	eventTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	ghEvent := github.Event{
+		ID:        "123",
+		Type:      "PushEvent",
+		Actor:     github.User{Login: "actor", AvatarURL: "https://avatars.githubusercontent.com/u/1?"},
+		Repo:      github.EventRepo{Name: "owner/repo"},
+		CreatedAt: eventTime,
+	}
+
+	diffEvent := convertEvent(ghEvent)
+
+	if diffEvent.Type != "PushEvent" {
+		t.Errorf("expected type 'PushEvent', got: %s", diffEvent.Type)
+	}
+	if diffEvent.Actor != "actor" {
+		t.Errorf("expected actor 'actor', got: %s", diffEvent.Actor)
+	}
+	if diffEvent.ActorAvatarURL != "https://avatars.githubusercontent.com/u/1?" {
+		t.Errorf("expected the actor's avatar, got: %s", diffEvent.ActorAvatarURL)
+	}
+	if diffEvent.Repo != "owner/repo" {
+		t.Errorf("expected repo 'owner/repo', got: %s", diffEvent.Repo)
+	}
+	if !diffEvent.CreatedAt.Equal(eventTime) {
+		t.Errorf("expected time %v, got: %v", eventTime, diffEvent.CreatedAt)
+	}
+	if diffEvent.ID != "123" {
+		t.Errorf("expected ID '123', got: %s", diffEvent.ID)
+	}
+}
+
+func TestConvertEvent_Payload(t *testing.T) {
+	member := convertEvent(github.Event{
+		Type:    "MemberEvent",
+		Actor:   github.User{Login: "alice"},
+		Repo:    github.EventRepo{Name: "alice/repo"},
+		Payload: []byte(`{"action": "added", "member": {"login": "bob"}}`),
+	})
+	if member.Member != "bob" {
+		t.Errorf("expected member bob, got %q", member.Member)
+	}
+
+	deleted := convertEvent(github.Event{
+		Type:    "DeleteEvent",
+		Repo:    github.EventRepo{Name: "alice/repo"},
+		Payload: []byte(`{"ref": "feature-x", "ref_type": "branch", "pusher_type": "user"}`),
+	})
+	if deleted.RefType != "branch" || deleted.Ref != "feature-x" {
+		t.Errorf("expected branch feature-x, got %q %q", deleted.RefType, deleted.Ref)
+	}
+
+	release := convertEvent(github.Event{
+		Type:    "ReleaseEvent",
+		Repo:    github.EventRepo{Name: "alice/repo"},
+		Payload: []byte(`{"action": "published", "release": {"tag_name": "v2.0", "name": "Two"}}`),
+	})
+	if release.RefType != "tag" || release.Ref != "v2.0" {
+		t.Errorf("expected tag v2.0, got %q %q", release.RefType, release.Ref)
+	}
+
+	push := convertEvent(github.Event{
+		Type:    "PushEvent",
+		Repo:    github.EventRepo{Name: "alice/repo"},
+		Payload: []byte(`{"ref": "refs/heads/main", "before": "1a2b3c4", "head": "5d6e7f8", "commits": [{"message": "Add a parser\n\nWith tests"}, {"message": "Fix the parser"}]}`),
+	})
+	if !slices.Equal(push.Commits, []string{"Add a parser", "Fix the parser"}) || push.Ref != "" {
+		t.Errorf("expected the commit summaries and no ref, got %q %q", push.Commits, push.Ref)
+	}
+	if push.Before != "1a2b3c4" || push.Head != "5d6e7f8" {
+		t.Errorf("expected the pushed range, got %q...%q", push.Before, push.Head)
+	}
+
+	// A payload that doesn't parse still leaves the event
+	broken := convertEvent(github.Event{Type: "MemberEvent", Repo: github.EventRepo{Name: "alice/repo"}, Payload: []byte(`not json`)})
+	if broken.Member != "" || broken.Repo != "alice/repo" {
+		t.Errorf("expected the event without its payload, got %+v", broken)
+	}
+}
+
+// FuzzConvertEvent feeds convertEvent payloads it can't trust: whatever
+// GitHub sends, the event survives, and survives being stored.
+func FuzzConvertEvent(f *testing.F) {
+	f.Add("MemberEvent", []byte(`{"action": "added", "member": {"login": "bob"}}`))
+	f.Add("CreateEvent", []byte(`{"ref": "feature-x", "ref_type": "branch"}`))
+	f.Add("ReleaseEvent", []byte(`{"release": {"tag_name": "v2.0"}}`))
+	f.Add("PushEvent", []byte(`{"ref": "refs/heads/main", "commits": [{"message": "Add a parser\n\nWith tests"}]}`))
+	f.Add("PushEvent", []byte(`{"commits": null, "member": "bob", "release": []}`))
+	f.Add("WatchEvent", []byte(`not json`))
+
+	createdAt := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	f.Fuzz(func(t *testing.T, typ string, payload []byte) {
+		if !utf8.ValidString(typ) {
+			t.Skip("event types come from decoded JSON, so are valid UTF-8")
+		}
+		e := convertEvent(github.Event{
+			ID:        "1",
+			Type:      typ,
+			Actor:     github.User{Login: "alice"},
+			Repo:      github.EventRepo{Name: "alice/repo"},
+			CreatedAt: createdAt,
+			Payload:   payload,
+		})
+		if e.ID != "1" || e.Type != typ || e.Actor != "alice" || e.Repo != "alice/repo" || !e.CreatedAt.Equal(createdAt) {
+			t.Fatalf("payload changed the event's envelope: %+v", e)
+		}
+		for _, c := range e.Commits {
+			if strings.Contains(c, "\n") {
+				t.Errorf("commit summary %q has more than one line", c)
+			}
+		}
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			t.Fatalf("marshaling event: %v", err)
+		}
+		var restored diff.Event
+		if err := json.Unmarshal(data, &restored); err != nil {
+			t.Fatalf("unmarshaling event: %v", err)
+		}
+		if !reflect.DeepEqual(restored, e) {
+			t.Errorf("event changed in storage:\n got %+v\nwant %+v", restored, e)
+		}
+	})
+}
+
+func TestGitHubIsSource(t *testing.T) {
+	var _ Source = (*GitHub)(nil)
+}
+
+func TestForcePushes(t *testing.T) {
+	push := func(repo, ref, before, head string) github.Event {
+		return github.Event{Type: "PushEvent", Repo: github.EventRepo{Name: repo}, Payload: []byte(`{"ref": "` + ref + `", "before": "` + before + `", "head": "` + head + `"}`)}
+	}
+	// Newest first, as the API lists them
+	events := []github.Event{
+		push("a/x", "refs/heads/main", "3333333", "4444444"),      // Rewrote main, which was at 2222222
+		push("a/x", "refs/heads/topic", "9999999", "aaaaaaa"),     // The first push to topic seen
+		push("a/y", "refs/heads/main", "7777777", "8888888"),      // Another repo's main
+		{Type: "WatchEvent", Repo: github.EventRepo{Name: "a/x"}}, // Not a push
+		push("a/x", "refs/heads/main", "1111111", "2222222"),
+		push("a/x", "refs/heads/main", "0000000000000000000000000000000000000000", "1111111"), // Created main
+	}
+	want := []bool{true, false, false, false, false, false}
+	if got := forcePushes(events); !slices.Equal(got, want) {
+		t.Errorf("forcePushes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+// Package source abstracts where followed users' activity comes from, so
+// forges other than GitHub can feed the same diff and report.
+package source
+
+import (
+	"context"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+// Parts of a subject's activity a Failure can name. They're the diff
+// package's Part constants, so failures can be marked on snapshots as is.
+const (
+	EndpointStarred = diff.PartStarred
+	EndpointOwned   = diff.PartOwned
+	EndpointEvents  = diff.PartEvents
+	// EndpointDiscussions is GitHub Discussions, which come through the
+	// GraphQL API rather than the events feed.
+	EndpointDiscussions = diff.PartDiscussions
+	// EndpointProfile is the subject's profile: name, bio and the like.
+	EndpointProfile = diff.PartProfile
+	// EndpointFollowing is who the subject follows in turn.
+	EndpointFollowing = diff.PartFollowing
+)
+
+// Subject is someone whose activity a Source follows.
+type Subject struct {
+	Name string
+}
+
+// Failure is a part of a subject's activity that couldn't be fetched.
+type Failure struct {
+	Err      error
+	Endpoint string // One of the Endpoint constants
+	// Partial is set when the rest of the subject's activity was fetched,
+	// so some of it was returned rather than none.
+	Partial bool
+}
+
+// Source lists who to follow and fetches their activity.
+type Source interface {
+	// ListSubjects returns the subjects to fetch activity for.
+	ListSubjects(ctx context.Context) ([]Subject, error)
+	// FetchActivity returns subject's activity since cutoff. Parts that
+	// can't be fetched are left out and reported as failures rather than
+	// failing the whole subject.
+	FetchActivity(ctx context.Context, subject Subject, cutoff time.Time) (diff.UserActivity, []Failure)
+}
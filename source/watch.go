@@ -0,0 +1,81 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+// WatchClient is implemented by GitHub clients that can fetch a repo's
+// details, releases and events, for repos on a watchlist. WatchRepo skips
+// repos when the client isn't one.
+type WatchClient interface {
+	GetRepository(ctx context.Context, owner, name string) (*github.Repository, error)
+	GetRepoReleases(ctx context.Context, owner, name string) ([]github.Release, error)
+	GetRepoEvents(ctx context.Context, owner, name string) ([]github.Event, error)
+}
+
+// WatchRepo fetches fullName, as owner/name, for a watchlist: its details,
+// with its star count, and its releases and pushes since cutoff. Drafts
+// aren't released yet, so they're left out. ok is false if client can't
+// fetch repos.
+func WatchRepo(ctx context.Context, client GitHubClient, fullName string, cutoff time.Time) (watched diff.WatchedRepo, ok bool, err error) {
+	wc, ok := client.(WatchClient)
+	if !ok {
+		return diff.WatchedRepo{}, false, nil
+	}
+	owner, name, found := strings.Cut(fullName, "/")
+	if !found {
+		return diff.WatchedRepo{}, true, fmt.Errorf("watched repo %q should look like owner/name", fullName)
+	}
+
+	repo, err := wc.GetRepository(ctx, owner, name)
+	if err != nil {
+		return diff.WatchedRepo{}, true, err
+	}
+	watched.Repo = convertRepo(*repo)
+
+	releases, err := wc.GetRepoReleases(ctx, owner, name)
+	if err != nil {
+		return diff.WatchedRepo{}, true, err
+	}
+	for _, r := range releases {
+		if !r.Draft && !r.PublishedAt.Before(cutoff) {
+			watched.Events = append(watched.Events, convertRelease(watched.Repo.FullName(), r))
+		}
+	}
+
+	// Releases come from their own listing, which goes back further than
+	// the events feed, so only pushes are taken from the feed
+	events, err := wc.GetRepoEvents(ctx, owner, name)
+	if err != nil {
+		return diff.WatchedRepo{}, true, err
+	}
+	forced := forcePushes(events)
+	for i, event := range events {
+		if event.Type == "PushEvent" && !event.CreatedAt.Before(cutoff) {
+			e := convertEvent(event)
+			e.Forced = forced[i]
+			watched.Events = append(watched.Events, e)
+		}
+	}
+	return watched, true, nil
+}
+
+// convertRelease records a release of repo as the ReleaseEvent publishing
+// it would be.
+func convertRelease(repo string, r github.Release) diff.Event {
+	return diff.Event{
+		Type:           "ReleaseEvent",
+		Actor:          r.Author.Login,
+		ActorAvatarURL: r.Author.AvatarURL,
+		Repo:           repo,
+		RefType:        "tag",
+		Ref:            r.TagName,
+		CreatedAt:      r.PublishedAt,
+	}
+}
@@ -0,0 +1,83 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+// watchClient adds repos' details, releases and events to fakeClient.
+type watchClient struct {
+	fakeClient
+	repo      github.Repository
+	releases  []github.Release
+	repoEvent []github.Event
+	repoErr   error
+}
+
+func (w *watchClient) GetRepository(ctx context.Context, owner, name string) (*github.Repository, error) {
+	if w.repoErr != nil {
+		return nil, w.repoErr
+	}
+	return &w.repo, nil
+}
+
+func (w *watchClient) GetRepoReleases(ctx context.Context, owner, name string) ([]github.Release, error) {
+	return w.releases, nil
+}
+
+func (w *watchClient) GetRepoEvents(ctx context.Context, owner, name string) ([]github.Event, error) {
+	return w.repoEvent, nil
+}
+
+func TestWatchRepo(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	cutoff := now.AddDate(0, 0, -30)
+
+	if _, ok, err := WatchRepo(ctx, &fakeClient{}, "golang/go", cutoff); ok || err != nil {
+		t.Errorf("WatchRepo() with a client that can't fetch repos = %v, %v; want not ok", ok, err)
+	}
+
+	client := &watchClient{
+		repo: github.Repository{ID: 1, Name: "go", Owner: github.User{Login: "golang"}, StarCount: 120000},
+		releases: []github.Release{
+			{TagName: "go1.27rc1", Draft: true, PublishedAt: now},
+			{TagName: "go1.26", Author: github.User{Login: "gopherbot"}, PublishedAt: now.Add(-time.Hour)},
+			{TagName: "go1.25", PublishedAt: cutoff.Add(-time.Hour)},
+		},
+		repoEvent: []github.Event{
+			{Type: "PushEvent", Actor: github.User{Login: "rsc"}, Repo: github.EventRepo{Name: "golang/go"}, CreatedAt: now, Payload: json.RawMessage(`{"ref":"refs/heads/master","before":"a","head":"b"}`)},
+			{Type: "IssuesEvent", Actor: github.User{Login: "bob"}, Repo: github.EventRepo{Name: "golang/go"}, CreatedAt: now},
+			{Type: "ReleaseEvent", Actor: github.User{Login: "gopherbot"}, Repo: github.EventRepo{Name: "golang/go"}, CreatedAt: now.Add(-time.Hour)},
+		},
+	}
+	watched, ok, err := WatchRepo(ctx, client, "golang/go", cutoff)
+	if !ok || err != nil {
+		t.Fatalf("WatchRepo() = %v, %v", ok, err)
+	}
+	if watched.Repo.FullName() != "golang/go" || watched.Repo.Stars != 120000 {
+		t.Errorf("Repo = %+v, want golang/go with its stars", watched.Repo)
+	}
+	if len(watched.Events) != 2 {
+		t.Fatalf("Events = %+v, want the published release and the push", watched.Events)
+	}
+	if e := watched.Events[0]; e.Type != "ReleaseEvent" || e.Ref != "go1.26" || e.Actor != "gopherbot" || e.Repo != "golang/go" {
+		t.Errorf("first event = %+v, want the go1.26 release", e)
+	}
+	if e := watched.Events[1]; e.Type != "PushEvent" || e.Actor != "rsc" || e.Head != "b" {
+		t.Errorf("second event = %+v, want rsc's push", e)
+	}
+
+	client.repoErr = errors.New("boom")
+	if _, ok, err := WatchRepo(ctx, client, "golang/go", cutoff); !ok || err == nil {
+		t.Errorf("WatchRepo() with a failing fetch = %v, %v; want the error", ok, err)
+	}
+	if _, _, err := WatchRepo(ctx, client, "golang", cutoff); err == nil {
+		t.Error("expected an error for a name without an owner")
+	}
+}
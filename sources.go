@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/progress"
+)
+
+// accountSource is a GitHub account whose follow list feeds the report.
+type accountSource struct {
+	Label string // Shown as a badge on users followed from this account
+	Token string
+}
+
+// sourceList is the -source flag: comma-separated label=token pairs, where
+// the token may name an environment variable as $VAR. The flag can be
+// repeated. Its string form lists only the labels, so tokens don't end up in
+// `config show` or debug bundles.
+type sourceList []accountSource
+
+func (l *sourceList) String() string {
+	if l == nil {
+		return ""
+	}
+	labels, _ := l.Get().([]string)
+	return strings.Join(labels, ",")
+}
+
+// Get returns the labels, and marks this as a list flag for the config file.
+func (l *sourceList) Get() any {
+	labels := make([]string, len(*l))
+	for i, s := range *l {
+		labels[i] = s.Label
+	}
+	return labels
+}
+
+func (l *sourceList) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		label, token, ok := strings.Cut(entry, "=")
+		label = strings.TrimSpace(label)
+		if !ok || label == "" || token == "" {
+			return fmt.Errorf("source %q should look like label=token or label=$ENV_VAR", entry)
+		}
+		token, err := resolveToken(token)
+		if err != nil {
+			return fmt.Errorf("source %q: %w", label, err)
+		}
+		for _, s := range *l {
+			if s.Label == label {
+				return fmt.Errorf("source %q given twice", label)
+			}
+		}
+		*l = append(*l, accountSource{Label: label, Token: token})
+	}
+	return nil
+}
+
+// resolveToken returns token, or if it names an environment variable as
+// $VAR, that variable's value.
+func resolveToken(token string) (string, error) {
+	name, isVar := strings.CutPrefix(token, "$")
+	if !isVar {
+		return token, nil
+	}
+	value := os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("$%s is not set", name)
+	}
+	return value, nil
+}
+
+// fetchSources fetches the follow list of every configured account and merges
+// them into one snapshot. Users followed from several accounts appear once,
+// badged with each account's label. Warnings from every account are
+// returned together, as is how many API requests they made between them.
+// What's skipped to save API budget is kept from previous, which may be
+// nil.
+//
+// The accounts are fetched at once, each with its own client and so its own
+// rate limit to plan against. What each prints is held back and printed in
+// order once they're all done, so their output doesn't interleave. If one
+// fails, the rest are cancelled.
+func fetchSources(ctx context.Context, cfg *Config, deps *Dependencies, previous *diff.Snapshot, now, cutoff time.Time, stdout, stderr io.Writer) (*diff.Snapshot, []FetchWarning, int, error) {
+	fetches := make([]sourceFetch, len(cfg.Sources))
+	for i, src := range cfg.Sources {
+		fetches[i].client = newGitHubClient(cfg, deps, src.Token)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	spinner := progress.NewSpinner(stderr)
+	spinner.Start(fmt.Sprintf("Fetching activity from %d sources...", len(cfg.Sources)))
+	var wg sync.WaitGroup
+	for i := range fetches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f := &fetches[i]
+			plan := defaultFetchPlan()
+			plan.Discussions = cfg.Discussions
+			plan.Following = cfg.Following
+			plan.Concurrency = cfg.Concurrency
+			plan.Previous = previous
+			plan.Quiet = true
+			started := deps.Now()
+			f.snapshot, f.warnings, f.err = fetchActivityWithPlan(ctx, f.client, plan, now, cutoff, &f.stdout, &f.stderr, cfg.Verbose)
+			f.took = deps.Now().Sub(started)
+			if f.err != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	spinner.Stop()
+
+	// The first failure is what cancelled the others, so report that one
+	var failed error
+	snapshots := make([]*diff.Snapshot, 0, len(fetches))
+	var warnings []FetchWarning
+	requests := 0
+	for i, src := range cfg.Sources {
+		f := &fetches[i]
+		if cfg.Verbose {
+			_, _ = fmt.Fprintf(stdout, "Fetching activity for source %q\n", src.Label)
+		}
+		_, _ = io.Copy(stdout, &f.stdout)
+		_, _ = io.Copy(stderr, &f.stderr)
+		if f.err != nil {
+			if failed == nil || (errors.Is(failed, context.Canceled) && !errors.Is(f.err, context.Canceled)) {
+				failed = fmt.Errorf("source %q: %w", src.Label, f.err)
+			}
+			continue
+		}
+		warnings = append(warnings, f.warnings...)
+		requests += requestCount(f.client)
+		for name, ua := range f.snapshot.Users {
+			ua.Sources = []string{src.Label}
+			f.snapshot.Users[name] = ua
+		}
+		if cfg.Verbose {
+			printRequestStats(stdout, f.client)
+			_, _ = fmt.Fprintf(stdout, "Source %q fetched in %s (users: %d)\n", src.Label, f.took.Round(time.Millisecond), len(f.snapshot.Users))
+		}
+		snapshots = append(snapshots, f.snapshot)
+	}
+	if failed != nil {
+		return nil, nil, 0, failed
+	}
+	return diff.MergeSnapshots(now, snapshots...), warnings, requests, nil
+}
+
+// sourceFetch is one account's part of fetchSources: its client, what it
+// fetched, how long that took, and what it printed along the way.
+type sourceFetch struct {
+	err      error
+	client   GitHubClient
+	snapshot *diff.Snapshot
+	warnings []FetchWarning
+	stdout   bytes.Buffer
+	stderr   bytes.Buffer
+	took     time.Duration
+}
+
+// userSources maps each user in a snapshot to the accounts they're followed
+// from.
+func userSources(s *diff.Snapshot) map[string][]string {
+	sources := make(map[string][]string, len(s.Users))
+	for name, ua := range s.Users {
+		sources[name] = ua.Sources
+	}
+	return sources
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+func TestSourceList_Set(t *testing.T) {
+	t.Setenv("WORK_TOKEN", "ghp_work")
+
+	var l sourceList
+	if err := l.Set("work=$WORK_TOKEN, personal=ghp_home"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	want := sourceList{{Label: "work", Token: "ghp_work"}, {Label: "personal", Token: "ghp_home"}}
+	if !slices.Equal(l, want) {
+		t.Errorf("got %+v, want %+v", l, want)
+	}
+	if l.String() != "work,personal" {
+		t.Errorf("String() = %q; should list labels only", l.String())
+	}
+
+	tests := []struct {
+		value   string
+		wantErr string
+	}{
+		{"work", "label=token"},
+		{"=token", "label=token"},
+		{"oss=$UNSET_GITSTREAMS_TOKEN", "not set"},
+		{"work=other", "given twice"},
+	}
+	for _, tt := range tests {
+		if err := l.Set(tt.value); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Set(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseFlags_SourcesFromConfigFile(t *testing.T) {
+	home := setHome(t)
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultConfigName),
+		`{"source": ["work=ghp_work", "personal=ghp_home"]}`)
+
+	cfg, err := parseFlags(nil)
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	if len(cfg.Sources) != 2 || cfg.Sources[1].Token != "ghp_home" {
+		t.Errorf("unexpected sources %+v", cfg.Sources)
+	}
+}
+
+func TestRun_MultipleSources(t *testing.T) {
+	setHome(t)
+	now := fixedTime()
+	clients := map[string]*mockGitHubClient{
+		"ghp_work": {
+			followedUsers: []github.User{{Login: "alice", ID: 1}, {Login: "bob", ID: 2}},
+			starredRepos: map[string][]github.Repository{
+				"alice": {{Name: "go", Owner: github.User{Login: "golang"}, CreatedAt: now}},
+			},
+		},
+		"ghp_home": {
+			followedUsers: []github.User{{Login: "alice", ID: 1}},
+			starredRepos: map[string][]github.Repository{
+				"alice": {{Name: "go", Owner: github.User{Login: "golang"}, CreatedAt: now}},
+			},
+		},
+	}
+	gen := &mockReportGenerator{}
+	var tokens []string
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			tokens = append(tokens, token)
+			return clients[token]
+		},
+		StoreFactory:    func(path string) (Store, error) { return &mockStore{}, nil },
+		NotifierFactory: func() Notifier { return &mockNotifier{} },
+		ReportGenerator: func(string) (ReportGenerator, error) { return gen, nil },
+		OpenBrowser:     func(url string) error { return nil },
+		Now:             fixedTime,
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(&stdout, &stderr, []string{
+		"-source", "work=ghp_work",
+		"-source", "personal=ghp_home",
+		"-report", filepath.Join(t.TempDir(), "report.html"),
+		"-no-notify",
+	}, deps)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+
+	if !slices.Equal(tokens, []string{"ghp_work", "ghp_home"}) {
+		t.Errorf("expected a client per source, got tokens %v", tokens)
+	}
+	rpt := gen.generatedReport
+	if rpt == nil {
+		t.Fatal("expected a report")
+	}
+	// alice's star is reported once, although both accounts see it
+	if got := rpt.TotalActivities(); got != 1 {
+		t.Errorf("expected 1 de-duplicated activity, got %d", got)
+	}
+	for _, ua := range rpt.UserActivities {
+		if ua.User == "alice" && !slices.Equal(ua.Sources, []string{"work", "personal"}) {
+			t.Errorf("alice's sources = %v, want [work personal]", ua.Sources)
+		}
+	}
+}
+
+// meetingClient lists its follows only once every client sharing meet has
+// started to, so fetches that aren't concurrent time out.
+type meetingClient struct {
+	*mockGitHubClient
+	meet *sync.WaitGroup
+}
+
+func (m meetingClient) GetFollowedUsers(ctx context.Context) ([]github.User, error) {
+	m.meet.Done()
+	met := make(chan struct{})
+	go func() { m.meet.Wait(); close(met) }()
+	select {
+	case <-met:
+		return m.mockGitHubClient.GetFollowedUsers(ctx)
+	case <-time.After(5 * time.Second):
+		return nil, errors.New("sources weren't fetched at once")
+	}
+}
+
+func TestFetchSources_Concurrent(t *testing.T) {
+	var meet sync.WaitGroup
+	meet.Add(2)
+	clients := map[string]GitHubClient{
+		"ghp_work": meetingClient{&mockGitHubClient{followedUsers: []github.User{{Login: "alice"}, {Login: "bob"}}}, &meet},
+		"ghp_home": meetingClient{&mockGitHubClient{followedUsers: []github.User{{Login: "carol"}}}, &meet},
+	}
+	cfg := &Config{Verbose: true, Sources: sourceList{{Label: "work", Token: "ghp_work"}, {Label: "home", Token: "ghp_home"}}}
+	deps := &Dependencies{GitHubClientFactory: func(token string) GitHubClient { return clients[token] }, Now: fixedTime}
+
+	var stdout, stderr bytes.Buffer
+	snapshot, _, _, err := fetchSources(context.Background(), cfg, deps, nil, fixedTime(), fixedTime().AddDate(0, 0, -30), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("fetchSources() error = %v", err)
+	}
+	if len(snapshot.Users) != 3 || !slices.Equal(snapshot.Users["carol"].Sources, []string{"home"}) {
+		t.Errorf("unexpected merged users %+v", snapshot.Users)
+	}
+
+	// Each source's output comes out whole, in the order they're configured
+	out := stdout.String()
+	work, home := strings.Index(out, `source "work"`), strings.Index(out, `source "home"`)
+	if work < 0 || home < work || strings.Contains(out[work:home], "carol") || strings.Contains(out[home:], "alice") {
+		t.Errorf("expected each source's output together, in order, got:\n%s", out)
+	}
+	for _, want := range []string{`Source "work" fetched in 0s (users: 2)`, `Source "home" fetched in 0s (users: 1)`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected per-source timing %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(stderr.String(), "Fetching activity for user") {
+		t.Errorf("per-user progress should be left out when sources run at once, got:\n%s", stderr.String())
+	}
+}
+
+func TestFetchSources_Failure(t *testing.T) {
+	clients := map[string]GitHubClient{
+		"ghp_work": &mockGitHubClient{followedUsers: []github.User{{Login: "alice"}}},
+		"ghp_home": &mockGitHubClient{followedErr: errors.New("bad credentials")},
+	}
+	cfg := &Config{Sources: sourceList{{Label: "work", Token: "ghp_work"}, {Label: "home", Token: "ghp_home"}}}
+	deps := &Dependencies{GitHubClientFactory: func(token string) GitHubClient { return clients[token] }, Now: fixedTime}
+
+	var stdout, stderr bytes.Buffer
+	_, _, _, err := fetchSources(context.Background(), cfg, deps, nil, fixedTime(), fixedTime().AddDate(0, 0, -30), &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), `source "home"`) || !strings.Contains(err.Error(), "bad credentials") {
+		t.Errorf("expected home's error, got %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Activity kinds stored in the activities table.
+const (
+	KindStar  = "star"
+	KindRepo  = "repo"
+	KindEvent = "event"
+)
+
+// timeLayout is how activity times are stored: UTC in SQLite's own datetime
+// format, so its date functions and string comparisons work on them.
+const timeLayout = "2006-01-02 15:04:05"
+
+// Activity is one thing a followed user did, as a row in the normalized
+// activities table that sits beside the snapshot blobs. Rows are added as
+// snapshots are saved and identified by Scope, Username, Kind and Key, so
+// seeing the same activity in later snapshots doesn't add it again.
+type Activity struct {
+	OccurredAt time.Time // When it happened, as best the API tells us
+	FirstSeen  time.Time // When the snapshot that first held it was taken
+	Scope      string    // The snapshot series it came from, e.g. a team profile
+	Username   string
+	Kind       string // KindStar, KindRepo or KindEvent
+	EventType  string // GitHub event type, for KindEvent
+	Repo       string // owner/name
+	Key        string // Tells apart activities of the same kind by the same user
+	Details    string
+}
+
+// DayCount is the number of activities on one day.
+type DayCount struct {
+	Day   time.Time
+	Count int
+}
+
+// SaveActivities adds activities not already stored and returns how many
+// were new.
+func (s *SQLiteStore) SaveActivities(activities []Activity) (added int, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO activities
+		(scope, username, kind, event_type, repo, key, details, occurred_at, first_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("preparing insert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, a := range activities {
+		result, err := stmt.Exec(a.Scope, a.Username, a.Kind, a.EventType, a.Repo, a.Key, a.Details,
+			a.OccurredAt.UTC().Format(timeLayout), a.FirstSeen.UTC().Format(timeLayout))
+		if err != nil {
+			return 0, fmt.Errorf("saving activity: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("counting rows: %w", err)
+		}
+		added += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing activities: %w", err)
+	}
+	return added, nil
+}
+
+// DailyActivityCounts counts a scope's activities per day from since
+// onwards, with days starting at midnight in loc. Days without activity are
+// left out.
+func (s *SQLiteStore) DailyActivityCounts(scope string, since time.Time, loc *time.Location) ([]DayCount, error) {
+	return s.dailyCounts("scope = ?", since, loc, scope)
+}
+
+// UserDailyActivityCounts is DailyActivityCounts for one user's activities.
+func (s *SQLiteStore) UserDailyActivityCounts(scope, username string, since time.Time, loc *time.Location) ([]DayCount, error) {
+	return s.dailyCounts("scope = ? AND username = ?", since, loc, scope, username)
+}
+
+// dailyCounts counts activities matching where per day from since onwards.
+func (s *SQLiteStore) dailyCounts(where string, since time.Time, loc *time.Location, args ...any) (counts []DayCount, err error) {
+	_, offset := since.In(loc).Zone()
+	args = append([]any{fmt.Sprintf("%+d seconds", offset)}, args...)
+	// #nosec G202 -- where is one of this package's constant filters
+	rows, err := s.db.Query(`SELECT date(occurred_at, ?) AS day, COUNT(*) FROM activities
+		WHERE `+where+` AND occurred_at >= ?
+		GROUP BY day ORDER BY day`,
+		append(args, since.UTC().Format(timeLayout))...)
+	if err != nil {
+		return nil, fmt.Errorf("counting activities: %w", err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var day string
+		var c DayCount
+		if err := rows.Scan(&day, &c.Count); err != nil {
+			return nil, fmt.Errorf("scanning activity count: %w", err)
+		}
+		if c.Day, err = time.ParseInLocation("2006-01-02", day, loc); err != nil {
+			return nil, fmt.Errorf("parsing day %q: %w", day, err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return counts, nil
+}
+
+// RepoActivities returns a scope's activities on repo, newest first, up to
+// limit.
+func (s *SQLiteStore) RepoActivities(scope, repo string, limit int) ([]Activity, error) {
+	return s.queryActivities(`SELECT scope, username, kind, event_type, repo, key, details, occurred_at, first_seen
+		FROM activities WHERE scope = ? AND repo = ?
+		ORDER BY occurred_at DESC, id DESC LIMIT ?`, scope, repo, limit)
+}
+
+// ActivitiesSince returns a scope's activities from since onwards, oldest
+// first.
+func (s *SQLiteStore) ActivitiesSince(scope string, since time.Time) ([]Activity, error) {
+	return s.queryActivities(`SELECT scope, username, kind, event_type, repo, key, details, occurred_at, first_seen
+		FROM activities WHERE scope = ? AND occurred_at >= ?
+		ORDER BY occurred_at, id`, scope, since.UTC().Format(timeLayout))
+}
+
+// UserActivities returns a user's activities in scope, newest first, up to
+// limit.
+func (s *SQLiteStore) UserActivities(scope, username string, limit int) ([]Activity, error) {
+	return s.queryActivities(`SELECT scope, username, kind, event_type, repo, key, details, occurred_at, first_seen
+		FROM activities WHERE scope = ? AND username = ?
+		ORDER BY occurred_at DESC, id DESC LIMIT ?`, scope, username, limit)
+}
+
+// ActivityFilter narrows SearchActivities. Zero fields match everything.
+type ActivityFilter struct {
+	Since     time.Time
+	Scope     string
+	Usernames []string // Any of these users, case-insensitively
+	Kind      string   // KindStar, KindRepo or KindEvent
+	EventType string   // GitHub event type, for KindEvent
+	Repo      string   // owner/name, case-insensitively
+	Text      string   // Found in the repo or details, case-insensitively
+	Limit     int      // At most this many, the newest; 0 for all
+}
+
+// SearchActivities returns the activities matching f, newest first.
+func (s *SQLiteStore) SearchActivities(f ActivityFilter) ([]Activity, error) {
+	where := []string{"scope = ?"}
+	args := []any{f.Scope}
+	if len(f.Usernames) > 0 {
+		where = append(where, "username COLLATE NOCASE IN (?"+strings.Repeat(", ?", len(f.Usernames)-1)+")")
+		for _, u := range f.Usernames {
+			args = append(args, u)
+		}
+	}
+	if f.Kind != "" {
+		where, args = append(where, "kind = ?"), append(args, f.Kind)
+	}
+	if f.EventType != "" {
+		where, args = append(where, "event_type = ?"), append(args, f.EventType)
+	}
+	if f.Repo != "" {
+		where, args = append(where, "repo = ? COLLATE NOCASE"), append(args, f.Repo)
+	}
+	if f.Text != "" {
+		where = append(where, "(instr(lower(repo), lower(?)) > 0 OR instr(lower(details), lower(?)) > 0)")
+		args = append(args, f.Text, f.Text)
+	}
+	if !f.Since.IsZero() {
+		where, args = append(where, "occurred_at >= ?"), append(args, f.Since.UTC().Format(timeLayout))
+	}
+	limit := -1 // No limit, to SQLite
+	if f.Limit > 0 {
+		limit = f.Limit
+	}
+	// #nosec G202 -- where is built from this function's constant filters
+	return s.queryActivities(`SELECT scope, username, kind, event_type, repo, key, details, occurred_at, first_seen
+		FROM activities WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY occurred_at DESC, id DESC LIMIT ?`, append(args, limit)...)
+}
+
+// RepoCount is how often a user has touched a repo, and when they last did.
+type RepoCount struct {
+	Last  time.Time
+	Repo  string
+	Count int
+}
+
+// UserRepos returns the repos a user's activities in scope touch, most
+// recently touched first, up to limit.
+func (s *SQLiteStore) UserRepos(scope, username string, limit int) (repos []RepoCount, err error) {
+	rows, err := s.db.Query(`SELECT repo, COUNT(*), MAX(occurred_at) AS last FROM activities
+		WHERE scope = ? AND username = ?
+		GROUP BY repo ORDER BY last DESC, repo LIMIT ?`, scope, username, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying repos: %w", err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var r RepoCount
+		var last string
+		if err := rows.Scan(&r.Repo, &r.Count, &last); err != nil {
+			return nil, fmt.Errorf("scanning repo: %w", err)
+		}
+		if r.Last, err = time.Parse(timeLayout, last); err != nil {
+			return nil, fmt.Errorf("parsing time %q: %w", last, err)
+		}
+		repos = append(repos, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return repos, nil
+}
+
+// queryActivities runs a query selecting whole activity rows.
+func (s *SQLiteStore) queryActivities(query string, args ...any) (activities []Activity, err error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying activities: %w", err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var a Activity
+		var occurred, seen string
+		if err := rows.Scan(&a.Scope, &a.Username, &a.Kind, &a.EventType, &a.Repo, &a.Key, &a.Details, &occurred, &seen); err != nil {
+			return nil, fmt.Errorf("scanning activity: %w", err)
+		}
+		if a.OccurredAt, err = time.Parse(timeLayout, occurred); err != nil {
+			return nil, fmt.Errorf("parsing occurred_at %q: %w", occurred, err)
+		}
+		if a.FirstSeen, err = time.Parse(timeLayout, seen); err != nil {
+			return nil, fmt.Errorf("parsing first_seen %q: %w", seen, err)
+		}
+		activities = append(activities, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return activities, nil
+}
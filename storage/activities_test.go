@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveActivities(t *testing.T) {
+	store := newTestStore(t)
+	day := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	seen := day.Add(12 * time.Hour)
+
+	activities := []Activity{
+		{Scope: "s", Username: "alice", Kind: KindStar, Repo: "golang/go", Key: "golang/go", OccurredAt: day.Add(9 * time.Hour), FirstSeen: seen},
+		{Scope: "s", Username: "alice", Kind: KindEvent, EventType: "PushEvent", Repo: "alice/a", Key: "PushEvent 1", OccurredAt: day.Add(10 * time.Hour), FirstSeen: seen},
+		{Scope: "s", Username: "bob", Kind: KindEvent, EventType: "PushEvent", Repo: "bob/b", Key: "PushEvent 2", OccurredAt: day.Add(-time.Hour), FirstSeen: seen},
+		{Scope: "other", Username: "alice", Kind: KindStar, Repo: "golang/go", Key: "golang/go", OccurredAt: day, FirstSeen: seen},
+	}
+	added, err := store.SaveActivities(activities)
+	if err != nil {
+		t.Fatalf("SaveActivities failed: %v", err)
+	}
+	if added != 4 {
+		t.Errorf("expected 4 new activities, got %d", added)
+	}
+
+	// Seen again in a later snapshot
+	later := activities[0]
+	later.FirstSeen = seen.Add(time.Hour)
+	if added, err = store.SaveActivities([]Activity{later}); err != nil || added != 0 {
+		t.Errorf("re-saving: added %d, err %v; want 0", added, err)
+	}
+
+	counts, err := store.DailyActivityCounts("s", day.AddDate(0, 0, -7), time.UTC)
+	if err != nil {
+		t.Fatalf("DailyActivityCounts failed: %v", err)
+	}
+	if len(counts) != 2 || counts[0].Count != 1 || counts[1].Count != 2 || !counts[1].Day.Equal(day) {
+		t.Errorf("unexpected counts %+v", counts)
+	}
+
+	// Days follow the requested time zone: 23:00 UTC on the 14th is the
+	// 15th two hours east
+	east := time.FixedZone("UTC+2", 2*60*60)
+	counts, err = store.DailyActivityCounts("s", day.AddDate(0, 0, -7), east)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 1 || counts[0].Count != 3 {
+		t.Errorf("unexpected counts in UTC+2: %+v", counts)
+	}
+
+	counts, _ = store.DailyActivityCounts("s", day, time.UTC)
+	if len(counts) != 1 || counts[0].Count != 2 {
+		t.Errorf("expected only activity since the 15th, got %+v", counts)
+	}
+}
+
+func TestRepoActivities(t *testing.T) {
+	store := newTestStore(t)
+	at := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	_, err := store.SaveActivities([]Activity{
+		{Scope: "s", Username: "alice", Kind: KindStar, Repo: "golang/go", Key: "golang/go", Details: "The Go language", OccurredAt: at, FirstSeen: at},
+		{Scope: "s", Username: "bob", Kind: KindEvent, EventType: "ReleaseEvent", Repo: "golang/go", Key: "ReleaseEvent 1", OccurredAt: at.Add(time.Hour), FirstSeen: at.Add(2 * time.Hour)},
+		{Scope: "s", Username: "bob", Kind: KindEvent, EventType: "PushEvent", Repo: "bob/b", Key: "PushEvent 1", OccurredAt: at, FirstSeen: at},
+		{Scope: "other", Username: "carol", Kind: KindStar, Repo: "golang/go", Key: "golang/go", OccurredAt: at, FirstSeen: at},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.RepoActivities("s", "golang/go", 10)
+	if err != nil {
+		t.Fatalf("RepoActivities failed: %v", err)
+	}
+	if len(got) != 2 || got[0].EventType != "ReleaseEvent" || got[1].Username != "alice" {
+		t.Fatalf("unexpected activities %+v", got)
+	}
+	if !got[0].OccurredAt.Equal(at.Add(time.Hour)) || !got[0].FirstSeen.Equal(at.Add(2*time.Hour)) || got[1].Details != "The Go language" {
+		t.Errorf("fields didn't round-trip: %+v", got)
+	}
+
+	if got, _ := store.RepoActivities("s", "golang/go", 1); len(got) != 1 {
+		t.Errorf("expected the limit to apply, got %d", len(got))
+	}
+}
+
+func TestActivitiesSince(t *testing.T) {
+	store := newTestStore(t)
+	at := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	_, err := store.SaveActivities([]Activity{
+		{Scope: "s", Username: "alice", Kind: KindStar, Repo: "golang/go", Key: "golang/go", OccurredAt: at, FirstSeen: at},
+		{Scope: "s", Username: "bob", Kind: KindRepo, Repo: "bob/b", Key: "bob/b", OccurredAt: at.Add(-time.Hour), FirstSeen: at},
+		{Scope: "s", Username: "bob", Kind: KindStar, Repo: "old/thing", Key: "old/thing", OccurredAt: at.AddDate(0, 0, -40), FirstSeen: at},
+		{Scope: "other", Username: "carol", Kind: KindStar, Repo: "golang/go", Key: "golang/go", OccurredAt: at, FirstSeen: at},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.ActivitiesSince("s", at.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("ActivitiesSince failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Repo != "bob/b" || got[1].Repo != "golang/go" {
+		t.Errorf("unexpected activities %+v", got)
+	}
+}
+
+func TestSearchActivities(t *testing.T) {
+	store := newTestStore(t)
+	day := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	_, err := store.SaveActivities([]Activity{
+		{Scope: "s", Username: "simonw", Kind: KindStar, Repo: "golang/go", Key: "golang/go", Details: "The Go language", OccurredAt: day.Add(-100 * 24 * time.Hour)},
+		{Scope: "s", Username: "simonw", Kind: KindStar, Repo: "datasette/datasette", Key: "datasette/datasette", Details: "Explore data", OccurredAt: day.Add(time.Hour)},
+		{Scope: "s", Username: "simonw", Kind: KindEvent, EventType: "PushEvent", Repo: "simonw/llm", Key: "PushEvent 1", OccurredAt: day.Add(2 * time.Hour)},
+		{Scope: "s", Username: "alice", Kind: KindStar, Repo: "golang/go", Key: "golang/go", OccurredAt: day.Add(3 * time.Hour)},
+		{Scope: "other", Username: "simonw", Kind: KindStar, Repo: "rust-lang/rust", Key: "rust-lang/rust", OccurredAt: day},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		filter ActivityFilter
+		want   []string // Repos, newest first
+	}{
+		{"everything in scope", ActivityFilter{Scope: "s"}, []string{"golang/go", "simonw/llm", "datasette/datasette", "golang/go"}},
+		{"user and kind since", ActivityFilter{Scope: "s", Usernames: []string{"SimonW"}, Kind: KindStar, Since: day.AddDate(0, 0, -90)}, []string{"datasette/datasette"}},
+		{"several users", ActivityFilter{Scope: "s", Usernames: []string{"alice", "simonw"}, Kind: KindStar}, []string{"golang/go", "datasette/datasette", "golang/go"}},
+		{"event type", ActivityFilter{Scope: "s", Kind: KindEvent, EventType: "PushEvent"}, []string{"simonw/llm"}},
+		{"repo", ActivityFilter{Scope: "s", Repo: "Golang/Go"}, []string{"golang/go", "golang/go"}},
+		{"text in details", ActivityFilter{Scope: "s", Text: "explore"}, []string{"datasette/datasette"}},
+		{"limit keeps the newest", ActivityFilter{Scope: "s", Limit: 2}, []string{"golang/go", "simonw/llm"}},
+		{"no match", ActivityFilter{Scope: "s", Usernames: []string{"bob"}}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.SearchActivities(tt.filter)
+			if err != nil {
+				t.Fatalf("SearchActivities() error = %v", err)
+			}
+			var repos []string
+			for _, a := range got {
+				repos = append(repos, a.Repo)
+			}
+			if strings.Join(repos, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("SearchActivities() = %v, want %v", repos, tt.want)
+			}
+		})
+	}
+}
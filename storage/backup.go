@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupSuffix separates a database's path from the time in its backups'
+// names.
+const backupSuffix = ".backup-"
+
+// backupTimeLayout dates backups in their names so they sort by age.
+const backupTimeLayout = "20060102-150405"
+
+// NeedsMigration reports whether opening the SQLite database at dbPath would
+// change its schema. A missing or empty database doesn't: there's nothing in
+// it to lose.
+func NeedsMigration(dbPath string) (needed bool, err error) {
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	db, err := openReadOnly(dbPath)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = db.Close() }()
+
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'snapshots'").Scan(&tables); err != nil {
+		return false, fmt.Errorf("reading schema: %w", err)
+	}
+	if tables == 0 {
+		return false, nil
+	}
+	version, err := userVersion(db)
+	if err != nil {
+		return false, err
+	}
+	return version < schemaVersion, nil
+}
+
+// Backup writes a consistent copy of the SQLite database at dbPath next to
+// it, named for now, and returns the copy's path.
+func Backup(dbPath string, now time.Time) (string, error) {
+	dest := dbPath + backupSuffix + now.UTC().Format(backupTimeLayout)
+	if _, err := os.Stat(dest); err == nil {
+		return "", fmt.Errorf("backup %s already exists", dest)
+	}
+	db, err := openReadOnly(dbPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = db.Close() }()
+
+	// VACUUM INTO copies through SQLite, so a write in progress can't leave
+	// the copy torn the way a file copy could
+	if _, err := db.Exec("VACUUM INTO ?", dest); err != nil {
+		return "", fmt.Errorf("backing up database: %w", err)
+	}
+	return dest, nil
+}
+
+// Backups returns the paths of the SQLite database's backups, newest first.
+func Backups(dbPath string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("listing backups: %w", err)
+	}
+	prefix := filepath.Base(dbPath) + backupSuffix
+	var backups []string
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(backupTimeLayout, stamp); err == nil {
+			backups = append(backups, filepath.Join(filepath.Dir(dbPath), e.Name()))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
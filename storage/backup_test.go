@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLegacyDB creates a database in the original schema, from before
+// versions were recorded.
+func writeLegacyDB(t *testing.T, dbPath string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	_, err = db.Exec(`
+	CREATE TABLE snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		activity_json TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO snapshots (user_id, timestamp, activity_json) VALUES ('user1', '2024-01-15 10:00:00', '{"legacy":true}');
+	`)
+	if err != nil {
+		t.Fatalf("creating legacy schema: %v", err)
+	}
+}
+
+func TestNeedsMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	if needed, err := NeedsMigration(filepath.Join(dir, "missing.db")); err != nil || needed {
+		t.Errorf("NeedsMigration(missing) = %v, %v; want false", needed, err)
+	}
+
+	empty := filepath.Join(dir, "empty.db")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if needed, err := NeedsMigration(empty); err != nil || needed {
+		t.Errorf("NeedsMigration(empty) = %v, %v; want false", needed, err)
+	}
+
+	legacy := filepath.Join(dir, "legacy.db")
+	writeLegacyDB(t, legacy)
+	if needed, err := NeedsMigration(legacy); err != nil || !needed {
+		t.Errorf("NeedsMigration(legacy) = %v, %v; want true", needed, err)
+	}
+	store, err := NewSQLiteStore(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	if needed, err := NeedsMigration(legacy); err != nil || needed {
+		t.Errorf("NeedsMigration after migrating = %v, %v; want false", needed, err)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "gitstreams.db")
+	writeLegacyDB(t, dbPath)
+	// Files that look like backups but aren't are ignored
+	for _, name := range []string{"gitstreams.db.backup-notes", "other.db.backup-20240101-000000"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	older, err := Backup(dbPath, first)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if want := dbPath + ".backup-20260115-090000"; older != want {
+		t.Errorf("backup at %s, want %s", older, want)
+	}
+	if _, err := Backup(dbPath, first); err == nil {
+		t.Error("expected error overwriting a backup")
+	}
+	newer, err := Backup(dbPath, first.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := Backups(dbPath)
+	if err != nil {
+		t.Fatalf("Backups failed: %v", err)
+	}
+	if len(backups) != 2 || backups[0] != newer || backups[1] != older {
+		t.Errorf("Backups = %v, want [%s %s]", backups, newer, older)
+	}
+
+	// The backup is a working copy of the database as it was
+	if needed, err := NeedsMigration(older); err != nil || !needed {
+		t.Errorf("expected the backup to keep the old schema, got %v, %v", needed, err)
+	}
+	store, err := NewSQLiteStore(older)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	if snapshots, _ := store.GetByUser("user1", 10); len(snapshots) != 1 {
+		t.Errorf("expected the backup to hold the snapshot, got %d", len(snapshots))
+	}
+}
@@ -0,0 +1,630 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bolt buckets. Snapshots are keyed by ID; byUser holds a bucket per user
+// whose keys are timestamp+ID, so a user's snapshots can be walked in time
+// order without decoding them.
+var (
+	snapshotsBucket = []byte("snapshots")
+	byUserBucket    = []byte("snapshots_by_user")
+	pendingBucket   = []byte("pending_diffs")
+	metaBucket      = []byte("meta")
+	reportedBucket  = []byte("reported_changes")
+	runsBucket      = []byte("runs")
+)
+
+// boltSchemaVersion numbers the layout of a Bolt file, kept in its meta
+// bucket under boltSchemaKey. Bump it whenever the layout changes.
+const boltSchemaVersion = 3
+
+var boltSchemaKey = []byte("schema_version")
+
+// boltOpenTimeout bounds how long opening a Bolt file waits for another
+// process to let go of it.
+const boltOpenTimeout = 5 * time.Second
+
+// BoltStore implements Store in a single Bolt file. It's pure Go and much
+// smaller than SQLite, at the cost of the SQL-backed extras: the activity
+// table, followed-user pages and the query command need a SQLiteStore.
+type BoltStore struct {
+	db  *bolt.DB
+	now func() time.Time // Stamps snapshots saved without a timestamp
+}
+
+// snapshotRecord is a snapshot as stored by the key/value stores. Data is a
+// []byte rather than the Snapshot's json.RawMessage since it's opaque and
+// needn't be JSON.
+type snapshotRecord struct {
+	Activity      map[string]interface{} `json:"activity,omitempty"`
+	Timestamp     time.Time              `json:"timestamp"`
+	UserID        string                 `json:"user_id"`
+	AppVersion    string                 `json:"app_version,omitempty"`
+	Data          []byte                 `json:"data,omitempty"`
+	ID            int64                  `json:"id,omitempty"` // Only in MemoryStore dumps; Bolt keys by it
+	SchemaVersion int                    `json:"schema_version,omitempty"`
+	Partial       bool                   `json:"partial,omitempty"`
+}
+
+// encodeSnapshot encodes a snapshot as a snapshotRecord.
+func encodeSnapshot(snapshot *Snapshot) ([]byte, error) {
+	record := snapshotRecord{
+		UserID:        snapshot.UserID,
+		Timestamp:     snapshot.Timestamp,
+		AppVersion:    snapshot.AppVersion,
+		SchemaVersion: snapshot.SchemaVersion,
+		Partial:       snapshot.Partial,
+	}
+	if snapshot.SchemaVersion > 0 {
+		record.Data = snapshot.Data
+	} else {
+		record.Activity = snapshot.Activity
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling snapshot: %w", err)
+	}
+	return value, nil
+}
+
+// decodeSnapshot decodes a snapshot encoded by encodeSnapshot.
+func decodeSnapshot(id int64, value []byte) (*Snapshot, error) {
+	var record snapshotRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, fmt.Errorf("unmarshaling snapshot %d: %w", id, err)
+	}
+	return record.snapshot(id), nil
+}
+
+func (r *snapshotRecord) snapshot(id int64) *Snapshot {
+	snapshot := &Snapshot{
+		ID:            id,
+		UserID:        r.UserID,
+		Timestamp:     r.Timestamp,
+		AppVersion:    r.AppVersion,
+		SchemaVersion: r.SchemaVersion,
+		Partial:       r.Partial,
+	}
+	if r.SchemaVersion > 0 {
+		snapshot.Data = r.Data
+	} else {
+		snapshot.Activity = r.Activity
+	}
+	return snapshot
+}
+
+// pendingRecord is a queued change set as stored by the key/value stores.
+type pendingRecord struct {
+	CapturedAt time.Time `json:"captured_at"`
+	Data       []byte    `json:"data"`
+	ID         int64     `json:"id,omitempty"` // Only in MemoryStore dumps; Bolt keys by it
+}
+
+// NewBoltStore opens or creates a Bolt-backed store at path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: boltOpenTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("opening database: %s is in use by another process", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{snapshotsBucket, byUserBucket, pendingBucket, metaBucket, reportedBucket, runsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("creating bucket %s: %w", name, err)
+			}
+		}
+		return checkBoltSchema(tx)
+	})
+	var schemaErr *NewerSchemaError
+	if errors.As(err, &schemaErr) {
+		_ = db.Close()
+		return nil, err
+	}
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("running migrations: %w", err)
+	}
+
+	return &BoltStore{db: db, now: time.Now}, nil
+}
+
+// checkBoltSchema records boltSchemaVersion in a new or older file, or
+// returns a NewerSchemaError for a newer one.
+func checkBoltSchema(tx *bolt.Tx) error {
+	meta := tx.Bucket(metaBucket)
+	version := 0
+	if v := meta.Get(boltSchemaKey); v != nil {
+		var err error
+		if version, err = strconv.Atoi(string(v)); err != nil {
+			return fmt.Errorf("reading schema version: %w", err)
+		}
+	}
+	if version > boltSchemaVersion {
+		schemaErr := &NewerSchemaError{Version: version, Supported: boltSchemaVersion}
+		if _, v := tx.Bucket(snapshotsBucket).Cursor().Last(); v != nil {
+			var record snapshotRecord
+			if json.Unmarshal(v, &record) == nil {
+				schemaErr.WrittenBy = record.AppVersion
+			}
+		}
+		return schemaErr
+	}
+	if version < boltSchemaVersion {
+		return meta.Put(boltSchemaKey, []byte(strconv.Itoa(boltSchemaVersion)))
+	}
+	return nil
+}
+
+// itob encodes an ID as a sortable key.
+func itob(id int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id)) // #nosec G115 -- IDs are positive
+	return b
+}
+
+// The times timeKey can tell apart; UnixNano overflows outside them.
+var (
+	minKeyTime = time.Unix(0, math.MinInt64)
+	maxKeyTime = time.Unix(0, math.MaxInt64)
+)
+
+// timeKey encodes a timestamp so keys sort in time order, including times
+// before 1970. Times UnixNano can't represent, like the zero time used as an
+// open start, are clamped.
+func timeKey(t time.Time) []byte {
+	switch {
+	case t.Before(minKeyTime):
+		t = minKeyTime
+	case t.After(maxKeyTime):
+		t = maxKeyTime
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano())^(1<<63)) // #nosec G115 -- flipping the sign bit is the point
+	return b
+}
+
+// userKey is a snapshot's key in its user's byUser bucket.
+func userKey(snapshot *Snapshot) []byte {
+	return append(timeKey(snapshot.Timestamp), itob(snapshot.ID)...)
+}
+
+// Save stores a snapshot. If the snapshot has no ID, a new record is created.
+// On insert, the snapshot's ID is updated with the generated value.
+func (s *BoltStore) Save(snapshot *Snapshot) error {
+	return s.SaveAll([]*Snapshot{snapshot})
+}
+
+// SetNow sets the clock snapshots saved without a timestamp are stamped
+// with. It defaults to time.Now.
+func (s *BoltStore) SetNow(now func() time.Time) {
+	s.now = now
+}
+
+// SaveAll stores several snapshots in a single transaction. Either every
+// snapshot is persisted or, if any write fails, none are. On failure, IDs
+// assigned to new snapshots during the attempt are reset.
+func (s *BoltStore) SaveAll(snapshots []*Snapshot) (err error) {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	origIDs := make([]int64, len(snapshots))
+	for i, snapshot := range snapshots {
+		if snapshot == nil {
+			if len(snapshots) == 1 {
+				return errors.New("snapshot cannot be nil")
+			}
+			return fmt.Errorf("snapshot %d cannot be nil", i)
+		}
+		origIDs[i] = snapshot.ID
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i, snapshot := range snapshots {
+			snapshot.ID = origIDs[i]
+		}
+	}()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, snapshot := range snapshots {
+			if err := saveBoltSnapshot(tx, snapshot, s.now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func saveBoltSnapshot(tx *bolt.Tx, snapshot *Snapshot, now func() time.Time) error {
+	if err := checkSnapshot(snapshot); err != nil {
+		return err
+	}
+	if snapshot.Timestamp.IsZero() {
+		snapshot.Timestamp = now()
+	}
+
+	snapshots := tx.Bucket(snapshotsBucket)
+	if snapshot.ID == 0 {
+		seq, err := snapshots.NextSequence()
+		if err != nil {
+			return fmt.Errorf("assigning snapshot id: %w", err)
+		}
+		snapshot.ID = int64(seq) // #nosec G115 -- sequences start at 1 and won't reach 2^63
+	} else {
+		// Updates drop the old index entry, since the user or time may change
+		old, err := getBoltSnapshot(tx, snapshot.ID)
+		if errors.Is(err, ErrNotFound) {
+			// Matches SQLite, where updating a missing row is a no-op
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := deleteUserKey(tx, old); err != nil {
+			return err
+		}
+	}
+
+	value, err := encodeSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := snapshots.Put(itob(snapshot.ID), value); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	users, err := tx.Bucket(byUserBucket).CreateBucketIfNotExists([]byte(snapshot.UserID))
+	if err != nil {
+		return fmt.Errorf("indexing snapshot: %w", err)
+	}
+	if err := users.Put(userKey(snapshot), nil); err != nil {
+		return fmt.Errorf("indexing snapshot: %w", err)
+	}
+	return nil
+}
+
+// deleteUserKey removes a snapshot from its user's index.
+func deleteUserKey(tx *bolt.Tx, snapshot *Snapshot) error {
+	users := tx.Bucket(byUserBucket).Bucket([]byte(snapshot.UserID))
+	if users == nil {
+		return nil
+	}
+	if err := users.Delete(userKey(snapshot)); err != nil {
+		return fmt.Errorf("unindexing snapshot: %w", err)
+	}
+	return nil
+}
+
+func getBoltSnapshot(tx *bolt.Tx, id int64) (*Snapshot, error) {
+	value := tx.Bucket(snapshotsBucket).Get(itob(id))
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	return decodeSnapshot(id, value)
+}
+
+// Get retrieves a snapshot by ID.
+func (s *BoltStore) Get(id int64) (snapshot *Snapshot, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		snapshot, err = getBoltSnapshot(tx, id)
+		return err
+	})
+	return snapshot, err
+}
+
+// GetByUser retrieves the most recent snapshots for a user, up to limit.
+func (s *BoltStore) GetByUser(userID string, limit int) (snapshots []*Snapshot, err error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		users := tx.Bucket(byUserBucket).Bucket([]byte(userID))
+		if users == nil {
+			return nil
+		}
+		c := users.Cursor()
+		for k, _ := c.Last(); k != nil && len(snapshots) < limit; k, _ = c.Prev() {
+			snapshot, err := getBoltSnapshot(tx, int64(binary.BigEndian.Uint64(k[8:]))) // #nosec G115 -- written by itob
+			if err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// GetByTimeRange retrieves snapshots for a user within a time range.
+func (s *BoltStore) GetByTimeRange(userID string, start, end time.Time) (snapshots []*Snapshot, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		users := tx.Bucket(byUserBucket).Bucket([]byte(userID))
+		if users == nil {
+			return nil
+		}
+		last := timeKey(end)
+		c := users.Cursor()
+		for k, _ := c.Seek(timeKey(start)); k != nil && bytes.Compare(k[:8], last) <= 0; k, _ = c.Next() {
+			snapshot, err := getBoltSnapshot(tx, int64(binary.BigEndian.Uint64(k[8:]))) // #nosec G115 -- written by itob
+			if err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots by time range: %w", err)
+	}
+	// Newest first, like GetByUser
+	slices.Reverse(snapshots)
+	return snapshots, nil
+}
+
+// Delete removes a snapshot by ID.
+func (s *BoltStore) Delete(id int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		snapshot, err := getBoltSnapshot(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := deleteUserKey(tx, snapshot); err != nil {
+			return err
+		}
+		if err := tx.Bucket(snapshotsBucket).Delete(itob(id)); err != nil {
+			return fmt.Errorf("deleting snapshot: %w", err)
+		}
+		return nil
+	})
+}
+
+// QueueDiff stores a change set until the next digest is delivered.
+func (s *BoltStore) QueueDiff(capturedAt time.Time, data []byte) (id int64, err error) {
+	value, err := json.Marshal(pendingRecord{CapturedAt: capturedAt, Data: data})
+	if err != nil {
+		return 0, fmt.Errorf("queueing diff: %w", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		pending := tx.Bucket(pendingBucket)
+		seq, err := pending.NextSequence()
+		if err != nil {
+			return err
+		}
+		id = int64(seq) // #nosec G115 -- sequences start at 1 and won't reach 2^63
+		return pending.Put(itob(id), value)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("queueing diff: %w", err)
+	}
+	return id, nil
+}
+
+// PendingDiffs returns every queued change set, oldest first.
+func (s *BoltStore) PendingDiffs() (diffs []PendingDiff, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).ForEach(func(k, v []byte) error {
+			var d pendingRecord
+			if err := json.Unmarshal(v, &d); err != nil {
+				return fmt.Errorf("unmarshaling pending diff: %w", err)
+			}
+			diffs = append(diffs, PendingDiff{
+				ID:         int64(binary.BigEndian.Uint64(k)), // #nosec G115 -- written by itob
+				CapturedAt: d.CapturedAt,
+				Data:       d.Data,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying pending diffs: %w", err)
+	}
+	return diffs, nil
+}
+
+// ClearPendingDiffs removes queued change sets up to and including
+// throughID. Anything queued after them is kept.
+func (s *BoltStore) ClearPendingDiffs(throughID int64) error {
+	if throughID <= 0 {
+		return nil
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(pendingBucket).Cursor()
+		last := itob(throughID)
+		for k, _ := c.First(); k != nil && bytes.Compare(k, last) <= 0; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("clearing pending diffs: %w", err)
+	}
+	return nil
+}
+
+// GetMeta returns the value stored under key, or "" if there is none.
+func (s *BoltStore) GetMeta(key string) (value string, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		value = string(tx.Bucket(metaBucket).Get([]byte(key)))
+		return nil
+	})
+	return value, err
+}
+
+// SetMeta stores value under key, replacing any earlier value.
+func (s *BoltStore) SetMeta(key, value string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put([]byte(key), []byte(value))
+	})
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", key, err)
+	}
+	return nil
+}
+
+// MarkReported records content hashes of changes that have gone out in a
+// report. Hashes already recorded keep their first time.
+func (s *BoltStore) MarkReported(hashes []string, at time.Time) error {
+	reportedAt := []byte(at.UTC().Format(time.RFC3339))
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		reported := tx.Bucket(reportedBucket)
+		for _, h := range hashes {
+			if reported.Get([]byte(h)) != nil {
+				continue
+			}
+			if err := reported.Put([]byte(h), reportedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("marking changes reported: %w", err)
+	}
+	return nil
+}
+
+// Reported returns which of hashes MarkReported has recorded.
+func (s *BoltStore) Reported(hashes []string) (map[string]bool, error) {
+	reported := make(map[string]bool)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(reportedBucket)
+		for _, h := range hashes {
+			if bucket.Get([]byte(h)) != nil {
+				reported[h] = true
+			}
+		}
+		return nil
+	})
+	return reported, err
+}
+
+// PruneReported forgets changes reported before cutoff and returns how many
+// there were. With dryRun set, it only counts them.
+func (s *BoltStore) PruneReported(cutoff time.Time, dryRun bool) (int64, error) {
+	var stale [][]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(reportedBucket).ForEach(func(k, v []byte) error {
+			at, err := time.Parse(time.RFC3339, string(v))
+			if err != nil {
+				return fmt.Errorf("reading when %s was reported: %w", k, err)
+			}
+			if at.Before(cutoff) {
+				stale = append(stale, k)
+			}
+			return nil
+		})
+	})
+	if err != nil || dryRun || len(stale) == 0 {
+		return int64(len(stale)), err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		reported := tx.Bucket(reportedBucket)
+		for _, k := range stale {
+			if err := reported.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("pruning reported changes: %w", err)
+	}
+	return int64(len(stale)), nil
+}
+
+// RecordRun adds run to the run history and returns its ID. Its ID field
+// is ignored.
+func (s *BoltStore) RecordRun(run Run) (id int64, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(runsBucket)
+		seq, err := runs.NextSequence()
+		if err != nil {
+			return err
+		}
+		id = int64(seq) // #nosec G115 -- sequences start at 1 and won't reach 2^63
+		run.ID = 0
+		value, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+		return runs.Put(itob(id), value)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("recording run: %w", err)
+	}
+	return id, nil
+}
+
+// Runs returns the latest limit runs, newest first, or every run if limit
+// isn't positive.
+func (s *BoltStore) Runs(limit int) (runs []Run, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(runsBucket).Cursor()
+		for k, v := c.Last(); k != nil && (limit <= 0 || len(runs) < limit); k, v = c.Prev() {
+			var r Run
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("unmarshaling run: %w", err)
+			}
+			r.ID = int64(binary.BigEndian.Uint64(k)) // #nosec G115 -- written by itob
+			runs = append(runs, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying runs: %w", err)
+	}
+	return runs, nil
+}
+
+// LastSuccessfulRun returns the latest run that didn't fail, or nil if
+// none has.
+func (s *BoltStore) LastSuccessfulRun() (run *Run, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(runsBucket).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var r Run
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("unmarshaling run: %w", err)
+			}
+			if r.Error == "" {
+				r.ID = int64(binary.BigEndian.Uint64(k)) // #nosec G115 -- written by itob
+				run = &r
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying runs: %w", err)
+	}
+	return run, nil
+}
+
+// Close closes the database file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
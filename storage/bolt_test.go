@@ -0,0 +1,393 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestBoltStore(t *testing.T) *BoltStore {
+	t.Helper()
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "test.bolt"))
+	if err != nil {
+		t.Fatalf("NewBoltStore failed: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestBoltSaveAndGet(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	snapshot := &Snapshot{
+		UserID:    "user123",
+		Timestamp: time.Now().Truncate(time.Second),
+		Activity:  map[string]interface{}{"commits": float64(5)},
+	}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if snapshot.ID == 0 {
+		t.Fatal("expected ID to be set after save")
+	}
+
+	retrieved, err := store.Get(snapshot.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if retrieved.UserID != "user123" || !retrieved.Timestamp.Equal(snapshot.Timestamp) {
+		t.Errorf("retrieved %+v, want %+v", retrieved, snapshot)
+	}
+	if retrieved.Activity["commits"] != float64(5) {
+		t.Errorf("expected commits=5, got %v", retrieved.Activity["commits"])
+	}
+}
+
+func TestBoltSaveUpdate(t *testing.T) {
+	store := newTestBoltStore(t)
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	snapshot := &Snapshot{UserID: "user1", Timestamp: base, Activity: map[string]interface{}{"n": float64(1)}}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	id := snapshot.ID
+
+	// Moving it to another user and time should move it in the index too
+	snapshot.UserID = "user2"
+	snapshot.Timestamp = base.Add(time.Hour)
+	snapshot.Activity = map[string]interface{}{"n": float64(2)}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.ID != id {
+		t.Errorf("expected ID %d to be kept, got %d", id, snapshot.ID)
+	}
+
+	if old, err := store.GetByUser("user1", 10); err != nil || len(old) != 0 {
+		t.Errorf("expected user1 to have no snapshots, got %d (%v)", len(old), err)
+	}
+	moved, err := store.GetByUser("user2", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 1 || moved[0].Activity["n"] != float64(2) {
+		t.Errorf("expected the updated snapshot under user2, got %+v", moved)
+	}
+}
+
+func TestBoltGetNotFound(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	if _, err := store.Get(999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := store.Delete(999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound from Delete, got %v", err)
+	}
+}
+
+func TestBoltGetByUser(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	now := time.Now().Truncate(time.Second)
+	// Saved out of order, to check they come back by time rather than ID
+	for _, i := range []int{2, 0, 4, 1, 3} {
+		err := store.Save(&Snapshot{
+			UserID:    "user123",
+			Timestamp: now.Add(time.Duration(i) * time.Hour),
+			Activity:  map[string]interface{}{"index": float64(i)},
+		})
+		if err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+	if err := store.Save(&Snapshot{UserID: "otheruser", Timestamp: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := store.GetByUser("user123", 3)
+	if err != nil {
+		t.Fatalf("GetByUser failed: %v", err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("expected 3 snapshots, got %d", len(snapshots))
+	}
+	for i, want := range []float64{4, 3, 2} {
+		if got := snapshots[i].Activity["index"]; got != want {
+			t.Errorf("snapshot %d has index %v, want %v", i, got, want)
+		}
+	}
+
+	if snapshots, err := store.GetByUser("nobody", 0); err != nil || len(snapshots) != 0 {
+		t.Errorf("GetByUser(nobody) = %d snapshots, %v; want none", len(snapshots), err)
+	}
+}
+
+func TestBoltGetByTimeRange(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := -2; i <= 2; i++ {
+		err := store.Save(&Snapshot{
+			UserID:    "user123",
+			Timestamp: base.Add(time.Duration(i) * 24 * time.Hour),
+			Activity:  map[string]interface{}{"day": float64(i)},
+		})
+		if err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	snapshots, err := store.GetByTimeRange("user123", base.Add(-24*time.Hour), base.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetByTimeRange failed: %v", err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("expected 3 snapshots in range, got %d", len(snapshots))
+	}
+	if snapshots[0].Activity["day"] != float64(1) {
+		t.Errorf("expected newest first, got %v", snapshots[0].Activity["day"])
+	}
+
+	// The zero time is an open start
+	snapshots, err = store.GetByTimeRange("user123", time.Time{}, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 3 {
+		t.Errorf("expected 3 snapshots up to base, got %d", len(snapshots))
+	}
+}
+
+func TestBoltDelete(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	snapshot := &Snapshot{UserID: "user1", Activity: map[string]interface{}{"ok": true}}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(snapshot.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(snapshot.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if snapshots, _ := store.GetByUser("user1", 10); len(snapshots) != 0 {
+		t.Errorf("expected deleted snapshot to leave the index, got %d", len(snapshots))
+	}
+}
+
+func TestBoltSaveAllRollsBackOnError(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	good := &Snapshot{UserID: "user1", Activity: map[string]interface{}{"ok": true}}
+	// Channels can't be marshaled to JSON, so this write fails mid-transaction.
+	bad := &Snapshot{UserID: "user1", Activity: map[string]interface{}{"bad": make(chan int)}}
+
+	if err := store.SaveAll([]*Snapshot{good, bad}); err == nil {
+		t.Fatal("expected error from SaveAll")
+	}
+	if good.ID != 0 {
+		t.Errorf("expected ID to be reset after rollback, got %d", good.ID)
+	}
+	if snapshots, _ := store.GetByUser("user1", 10); len(snapshots) != 0 {
+		t.Errorf("expected rollback to leave no snapshots, got %d", len(snapshots))
+	}
+
+	if err := store.Save(nil); err == nil {
+		t.Error("expected error saving nil snapshot")
+	}
+}
+
+func TestBoltSaveVersionedData(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	snapshot := &Snapshot{
+		UserID:        "user1",
+		Timestamp:     time.Now(),
+		SchemaVersion: 2,
+		Data:          json.RawMessage(`{"users":["a","b"]}`),
+	}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	retrieved, err := store.Get(snapshot.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if retrieved.SchemaVersion != 2 || string(retrieved.Data) != string(snapshot.Data) {
+		t.Errorf("retrieved version %d data %s, want 2 %s", retrieved.SchemaVersion, retrieved.Data, snapshot.Data)
+	}
+
+	if err := store.Save(&Snapshot{UserID: "user1", SchemaVersion: 1}); err == nil {
+		t.Error("expected error for versioned snapshot without data")
+	}
+}
+
+func TestBoltSavePartial(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	snapshot := &Snapshot{UserID: "user1", Timestamp: time.Now(), Partial: true}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	retrieved, err := store.Get(snapshot.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !retrieved.Partial {
+		t.Error("expected the snapshot to stay partial")
+	}
+}
+
+func TestBoltPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.bolt")
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(&Snapshot{UserID: "user1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetMeta("key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	if snapshots, err := store.GetByUser("user1", 10); err != nil || len(snapshots) != 1 {
+		t.Errorf("expected the snapshot to survive reopening, got %d (%v)", len(snapshots), err)
+	}
+	if value, err := store.GetMeta("key"); err != nil || value != "value" {
+		t.Errorf("GetMeta = %q, %v; want value", value, err)
+	}
+	// New snapshots don't reuse IDs
+	next := &Snapshot{UserID: "user1"}
+	if err := store.Save(next); err != nil || next.ID != 2 {
+		t.Errorf("expected ID 2 after reopening, got %d (%v)", next.ID, err)
+	}
+}
+
+func TestBoltPendingDiffs(t *testing.T) {
+	store := newTestBoltStore(t)
+	base := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	var ids []int64
+	for i, data := range []string{`{"a":1}`, `{"b":2}`, `{"c":3}`} {
+		id, err := store.QueueDiff(base.Add(time.Duration(i)*time.Hour), []byte(data))
+		if err != nil {
+			t.Fatalf("QueueDiff failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	diffs, err := store.PendingDiffs()
+	if err != nil {
+		t.Fatalf("PendingDiffs failed: %v", err)
+	}
+	if len(diffs) != 3 || string(diffs[0].Data) != `{"a":1}` || !diffs[2].CapturedAt.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("unexpected pending diffs: %+v", diffs)
+	}
+
+	if err := store.ClearPendingDiffs(ids[1]); err != nil {
+		t.Fatalf("ClearPendingDiffs failed: %v", err)
+	}
+	diffs, err = store.PendingDiffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].ID != ids[2] {
+		t.Errorf("expected only the last diff to remain, got %+v", diffs)
+	}
+}
+
+func TestBoltStoreInterface(t *testing.T) {
+	var _ Store = (*BoltStore)(nil)
+}
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn, backend, path string
+	}{
+		{"/data/gitstreams.db", BackendSQLite, "/data/gitstreams.db"},
+		{"relative.db", BackendSQLite, "relative.db"},
+		{":memory:", BackendSQLite, ":memory:"},
+		{"sqlite:///data/gitstreams.db", BackendSQLite, "/data/gitstreams.db"},
+		{"bolt:///data/gitstreams.bolt", BackendBolt, "/data/gitstreams.bolt"},
+		{"bolt://gitstreams.bolt", BackendBolt, "gitstreams.bolt"},
+	}
+	for _, tt := range tests {
+		backend, path := ParseDSN(tt.dsn)
+		if backend != tt.backend || path != tt.path {
+			t.Errorf("ParseDSN(%q) = %q, %q; want %q, %q", tt.dsn, backend, path, tt.backend, tt.path)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := Open("bolt://" + filepath.Join(dir, "test.bolt"))
+	if err != nil {
+		t.Fatalf("Open(bolt) failed: %v", err)
+	}
+	if _, ok := store.(*BoltStore); !ok {
+		t.Errorf("expected a BoltStore, got %T", store)
+	}
+	_ = store.Close()
+
+	store, err = Open(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("Open(sqlite) failed: %v", err)
+	}
+	if _, ok := store.(*SQLiteStore); !ok {
+		t.Errorf("expected a SQLiteStore, got %T", store)
+	}
+	_ = store.Close()
+
+	if _, err := Open("postgres://localhost/gitstreams"); err == nil || !strings.Contains(err.Error(), "unknown database backend") {
+		t.Errorf("expected unknown backend error, got %v", err)
+	}
+	if _, err := Open("bolt://"); err == nil {
+		t.Error("expected error for a DSN without a path")
+	}
+}
+
+func TestBoltNewerSchemaRefused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.bolt")
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := &Snapshot{UserID: "user1", AppVersion: "v9.0.0"}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Get(snapshot.ID); got.AppVersion != "v9.0.0" {
+		t.Errorf("expected app version v9.0.0, got %q", got.AppVersion)
+	}
+	if err := store.SetMeta(string(boltSchemaKey), "99"); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	_, err = NewBoltStore(path)
+	var schemaErr *NewerSchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected a NewerSchemaError, got %v", err)
+	}
+	if schemaErr.Version != 99 || schemaErr.WrittenBy != "v9.0.0" {
+		t.Errorf("unexpected error %+v", schemaErr)
+	}
+}
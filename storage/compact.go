@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"fmt"
+)
+
+// CompactResult describes what Compact removed, or would remove on a dry
+// run.
+type CompactResult struct {
+	Removed     map[string]int // Snapshots removed, by the reason given for each
+	BytesBefore int64
+	BytesAfter  int64 // Same as BytesBefore on a dry run
+}
+
+// Total returns how many snapshots were removed.
+func (r *CompactResult) Total() int {
+	total := 0
+	for _, n := range r.Removed {
+		total += n
+	}
+	return total
+}
+
+// Compact removes the snapshots garbage gives a reason for, then vacuums
+// the database to hand the freed space back to the filesystem. A snapshot
+// garbage returns "" for is kept. With dryRun set, nothing is changed.
+func (s *SQLiteStore) Compact(garbage func(*Snapshot) string, dryRun bool) (result *CompactResult, err error) {
+	result = &CompactResult{Removed: make(map[string]int)}
+	if result.BytesBefore, err = s.size(); err != nil {
+		return nil, err
+	}
+	result.BytesAfter = result.BytesBefore
+
+	ids, err := s.garbageIDs(garbage, result.Removed)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec("DELETE FROM snapshots WHERE id = ?", id); err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("deleting snapshot %d: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	// VACUUM can't run in a transaction, so it comes after the deletes
+	if _, err := s.db.Exec("VACUUM"); err != nil {
+		return nil, fmt.Errorf("vacuuming: %w", err)
+	}
+	if result.BytesAfter, err = s.size(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// garbageIDs returns the IDs of the snapshots garbage gives a reason for,
+// counting them by reason in removed.
+func (s *SQLiteStore) garbageIDs(garbage func(*Snapshot) string, removed map[string]int) (ids []int64, err error) {
+	rows, err := s.db.Query("SELECT id, user_id, timestamp, activity_json, schema_version, data, app_version, partial FROM snapshots ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots: %w", err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		snapshot, err := scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		if reason := garbage(snapshot); reason != "" {
+			ids = append(ids, snapshot.ID)
+			removed[reason]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return ids, nil
+}
+
+// size returns how many bytes the database takes up, going by SQLite's page
+// count rather than the file so it works for in-memory databases too.
+func (s *SQLiteStore) size() (int64, error) {
+	var pages, pageSize int64
+	if err := s.db.QueryRow("PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, fmt.Errorf("reading page count: %w", err)
+	}
+	if err := s.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("reading page size: %w", err)
+	}
+	return pages * pageSize, nil
+}
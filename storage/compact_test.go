@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompact(t *testing.T) {
+	store := newTestStore(t)
+
+	padding := strings.Repeat("x", 64*1024)
+	for _, userID := range []string{"keep", "drop", "drop", "keep"} {
+		err := store.Save(&Snapshot{UserID: userID, Timestamp: time.Now(), Activity: map[string]interface{}{"padding": padding}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	garbage := func(s *Snapshot) string {
+		if s.UserID == "drop" {
+			return "dropped"
+		}
+		return ""
+	}
+
+	result, err := store.Compact(garbage, true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if result.Total() != 2 || result.Removed["dropped"] != 2 || result.BytesAfter != result.BytesBefore {
+		t.Errorf("unexpected dry run result %+v", result)
+	}
+	if snapshots, _ := store.GetByUser("drop", 10); len(snapshots) != 2 {
+		t.Errorf("dry run removed snapshots; %d left", len(snapshots))
+	}
+
+	result, err = store.Compact(garbage, false)
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if result.Total() != 2 {
+		t.Errorf("expected 2 removed, got %+v", result)
+	}
+	if result.BytesAfter >= result.BytesBefore {
+		t.Errorf("expected vacuuming to shrink the database, went from %d to %d bytes", result.BytesBefore, result.BytesAfter)
+	}
+	if snapshots, _ := store.GetByUser("drop", 10); len(snapshots) != 0 {
+		t.Errorf("expected dropped snapshots to be gone, %d left", len(snapshots))
+	}
+	if snapshots, _ := store.GetByUser("keep", 10); len(snapshots) != 2 {
+		t.Errorf("expected kept snapshots to stay, got %d", len(snapshots))
+	}
+}
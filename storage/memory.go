@@ -0,0 +1,462 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore implements Store in memory, for tests and throwaway CI runs.
+// Snapshots round-trip through JSON as they do in the other stores, so
+// activity numbers come back as float64 and callers can't share maps with
+// the store. Its contents can be dumped to and loaded from JSON.
+type MemoryStore struct {
+	snapshots  map[int64][]byte
+	meta       map[string]string
+	reported   map[string]time.Time
+	now        func() time.Time // Stamps snapshots saved without a timestamp
+	path       string           // Where Close dumps to; empty for none
+	pending    []PendingDiff
+	runs       []Run
+	nextID     int64
+	nextDiffID int64
+	mu         sync.Mutex
+}
+
+// memoryDump is the JSON form of a MemoryStore.
+type memoryDump struct {
+	Meta         map[string]string    `json:"meta,omitempty"`
+	Reported     map[string]time.Time `json:"reported_changes,omitempty"`
+	Snapshots    []snapshotRecord     `json:"snapshots"`
+	PendingDiffs []pendingRecord      `json:"pending_diffs,omitempty"`
+	Runs         []Run                `json:"runs,omitempty"`
+	NextID       int64                `json:"next_id"`
+	NextDiffID   int64                `json:"next_diff_id"`
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		snapshots: make(map[int64][]byte),
+		meta:      make(map[string]string),
+		reported:  make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// SetNow sets the clock snapshots saved without a timestamp are stamped
+// with. It defaults to time.Now.
+func (s *MemoryStore) SetNow(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// OpenMemoryStore creates an in-memory store backed by a JSON file: it's
+// loaded from path if that exists and dumped back there on Close.
+func OpenMemoryStore(path string) (*MemoryStore, error) {
+	store := NewMemoryStore()
+	store.path = path
+
+	f, err := os.Open(path) // #nosec G304 -- path is user-specified via -db
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if err := store.Load(f); err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Save stores a snapshot. If the snapshot has no ID, a new record is created.
+// On insert, the snapshot's ID is updated with the generated value.
+func (s *MemoryStore) Save(snapshot *Snapshot) error {
+	return s.SaveAll([]*Snapshot{snapshot})
+}
+
+// SaveAll stores several snapshots at once. Either every snapshot is saved
+// or, if any can't be, none are.
+func (s *MemoryStore) SaveAll(snapshots []*Snapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	now := s.now
+	s.mu.Unlock()
+
+	// Encode everything before touching the store so a failure leaves it as
+	// it was
+	values := make([][]byte, len(snapshots))
+	stamps := make([]time.Time, len(snapshots))
+	for i, snapshot := range snapshots {
+		if snapshot == nil && len(snapshots) > 1 {
+			return fmt.Errorf("snapshot %d cannot be nil", i)
+		}
+		if err := checkSnapshot(snapshot); err != nil {
+			return err
+		}
+		stamped := *snapshot
+		if stamped.Timestamp.IsZero() {
+			stamped.Timestamp = now()
+		}
+		value, err := encodeSnapshot(&stamped)
+		if err != nil {
+			return err
+		}
+		values[i], stamps[i] = value, stamped.Timestamp
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, snapshot := range snapshots {
+		snapshot.Timestamp = stamps[i]
+		if snapshot.ID == 0 {
+			s.nextID++
+			snapshot.ID = s.nextID
+		} else if _, ok := s.snapshots[snapshot.ID]; !ok {
+			// Matches SQLite, where updating a missing row is a no-op
+			continue
+		}
+		s.snapshots[snapshot.ID] = values[i]
+	}
+	return nil
+}
+
+// Get retrieves a snapshot by ID.
+func (s *MemoryStore) Get(id int64) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.snapshots[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return decodeSnapshot(id, value)
+}
+
+// GetByUser retrieves the most recent snapshots for a user, up to limit.
+func (s *MemoryStore) GetByUser(userID string, limit int) ([]*Snapshot, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	snapshots, err := s.find(func(snapshot *Snapshot) bool { return snapshot.UserID == userID })
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots: %w", err)
+	}
+	if len(snapshots) > limit {
+		snapshots = snapshots[:limit]
+	}
+	return snapshots, nil
+}
+
+// GetByTimeRange retrieves snapshots for a user within a time range.
+func (s *MemoryStore) GetByTimeRange(userID string, start, end time.Time) ([]*Snapshot, error) {
+	snapshots, err := s.find(func(snapshot *Snapshot) bool {
+		return snapshot.UserID == userID && !snapshot.Timestamp.Before(start) && !snapshot.Timestamp.After(end)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots by time range: %w", err)
+	}
+	return snapshots, nil
+}
+
+// find returns the snapshots match accepts, newest first.
+func (s *MemoryStore) find(match func(*Snapshot) bool) ([]*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var snapshots []*Snapshot
+	for id, value := range s.snapshots {
+		snapshot, err := decodeSnapshot(id, value)
+		if err != nil {
+			return nil, err
+		}
+		if match(snapshot) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].Timestamp.Equal(snapshots[j].Timestamp) {
+			return snapshots[i].Timestamp.After(snapshots[j].Timestamp)
+		}
+		return snapshots[i].ID > snapshots[j].ID
+	})
+	return snapshots, nil
+}
+
+// Delete removes a snapshot by ID.
+func (s *MemoryStore) Delete(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.snapshots[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.snapshots, id)
+	return nil
+}
+
+// QueueDiff stores a change set until the next digest is delivered.
+func (s *MemoryStore) QueueDiff(capturedAt time.Time, data []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextDiffID++
+	s.pending = append(s.pending, PendingDiff{ID: s.nextDiffID, CapturedAt: capturedAt, Data: append([]byte(nil), data...)})
+	return s.nextDiffID, nil
+}
+
+// PendingDiffs returns every queued change set, oldest first.
+func (s *MemoryStore) PendingDiffs() ([]PendingDiff, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	diffs := make([]PendingDiff, len(s.pending))
+	for i, d := range s.pending {
+		d.Data = append([]byte(nil), d.Data...)
+		diffs[i] = d
+	}
+	return diffs, nil
+}
+
+// ClearPendingDiffs removes queued change sets up to and including
+// throughID. Anything queued after them is kept.
+func (s *MemoryStore) ClearPendingDiffs(throughID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.pending[:0]
+	for _, d := range s.pending {
+		if d.ID > throughID {
+			kept = append(kept, d)
+		}
+	}
+	s.pending = kept
+	return nil
+}
+
+// GetMeta returns the value stored under key, or "" if there is none.
+func (s *MemoryStore) GetMeta(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.meta[key], nil
+}
+
+// SetMeta stores value under key, replacing any earlier value.
+func (s *MemoryStore) SetMeta(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meta[key] = value
+	return nil
+}
+
+// MarkReported records content hashes of changes that have gone out in a
+// report. Hashes already recorded keep their first time.
+func (s *MemoryStore) MarkReported(hashes []string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range hashes {
+		if _, ok := s.reported[h]; !ok {
+			s.reported[h] = at
+		}
+	}
+	return nil
+}
+
+// Reported returns which of hashes MarkReported has recorded.
+func (s *MemoryStore) Reported(hashes []string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reported := make(map[string]bool)
+	for _, h := range hashes {
+		if _, ok := s.reported[h]; ok {
+			reported[h] = true
+		}
+	}
+	return reported, nil
+}
+
+// PruneReported forgets changes reported before cutoff and returns how many
+// there were. With dryRun set, it only counts them.
+func (s *MemoryStore) PruneReported(cutoff time.Time, dryRun bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for h, at := range s.reported {
+		if at.Before(cutoff) {
+			n++
+			if !dryRun {
+				delete(s.reported, h)
+			}
+		}
+	}
+	return n, nil
+}
+
+// RecordRun adds run to the run history and returns its ID. Its ID field
+// is ignored.
+func (s *MemoryStore) RecordRun(run Run) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run.ID = 1
+	if len(s.runs) > 0 {
+		run.ID = s.runs[len(s.runs)-1].ID + 1
+	}
+	s.runs = append(s.runs, run)
+	return run.ID, nil
+}
+
+// Runs returns the latest limit runs, newest first, or every run if limit
+// isn't positive.
+func (s *MemoryStore) Runs(limit int) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := slices.Clone(s.runs)
+	slices.Reverse(runs)
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// LastSuccessfulRun returns the latest run that didn't fail, or nil if
+// none has.
+func (s *MemoryStore) LastSuccessfulRun() (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		if s.runs[i].Error == "" {
+			r := s.runs[i]
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+// Dump writes the store's contents to w as JSON.
+func (s *MemoryStore) Dump(w io.Writer) error {
+	dump, err := s.dump()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dump); err != nil {
+		return fmt.Errorf("writing dump: %w", err)
+	}
+	return nil
+}
+
+// dump copies the store's contents into their JSON form.
+func (s *MemoryStore) dump() (*memoryDump, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dump := &memoryDump{
+		Snapshots:  make([]snapshotRecord, 0, len(s.snapshots)),
+		Meta:       maps.Clone(s.meta),
+		Reported:   maps.Clone(s.reported),
+		Runs:       slices.Clone(s.runs),
+		NextID:     s.nextID,
+		NextDiffID: s.nextDiffID,
+	}
+	for id, value := range s.snapshots {
+		var record snapshotRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return nil, fmt.Errorf("unmarshaling snapshot %d: %w", id, err)
+		}
+		record.ID = id
+		dump.Snapshots = append(dump.Snapshots, record)
+	}
+	sort.Slice(dump.Snapshots, func(i, j int) bool { return dump.Snapshots[i].ID < dump.Snapshots[j].ID })
+	for _, d := range s.pending {
+		dump.PendingDiffs = append(dump.PendingDiffs, pendingRecord{ID: d.ID, CapturedAt: d.CapturedAt, Data: d.Data})
+	}
+	return dump, nil
+}
+
+// Load replaces the store's contents with a dump read from r.
+func (s *MemoryStore) Load(r io.Reader) error {
+	var dump memoryDump
+	if err := json.NewDecoder(r).Decode(&dump); err != nil {
+		return fmt.Errorf("reading dump: %w", err)
+	}
+
+	snapshots := make(map[int64][]byte, len(dump.Snapshots))
+	nextID := dump.NextID
+	for _, record := range dump.Snapshots {
+		if record.ID <= 0 {
+			return fmt.Errorf("snapshot has invalid id %d", record.ID)
+		}
+		if _, dup := snapshots[record.ID]; dup {
+			return fmt.Errorf("snapshot %d appears twice", record.ID)
+		}
+		snapshot := record.snapshot(record.ID)
+		if err := checkSnapshot(snapshot); err != nil {
+			return fmt.Errorf("snapshot %d: %w", record.ID, err)
+		}
+		value, err := encodeSnapshot(snapshot)
+		if err != nil {
+			return err
+		}
+		snapshots[record.ID] = value
+		nextID = max(nextID, record.ID)
+	}
+	pending := make([]PendingDiff, 0, len(dump.PendingDiffs))
+	nextDiffID := dump.NextDiffID
+	for _, d := range dump.PendingDiffs {
+		pending = append(pending, PendingDiff{ID: d.ID, CapturedAt: d.CapturedAt, Data: d.Data})
+		nextDiffID = max(nextDiffID, d.ID)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	meta := dump.Meta
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+	reported := dump.Reported
+	if reported == nil {
+		reported = make(map[string]time.Time)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots, s.pending, s.meta, s.reported = snapshots, pending, meta, reported
+	s.runs = dump.Runs
+	sort.Slice(s.runs, func(i, j int) bool { return s.runs[i].ID < s.runs[j].ID })
+	s.nextID, s.nextDiffID = nextID, nextDiffID
+	return nil
+}
+
+// Close dumps the store to its file, if it has one. The dump is written
+// to a temporary file first so a failed write leaves the old one intact.
+func (s *MemoryStore) Close() (err error) {
+	if s.path == "" {
+		return nil
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("saving database: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+	if err := s.Dump(f); err != nil {
+		return fmt.Errorf("saving database: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("saving database: %w", err)
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		return fmt.Errorf("saving database: %w", err)
+	}
+	return nil
+}
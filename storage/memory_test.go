@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemorySaveAndGet(t *testing.T) {
+	store := NewMemoryStore()
+
+	activity := map[string]interface{}{"commits": 5}
+	snapshot := &Snapshot{UserID: "user123", Timestamp: time.Now().Truncate(time.Second), Activity: activity}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if snapshot.ID != 1 {
+		t.Fatalf("expected ID 1, got %d", snapshot.ID)
+	}
+	// The store keeps its own copy
+	activity["commits"] = 6
+
+	retrieved, err := store.Get(snapshot.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if retrieved.Activity["commits"] != float64(5) {
+		t.Errorf("expected commits=5 as float64 like SQLite, got %#v", retrieved.Activity["commits"])
+	}
+	if _, err := store.Get(999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemorySaveDefaultsAndErrors(t *testing.T) {
+	store := NewMemoryStore()
+
+	snapshot := &Snapshot{UserID: "user1"}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Timestamp.IsZero() {
+		t.Error("expected a default timestamp")
+	}
+	if err := store.Save(nil); err == nil {
+		t.Error("expected error saving nil snapshot")
+	}
+	if err := store.Save(&Snapshot{UserID: "user1", SchemaVersion: 1}); err == nil {
+		t.Error("expected error for versioned snapshot without data")
+	}
+
+	good := &Snapshot{UserID: "user1"}
+	bad := &Snapshot{UserID: "user1", Activity: map[string]interface{}{"bad": make(chan int)}}
+	if err := store.SaveAll([]*Snapshot{good, bad}); err == nil {
+		t.Fatal("expected error from SaveAll")
+	}
+	if good.ID != 0 {
+		t.Errorf("expected no ID after a failed SaveAll, got %d", good.ID)
+	}
+	if snapshots, _ := store.GetByUser("user1", 10); len(snapshots) != 1 {
+		t.Errorf("expected failed SaveAll to save nothing, got %d snapshots", len(snapshots))
+	}
+}
+
+func TestMemoryGetByUserAndTimeRange(t *testing.T) {
+	store := NewMemoryStore()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, i := range []int{2, 0, 4, 1, 3} {
+		err := store.Save(&Snapshot{
+			UserID:    "user123",
+			Timestamp: base.Add(time.Duration(i) * 24 * time.Hour),
+			Activity:  map[string]interface{}{"day": float64(i)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Save(&Snapshot{UserID: "otheruser", Timestamp: base}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := store.GetByUser("user123", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Activity["day"] != float64(4) || snapshots[1].Activity["day"] != float64(3) {
+		t.Errorf("expected days 4 and 3, got %+v", snapshots)
+	}
+
+	snapshots, err = store.GetByTimeRange("user123", base.Add(24*time.Hour), base.Add(3*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 3 || snapshots[0].Activity["day"] != float64(3) {
+		t.Errorf("expected days 3 to 1, newest first, got %+v", snapshots)
+	}
+}
+
+func TestMemoryDelete(t *testing.T) {
+	store := NewMemoryStore()
+
+	snapshot := &Snapshot{UserID: "user1"}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(snapshot.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete(snapshot.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestMemoryPendingDiffsAndMeta(t *testing.T) {
+	store := NewMemoryStore()
+
+	var ids []int64
+	for _, data := range []string{`{"a":1}`, `{"b":2}`, `{"c":3}`} {
+		id, err := store.QueueDiff(time.Now(), []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := store.ClearPendingDiffs(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := store.PendingDiffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].ID != ids[2] || string(diffs[0].Data) != `{"c":3}` {
+		t.Errorf("expected only the last diff to remain, got %+v", diffs)
+	}
+
+	if value, _ := store.GetMeta("key"); value != "" {
+		t.Errorf("GetMeta on missing key = %q, want empty", value)
+	}
+	_ = store.SetMeta("key", "value")
+	if value, _ := store.GetMeta("key"); value != "value" {
+		t.Errorf("GetMeta = %q, want value", value)
+	}
+}
+
+func TestMemoryDumpAndLoad(t *testing.T) {
+	store := NewMemoryStore()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.Save(&Snapshot{UserID: "user1", Timestamp: base, Activity: map[string]interface{}{"n": 1}}); err != nil {
+		t.Fatal(err)
+	}
+	versioned := &Snapshot{UserID: "user1", Timestamp: base.Add(time.Hour), SchemaVersion: 2, Data: json.RawMessage(`{"users":[]}`)}
+	if err := store.Save(versioned); err != nil {
+		t.Fatal(err)
+	}
+	deleted := &Snapshot{UserID: "user1", Timestamp: base}
+	_ = store.Save(deleted)
+	_ = store.Delete(deleted.ID)
+	_, _ = store.QueueDiff(base, []byte(`{"a":1}`))
+	_ = store.SetMeta("key", "value")
+	_ = store.MarkReported([]string{"hash"}, base)
+
+	var buf bytes.Buffer
+	if err := store.Dump(&buf); err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+
+	loaded := NewMemoryStore()
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	snapshots, err := loaded.GetByUser("user1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots after loading, got %d", len(snapshots))
+	}
+	if snapshots[0].ID != versioned.ID || string(snapshots[0].Data) != `{"users":[]}` {
+		t.Errorf("expected the versioned snapshot first, got %+v", snapshots[0])
+	}
+	if snapshots[1].Activity["n"] != float64(1) || !snapshots[1].Timestamp.Equal(base) {
+		t.Errorf("expected the legacy snapshot second, got %+v", snapshots[1])
+	}
+	if diffs, _ := loaded.PendingDiffs(); len(diffs) != 1 {
+		t.Errorf("expected 1 pending diff after loading, got %d", len(diffs))
+	}
+	if value, _ := loaded.GetMeta("key"); value != "value" {
+		t.Errorf("GetMeta after loading = %q, want value", value)
+	}
+	if reported, _ := loaded.Reported([]string{"hash"}); !reported["hash"] {
+		t.Error("expected the reported change to survive loading")
+	}
+
+	// IDs carry on past the deleted snapshot rather than reusing its ID
+	next := &Snapshot{UserID: "user1"}
+	if err := loaded.Save(next); err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != deleted.ID+1 {
+		t.Errorf("expected ID %d, got %d", deleted.ID+1, next.ID)
+	}
+}
+
+func TestMemoryLoadRejectsBadDumps(t *testing.T) {
+	for _, dump := range []string{
+		`not json`,
+		`{"snapshots":[{"user_id":"a"}]}`,
+		`{"snapshots":[{"id":1,"user_id":"a"},{"id":1,"user_id":"b"}]}`,
+		`{"snapshots":[{"id":1,"user_id":"a","schema_version":2}]}`,
+	} {
+		store := NewMemoryStore()
+		_ = store.Save(&Snapshot{UserID: "kept"})
+		if err := store.Load(bytes.NewBufferString(dump)); err == nil {
+			t.Errorf("Load(%s) succeeded, want error", dump)
+		}
+		if snapshots, _ := store.GetByUser("kept", 10); len(snapshots) != 1 {
+			t.Errorf("Load(%s) changed the store after failing", dump)
+		}
+	}
+}
+
+func TestOpenMemoryStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+
+	store, err := OpenMemoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenMemoryStore on a missing file failed: %v", err)
+	}
+	if err := store.Save(&Snapshot{UserID: "user1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	store, err = OpenMemoryStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshots, _ := store.GetByUser("user1", 10); len(snapshots) != 1 {
+		t.Errorf("expected the snapshot to survive reopening, got %d", len(snapshots))
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenMemoryStore(path); err == nil {
+		t.Error("expected error opening a corrupt dump")
+	}
+}
+
+func TestOpenMemory(t *testing.T) {
+	store, err := Open("memory://")
+	if err != nil {
+		t.Fatalf("Open(memory://) failed: %v", err)
+	}
+	if _, ok := store.(*MemoryStore); !ok {
+		t.Errorf("expected a MemoryStore, got %T", store)
+	}
+	if err := store.Close(); err != nil {
+		t.Errorf("Close without a file failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "db.json")
+	store, err = Open("memory://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected Close to write %s: %v", path, err)
+	}
+}
+
+func TestMemoryStoreInterface(t *testing.T) {
+	var _ Store = (*MemoryStore)(nil)
+}
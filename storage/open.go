@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Backends Open can select with a URL scheme.
+const (
+	BackendSQLite = "sqlite"
+	BackendBolt   = "bolt"
+	BackendMemory = "memory"
+)
+
+// ParseDSN splits a database location into its backend and file path.
+// "bolt://path" selects Bolt, "memory://" or "memory://path.json" a
+// MemoryStore, and "sqlite://path" or a plain path SQLite.
+func ParseDSN(dsn string) (backend, path string) {
+	if scheme, rest, ok := strings.Cut(dsn, "://"); ok {
+		return scheme, rest
+	}
+	return BackendSQLite, dsn
+}
+
+// Open opens the store dsn names, as parsed by ParseDSN.
+func Open(dsn string) (Store, error) {
+	backend, path := ParseDSN(dsn)
+	if backend == BackendMemory {
+		if path == "" {
+			return NewMemoryStore(), nil
+		}
+		return OpenMemoryStore(path)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("database %q has no path", dsn)
+	}
+	switch backend {
+	case BackendSQLite:
+		return NewSQLiteStore(path)
+	case BackendBolt:
+		return NewBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unknown database backend %q (supported: %s, %s, %s)", backend, BackendSQLite, BackendBolt, BackendMemory)
+	}
+}
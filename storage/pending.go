@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PendingDiff is a change set queued between digests. Like versioned
+// snapshot data, Data is opaque to the store.
+type PendingDiff struct {
+	CapturedAt time.Time
+	Data       []byte
+	ID         int64
+}
+
+// QueueDiff appends a change set to the pending queue and returns its ID.
+func (s *SQLiteStore) QueueDiff(capturedAt time.Time, data []byte) (int64, error) {
+	result, err := s.db.Exec("INSERT INTO pending_diffs (captured_at, data) VALUES (?, ?)", capturedAt, data)
+	if err != nil {
+		return 0, fmt.Errorf("queueing diff: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("getting last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// PendingDiffs returns every queued change set, oldest first.
+func (s *SQLiteStore) PendingDiffs() (diffs []PendingDiff, err error) {
+	rows, err := s.db.Query("SELECT id, captured_at, data FROM pending_diffs ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("querying pending diffs: %w", err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var d PendingDiff
+		if err := rows.Scan(&d.ID, &d.CapturedAt, &d.Data); err != nil {
+			return nil, fmt.Errorf("scanning pending diff: %w", err)
+		}
+		diffs = append(diffs, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return diffs, nil
+}
+
+// ClearPendingDiffs removes queued change sets up to and including
+// throughID. Anything queued after them is kept.
+func (s *SQLiteStore) ClearPendingDiffs(throughID int64) error {
+	if _, err := s.db.Exec("DELETE FROM pending_diffs WHERE id <= ?", throughID); err != nil {
+		return fmt.Errorf("clearing pending diffs: %w", err)
+	}
+	return nil
+}
+
+// GetMeta returns the value stored under key, or "" if there is none.
+func (s *SQLiteStore) GetMeta(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM meta WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// SetMeta stores value under key, replacing any earlier value.
+func (s *SQLiteStore) SetMeta(key, value string) error {
+	_, err := s.db.Exec("INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value", key, value)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPendingDiffs(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	var ids []int64
+	for i, data := range []string{`{"a":1}`, `{"b":2}`, `{"c":3}`} {
+		id, err := store.QueueDiff(base.Add(time.Duration(i)*time.Hour), []byte(data))
+		if err != nil {
+			t.Fatalf("QueueDiff failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	diffs, err := store.PendingDiffs()
+	if err != nil {
+		t.Fatalf("PendingDiffs failed: %v", err)
+	}
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 pending diffs, got %d", len(diffs))
+	}
+	if string(diffs[0].Data) != `{"a":1}` || !diffs[2].CapturedAt.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("unexpected pending diffs: %+v", diffs)
+	}
+
+	if err := store.ClearPendingDiffs(ids[1]); err != nil {
+		t.Fatalf("ClearPendingDiffs failed: %v", err)
+	}
+	diffs, err = store.PendingDiffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].ID != ids[2] {
+		t.Errorf("expected only the last diff to remain, got %+v", diffs)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	store := newTestStore(t)
+
+	value, err := store.GetMeta("last_digest")
+	if err != nil || value != "" {
+		t.Errorf("GetMeta on missing key = %q, %v; want empty", value, err)
+	}
+
+	if err := store.SetMeta("last_digest", "one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetMeta("last_digest", "two"); err != nil {
+		t.Fatal(err)
+	}
+	value, err = store.GetMeta("last_digest")
+	if err != nil || value != "two" {
+		t.Errorf("GetMeta = %q, %v; want two", value, err)
+	}
+}
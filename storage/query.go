@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+)
+
+// viewsSchema defines the documented views over the normalized tables,
+// which ad-hoc queries should use instead of the tables themselves. Columns
+// may be added to them, but existing ones keep their names and meaning.
+const viewsSchema = `
+DROP VIEW IF EXISTS v_activities;
+CREATE VIEW v_activities AS
+	SELECT scope, username, kind, event_type, repo, details, occurred_at, first_seen
+	FROM activities;
+DROP VIEW IF EXISTS v_stars;
+CREATE VIEW v_stars AS
+	SELECT scope, username, repo,
+		substr(repo, 1, instr(repo, '/') - 1) AS owner,
+		substr(repo, instr(repo, '/') + 1) AS name,
+		details AS description, occurred_at, first_seen
+	FROM activities WHERE kind = 'star';
+DROP VIEW IF EXISTS v_repos;
+CREATE VIEW v_repos AS
+	SELECT scope, username AS owner, repo,
+		substr(repo, instr(repo, '/') + 1) AS name,
+		details AS description, occurred_at AS created_at, first_seen
+	FROM activities WHERE kind = 'repo';
+`
+
+// QueryResult holds the rows of an ad-hoc query. Values are nil, int64,
+// float64 or string.
+type QueryResult struct {
+	Columns []string
+	Rows    [][]any
+}
+
+// QueryReadOnly runs an ad-hoc query against the database at dbPath,
+// opened read-only so a stray UPDATE or DROP can't damage it. Open the
+// database with NewSQLiteStore first so the views exist.
+func QueryReadOnly(ctx context.Context, dbPath string, query string) (result *QueryResult, err error) {
+	db, err := openReadOnly(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	result = &QueryResult{}
+	if result.Columns, err = rows.Columns(); err != nil {
+		return nil, fmt.Errorf("reading columns: %w", err)
+	}
+	for rows.Next() {
+		values := make([]any, len(result.Columns))
+		ptrs := make([]any, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// openReadOnly opens the SQLite database at dbPath without running
+// migrations, in a mode SQLite itself won't let write.
+func openReadOnly(dbPath string) (*sql.DB, error) {
+	abs, err := filepath.Abs(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("resolving database path: %w", err)
+	}
+	dsn := url.URL{Scheme: "file", Path: abs, RawQuery: "mode=ro"}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	return db, nil
+}
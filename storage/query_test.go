@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newQueryDB creates a database file with a few activities for ad-hoc
+// queries.
+func newQueryDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "query test.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	at := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	_, err = store.SaveActivities([]Activity{
+		{Scope: "s", Username: "alice", Kind: KindStar, Repo: "golang/go", Key: "golang/go", Details: "The Go language", OccurredAt: at, FirstSeen: at},
+		{Scope: "s", Username: "alice", Kind: KindRepo, Repo: "alice/tool", Key: "alice/tool", OccurredAt: at, FirstSeen: at},
+		{Scope: "s", Username: "alice", Kind: KindEvent, EventType: "PushEvent", Repo: "alice/tool", Key: "1", OccurredAt: at, FirstSeen: at},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetMeta("k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestViews(t *testing.T) {
+	path := newQueryDB(t)
+	tests := []struct {
+		query string
+		want  []any
+	}{
+		{"SELECT COUNT(*) FROM v_activities", []any{int64(3)}},
+		{"SELECT owner, name, description, occurred_at FROM v_stars", []any{"golang", "go", "The Go language", "2026-01-15 09:00:00"}},
+		{"SELECT owner, name, created_at FROM v_repos", []any{"alice", "tool", "2026-01-15 09:00:00"}},
+	}
+	for _, tt := range tests {
+		result, err := QueryReadOnly(context.Background(), path, tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if len(result.Rows) != 1 || len(result.Rows[0]) != len(tt.want) {
+			t.Fatalf("%s: unexpected rows %v", tt.query, result.Rows)
+		}
+		for i, v := range result.Rows[0] {
+			if v != tt.want[i] {
+				t.Errorf("%s: column %s = %#v, want %#v", tt.query, result.Columns[i], v, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestQueryReadOnly_RefusesWrites(t *testing.T) {
+	path := newQueryDB(t)
+	for _, q := range []string{
+		"DELETE FROM meta",
+		"DROP TABLE activities",
+		"INSERT INTO meta VALUES ('k2', 'v')",
+		"PRAGMA query_only = OFF; DELETE FROM meta",
+	} {
+		if _, err := QueryReadOnly(context.Background(), path, q); err == nil {
+			t.Errorf("expected %q to be refused", q)
+		}
+	}
+	if _, err := QueryReadOnly(context.Background(), path, "SELEKT"); err == nil {
+		t.Error("expected a syntax error")
+	}
+
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	if v, _ := store.GetMeta("k"); v != "v" {
+		t.Error("a query changed the database")
+	}
+	if v, _ := store.GetMeta("k2"); v != "" {
+		t.Error("a query inserted a row")
+	}
+	if counts, _ := store.DailyActivityCounts("s", time.Time{}, time.UTC); len(counts) != 1 {
+		t.Error("a query dropped the activities")
+	}
+}
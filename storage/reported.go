@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MarkReported records content hashes of changes that have gone out in a
+// report, so later syncs that see the same changes can leave them out.
+// Hashes already recorded keep their first time.
+func (s *SQLiteStore) MarkReported(hashes []string, at time.Time) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.Prepare("INSERT OR IGNORE INTO reported_changes (hash, reported_at) VALUES (?, ?)")
+	if err != nil {
+		return fmt.Errorf("preparing insert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	reportedAt := at.UTC().Format(timeLayout)
+	for _, h := range hashes {
+		if _, err := stmt.Exec(h, reportedAt); err != nil {
+			return fmt.Errorf("marking change reported: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing reported changes: %w", err)
+	}
+	return nil
+}
+
+// Reported returns which of hashes MarkReported has recorded.
+func (s *SQLiteStore) Reported(hashes []string) (map[string]bool, error) {
+	stmt, err := s.db.Prepare("SELECT 1 FROM reported_changes WHERE hash = ?")
+	if err != nil {
+		return nil, fmt.Errorf("preparing query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	reported := make(map[string]bool)
+	for _, h := range hashes {
+		var one int
+		err := stmt.QueryRow(h).Scan(&one)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("querying reported changes: %w", err)
+		}
+		reported[h] = true
+	}
+	return reported, nil
+}
+
+// PruneReported forgets changes reported before cutoff and returns how many
+// there were. With dryRun set, it only counts them.
+func (s *SQLiteStore) PruneReported(cutoff time.Time, dryRun bool) (int64, error) {
+	before := cutoff.UTC().Format(timeLayout)
+	if dryRun {
+		var n int64
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM reported_changes WHERE reported_at < ?", before).Scan(&n); err != nil {
+			return 0, fmt.Errorf("counting reported changes: %w", err)
+		}
+		return n, nil
+	}
+	res, err := s.db.Exec("DELETE FROM reported_changes WHERE reported_at < ?", before)
+	if err != nil {
+		return 0, fmt.Errorf("pruning reported changes: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("counting pruned reported changes: %w", err)
+	}
+	return n, nil
+}
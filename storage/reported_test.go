@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// reportedTracker is what the stores that remember reported changes share.
+type reportedTracker interface {
+	MarkReported(hashes []string, at time.Time) error
+	Reported(hashes []string) (map[string]bool, error)
+	PruneReported(cutoff time.Time, dryRun bool) (int64, error)
+}
+
+func TestReported(t *testing.T) {
+	stores := map[string]reportedTracker{
+		"sqlite": newTestStore(t),
+		"bolt":   newTestBoltStore(t),
+		"memory": NewMemoryStore(),
+	}
+	at := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			reported, err := store.Reported([]string{"a", "b"})
+			if err != nil || len(reported) != 0 {
+				t.Fatalf("Reported before marking = %v, %v; want none", reported, err)
+			}
+
+			if err := store.MarkReported([]string{"a", "b"}, at); err != nil {
+				t.Fatalf("MarkReported failed: %v", err)
+			}
+			// Marking again is harmless
+			if err := store.MarkReported([]string{"b"}, at.Add(time.Hour)); err != nil {
+				t.Fatalf("MarkReported again failed: %v", err)
+			}
+
+			reported, err = store.Reported([]string{"a", "b", "c"})
+			if err != nil {
+				t.Fatalf("Reported failed: %v", err)
+			}
+			if !reported["a"] || !reported["b"] || reported["c"] || len(reported) != 2 {
+				t.Errorf("Reported = %v, want a and b", reported)
+			}
+		})
+	}
+}
+
+func TestPruneReported(t *testing.T) {
+	stores := map[string]reportedTracker{
+		"sqlite": newTestStore(t),
+		"bolt":   newTestBoltStore(t),
+		"memory": NewMemoryStore(),
+	}
+	at := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	cutoff := at.AddDate(0, 0, -30)
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if err := store.MarkReported([]string{"old"}, at.AddDate(0, 0, -40)); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.MarkReported([]string{"new"}, at.AddDate(0, 0, -1)); err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := store.PruneReported(cutoff, true)
+			if err != nil || n != 1 {
+				t.Fatalf("PruneReported dry run = %d, %v; want 1", n, err)
+			}
+			if reported, _ := store.Reported([]string{"old"}); !reported["old"] {
+				t.Error("dry run removed a reported change")
+			}
+
+			n, err = store.PruneReported(cutoff, false)
+			if err != nil || n != 1 {
+				t.Fatalf("PruneReported = %d, %v; want 1", n, err)
+			}
+			reported, err := store.Reported([]string{"old", "new"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reported["old"] || !reported["new"] {
+				t.Errorf("Reported after pruning = %v, want only new", reported)
+			}
+		})
+	}
+}
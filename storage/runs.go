@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Run records one gitstreams run: when and how it ran, what it found and
+// whether it failed. The run history is the one place health checks and
+// catch-up logic look to see how syncs have been going.
+type Run struct {
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Mode        string    `json:"mode,omitempty"`  // How the run got its data: live, cached or historical
+	Error       string    `json:"error,omitempty"` // Why the run failed; empty if it succeeded
+	ID          int64     `json:"id,omitempty"`
+	APIRequests int       `json:"api_requests,omitempty"`
+	Users       int       `json:"users,omitempty"`      // Users in the snapshot the run reported on
+	Changes     int       `json:"changes,omitempty"`    // Changes found since the snapshot before it
+	Activities  int       `json:"activities,omitempty"` // Activities in the run's report
+	Warnings    int       `json:"warnings,omitempty"`   // Fetches that failed, leaving the snapshot partial
+}
+
+// runTimeLayout is how run times are stored: like timeLayout, but to the
+// millisecond, since a cached run can take less than a second.
+const runTimeLayout = "2006-01-02 15:04:05.000"
+
+// Duration returns how long the run took.
+func (r Run) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// RecordRun adds run to the run history and returns its ID. Its ID field
+// is ignored.
+func (s *SQLiteStore) RecordRun(run Run) (int64, error) {
+	result, err := s.db.Exec(`INSERT INTO runs
+		(started_at, finished_at, mode, error, api_requests, users, changes, activities, warnings)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.StartedAt.UTC().Format(runTimeLayout), run.FinishedAt.UTC().Format(runTimeLayout), run.Mode, run.Error,
+		run.APIRequests, run.Users, run.Changes, run.Activities, run.Warnings)
+	if err != nil {
+		return 0, fmt.Errorf("recording run: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("getting last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// runColumns are the columns scanRun reads, in order.
+const runColumns = "id, started_at, finished_at, mode, error, api_requests, users, changes, activities, warnings"
+
+// scanRun reads a run selected as runColumns.
+func scanRun(row interface{ Scan(dest ...any) error }) (Run, error) {
+	var r Run
+	var started, finished string
+	if err := row.Scan(&r.ID, &started, &finished, &r.Mode, &r.Error, &r.APIRequests, &r.Users, &r.Changes, &r.Activities, &r.Warnings); err != nil {
+		return Run{}, err
+	}
+	var err error
+	if r.StartedAt, err = time.Parse(runTimeLayout, started); err != nil {
+		return Run{}, fmt.Errorf("parsing run %d start: %w", r.ID, err)
+	}
+	if r.FinishedAt, err = time.Parse(runTimeLayout, finished); err != nil {
+		return Run{}, fmt.Errorf("parsing run %d finish: %w", r.ID, err)
+	}
+	return r, nil
+}
+
+// Runs returns the latest limit runs, newest first, or every run if limit
+// isn't positive.
+func (s *SQLiteStore) Runs(limit int) (runs []Run, err error) {
+	if limit <= 0 {
+		limit = -1 // No limit, to SQLite
+	}
+	rows, err := s.db.Query("SELECT "+runColumns+" FROM runs ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying runs: %w", err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning run: %w", err)
+		}
+		runs = append(runs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return runs, nil
+}
+
+// LastSuccessfulRun returns the latest run that didn't fail, or nil if
+// none has.
+func (s *SQLiteStore) LastSuccessfulRun() (*Run, error) {
+	r, err := scanRun(s.db.QueryRow("SELECT " + runColumns + " FROM runs WHERE error = '' ORDER BY id DESC LIMIT 1"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying runs: %w", err)
+	}
+	return &r, nil
+}
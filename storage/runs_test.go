@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// runRecorder is what the stores that keep a run history share.
+type runRecorder interface {
+	RecordRun(run Run) (int64, error)
+	Runs(limit int) ([]Run, error)
+	LastSuccessfulRun() (*Run, error)
+}
+
+func TestRuns(t *testing.T) {
+	stores := map[string]runRecorder{
+		"sqlite": newTestStore(t),
+		"bolt":   newTestBoltStore(t),
+		"memory": NewMemoryStore(),
+	}
+	start := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if runs, err := store.Runs(0); err != nil || len(runs) != 0 {
+				t.Fatalf("Runs before any = %v, %v; want none", runs, err)
+			}
+			if last, err := store.LastSuccessfulRun(); err != nil || last != nil {
+				t.Fatalf("LastSuccessfulRun before any = %v, %v; want none", last, err)
+			}
+
+			first := Run{
+				StartedAt:   start,
+				FinishedAt:  start.Add(1500 * time.Millisecond),
+				Mode:        "live",
+				APIRequests: 42,
+				Users:       3,
+				Changes:     7,
+				Activities:  5,
+				Warnings:    1,
+			}
+			id, err := store.RecordRun(first)
+			if err != nil || id != 1 {
+				t.Fatalf("RecordRun() = %d, %v; want the first ID", id, err)
+			}
+			failed := Run{StartedAt: start.Add(time.Hour), FinishedAt: start.Add(time.Hour + time.Second), Mode: "live", Error: "fetching: rate limited"}
+			if id, err := store.RecordRun(failed); err != nil || id != 2 {
+				t.Fatalf("RecordRun() = %d, %v; want the second ID", id, err)
+			}
+
+			runs, err := store.Runs(0)
+			if err != nil || len(runs) != 2 {
+				t.Fatalf("Runs(0) = %v, %v; want both", runs, err)
+			}
+			if runs[0].ID != 2 || runs[0].Error != failed.Error {
+				t.Errorf("newest run = %+v, want the failed one first", runs[0])
+			}
+			got := runs[1]
+			first.ID = 1
+			if !got.StartedAt.Equal(first.StartedAt) || !got.FinishedAt.Equal(first.FinishedAt) {
+				t.Errorf("run times = %v–%v, want %v–%v", got.StartedAt, got.FinishedAt, first.StartedAt, first.FinishedAt)
+			}
+			got.StartedAt, got.FinishedAt = first.StartedAt, first.FinishedAt
+			if got != first {
+				t.Errorf("oldest run = %+v, want %+v", got, first)
+			}
+			if d := runs[1].Duration(); d != 1500*time.Millisecond {
+				t.Errorf("Duration() = %v, want 1.5s", d)
+			}
+
+			if runs, err := store.Runs(1); err != nil || len(runs) != 1 || runs[0].ID != 2 {
+				t.Errorf("Runs(1) = %v, %v; want only the newest", runs, err)
+			}
+			if last, err := store.LastSuccessfulRun(); err != nil || last == nil || last.ID != 1 || !last.FinishedAt.Equal(first.FinishedAt) {
+				t.Errorf("LastSuccessfulRun() = %+v, %v; want the first run, not the failed one", last, err)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreRunsDump(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	if _, err := store.RecordRun(Run{StartedAt: start, FinishedAt: start, Mode: "cached"}); err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Dump(&buf); err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	loaded := NewMemoryStore()
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if id, err := loaded.RecordRun(Run{StartedAt: start, FinishedAt: start}); err != nil || id != 2 {
+		t.Errorf("RecordRun() after loading = %d, %v; want IDs to carry on", id, err)
+	}
+	runs, _ := loaded.Runs(0)
+	if len(runs) != 2 || runs[1].Mode != "cached" {
+		t.Errorf("Runs() after loading = %+v", runs)
+	}
+}
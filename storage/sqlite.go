@@ -15,16 +15,28 @@ import (
 var ErrNotFound = errors.New("snapshot not found")
 
 // Snapshot represents a point-in-time record of user activity.
+//
+// Snapshots carry their payload either as an untyped Activity map (the
+// original format, SchemaVersion 0) or as opaque Data bytes tagged with a
+// caller-defined SchemaVersion greater than zero. Data is stored and
+// returned verbatim, so versioned payloads skip the generic map round-trip.
+// AppVersion records which version of the app wrote the snapshot, and
+// Partial that some of the activity it was taken from couldn't be fetched.
 type Snapshot struct {
-	Activity  map[string]interface{} `json:"activity"`
-	Timestamp time.Time              `json:"timestamp"`
-	UserID    string                 `json:"user_id"`
-	ID        int64                  `json:"id"`
+	Activity      map[string]interface{} `json:"activity,omitempty"`
+	Timestamp     time.Time              `json:"timestamp"`
+	UserID        string                 `json:"user_id"`
+	AppVersion    string                 `json:"app_version,omitempty"`
+	Data          json.RawMessage        `json:"data,omitempty"`
+	ID            int64                  `json:"id"`
+	SchemaVersion int                    `json:"schema_version,omitempty"`
+	Partial       bool                   `json:"partial,omitempty"`
 }
 
 // Store defines the interface for snapshot storage operations.
 type Store interface {
 	Save(snapshot *Snapshot) error
+	SaveAll(snapshots []*Snapshot) error
 	Get(id int64) (*Snapshot, error)
 	GetByUser(userID string, limit int) ([]*Snapshot, error)
 	GetByTimeRange(userID string, start, end time.Time) ([]*Snapshot, error)
@@ -34,7 +46,8 @@ type Store interface {
 
 // SQLiteStore implements Store using SQLite.
 type SQLiteStore struct {
-	db *sql.DB
+	db  *sql.DB
+	now func() time.Time // Stamps snapshots saved without a timestamp
 }
 
 // NewSQLiteStore creates a new SQLite-backed store.
@@ -45,7 +58,19 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
 
-	store := &SQLiteStore{db: db}
+	// Each connection to ":memory:" gets its own private database, so pin the
+	// pool to a single connection or transactions would see an empty schema.
+	if dbPath == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
+
+	store := &SQLiteStore{db: db, now: time.Now}
+	// Migrating a newer layout would clobber what this build doesn't know,
+	// like the views, so stop before touching it
+	if err := store.checkNotNewer(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 	if err := store.migrate(); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("running migrations: %w", err)
@@ -54,6 +79,57 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 	return store, nil
 }
 
+// OpenSQLiteReadOnly opens the SQLite database at dbPath as it is, without
+// running migrations, in a mode SQLite itself won't let write. A database
+// from an older version may lack columns its queries need.
+func OpenSQLiteReadOnly(dbPath string) (*SQLiteStore, error) {
+	db, err := openReadOnly(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db, now: time.Now}, nil
+}
+
+// SchemaVersion returns the version of the layout the database has, which
+// is 0 for one from before versions were recorded.
+func (s *SQLiteStore) SchemaVersion() (int, error) {
+	return userVersion(s.db)
+}
+
+// schemaVersion numbers the layout migrate produces, kept in the database's
+// user_version. Bump it whenever migrate changes so databases are backed up
+// before being upgraded; see NeedsMigration.
+const schemaVersion = 5
+
+// NewerSchemaError is returned when opening a database written by a newer
+// version whose schema this one doesn't know.
+type NewerSchemaError struct {
+	WrittenBy string // App version of the latest snapshot, if recorded
+	Version   int
+	Supported int
+}
+
+func (e *NewerSchemaError) Error() string {
+	msg := fmt.Sprintf("database schema version %d is newer than this version supports (%d)", e.Version, e.Supported)
+	if e.WrittenBy != "" {
+		msg += "; it was last written by version " + e.WrittenBy
+	}
+	return msg + ". Upgrade to open it"
+}
+
+// checkNotNewer returns a NewerSchemaError if the database's schema is
+// newer than schemaVersion.
+func (s *SQLiteStore) checkNotNewer() error {
+	version, err := userVersion(s.db)
+	if err != nil || version <= schemaVersion {
+		return err
+	}
+	schemaErr := &NewerSchemaError{Version: version, Supported: schemaVersion}
+	// Best effort: the error stands without it
+	_ = s.db.QueryRow("SELECT app_version FROM snapshots WHERE app_version != '' ORDER BY id DESC LIMIT 1").Scan(&schemaErr.WrittenBy)
+	return schemaErr
+}
+
 func (s *SQLiteStore) migrate() error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS snapshots (
@@ -66,31 +142,236 @@ func (s *SQLiteStore) migrate() error {
 	CREATE INDEX IF NOT EXISTS idx_snapshots_user_id ON snapshots(user_id);
 	CREATE INDEX IF NOT EXISTS idx_snapshots_timestamp ON snapshots(timestamp);
 	CREATE INDEX IF NOT EXISTS idx_snapshots_user_timestamp ON snapshots(user_id, timestamp);
+	CREATE TABLE IF NOT EXISTS pending_diffs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		captured_at DATETIME NOT NULL,
+		data BLOB NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS activities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		scope TEXT NOT NULL,
+		username TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		event_type TEXT NOT NULL DEFAULT '',
+		repo TEXT NOT NULL,
+		key TEXT NOT NULL,
+		details TEXT NOT NULL DEFAULT '',
+		occurred_at TEXT NOT NULL,
+		first_seen TEXT NOT NULL,
+		UNIQUE (scope, username, kind, key)
+	);
+	CREATE INDEX IF NOT EXISTS idx_activities_scope_occurred ON activities(scope, occurred_at);
+	CREATE INDEX IF NOT EXISTS idx_activities_scope_repo ON activities(scope, repo);
+	CREATE INDEX IF NOT EXISTS idx_activities_scope_user ON activities(scope, username, occurred_at);
+	CREATE TABLE IF NOT EXISTS users (
+		username TEXT PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		bio TEXT NOT NULL DEFAULT '',
+		fetched_at TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS user_prefs (
+		scope TEXT NOT NULL,
+		username TEXT NOT NULL,
+		muted INTEGER NOT NULL DEFAULT 0,
+		tags TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (scope, username)
+	);
+	CREATE TABLE IF NOT EXISTS reported_changes (
+		hash TEXT PRIMARY KEY,
+		reported_at TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at TEXT NOT NULL,
+		finished_at TEXT NOT NULL,
+		mode TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		api_requests INTEGER NOT NULL DEFAULT 0,
+		users INTEGER NOT NULL DEFAULT 0,
+		changes INTEGER NOT NULL DEFAULT 0,
+		activities INTEGER NOT NULL DEFAULT 0,
+		warnings INTEGER NOT NULL DEFAULT 0
+	);
 	`
-	_, err := s.db.Exec(schema)
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Columns added after the initial schema
+	if err := s.addColumnIfMissing("snapshots", "schema_version", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfMissing("snapshots", "data", "BLOB"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfMissing("snapshots", "app_version", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfMissing("snapshots", "partial", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Recreated on every open so they always match this version's definitions
+	if _, err := s.db.Exec(viewsSchema); err != nil {
+		return err
+	}
+
+	version, err := userVersion(s.db)
+	if err != nil || version >= schemaVersion {
+		return err
+	}
+	// #nosec G202 -- schemaVersion is a constant
+	_, err = s.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion))
 	return err
 }
 
+// userVersion reads the schema version stored in a database.
+func userVersion(db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return version, nil
+}
+
+// addColumnIfMissing adds a column to an existing table, doing nothing if a
+// column with that name is already present.
+func (s *SQLiteStore) addColumnIfMissing(table, column, definition string) (err error) {
+	// #nosec G202 -- table name is a constant from migrate, not user input
+	rows, err := s.db.Query("SELECT name FROM pragma_table_info('" + table + "')")
+	if err != nil {
+		return fmt.Errorf("reading %s columns: %w", table, err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("scanning %s columns: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating %s columns: %w", table, err)
+	}
+
+	// #nosec G202 -- identifiers are constants from migrate, not user input
+	if _, err := s.db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition); err != nil {
+		return fmt.Errorf("adding column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 // Save stores a snapshot. If the snapshot has no ID, a new record is created.
 // On insert, the snapshot's ID is updated with the generated value.
 func (s *SQLiteStore) Save(snapshot *Snapshot) error {
+	return saveSnapshot(s.db, snapshot, s.now)
+}
+
+// SetNow sets the clock snapshots saved without a timestamp are stamped
+// with. It defaults to time.Now.
+func (s *SQLiteStore) SetNow(now func() time.Time) {
+	s.now = now
+}
+
+// SaveAll stores several snapshots in a single transaction. Either every
+// snapshot is persisted or, if any write fails, none are. This keeps a sync
+// that writes multiple rows from leaving half a snapshot behind on a crash.
+// On failure, IDs assigned to new snapshots during the attempt are reset.
+func (s *SQLiteStore) SaveAll(snapshots []*Snapshot) (err error) {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	origIDs := make([]int64, len(snapshots))
+	for i, snapshot := range snapshots {
+		if snapshot == nil {
+			return fmt.Errorf("snapshot %d cannot be nil", i)
+		}
+		origIDs[i] = snapshot.ID
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		_ = tx.Rollback()
+		for i, snapshot := range snapshots {
+			snapshot.ID = origIDs[i]
+		}
+	}()
+
+	for _, snapshot := range snapshots {
+		if err = saveSnapshot(tx, snapshot, s.now); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// checkSnapshot rejects snapshots no store should save.
+func checkSnapshot(snapshot *Snapshot) error {
 	if snapshot == nil {
 		return errors.New("snapshot cannot be nil")
 	}
+	if snapshot.SchemaVersion < 0 {
+		return fmt.Errorf("invalid schema version %d", snapshot.SchemaVersion)
+	}
+	if snapshot.SchemaVersion > 0 && len(snapshot.Data) == 0 {
+		return fmt.Errorf("schema version %d snapshot has no data", snapshot.SchemaVersion)
+	}
+	return nil
+}
+
+func saveSnapshot(db execer, snapshot *Snapshot, now func() time.Time) error {
+	if err := checkSnapshot(snapshot); err != nil {
+		return err
+	}
 
-	activityJSON, err := json.Marshal(snapshot.Activity)
+	// Versioned snapshots keep their payload in the data column; the legacy
+	// activity column still needs a value because it's NOT NULL.
+	activity := snapshot.Activity
+	var data []byte
+	if snapshot.SchemaVersion > 0 {
+		activity = nil
+		data = snapshot.Data
+	}
+	activityJSON, err := json.Marshal(activity)
 	if err != nil {
 		return fmt.Errorf("marshaling activity: %w", err)
 	}
 
 	if snapshot.Timestamp.IsZero() {
-		snapshot.Timestamp = time.Now()
+		snapshot.Timestamp = now()
 	}
 
 	if snapshot.ID == 0 {
-		result, err := s.db.Exec(
-			"INSERT INTO snapshots (user_id, timestamp, activity_json) VALUES (?, ?, ?)",
-			snapshot.UserID, snapshot.Timestamp, string(activityJSON),
+		result, err := db.Exec(
+			"INSERT INTO snapshots (user_id, timestamp, activity_json, schema_version, data, app_version, partial) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			snapshot.UserID, snapshot.Timestamp, string(activityJSON), snapshot.SchemaVersion, data, snapshot.AppVersion, snapshot.Partial,
 		)
 		if err != nil {
 			return fmt.Errorf("inserting snapshot: %w", err)
@@ -101,9 +382,9 @@ func (s *SQLiteStore) Save(snapshot *Snapshot) error {
 		}
 		snapshot.ID = id
 	} else {
-		_, err := s.db.Exec(
-			"UPDATE snapshots SET user_id = ?, timestamp = ?, activity_json = ? WHERE id = ?",
-			snapshot.UserID, snapshot.Timestamp, string(activityJSON), snapshot.ID,
+		_, err := db.Exec(
+			"UPDATE snapshots SET user_id = ?, timestamp = ?, activity_json = ?, schema_version = ?, data = ?, app_version = ?, partial = ? WHERE id = ?",
+			snapshot.UserID, snapshot.Timestamp, string(activityJSON), snapshot.SchemaVersion, data, snapshot.AppVersion, snapshot.Partial, snapshot.ID,
 		)
 		if err != nil {
 			return fmt.Errorf("updating snapshot: %w", err)
@@ -116,7 +397,7 @@ func (s *SQLiteStore) Save(snapshot *Snapshot) error {
 // Get retrieves a snapshot by ID.
 func (s *SQLiteStore) Get(id int64) (*Snapshot, error) {
 	row := s.db.QueryRow(
-		"SELECT id, user_id, timestamp, activity_json FROM snapshots WHERE id = ?",
+		"SELECT id, user_id, timestamp, activity_json, schema_version, data, app_version, partial FROM snapshots WHERE id = ?",
 		id,
 	)
 
@@ -130,7 +411,7 @@ func (s *SQLiteStore) GetByUser(userID string, limit int) (snapshots []*Snapshot
 	}
 
 	rows, err := s.db.Query(
-		"SELECT id, user_id, timestamp, activity_json FROM snapshots WHERE user_id = ? ORDER BY timestamp DESC LIMIT ?",
+		"SELECT id, user_id, timestamp, activity_json, schema_version, data, app_version, partial FROM snapshots WHERE user_id = ? ORDER BY timestamp DESC LIMIT ?",
 		userID, limit,
 	)
 	if err != nil {
@@ -148,7 +429,7 @@ func (s *SQLiteStore) GetByUser(userID string, limit int) (snapshots []*Snapshot
 // GetByTimeRange retrieves snapshots for a user within a time range.
 func (s *SQLiteStore) GetByTimeRange(userID string, start, end time.Time) (snapshots []*Snapshot, err error) {
 	rows, err := s.db.Query(
-		"SELECT id, user_id, timestamp, activity_json FROM snapshots WHERE user_id = ? AND timestamp >= ? AND timestamp <= ? ORDER BY timestamp DESC",
+		"SELECT id, user_id, timestamp, activity_json, schema_version, data, app_version, partial FROM snapshots WHERE user_id = ? AND timestamp >= ? AND timestamp <= ? ORDER BY timestamp DESC",
 		userID, start, end,
 	)
 	if err != nil {
@@ -163,6 +444,35 @@ func (s *SQLiteStore) GetByTimeRange(userID string, start, end time.Time) (snaps
 	return s.scanSnapshots(rows)
 }
 
+// SnapshotTimes returns when a user's snapshots since a time were taken,
+// newest first, without loading them.
+func (s *SQLiteStore) SnapshotTimes(userID string, since time.Time) (times []time.Time, err error) {
+	rows, err := s.db.Query(
+		"SELECT timestamp FROM snapshots WHERE user_id = ? AND timestamp >= ? ORDER BY timestamp DESC",
+		userID, since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshot times: %w", err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("scanning snapshot time: %w", err)
+		}
+		times = append(times, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return times, nil
+}
+
 // Delete removes a snapshot by ID.
 func (s *SQLiteStore) Delete(id int64) error {
 	result, err := s.db.Exec("DELETE FROM snapshots WHERE id = ?", id)
@@ -191,39 +501,24 @@ type scanner interface {
 }
 
 func (s *SQLiteStore) scanSnapshot(row scanner) (*Snapshot, error) {
-	var snapshot Snapshot
-	var activityJSON string
-
-	err := row.Scan(&snapshot.ID, &snapshot.UserID, &snapshot.Timestamp, &activityJSON)
+	snapshot, err := scanRow(row)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	}
 	if err != nil {
-		return nil, fmt.Errorf("scanning snapshot: %w", err)
-	}
-
-	if err := json.Unmarshal([]byte(activityJSON), &snapshot.Activity); err != nil {
-		return nil, fmt.Errorf("unmarshaling activity: %w", err)
+		return nil, err
 	}
-
-	return &snapshot, nil
+	return snapshot, nil
 }
 
 func (s *SQLiteStore) scanSnapshots(rows *sql.Rows) ([]*Snapshot, error) {
 	var snapshots []*Snapshot
 	for rows.Next() {
-		var snapshot Snapshot
-		var activityJSON string
-
-		if err := rows.Scan(&snapshot.ID, &snapshot.UserID, &snapshot.Timestamp, &activityJSON); err != nil {
-			return nil, fmt.Errorf("scanning snapshot row: %w", err)
-		}
-
-		if err := json.Unmarshal([]byte(activityJSON), &snapshot.Activity); err != nil {
-			return nil, fmt.Errorf("unmarshaling activity: %w", err)
+		snapshot, err := scanRow(rows)
+		if err != nil {
+			return nil, err
 		}
-
-		snapshots = append(snapshots, &snapshot)
+		snapshots = append(snapshots, snapshot)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -232,3 +527,30 @@ func (s *SQLiteStore) scanSnapshots(rows *sql.Rows) ([]*Snapshot, error) {
 
 	return snapshots, nil
 }
+
+// scanRow reads one snapshot row. Legacy rows have their activity decoded;
+// versioned rows return their data untouched.
+func scanRow(row scanner) (*Snapshot, error) {
+	var snapshot Snapshot
+	var activityJSON string
+	var data []byte
+
+	err := row.Scan(&snapshot.ID, &snapshot.UserID, &snapshot.Timestamp, &activityJSON, &snapshot.SchemaVersion, &data, &snapshot.AppVersion, &snapshot.Partial)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning snapshot: %w", err)
+	}
+
+	if snapshot.SchemaVersion > 0 {
+		snapshot.Data = data
+		return &snapshot, nil
+	}
+
+	if err := json.Unmarshal([]byte(activityJSON), &snapshot.Activity); err != nil {
+		return nil, fmt.Errorf("unmarshaling activity: %w", err)
+	}
+
+	return &snapshot, nil
+}
@@ -1,7 +1,12 @@
 package storage
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -239,6 +244,31 @@ func TestGetByTimeRange(t *testing.T) {
 	}
 }
 
+func TestSnapshotTimes(t *testing.T) {
+	store := newTestStore(t)
+
+	baseTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, s := range []*Snapshot{
+		{UserID: "user123", Timestamp: baseTime.Add(-48 * time.Hour)},
+		{UserID: "user123", Timestamp: baseTime},
+		{UserID: "user123", Timestamp: baseTime.Add(time.Hour)},
+		{UserID: "other", Timestamp: baseTime},
+	} {
+		s.Activity = map[string]interface{}{}
+		if err := store.Save(s); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	times, err := store.SnapshotTimes("user123", baseTime.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("SnapshotTimes failed: %v", err)
+	}
+	if len(times) != 2 || !times[0].Equal(baseTime.Add(time.Hour)) || !times[1].Equal(baseTime) {
+		t.Errorf("SnapshotTimes() = %v, want the two snapshots since the day before, newest first", times)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	store := newTestStore(t)
 
@@ -298,7 +328,278 @@ func TestSaveDefaultTimestamp(t *testing.T) {
 	}
 }
 
+func TestSaveDefaultTimestampUsesSetNow(t *testing.T) {
+	now := time.Date(2026, 1, 22, 12, 0, 0, 0, time.UTC)
+	stores := map[string]interface {
+		Save(*Snapshot) error
+		Get(int64) (*Snapshot, error)
+		SetNow(func() time.Time)
+	}{
+		"sqlite": newTestStore(t),
+		"bolt":   newTestBoltStore(t),
+		"memory": NewMemoryStore(),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			store.SetNow(func() time.Time { return now })
+			snapshot := &Snapshot{UserID: "user123", Activity: map[string]interface{}{"test": true}}
+			if err := store.Save(snapshot); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			retrieved, err := store.Get(snapshot.ID)
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			if !retrieved.Timestamp.Equal(now) {
+				t.Errorf("timestamp = %v, want the store's clock's %v", retrieved.Timestamp, now)
+			}
+		})
+	}
+}
+
+func TestSaveAll(t *testing.T) {
+	store := newTestStore(t)
+
+	now := time.Now().Truncate(time.Second)
+	snapshots := []*Snapshot{
+		{UserID: "user1", Timestamp: now, Activity: map[string]interface{}{"index": float64(1)}},
+		{UserID: "user2", Timestamp: now, Activity: map[string]interface{}{"index": float64(2)}},
+	}
+
+	if err := store.SaveAll(snapshots); err != nil {
+		t.Fatalf("SaveAll failed: %v", err)
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.ID == 0 {
+			t.Errorf("expected ID to be set for %s", snapshot.UserID)
+		}
+		retrieved, err := store.Get(snapshot.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if retrieved.UserID != snapshot.UserID {
+			t.Errorf("UserID mismatch: got %q, want %q", retrieved.UserID, snapshot.UserID)
+		}
+	}
+}
+
+func TestSaveAllEmpty(t *testing.T) {
+	store := newTestStore(t)
+
+	if err := store.SaveAll(nil); err != nil {
+		t.Errorf("SaveAll(nil) should be a no-op, got %v", err)
+	}
+}
+
+func TestSaveAllNil(t *testing.T) {
+	store := newTestStore(t)
+
+	err := store.SaveAll([]*Snapshot{{UserID: "user1"}, nil})
+	if err == nil {
+		t.Fatal("expected error when saving nil snapshot")
+	}
+
+	snapshots, err := store.GetByUser("user1", 10)
+	if err != nil {
+		t.Fatalf("GetByUser failed: %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("expected no snapshots to be saved, got %d", len(snapshots))
+	}
+}
+
+func TestSaveAllRollsBackOnError(t *testing.T) {
+	store := newTestStore(t)
+
+	good := &Snapshot{UserID: "user1", Activity: map[string]interface{}{"ok": true}}
+	// Channels can't be marshaled to JSON, so this write fails mid-transaction.
+	bad := &Snapshot{UserID: "user1", Activity: map[string]interface{}{"bad": make(chan int)}}
+
+	err := store.SaveAll([]*Snapshot{good, bad})
+	if err == nil {
+		t.Fatal("expected error from SaveAll")
+	}
+
+	if good.ID != 0 {
+		t.Errorf("expected ID to be reset after rollback, got %d", good.ID)
+	}
+
+	snapshots, err := store.GetByUser("user1", 10)
+	if err != nil {
+		t.Fatalf("GetByUser failed: %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("expected rollback to leave no snapshots, got %d", len(snapshots))
+	}
+}
+
+func TestSaveVersionedData(t *testing.T) {
+	store := newTestStore(t)
+
+	snapshot := &Snapshot{
+		UserID:        "user1",
+		Timestamp:     time.Now(),
+		SchemaVersion: 2,
+		Data:          json.RawMessage(`{"users":["a","b"]}`),
+	}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	retrieved, err := store.Get(snapshot.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if retrieved.SchemaVersion != 2 {
+		t.Errorf("expected schema version 2, got %d", retrieved.SchemaVersion)
+	}
+	if string(retrieved.Data) != string(snapshot.Data) {
+		t.Errorf("expected data %s, got %s", snapshot.Data, retrieved.Data)
+	}
+	if retrieved.Activity != nil {
+		t.Errorf("expected no activity for versioned snapshot, got %v", retrieved.Activity)
+	}
+}
+
+func TestSaveVersionedWithoutData(t *testing.T) {
+	store := newTestStore(t)
+
+	err := store.Save(&Snapshot{UserID: "user1", SchemaVersion: 1})
+	if err == nil {
+		t.Error("expected error for versioned snapshot without data")
+	}
+}
+
+func TestMigrateAddsColumnsToExistingDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// Create a database with the original schema and a legacy row
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		activity_json TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO snapshots (user_id, timestamp, activity_json) VALUES ('user1', '2024-01-15 10:00:00', '{"legacy":true}');
+	`)
+	if err != nil {
+		t.Fatalf("creating legacy schema: %v", err)
+	}
+	_ = db.Close()
+
+	// Opening twice checks the migration is idempotent
+	for i := 0; i < 2; i++ {
+		store, err := NewSQLiteStore(dbPath)
+		if err != nil {
+			t.Fatalf("NewSQLiteStore (open %d) failed: %v", i+1, err)
+		}
+		_ = store.Close()
+	}
+
+	store, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	snapshots, err := store.GetByUser("user1", 10)
+	if err != nil {
+		t.Fatalf("GetByUser failed: %v", err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot, got %d", len(snapshots))
+	}
+	if snapshots[0].SchemaVersion != 0 {
+		t.Errorf("expected legacy schema version 0, got %d", snapshots[0].SchemaVersion)
+	}
+	if snapshots[0].Activity["legacy"] != true {
+		t.Errorf("expected legacy activity to be readable, got %v", snapshots[0].Activity)
+	}
+}
+
 func TestStoreInterface(t *testing.T) {
 	// Verify SQLiteStore implements Store interface
 	var _ Store = (*SQLiteStore)(nil)
 }
+
+func TestSaveAppVersion(t *testing.T) {
+	store := newTestStore(t)
+
+	snapshot := &Snapshot{UserID: "user1", AppVersion: "v1.2.3"}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	retrieved, err := store.Get(snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retrieved.AppVersion != "v1.2.3" {
+		t.Errorf("expected app version v1.2.3, got %q", retrieved.AppVersion)
+	}
+}
+
+func TestSavePartial(t *testing.T) {
+	store := newTestStore(t)
+
+	snapshot := &Snapshot{UserID: "user1", Partial: true}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(&Snapshot{UserID: "user1"}); err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := store.GetByUser("user1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := 0
+	for _, ss := range snapshots {
+		if ss.Partial {
+			partial++
+			if ss.ID != snapshot.ID {
+				t.Errorf("expected snapshot %d to be partial, got %d", snapshot.ID, ss.ID)
+			}
+		}
+	}
+	if partial != 1 {
+		t.Errorf("expected 1 partial snapshot, got %d", partial)
+	}
+}
+
+func TestNewerSchemaRefused(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "newer.db")
+	store, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(&Snapshot{UserID: "user1", AppVersion: "v9.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion+1)); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	_, err = NewSQLiteStore(dbPath)
+	var schemaErr *NewerSchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected a NewerSchemaError, got %v", err)
+	}
+	if schemaErr.Version != schemaVersion+1 || schemaErr.WrittenBy != "v9.0.0" {
+		t.Errorf("unexpected error %+v", schemaErr)
+	}
+	if !strings.Contains(err.Error(), "last written by version v9.0.0") {
+		t.Errorf("expected the writer in %q", err)
+	}
+	// Refusing leaves the database as the newer version left it
+	if needed, err := NeedsMigration(dbPath); err != nil || needed {
+		t.Errorf("NeedsMigration = %v, %v; want false", needed, err)
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UserInfo is a followed user's public profile, cached from GitHub since
+// follow lists don't include it.
+type UserInfo struct {
+	FetchedAt time.Time
+	Username  string
+	Name      string
+	Bio       string
+}
+
+// UserPrefs is how a viewer treats a followed user. Prefs are kept per
+// scope, so each team profile has its own.
+type UserPrefs struct {
+	Scope    string
+	Username string
+	Tags     []string
+	Muted    bool // Left out of reports
+}
+
+// GetUserInfo returns a user's cached profile, or nil if there isn't one.
+func (s *SQLiteStore) GetUserInfo(username string) (*UserInfo, error) {
+	info := UserInfo{Username: username}
+	var fetched string
+	err := s.db.QueryRow(`SELECT name, bio, fetched_at FROM users WHERE username = ?`, username).
+		Scan(&info.Name, &info.Bio, &fetched)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting user %s: %w", username, err)
+	}
+	if info.FetchedAt, err = time.Parse(timeLayout, fetched); err != nil {
+		return nil, fmt.Errorf("parsing fetched_at %q: %w", fetched, err)
+	}
+	return &info, nil
+}
+
+// SaveUserInfo caches a user's profile, replacing any older copy.
+func (s *SQLiteStore) SaveUserInfo(info UserInfo) error {
+	_, err := s.db.Exec(`INSERT INTO users (username, name, bio, fetched_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (username) DO UPDATE SET name = excluded.name, bio = excluded.bio, fetched_at = excluded.fetched_at`,
+		info.Username, info.Name, info.Bio, info.FetchedAt.UTC().Format(timeLayout))
+	if err != nil {
+		return fmt.Errorf("saving user %s: %w", info.Username, err)
+	}
+	return nil
+}
+
+// GetUserPrefs returns a user's prefs in scope; a user without any is
+// unmuted and untagged.
+func (s *SQLiteStore) GetUserPrefs(scope, username string) (UserPrefs, error) {
+	prefs := UserPrefs{Scope: scope, Username: username}
+	var tags string
+	err := s.db.QueryRow(`SELECT muted, tags FROM user_prefs WHERE scope = ? AND username = ?`, scope, username).
+		Scan(&prefs.Muted, &tags)
+	if errors.Is(err, sql.ErrNoRows) {
+		return prefs, nil
+	}
+	if err != nil {
+		return prefs, fmt.Errorf("getting prefs for %s: %w", username, err)
+	}
+	prefs.Tags = splitTags(tags)
+	return prefs, nil
+}
+
+// SetUserPrefs saves a user's prefs in their scope.
+func (s *SQLiteStore) SetUserPrefs(prefs UserPrefs) error {
+	_, err := s.db.Exec(`INSERT INTO user_prefs (scope, username, muted, tags) VALUES (?, ?, ?, ?)
+		ON CONFLICT (scope, username) DO UPDATE SET muted = excluded.muted, tags = excluded.tags`,
+		prefs.Scope, prefs.Username, prefs.Muted, strings.Join(prefs.Tags, ","))
+	if err != nil {
+		return fmt.Errorf("saving prefs for %s: %w", prefs.Username, err)
+	}
+	return nil
+}
+
+// MutedUsers returns the users muted in scope.
+func (s *SQLiteStore) MutedUsers(scope string) (users []string, err error) {
+	rows, err := s.db.Query(`SELECT username FROM user_prefs WHERE scope = ? AND muted ORDER BY username`, scope)
+	if err != nil {
+		return nil, fmt.Errorf("querying muted users: %w", err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing rows: %w", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, fmt.Errorf("scanning user: %w", err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return users, nil
+}
+
+// splitTags parses tags stored comma-separated.
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
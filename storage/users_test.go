@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestUserInfo(t *testing.T) {
+	store := newTestStore(t)
+	if info, err := store.GetUserInfo("rsc"); err != nil || info != nil {
+		t.Fatalf("GetUserInfo() before saving = %v, %v; want nil", info, err)
+	}
+
+	fetched := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	for _, bio := range []string{"Go", "Go and more"} {
+		if err := store.SaveUserInfo(UserInfo{Username: "rsc", Name: "Russ Cox", Bio: bio, FetchedAt: fetched}); err != nil {
+			t.Fatalf("SaveUserInfo failed: %v", err)
+		}
+	}
+	info, err := store.GetUserInfo("rsc")
+	if err != nil || info == nil {
+		t.Fatalf("GetUserInfo() = %v, %v", info, err)
+	}
+	if info.Name != "Russ Cox" || info.Bio != "Go and more" || !info.FetchedAt.Equal(fetched) {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestUserPrefs(t *testing.T) {
+	store := newTestStore(t)
+	prefs, err := store.GetUserPrefs("s", "rsc")
+	if err != nil || prefs.Muted || prefs.Tags != nil || prefs.Username != "rsc" {
+		t.Fatalf("default prefs = %+v, %v", prefs, err)
+	}
+
+	if err := store.SetUserPrefs(UserPrefs{Scope: "s", Username: "rsc", Muted: true, Tags: []string{"go", "compilers"}}); err != nil {
+		t.Fatalf("SetUserPrefs failed: %v", err)
+	}
+	if err := store.SetUserPrefs(UserPrefs{Scope: "s", Username: "bradfitz", Tags: []string{"go"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetUserPrefs(UserPrefs{Scope: "other", Username: "robpike", Muted: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	prefs, err = store.GetUserPrefs("s", "rsc")
+	if err != nil || !prefs.Muted || !slices.Equal(prefs.Tags, []string{"go", "compilers"}) {
+		t.Errorf("GetUserPrefs() = %+v, %v", prefs, err)
+	}
+	if muted, err := store.MutedUsers("s"); err != nil || !slices.Equal(muted, []string{"rsc"}) {
+		t.Errorf("MutedUsers() = %v, %v", muted, err)
+	}
+
+	// Unmuting replaces the row
+	if err := store.SetUserPrefs(UserPrefs{Scope: "s", Username: "rsc"}); err != nil {
+		t.Fatal(err)
+	}
+	if muted, _ := store.MutedUsers("s"); len(muted) != 0 {
+		t.Errorf("expected no muted users, got %v", muted)
+	}
+}
+
+func TestUserActivityQueries(t *testing.T) {
+	store := newTestStore(t)
+	at := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	_, err := store.SaveActivities([]Activity{
+		{Scope: "s", Username: "rsc", Kind: KindStar, Repo: "golang/go", Key: "golang/go", OccurredAt: at.AddDate(0, 0, -3), FirstSeen: at},
+		{Scope: "s", Username: "rsc", Kind: KindEvent, EventType: "PushEvent", Repo: "rsc/tmp", Key: "1", OccurredAt: at.AddDate(0, 0, -1), FirstSeen: at},
+		{Scope: "s", Username: "rsc", Kind: KindEvent, EventType: "PushEvent", Repo: "rsc/tmp", Key: "2", OccurredAt: at, FirstSeen: at},
+		{Scope: "s", Username: "bradfitz", Kind: KindStar, Repo: "golang/go", Key: "golang/go", OccurredAt: at, FirstSeen: at},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	activities, err := store.UserActivities("s", "rsc", 10)
+	if err != nil || len(activities) != 3 || activities[0].Key != "2" {
+		t.Errorf("UserActivities() = %+v, %v", activities, err)
+	}
+
+	repos, err := store.UserRepos("s", "rsc", 10)
+	if err != nil {
+		t.Fatalf("UserRepos failed: %v", err)
+	}
+	if len(repos) != 2 || repos[0].Repo != "rsc/tmp" || repos[0].Count != 2 || !repos[0].Last.Equal(at) || repos[1].Repo != "golang/go" {
+		t.Errorf("unexpected repos %+v", repos)
+	}
+
+	counts, err := store.UserDailyActivityCounts("s", "rsc", at.AddDate(0, 0, -2), time.UTC)
+	if err != nil || len(counts) != 2 {
+		t.Errorf("UserDailyActivityCounts() = %+v, %v", counts, err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/tags"
+)
+
+const defaultTagsName = "tags.json"
+
+// loadTags loads the activity tags at path, or from the config directory
+// if path is empty. A missing default tags file means there are no tags.
+func loadTags(path string) ([]tags.Tag, error) {
+	explicit := path != ""
+	if !explicit {
+		dir, err := configDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, defaultTagsName)
+	}
+
+	loaded, err := tags.Load(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading tags: %w", err)
+	}
+	return loaded, nil
+}
+
+// tagReport tags rpt's activities by their repos. A repo seen only in
+// events is tagged by its name alone.
+func tagReport(rpt *report.Report, tagSet []tags.Tag, snapshot *diff.Snapshot, result *diff.Result) {
+	if len(tagSet) == 0 {
+		return
+	}
+	repos := knownRepos(snapshot, result)
+
+	repoTags := make(map[string][]string)
+	for _, ua := range rpt.UserActivities {
+		for _, a := range ua.Activities {
+			// Follows name a user, not a repo
+			if _, done := repoTags[a.RepoName]; done || a.Type == report.ActivityFollowed {
+				continue
+			}
+			repo, ok := repos[a.RepoName]
+			if !ok {
+				repo.Owner, repo.Name, _ = strings.Cut(a.RepoName, "/")
+			}
+			repoTags[a.RepoName] = tags.Match(tagSet, repo)
+		}
+	}
+	rpt.SetRepoTags(repoTags)
+}
+
+// knownRepos returns what's known about repos by full name: the repos
+// starred and owned in snapshot and result. Repos seen only in events
+// aren't among them.
+func knownRepos(snapshot *diff.Snapshot, result *diff.Result) map[string]diff.Repo {
+	repos := make(map[string]diff.Repo)
+	for _, ua := range snapshot.Users {
+		for _, r := range ua.StarredRepos {
+			repos[r.FullName()] = r
+		}
+		for _, r := range ua.OwnedRepos {
+			repos[r.FullName()] = r
+		}
+	}
+	for _, c := range result.NewStars {
+		repos[c.Repo.FullName()] = c.Repo
+	}
+	for _, c := range result.NewRepos {
+		repos[c.Repo.FullName()] = c.Repo
+	}
+	return repos
+}
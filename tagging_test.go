@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/tags"
+)
+
+func TestLoadTags(t *testing.T) {
+	home := setHome(t)
+	if loaded, err := loadTags(""); err != nil || loaded != nil {
+		t.Errorf("loadTags() without a tags file = %v, %v; want none", loaded, err)
+	}
+	if _, err := loadTags(filepath.Join(home, "missing.json")); err == nil {
+		t.Error("expected an error for a missing tags file given explicitly")
+	}
+
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultTagsName), `[{"name": "go", "languages": ["Go"]}]`)
+	loaded, err := loadTags("")
+	if err != nil || len(loaded) != 1 || loaded[0].Name != "go" {
+		t.Errorf("loadTags() = %+v, %v; want the default file's tag", loaded, err)
+	}
+}
+
+func TestTagReport(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	snapshot := diff.NewSnapshot(now)
+	snapshot.Users["alice"] = diff.UserActivity{
+		Username:   "alice",
+		OwnedRepos: []diff.Repo{{Owner: "alice", Name: "notes", Topics: []string{"golang"}}},
+	}
+	result := &diff.Result{
+		NewStars: []diff.RepoChange{{Username: "bob", Repo: diff.Repo{Owner: "ggml-org", Name: "llama.cpp", Language: "C++"}}},
+		NewEvents: []diff.EventChange{
+			{Username: "alice", Event: diff.Event{Type: "PushEvent", Actor: "alice", Repo: "alice/notes", CreatedAt: now}},
+			{Username: "alice", Event: diff.Event{Type: "PushEvent", Actor: "alice", Repo: "carol/llm-tools", CreatedAt: now}},
+			{Username: "alice", Event: diff.Event{Type: "PushEvent", Actor: "alice", Repo: "carol/dotfiles", CreatedAt: now}},
+		},
+	}
+	rpt := report.FromDiff(result, report.Options{GeneratedAt: now})
+	tagSet := []tags.Tag{
+		{Name: "go", Topics: []string{"golang"}},
+		{Name: "ml", Languages: []string{"C++"}, Keywords: []string{"llm"}},
+	}
+	tagReport(rpt, tagSet, snapshot, result)
+
+	got := make(map[string]string)
+	for _, ua := range rpt.UserActivities {
+		for _, a := range ua.Activities {
+			got[a.RepoName] = strings.Join(a.Tags, ",")
+		}
+	}
+	want := map[string]string{
+		"alice/notes":        "go", // From the snapshot's owned repos
+		"ggml-org/llama.cpp": "ml", // From the new star
+		"carol/llm-tools":    "ml", // By name alone
+		"carol/dotfiles":     "",
+	}
+	for repo, wantTags := range want {
+		if got[repo] != wantTags {
+			t.Errorf("%s tagged %q, want %q", repo, got[repo], wantTags)
+		}
+	}
+	if names := rpt.TagNames(); !slices.Equal(names, []string{"go", "ml"}) {
+		t.Errorf("TagNames() = %v", names)
+	}
+}
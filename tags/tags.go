@@ -0,0 +1,143 @@
+// Package tags labels activity by what its repo is about. Each tag names
+// the repo topics, languages and keywords that earn it, so a report can be
+// filtered down to, say, everything about Go or machine learning.
+//
+// Tags are kept in a JSON file holding an array of tags:
+//
+//	[
+//	  {"name": "go", "languages": ["Go"], "topics": ["golang"]},
+//	  {"name": "ml", "topics": ["machine-learning"], "keywords": ["llm", "neural network"]}
+//	]
+package tags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+// Tag describes which repos get a tag. A repo gets it if it matches any of
+// the tag's topics, languages or keywords, all compared case-insensitively.
+type Tag struct {
+	Name      string   `json:"name"`
+	Topics    []string `json:"topics,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+	Keywords  []string `json:"keywords,omitempty"` // Whole words or phrases in the repo's name or description
+}
+
+// Validate checks that the tag has a usable name and something to match.
+func (t Tag) Validate() error {
+	if t.Name == "" {
+		return errors.New("tag has no name")
+	}
+	if strings.ContainsFunc(t.Name, unicode.IsSpace) {
+		return fmt.Errorf("tag %q: name can't contain spaces", t.Name)
+	}
+	if len(t.Topics) == 0 && len(t.Languages) == 0 && len(t.Keywords) == 0 {
+		return fmt.Errorf("tag %q: needs topics, languages or keywords to match", t.Name)
+	}
+	for _, k := range t.Keywords {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("tag %q: empty keyword", t.Name)
+		}
+	}
+	return nil
+}
+
+// Load reads and validates the tags in the JSON file at path. Every invalid
+// tag is reported, not just the first.
+func Load(path string) ([]Tag, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- tags path is user-specified or a fixed default
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []Tag
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		if err := t.Validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if seen[strings.ToLower(t.Name)] {
+			errs = append(errs, fmt.Errorf("tag %q: duplicate name", t.Name))
+		}
+		seen[strings.ToLower(t.Name)] = true
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tags, nil
+}
+
+// Match returns the names of the tags repo gets, in the order they're
+// defined.
+func Match(tags []Tag, repo diff.Repo) []string {
+	var names []string
+	for _, t := range tags {
+		if t.matches(repo) {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+func (t Tag) matches(repo diff.Repo) bool {
+	for _, topic := range t.Topics {
+		if containsFold(repo.Topics, topic) {
+			return true
+		}
+	}
+	if repo.Language != "" && containsFold(t.Languages, repo.Language) {
+		return true
+	}
+	text := strings.ToLower(repo.Name + " " + repo.Description)
+	for _, k := range t.Keywords {
+		if containsWord(text, strings.ToLower(strings.TrimSpace(k))) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWord reports whether word appears in text between word
+// boundaries, so "go" is found in "go-kit" but not in "mongo".
+func containsWord(text, word string) bool {
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// isWordByte reports whether b is part of a word, treating every byte of
+// a multi-byte character as one so boundaries only fall on ASCII
+// punctuation and spaces.
+func isWordByte(b byte) bool {
+	return b >= 0x80 || b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
@@ -0,0 +1,101 @@
+package tags
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/gitstreams/diff"
+)
+
+func TestMatch(t *testing.T) {
+	tags := []Tag{
+		{Name: "go", Languages: []string{"Go"}, Topics: []string{"golang"}},
+		{Name: "ml", Topics: []string{"machine-learning"}, Keywords: []string{"llm", "neural network"}},
+		{Name: "db", Keywords: []string{"database", "go"}},
+	}
+	tests := []struct {
+		name string
+		repo diff.Repo
+		want []string
+	}{
+		{"language", diff.Repo{Name: "tool", Language: "go"}, []string{"go"}},
+		{"topic", diff.Repo{Name: "tool", Topics: []string{"Machine-Learning"}}, []string{"ml"}},
+		{"keyword in description", diff.Repo{Name: "tool", Description: "A tiny LLM runner"}, []string{"ml"}},
+		{"phrase", diff.Repo{Name: "tool", Description: "Neural network toys"}, []string{"ml"}},
+		{"keyword in name", diff.Repo{Name: "go-kit"}, []string{"db"}},
+		{"keyword inside a word", diff.Repo{Name: "mongo", Description: "databases galore"}, nil},
+		{"several, in definition order", diff.Repo{Name: "sqlc", Language: "Go", Description: "Type-safe database code"}, []string{"go", "db"}},
+		{"nothing", diff.Repo{Name: "dotfiles"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tags, tt.repo); !slices.Equal(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		text, word string
+		want       bool
+	}{
+		{"go", "go", true},
+		{"mongo go", "go", true},
+		{"mongo", "go", false},
+		{"gopher", "go", false},
+		{"(go)", "go", true},
+		{"go_kit", "go", false},
+		{"über go", "go", true},
+		{"", "go", false},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.text, tt.word); got != tt.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tt.text, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tags.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "go", "languages": ["Go"]}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tags, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(tags) != 1 || tags[0].Name != "go" {
+		t.Errorf("Load() = %+v", tags)
+	}
+
+	bad := `[
+		{"languages": ["Go"]},
+		{"name": "two words", "languages": ["Go"]},
+		{"name": "empty"},
+		{"name": "blank", "keywords": [" "]},
+		{"name": "go", "languages": ["Go"]},
+		{"name": "Go", "topics": ["golang"]}
+	]`
+	if err := os.WriteFile(path, []byte(bad), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Load(path)
+	if err == nil {
+		t.Fatal("expected an error for invalid tags")
+	}
+	for _, want := range []string{"no name", "can't contain spaces", "needs topics", "empty keyword", `"Go": duplicate name`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("Load() of a missing file: error = %v, want not exist", err)
+	}
+}
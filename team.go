@@ -0,0 +1,547 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/progress"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/serve"
+	"github.com/justinabrahms/gitstreams/source"
+	"github.com/justinabrahms/gitstreams/storage"
+	"github.com/justinabrahms/gitstreams/tags"
+)
+
+// defaultProfilesName is the team profiles file looked for in the config
+// directory when -profiles isn't given.
+const defaultProfilesName = "profiles.json"
+
+// profileSnapshotPrefix keys each team profile's snapshots in the store.
+const profileSnapshotPrefix = "profile:"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// teamProfile is a viewer on a shared server. Their follow list comes from
+// their own token, or from a public account's follows via the server's token.
+type teamProfile struct {
+	Name  string `json:"name"`
+	Token string `json:"token,omitempty"` // May name an environment variable as $VAR
+	User  string `json:"user,omitempty"`
+
+	// snapshotID keys the profile's snapshots in the store
+	snapshotID string
+}
+
+// loadProfiles reads team profiles from path, or from profiles.json in the
+// config directory if path is empty. Without a profiles file, the server has
+// one profile: the account configured for regular runs, sharing their
+// snapshot history.
+func loadProfiles(path string, cfg *Config) ([]teamProfile, error) {
+	explicit := path != ""
+	if !explicit {
+		dir, err := configDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, defaultProfilesName)
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- path is user-specified via flag or the default location
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		name := cfg.Username
+		if name == "" {
+			name = "default"
+		}
+		return []teamProfile{{Name: name, snapshotID: snapshotUserID}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading profiles: %w", err)
+	}
+
+	var profiles []teamProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("parsing profiles %s: %w", path, err)
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("%s has no profiles", path)
+	}
+	seen := make(map[string]bool)
+	for i := range profiles {
+		p := &profiles[i]
+		if !profileNamePattern.MatchString(p.Name) {
+			return nil, fmt.Errorf("profile %d: name %q must be letters, digits, - or _", i+1, p.Name)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("profile %q given twice", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Token == "" && p.User == "" {
+			return nil, fmt.Errorf("profile %q needs a token or a user", p.Name)
+		}
+		if p.Token, err = resolveToken(p.Token); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		p.snapshotID = profileSnapshotPrefix + p.Name
+	}
+	return profiles, nil
+}
+
+// profileState is what the server knows about a profile after a sync.
+type profileState struct {
+	lastSync time.Time
+	report   *report.Report
+	result   *diff.Result // The diff report was made from
+	users    int
+}
+
+// team syncs a set of viewer profiles through one shared client, fetching
+// each followed user once however many profiles follow them, and keeps each
+// profile's latest report for the dashboard.
+type team struct {
+	cfg      *Config
+	deps     *Dependencies
+	store    Store
+	state    map[string]*profileState
+	events   *progress.Broadcaster // Each sync's progress, for the dashboard
+	wake     chan struct{}         // Asks syncEvery for a sync before its next tick
+	profiles []teamProfile
+	window   time.Duration // How far back each report reaches
+	mu       sync.RWMutex
+	syncing  atomic.Bool
+}
+
+func newTeam(cfg *Config, deps *Dependencies, store Store, profiles []teamProfile, window time.Duration) *team {
+	state := make(map[string]*profileState, len(profiles))
+	for _, p := range profiles {
+		state[p.Name] = &profileState{}
+	}
+	return &team{
+		cfg:      cfg,
+		deps:     deps,
+		store:    store,
+		state:    state,
+		events:   progress.NewBroadcaster(deps.Now),
+		wake:     make(chan struct{}, 1),
+		profiles: profiles,
+		window:   window,
+	}
+}
+
+// Profiles implements serve.Source.
+func (t *team) Profiles() []serve.Profile {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	profiles := make([]serve.Profile, len(t.profiles))
+	for i, p := range t.profiles {
+		st := t.state[p.Name]
+		profiles[i] = serve.Profile{Name: p.Name, LastSync: st.lastSync, Users: st.users}
+	}
+	return profiles
+}
+
+// Report implements serve.Source.
+func (t *team) Report(name string) (*report.Report, error) {
+	if _, ok := t.profile(name); !ok {
+		return nil, serve.ErrUnknownProfile
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.state[name].report, nil
+}
+
+// DailyActivity implements serve.Source from the store's activities table.
+// Stores that don't keep one have no activity to show.
+func (t *team) DailyActivity(name string, since time.Time) ([]storage.DayCount, error) {
+	p, ok := t.profile(name)
+	if !ok {
+		return nil, serve.ErrUnknownProfile
+	}
+	as, ok := t.store.(activityStore)
+	if !ok {
+		return nil, nil
+	}
+	return as.DailyActivityCounts(p.snapshotID, since, since.Location())
+}
+
+// RepoTimeline implements serve.Source, taking star counts from the
+// profile's stored snapshots and who did what from its activities.
+func (t *team) RepoTimeline(name, repo string) (*serve.RepoTimeline, error) {
+	p, ok := t.profile(name)
+	if !ok {
+		return nil, serve.ErrUnknownProfile
+	}
+	timeline := &serve.RepoTimeline{Repo: repo}
+	if as, ok := t.store.(activityStore); ok {
+		activities, err := as.RepoActivities(p.snapshotID, repo, timelineActivities)
+		if err != nil {
+			return nil, err
+		}
+		timeline.Activities = activities
+	}
+
+	snapshots, err := t.store.GetByUser(p.snapshotID, timelineSnapshots)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		s, err := storageToSnapshot(snapshots[i])
+		if err != nil {
+			return nil, err
+		}
+		stars, ok := repoStars(s, repo)
+		if !ok || (len(timeline.Stars) > 0 && timeline.Stars[len(timeline.Stars)-1].Stars == stars) {
+			continue
+		}
+		timeline.Stars = append(timeline.Stars, serve.StarCount{At: snapshots[i].Timestamp, Stars: stars})
+	}
+
+	if len(timeline.Activities) == 0 && len(timeline.Stars) == 0 {
+		return nil, nil
+	}
+	return timeline, nil
+}
+
+// How much history a repo timeline reads.
+const (
+	timelineActivities = 500
+	timelineSnapshots  = 100
+)
+
+// repoStars finds repo's star count among a snapshot's starred and owned
+// repos.
+func repoStars(s *diff.Snapshot, repo string) (int, bool) {
+	for _, ua := range s.Users {
+		for _, repos := range [][]diff.Repo{ua.StarredRepos, ua.OwnedRepos} {
+			for _, r := range repos {
+				if r.FullName() == repo {
+					return r.Stars, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// snapshotTimer is implemented by stores that can list when snapshots were
+// taken without loading them.
+type snapshotTimer interface {
+	SnapshotTimes(userID string, since time.Time) ([]time.Time, error)
+}
+
+// Snapshots implements serve.Source.
+func (t *team) Snapshots(name string, since time.Time) ([]time.Time, error) {
+	p, ok := t.profile(name)
+	if !ok {
+		return nil, serve.ErrUnknownProfile
+	}
+	if st, ok := t.store.(snapshotTimer); ok {
+		return st.SnapshotTimes(p.snapshotID, since)
+	}
+	snapshots, err := t.store.GetByTimeRange(p.snapshotID, since, t.deps.Now())
+	if err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, len(snapshots))
+	for i, s := range snapshots {
+		times[i] = s.Timestamp
+	}
+	return times, nil
+}
+
+// Day implements serve.Source, reporting a day of the profile's history the
+// way a sync would have.
+func (t *team) Day(name string, day time.Time) (*serve.DayDiff, error) {
+	p, ok := t.profile(name)
+	if !ok {
+		return nil, serve.ErrUnknownProfile
+	}
+	taken, err := t.store.GetByTimeRange(p.snapshotID, day, day.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	if err != nil || len(taken) == 0 {
+		return nil, err
+	}
+	snapshot, err := storageToSnapshot(taken[0])
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := t.baseline(p, day.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	// A broken tags file is already warned about on every sync
+	tagSet, _ := loadTags(t.cfg.TagsPath)
+	result, rpt, err := t.compare(p, baseline, snapshot, tagSet, day, taken[0].Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return &serve.DayDiff{Result: result, Report: rpt}, nil
+}
+
+// Diff implements serve.Source.
+func (t *team) Diff(name string) (*diff.Result, error) {
+	if _, ok := t.profile(name); !ok {
+		return nil, serve.ErrUnknownProfile
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.state[name].result, nil
+}
+
+// profile looks up a profile by name.
+func (t *team) profile(name string) (teamProfile, bool) {
+	for _, p := range t.profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return teamProfile{}, false
+}
+
+// sync fetches every profile's follow list, then the activity of everyone
+// on any of them once, and saves and reports each profile's share. A profile
+// whose follow list can't be fetched keeps its previous report. Each sync
+// gives up at -timeout. It returns what the sync found, for the run
+// history, without its times.
+func (t *team) sync(ctx context.Context, stdout, stderr io.Writer) (storage.Run, error) {
+	if t.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.cfg.Timeout)
+		defer cancel()
+	}
+	now := t.deps.Now()
+	cutoff := now.AddDate(0, 0, -t.cfg.Days)
+	shared := newGitHubClient(t.cfg, t.deps, t.cfg.Token)
+	t.events.Publish(progress.Event{Time: now, Type: progress.EventStart, Message: fmt.Sprintf("Fetching follow lists for %d profiles...", len(t.profiles))})
+
+	follows := make(map[string][]github.User, len(t.profiles))
+	var everyone []github.User
+	seen := make(map[string]bool)
+	for _, p := range t.profiles {
+		users, err := t.followedUsers(ctx, p, shared)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: could not fetch follows for profile %q: %v\n", p.Name, err)
+			t.events.Publish(progress.Event{Type: progress.EventWarning, Message: fmt.Sprintf("could not fetch follows for profile %q: %v", p.Name, err)})
+			continue
+		}
+		follows[p.Name] = users
+		for _, u := range users {
+			if !seen[u.Login] {
+				seen[u.Login] = true
+				everyone = append(everyone, u)
+			}
+		}
+	}
+	run := storage.Run{Mode: report.ModeLive}
+	if len(follows) == 0 {
+		return run, errors.New("no profile's follow list could be fetched")
+	}
+
+	plan := defaultFetchPlan()
+	if t.cfg.Token == "" {
+		// Unauthenticated, as for a regular run with only -user
+		plan = fetchPlan{Events: true}
+	}
+	plan.Concurrency = t.cfg.Concurrency
+	plan, cutoff = adaptToBudget(ctx, shared, plan, len(everyone), now, cutoff, stderr)
+	subjects := source.Subjects(everyone)
+	src := githubSource(shared, plan)
+	src.Planner = planFetches(shared, plan, subjects)
+	prefetch(ctx, shared, subjects, stdout, t.cfg.Verbose)
+	all, warnings, err := fetchSubjectsActivity(ctx, src, subjects, plan.Concurrency, now, cutoff, stdout, stderr, t.events, t.cfg.Verbose)
+	run.APIRequests = requestCount(shared)
+	if err != nil {
+		return run, err
+	}
+	run.Users, run.Warnings = len(everyone), len(warnings)
+	if t.cfg.Verbose {
+		printFetchWarnings(stdout, warnings)
+	}
+
+	tagSet, err := loadTags(t.cfg.TagsPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Warning: could not tag activities: %v\n", err)
+	}
+	for _, p := range t.profiles {
+		users, ok := follows[p.Name]
+		if !ok {
+			continue
+		}
+		snapshot := diff.NewSnapshot(now)
+		for _, u := range users {
+			snapshot.Users[u.Login] = all.Users[u.Login]
+		}
+		rpt, result, err := t.profileReport(p, snapshot, tagSet, now)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: could not update profile %q: %v\n", p.Name, err)
+			continue
+		}
+
+		t.mu.Lock()
+		t.state[p.Name] = &profileState{lastSync: now, report: rpt, result: result, users: len(users)}
+		t.mu.Unlock()
+		run.Changes += countChanges(result)
+		run.Activities += rpt.TotalActivities()
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Synced %d profiles (%d users) at %s\n", len(follows), len(everyone), now.Format("15:04"))
+	if t.cfg.Verbose {
+		printRequestStats(stdout, shared)
+	}
+	return run, nil
+}
+
+// followedUsers returns the users a profile follows.
+func (t *team) followedUsers(ctx context.Context, p teamProfile, shared GitHubClient) ([]github.User, error) {
+	switch {
+	case p.Token != "":
+		return t.deps.GitHubClientFactory(p.Token).GetFollowedUsers(ctx)
+	case p.User != "":
+		return shared.GetFollowedUsersByUsername(ctx, p.User)
+	case t.cfg.Username != "":
+		return shared.GetFollowedUsersByUsername(ctx, t.cfg.Username)
+	default:
+		return shared.GetFollowedUsers(ctx)
+	}
+}
+
+// profileReport saves a profile's snapshot and reports what changed over
+// the team's window: against the newest snapshot from before it, or
+// everything fetched if the profile is newer than that.
+func (t *team) profileReport(p teamProfile, snapshot *diff.Snapshot, tagSet []tags.Tag, now time.Time) (*report.Report, *diff.Result, error) {
+	start := now.Add(-t.window)
+	baseline, err := t.baseline(p, start)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := saveSnapshotAs(t.store, p.snapshotID, snapshot, now); err != nil {
+		return nil, nil, fmt.Errorf("saving snapshot: %w", err)
+	}
+	result, rpt, err := t.compare(p, baseline, snapshot, tagSet, start, now)
+	return rpt, result, err
+}
+
+// rereport remakes a profile's report from its latest sync's snapshot, as
+// profileReport made it, for when the profile's muted users change.
+func (t *team) rereport(p teamProfile) error {
+	t.mu.RLock()
+	synced := t.state[p.Name].lastSync
+	t.mu.RUnlock()
+	if synced.IsZero() {
+		return nil
+	}
+	snapshot, err := loadLatestSnapshot(t.store, p.snapshotID)
+	if err != nil {
+		return err
+	}
+	start := synced.Add(-t.window)
+	baseline, err := t.baseline(p, start)
+	if err != nil {
+		return err
+	}
+	// A broken tags file is already warned about on every sync
+	tagSet, _ := loadTags(t.cfg.TagsPath)
+	result, rpt, err := t.compare(p, baseline, snapshot, tagSet, start, synced)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// A sync that finished meanwhile has already used the new preferences
+	if st := t.state[p.Name]; st.lastSync.Equal(synced) {
+		st.report, st.result = rpt, result
+	}
+	return nil
+}
+
+// baseline returns a profile's newest snapshot from before a time, or an
+// empty one if it has none.
+func (t *team) baseline(p teamProfile, before time.Time) (*diff.Snapshot, error) {
+	older, err := t.store.GetByTimeRange(p.snapshotID, time.Time{}, before)
+	if err != nil {
+		return nil, err
+	}
+	if len(older) == 0 {
+		return diff.NewSnapshot(time.Time{}), nil
+	}
+	return storageToSnapshot(older[0])
+}
+
+// compare diffs a profile's snapshot against baseline and reports the
+// changes between start and end, leaving out users the profile has muted.
+func (t *team) compare(p teamProfile, baseline, snapshot *diff.Snapshot, tagSet []tags.Tag, start, end time.Time) (*diff.Result, *report.Report, error) {
+	muted, err := mutedUsers(t.store, p.snapshotID)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := diff.CompareWithOptions(baseline, snapshot, diff.Options{Since: start, Muted: muted, MatchByID: true})
+	rpt := report.FromDiff(result, report.Options{GeneratedAt: end, PeriodStart: start, PeriodEnd: end, WebURL: t.cfg.GitHubURL})
+	rpt.SetProfiles(userProfiles(snapshot))
+	rpt.Pinned = t.cfg.Pin
+	rpt.Noise = t.cfg.noiseRules()
+	tagReport(rpt, tagSet, snapshot, result)
+	return result, rpt, nil
+}
+
+// syncEvery syncs the team now and then on every tick of interval, or when
+// SyncNow asks, until ctx is done, recording each sync in the run history.
+func (t *team) syncEvery(ctx context.Context, interval time.Duration, stdout, stderr io.Writer) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		t.syncing.Store(true)
+		started := t.deps.Now()
+		run, err := t.sync(ctx, stdout, stderr)
+		done := progress.Event{Type: progress.EventDone, Message: fmt.Sprintf("Synced %d users, %d new activities", run.Users, run.Activities)}
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Error syncing: %v\n", err)
+			run.Error = err.Error()
+			done.Message, done.Failed = "Sync failed: "+err.Error(), true
+		}
+		run.StartedAt, run.FinishedAt = started, t.deps.Now()
+		finishRun(ctx, t.cfg, t.store, run, stderr)
+		done.Time = run.FinishedAt
+		t.syncing.Store(false)
+		t.events.Publish(done)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-t.wake:
+		}
+	}
+}
+
+// SyncNow implements serve.Syncer, waking syncEvery for a sync before its
+// next tick. It's false if a sync is already running or about to.
+func (t *team) SyncNow() bool {
+	if t.syncing.Load() {
+		return false
+	}
+	select {
+	case t.wake <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// SyncProgress implements serve.Syncer.
+func (t *team) SyncProgress() (<-chan progress.Event, func()) {
+	return t.events.Subscribe()
+}
+
+// LastSuccessfulSync implements serve.HealthChecker from the run history.
+func (t *team) LastSuccessfulSync() (time.Time, error) {
+	return runHistory{t.store}.LastSuccessfulSync()
+}
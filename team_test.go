@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/progress"
+	"github.com/justinabrahms/gitstreams/serve"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	home := setHome(t)
+	t.Setenv("BOB_TOKEN", "ghp_bob")
+
+	// Without a profiles file, there's one profile for the regular account
+	profiles, err := loadProfiles("", &Config{Username: "octocat"})
+	if err != nil {
+		t.Fatalf("loadProfiles() error = %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].Name != "octocat" || profiles[0].snapshotID != snapshotUserID {
+		t.Errorf("unexpected default profiles %+v", profiles)
+	}
+
+	writeFile(t, filepath.Join(home, ".config", "gitstreams", defaultProfilesName),
+		`[{"name": "alice", "user": "alice"}, {"name": "bob", "token": "$BOB_TOKEN"}]`)
+	profiles, err = loadProfiles("", &Config{})
+	if err != nil {
+		t.Fatalf("loadProfiles() error = %v", err)
+	}
+	if len(profiles) != 2 || profiles[1].Token != "ghp_bob" || profiles[1].snapshotID != "profile:bob" {
+		t.Errorf("unexpected profiles %+v", profiles)
+	}
+
+	tests := []struct {
+		content string
+		wantErr string
+	}{
+		{`[]`, "no profiles"},
+		{`[{"name": "a b", "user": "x"}]`, "letters, digits"},
+		{`[{"name": "a", "user": "x"}, {"name": "a", "user": "y"}]`, "given twice"},
+		{`[{"name": "a"}]`, "token or a user"},
+		{`[{"name": "a", "token": "$UNSET_GITSTREAMS_TOKEN"}]`, "not set"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "profiles.json")
+		writeFile(t, path, tt.content)
+		if _, err := loadProfiles(path, &Config{}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("loadProfiles(%s) error = %v, want %q", tt.content, err, tt.wantErr)
+		}
+	}
+
+	if _, err := loadProfiles(filepath.Join(t.TempDir(), "missing.json"), &Config{}); err == nil {
+		t.Error("expected error for a missing explicit profiles file")
+	}
+}
+
+func TestTeamSync(t *testing.T) {
+	now := fixedTime()
+	star := func(name string) github.Repository {
+		return github.Repository{Name: name, Owner: github.User{Login: "o"}, CreatedAt: now.Add(-time.Hour)}
+	}
+	shared := &mockGitHubClient{
+		followedBy: map[string][]github.User{
+			"alice": {{Login: "rsc"}, {Login: "bradfitz"}},
+		},
+		starredRepos: map[string][]github.Repository{
+			"rsc":      {star("a")},
+			"bradfitz": {star("b")},
+			"robpike":  {star("c")},
+		},
+	}
+	bobs := &mockGitHubClient{followedUsers: []github.User{{Login: "rsc"}, {Login: "robpike"}}}
+	broken := &mockGitHubClient{followedErr: errors.New("bad credentials")}
+
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			switch token {
+			case "ghp_bob":
+				return bobs
+			case "ghp_carol":
+				return broken
+			}
+			return shared
+		},
+		Now: func() time.Time { return now },
+	}
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	profiles := []teamProfile{
+		{Name: "alice", User: "alice", snapshotID: "profile:alice"},
+		{Name: "bob", Token: "ghp_bob", snapshotID: "profile:bob"},
+		{Name: "carol", Token: "ghp_carol", snapshotID: "profile:carol"},
+	}
+	team := newTeam(&Config{Token: "ghp_server", Days: 30}, deps, store, profiles, 7*24*time.Hour)
+
+	var stdout, stderr strings.Builder
+	run, err := team.sync(context.Background(), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	if run.Mode != "live" || run.Users != 3 || run.Warnings != 0 {
+		t.Errorf("sync() run = %+v, want 3 users fetched live", run)
+	}
+
+	// rsc is followed by both alice and bob but fetched once
+	if shared.starredCalls != 3 {
+		t.Errorf("expected 3 users fetched through the shared client, got %d", shared.starredCalls)
+	}
+	if !strings.Contains(stderr.String(), `profile "carol"`) {
+		t.Errorf("expected a warning for carol, got %q", stderr.String())
+	}
+
+	for name, want := range map[string][]string{"alice": {"rsc", "bradfitz"}, "bob": {"rsc", "robpike"}} {
+		rpt, err := team.Report(name)
+		if err != nil || rpt == nil {
+			t.Fatalf("Report(%s) = %v, %v", name, rpt, err)
+		}
+		if len(rpt.UserActivities) != len(want) {
+			t.Errorf("%s's report has %d users, want %v", name, len(rpt.UserActivities), want)
+		}
+		snaps, _ := store.GetByUser("profile:"+name, 10)
+		if len(snaps) != 1 {
+			t.Errorf("expected 1 saved snapshot for %s, got %d", name, len(snaps))
+		}
+	}
+	if rpt, err := team.Report("carol"); err != nil || rpt != nil {
+		t.Errorf("carol should have no report yet, got %v, %v", rpt, err)
+	}
+	if _, err := team.Report("dave"); !errors.Is(err, serve.ErrUnknownProfile) {
+		t.Errorf("expected ErrUnknownProfile, got %v", err)
+	}
+
+	if result, err := team.Diff("alice"); err != nil || result == nil || len(result.NewStars) != 2 {
+		t.Errorf("Diff(alice) = %+v, %v; want the two new stars", result, err)
+	}
+	if result, err := team.Diff("carol"); err != nil || result != nil {
+		t.Errorf("carol should have no diff yet, got %v, %v", result, err)
+	}
+
+	listed := team.Profiles()
+	if len(listed) != 3 || listed[0].Users != 2 || !listed[0].LastSync.Equal(now) || !listed[2].LastSync.IsZero() {
+		t.Errorf("unexpected profiles %+v", listed)
+	}
+}
+
+func TestTeamSync_Progress(t *testing.T) {
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			if token == "ghp_bob" {
+				return &mockGitHubClient{followedErr: errors.New("bad credentials")}
+			}
+			return &mockGitHubClient{followedUsers: []github.User{{Login: "rsc"}, {Login: "robpike"}}}
+		},
+		Now: fixedTime,
+	}
+	profiles := []teamProfile{{Name: "alice", snapshotID: "profile:alice"}, {Name: "bob", Token: "ghp_bob"}}
+	team := newTeam(&Config{Days: 30}, deps, &mockStore{}, profiles, time.Hour)
+	var stdout, stderr strings.Builder
+	if _, err := team.sync(context.Background(), &stdout, &stderr); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+
+	events, cancel := team.SyncProgress()
+	cancel()
+	var got []progress.Event
+	for e := range events {
+		got = append(got, e)
+	}
+	if len(got) != 3 || got[0].Type != progress.EventStart || got[1].Type != progress.EventWarning || !strings.Contains(got[1].Message, `"bob"`) {
+		t.Fatalf("progress = %+v, want the start, bob's warning and the latest step", got)
+	}
+	if last := got[2]; last.Type != progress.EventProgress || last.Current != 2 || last.Total != 2 {
+		t.Errorf("latest step = %+v, want user 2 of 2", last)
+	}
+}
+
+func TestTeamSyncNow(t *testing.T) {
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			return &mockGitHubClient{followedErr: errors.New("bad credentials")}
+		},
+		Now: fixedTime,
+	}
+	team := newTeam(&Config{Days: 30}, deps, &mockStore{}, []teamProfile{{Name: "me"}}, time.Hour)
+	events, cancel := team.SyncProgress()
+	defer cancel()
+	// waitDone waits for a sync to finish
+	waitDone := func() {
+		t.Helper()
+		for e := range events {
+			if e.Type == progress.EventDone {
+				if !e.Failed {
+					t.Errorf("done = %+v, want the sync to have failed", e)
+				}
+				return
+			}
+		}
+		t.Fatal("progress stream ended before the sync finished")
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	synced := make(chan struct{})
+	go func() {
+		defer close(synced)
+		team.syncEvery(ctx, time.Hour, io.Discard, io.Discard)
+	}()
+	waitDone()
+	if !team.SyncNow() {
+		t.Fatal("SyncNow() = false between syncs, want true")
+	}
+	waitDone()
+	stop()
+	<-synced
+}
+
+func TestTeamSync_NoFollowLists(t *testing.T) {
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient {
+			return &mockGitHubClient{followedErr: errors.New("bad credentials")}
+		},
+		Now: fixedTime,
+	}
+	team := newTeam(&Config{Days: 30}, deps, &mockStore{}, []teamProfile{{Name: "me"}}, time.Hour)
+	var stdout, stderr strings.Builder
+	if _, err := team.sync(context.Background(), &stdout, &stderr); err == nil {
+		t.Error("expected an error when no follow list could be fetched")
+	}
+}
+
+func TestTeamLastSuccessfulSync(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	team := newTeam(&Config{}, &Dependencies{Now: fixedTime}, store, nil, time.Hour)
+
+	if last, err := team.LastSuccessfulSync(); err != nil || !last.IsZero() {
+		t.Errorf("LastSuccessfulSync() before any = %v, %v; want zero", last, err)
+	}
+	finished := fixedTime().Add(time.Minute)
+	var stderr strings.Builder
+	finishRun(context.Background(), team.cfg, store, storage.Run{StartedAt: fixedTime(), FinishedAt: finished}, &stderr)
+	finishRun(context.Background(), team.cfg, store, storage.Run{StartedAt: finished, FinishedAt: finished.Add(time.Hour), Error: "boom"}, &stderr)
+	if last, err := team.LastSuccessfulSync(); err != nil || !last.Equal(finished) {
+		t.Errorf("LastSuccessfulSync() = %v, %v; want %v", last, err, finished)
+	}
+
+	noHistory := newTeam(&Config{}, &Dependencies{Now: fixedTime}, &mockStore{}, nil, time.Hour)
+	if _, err := noHistory.LastSuccessfulSync(); err == nil {
+		t.Error("expected an error from a store without a run history")
+	}
+}
+
+func TestTeamRepoTimeline(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := fixedTime()
+	for i, stars := range []int{10, 10, 14} {
+		s := diff.NewSnapshot(now)
+		s.Users["rsc"] = diff.UserActivity{
+			Username:     "rsc",
+			StarredRepos: []diff.Repo{{Owner: "golang", Name: "go", Stars: stars}},
+		}
+		if i == 2 {
+			s.Users["rsc"] = diff.UserActivity{
+				Username:     "rsc",
+				StarredRepos: s.Users["rsc"].StarredRepos,
+				Events:       []diff.Event{{Type: "ReleaseEvent", Repo: "golang/go", CreatedAt: now}},
+			}
+		}
+		if _, err := saveSnapshotAs(store, "profile:alice", s, now.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	team := newTeam(&Config{}, &Dependencies{Now: fixedTime}, store, []teamProfile{{Name: "alice", snapshotID: "profile:alice"}}, time.Hour)
+	timeline, err := team.RepoTimeline("alice", "golang/go")
+	if err != nil || timeline == nil {
+		t.Fatalf("RepoTimeline() = %v, %v", timeline, err)
+	}
+	if len(timeline.Stars) != 2 || timeline.StarsGained() != 4 || !timeline.Stars[0].At.Equal(now) {
+		t.Errorf("expected star counts where they changed, got %+v", timeline.Stars)
+	}
+	if len(timeline.Activities) != 2 || len(timeline.Releases()) != 1 {
+		t.Errorf("unexpected activities %+v", timeline.Activities)
+	}
+
+	if timeline, err := team.RepoTimeline("alice", "nobody/nothing"); err != nil || timeline != nil {
+		t.Errorf("unknown repo: RepoTimeline() = %v, %v; want nil", timeline, err)
+	}
+	if _, err := team.RepoTimeline("dave", "golang/go"); !errors.Is(err, serve.ErrUnknownProfile) {
+		t.Errorf("expected ErrUnknownProfile, got %v", err)
+	}
+}
+
+func TestTeamDay(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := fixedTime()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	event := func(repo string, at time.Time) diff.Event {
+		return diff.Event{ID: repo, Type: "PushEvent", Actor: "rsc", Repo: repo, CreatedAt: at}
+	}
+	// One snapshot the day before, two on the day
+	var events []diff.Event
+	for _, at := range []time.Time{day.Add(-2 * time.Hour), day.Add(time.Hour), day.Add(3 * time.Hour)} {
+		events = append(events, event("golang/"+at.Format("15"), at))
+		s := diff.NewSnapshot(at)
+		s.Users["rsc"] = diff.UserActivity{Username: "rsc", Events: slices.Clone(events)}
+		if _, err := saveSnapshotAs(store, "profile:alice", s, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	team := newTeam(&Config{}, &Dependencies{Now: fixedTime}, store, []teamProfile{{Name: "alice", snapshotID: "profile:alice"}}, time.Hour)
+	times, err := team.Snapshots("alice", day.AddDate(0, 0, -7))
+	if err != nil || len(times) != 3 || !times[0].Equal(day.Add(3*time.Hour)) {
+		t.Errorf("Snapshots() = %v, %v", times, err)
+	}
+
+	dd, err := team.Day("alice", day)
+	if err != nil || dd == nil {
+		t.Fatalf("Day() = %v, %v", dd, err)
+	}
+	var repos []string
+	for _, c := range dd.Result.NewEvents {
+		repos = append(repos, c.Event.Repo)
+	}
+	slices.Sort(repos)
+	want := []string{"golang/" + day.Add(time.Hour).Format("15"), "golang/" + day.Add(3*time.Hour).Format("15")}
+	if !slices.Equal(repos, want) {
+		t.Errorf("Day() found new events in %v, want %v", repos, want)
+	}
+	if len(dd.Report.UserActivities) != 1 || !dd.Report.PeriodStart.Equal(day) {
+		t.Errorf("unexpected day report %+v", dd.Report)
+	}
+
+	if dd, err := team.Day("alice", day.AddDate(0, 0, -3)); err != nil || dd != nil {
+		t.Errorf("a day without snapshots: Day() = %v, %v; want nil", dd, err)
+	}
+	if _, err := team.Day("dave", day); !errors.Is(err, serve.ErrUnknownProfile) {
+		t.Errorf("expected ErrUnknownProfile, got %v", err)
+	}
+}
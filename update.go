@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+const (
+	// releaseOwner and releaseRepo name the repository whose releases are
+	// checked for updates.
+	releaseOwner = "justinabrahms"
+	releaseRepo  = "gitstreams"
+
+	// updateCheckInterval is how often the release check hits GitHub; in
+	// between, the last answer is reused.
+	updateCheckInterval = 24 * time.Hour
+
+	// updateCheckTimeout bounds the release check so a slow network never
+	// holds up a run.
+	updateCheckTimeout = 3 * time.Second
+
+	updateStateName = "update-check.json"
+)
+
+// updateState records the last release check, kept in the data directory.
+type updateState struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest,omitempty"`
+	URL       string    `json:"url,omitempty"`
+}
+
+// latestGitstreamsRelease asks GitHub for the newest gitstreams release.
+func latestGitstreamsRelease(ctx context.Context) (*github.Release, error) {
+	return github.NewClient("").GetLatestRelease(ctx, releaseOwner, releaseRepo)
+}
+
+// checkForUpdate returns a one-line notice if a release newer than current
+// is available, or "" if not. GitHub is asked at most once per
+// updateCheckInterval; the answer is cached in statePath. Development
+// builds are never checked.
+func checkForUpdate(ctx context.Context, statePath, current string, now time.Time, latest func(context.Context) (*github.Release, error)) (string, error) {
+	if _, ok := parseVersion(current); !ok {
+		return "", nil
+	}
+
+	state, err := loadUpdateState(statePath)
+	if err != nil {
+		return "", err
+	}
+
+	var checkErr error
+	if now.Sub(state.CheckedAt) >= updateCheckInterval || now.Before(state.CheckedAt) {
+		ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+		release, err := latest(ctx)
+		cancel()
+		if err != nil {
+			checkErr = fmt.Errorf("checking for updates: %w", err)
+		} else {
+			state.Latest = release.TagName
+			state.URL = release.HTMLURL
+		}
+		// Failures are rate limited too, so an offline host isn't slowed
+		// down on every run
+		state.CheckedAt = now
+		if err := saveUpdateState(statePath, state); err != nil && checkErr == nil {
+			checkErr = err
+		}
+	}
+
+	if !newerVersion(state.Latest, current) {
+		return "", checkErr
+	}
+	return updateNotice(state.Latest, current, state.URL), checkErr
+}
+
+func updateNotice(latest, current, url string) string {
+	msg := fmt.Sprintf("gitstreams %s is available (you have %s)", latest, current)
+	if url != "" {
+		msg += ": " + url
+	}
+	return msg
+}
+
+func loadUpdateState(path string) (updateState, error) {
+	var state updateState
+	data, err := os.ReadFile(path) // #nosec G304 -- path is in the gitstreams data directory
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("reading update check state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		// A corrupt state file just means checking again
+		return updateState{}, nil
+	}
+	return state, nil
+}
+
+func saveUpdateState(path string, state updateState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("saving update check state: %w", err)
+	}
+	return nil
+}
+
+// newerVersion reports whether latest is a higher release than current.
+// Versions that don't parse are never newer.
+func newerVersion(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses "v1.2.3" or "1.2.3" into its numeric parts. Any
+// pre-release or build suffix ("-rc1", "+abc") is ignored.
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// updateNoticeFor runs the daily update check unless it's disabled or the
+// run is offline. Failures are only reported in verbose mode; an update
+// check should never get in the way.
+func updateNoticeFor(ctx context.Context, cfg *Config, deps *Dependencies, stderr io.Writer) string {
+	if cfg.NoUpdateCheck || cfg.Offline || deps.LatestRelease == nil {
+		return ""
+	}
+	dir, err := dataDir(cfg.LegacyDirs)
+	if err == nil {
+		err = os.MkdirAll(dir, 0750)
+	}
+	if err != nil {
+		return ""
+	}
+
+	notice, err := checkForUpdate(ctx, filepath.Join(dir, updateStateName), version, deps.Now(), deps.LatestRelease)
+	if err != nil && cfg.Verbose {
+		_, _ = fmt.Fprintf(stderr, "Warning: %v\n", err)
+	}
+	return notice
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/github"
+)
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest  string
+		current string
+		want    bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v2.0.0", "1.9.9", true},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.1.0", "v1.2.0", false},
+		{"v1.2.0", "v1.2.0-rc1", false},
+		{"", "v1.0.0", false},
+		{"v1.2.0", "dev", false},
+		{"nightly", "v1.0.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := newerVersion(tt.latest, tt.current); got != tt.want {
+			t.Errorf("newerVersion(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), updateStateName)
+	now := fixedTime()
+
+	calls := 0
+	latest := func(context.Context) (*github.Release, error) {
+		calls++
+		return &github.Release{TagName: "v1.3.0", HTMLURL: "https://example.com/v1.3.0"}, nil
+	}
+
+	notice, err := checkForUpdate(context.Background(), statePath, "v1.2.0", now, latest)
+	if err != nil {
+		t.Fatalf("checkForUpdate() error = %v", err)
+	}
+	if want := "gitstreams v1.3.0 is available (you have v1.2.0): https://example.com/v1.3.0"; notice != want {
+		t.Errorf("notice = %q, want %q", notice, want)
+	}
+
+	// Within a day the cached answer is reused
+	notice, err = checkForUpdate(context.Background(), statePath, "v1.2.0", now.Add(time.Hour), latest)
+	if err != nil || notice == "" {
+		t.Errorf("cached check = %q, %v; want notice", notice, err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 release lookup within a day, got %d", calls)
+	}
+
+	// A day later GitHub is asked again
+	if _, err := checkForUpdate(context.Background(), statePath, "v1.2.0", now.Add(25*time.Hour), latest); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected a second lookup after a day, got %d", calls)
+	}
+
+	// Up to date
+	notice, _ = checkForUpdate(context.Background(), statePath, "v1.3.0", now.Add(26*time.Hour), latest)
+	if notice != "" {
+		t.Errorf("expected no notice when up to date, got %q", notice)
+	}
+}
+
+func TestCheckForUpdate_Failure(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), updateStateName)
+	now := fixedTime()
+
+	calls := 0
+	failing := func(context.Context) (*github.Release, error) {
+		calls++
+		return nil, errors.New("network unreachable")
+	}
+
+	notice, err := checkForUpdate(context.Background(), statePath, "v1.2.0", now, failing)
+	if err == nil || notice != "" {
+		t.Errorf("checkForUpdate() = %q, %v; want error and no notice", notice, err)
+	}
+	// Failed checks are rate limited too
+	if _, err := checkForUpdate(context.Background(), statePath, "v1.2.0", now.Add(time.Hour), failing); err != nil {
+		t.Errorf("expected cached result after a failed check, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 lookup, got %d", calls)
+	}
+}
+
+func TestCheckForUpdate_DevBuild(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), updateStateName)
+	latest := func(context.Context) (*github.Release, error) {
+		t.Error("development builds shouldn't check for updates")
+		return nil, nil
+	}
+
+	notice, err := checkForUpdate(context.Background(), statePath, "dev", fixedTime(), latest)
+	if err != nil || notice != "" {
+		t.Errorf("checkForUpdate() = %q, %v; want nothing", notice, err)
+	}
+	if fileExists(statePath) {
+		t.Error("expected no state file for development builds")
+	}
+}
+
+func TestCheckForUpdate_CorruptState(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), updateStateName)
+	if err := os.WriteFile(statePath, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	latest := func(context.Context) (*github.Release, error) {
+		return &github.Release{TagName: "v9.0.0"}, nil
+	}
+
+	notice, err := checkForUpdate(context.Background(), statePath, "v1.0.0", fixedTime(), latest)
+	if err != nil || !strings.Contains(notice, "v9.0.0") {
+		t.Errorf("checkForUpdate() = %q, %v; want fresh check", notice, err)
+	}
+}
+
+func TestRun_UpdateNotice(t *testing.T) {
+	setHome(t)
+	oldVersion := version
+	version = "v1.0.0"
+	t.Cleanup(func() { version = oldVersion })
+
+	checked := false
+	deps := &Dependencies{
+		StoreFactory: func(string) (Store, error) { return nil, errors.New("stop") },
+		LatestRelease: func(context.Context) (*github.Release, error) {
+			checked = true
+			return &github.Release{TagName: "v1.1.0"}, nil
+		},
+		Now:    fixedTime,
+		Logger: slog.Default(),
+	}
+	var stdout, stderr strings.Builder
+
+	_ = run(&stdout, &stderr, []string{"-no-notify", "-no-open"}, deps)
+	if !strings.Contains(stdout.String(), "gitstreams v1.1.0 is available (you have v1.0.0)") {
+		t.Errorf("expected update notice in output, got %q", stdout.String())
+	}
+
+	checked = false
+	stdout.Reset()
+	_ = run(&stdout, &stderr, []string{"-no-update-check"}, deps)
+	_ = run(&stdout, &stderr, []string{"-offline"}, deps)
+	if checked || strings.Contains(stdout.String(), "is available") {
+		t.Error("update check should be skipped with -no-update-check or -offline")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/serve"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// userStore is implemented by stores that keep followed users' profiles
+// and each viewer's prefs for them, for the dashboard's user pages.
+type userStore interface {
+	UserActivities(scope, username string, limit int) ([]storage.Activity, error)
+	UserRepos(scope, username string, limit int) ([]storage.RepoCount, error)
+	UserDailyActivityCounts(scope, username string, since time.Time, loc *time.Location) ([]storage.DayCount, error)
+	GetUserInfo(username string) (*storage.UserInfo, error)
+	SaveUserInfo(info storage.UserInfo) error
+	GetUserPrefs(scope, username string) (storage.UserPrefs, error)
+	SetUserPrefs(prefs storage.UserPrefs) error
+	MutedUsers(scope string) ([]string, error)
+}
+
+// userFetcher is implemented by clients that can fetch a user's profile.
+type userFetcher interface {
+	GetUser(ctx context.Context, username string) (*github.User, error)
+}
+
+// userInfoTTL is how long a followed user's cached name and bio are shown
+// before they're fetched again.
+const userInfoTTL = 7 * 24 * time.Hour
+
+// How much of a user's history their page shows.
+const (
+	userPageActivities = 50
+	userPageRepos      = 20
+)
+
+// User implements serve.Source. The user's name and bio are fetched from
+// GitHub when the cached copy is missing or stale; if that fails, the page
+// does without or with the stale copy.
+func (t *team) User(ctx context.Context, name, username string, since time.Time) (*serve.UserDetails, error) {
+	p, ok := t.profile(name)
+	if !ok {
+		return nil, serve.ErrUnknownProfile
+	}
+	us, ok := t.store.(userStore)
+	if !ok {
+		return nil, nil
+	}
+
+	var details serve.UserDetails
+	var err error
+	if details.Prefs, err = us.GetUserPrefs(p.snapshotID, username); err != nil {
+		return nil, err
+	}
+	if details.Recent, err = us.UserActivities(p.snapshotID, username, userPageActivities); err != nil {
+		return nil, err
+	}
+	// Muted or tagged users stay reachable so they can be unmuted
+	if len(details.Recent) == 0 && !details.Prefs.Muted && len(details.Prefs.Tags) == 0 {
+		return nil, nil
+	}
+	if details.Repos, err = us.UserRepos(p.snapshotID, username, userPageRepos); err != nil {
+		return nil, err
+	}
+	if details.Daily, err = us.UserDailyActivityCounts(p.snapshotID, username, since, since.Location()); err != nil {
+		return nil, err
+	}
+	if details.Info, err = us.GetUserInfo(username); err != nil {
+		return nil, err
+	}
+	if details.Info == nil || t.deps.Now().Sub(details.Info.FetchedAt) > userInfoTTL {
+		if info := t.fetchUserInfo(ctx, us, username); info != nil {
+			details.Info = info
+		}
+	}
+	return &details, nil
+}
+
+// fetchUserInfo fetches and caches a user's profile, returning nil if it
+// can't.
+func (t *team) fetchUserInfo(ctx context.Context, us userStore, username string) *storage.UserInfo {
+	fetcher, ok := t.deps.GitHubClientFactory(t.cfg.Token).(userFetcher)
+	if !ok {
+		return nil
+	}
+	user, err := fetcher.GetUser(ctx, username)
+	if err != nil {
+		return nil
+	}
+	info := storage.UserInfo{Username: username, Name: user.Name, Bio: user.Bio, FetchedAt: t.deps.Now()}
+	if err := us.SaveUserInfo(info); err != nil {
+		return nil
+	}
+	return &info
+}
+
+// userProfiles maps each user in a snapshot with a fetched profile to it.
+func userProfiles(s *diff.Snapshot) map[string]diff.Profile {
+	profiles := make(map[string]diff.Profile)
+	for name, ua := range s.Users {
+		if ua.Profile != nil {
+			profiles[name] = *ua.Profile
+		}
+	}
+	return profiles
+}
+
+// SetUserPrefs implements serve.Source.
+func (t *team) SetUserPrefs(name string, prefs storage.UserPrefs) error {
+	p, ok := t.profile(name)
+	if !ok {
+		return serve.ErrUnknownProfile
+	}
+	us, ok := t.store.(userStore)
+	if !ok {
+		return nil
+	}
+	prefs.Scope = p.snapshotID
+	if err := us.SetUserPrefs(prefs); err != nil {
+		return err
+	}
+	// Reports leave muted users out as they're made, so the current one is
+	// made again
+	return t.rereport(p)
+}
+
+// mutedUsers returns the users muted in scope, or none if the store
+// doesn't keep preferences.
+func mutedUsers(store Store, scope string) ([]string, error) {
+	us, ok := store.(userStore)
+	if !ok {
+		return nil, nil
+	}
+	return us.MutedUsers(scope)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/serve"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+// profileClient is a mock client that can also fetch user profiles.
+type profileClient struct {
+	*mockGitHubClient
+	users map[string]*github.User
+	calls int
+}
+
+func (c *profileClient) GetUser(ctx context.Context, username string) (*github.User, error) {
+	c.calls++
+	if u, ok := c.users[username]; ok {
+		return u, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestTeamUser(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := fixedTime()
+	s := diff.NewSnapshot(now)
+	s.Users["rsc"] = diff.UserActivity{
+		Username:     "rsc",
+		StarredRepos: []diff.Repo{{Owner: "golang", Name: "go", CreatedAt: now.AddDate(0, 0, -1)}},
+		Events:       []diff.Event{{Type: "PushEvent", Repo: "rsc/tmp", CreatedAt: now}},
+	}
+	if _, err := saveSnapshotAs(store, "profile:alice", s, now); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &profileClient{
+		mockGitHubClient: &mockGitHubClient{},
+		users:            map[string]*github.User{"rsc": {Login: "rsc", Name: "Russ Cox", Bio: "Go"}},
+	}
+	clock := now
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return client },
+		Now:                 func() time.Time { return clock },
+	}
+	team := newTeam(&Config{}, deps, store, []teamProfile{{Name: "alice", snapshotID: "profile:alice"}}, time.Hour)
+
+	details, err := team.User(context.Background(), "alice", "rsc", now.AddDate(0, 0, -30))
+	if err != nil || details == nil {
+		t.Fatalf("User() = %v, %v", details, err)
+	}
+	if details.Info == nil || details.Info.Bio != "Go" {
+		t.Errorf("expected the fetched bio, got %+v", details.Info)
+	}
+	if len(details.Recent) != 2 || len(details.Repos) != 2 || len(details.Daily) != 2 {
+		t.Errorf("unexpected details %+v", details)
+	}
+
+	// The profile is cached until it goes stale
+	if _, err := team.User(context.Background(), "alice", "rsc", now); err != nil || client.calls != 1 {
+		t.Errorf("expected a cached profile, got %d fetches, err %v", client.calls, err)
+	}
+	clock = now.Add(userInfoTTL + time.Hour)
+	client.users["rsc"].Bio = "Go, still"
+	if details, _ := team.User(context.Background(), "alice", "rsc", now); client.calls != 2 || details.Info.Bio != "Go, still" {
+		t.Errorf("expected a refetch after %v, got %d fetches", userInfoTTL, client.calls)
+	}
+
+	if details, err := team.User(context.Background(), "alice", "nobody", now); err != nil || details != nil {
+		t.Errorf("unknown user: User() = %v, %v; want nil", details, err)
+	}
+	if _, err := team.User(context.Background(), "dave", "rsc", now); !errors.Is(err, serve.ErrUnknownProfile) {
+		t.Errorf("expected ErrUnknownProfile, got %v", err)
+	}
+}
+
+func TestTeamMute(t *testing.T) {
+	store, err := storage.NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	profiles := []teamProfile{{Name: "alice", snapshotID: "profile:alice"}, {Name: "bob", snapshotID: "profile:bob"}}
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return &mockGitHubClient{} },
+		Now:                 fixedTime,
+	}
+	team := newTeam(&Config{}, deps, store, profiles, time.Hour)
+	now := fixedTime()
+	snapshot := diff.NewSnapshot(now)
+	for _, user := range []string{"rsc", "bradfitz"} {
+		snapshot.Users[user] = diff.UserActivity{Username: user, StarredRepos: []diff.Repo{{Owner: "golang", Name: "go", CreatedAt: now.Add(-time.Minute)}}}
+	}
+	for _, p := range profiles {
+		if _, err := saveSnapshotAs(store, p.snapshotID, snapshot, now); err != nil {
+			t.Fatal(err)
+		}
+		team.state[p.Name].lastSync = now
+		if err := team.rereport(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := team.SetUserPrefs("alice", storage.UserPrefs{Username: "rsc", Muted: true, Tags: []string{"go"}}); err != nil {
+		t.Fatalf("SetUserPrefs() error = %v", err)
+	}
+	if err := team.SetUserPrefs("dave", storage.UserPrefs{Username: "rsc"}); !errors.Is(err, serve.ErrUnknownProfile) {
+		t.Errorf("expected ErrUnknownProfile, got %v", err)
+	}
+
+	rpt, err := team.Report("alice")
+	if err != nil || len(rpt.UserActivities) != 1 || rpt.UserActivities[0].User != "bradfitz" {
+		t.Errorf("expected rsc muted from alice's report, got %+v, %v", rpt, err)
+	}
+	if result, _ := team.Diff("alice"); len(result.NewStars) != 1 || result.NewStars[0].Username != "bradfitz" {
+		t.Errorf("expected rsc muted from alice's diff, got %+v", result)
+	}
+	if rpt, _ := team.Report("bob"); len(rpt.UserActivities) != 2 {
+		t.Errorf("muting is per profile; bob's report has %d users", len(rpt.UserActivities))
+	}
+
+	// A muted user still has a page to unmute them from
+	if details, err := team.User(context.Background(), "alice", "rsc", fixedTime()); err != nil || details == nil || !details.Prefs.Muted {
+		t.Errorf("User() for a muted user = %+v, %v", details, err)
+	}
+
+	if err := team.SetUserPrefs("alice", storage.UserPrefs{Username: "rsc"}); err != nil {
+		t.Fatalf("SetUserPrefs() error = %v", err)
+	}
+	if rpt, _ := team.Report("alice"); len(rpt.UserActivities) != 2 {
+		t.Errorf("unmuting should bring rsc back; alice's report has %d users", len(rpt.UserActivities))
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/serve"
+)
+
+// runWatch handles the "watch" subcommand: it runs a regular sync on an
+// interval until interrupted, saving a snapshot each time and notifying
+// only when a sync finds new activity.
+func runWatch(stdout, stderr io.Writer, args []string, deps *Dependencies) int {
+	fs := newFlagSet()
+	fs.SetOutput(stderr)
+	interval := fs.Duration("interval", time.Hour, "How often to sync")
+	healthAddr := fs.String("health-addr", "", "Serve /healthz on this address, e.g. :8081, for uptime checks")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	sources, err := fs.resolve()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := applySubcommandEnv(fs); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg := fs.cfg
+	if *interval < time.Minute {
+		_, _ = fmt.Fprintf(stderr, "Error: interval must be at least 1m, got %s\n", *interval)
+		return 1
+	}
+	if cfg.Offline || cfg.ReportSince != "" {
+		_, _ = fmt.Fprintln(stderr, "Error: watch syncs with GitHub, so -offline and -report-since don't apply")
+		return 1
+	}
+
+	// A tab opened on every sync would pile up; open one only if asked
+	if sources["no-open"] == sourceDefault {
+		cfg.NoOpen = true
+	}
+	if deps.IsHeadless != nil && deps.IsHeadless() {
+		applyHeadlessDefaults(cfg, sources)
+	}
+
+	store, err := openStore(stderr, cfg, deps)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = store.Close() }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *healthAddr != "" {
+		health := serve.HealthHandler(serve.Options{
+			Health:     runHistory{store},
+			MaxSyncAge: maxSyncAge(*interval, cfg.Timeout),
+			Now:        deps.Now,
+			Logger:     deps.Logger,
+		})
+		shutdown, err := serveHealth(*healthAddr, health, stdout, stderr)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer shutdown()
+	}
+
+	p := &pipeline{cfg: cfg, deps: deps, store: store, stdout: stdout, stderr: stderr}
+	p.releaseNotice = updateNoticeFor(ctx, cfg, deps, stderr)
+	_, _ = fmt.Fprintf(stdout, "Watching for new activity every %s\n", *interval)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	watchEvery(ctx, ticker.C, func(ctx context.Context) { p.sync(ctx) })
+	_, _ = fmt.Fprintln(stdout, "Stopped watching.")
+	return 0
+}
+
+// serveHealth serves h on addr until the returned function shuts it down.
+func serveHealth(addr string, h http.Handler, stdout, stderr io.Writer) (shutdown func(), err error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			_, _ = fmt.Fprintf(stderr, "Warning: health checks stopped: %v\n", err)
+		}
+	}()
+	_, _ = fmt.Fprintf(stdout, "Serving health checks on http://%s/healthz\n", ln.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+		<-served
+	}, nil
+}
+
+// watchEvery calls sync now and then on every tick until ctx is done.
+func watchEvery(ctx context.Context, tick <-chan time.Time, sync func(ctx context.Context)) {
+	for {
+		sync(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		}
+	}
+}
+
+// sync runs the pipeline once, giving up at -timeout. Failures are
+// reported and left for the next sync to retry; being stopped isn't one.
+func (p *pipeline) sync(ctx context.Context) {
+	if p.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.Timeout)
+		defer cancel()
+	}
+	err := p.execute(ctx)
+	if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		_, _ = fmt.Fprintf(p.stderr, "Error syncing: %v\n", err)
+	}
+}
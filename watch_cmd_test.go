@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/github/githubtest"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/serve"
+	"github.com/justinabrahms/gitstreams/storage"
+)
+
+func TestRunWatch_InvalidSettings(t *testing.T) {
+	setHome(t)
+	deps := &Dependencies{StoreFactory: func(path string) (Store, error) { return nil, errors.New("stop") }}
+
+	var stdout, stderr strings.Builder
+	if code := runWatch(&stdout, &stderr, []string{"-interval", "10s"}, deps); code != 1 || !strings.Contains(stderr.String(), "at least 1m") {
+		t.Errorf("expected interval error, got %d: %s", code, stderr.String())
+	}
+
+	stderr.Reset()
+	if code := runWatch(&stdout, &stderr, []string{"-offline"}, deps); code != 1 || !strings.Contains(stderr.String(), "-offline") {
+		t.Errorf("expected offline error, got %d: %s", code, stderr.String())
+	}
+
+	stderr.Reset()
+	if code := runWatch(&stdout, &stderr, nil, deps); code != 1 || !strings.Contains(stderr.String(), "stop") {
+		t.Errorf("expected store error, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestWatchEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tick := make(chan time.Time)
+	syncs := make(chan int, 10)
+	n := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watchEvery(ctx, tick, func(context.Context) {
+			n++
+			syncs <- n
+		})
+	}()
+
+	if got := <-syncs; got != 1 {
+		t.Fatalf("expected a sync straight away, got sync %d", got)
+	}
+	tick <- time.Now()
+	if got := <-syncs; got != 2 {
+		t.Fatalf("expected a sync on the tick, got sync %d", got)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchEvery didn't stop when its context was done")
+	}
+}
+
+func TestPipelineSync_NotifiesOnlyOnNewActivity(t *testing.T) {
+	setHome(t)
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	srv := githubtest.NewServer(t)
+	srv.Follow("alice")
+
+	notifier := &mockNotifier{}
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return srv.Client(token) },
+		NotifierFactory:     func() Notifier { return notifier },
+		ReportGenerator:     func(format string) (ReportGenerator, error) { return report.New(format) },
+		OpenBrowser:         func(url string) error { return errors.New("watch shouldn't open the browser") },
+		Now:                 func() time.Time { return now },
+	}
+	store, err := storage.Open(filepath.Join(t.TempDir(), "gitstreams.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	var stdout, stderr strings.Builder
+	cfg := &Config{Token: "test-token", Format: report.FormatJSON, ReportPath: filepath.Join(t.TempDir(), "report.json"), Days: 30, Concurrency: 1, NoOpen: true}
+	p := &pipeline{cfg: cfg, deps: deps, store: store, stdout: &stdout, stderr: &stderr}
+
+	p.sync(context.Background())
+	notifier.sentNotification = nil
+	now = now.Add(time.Hour)
+	p.sync(context.Background())
+	if notifier.sentNotification != nil {
+		t.Errorf("expected no notification without new activity, got %+v", notifier.sentNotification)
+	}
+
+	now = now.Add(time.Hour)
+	srv.AddRepos("alice", github.Repository{ID: 1, Name: "tool", FullName: "alice/tool", CreatedAt: now.Add(-time.Minute)})
+	p.sync(context.Background())
+	if notifier.sentNotification == nil {
+		t.Errorf("expected a notification for alice's new repo; stdout: %s stderr: %s", stdout.String(), stderr.String())
+	}
+	if strings.Contains(stderr.String(), "Error") {
+		t.Errorf("unexpected errors: %s", stderr.String())
+	}
+
+	snapshots, err := store.GetByUser(snapshotUserID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 3 {
+		t.Errorf("expected a snapshot per sync, got %d", len(snapshots))
+	}
+}
+
+func TestPipelineSync_StoppedIsntAnError(t *testing.T) {
+	setHome(t)
+	srv := githubtest.NewServer(t)
+	srv.Follow("alice")
+	deps := &Dependencies{
+		GitHubClientFactory: func(token string) GitHubClient { return srv.Client(token) },
+		Now:                 fixedTime,
+	}
+	store := storage.NewMemoryStore()
+
+	var stdout, stderr strings.Builder
+	p := &pipeline{cfg: &Config{Token: "test-token", Days: 30, Concurrency: 1}, deps: deps, store: store, stdout: &stdout, stderr: &stderr}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.sync(ctx)
+	if strings.Contains(stderr.String(), "Error") {
+		t.Errorf("expected a stopped sync to go quietly, got: %s", stderr.String())
+	}
+}
+
+func TestServeHealth(t *testing.T) {
+	store := storage.NewMemoryStore()
+	if _, err := store.RecordRun(storage.Run{StartedAt: fixedTime(), FinishedAt: fixedTime()}); err != nil {
+		t.Fatal(err)
+	}
+	h := serve.HealthHandler(serve.Options{Health: runHistory{store}, Now: fixedTime})
+
+	var stdout, stderr strings.Builder
+	shutdown, err := serveHealth("127.0.0.1:0", h, &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown()
+	_, url, ok := strings.Cut(strings.TrimSpace(stdout.String()), " on ")
+	if !ok {
+		t.Fatalf("expected the health check address, got %q", stdout.String())
+	}
+
+	resp, err := http.Get(url) // #nosec G107 -- test server
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"last_sync"`) {
+		t.Errorf("GET %s = %d %s, want the last run as the last sync", url, resp.StatusCode, body)
+	}
+
+	if _, err := serveHealth("127.0.0.1:-1", h, &stdout, &stderr); err == nil {
+		t.Error("expected an error for a bad address")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/report"
+	"github.com/justinabrahms/gitstreams/source"
+)
+
+// repoList is the -watch-repo flag: comma-separated repos, as owner/name,
+// whose releases, pushes and stars are tracked whoever follows them.
+// Repeating the flag adds to it.
+type repoList []string
+
+func (l *repoList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+// Get returns the repos, and marks this as a list flag for the config file.
+func (l *repoList) Get() any {
+	return []string(*l)
+}
+
+func (l *repoList) Set(value string) error {
+	for _, repo := range strings.Split(value, ",") {
+		repo = strings.TrimSpace(repo)
+		if repo == "" {
+			continue
+		}
+		if !report.ValidRepo(repo) {
+			return fmt.Errorf("watched repo %q should look like owner/name", repo)
+		}
+		if !slices.ContainsFunc(*l, func(r string) bool { return strings.EqualFold(r, repo) }) {
+			*l = append(*l, repo)
+		}
+	}
+	return nil
+}
+
+// fetchWatched records the watchlist's repos in snapshot. A repo that
+// can't be fetched keeps what previous, which may be nil, had for it, so
+// its activity isn't new again next time; it's warned about on w. Clients
+// that can't fetch repos leave the snapshot without a watchlist.
+func fetchWatched(ctx context.Context, client GitHubClient, repos []string, previous, snapshot *diff.Snapshot, cutoff time.Time, w io.Writer) {
+	watched := make(map[string]diff.WatchedRepo, len(repos))
+	for _, name := range repos {
+		repo, ok, err := source.WatchRepo(ctx, client, name, cutoff)
+		if !ok {
+			return
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: could not fetch watched repo %s: %v\n", name, err)
+			if previous != nil {
+				if prev, ok := previous.Watched[name]; ok {
+					watched[name] = prev
+				}
+			}
+			continue
+		}
+		watched[name] = repo
+	}
+	snapshot.Watched = watched
+}
+
+// watchToken returns the token the watchlist is fetched with: -token, or
+// the first -source account's when there are several.
+func watchToken(cfg *Config) string {
+	if cfg.Token == "" && len(cfg.Sources) > 0 {
+		return cfg.Sources[0].Token
+	}
+	return cfg.Token
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/gitstreams/diff"
+	"github.com/justinabrahms/gitstreams/github"
+	"github.com/justinabrahms/gitstreams/github/githubtest"
+)
+
+func TestRepoList(t *testing.T) {
+	var l repoList
+	if err := l.Set("golang/go, rust-lang/rust"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := l.Set("Golang/Go,cli/cli"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if want := []string{"golang/go", "rust-lang/rust", "cli/cli"}; !slices.Equal(l, want) {
+		t.Errorf("repoList = %v, want %v", l, want)
+	}
+	if err := l.Set("golang"); err == nil {
+		t.Error("expected an error for a repo without an owner")
+	}
+}
+
+func TestFetchWatched(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	srv := githubtest.NewServer(t)
+	srv.AddRepo(github.Repository{Name: "go", Owner: github.User{Login: "golang"}, StarCount: 100})
+	srv.AddReleases("golang/go",
+		github.Release{TagName: "go1.26.0", PublishedAt: now.Add(-time.Hour)},
+		github.Release{TagName: "go1.24.0", PublishedAt: now.Add(-90 * 24 * time.Hour)},
+	)
+	previous := &diff.Snapshot{Watched: map[string]diff.WatchedRepo{
+		"rust-lang/rust": {Repo: diff.Repo{Owner: "rust-lang", Name: "rust", Stars: 50}},
+	}}
+
+	var snapshot diff.Snapshot
+	var stderr bytes.Buffer
+	repos := []string{"golang/go", "rust-lang/rust", "cli/cli"}
+	fetchWatched(context.Background(), srv.Client(""), repos, previous, &snapshot, now.Add(-7*24*time.Hour), &stderr)
+
+	goRepo, ok := snapshot.Watched["golang/go"]
+	if !ok || goRepo.Repo.Stars != 100 || len(goRepo.Events) != 1 {
+		t.Errorf("golang/go = %+v, want 100 stars and the release since the cutoff", goRepo)
+	}
+	if rust := snapshot.Watched["rust-lang/rust"]; rust.Repo.Stars != 50 {
+		t.Errorf("rust-lang/rust = %+v, want the previous snapshot's when it can't be fetched", rust)
+	}
+	if _, ok := snapshot.Watched["cli/cli"]; ok {
+		t.Error("cli/cli was never fetched, so it shouldn't be in the snapshot")
+	}
+	for _, repo := range []string{"rust-lang/rust", "cli/cli"} {
+		if !strings.Contains(stderr.String(), repo) {
+			t.Errorf("stderr = %q, want a warning about %s", stderr.String(), repo)
+		}
+	}
+
+	var none diff.Snapshot
+	fetchWatched(context.Background(), &mockGitHubClient{}, repos, previous, &none, now, &stderr)
+	if none.Watched != nil {
+		t.Errorf("Watched = %+v, want none from a client that can't fetch repos", none.Watched)
+	}
+}